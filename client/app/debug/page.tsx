@@ -40,7 +40,15 @@ export default function DebugPage() {
   useEffect(() => {
     const fetchDebug = async () => {
       try {
-        const res = await fetch(`${process.env.NEXT_PUBLIC_API_URL}/api/debug`);
+        // The debug endpoint requires the server's admin token
+        const token = localStorage.getItem('slapjack_admin_token') ?? '';
+        const res = await fetch(`${process.env.NEXT_PUBLIC_API_URL}/api/debug`, {
+          headers: { Authorization: `Bearer ${token}` },
+        });
+        if (!res.ok) {
+          setError(res.status === 401 ? 'Set slapjack_admin_token in localStorage to view debug info' : 'Debug endpoint unavailable');
+          return;
+        }
         const data = await res.json();
         setDebug(data);
         setLastUpdate(new Date());
@@ -0,0 +1,94 @@
+'use client';
+
+import { useEffect, useState } from 'react';
+import { LeaderboardEntry, LeaderboardMetric, LeaderboardResponse } from '@/types/game';
+
+const metrics: { id: LeaderboardMetric; label: string }[] = [
+  { id: 'wins', label: 'Wins' },
+  { id: 'win_rate', label: 'Win Rate' },
+  { id: 'fastest_slap', label: 'Fastest Slap' },
+];
+
+// formatScore shows a leaderboard score in the metric's units
+function formatScore(metric: LeaderboardMetric, score: number): string {
+  switch (metric) {
+    case 'win_rate':
+      return `${Math.round(score * 100)}%`;
+    case 'fastest_slap':
+      return `${score}ms`;
+    default:
+      return String(score);
+  }
+}
+
+export default function LeaderboardPage() {
+  const [metric, setMetric] = useState<LeaderboardMetric>('wins');
+  const [entries, setEntries] = useState<LeaderboardEntry[]>([]);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    const fetchLeaderboard = async () => {
+      try {
+        const res = await fetch(`${process.env.NEXT_PUBLIC_API_URL}/api/leaderboard?metric=${metric}&limit=50`);
+        if (!res.ok) {
+          setError('Leaderboard unavailable');
+          return;
+        }
+        const data: LeaderboardResponse = await res.json();
+        setEntries(data.entries);
+        setError(null);
+      } catch (err) {
+        setError('Failed to fetch the leaderboard');
+      }
+    };
+
+    fetchLeaderboard();
+  }, [metric]);
+
+  return (
+    <div className="min-h-screen bg-gray-900 text-white p-8">
+      <div className="max-w-2xl mx-auto">
+        <h1 className="text-3xl font-bold mb-6">Leaderboard</h1>
+
+        <div className="flex gap-2 mb-6">
+          {metrics.map((m) => (
+            <button
+              key={m.id}
+              onClick={() => setMetric(m.id)}
+              className={`px-4 py-2 rounded-lg text-sm font-medium transition-colors ${
+                metric === m.id ? 'bg-yellow-500 text-black' : 'bg-white/10 hover:bg-white/20'
+              }`}
+            >
+              {m.label}
+            </button>
+          ))}
+        </div>
+
+        {error && (
+          <div className="bg-red-500/20 border border-red-500 rounded-lg p-4 mb-6">
+            <p className="text-red-400">{error}</p>
+          </div>
+        )}
+
+        {!error && entries.length === 0 && (
+          <p className="text-gray-400">Nobody has made this board yet.</p>
+        )}
+
+        <div className="space-y-2">
+          {entries.map((entry) => (
+            <div key={entry.rank} className="flex items-center gap-4 bg-white/5 rounded-lg p-3">
+              <span className="w-8 text-center text-gray-400">{entry.rank}</span>
+              <span className="flex-1 font-medium">{entry.name}</span>
+              <span className="text-xs text-gray-500">
+                {entry.wins}/{entry.played} won
+              </span>
+              <span className="w-16 text-right font-bold text-yellow-400">
+                {formatScore(metric, entry.score)}
+              </span>
+            </div>
+          ))}
+        </div>
+      </div>
+    </div>
+  );
+}
@@ -23,6 +23,7 @@ interface RoomSummary {
   maxPlayers: number;
   status: string;
   hostName: string;
+  dropIn?: string;
 }
 
 export default function Home() {
@@ -30,6 +31,7 @@ export default function Home() {
   const [activeTab, setActiveTab] = useState<Tab>('create');
   const [isLoading, setIsLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
+  const [idleDisconnected, setIdleDisconnected] = useState(false);
   const [liveRooms, setLiveRooms] = useState<RoomSummary[]>([]);
   const sound = useSound();
   const { handleMessage, setPlayerId } = useGameState();
@@ -73,6 +75,11 @@ export default function Home() {
         case ServerMessageTypes.ERROR: {
           const payload = message.payload as ErrorPayload;
           setIsLoading(false);
+          if (payload.code === 'IDLE_TIMEOUT') {
+            // The server closes idle connections normally, so we don't reconnect until asked
+            setIdleDisconnected(true);
+            break;
+          }
           setError(payload.message);
           break;
         }
@@ -81,10 +88,11 @@ export default function Home() {
     [handleMessage]
   );
 
-  const { isConnected, send } = useWebSocket({
+  const { isConnected, send, reconnect } = useWebSocket({
     onMessage,
     onConnect: () => {
       console.log('WebSocket connected');
+      setIdleDisconnected(false);
     },
     onDisconnect: () => {
       console.log('WebSocket disconnected');
@@ -92,7 +100,7 @@ export default function Home() {
   });
 
   const handleCreate = useCallback(
-    (playerName: string) => {
+    (playerName: string, variantId: string, scheduledAt?: number) => {
       if (!isConnected) {
         setError('Not connected to server');
         return;
@@ -102,6 +110,12 @@ export default function Home() {
       // Store player name for room page (sessionStorage = per tab)
       sessionStorage.setItem('slapjack_player_name', playerName);
       sessionStorage.setItem('slapjack_is_creating', 'true');
+      sessionStorage.setItem('slapjack_variant', variantId);
+      if (scheduledAt) {
+        sessionStorage.setItem('slapjack_scheduled_at', String(scheduledAt));
+      } else {
+        sessionStorage.removeItem('slapjack_scheduled_at');
+      }
       // Navigate to room page - it will send CREATE_ROOM on its own WebSocket
       router.push('/room/NEW');
     },
@@ -127,6 +141,18 @@ export default function Home() {
     [isConnected, sound, router]
   );
 
+  const handlePlayNow = useCallback(() => {
+    let playerName = sessionStorage.getItem('slapjack_player_name');
+    if (!playerName) {
+      playerName = window.prompt('Enter your name to play:');
+      if (!playerName || playerName.trim() === '') {
+        return;
+      }
+      playerName = playerName.trim();
+    }
+    handleJoin('PLAY', playerName);
+  }, [handleJoin]);
+
   const handleQuickJoin = useCallback(
     (roomCode: string) => {
       let playerName = sessionStorage.getItem('slapjack_player_name');
@@ -176,11 +202,25 @@ export default function Home() {
               }`}
             />
             <span className="text-sm text-gray-400">
-              {isConnected ? 'Connected' : 'Connecting...'}
+              {isConnected ? 'Connected' : idleDisconnected ? 'Disconnected while idle' : 'Connecting...'}
             </span>
+            {idleDisconnected && !isConnected && (
+              <button onClick={reconnect} className="text-sm text-yellow-400 hover:underline">
+                Reconnect
+              </button>
+            )}
           </div>
         </div>
 
+        {/* Drop-in game */}
+        <button
+          onClick={handlePlayNow}
+          disabled={!isConnected}
+          className="w-full mb-4 py-4 rounded-2xl bg-green-600 hover:bg-green-500 disabled:opacity-50 text-white text-lg font-bold shadow-2xl transition-colors"
+        >
+          Play now
+        </button>
+
         {/* Card container */}
         <div className="bg-gradient-to-b from-gray-800/90 to-gray-900/90 backdrop-blur-sm rounded-2xl shadow-2xl overflow-hidden border border-white/10">
           {/* Tabs */}
@@ -250,7 +290,9 @@ export default function Home() {
                 >
                   <div>
                     <span className="font-mono text-white font-bold">{room.code}</span>
-                    <span className="text-gray-400 ml-2 text-sm">hosted by {room.hostName}</span>
+                    <span className="text-gray-400 ml-2 text-sm">
+                      {room.dropIn ? `drop-in · ${room.dropIn}` : `hosted by ${room.hostName}`}
+                    </span>
                   </div>
                   <div className="flex items-center gap-2">
                     <span className="text-gray-400 text-sm">
@@ -5,6 +5,7 @@ import { useParams, useRouter } from 'next/navigation';
 import { motion, AnimatePresence } from 'framer-motion';
 import { Button } from '@/components/ui/Button';
 import { RoomSettings } from '@/components/lobby/RoomSettings';
+import { Calibration } from '@/components/lobby/Calibration';
 import { GameBoard } from '@/components/game/GameBoard';
 import { PlayerSlot } from '@/components/game/PlayerSlot';
 import { useWebSocket } from '@/hooks/useWebSocket';
@@ -21,6 +22,10 @@ import {
   ErrorPayload,
   PlayerKickedPayload,
   GameEndedPayload,
+  ScheduleReminderPayload,
+  CalibrateFlashPayload,
+  CalibrationResultPayload,
+  Invite,
 } from '@/types/game';
 
 export default function RoomPage() {
@@ -33,6 +38,9 @@ export default function RoomPage() {
   const [myPlayerId, setMyPlayerId] = useState<string | null>(null);
   const [waitingForReconnect, setWaitingForReconnect] = useState(true);
   const [actualRoomCode, setActualRoomCode] = useState<string | null>(null);
+  const [calibrationFlash, setCalibrationFlash] = useState<CalibrateFlashPayload | null>(null);
+  const [calibration, setCalibration] = useState<CalibrationResultPayload | null>(null);
+  const [webhookUrl, setWebhookUrl] = useState('');
 
   // Use actual room code if we have it (after creation), otherwise use URL
   const roomCode = actualRoomCode || urlCode;
@@ -41,10 +49,17 @@ export default function RoomPage() {
     room,
     game,
     countdown,
+    readyCheck,
+    kickVote,
+    party,
     lastSlapAttempt,
     lastSlapResult,
+    lastBurn,
+    lastSlapReplay,
+    lastAnnouncement,
     gameOver,
     turnWarning,
+    gameClockMs,
     handleMessage,
     setPlayerId,
     isMyTurn,
@@ -101,6 +116,11 @@ export default function RoomPage() {
           }
           // Clear joining flag
           sessionStorage.removeItem('slapjack_joining_room');
+          // Drop-in rooms are joined from /room/PLAY, show the real code
+          if (payload.room.code !== urlCode) {
+            setActualRoomCode(payload.room.code);
+            window.history.replaceState(null, '', `/room/${payload.room.code}`);
+          }
           break;
         }
 
@@ -128,8 +148,11 @@ export default function RoomPage() {
           const payload = message.payload as ErrorPayload;
           console.error('[Room] Error:', payload);
           setError(payload.message);
+          if (payload.code === 'CALIBRATION_FAILED') {
+            setCalibrationFlash(null);
+          }
           // If room not found, go back to home
-          if (payload.code === 'ROOM_NOT_FOUND' || payload.code === 'JOIN_FAILED') {
+          if (payload.code === 'ROOM_NOT_FOUND' || payload.code === 'JOIN_FAILED' || payload.code === 'BANNED') {
             setTimeout(() => router.push('/'), 2000);
           }
           break;
@@ -140,6 +163,13 @@ export default function RoomPage() {
           break;
         }
 
+        case ServerMessageTypes.SCHEDULE_REMINDER: {
+          const payload = message.payload as ScheduleReminderPayload;
+          console.log('[Room] Scheduled game starts in', payload.secondsLeft, 'seconds');
+          sound.play('countdown');
+          break;
+        }
+
         case ServerMessageTypes.CARD_PLAYED: {
           sound.play('cardPlace');
           break;
@@ -175,6 +205,17 @@ export default function RoomPage() {
           console.log('[Room] Game ended:', payload.reason);
           break;
         }
+
+        case ServerMessageTypes.CALIBRATE_FLASH: {
+          setCalibrationFlash(message.payload as CalibrateFlashPayload);
+          break;
+        }
+
+        case ServerMessageTypes.CALIBRATE_RESULT: {
+          setCalibrationFlash(null);
+          setCalibration(message.payload as CalibrationResultPayload);
+          break;
+        }
       }
     },
     [handleMessage, sound, router, setPlayerId, urlCode, myPlayerId]
@@ -191,12 +232,19 @@ export default function RoomPage() {
     }
 
     const playerName = sessionStorage.getItem('slapjack_player_name');
-    // Use URL to determine create vs join: /room/NEW = create, anything else = join
+    // Use URL to determine create vs join: /room/NEW = create, /room/PLAY = drop-in, anything else = join
     const isCreating = urlCode === 'NEW';
+    const isPlayNow = urlCode === 'PLAY';
 
     console.log('[Room] Ready to join, playerName:', playerName, 'isCreating:', isCreating, 'urlCode:', urlCode);
 
+    // An invite link gets its holder into a private room; keep it while they pick a name
+    const invite = new URLSearchParams(window.location.search).get('invite')
+      || sessionStorage.getItem('slapjack_invite')
+      || undefined;
+
     if (!playerName) {
+      if (invite) sessionStorage.setItem('slapjack_invite', invite);
       console.log('[Room] No player name, redirecting to home');
       router.push('/');
       return;
@@ -206,11 +254,19 @@ export default function RoomPage() {
 
     if (isCreating) {
       console.log('[Room] Creating room...');
+      const variantId = sessionStorage.getItem('slapjack_variant') || undefined;
+      const scheduledAt = Number(sessionStorage.getItem('slapjack_scheduled_at')) || undefined;
       sessionStorage.removeItem('slapjack_is_creating'); // Clean up
-      send(MessageTypes.CREATE_ROOM, { playerName });
+      sessionStorage.removeItem('slapjack_variant');
+      sessionStorage.removeItem('slapjack_scheduled_at');
+      send(MessageTypes.CREATE_ROOM, { playerName, variantId, scheduledAt });
+    } else if (isPlayNow) {
+      console.log('[Room] Finding a drop-in room...');
+      send(MessageTypes.PLAY_NOW, { playerName });
     } else {
       console.log('[Room] Joining room:', urlCode);
-      send(MessageTypes.JOIN_ROOM, { roomCode: urlCode, playerName });
+      sessionStorage.removeItem('slapjack_invite');
+      send(MessageTypes.JOIN_ROOM, { roomCode: urlCode, playerName, invite });
     }
   }, [isConnected, urlCode, router, send, waitingForReconnect]);
 
@@ -226,12 +282,22 @@ export default function RoomPage() {
     send(MessageTypes.START_GAME, {});
   }, [send, sound]);
 
+  const handleReady = useCallback(() => {
+    sound.play('click');
+    send(MessageTypes.READY, {});
+  }, [send, sound]);
+
+  const handleCancelStart = useCallback(() => {
+    sound.play('click');
+    send(MessageTypes.CANCEL_START, {});
+  }, [send, sound]);
+
   const handlePlayCard = useCallback(() => {
     if (isMyTurn && myCardCount > 0) {
       sound.play('cardSlide');
-      send(MessageTypes.PLAY_CARD, {});
+      send(MessageTypes.PLAY_CARD, { turnToken: game?.turnToken });
     }
-  }, [isMyTurn, myCardCount, send, sound]);
+  }, [isMyTurn, myCardCount, send, sound, game?.turnToken]);
 
   const handleSlap = useCallback(() => {
     send(MessageTypes.SLAP, { timestamp: Date.now() });
@@ -244,6 +310,20 @@ export default function RoomPage() {
     [send, room?.settings]
   );
 
+  const handleSetWebhook = useCallback((url: string) => {
+    send(MessageTypes.SET_INTEGRATION, { kind: 'discord', url });
+    setWebhookUrl('');
+  }, [send]);
+
+  const handleCalibrate = useCallback(() => {
+    const device = window.matchMedia('(pointer: coarse)').matches ? 'mobile' : 'desktop';
+    send(MessageTypes.CALIBRATE_START, { device });
+  }, [send]);
+
+  const handleCalibrateTap = useCallback(() => {
+    send(MessageTypes.CALIBRATE_TAP, {});
+  }, [send]);
+
   const handleLeaveRoom = useCallback(() => {
     send(MessageTypes.LEAVE_ROOM, {});
     sessionStorage.removeItem('slapjack_player_name');
@@ -265,13 +345,43 @@ export default function RoomPage() {
     navigator.clipboard.writeText(roomCode);
   }, [roomCode]);
 
+  // Single-use link into the room, private or not; only the host can make one
+  const handleCopyInvite = useCallback(async () => {
+    const playerToken = localStorage.getItem('slapjack_player_token');
+    try {
+      const res = await fetch(`${process.env.NEXT_PUBLIC_API_URL}/api/rooms/${roomCode}/invites`, {
+        method: 'POST',
+        headers: { Authorization: `Bearer ${playerToken}` },
+      });
+      if (!res.ok) throw new Error(await res.text());
+      const invite: Invite = await res.json();
+      const link = `${window.location.origin}/room/${invite.roomCode}?invite=${encodeURIComponent(invite.token)}`;
+      await navigator.clipboard.writeText(link);
+    } catch (err) {
+      console.error('[Room] Failed to create invite:', err);
+      setError('Could not create an invite link');
+    }
+  }, [roomCode]);
+
   const handleKickPlayer = useCallback((playerId: string) => {
     const player = room?.players.find(p => p.id === playerId);
     if (player && window.confirm(`Kick ${player.name}?`)) {
-      send(MessageTypes.KICK_PLAYER, { playerId });
+      // A ban with no duration lasts until the room closes
+      const ban = window.confirm(`Also ban ${player.name} from rejoining this room?`);
+      send(MessageTypes.KICK_PLAYER, { playerId, ban });
     }
   }, [send, room?.players]);
 
+  const handleVoteKick = useCallback((playerId: string, yes: boolean) => {
+    sound.play('click');
+    send(MessageTypes.VOTE_KICK, { playerId, yes });
+  }, [send, sound]);
+
+  const handleSetTeam = useCallback((playerId: string, teamId: number) => {
+    sound.play('click');
+    send(MessageTypes.SET_TEAM, { playerId, teamId });
+  }, [send, sound]);
+
   const handleEndGame = useCallback(() => {
     if (window.confirm('End the current game?')) {
       send(MessageTypes.END_GAME, {});
@@ -310,9 +420,12 @@ export default function RoomPage() {
 
   // Game in progress
   if (room.status === 'playing' && game) {
+    // A party room's piles only show the players on our own
+    const pileIndex = room.piles ? Math.max(room.piles.findIndex(ids => ids.includes(myPlayerId ?? '')), 0) : -1;
+    const tablePlayers = room.piles ? room.players.filter(p => room.piles![pileIndex].includes(p.id)) : room.players;
     return (
       <GameBoard
-        players={room.players}
+        players={tablePlayers}
         currentPlayerId={game.currentPlayerId}
         myPlayerId={myPlayerId || ''}
         gameState={game}
@@ -323,7 +436,14 @@ export default function RoomPage() {
         isHost={room.hostId === myPlayerId}
         lastSlapAttempt={lastSlapAttempt}
         lastSlapResult={lastSlapResult}
+        lastBurn={lastBurn}
+        lastSlapReplay={lastSlapReplay}
+        lastAnnouncement={lastAnnouncement}
         turnWarning={turnWarning}
+        gameClockMs={gameClockMs}
+        playerLatency={room.playerLatency}
+        pile={pileIndex + 1}
+        pileWinners={party?.winners}
       />
     );
   }
@@ -331,6 +451,8 @@ export default function RoomPage() {
   // Game over
   if (gameOver) {
     const amIHost = room.hostId === myPlayerId;
+    const myTeam = room.players.find(p => p.id === myPlayerId)?.teamId;
+    const iWon = gameOver.winningTeam ? myTeam === gameOver.winningTeam : gameOver.winnerId === myPlayerId;
     const sortedPlayers = [...room.players].sort((a, b) => {
       // Sort by successful slaps descending
       const aSlaps = gameOver.stats.successfulSlaps[a.id] || 0;
@@ -351,12 +473,17 @@ export default function RoomPage() {
             transition={{ type: 'spring', delay: 0.2 }}
             className="text-6xl mb-4"
           >
-            {gameOver.winnerId === myPlayerId ? '🎉' : '😔'}
+            {iWon ? '🎉' : '😔'}
           </motion.div>
           <h1 className="text-3xl font-bold text-white mb-2">Game Over!</h1>
           <p className="text-xl text-yellow-400 mb-6">
-            {gameOver.winnerName} wins!
+            {gameOver.winningTeam ? `Team ${gameOver.winningTeam} wins!` : `${gameOver.winnerName} wins!`}
           </p>
+          {gameOver.winReason && gameOver.winReason !== 'last_standing' && (
+            <p className="text-sm text-gray-400 -mt-4 mb-6">
+              {gameOver.winReason === 'piles' ? 'First to the pile target' : 'Most cards when time ran out'}
+            </p>
+          )}
 
           {/* Game summary */}
           <div className="bg-white/5 rounded-lg p-4 mb-4 text-left">
@@ -379,6 +506,7 @@ export default function RoomPage() {
               {sortedPlayers.map((player, index) => {
                 const successfulSlaps = gameOver.stats.successfulSlaps[player.id] || 0;
                 const cardsBurned = gameOver.stats.cardsBurned[player.id] || 0;
+                const playerStats = gameOver.stats.players?.[player.id];
                 const isWinner = player.id === gameOver.winnerId;
                 const isMe = player.id === myPlayerId;
 
@@ -414,6 +542,30 @@ export default function RoomPage() {
                         <div className="text-red-400 font-medium">{cardsBurned}</div>
                         <div className="text-gray-500">burned</div>
                       </div>
+                      {playerStats && (
+                        <>
+                          <div className="text-center">
+                            <div className="text-gray-300 font-medium">{playerStats.missedSlaps}</div>
+                            <div className="text-gray-500">missed</div>
+                          </div>
+                          <div className="text-center">
+                            <div className="text-blue-400 font-medium">
+                              {playerStats.averageMs ? `${playerStats.averageMs}ms` : '–'}
+                            </div>
+                            <div className="text-gray-500">
+                              {playerStats.fastestMs ? `best ${playerStats.fastestMs}ms` : 'avg'}
+                            </div>
+                          </div>
+                          <div className="text-center">
+                            <div className="text-yellow-400 font-medium">{playerStats.longestPile}</div>
+                            <div className="text-gray-500">top pile</div>
+                          </div>
+                          <div className="text-center">
+                            <div className="text-red-300 font-medium">{playerStats.biggestBurn}</div>
+                            <div className="text-gray-500">worst burn</div>
+                          </div>
+                        </>
+                      )}
                     </div>
                   </motion.div>
                 );
@@ -439,10 +591,34 @@ export default function RoomPage() {
     );
   }
 
+  // Ready check before the countdown
+  if (readyCheck !== null) {
+    const amIReady = myPlayerId !== null && readyCheck.ready.includes(myPlayerId);
+    const amIAsked = myPlayerId !== null && readyCheck.playerIds.includes(myPlayerId);
+    return (
+      <main className="min-h-screen flex flex-col items-center justify-center gap-6">
+        <h2 className="text-4xl font-black text-white">Ready?</h2>
+        <p className="text-gray-400">
+          {readyCheck.ready.length} of {readyCheck.playerIds.length} ready · {readyCheck.timeoutSeconds}s to answer
+        </p>
+        {amIAsked && (
+          <Button onAction={handleReady} variant="primary" disabled={amIReady}>
+            {amIReady ? 'Waiting for others...' : "I'm ready"}
+          </Button>
+        )}
+        {room.hostId === myPlayerId && (
+          <Button onAction={handleCancelStart} variant="ghost" size="sm">
+            Cancel
+          </Button>
+        )}
+      </main>
+    );
+  }
+
   // Countdown
   if (countdown !== null) {
     return (
-      <main className="min-h-screen flex items-center justify-center">
+      <main className="min-h-screen flex flex-col items-center justify-center gap-8">
         <motion.div
           key={countdown}
           initial={{ scale: 0, opacity: 0 }}
@@ -453,6 +629,11 @@ export default function RoomPage() {
         >
           {countdown}
         </motion.div>
+        {room.hostId === myPlayerId && (
+          <Button onAction={handleCancelStart} variant="ghost" size="sm">
+            Cancel
+          </Button>
+        )}
       </main>
     );
   }
@@ -478,11 +659,46 @@ export default function RoomPage() {
             >
               {roomCode}
             </button>
+            {room.scheduledAt && (
+              <p className="text-sm text-yellow-400 mt-1">
+                Starts at {new Date(room.scheduledAt).toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' })}
+              </p>
+            )}
+            {amIHost && (
+              <button
+                onClick={handleCopyInvite}
+                className="block mx-auto mt-1 text-xs text-gray-400 hover:text-yellow-400 transition-colors"
+              >
+                Copy invite link
+              </button>
+            )}
           </div>
 
           <div className="w-20" />
         </div>
 
+        {/* Running kick vote */}
+        {kickVote && (
+          <div className="mb-6 bg-orange-500/20 border border-orange-500/40 rounded-xl p-4 flex items-center justify-between gap-4">
+            <div>
+              <p className="text-white font-semibold">Vote to kick {kickVote.targetName}</p>
+              <p className="text-xs text-gray-300">
+                {kickVote.needed} of {kickVote.voters.length} votes needed · {kickVote.timeoutSeconds}s to decide
+              </p>
+            </div>
+            {myPlayerId !== null && kickVote.voters.includes(myPlayerId) && kickVote.startedBy !== myPlayerId && (
+              <div className="flex gap-2">
+                <Button onAction={() => handleVoteKick(kickVote.targetId, true)} variant="primary" size="sm">
+                  Kick
+                </Button>
+                <Button onAction={() => handleVoteKick(kickVote.targetId, false)} variant="ghost" size="sm">
+                  Keep
+                </Button>
+              </div>
+            )}
+          </div>
+        )}
+
         <div className="grid md:grid-cols-2 gap-8">
           {/* Players */}
           <motion.div
@@ -508,7 +724,32 @@ export default function RoomPage() {
                       player={player}
                       isCurrentTurn={false}
                       isCurrentPlayer={player.id === myPlayerId}
+                      latencyMs={room.playerLatency?.[player.id]}
                     />
+                    {/* Team switch, for the player themselves or the host */}
+                    {room.settings.teams && player.teamId && (
+                      <button
+                        onClick={() => handleSetTeam(player.id, player.teamId === 1 ? 2 : 1)}
+                        disabled={!amIHost && player.id !== myPlayerId}
+                        className={`mt-1 w-full text-xs rounded py-0.5 ${
+                          player.teamId === 1 ? 'bg-blue-500/30 text-blue-200' : 'bg-red-500/30 text-red-200'
+                        } disabled:cursor-default`}
+                        title={amIHost || player.id === myPlayerId ? 'Switch team' : undefined}
+                      >
+                        Team {player.teamId}
+                      </button>
+                    )}
+                    {/* Vote to kick, for everyone else */}
+                    {!amIHost && player.id !== myPlayerId && !kickVote &&
+                      (player.id !== room.hostId || room.settings.voteKickHost) && (
+                      <button
+                        onClick={() => handleVoteKick(player.id, true)}
+                        className="absolute -top-2 -right-2 w-6 h-6 bg-orange-500 hover:bg-orange-600 text-white rounded-full text-xs opacity-0 group-hover:opacity-100 transition-opacity"
+                        title="Vote to kick"
+                      >
+                        ×
+                      </button>
+                    )}
                     {/* Kick button for host */}
                     {amIHost && player.id !== myPlayerId && (
                       <button
@@ -579,6 +820,38 @@ export default function RoomPage() {
                 Only the host can change settings
               </p>
             )}
+            {amIHost && (
+              <div className="mt-4">
+                <p className="text-sm text-gray-400 mb-2">
+                  Discord webhook {room.integrations?.includes('discord') ? '· connected' : ''}
+                </p>
+                <div className="flex gap-2">
+                  <input
+                    type="url"
+                    value={webhookUrl}
+                    onChange={(e) => setWebhookUrl(e.target.value)}
+                    placeholder="https://discord.com/api/webhooks/..."
+                    className="flex-1 bg-white/10 border border-white/20 rounded-lg px-3 py-2 text-sm text-white placeholder-gray-500"
+                  />
+                  <Button onAction={() => handleSetWebhook(webhookUrl)} variant="secondary" size="sm" disabled={!webhookUrl}>
+                    Save
+                  </Button>
+                  {room.integrations?.includes('discord') && (
+                    <Button onAction={() => handleSetWebhook('')} variant="ghost" size="sm">
+                      Remove
+                    </Button>
+                  )}
+                </div>
+              </div>
+            )}
+            <div className="mt-6 pt-6 border-t border-white/10">
+              <Calibration
+                flash={calibrationFlash}
+                result={calibration}
+                onStart={handleCalibrate}
+                onTap={handleCalibrateTap}
+              />
+            </div>
           </motion.div>
         </div>
 
@@ -0,0 +1,48 @@
+'use client';
+
+import { motion, AnimatePresence } from 'framer-motion';
+import { AnnouncePayload } from '@/types/game';
+
+interface AnnouncerProps {
+  announcement: AnnouncePayload | null;
+  getPlayerName: (playerId: string) => string;
+}
+
+// Banner text for each announcer call
+function announcementText({ kind, playerId, count }: AnnouncePayload, getPlayerName: (playerId: string) => string) {
+  const name = playerId ? getPlayerName(playerId) : '';
+  switch (kind) {
+    case 'BIG_PILE':
+      return { title: 'Big Pile!', detail: `${count} cards up for grabs` };
+    case 'COMEBACK':
+      return { title: 'Comeback!', detail: `${name} is back with ${count} cards` };
+    case 'LAST_TWO_PLAYERS':
+      return { title: 'Last Two Standing', detail: 'Winner takes all' };
+    case 'FIRST_BLOOD':
+      return { title: 'First Blood!', detail: `${name} takes the first pile` };
+  }
+}
+
+// Shows the server's announcer calls as a banner across the top of the table
+export function Announcer({ announcement, getPlayerName }: AnnouncerProps) {
+  const text = announcement ? announcementText(announcement, getPlayerName) : null;
+
+  return (
+    <AnimatePresence>
+      {announcement && text && (
+        <motion.div
+          key={`${announcement.kind}-${announcement.playerId ?? ''}-${announcement.count ?? 0}`}
+          initial={{ opacity: 0, scale: 0.8, y: -20 }}
+          animate={{ opacity: 1, scale: 1, y: 0 }}
+          exit={{ opacity: 0, y: -20 }}
+          className="absolute top-20 left-1/2 -translate-x-1/2 z-30 text-center pointer-events-none"
+        >
+          <div className="text-3xl font-black text-yellow-400 uppercase tracking-wide drop-shadow-lg">
+            {text.title}
+          </div>
+          <div className="text-sm text-white/80">{text.detail}</div>
+        </motion.div>
+      )}
+    </AnimatePresence>
+  );
+}
@@ -6,11 +6,16 @@ import { CardPile } from './CardPile';
 import { PlayerHand } from './PlayerHand';
 import { PlayerSlot } from './PlayerSlot';
 import { SlapEffect, SlapAttemptIndicator } from './SlapEffect';
+import { SlapReplay } from './SlapReplay';
+import { Announcer } from './Announcer';
 import {
   Player,
   GameState,
   SlapAttemptedPayload,
   SlapResultPayload,
+  CardsBurnedPayload,
+  SlapReplayPayload,
+  AnnouncePayload,
 } from '@/types/game';
 import { clsx } from 'clsx';
 
@@ -26,7 +31,14 @@ interface GameBoardProps {
   isHost: boolean;
   lastSlapAttempt: SlapAttemptedPayload | null;
   lastSlapResult: SlapResultPayload | null;
+  lastBurn: CardsBurnedPayload | null;
+  lastSlapReplay?: SlapReplayPayload | null;
+  lastAnnouncement?: AnnouncePayload | null;
   turnWarning: number | null;
+  gameClockMs?: number | null; // Time left in a timed game
+  playerLatency?: Record<string, number>;
+  pile?: number; // Our party room pile, 0 outside the piles
+  pileWinners?: Record<number, string>; // Winner names of party piles won so far
 }
 
 export function GameBoard({
@@ -41,7 +53,14 @@ export function GameBoard({
   isHost,
   lastSlapAttempt,
   lastSlapResult,
+  lastBurn,
+  lastSlapReplay = null,
+  lastAnnouncement = null,
   turnWarning,
+  gameClockMs,
+  playerLatency,
+  pile = 0,
+  pileWinners = {},
 }: GameBoardProps) {
   const [screenShake, setScreenShake] = useState(false);
   const [redFlash, setRedFlash] = useState(false);
@@ -141,6 +160,20 @@ export function GameBoard({
         )}
       </div>
 
+      {/* Game clock */}
+      {gameClockMs != null && (
+        <div className="absolute top-4 left-1/2 -translate-x-1/2 z-20 px-3 py-1.5 bg-black/40 rounded-lg">
+          <span
+            className={clsx(
+              'font-mono text-lg font-bold',
+              gameClockMs <= 30000 ? 'text-red-400' : 'text-white'
+            )}
+          >
+            {Math.floor(gameClockMs / 60000)}:{String(Math.floor((gameClockMs % 60000) / 1000)).padStart(2, '0')}
+          </span>
+        </div>
+      )}
+
       {/* Turn warning */}
       {turnWarning !== null && turnWarning <= 3 && (
         <motion.div
@@ -165,6 +198,7 @@ export function GameBoard({
             isCurrentTurn={currentPlayerId === player.id}
             isCurrentPlayer={false}
             showSlap={lastSlapAttempt?.playerId === player.id}
+            latencyMs={playerLatency?.[player.id]}
           />
         </div>
       ))}
@@ -221,7 +255,28 @@ export function GameBoard({
         playerName={
           lastSlapResult ? getPlayerName(lastSlapResult.playerId) : undefined
         }
+        burn={lastBurn}
+        recipientName={lastBurn?.recipientId ? getPlayerName(lastBurn.recipientId) : undefined}
       />
+
+      {/* Slow-motion replay of the last winning slap */}
+      <SlapReplay replay={lastSlapReplay} getPlayerName={getPlayerName} />
+
+      {/* Announcer banners */}
+      <Announcer announcement={lastAnnouncement} getPlayerName={getPlayerName} />
+
+      {/* Party room piles */}
+      {pile > 0 && (
+        <div className="absolute top-4 right-4 z-20 bg-black/50 rounded-lg px-3 py-2 text-sm text-white">
+          <p className="font-semibold">Pile {pile}</p>
+          {Object.entries(pileWinners).map(([won, name]) => (
+            <p key={won} className="text-xs text-gray-300">
+              Pile {won}: {name} to the finale
+            </p>
+          ))}
+          {pileWinners[pile] && <p className="text-xs text-yellow-300">Waiting for the other pile</p>}
+        </div>
+      )}
     </div>
   );
 }
@@ -9,6 +9,7 @@ interface PlayerSlotProps {
   isCurrentTurn: boolean;
   isCurrentPlayer: boolean;
   showSlap?: boolean;
+  latencyMs?: number;
 }
 
 export function PlayerSlot({
@@ -16,6 +17,7 @@ export function PlayerSlot({
   isCurrentTurn,
   isCurrentPlayer,
   showSlap = false,
+  latencyMs,
 }: PlayerSlotProps) {
   return (
     <motion.div
@@ -55,6 +57,17 @@ export function PlayerSlot({
           </svg>
           {player.cardCount}
         </div>
+        {latencyMs !== undefined && (
+          <div
+            className={clsx(
+              'text-[10px]',
+              latencyMs < 100 ? 'text-green-400' : latencyMs < 250 ? 'text-yellow-400' : 'text-red-400'
+            )}
+            title="Round-trip latency"
+          >
+            {latencyMs}ms
+          </div>
+        )}
       </div>
 
       {/* Connection status */}
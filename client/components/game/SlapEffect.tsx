@@ -1,17 +1,27 @@
 'use client';
 
 import { motion, AnimatePresence } from 'framer-motion';
-import { SlapResultPayload } from '@/types/game';
+import { CardsBurnedPayload, SlapResultPayload } from '@/types/game';
+
+const suitSymbols: Record<string, string> = {
+  hearts: '♥',
+  diamonds: '♦',
+  clubs: '♣',
+  spades: '♠',
+};
 
 interface SlapEffectProps {
   result: SlapResultPayload | null;
   playerName?: string;
+  burn?: CardsBurnedPayload | null; // The cards a false slap cost
+  recipientName?: string;
 }
 
-export function SlapEffect({ result, playerName }: SlapEffectProps) {
+export function SlapEffect({ result, playerName, burn, recipientName }: SlapEffectProps) {
   if (!result) return null;
 
   const isSuccess = result.success;
+  const behind = result.contenders?.find((c) => c.playerId === result.playerId && c.deltaMs > 0);
   const text = isSuccess
     ? `+${result.cardsWon} cards!`
     : `BURN -${result.burnPenalty || 0}`;
@@ -22,6 +32,7 @@ export function SlapEffect({ result, playerName }: SlapEffectProps) {
     sandwich: 'SANDWICH!',
     invalid: 'MISS!',
     cooldown: 'TOO FAST!',
+    beaten: 'TOO SLOW!',
   }[result.reason];
 
   return (
@@ -74,6 +85,34 @@ export function SlapEffect({ result, playerName }: SlapEffectProps) {
             {text}
           </motion.div>
 
+          {/* How far behind the winning slap a late one landed */}
+          {!isSuccess && behind && (
+            <motion.div
+              initial={{ opacity: 0 }}
+              animate={{ opacity: 1 }}
+              className="text-white/80 text-sm"
+            >
+              {behind.deltaMs}ms too slow
+            </motion.div>
+          )}
+
+          {/* Burned cards and where they went */}
+          {burn && burn.playerId === result.playerId && burn.cards.length > 0 && (
+            <motion.div
+              initial={{ opacity: 0 }}
+              animate={{ opacity: 1 }}
+              className="text-white/80 text-sm"
+            >
+              {burn.cards.map((c) => `${c.rank}${suitSymbols[c.suit] ?? ''}`).join(' ')}
+              {' → '}
+              {burn.destination === 'winner'
+                ? recipientName ?? 'pile winner'
+                : burn.destination === 'discard'
+                ? 'out of play'
+                : 'bottom of pile'}
+            </motion.div>
+          )}
+
           {/* Impact effect */}
           <motion.div
             initial={{ scale: 0, opacity: 1 }}
@@ -0,0 +1,61 @@
+'use client';
+
+import { motion, AnimatePresence } from 'framer-motion';
+import { SlapReplayPayload } from '@/types/game';
+import { Card } from './Card';
+
+interface SlapReplayProps {
+  replay: SlapReplayPayload | null;
+  getPlayerName: (playerId: string) => string;
+}
+
+// Replays a winning slap in slow motion: the cards landing on the pile, then
+// each slap in the order the server received them, spaced by reaction time
+export function SlapReplay({ replay, getPlayerName }: SlapReplayProps) {
+  const cardDelay = 0.4; // Seconds between cards landing
+  const msToSeconds = 4 / 1000; // Slaps play back at a quarter speed
+
+  return (
+    <AnimatePresence>
+      {replay && (
+        <motion.div
+          key={replay.slaps.map((s) => s.timestamp).join('-') + replay.winnerId}
+          initial={{ opacity: 0, y: 20 }}
+          animate={{ opacity: 1, y: 0 }}
+          exit={{ opacity: 0, y: 20 }}
+          className="absolute bottom-24 right-4 z-30 bg-black/60 rounded-xl p-3 pointer-events-none"
+        >
+          <div className="text-xs text-yellow-400 font-bold uppercase tracking-wide mb-2">
+            Instant Replay
+          </div>
+          <div className="flex gap-1 mb-2">
+            {replay.plays.map((play, i) => (
+              <motion.div
+                key={`${play.timestamp}-${i}`}
+                initial={{ opacity: 0, y: -10 }}
+                animate={{ opacity: 1, y: 0 }}
+                transition={{ delay: i * cardDelay }}
+              >
+                <Card card={play.card} size="sm" animate={false} />
+              </motion.div>
+            ))}
+          </div>
+          <ul className="space-y-1">
+            {replay.slaps.map((slap, i) => (
+              <motion.li
+                key={`${slap.playerId}-${i}`}
+                initial={{ opacity: 0, x: -10 }}
+                animate={{ opacity: 1, x: 0 }}
+                transition={{ delay: replay.plays.length * cardDelay + slap.reactionMs * msToSeconds }}
+                className={`flex justify-between gap-4 text-sm ${slap.won ? 'text-green-400 font-semibold' : 'text-gray-300'}`}
+              >
+                <span>{getPlayerName(slap.playerId)}</span>
+                <span className="font-mono">{slap.reactionMs}ms</span>
+              </motion.li>
+            ))}
+          </ul>
+        </motion.div>
+      )}
+    </AnimatePresence>
+  );
+}
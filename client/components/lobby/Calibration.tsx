@@ -0,0 +1,53 @@
+'use client';
+
+import { useEffect, useState } from 'react';
+import { Button } from '@/components/ui/Button';
+import { CalibrateFlashPayload, CalibrationResultPayload } from '@/types/game';
+
+interface CalibrationProps {
+  flash: CalibrateFlashPayload | null;
+  result: CalibrationResultPayload | null;
+  onStart: () => void;
+  onTap: () => void;
+}
+
+// Measures this device's input lag: the server flashes on a steady beat and
+// the player taps in time with it
+export function Calibration({ flash, result, onStart, onTap }: CalibrationProps) {
+  const [lit, setLit] = useState(false);
+  const running = flash !== null && flash.round <= flash.rounds;
+
+  useEffect(() => {
+    if (!flash) return;
+    setLit(true);
+    const timer = setTimeout(() => setLit(false), 150);
+    return () => clearTimeout(timer);
+  }, [flash]);
+
+  return (
+    <div className="space-y-3">
+      <h3 className="text-lg font-semibold text-white">Input Latency</h3>
+      {running ? (
+        <button
+          onPointerDown={onTap}
+          className={`w-full h-24 rounded-xl text-white font-bold transition-colors ${
+            lit ? 'bg-yellow-500' : 'bg-white/10'
+          }`}
+        >
+          Tap in time with the flash ({flash.round}/{flash.rounds})
+        </button>
+      ) : (
+        <>
+          <p className="text-sm text-gray-400">
+            {result
+              ? `This device adds about ${result.inputLagMs}ms, which is taken off your slap times.`
+              : 'Calibrate so your device’s lag isn’t held against your slaps.'}
+          </p>
+          <Button onAction={onStart} variant="secondary" size="sm">
+            {result ? 'Recalibrate' : 'Calibrate'}
+          </Button>
+        </>
+      )}
+    </div>
+  );
+}
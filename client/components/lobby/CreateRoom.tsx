@@ -1,19 +1,34 @@
 'use client';
 
-import { useState } from 'react';
+import { useEffect, useState } from 'react';
 import { Button } from '@/components/ui/Button';
+import { RuleVariant } from '@/types/game';
 
 interface CreateRoomProps {
-  onCreate: (playerName: string) => void;
+  onCreate: (playerName: string, variantId: string, scheduledAt?: number) => void;
   isLoading?: boolean;
 }
 
 export function CreateRoom({ onCreate, isLoading = false }: CreateRoomProps) {
   const [name, setName] = useState('');
+  const [variants, setVariants] = useState<RuleVariant[]>([]);
+  const [variantId, setVariantId] = useState('');
+  const [startAt, setStartAt] = useState(''); // datetime-local value, in local time
+
+  // Variant names and descriptions come back in the browser's language
+  useEffect(() => {
+    const lang = encodeURIComponent(navigator.language);
+    fetch(`${process.env.NEXT_PUBLIC_API_URL}/api/variants?lang=${lang}`)
+      .then((res) => res.json())
+      .then((list: RuleVariant[]) => setVariants(list || []))
+      .catch((err) => console.log('Failed to fetch variants:', err));
+  }, []);
+
+  const selected = variants.find((v) => v.id === variantId);
 
   const handleSubmit = () => {
     if (name.trim()) {
-      onCreate(name.trim());
+      onCreate(name.trim(), variantId, startAt ? new Date(startAt).getTime() : undefined);
     }
   };
 
@@ -43,6 +58,56 @@ export function CreateRoom({ onCreate, isLoading = false }: CreateRoomProps) {
         />
       </div>
 
+      {variants.length > 0 && (
+        <div>
+          <label
+            htmlFor="variant"
+            className="block text-sm font-medium text-gray-300 mb-2"
+          >
+            Rules
+          </label>
+          <select
+            id="variant"
+            value={variantId}
+            onChange={(e) => setVariantId(e.target.value)}
+            disabled={isLoading}
+            className="w-full bg-white/10 border border-white/20 rounded-lg px-3 py-2 text-white"
+          >
+            <option value="">Default</option>
+            {variants.map((v) => (
+              <option key={v.id} value={v.id}>
+                {v.name} ({v.minPlayers}–{v.maxPlayers} players)
+              </option>
+            ))}
+          </select>
+          {selected && (
+            <p className="text-xs text-gray-400 mt-1">{selected.description}</p>
+          )}
+        </div>
+      )}
+
+      <div>
+        <label
+          htmlFor="startAt"
+          className="block text-sm font-medium text-gray-300 mb-2"
+        >
+          Start at (optional)
+        </label>
+        <input
+          id="startAt"
+          type="datetime-local"
+          value={startAt}
+          onChange={(e) => setStartAt(e.target.value)}
+          disabled={isLoading}
+          className="w-full bg-white/10 border border-white/20 rounded-lg px-3 py-2 text-white"
+        />
+        {startAt && (
+          <p className="text-xs text-gray-400 mt-1">
+            The game starts by itself then if enough players are in
+          </p>
+        )}
+      </div>
+
       <Button
         onAction={handleSubmit}
         disabled={!name.trim() || isLoading}
@@ -1,6 +1,15 @@
 'use client';
 
-import { RoomSettings as RoomSettingsType } from '@/types/game';
+import { BurnDestination, DisconnectedTurns, LeaverCards, RANKS, RoomSettings as RoomSettingsType, SettingsPreset, Visibility, WinCondition } from '@/types/game';
+
+const presets: { id: SettingsPreset; label: string }[] = [
+  { id: 'classic', label: 'Classic' },
+  { id: 'speed', label: 'Speed' },
+  { id: 'party', label: 'Party' },
+];
+
+// Fewest ranks a custom deck can have; the server enforces the same
+const MIN_DECK_RANKS = 4;
 
 interface RoomSettingsProps {
   settings: RoomSettingsType;
@@ -13,10 +22,44 @@ export function RoomSettings({
   onChange,
   disabled = false,
 }: RoomSettingsProps) {
+  const deckRanks = settings.deck?.ranks?.length ? settings.deck.ranks : RANKS;
+  const extraJacks = settings.deck?.extraJacks ?? 0;
+
+  const toggleRank = (rank: string) => {
+    const ranks = deckRanks.includes(rank)
+      ? deckRanks.filter((r) => r !== rank)
+      : RANKS.filter((r) => r === rank || deckRanks.includes(r));
+    if (ranks.length < MIN_DECK_RANKS) return;
+    onChange({ deck: { ranks, extraJacks } });
+  };
+
   return (
     <div className="space-y-4">
       <h3 className="text-lg font-semibold text-white mb-4">Game Settings</h3>
 
+      {/* Presets */}
+      <div>
+        <label className="block text-sm text-gray-300 mb-2">Preset</label>
+        <div className="flex gap-2">
+          {presets.map(({ id, label }) => (
+            <button
+              key={id}
+              type="button"
+              onClick={() => onChange({ preset: id })}
+              disabled={disabled}
+              className={`flex-1 px-3 py-2 rounded-lg text-sm font-medium transition-colors ${
+                settings.preset === id
+                  ? 'bg-yellow-500 text-black'
+                  : 'bg-white/10 text-white hover:bg-white/20'
+              }`}
+            >
+              {label}
+            </button>
+          ))}
+        </div>
+        {!settings.preset && <p className="text-xs text-gray-400 mt-1">Custom</p>}
+      </div>
+
       {/* Max Players */}
       <div>
         <label className="flex justify-between items-center text-sm text-gray-300 mb-2">
@@ -26,7 +69,7 @@ export function RoomSettings({
         <input
           type="range"
           min={2}
-          max={8}
+          max={16}
           value={settings.maxPlayers}
           onChange={(e) =>
             onChange({ maxPlayers: parseInt(e.target.value) })
@@ -36,8 +79,13 @@ export function RoomSettings({
         />
         <div className="flex justify-between text-xs text-gray-500 mt-1">
           <span>2</span>
-          <span>8</span>
+          <span>16</span>
         </div>
+        {settings.maxPlayers > 8 && (
+          <p className="text-xs text-gray-400 mt-1">
+            Over 8 players split across two piles, whose winners meet in a finale
+          </p>
+        )}
       </div>
 
       {/* Turn Timeout */}
@@ -66,6 +114,347 @@ export function RoomSettings({
         </div>
       </div>
 
+      {/* Adaptive Pacing */}
+      <div>
+        <label className="flex items-center gap-3 cursor-pointer">
+          <input
+            type="checkbox"
+            checked={settings.adaptivePacing ?? false}
+            onChange={(e) => onChange({ adaptivePacing: e.target.checked })}
+            disabled={disabled}
+            className="w-5 h-5 rounded bg-white/20 border-white/30 text-yellow-500 focus:ring-yellow-500 focus:ring-offset-0"
+          />
+          <div>
+            <span className="text-white">Adaptive Pacing</span>
+            <p className="text-xs text-gray-400">
+              Turns get shorter as the pile grows, longer after it&apos;s won
+            </p>
+          </div>
+        </label>
+        {settings.adaptivePacing && (
+          <div className="mt-3">
+            <label className="flex justify-between items-center text-sm text-gray-300 mb-2">
+              <span>Shortest Turn</span>
+              <span className="text-white font-medium">
+                {(settings.pacingMinTimeoutMs ?? 5000) / 1000}s
+              </span>
+            </label>
+            <input
+              type="range"
+              min={3000}
+              max={settings.turnTimeoutMs}
+              step={1000}
+              value={settings.pacingMinTimeoutMs ?? 5000}
+              onChange={(e) =>
+                onChange({ pacingMinTimeoutMs: parseInt(e.target.value) })
+              }
+              disabled={disabled}
+              className="w-full h-2 bg-white/20 rounded-lg appearance-none cursor-pointer accent-yellow-500"
+            />
+          </div>
+        )}
+      </div>
+
+      {/* Auto Pace */}
+      <label className="flex items-center gap-3 cursor-pointer">
+        <input
+          type="checkbox"
+          checked={settings.autoPace ?? false}
+          onChange={(e) => onChange({ autoPace: e.target.checked })}
+          disabled={disabled}
+          className="w-5 h-5 rounded bg-white/20 border-white/30 text-yellow-500 focus:ring-yellow-500 focus:ring-offset-0"
+        />
+        <div>
+          <span className="text-white">Speed Up Over Time</span>
+          <p className="text-xs text-gray-400">
+            Turns get shorter as the game goes on and players drop out
+          </p>
+        </div>
+      </label>
+
+      {/* Countdown */}
+      <div>
+        <label className="flex justify-between items-center text-sm text-gray-300 mb-2">
+          <span>Start Countdown</span>
+          <span className="text-white font-medium">{settings.countdownSeconds ?? 3}s</span>
+        </label>
+        <input
+          type="range"
+          min={1}
+          max={10}
+          step={1}
+          value={settings.countdownSeconds ?? 3}
+          onChange={(e) => onChange({ countdownSeconds: parseInt(e.target.value) })}
+          disabled={disabled}
+          className="w-full h-2 bg-white/20 rounded-lg appearance-none cursor-pointer accent-yellow-500"
+        />
+      </div>
+
+      {/* Ready Check */}
+      <label className="flex items-center gap-3 cursor-pointer">
+        <input
+          type="checkbox"
+          checked={settings.readyCheck ?? false}
+          onChange={(e) => onChange({ readyCheck: e.target.checked })}
+          disabled={disabled}
+          className="w-5 h-5 rounded bg-white/20 border-white/30 text-yellow-500 focus:ring-yellow-500 focus:ring-offset-0"
+        />
+        <div>
+          <span className="text-white">Ready Check</span>
+          <p className="text-xs text-gray-400">
+            Everyone confirms they&apos;re here before the countdown starts
+          </p>
+        </div>
+      </label>
+
+      {/* Teams */}
+      <label className="flex items-center gap-3 cursor-pointer">
+        <input
+          type="checkbox"
+          checked={settings.teams ?? false}
+          onChange={(e) => onChange({ teams: e.target.checked })}
+          disabled={disabled}
+          className="w-5 h-5 rounded bg-white/20 border-white/30 text-yellow-500 focus:ring-yellow-500 focus:ring-offset-0"
+        />
+        <div>
+          <span className="text-white">Teams</span>
+          <p className="text-xs text-gray-400">
+            Two teams take turns in alternation; a team is out once all its players are
+          </p>
+        </div>
+      </label>
+
+      {/* Vote Kick Host */}
+      <label className="flex items-center gap-3 cursor-pointer">
+        <input
+          type="checkbox"
+          checked={settings.voteKickHost ?? false}
+          onChange={(e) => onChange({ voteKickHost: e.target.checked })}
+          disabled={disabled}
+          className="w-5 h-5 rounded bg-white/20 border-white/30 text-yellow-500 focus:ring-yellow-500 focus:ring-offset-0"
+        />
+        <div>
+          <span className="text-white">Host Can Be Voted Out</span>
+          <p className="text-xs text-gray-400">
+            Players may vote to kick the host, not just each other
+          </p>
+        </div>
+      </label>
+
+      {/* Disconnected Turns */}
+      <div>
+        <label className="block text-sm text-gray-300 mb-2">Disconnected Players</label>
+        <select
+          value={settings.disconnectedTurns ?? 'skip'}
+          onChange={(e) => onChange({ disconnectedTurns: e.target.value as DisconnectedTurns })}
+          disabled={disabled}
+          className="w-full bg-white/10 border border-white/20 rounded-lg px-3 py-2 text-white"
+        >
+          <option value="skip">Skip their turns until they're back</option>
+          <option value="autoplay">Play their cards for them</option>
+        </select>
+      </div>
+
+      {/* Leaver Cards */}
+      <div>
+        <label className="block text-sm text-gray-300 mb-2">Cards of Players Who Leave</label>
+        <select
+          value={settings.leaverCards ?? 'deal'}
+          onChange={(e) => onChange({ leaverCards: e.target.value as LeaverCards })}
+          disabled={disabled}
+          className="w-full bg-white/10 border border-white/20 rounded-lg px-3 py-2 text-white"
+        >
+          <option value="deal">Deal them out to the other players</option>
+          <option value="pile">Put them under the pile</option>
+          <option value="discard">Take them out of play</option>
+        </select>
+      </div>
+
+      {/* Visibility */}
+      <div>
+        <label className="block text-sm text-gray-300 mb-2">Visibility</label>
+        <select
+          value={settings.visibility ?? 'public'}
+          onChange={(e) => onChange({ visibility: e.target.value as Visibility })}
+          disabled={disabled}
+          className="w-full bg-white/10 border border-white/20 rounded-lg px-3 py-2 text-white"
+        >
+          <option value="public">Public: listed in the lobby</option>
+          <option value="unlisted">Unlisted: anyone with the code can join</option>
+          <option value="private">Private: invite links only</option>
+        </select>
+      </div>
+
+      {/* Max Spectators */}
+      <div>
+        <label className="flex justify-between items-center text-sm text-gray-300 mb-2">
+          <span>Max Spectators</span>
+          <span className="text-white font-medium">
+            {settings.maxSpectators ? settings.maxSpectators : 'No limit'}
+          </span>
+        </label>
+        <input
+          type="range"
+          min={0}
+          max={200}
+          step={10}
+          value={settings.maxSpectators ?? 0}
+          onChange={(e) =>
+            onChange({ maxSpectators: parseInt(e.target.value) })
+          }
+          disabled={disabled}
+          className="w-full h-2 bg-white/20 rounded-lg appearance-none cursor-pointer accent-yellow-500"
+        />
+        <div className="flex justify-between text-xs text-gray-500 mt-1">
+          <span>No limit</span>
+          <span>200</span>
+        </div>
+      </div>
+
+      {/* Win Condition */}
+      <div>
+        <label className="block text-sm text-gray-300 mb-2">Win Condition</label>
+        <select
+          value={settings.winCondition ?? 'last_standing'}
+          onChange={(e) => onChange({ winCondition: e.target.value as WinCondition })}
+          disabled={disabled}
+          className="w-full bg-white/10 border border-white/20 rounded-lg px-3 py-2 text-white"
+        >
+          <option value="last_standing">Last player standing</option>
+          <option value="piles">First to win a number of piles</option>
+          <option value="timed">Most cards when time runs out</option>
+        </select>
+        {settings.winCondition === 'piles' && (
+          <div className="mt-3">
+            <label className="flex justify-between items-center text-sm text-gray-300 mb-2">
+              <span>Piles to Win</span>
+              <span className="text-white font-medium">{settings.winPiles ?? 10}</span>
+            </label>
+            <input
+              type="range"
+              min={1}
+              max={50}
+              step={1}
+              value={settings.winPiles ?? 10}
+              onChange={(e) => onChange({ winPiles: parseInt(e.target.value) })}
+              disabled={disabled}
+              className="w-full h-2 bg-white/20 rounded-lg appearance-none cursor-pointer accent-yellow-500"
+            />
+          </div>
+        )}
+        {settings.winCondition === 'timed' && (
+          <div className="mt-3">
+            <label className="flex justify-between items-center text-sm text-gray-300 mb-2">
+              <span>Time Limit</span>
+              <span className="text-white font-medium">{Math.round((settings.timeLimitSeconds ?? 300) / 60)} min</span>
+            </label>
+            <input
+              type="range"
+              min={60}
+              max={1800}
+              step={60}
+              value={settings.timeLimitSeconds ?? 300}
+              onChange={(e) => onChange({ timeLimitSeconds: parseInt(e.target.value) })}
+              disabled={disabled}
+              className="w-full h-2 bg-white/20 rounded-lg appearance-none cursor-pointer accent-yellow-500"
+            />
+          </div>
+        )}
+      </div>
+
+      {/* Deck */}
+      <div>
+        <label className="flex justify-between items-center text-sm text-gray-300 mb-2">
+          <span>Deck</span>
+          <span className="text-white font-medium">
+            {deckRanks.length * 4 + extraJacks} cards, {4 + extraJacks} Jacks
+          </span>
+        </label>
+        <div className="flex flex-wrap gap-1">
+          {RANKS.map((rank) => (
+            <button
+              key={rank}
+              type="button"
+              onClick={() => toggleRank(rank)}
+              disabled={disabled || rank === 'J'}
+              className={`w-9 py-1 rounded text-sm font-medium transition-colors ${
+                deckRanks.includes(rank)
+                  ? 'bg-yellow-500 text-black'
+                  : 'bg-white/10 text-gray-400 hover:bg-white/20'
+              }`}
+            >
+              {rank}
+            </button>
+          ))}
+        </div>
+        <label className="flex justify-between items-center text-sm text-gray-300 mt-3 mb-2">
+          <span>Extra Jacks</span>
+          <span className="text-white font-medium">{extraJacks}</span>
+        </label>
+        <input
+          type="range"
+          min={0}
+          max={8}
+          step={1}
+          value={extraJacks}
+          onChange={(e) => onChange({ deck: { ranks: deckRanks, extraJacks: parseInt(e.target.value) } })}
+          disabled={disabled}
+          className="w-full h-2 bg-white/20 rounded-lg appearance-none cursor-pointer accent-yellow-500"
+        />
+      </div>
+
+      {/* AFK Strikes */}
+      <div>
+        <label className="flex justify-between items-center text-sm text-gray-300 mb-2">
+          <span>Timeouts Before AFK</span>
+          <span className="text-white font-medium">
+            {settings.afkStrikes ? settings.afkStrikes : 'Never'}
+          </span>
+        </label>
+        <input
+          type="range"
+          min={0}
+          max={10}
+          step={1}
+          value={settings.afkStrikes ?? 3}
+          onChange={(e) =>
+            onChange({ afkStrikes: parseInt(e.target.value) })
+          }
+          disabled={disabled}
+          className="w-full h-2 bg-white/20 rounded-lg appearance-none cursor-pointer accent-yellow-500"
+        />
+        <div className="flex justify-between text-xs text-gray-500 mt-1">
+          <span>Never</span>
+          <span>10</span>
+        </div>
+      </div>
+
+      {/* Slow Mode */}
+      <div>
+        <label className="flex justify-between items-center text-sm text-gray-300 mb-2">
+          <span>Reaction Slow Mode</span>
+          <span className="text-white font-medium">
+            {settings.slowModeSeconds ? `${settings.slowModeSeconds}s` : 'Off'}
+          </span>
+        </label>
+        <input
+          type="range"
+          min={0}
+          max={60}
+          step={5}
+          value={settings.slowModeSeconds ?? 0}
+          onChange={(e) =>
+            onChange({ slowModeSeconds: parseInt(e.target.value) })
+          }
+          disabled={disabled}
+          className="w-full h-2 bg-white/20 rounded-lg appearance-none cursor-pointer accent-yellow-500"
+        />
+        <div className="flex justify-between text-xs text-gray-500 mt-1">
+          <span>Off</span>
+          <span>60s</span>
+        </div>
+      </div>
+
       {/* Slap Rules */}
       <div className="space-y-3">
         <label className="text-sm text-gray-300">Slap Rules</label>
@@ -131,6 +520,49 @@ export function RoomSettings({
         </p>
       </div>
 
+      {/* Burn Destination */}
+      <div>
+        <label className="block text-sm text-gray-300 mb-2">Burned Cards Go To</label>
+        <select
+          value={settings.burnDestination ?? 'pile'}
+          onChange={(e) =>
+            onChange({ burnDestination: e.target.value as BurnDestination })
+          }
+          disabled={disabled}
+          className="w-full bg-white/10 border border-white/20 rounded-lg px-3 py-2 text-white"
+        >
+          <option value="pile">Bottom of the pile</option>
+          <option value="winner">Last pile winner</option>
+          <option value="discard">Out of play</option>
+        </select>
+      </div>
+
+      {/* Pile Cap */}
+      <div>
+        <label className="flex justify-between items-center text-sm text-gray-300 mb-2">
+          <span>Pile Cap</span>
+          <span className="text-white font-medium">
+            {settings.pileCap ? `${settings.pileCap} cards` : 'Off'}
+          </span>
+        </label>
+        <input
+          type="range"
+          min={0}
+          max={100}
+          step={5}
+          value={settings.pileCap ?? 0}
+          onChange={(e) => {
+            const cap = parseInt(e.target.value);
+            onChange({ pileCap: cap > 0 && cap < 10 ? 10 : cap });
+          }}
+          disabled={disabled}
+          className="w-full h-2 bg-white/20 rounded-lg appearance-none cursor-pointer accent-yellow-500"
+        />
+        <p className="text-xs text-gray-400 mt-1">
+          A pile nobody wins by this size is discarded on the next play
+        </p>
+      </div>
+
       {/* Slap Back In */}
       <div className="space-y-3">
         <label className="flex items-center gap-3 cursor-pointer">
@@ -172,6 +604,23 @@ export function RoomSettings({
           </div>
         )}
       </div>
+
+      {/* Voice & Video */}
+      <label className="flex items-center gap-3 cursor-pointer">
+        <input
+          type="checkbox"
+          checked={settings.enableRtc ?? true}
+          onChange={(e) => onChange({ enableRtc: e.target.checked })}
+          disabled={disabled}
+          className="w-5 h-5 rounded bg-white/20 border-white/30 text-yellow-500 focus:ring-yellow-500 focus:ring-offset-0"
+        />
+        <div>
+          <span className="text-white">Voice & Video</span>
+          <p className="text-xs text-gray-400">
+            Let players call each other during games
+          </p>
+        </div>
+      </label>
     </div>
   );
 }
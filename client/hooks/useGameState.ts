@@ -1,6 +1,6 @@
 'use client';
 
-import { useCallback, useReducer } from 'react';
+import { useCallback, useReducer, useRef } from 'react';
 import {
   Card,
   GameState,
@@ -14,6 +14,9 @@ import {
   TurnChangedPayload,
   SlapAttemptedPayload,
   SlapResultPayload,
+  CardsBurnedPayload,
+  CardsRedistributedPayload,
+  CardCountsUpdatedPayload,
   GameStartingPayload,
   GameStartedPayload,
   CardsDealtPayload,
@@ -22,9 +25,22 @@ import {
   NameChangedPayload,
   GameOverPayload,
   PlayerEliminatedPayload,
+  PlayerAFKPayload,
   RoomJoinedPayload,
+  ResyncStatePayload,
   RoomCreatedPayload,
   TurnWarningPayload,
+  ReadyCheckPayload,
+  PlayerReadyPayload,
+  TeamsChangedPayload,
+  GameClockPayload,
+  SlapReplayPayload,
+  PileDiscardedPayload,
+  AnnouncePayload,
+  VoteStartedPayload,
+  VoteResultPayload,
+  PartyStartedPayload,
+  PileWonPayload,
 } from '@/types/game';
 
 // State
@@ -33,10 +49,17 @@ interface State {
   game: GameState | null;
   playerId: string | null;
   countdown: number | null;
+  readyCheck: { playerIds: string[]; ready: string[]; timeoutSeconds: number } | null;
   lastSlapAttempt: SlapAttemptedPayload | null;
   lastSlapResult: SlapResultPayload | null;
+  lastBurn: CardsBurnedPayload | null;
+  lastSlapReplay: SlapReplayPayload | null;
+  lastAnnouncement: AnnouncePayload | null;
+  kickVote: VoteStartedPayload | null;
+  party: { winners: Record<number, string>; finale: boolean } | null; // Party room piles won so far
   gameOver: GameOverPayload | null;
   turnWarning: number | null;
+  gameClockMs: number | null; // Time left in a timed game
   eliminatedPlayers: string[];
 }
 
@@ -45,10 +68,17 @@ const initialState: State = {
   game: null,
   playerId: null,
   countdown: null,
+  readyCheck: null,
   lastSlapAttempt: null,
   lastSlapResult: null,
+  lastBurn: null,
+  lastSlapReplay: null,
+  lastAnnouncement: null,
+  kickVote: null,
+  party: null,
   gameOver: null,
   turnWarning: null,
+  gameClockMs: null,
   eliminatedPlayers: [],
 };
 
@@ -61,17 +91,36 @@ type Action =
   | { type: 'NAME_CHANGED'; payload: NameChangedPayload }
   | { type: 'SETTINGS_CHANGED'; payload: RoomSettings }
   | { type: 'GAME_STARTING'; payload: number }
+  | { type: 'START_CANCELLED'; payload: null }
+  | { type: 'READY_CHECK'; payload: ReadyCheckPayload }
+  | { type: 'PLAYER_READY'; payload: PlayerReadyPayload }
+  | { type: 'TEAMS_CHANGED'; payload: TeamsChangedPayload }
+  | { type: 'VOTE_STARTED'; payload: VoteStartedPayload }
+  | { type: 'VOTE_RESULT'; payload: VoteResultPayload }
+  | { type: 'PARTY_STARTED'; payload: PartyStartedPayload }
+  | { type: 'PILE_WON'; payload: PileWonPayload }
+  | { type: 'FINALE_STARTING'; payload: null }
   | { type: 'GAME_STARTED'; payload: GameState }
   | { type: 'CARDS_DEALT'; payload: Record<string, number> }
   | { type: 'CARD_PLAYED'; payload: CardPlayedPayload }
-  | { type: 'TURN_CHANGED'; payload: string }
+  | { type: 'TURN_CHANGED'; payload: TurnChangedPayload }
   | { type: 'TURN_WARNING'; payload: number }
+  | { type: 'GAME_CLOCK'; payload: number }
   | { type: 'SLAP_ATTEMPTED'; payload: SlapAttemptedPayload }
   | { type: 'SLAP_RESULT'; payload: SlapResultPayload }
+  | { type: 'CARDS_BURNED'; payload: CardsBurnedPayload }
+  | { type: 'PILE_DISCARDED'; payload: PileDiscardedPayload }
+  | { type: 'CARD_COUNTS_UPDATED'; payload: CardCountsUpdatedPayload }
   | { type: 'PLAYER_ELIMINATED'; payload: string }
+  | { type: 'PLAYER_AFK'; payload: PlayerAFKPayload }
   | { type: 'GAME_OVER'; payload: GameOverPayload }
   | { type: 'GAME_ENDED'; payload: null }
+  | { type: 'RESYNC'; payload: ResyncStatePayload }
   | { type: 'CLEAR_SLAP'; payload: null }
+  | { type: 'SLAP_REPLAY'; payload: SlapReplayPayload }
+  | { type: 'CLEAR_SLAP_REPLAY'; payload: SlapReplayPayload }
+  | { type: 'ANNOUNCE'; payload: AnnouncePayload }
+  | { type: 'CLEAR_ANNOUNCE'; payload: AnnouncePayload }
   | { type: 'RESET'; payload: null };
 
 function reducer(state: State, action: Action): State {
@@ -126,14 +175,92 @@ function reducer(state: State, action: Action): State {
         },
       };
 
+    case 'READY_CHECK':
+      if (!state.room) return state;
+      return {
+        ...state,
+        readyCheck: { ...action.payload, ready: [] },
+        room: { ...state.room, status: 'starting' },
+      };
+
+    case 'PLAYER_READY':
+      if (!state.readyCheck) return state;
+      return {
+        ...state,
+        readyCheck: {
+          ...state.readyCheck,
+          ready: [...state.readyCheck.ready, action.payload.playerId],
+        },
+      };
+
+    case 'VOTE_STARTED':
+      return { ...state, kickVote: action.payload };
+
+    case 'VOTE_RESULT':
+      if (state.kickVote?.targetId !== action.payload.targetId) return state;
+      return { ...state, kickVote: null };
+
+    case 'PARTY_STARTED':
+      if (!state.room) return state;
+      return {
+        ...state,
+        room: { ...state.room, piles: action.payload.piles },
+        party: { winners: {}, finale: false },
+      };
+
+    case 'PILE_WON':
+      if (!state.party) return state;
+      return {
+        ...state,
+        party: {
+          ...state.party,
+          winners: { ...state.party.winners, [action.payload.pile]: action.payload.winnerName },
+        },
+      };
+
+    case 'FINALE_STARTING':
+      if (!state.room || !state.party) return state;
+      return {
+        ...state,
+        room: { ...state.room, piles: undefined },
+        game: null,
+        party: { ...state.party, finale: true },
+      };
+
+    case 'TEAMS_CHANGED': {
+      if (!state.room) return state;
+      const teams = action.payload.teams;
+      return {
+        ...state,
+        room: {
+          ...state.room,
+          players: state.room.players.map((p) => ({
+            ...p,
+            teamId: teams ? teams[p.id] : undefined,
+          })),
+        },
+      };
+    }
+
     case 'GAME_STARTING':
       if (!state.room) return state;
       return {
         ...state,
         countdown: action.payload,
+        readyCheck: null,
+        party: null,
         room: { ...state.room, status: 'starting' },
       };
 
+    case 'START_CANCELLED':
+      if (!state.room) return state;
+      return {
+        ...state,
+        countdown: null,
+        readyCheck: null,
+        room: { ...state.room, status: 'waiting' },
+      };
+
     case 'GAME_STARTED':
       if (!state.room) return state;
       return {
@@ -143,6 +270,7 @@ function reducer(state: State, action: Action): State {
         room: { ...state.room, status: 'playing' },
         eliminatedPlayers: [],
         gameOver: null,
+        gameClockMs: null,
       };
 
     case 'CARDS_DEALT':
@@ -188,7 +316,9 @@ function reducer(state: State, action: Action): State {
         ...state,
         game: {
           ...state.game,
-          currentPlayerId: action.payload,
+          currentPlayerId: action.payload.currentPlayerId,
+          turnToken: action.payload.turnToken,
+          turnTimeoutMs: action.payload.turnTimeoutMs,
         },
         turnWarning: null,
       };
@@ -196,6 +326,9 @@ function reducer(state: State, action: Action): State {
     case 'TURN_WARNING':
       return { ...state, turnWarning: action.payload };
 
+    case 'GAME_CLOCK':
+      return { ...state, gameClockMs: action.payload };
+
     case 'SLAP_ATTEMPTED':
       return { ...state, lastSlapAttempt: action.payload };
 
@@ -241,6 +374,67 @@ function reducer(state: State, action: Action): State {
         lastSlapAttempt: null,
       };
 
+    case 'CARDS_BURNED': {
+      // The slapper's count already dropped with SLAP_RESULT; a pile winner
+      // given the cards gains them
+      const { recipientId, cards } = action.payload;
+      if (!recipientId || !state.game || !state.room) {
+        return { ...state, lastBurn: action.payload };
+      }
+      return {
+        ...state,
+        lastBurn: action.payload,
+        game: {
+          ...state.game,
+          playerCardCounts: {
+            ...state.game.playerCardCounts,
+            [recipientId]: (state.game.playerCardCounts[recipientId] || 0) + cards.length,
+          },
+        },
+        room: {
+          ...state.room,
+          players: state.room.players.map((p) =>
+            p.id === recipientId ? { ...p, cardCount: p.cardCount + cards.length } : p
+          ),
+        },
+      };
+    }
+
+    case 'CARD_COUNTS_UPDATED': {
+      // The server's counts replace whatever was worked out from earlier messages
+      const counts = action.payload.playerCardCounts;
+      if (!state.game || !state.room) return state;
+      return {
+        ...state,
+        game: { ...state.game, playerCardCounts: counts },
+        room: {
+          ...state.room,
+          players: state.room.players.map((p) =>
+            p.id in counts ? { ...p, cardCount: counts[p.id] } : p
+          ),
+        },
+      };
+    }
+
+    case 'PLAYER_AFK': {
+      // The AFK player's hand went under the pile
+      const { playerId } = action.payload;
+      if (!state.game || !state.room) return state;
+      return {
+        ...state,
+        game: {
+          ...state.game,
+          playerCardCounts: { ...state.game.playerCardCounts, [playerId]: 0 },
+        },
+        room: {
+          ...state.room,
+          players: state.room.players.map((p) =>
+            p.id === playerId ? { ...p, cardCount: 0 } : p
+          ),
+        },
+      };
+    }
+
     case 'PLAYER_ELIMINATED':
       return {
         ...state,
@@ -266,8 +460,35 @@ function reducer(state: State, action: Action): State {
         eliminatedPlayers: [],
       };
 
+    case 'PILE_DISCARDED':
+      // The pile's cards are out of play; the next CARD_PLAYED starts a new one
+      if (!state.game) return state;
+      return { ...state, game: { ...state.game, pile: [] } };
+
     case 'CLEAR_SLAP':
-      return { ...state, lastSlapAttempt: null, lastSlapResult: null };
+      return { ...state, lastSlapAttempt: null, lastSlapResult: null, lastBurn: null };
+
+    case 'SLAP_REPLAY':
+      return { ...state, lastSlapReplay: action.payload };
+
+    case 'CLEAR_SLAP_REPLAY':
+      if (state.lastSlapReplay !== action.payload) return state;
+      return { ...state, lastSlapReplay: null };
+
+    case 'ANNOUNCE':
+      return { ...state, lastAnnouncement: action.payload };
+
+    case 'CLEAR_ANNOUNCE':
+      if (state.lastAnnouncement !== action.payload) return state;
+      return { ...state, lastAnnouncement: null };
+
+    case 'RESYNC':
+      return {
+        ...state,
+        room: action.payload.room,
+        game: action.payload.gameState ?? null,
+        countdown: null,
+      };
 
     case 'RESET':
       return initialState;
@@ -280,7 +501,18 @@ function reducer(state: State, action: Action): State {
 export function useGameState() {
   const [state, dispatch] = useReducer(reducer, initialState);
 
+  // The party pile we play on, 0 outside the piles; kept in refs as the
+  // pile's messages can arrive before a dispatch has rendered
+  const playerIdRef = useRef(state.playerId);
+  playerIdRef.current = state.playerId;
+  const myPileRef = useRef(0);
+
   const handleMessage = useCallback((message: WSMessage) => {
+    // Only our own pile's game messages apply while a party room plays its piles
+    if (message.pile && myPileRef.current && message.pile !== myPileRef.current) {
+      return;
+    }
+
     switch (message.type) {
       case ServerMessageTypes.ROOM_CREATED: {
         const payload = message.payload as RoomCreatedPayload;
@@ -296,6 +528,33 @@ export function useGameState() {
         break;
       }
 
+      case ServerMessageTypes.RESYNC_STATE: {
+        const payload = message.payload as ResyncStatePayload;
+        myPileRef.current = payload.pile ?? 0;
+        dispatch({ type: 'RESYNC', payload });
+        break;
+      }
+
+      case ServerMessageTypes.PARTY_STARTED: {
+        const payload = message.payload as PartyStartedPayload;
+        const index = payload.piles.findIndex(ids => ids.includes(playerIdRef.current ?? ''));
+        myPileRef.current = index >= 0 ? index + 1 : 1; // Spectators watch the first pile
+        dispatch({ type: 'PARTY_STARTED', payload });
+        break;
+      }
+
+      case ServerMessageTypes.PILE_WON: {
+        const payload = message.payload as PileWonPayload;
+        dispatch({ type: 'PILE_WON', payload });
+        break;
+      }
+
+      case ServerMessageTypes.FINALE_STARTING: {
+        myPileRef.current = 0;
+        dispatch({ type: 'FINALE_STARTING', payload: null });
+        break;
+      }
+
       case ServerMessageTypes.PLAYER_JOINED: {
         const payload = message.payload as PlayerJoinedPayload;
         dispatch({ type: 'PLAYER_JOINED', payload: payload.player });
@@ -326,6 +585,41 @@ export function useGameState() {
         break;
       }
 
+      case ServerMessageTypes.READY_CHECK: {
+        const payload = message.payload as ReadyCheckPayload;
+        dispatch({ type: 'READY_CHECK', payload });
+        break;
+      }
+
+      case ServerMessageTypes.PLAYER_READY: {
+        const payload = message.payload as PlayerReadyPayload;
+        dispatch({ type: 'PLAYER_READY', payload });
+        break;
+      }
+
+      case ServerMessageTypes.VOTE_STARTED: {
+        const payload = message.payload as VoteStartedPayload;
+        dispatch({ type: 'VOTE_STARTED', payload });
+        break;
+      }
+
+      case ServerMessageTypes.VOTE_RESULT: {
+        const payload = message.payload as VoteResultPayload;
+        dispatch({ type: 'VOTE_RESULT', payload });
+        break;
+      }
+
+      case ServerMessageTypes.TEAMS_CHANGED: {
+        const payload = message.payload as TeamsChangedPayload;
+        dispatch({ type: 'TEAMS_CHANGED', payload });
+        break;
+      }
+
+      case ServerMessageTypes.START_CANCELLED: {
+        dispatch({ type: 'START_CANCELLED', payload: null });
+        break;
+      }
+
       case ServerMessageTypes.GAME_STARTED: {
         const payload = message.payload as GameStartedPayload;
         dispatch({ type: 'GAME_STARTED', payload: payload.gameState });
@@ -346,7 +640,7 @@ export function useGameState() {
 
       case ServerMessageTypes.TURN_CHANGED: {
         const payload = message.payload as TurnChangedPayload;
-        dispatch({ type: 'TURN_CHANGED', payload: payload.currentPlayerId });
+        dispatch({ type: 'TURN_CHANGED', payload });
         break;
       }
 
@@ -356,6 +650,12 @@ export function useGameState() {
         break;
       }
 
+      case ServerMessageTypes.GAME_CLOCK: {
+        const payload = message.payload as GameClockPayload;
+        dispatch({ type: 'GAME_CLOCK', payload: payload.remainingMs });
+        break;
+      }
+
       case ServerMessageTypes.SLAP_ATTEMPTED: {
         const payload = message.payload as SlapAttemptedPayload;
         dispatch({ type: 'SLAP_ATTEMPTED', payload });
@@ -372,6 +672,59 @@ export function useGameState() {
         break;
       }
 
+      case ServerMessageTypes.CARDS_BURNED: {
+        const payload = message.payload as CardsBurnedPayload;
+        dispatch({ type: 'CARDS_BURNED', payload });
+        break;
+      }
+
+      case ServerMessageTypes.ANNOUNCE: {
+        const payload = message.payload as AnnouncePayload;
+        dispatch({ type: 'ANNOUNCE', payload });
+        // Clear once the banner has shown, unless another replaced it
+        setTimeout(() => {
+          dispatch({ type: 'CLEAR_ANNOUNCE', payload });
+        }, 2500);
+        break;
+      }
+
+      case ServerMessageTypes.PILE_DISCARDED: {
+        const payload = message.payload as PileDiscardedPayload;
+        dispatch({ type: 'PILE_DISCARDED', payload });
+        break;
+      }
+
+      case ServerMessageTypes.SLAP_REPLAY: {
+        const payload = message.payload as SlapReplayPayload;
+        dispatch({ type: 'SLAP_REPLAY', payload });
+        // Clear once the replay has played, unless another replaced it
+        setTimeout(() => {
+          dispatch({ type: 'CLEAR_SLAP_REPLAY', payload });
+        }, 4000);
+        break;
+      }
+
+      case ServerMessageTypes.CARD_COUNTS_UPDATED: {
+        const payload = message.payload as CardCountsUpdatedPayload;
+        dispatch({ type: 'CARD_COUNTS_UPDATED', payload });
+        break;
+      }
+
+      case ServerMessageTypes.CARDS_REDISTRIBUTED: {
+        const payload = message.payload as CardsRedistributedPayload;
+        dispatch({
+          type: 'CARD_COUNTS_UPDATED',
+          payload: { playerCardCounts: payload.playerCards, pileCount: payload.pileCount },
+        });
+        break;
+      }
+
+      case ServerMessageTypes.PLAYER_AFK: {
+        const payload = message.payload as PlayerAFKPayload;
+        dispatch({ type: 'PLAYER_AFK', payload });
+        break;
+      }
+
       case ServerMessageTypes.PLAYER_ELIMINATED: {
         const payload = message.payload as PlayerEliminatedPayload;
         dispatch({ type: 'PLAYER_ELIMINATED', payload: payload.playerId });
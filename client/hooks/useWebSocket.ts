@@ -1,7 +1,7 @@
 'use client';
 
 import { useCallback, useEffect, useRef, useState } from 'react';
-import { WSMessage } from '@/types/game';
+import { InputAckPayload, ResumedPayload, ResyncStatePayload, WSMessage } from '@/types/game';
 
 interface UseWebSocketOptions {
   onMessage?: (message: WSMessage) => void;
@@ -22,6 +22,12 @@ interface UseWebSocketReturn {
 
 const WS_URL = process.env.NEXT_PUBLIC_WS_URL || 'ws://localhost:8080/ws';
 
+// Actions numbered so the server applies a retried one only once
+const NUMBERED_INPUTS = new Set(['PLAY_CARD', 'SLAP']);
+
+// How often to acknowledge the room broadcasts received, so a reconnect replays only what was missed
+const ACK_INTERVAL = 2000;
+
 export function useWebSocket(options: UseWebSocketOptions = {}): UseWebSocketReturn {
   const {
     onMessage,
@@ -39,6 +45,11 @@ export function useWebSocket(options: UseWebSocketOptions = {}): UseWebSocketRet
   const reconnectTimeoutRef = useRef<NodeJS.Timeout | null>(null);
   const isConnectingRef = useRef(false);
   const isUnmountedRef = useRef(false);
+  const lastSeqRef = useRef(0);
+  const ackedSeqRef = useRef(0);
+  const inputSeqRef = useRef(0);
+  // Numbered actions sent but not yet acknowledged, resent with their number after a reconnect
+  const pendingInputsRef = useRef<WSMessage[]>([]);
 
   // Store callbacks in refs to avoid re-creating connect function
   const callbacksRef = useRef({ onMessage, onConnect, onDisconnect, onError });
@@ -47,6 +58,16 @@ export function useWebSocket(options: UseWebSocketOptions = {}): UseWebSocketRet
   const connect = useCallback(() => {
     console.log('[WS] connect() called');
 
+    // After a reconnect, resend the actions the server hadn't applied, keeping
+    // their numbers so any it did apply after all are dropped rather than repeated
+    const resendPendingInputs = (ws: WebSocket, lastInputSeq = 0) => {
+      inputSeqRef.current = Math.max(inputSeqRef.current, lastInputSeq);
+      pendingInputsRef.current = pendingInputsRef.current.filter((m) => (m.inputSeq ?? 0) > lastInputSeq);
+      for (const message of pendingInputsRef.current) {
+        ws.send(JSON.stringify(message));
+      }
+    };
+
     // Prevent multiple simultaneous connections
     if (isConnectingRef.current || wsRef.current?.readyState === WebSocket.OPEN) {
       console.log('[WS] Already connecting or connected, skipping');
@@ -73,9 +94,24 @@ export function useWebSocket(options: UseWebSocketOptions = {}): UseWebSocketRet
       ? sessionStorage.getItem('slapjack_session_id')
       : null;
 
-    const url = storedSessionId
-      ? `${WS_URL}?sessionId=${storedSessionId}`
-      : WS_URL;
+    // The server only honours the session ID together with the token it issued
+    const storedSessionToken = typeof window !== 'undefined'
+      ? sessionStorage.getItem('slapjack_session_token')
+      : null;
+
+    // Durable player token survives tabs and restarts (localStorage)
+    const storedPlayerToken = typeof window !== 'undefined'
+      ? localStorage.getItem('slapjack_player_token')
+      : null;
+
+    const params = new URLSearchParams();
+    if (storedSessionId) params.set('sessionId', storedSessionId);
+    if (storedSessionToken) params.set('sessionToken', storedSessionToken);
+    if (storedPlayerToken) params.set('playerToken', storedPlayerToken);
+    // Server messages come back in the browser's language where the server has it
+    if (typeof navigator !== 'undefined' && navigator.language) params.set('lang', navigator.language);
+    const query = params.toString();
+    const url = query ? `${WS_URL}?${query}` : WS_URL;
 
     console.log('[WS] Connecting to:', url);
 
@@ -136,12 +172,42 @@ export function useWebSocket(options: UseWebSocketOptions = {}): UseWebSocketRet
           try {
             const message: WSMessage = JSON.parse(msgStr);
 
+            // Room broadcasts are numbered; a gap means we missed some, so ask for the full state
+            if (message.type === 'ROOM_CREATED' || message.type === 'ROOM_JOINED') {
+              lastSeqRef.current = 0;
+            } else if (message.type === 'RESUMED') {
+              // The broadcasts missed while disconnected follow; keep our place so ones
+              // already seen are skipped, unless the room's numbering no longer matches
+              const { fromSeq, toSeq, lastInputSeq } = message.payload as ResumedPayload;
+              if (lastSeqRef.current < fromSeq || lastSeqRef.current > toSeq) {
+                lastSeqRef.current = fromSeq;
+              }
+              resendPendingInputs(ws, lastInputSeq);
+            } else if (message.type === 'RESYNC_STATE') {
+              const { seq, lastInputSeq } = message.payload as ResyncStatePayload;
+              lastSeqRef.current = seq;
+              resendPendingInputs(ws, lastInputSeq);
+            } else if (message.type === 'INPUT_ACK') {
+              const { inputSeq } = message.payload as InputAckPayload;
+              pendingInputsRef.current = pendingInputsRef.current.filter((m) => (m.inputSeq ?? 0) > inputSeq);
+            } else if (message.seq) {
+              if (message.seq <= lastSeqRef.current) {
+                continue; // Already seen before a reconnect
+              }
+              if (lastSeqRef.current > 0 && message.seq > lastSeqRef.current + 1) {
+                ws.send(JSON.stringify({ type: 'RESYNC', payload: {}, timestamp: Date.now() }));
+              }
+              lastSeqRef.current = Math.max(lastSeqRef.current, message.seq);
+            }
+
             // Handle CONNECTED message to store session ID
             if (message.type === 'CONNECTED') {
-              const payload = message.payload as { sessionId: string };
+              const payload = message.payload as { sessionId: string; sessionToken: string; playerToken: string };
               setSessionId(payload.sessionId);
               if (typeof window !== 'undefined') {
                 sessionStorage.setItem('slapjack_session_id', payload.sessionId);
+                sessionStorage.setItem('slapjack_session_token', payload.sessionToken);
+                localStorage.setItem('slapjack_player_token', payload.playerToken);
               }
             }
 
@@ -183,6 +249,10 @@ export function useWebSocket(options: UseWebSocketOptions = {}): UseWebSocketRet
         payload: payload || {},
         timestamp: Date.now(),
       };
+      if (NUMBERED_INPUTS.has(type)) {
+        message.inputSeq = ++inputSeqRef.current;
+        pendingInputsRef.current.push(message);
+      }
       wsRef.current.send(JSON.stringify(message));
     } else {
       console.warn('WebSocket not connected, message not sent:', type);
@@ -215,6 +285,19 @@ export function useWebSocket(options: UseWebSocketOptions = {}): UseWebSocketRet
     // eslint-disable-next-line react-hooks/exhaustive-deps
   }, []); // Empty deps - only run on mount/unmount
 
+  // Acknowledge new room broadcasts now and then
+  useEffect(() => {
+    const interval = setInterval(() => {
+      const seq = lastSeqRef.current;
+      if (seq === ackedSeqRef.current || wsRef.current?.readyState !== WebSocket.OPEN) {
+        return;
+      }
+      wsRef.current.send(JSON.stringify({ type: 'ACK', payload: { seq }, timestamp: Date.now() }));
+      ackedSeqRef.current = seq;
+    }, ACK_INTERVAL);
+    return () => clearInterval(interval);
+  }, []);
+
   return {
     isConnected,
     sessionId,
@@ -3,6 +3,9 @@ export interface WSMessage {
   type: string;
   payload: unknown;
   timestamp: number;
+  seq?: number; // Per-room sequence number on room broadcasts
+  inputSeq?: number; // Action number kept across reconnects, so the server applies a resent action once
+  pile?: number; // The party room pile a game message is about
 }
 
 // Card types
@@ -22,18 +25,114 @@ export interface Player {
   isHost: boolean;
   isConnected: boolean;
   position: number;
+  avatar?: Avatar;
+  profileId?: string; // Same across rooms for players joining with a profile
+  loadout?: Record<string, string>; // Equipped cosmetic item IDs by slot
+  teamId?: number; // 1 or 2 when the room plays in teams
 }
 
+// Emoji shown on a colored background
+export interface Avatar {
+  emoji: string;
+  color: string; // #rrggbb
+}
+
+// Player identity kept across rooms and visits
+export interface Profile {
+  id: string;
+  name: string;
+  avatar: Avatar;
+}
+
+// Where cards burned for a false slap go
+export type BurnDestination = 'pile' | 'discard' | 'winner';
+
 // Room settings
 export interface RoomSettings {
   maxPlayers: number;
   slapCooldownMs: number;
   turnTimeoutMs: number;
+  afkStrikes?: number; // Timeouts in a row before a player is out; 0 for never
   enableSandwich: boolean;
   enableDoubles: boolean;
   burnPenalty: number;
+  burnDestination?: BurnDestination;
   enableSlapIn: boolean;
   maxSlapIns: number;
+  enableRtc?: boolean; // Relay voice and video signaling between players
+  slowModeSeconds?: number; // Seconds between reactions per player; 0 for off
+  adaptivePacing?: boolean; // Shorten turn timeouts as the pile grows
+  pacingMinTimeoutMs?: number;
+  pacingRampCards?: number;
+  pacingClaimBonusMs?: number;
+  autoPace?: boolean; // Shorten turn timeouts as the game goes on
+  autoPaceStepTurns?: number;
+  autoPaceFloorMs?: number;
+  countdownSeconds?: number; // Countdown before a game starts
+  readyCheck?: boolean; // Players confirm they're ready when the host starts
+  readyCheckSeconds?: number;
+  teams?: boolean; // Two teams that alternate turns and are out together
+  voteKickHost?: boolean; // Players may vote the host out, not just each other
+  disconnectedTurns?: DisconnectedTurns;
+  leaverCards?: LeaverCards;
+  visibility?: Visibility;
+  maxSpectators?: number; // Most spectators at once; 0 for no limit
+  winCondition?: WinCondition;
+  winPiles?: number; // Piles to win under the piles condition
+  timeLimitSeconds?: number; // Game clock under the timed condition
+  deck?: DeckSpec; // Custom deck composition
+  pileCap?: number; // Unwon pile size at which the next play discards it; 0 for off
+  preset?: SettingsPreset; // The preset the settings match; send one to apply it
+}
+
+// Ranks, ace low
+export const RANKS = ['A', '2', '3', '4', '5', '6', '7', '8', '9', '10', 'J', 'Q', 'K'];
+
+// A custom deck: the ranks in each suit, always including the Jack, and Jacks added on top
+export interface DeckSpec {
+  ranks: string[]; // Empty for every rank
+  extraJacks: number;
+}
+
+export type SettingsPreset = 'classic' | 'speed' | 'party';
+
+// Who sees the room in the lobby and who can join it by code
+export type Visibility = 'public' | 'unlisted' | 'private';
+
+// Whether a disconnected player's turns are passed over or played for them; they keep their cards either way
+export type DisconnectedTurns = 'skip' | 'autoplay';
+
+// Where the hand of a player who leaves mid-game goes
+export type LeaverCards = 'deal' | 'pile' | 'discard';
+
+// How a game is won; the last player standing also wins the other two outright
+export type WinCondition = 'last_standing' | 'piles' | 'timed';
+
+// Leaderboards served by /api/leaderboard?metric=
+export type LeaderboardMetric = 'wins' | 'win_rate' | 'fastest_slap';
+
+export interface LeaderboardEntry {
+  rank: number;
+  name: string;
+  score: number; // Wins, win rate from 0 to 1, or average reaction in ms, by metric
+  played: number;
+  wins: number;
+  averageMs?: number;
+}
+
+export interface LeaderboardResponse {
+  metric: LeaderboardMetric;
+  entries: LeaderboardEntry[];
+}
+
+// Rule variant offered by /api/variants, described in the requested language
+export interface RuleVariant {
+  id: string;
+  name: string;
+  description: string;
+  minPlayers: number;
+  maxPlayers: number;
+  settings: RoomSettings;
 }
 
 // Room state
@@ -43,14 +142,33 @@ export interface RoomState {
   settings: RoomSettings;
   status: 'waiting' | 'starting' | 'playing' | 'finished';
   hostId: string;
+  dropIn?: string; // Rule preset of a server-run drop-in room, which has no host
+  variantId?: string; // Rule variant the room was created with
+  playerLatency?: Record<string, number>; // Round-trip ms by player ID
+  piles?: string[][]; // Player IDs on each pile while a party room plays its piles
+  integrations?: string[]; // Integrations the room posts to, e.g. 'discord'
+  scheduledAt?: number; // When a scheduled game starts by itself, Unix milliseconds
 }
 
 // Game state
 export interface GameState {
   pile: Card[];
   currentPlayerId: string;
+  turnToken?: number; // Echoed back with PLAY_CARD
+  turnTimeoutMs?: number; // Timeout for the current turn, from TURN_CHANGED
   playerCardCounts: Record<string, number>;
   canSlap: boolean;
+  fullPile?: Card[]; // Spectators, or rooms showing the full pile
+  pileHistory?: PilePlay[];
+}
+
+// One card played onto the pile, in the observer view's recent history
+export interface PilePlay {
+  playerId: string;
+  card: Card;
+  pileSize: number;
+  timeout?: boolean;
+  timestamp: number;
 }
 
 // Game statistics
@@ -58,9 +176,25 @@ export interface GameStats {
   totalSlaps: number;
   successfulSlaps: Record<string, number>;
   cardsBurned: Record<string, number>;
+  players: Record<string, PlayerStats>;
   duration: number;
 }
 
+// One player's record for a finished game
+export interface PlayerStats {
+  slaps: number;
+  successes: number;
+  missedSlaps: number; // Slaps that didn't win a pile, burned for or not
+  falseSlaps: number; // Missed slaps that were penalized
+  cardsBurned: number;
+  slapIns: number;
+  fastestMs?: number;
+  averageMs?: number; // Over every successful slap
+  reactionsMs: number[]; // The latest successful slaps'
+  longestPile: number; // Most cards won in one pile
+  biggestBurn: number; // Most cards burned by one false slap
+}
+
 // Game action
 export interface GameAction {
   type: 'card_played' | 'slap_success' | 'slap_fail';
@@ -77,11 +211,27 @@ export const MessageTypes = {
   UPDATE_SETTINGS: 'UPDATE_SETTINGS',
   CHANGE_NAME: 'CHANGE_NAME',
   START_GAME: 'START_GAME',
+  CANCEL_START: 'CANCEL_START',
   PLAY_CARD: 'PLAY_CARD',
   SLAP: 'SLAP',
   REACT: 'REACT',
   KICK_PLAYER: 'KICK_PLAYER',
   END_GAME: 'END_GAME',
+  RESYNC: 'RESYNC',
+  REGISTER_PROFILE: 'REGISTER_PROFILE',
+  PLAY_NOW: 'PLAY_NOW',
+  GET_WALLET: 'GET_WALLET',
+  BUY_ITEM: 'BUY_ITEM',
+  EQUIP_ITEM: 'EQUIP_ITEM',
+  REPORT_TELEMETRY: 'REPORT_TELEMETRY',
+  RTC_SIGNAL: 'RTC_SIGNAL',
+  CALIBRATE_START: 'CALIBRATE_START',
+  CALIBRATE_TAP: 'CALIBRATE_TAP',
+  READY: 'READY',
+  SET_TEAM: 'SET_TEAM',
+  VOTE_KICK: 'VOTE_KICK',
+  ACK: 'ACK',
+  SET_INTEGRATION: 'SET_INTEGRATION',
 } as const;
 
 // Message Types - Server to Client
@@ -98,6 +248,7 @@ export const ServerMessageTypes = {
   NAME_CHANGED: 'NAME_CHANGED',
   SETTINGS_CHANGED: 'SETTINGS_CHANGED',
   GAME_STARTING: 'GAME_STARTING',
+  START_CANCELLED: 'START_CANCELLED',
   GAME_STARTED: 'GAME_STARTED',
   CARDS_DEALT: 'CARDS_DEALT',
   CARD_PLAYED: 'CARD_PLAYED',
@@ -106,8 +257,33 @@ export const ServerMessageTypes = {
   SLAP_ATTEMPTED: 'SLAP_ATTEMPTED',
   SLAP_RESULT: 'SLAP_RESULT',
   PLAYER_ELIMINATED: 'PLAYER_ELIMINATED',
+  PLAYER_AFK: 'PLAYER_AFK',
+  RTC_SIGNAL: 'RTC_SIGNAL',
   GAME_OVER: 'GAME_OVER',
   GAME_ENDED: 'GAME_ENDED',
+  RESYNC_STATE: 'RESYNC_STATE',
+  PROFILE_REGISTERED: 'PROFILE_REGISTERED',
+  WALLET_UPDATED: 'WALLET_UPDATED',
+  CARDS_BURNED: 'CARDS_BURNED',
+  CALIBRATE_FLASH: 'CALIBRATE_FLASH',
+  CALIBRATE_RESULT: 'CALIBRATE_RESULT',
+  CARD_COUNTS_UPDATED: 'CARD_COUNTS_UPDATED',
+  READY_CHECK: 'READY_CHECK',
+  PLAYER_READY: 'PLAYER_READY',
+  TEAMS_CHANGED: 'TEAMS_CHANGED',
+  GAME_CLOCK: 'GAME_CLOCK',
+  SLAP_REPLAY: 'SLAP_REPLAY',
+  PILE_DISCARDED: 'PILE_DISCARDED',
+  CARDS_REDISTRIBUTED: 'CARDS_REDISTRIBUTED',
+  ANNOUNCE: 'ANNOUNCE',
+  VOTE_STARTED: 'VOTE_STARTED',
+  VOTE_RESULT: 'VOTE_RESULT',
+  RESUMED: 'RESUMED',
+  INPUT_ACK: 'INPUT_ACK',
+  PARTY_STARTED: 'PARTY_STARTED',
+  PILE_WON: 'PILE_WON',
+  FINALE_STARTING: 'FINALE_STARTING',
+  SCHEDULE_REMINDER: 'SCHEDULE_REMINDER',
   ERROR: 'ERROR',
 } as const;
 
@@ -116,6 +292,26 @@ export interface ConnectedPayload {
   sessionId: string;
 }
 
+export interface ProfileRegisteredPayload {
+  profile: Profile;
+  profileToken: string; // Send as profileToken in CREATE_ROOM and JOIN_ROOM
+}
+
+export interface CosmeticItem {
+  id: string;
+  slot: 'card_back' | 'table' | 'avatar_frame';
+  name: string;
+  price: number;
+}
+
+export interface WalletUpdatedPayload {
+  coins: number;
+  owned: string[];
+  equipped: Record<string, string>; // Item ID by slot
+  earned?: { reason: string; coins: number }[]; // Paid out for a finished game
+  catalog?: CosmeticItem[]; // Only in reply to GET_WALLET
+}
+
 export interface RoomCreatedPayload {
   roomCode: string;
   room: RoomState;
@@ -125,6 +321,26 @@ export interface RoomJoinedPayload {
   room: RoomState;
 }
 
+export interface ResyncStatePayload {
+  seq: number;
+  room: RoomState;
+  gameId?: string;
+  gameState?: GameState;
+  pile?: number; // The party pile gameState is for
+  lastInputSeq?: number; // Last numbered action the server applied
+}
+
+export interface ResumedPayload {
+  fromSeq: number;
+  toSeq: number;
+  count: number;
+  lastInputSeq?: number;
+}
+
+export interface InputAckPayload {
+  inputSeq: number;
+}
+
 export interface NameChangedPayload {
   playerId: string;
   newName: string;
@@ -142,6 +358,16 @@ export interface GameStartingPayload {
   countdown: number;
 }
 
+export interface StartCancelledPayload {
+  reason: 'host_cancelled' | 'not_enough_players' | 'stuck' | 'not_ready' | 'schedule_missed';
+}
+
+// Counts down to a scheduled game, before its GAME_STARTING countdown
+export interface ScheduleReminderPayload {
+  startsAt: number; // Unix milliseconds
+  secondsLeft: number;
+}
+
 export interface GameStartedPayload {
   gameState: GameState;
 }
@@ -158,6 +384,8 @@ export interface CardPlayedPayload {
 
 export interface TurnChangedPayload {
   currentPlayerId: string;
+  turnToken: number;
+  turnTimeoutMs?: number; // Effective timeout for this turn under adaptive pacing
 }
 
 export interface TurnWarningPayload {
@@ -172,24 +400,177 @@ export interface SlapAttemptedPayload {
 export interface SlapResultPayload {
   playerId: string;
   success: boolean;
-  reason: 'jack' | 'doubles' | 'sandwich' | 'invalid' | 'cooldown';
+  reason: 'jack' | 'doubles' | 'sandwich' | 'invalid' | 'cooldown' | 'beaten'; // beaten: a faster slap in the slap window won
   cardsWon?: number;
   burnPenalty?: number;
+  contenders?: SlapContender[]; // Winner first, then later slaps in arrival order
+}
+
+export interface SlapContender {
+  playerId: string;
+  deltaMs: number; // Behind the winning slap
+}
+
+// Follows a winning slap's SLAP_RESULT for a slow-motion replay: the last
+// plays onto the pile and every slap on its top card up to the winner's
+export interface SlapReplayPayload {
+  winnerId: string;
+  reason: string;
+  plays: PilePlay[]; // Oldest first, the top card last
+  slaps: ReplaySlap[]; // In arrival order
+}
+
+export interface ReplaySlap {
+  playerId: string;
+  timestamp: number;
+  reactionMs: number; // After the top card landed, less latency and input lag
+  won?: boolean;
+}
+
+export interface CardsBurnedPayload {
+  playerId: string;
+  cards: Card[];
+  destination: BurnDestination;
+  recipientId?: string; // The pile winner given the cards, for 'winner'
+  pileCount: number;
+}
+
+// An unwon pile at the pile cap, removed from play just before the player's
+// CARD_PLAYED starts a new one
+export interface PileDiscardedPayload {
+  playerId: string;
+  count: number;
+}
+
+// Announcer calls, worked out by the server so every client calls the same moments
+export type AnnounceKind = 'BIG_PILE' | 'COMEBACK' | 'LAST_TWO_PLAYERS' | 'FIRST_BLOOD';
+
+export interface AnnouncePayload {
+  kind: AnnounceKind;
+  playerId?: string;
+  count?: number; // Pile size, for BIG_PILE, COMEBACK and FIRST_BLOOD
+}
+
+// A kick vote opening; it passes once `needed` of the voters say yes
+export interface VoteStartedPayload {
+  targetId: string;
+  targetName: string;
+  startedBy: string;
+  voters: string[];
+  needed: number;
+  timeoutSeconds: number;
+}
+
+export type VoteResult = 'passed' | 'failed' | 'expired' | 'cancelled';
+
+// A kick vote's outcome; a passed vote is followed by PLAYER_KICKED
+export interface VoteResultPayload {
+  targetId: string;
+  result: VoteResult;
+  yes: number;
+  no: number;
+  needed: number;
+}
+
+// Party rooms split more than 8 players across piles played at once; each
+// pile's winner goes on to a finale
+export interface PartyStartedPayload {
+  piles: string[][];
+}
+
+export interface PileWonPayload {
+  pile: number;
+  winnerId: string;
+  winnerName: string;
+}
+
+export interface FinaleStartingPayload {
+  finalists: string[];
+}
+
+// Sent when the host starts a room with a ready check; each listed player
+// answers with READY
+export interface ReadyCheckPayload {
+  playerIds: string[];
+  timeoutSeconds: number;
+}
+
+export interface PlayerReadyPayload {
+  playerId: string;
+  ready: number;
+  needed: number;
+}
+
+// Time left on a timed game's clock, sent once a second
+export interface GameClockPayload {
+  remainingMs: number;
+  limitMs: number;
+}
+
+// Every player's team, or null when the room stopped playing in teams
+export interface TeamsChangedPayload {
+  teams: Record<string, number> | null;
+}
+
+// Every player's card count after a slap or penalty moved cards
+// The hand of a player who left mid-game, dealt out, put under the pile or taken out of play
+export interface CardsRedistributedPayload {
+  playerId: string;
+  destination: LeaverCards;
+  count: number;
+  dealt?: Record<string, number>; // Cards each player was given, for 'deal'
+  playerCards: Record<string, number>; // Card counts afterwards
+  pileCount: number;
+}
+
+export interface CardCountsUpdatedPayload {
+  playerCardCounts: Record<string, number>;
+  pileCount: number;
 }
 
 export interface PlayerEliminatedPayload {
   playerId: string;
 }
 
+// A player struck out for timing out too many turns in a row; their hand
+// went under the pile
+export interface PlayerAFKPayload {
+  playerId: string;
+  strikes: number;
+  cardsForfeited: number;
+  pileCount: number;
+}
+
 export interface GameOverPayload {
   winnerId: string;
   winnerName: string;
+  winningTeam?: number; // In a team game; the winner is its biggest hand
+  winReason: WinCondition; // The condition that decided the game
   stats: GameStats;
+  result?: SignedResult; // Checkable at /api/verify
+}
+
+// A game result signed by the server: payload is the result's JSON and
+// signature its Ed25519 signature, both base64url
+export interface SignedResult {
+  payload: string;
+  signature: string;
+  keyId: string;
+}
+
+// A single-use invite from POST /api/rooms/{code}/invites
+export interface Invite {
+  roomCode: string;
+  token: string;
+  expiresAt: number; // Unix milliseconds
 }
 
 export interface ErrorPayload {
   code: string;
-  message: string;
+  message: string; // In the requested language where the server has it
+  details?: Record<string, string>;
+  key?: string; // Message key, for translating the message client-side
+  params?: Record<string, string>;
 }
 
 export interface PlayerKickedPayload {
@@ -198,7 +579,41 @@ export interface PlayerKickedPayload {
 }
 
 export interface GameEndedPayload {
-  reason: string;
+  reason: string; // Always English, as every player gets the same broadcast
+  reasonKey?: string;
+  params?: Record<string, string>;
+}
+
+// Optional timings the client measured itself, in milliseconds
+export interface ReportTelemetryPayload {
+  renderLatencyMs: number;
+  inputLagMs: number;
+  rttMs?: number;
+  device?: string;
+}
+
+// WebRTC signaling relayed through the server to one other player: send
+// targetId, receive fromId. data is the SDP or ICE candidate, passed untouched.
+export type RTCSignalKind = 'offer' | 'answer' | 'candidate' | 'hangup';
+
+export interface RTCSignalPayload {
+  targetId?: string;
+  fromId?: string;
+  kind: RTCSignalKind;
+  data?: RTCSessionDescriptionInit | RTCIceCandidateInit;
+}
+
+export interface CalibrateFlashPayload {
+  round: number; // From 1
+  rounds: number;
+  intervalMs: number;
+}
+
+export interface CalibrationResultPayload {
+  inputLagMs: number; // Taken off slap reaction times
+  samples: number;
+  device?: string;
+  calibratedAt: number;
 }
 
 // Helper function to get card image path
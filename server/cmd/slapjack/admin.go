@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"slapjack/internal/config"
+)
+
+// adminEndpoint is the server endpoint behind an admin subcommand
+type adminEndpoint struct {
+	method string
+	path   string
+}
+
+// adminEndpoints maps admin subcommands to the server endpoints they call
+var adminEndpoints = map[string]adminEndpoint{
+	"debug":      {http.MethodGet, "/api/debug"},
+	"store":      {http.MethodGet, "/api/admin/store"},
+	"cheats":     {http.MethodGet, "/api/admin/cheats"},
+	"suspicions": {http.MethodGet, "/api/admin/suspicions"},
+	"telemetry":  {http.MethodGet, "/api/admin/telemetry"},
+	"stats":      {http.MethodGet, "/api/stats/summary"},
+	"reload":     {http.MethodPost, "/api/admin/config/reload"},
+}
+
+func adminFlags(fs *flag.FlagSet) func(*config.Live, []string) error {
+	server := fs.String("server", "", "base URL of the server (default http://localhost:<port>)")
+	roomCode := fs.String("room", "", "limit debug output to one room")
+	return func(live *config.Live, args []string) error {
+		cfg := live.Get()
+		base := *server
+		if base == "" {
+			base = "http://localhost:" + cfg.Port
+		}
+		return admin(cfg, base, *roomCode, args)
+	}
+}
+
+// admin calls one admin endpoint and prints its JSON
+func admin(cfg config.Config, base, roomCode string, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: expected one of %s", errUsage, strings.Join(adminTopics(), ", "))
+	}
+	endpoint, ok := adminEndpoints[args[0]]
+	if !ok {
+		return fmt.Errorf("%w: unknown topic %q, expected one of %s", errUsage, args[0], strings.Join(adminTopics(), ", "))
+	}
+
+	u, err := url.Parse(strings.TrimRight(base, "/") + endpoint.path)
+	if err != nil {
+		return err
+	}
+	if roomCode != "" {
+		u.RawQuery = url.Values{"room": {strings.ToUpper(roomCode)}}.Encode()
+	}
+
+	req, err := http.NewRequest(endpoint.method, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if cfg.AdminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.AdminToken)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); err != nil {
+		out.Reset()
+		out.Write(body)
+	}
+	out.WriteByte('\n')
+	_, err = out.WriteTo(os.Stdout)
+	return err
+}
+
+func adminTopics() []string {
+	topics := make([]string, 0, len(adminEndpoints))
+	for topic := range adminEndpoints {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"slapjack/internal/config"
+	"slapjack/internal/logging"
+)
+
+// command is one subcommand of the slapjack binary
+// Every command accepts the server configuration flags; setup adds any of its own
+type command struct {
+	name    string
+	args    string // Positional arguments, for usage
+	summary string
+	setup   func(fs *flag.FlagSet) func(cfg *config.Live, args []string) error
+}
+
+var commands = []command{
+	{
+		name:    "serve",
+		summary: "run the game server (default)",
+		setup: func(fs *flag.FlagSet) func(*config.Live, []string) error {
+			return serve
+		},
+	},
+	{
+		name:    "migrate",
+		summary: "bring stored Redis data up to the current schema",
+		setup:   migrateFlags,
+	},
+	{
+		name:    "simulate",
+		summary: "play bot games in-process and report how they went",
+		setup:   simulateFlags,
+	},
+	{
+		name:    "admin",
+		args:    "<topic>",
+		summary: "query a running server's admin endpoints",
+		setup:   adminFlags,
+	},
+}
+
+func main() {
+	args := os.Args[1:]
+	name := "serve"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		usage()
+		return
+	}
+
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+	fs, run := newFlagSet(cmd, &cfg, os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		os.Exit(2)
+	}
+
+	load := func() (config.Config, error) {
+		return loadConfig(cmd, args)
+	}
+	cfg, err := load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: loading config: %v\n", cmd.name, err)
+		os.Exit(1)
+	}
+
+	logging.Setup(cfg.LogLevel)
+	if err := run(config.NewLive(cfg, load), fs.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd.name, err)
+		if errors.Is(err, errUsage) {
+			fs.Usage()
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// errUsage marks errors caused by bad arguments, so usage is printed with them
+var errUsage = errors.New("invalid usage")
+
+// loadConfig builds the configuration from the environment, the config file it
+// names and the command's flags, in rising precedence
+// It runs again on every reload, so edits to the config file take effect
+func loadConfig(cmd command, args []string) (config.Config, error) {
+	cfg := config.Load()
+	if err := parseConfigFlags(cmd, &cfg, args); err != nil {
+		return cfg, err
+	}
+	if cfg.ConfigFile == "" {
+		return cfg, nil
+	}
+
+	cfg, err := config.LoadFile(cfg.ConfigFile)
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, parseConfigFlags(cmd, &cfg, args)
+}
+
+// parseConfigFlags applies the command-line flags to cfg
+func parseConfigFlags(cmd command, cfg *config.Config, args []string) error {
+	fs, _ := newFlagSet(cmd, cfg, io.Discard)
+	return fs.Parse(args)
+}
+
+// newFlagSet creates a command's flag set, with the configuration flags
+// setting cfg, and returns it with the function that runs the command
+// Parse errors and usage are written to out
+func newFlagSet(cmd command, cfg *config.Config, out io.Writer) (*flag.FlagSet, func(*config.Live, []string) error) {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	config.RegisterFlags(fs, cfg)
+	run := cmd.setup(fs)
+	fs.Usage = func() { commandUsage(fs, cmd) }
+	return fs, run
+}
+
+// commandUsage prints a command's synopsis, then its own flags and the server
+// configuration flags separately
+func commandUsage(fs *flag.FlagSet, cmd command) {
+	configFlags := flag.NewFlagSet("", flag.ContinueOnError)
+	config.RegisterFlags(configFlags, &config.Config{})
+
+	own := flag.NewFlagSet("", flag.ContinueOnError)
+	shared := flag.NewFlagSet("", flag.ContinueOnError)
+	for _, set := range []*flag.FlagSet{own, shared} {
+		set.SetOutput(fs.Output())
+	}
+	hasOwn := false
+	fs.VisitAll(func(f *flag.Flag) {
+		set := shared
+		if configFlags.Lookup(f.Name) == nil {
+			set, hasOwn = own, true
+		}
+		set.Var(f.Value, f.Name, f.Usage)
+		set.Lookup(f.Name).DefValue = f.DefValue
+	})
+
+	out := fs.Output()
+	synopsis := "slapjack " + cmd.name + " [flags]"
+	if cmd.args != "" {
+		synopsis += " " + cmd.args
+	}
+	fmt.Fprintf(out, "Usage: %s\n\n%s\n", synopsis, cmd.summary)
+	if hasOwn {
+		fmt.Fprintln(out, "\nFlags:")
+		own.PrintDefaults()
+	}
+	fmt.Fprintln(out, "\nServer configuration flags:")
+	shared.PrintDefaults()
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: slapjack <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'slapjack <command> -h' for a command's flags. Every flag can also be set")
+	fmt.Fprintln(os.Stderr, "through the environment variable named in its description.")
+}
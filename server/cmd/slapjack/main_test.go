@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"slapjack/internal/config"
+)
+
+func TestParseConfigFlags(t *testing.T) {
+	tests := []struct {
+		command string
+		args    []string
+		wantErr bool
+	}{
+		{"serve", nil, false},
+		{"serve", []string{"-port=9000", "-reconnect-grace=30s"}, false},
+		{"serve", []string{"-nope"}, true},
+		{"serve", []string{"-port"}, true},
+		{"serve", []string{"-reconnect-grace=soon"}, true},
+		{"serve", []string{"-max-rooms-per-session=two"}, true},
+		{"serve", []string{"-ws-compression=maybe"}, true},
+		{"serve", []string{"-server=http://localhost"}, true}, // admin's flag
+		{"admin", []string{"-server=http://localhost", "stats"}, false},
+		{"simulate", []string{"-games=lots"}, true},
+		{"simulate", []string{"-false-slap-rate=0.1"}, false},
+		{"migrate", []string{"-dry-run=perhaps"}, true},
+	}
+	for _, tt := range tests {
+		cmd, _ := findCommand(tt.command)
+		cfg := config.Default()
+		err := parseConfigFlags(cmd, &cfg, tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s %v: err = %v, want error %v", tt.command, tt.args, err, tt.wantErr)
+		}
+	}
+
+	cmd, _ := findCommand("serve")
+	cfg := config.Default()
+	if err := parseConfigFlags(cmd, &cfg, []string{"-port=9000", "-reconnect-grace=30s"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != "9000" || cfg.ReconnectGrace != 30*time.Second {
+		t.Errorf("port %q, reconnect grace %v; want 9000, 30s", cfg.Port, cfg.ReconnectGrace)
+	}
+}
+
+func TestCommandUsage(t *testing.T) {
+	for _, cmd := range commands {
+		var out bytes.Buffer
+		cfg := config.Default()
+		fs, _ := newFlagSet(cmd, &cfg, &out)
+		if err := fs.Parse([]string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+			t.Errorf("%s -h: err = %v, want flag.ErrHelp", cmd.name, err)
+		}
+		usage := out.String()
+		if !strings.HasPrefix(usage, "Usage: slapjack "+cmd.name+" [flags]") || !strings.Contains(usage, "-port") {
+			t.Errorf("%s usage missing synopsis or config flags:\n%s", cmd.name, usage)
+		}
+	}
+
+	var out bytes.Buffer
+	cmd, _ := findCommand("admin")
+	cfg := config.Default()
+	fs, _ := newFlagSet(cmd, &cfg, &out)
+	fs.Parse([]string{"-bogus"})
+	usage := out.String()
+	for _, want := range []string{"flag provided but not defined: -bogus", "[flags] <topic>", "Flags:\n  -room", "Server configuration flags:"} {
+		if !strings.Contains(usage, want) {
+			t.Errorf("admin usage after a bad flag missing %q:\n%s", want, usage)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"slapjack/internal/config"
+	"slapjack/internal/redis"
+)
+
+// migration upgrades stored data by one schema version
+type migration struct {
+	summary string
+	apply   func(ctx context.Context, store *redis.Store) error
+}
+
+// migrations in order; migrations[i] moves the schema from version i to i+1
+var migrations = []migration{
+	{
+		summary: "prune active room codes whose room state has expired",
+		apply:   pruneActiveRooms,
+	},
+}
+
+func migrateFlags(fs *flag.FlagSet) func(*config.Live, []string) error {
+	dryRun := fs.Bool("dry-run", false, "list pending migrations without applying them")
+	return func(live *config.Live, args []string) error {
+		return migrate(live.Get(), *dryRun)
+	}
+}
+
+// migrate applies every migration newer than the stored schema version
+func migrate(cfg config.Config, dryRun bool) error {
+	store, err := redis.NewStore(cfg.RedisURL, cfg.RedisTimeout)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	version, err := store.GetSchemaVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	if version >= len(migrations) {
+		slog.Info("schema is up to date", "version", version)
+		return nil
+	}
+
+	for i := version; i < len(migrations); i++ {
+		m := migrations[i]
+		if dryRun {
+			slog.Info("pending migration", "version", i+1, "summary", m.summary)
+			continue
+		}
+		slog.Info("applying migration", "version", i+1, "summary", m.summary)
+		if err := m.apply(ctx, store); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if err := store.SetSchemaVersion(ctx, i+1); err != nil {
+			return fmt.Errorf("recording schema version %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// pruneActiveRooms drops codes from the active set once their room state is gone,
+// which otherwise keeps the codes reserved forever
+func pruneActiveRooms(ctx context.Context, store *redis.Store) error {
+	codes, err := store.GetActiveRooms(ctx)
+	if err != nil {
+		return err
+	}
+	for _, code := range codes {
+		exists, err := store.RoomExists(ctx, code)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if err := store.RemoveActiveRoom(ctx, code); err != nil {
+			return err
+		}
+		slog.Info("pruned stale room code", "roomCode", code)
+	}
+	return nil
+}
@@ -0,0 +1,510 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"slapjack/internal/clock"
+	"slapjack/internal/config"
+	"slapjack/internal/history"
+	"slapjack/internal/logging"
+	"slapjack/internal/redis"
+	"slapjack/internal/room"
+	ws "slapjack/internal/websocket"
+	"slapjack/pkg/protocol"
+)
+
+// newUpgrader creates the WebSocket upgrader, checking origins against the live config
+// Compression is fixed at startup
+func newUpgrader(live *config.Live) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		EnableCompression: live.Get().WSCompression,
+		CheckOrigin: func(r *http.Request) bool {
+			// Non-browser clients send no origin
+			origin := r.Header.Get("Origin")
+			return origin == "" || live.Get().OriginAllowed(origin)
+		},
+	}
+}
+
+// serve runs the game server until it receives a shutdown signal
+// SIGHUP reloads the configuration
+func serve(live *config.Live, args []string) error {
+	cfg := live.Get()
+
+	// Connect to Redis
+	store, err := redis.NewStore(cfg.RedisURL, cfg.RedisTimeout)
+	if err != nil {
+		slog.Warn("failed to connect to Redis", "error", err)
+		slog.Warn("running without Redis, game state will be in-memory only")
+		store = nil
+	} else {
+		defer store.Close()
+		slog.Info("connected to Redis")
+	}
+
+	// Create hub
+	hub := ws.NewHub(store, live, clock.Real)
+	go hub.Run()
+
+	// Connect to the match history database, if there is one
+	var archive *history.Archive
+	if cfg.HistoryDatabaseURL != "" {
+		var err error
+		archive, err = history.Open(cfg.HistoryDatabaseURL)
+		if err != nil {
+			slog.Warn("failed to connect to the history database, match history is disabled", "error", err)
+		} else {
+			defer archive.Close()
+			go archive.Run()
+			hub.GetRoomManager().SetHistory(archive)
+			slog.Info("connected to the history database")
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reloadConfig(live, hub)
+		}
+	}()
+
+	// HTTP handlers
+	upgrader := newUpgrader(live)
+	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		handleWebSocket(hub, upgrader, live, w, r)
+	})
+
+	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	http.HandleFunc("/api/rooms", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		rooms := hub.GetRoomManager().GetActiveRooms()
+		json.NewEncoder(w).Encode(rooms)
+	})
+
+	// Rule variants for the create room menu, in the language asked for by
+	// ?lang= or Accept-Language
+	http.HandleFunc("GET /api/variants", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		lang := room.VariantLanguage(r.URL.Query().Get("lang"), r.Header.Get("Accept-Language"))
+		w.Header().Set("Content-Language", lang)
+		json.NewEncoder(w).Encode(hub.GetRoomManager().Variants(lang))
+	})
+
+	// Game result signatures: GET publishes the public key, POST checks a signed result
+	http.HandleFunc("GET /api/verify", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		json.NewEncoder(w).Encode(hub.GetRoomManager().VerificationKey())
+	})
+	http.HandleFunc("OPTIONS /api/verify", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	http.HandleFunc("POST /api/verify", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		var signed protocol.SignedResult
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&signed); err != nil {
+			http.Error(w, "invalid signed result", http.StatusBadRequest)
+			return
+		}
+		result, err := hub.GetRoomManager().VerifyResult(signed)
+		if err != nil {
+			json.NewEncoder(w).Encode(protocol.VerifyResponse{Error: err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(protocol.VerifyResponse{Valid: true, Result: &result})
+	})
+
+	// Long-polling fallback for clients that can't use WebSockets
+	http.HandleFunc("GET /api/rooms/{code}/events", hub.ServePollEvents)
+	http.HandleFunc("/api/rooms/{code}/actions", hub.ServePollActions)
+
+	// A room's recent joins, kicks, setting changes and game starts, for its
+	// host (bearer player token) or an admin (bearer admin token)
+	http.HandleFunc("GET /api/rooms/{code}/audit", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		rm := hub.GetRoomManager().GetRoom(strings.ToUpper(r.PathValue("code")))
+		if rm == nil {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !isAdminToken(live, token) && !rm.IsHostToken(token) {
+			http.Error(w, "only the host can see the room's audit log", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rm.AuditLog())
+	})
+	http.HandleFunc("OPTIONS /api/rooms/{code}/audit", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// Single-use invites into a room, private ones included, for its host
+	// (bearer player token) or an admin (bearer admin token) to share as links
+	http.HandleFunc("POST /api/rooms/{code}/invites", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		rm := hub.GetRoomManager().GetRoom(strings.ToUpper(r.PathValue("code")))
+		if rm == nil {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !isAdminToken(live, token) && !rm.IsHostToken(token) {
+			http.Error(w, "only the host can invite players", http.StatusUnauthorized)
+			return
+		}
+		invite, err := hub.GetRoomManager().CreateInvite(r.Context(), rm.Code)
+		if err != nil {
+			http.Error(w, "could not create invite", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(invite)
+	})
+	http.HandleFunc("OPTIONS /api/rooms/{code}/invites", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	http.HandleFunc("GET /api/replay/{roomCode}/{gameId}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if !live.Get().FeatureEnabled(protocol.FeatureReplay) {
+			http.Error(w, "replays are switched off", http.StatusNotFound)
+			return
+		}
+		roomCode := strings.ToUpper(r.PathValue("roomCode"))
+		replay, ok := hub.GetRoomManager().GetReplay(r.Context(), roomCode, r.PathValue("gameId"))
+		if !ok {
+			http.Error(w, "replay not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(replay)
+	})
+
+	http.HandleFunc("GET /api/games/{id}/highlights", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		highlights, ok := hub.GetRoomManager().GetHighlights(r.Context(), r.PathValue("id"))
+		if !ok {
+			http.Error(w, "game not found", http.StatusNotFound)
+			return
+		}
+		if highlights == nil {
+			highlights = []protocol.Highlight{}
+		}
+		json.NewEncoder(w).Encode(highlights)
+	})
+
+	// Full server state for operators, optionally scoped to one room
+	http.HandleFunc("/api/debug", requireAdmin(live, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		roomCode := strings.ToUpper(r.URL.Query().Get("room"))
+		defaultRedaction := ws.ParseRedaction(live.Get().DebugRedaction, ws.RedactPartial)
+		redaction := ws.ParseRedaction(r.URL.Query().Get("redact"), defaultRedaction)
+		json.NewEncoder(w).Encode(hub.GetDebugInfo(roomCode, redaction))
+	}))
+
+	// Message latency histograms and backpressure counters, for Prometheus
+	http.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		hub.WriteMetrics(w)
+	})
+
+	// Sanitized counts for the lobby
+	http.HandleFunc("/api/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		json.NewEncoder(w).Encode(hub.GetPublicStats())
+	})
+
+	// Top players by wins, win rate or fastest average slap
+	http.HandleFunc("GET /api/leaderboard", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		metric := r.URL.Query().Get("metric")
+		if metric == "" {
+			metric = protocol.LeaderboardWins
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		entries, err := hub.GetRoomManager().Leaderboard(r.Context(), metric, limit)
+		if errors.Is(err, room.ErrUnknownMetric) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "leaderboard unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(protocol.LeaderboardResponse{Metric: metric, Entries: entries})
+	})
+
+	// A player's finished games, latest first, by profile ID
+	http.HandleFunc("GET /api/history", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		playerID := r.URL.Query().Get("playerId")
+		if playerID == "" {
+			http.Error(w, "playerId is required", http.StatusBadRequest)
+			return
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		page, err := hub.GetRoomManager().MatchHistory(r.Context(), playerID, r.URL.Query().Get("cursor"), limit)
+		switch {
+		case errors.Is(err, room.ErrHistoryDisabled):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, history.ErrInvalidCursor):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			http.Error(w, "match history unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(page)
+	})
+
+	// Hourly and daily activity rollups
+	http.HandleFunc("GET /api/stats/summary", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		json.NewEncoder(w).Encode(hub.GetRoomManager().Stats().Summary())
+	})
+
+	http.HandleFunc("GET /api/admin/suspicions", requireAdmin(live, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hub.GetRoomManager().GetSuspicions())
+	}))
+
+	http.HandleFunc("GET /api/admin/cheats", requireAdmin(live, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hub.GetRoomManager().GetCheatReports())
+	}))
+
+	http.HandleFunc("GET /api/admin/telemetry", requireAdmin(live, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hub.GetRoomManager().GetTelemetry())
+	}))
+
+	// Redis health and how long in-memory state has diverged from it
+	http.HandleFunc("GET /api/admin/store", requireAdmin(live, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hub.GetRoomManager().GetStoreStatus())
+	}))
+
+	// Re-read the configuration, as SIGHUP does
+	http.HandleFunc("POST /api/admin/config/reload", requireAdmin(live, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		result, err := reloadConfig(live, hub)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+
+	// Serve static files (for testing)
+	http.Handle("/", http.FileServer(http.Dir("./static")))
+
+	srv := &http.Server{Addr: ":" + cfg.Port}
+
+	go func() {
+		slog.Info("server starting", "port", cfg.Port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("ListenAndServe failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	// Wait for a shutdown signal
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	countdown := live.Get().ShutdownCountdown
+	slog.Info("shutting down, notifying clients", "countdown", countdown.String())
+	hub.Shutdown(countdown)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("HTTP shutdown failed", "error", err)
+	}
+	// Games that ended during the countdown are still being archived
+	if archive != nil {
+		if err := archive.Flush(shutdownCtx); err != nil {
+			slog.Error("match history not fully archived", "error", err)
+		}
+	}
+	slog.Info("server stopped")
+	return nil
+}
+
+// reloadConfig re-reads the configuration and applies it to the running server
+func reloadConfig(live *config.Live, hub *ws.Hub) (config.ReloadResult, error) {
+	previous, result, err := live.Reload()
+	if err != nil {
+		slog.Error("config reload failed, keeping the current config", "error", err)
+		return result, err
+	}
+	logging.SetLevel(live.Get().LogLevel)
+	hub.ApplyConfig(previous, result)
+	return result, nil
+}
+
+func handleWebSocket(hub *ws.Hub, upgrader *websocket.Upgrader, live *config.Live, w http.ResponseWriter, r *http.Request) {
+	if hub.IsShuttingDown() {
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	if ip := hub.ClientIP(r); !hub.AllowConnection(ip) {
+		slog.Warn("rejecting WebSocket upgrade, rate limited", "ip", ip)
+		http.Error(w, "too many connections", http.StatusTooManyRequests)
+		return
+	}
+
+	// The upgrader accepts permessage-deflate whenever the client offers it
+	compressed := upgrader.EnableCompression && ws.OffersCompression(r)
+	conn, err := upgrader.Upgrade(hub.CountWireBytes(w, compressed), r, nil)
+	if err != nil {
+		slog.Warn("WebSocket upgrade failed", "error", err)
+		return
+	}
+
+	// Check for existing session (reconnection). The session ID is only honoured
+	// together with the token issued for it, so seats can't be hijacked by guessing IDs.
+	rooms := hub.GetRoomManager()
+	sessionID := r.URL.Query().Get("sessionId")
+	sessionToken := r.URL.Query().Get("sessionToken")
+	sessionRejected := false
+	if sessionID != "" && !rooms.VerifySessionToken(sessionID, sessionToken) {
+		slog.Warn("rejecting session with invalid token", "sessionId", sessionID)
+		sessionRejected = true
+		sessionID = ""
+	}
+	if sessionID == "" {
+		sessionID = uuid.New().String()
+		sessionToken = rooms.IssueSessionToken(sessionID)
+	}
+
+	// Durable player identity, issued on first connect and persisted by the client
+	playerToken := r.URL.Query().Get("playerToken")
+	if _, err := uuid.Parse(playerToken); err != nil {
+		playerToken = uuid.New().String()
+	}
+
+	// Create client
+	client := ws.NewClient(hub, conn, sessionID, playerToken)
+	client.SetCompression(compressed)
+	client.SetLocale(r.URL.Query().Get("lang"), r.Header.Get("Accept-Language"))
+	if enc, ok := protocol.EncoderFor(r.URL.Query().Get("encoding")); ok {
+		client.SetEncoder(enc)
+	}
+
+	// Check for reconnection
+	if session := rooms.GetSession(sessionID); session != nil {
+		// Reconnecting player
+		room := rooms.GetRoom(session.RoomCode)
+		if room != nil {
+			client.RoomCode = session.RoomCode
+			client.PlayerID = session.PlayerID
+			if player := rooms.ReconnectPlayer(session.RoomCode, session.PlayerID); player != nil {
+				client.PlayerName = player.Name
+			}
+		}
+	}
+
+	// Register with hub
+	hub.Register(client)
+
+	if sessionRejected {
+		client.SendMessage(protocol.NewMessage(protocol.SessionInvalid, protocol.SessionInvalidPayload{
+			Message: "Session could not be verified, starting a new session",
+		}))
+	}
+
+	// Send connected message with session ID
+	client.SendMessage(protocol.NewMessage(protocol.Connected, protocol.ConnectedPayload{
+		SessionID:       sessionID,
+		SessionToken:    sessionToken,
+		PlayerToken:     playerToken,
+		ProtocolVersion: protocol.ProtocolVersion,
+		Features:        live.Get().Features(),
+	}))
+
+	// If reconnecting, send current room state and replay what was missed
+	if client.RoomCode != "" {
+		room := rooms.GetRoom(client.RoomCode)
+		if room != nil {
+			client.SendMessage(protocol.NewMessage(protocol.Reconnected, protocol.RoomJoinedPayload{
+				Room: room.ToProtocol(),
+			}))
+			hub.Resume(client, room)
+
+			// Notify others of reconnection
+			reconnectMsg, _ := json.Marshal(protocol.NewMessage(protocol.PlayerReconnected, protocol.PlayerReconnectedPayload{
+				PlayerID: client.PlayerID,
+			}))
+			hub.BroadcastToRoomExcept(client.RoomCode, client.SessionID, reconnectMsg)
+			rooms.NotifyMembershipChanged(client.RoomCode, hub.BroadcastToRoom)
+		}
+	}
+
+	// Start client pumps
+	client.Start()
+}
+
+// requireAdmin only lets requests with the admin bearer token through
+// Admin endpoints are hidden entirely when no token is configured
+func requireAdmin(live *config.Live, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cfg := live.Get()
+		if cfg.AdminToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+		auth := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(auth, []byte("Bearer "+cfg.AdminToken)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// isAdminToken reports whether token is the configured admin token
+func isAdminToken(live *config.Live, token string) bool {
+	admin := live.Get().AdminToken
+	return admin != "" && subtle.ConstantTimeCompare([]byte(token), []byte(admin)) == 1
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"slapjack/internal/config"
+	"slapjack/internal/game"
+	"slapjack/internal/room"
+)
+
+// simulateOptions controls the bots in a simulation run
+type simulateOptions struct {
+	games         int
+	players       int
+	maxPlays      int
+	falseSlapRate float64
+	mode          string
+}
+
+// simulateResult is the outcome of one bot game
+type simulateResult struct {
+	winnerSeat int  // -1 if the game didn't finish
+	stuck      bool // The player to move had no cards and nobody had won
+	plays      int
+	slaps      int
+	duration   time.Duration
+}
+
+func simulateFlags(fs *flag.FlagSet) func(*config.Live, []string) error {
+	var opts simulateOptions
+	fs.IntVar(&opts.games, "games", 100, "number of games to play")
+	fs.IntVar(&opts.players, "players", 4, "bots per game")
+	fs.IntVar(&opts.maxPlays, "max-plays", 5000, "cards played before a game is called a stalemate")
+	fs.Float64Var(&opts.falseSlapRate, "false-slap-rate", 0.05, "chance per play that a bot slaps a card it shouldn't")
+	fs.StringVar(&opts.mode, "mode", game.ModeClassic, "game mode")
+	return func(live *config.Live, args []string) error {
+		return simulate(opts)
+	}
+}
+
+// simulate plays bot games with the default room settings and prints a summary
+func simulate(opts simulateOptions) error {
+	if opts.games < 1 || opts.players < 2 || opts.maxPlays < 1 {
+		return fmt.Errorf("%w: need at least 1 game, 2 players and 1 play", errUsage)
+	}
+
+	settings := room.DefaultSettings()
+	settings.GameMode = opts.mode
+	settings.SlapCooldownMs = 0
+	settings.Validate()
+	gameOpts := settings.GameOptions()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	results := make([]simulateResult, 0, opts.games)
+	for i := 0; i < opts.games; i++ {
+		results = append(results, simulateGame(rng, opts, gameOpts))
+	}
+
+	printSimulation(opts, results)
+	return nil
+}
+
+// simulateGame plays one game: bots take their turns in order, one of them slaps
+// every valid pile, and now and then one slaps a pile that isn't
+func simulateGame(rng *rand.Rand, opts simulateOptions, gameOpts game.Options) simulateResult {
+	seats := make(map[string]int, opts.players)
+	playerIDs := make([]string, opts.players)
+	for i := range playerIDs {
+		playerIDs[i] = fmt.Sprintf("bot-%d", i+1)
+		seats[playerIDs[i]] = i
+	}
+
+	start := time.Now()
+	g := game.NewGame(playerIDs, gameOpts)
+	result := simulateResult{winnerSeat: -1}
+
+	for result.plays < opts.maxPlays {
+		if _, _, err := g.PlayCard(g.GetCurrentPlayer(), 0); err != nil {
+			result.stuck = true
+			break
+		}
+		result.plays++
+
+		state := g.GetState()
+		if state.CanSlap || rng.Float64() < opts.falseSlapRate {
+			slapper := playerIDs[rng.Intn(len(playerIDs))]
+			g.ProcessSlap(slapper, time.Now().UnixMilli(), 0)
+			result.slaps++
+		}
+
+		g.CheckEliminations()
+		if winner := g.CheckWinner(); winner != "" {
+			result.winnerSeat = seats[winner]
+			break
+		}
+	}
+
+	result.duration = time.Since(start)
+	return result
+}
+
+func printSimulation(opts simulateOptions, results []simulateResult) {
+	wins := make([]int, opts.players)
+	var plays []int
+	var slaps int
+	var elapsed time.Duration
+	stalemates, stuck := 0, 0
+	for _, r := range results {
+		if r.stuck {
+			stuck++
+		} else if r.winnerSeat < 0 {
+			stalemates++
+		} else {
+			wins[r.winnerSeat]++
+		}
+		plays = append(plays, r.plays)
+		slaps += r.slaps
+		elapsed += r.duration
+	}
+	sort.Ints(plays)
+
+	fmt.Printf("games:           %d (%s mode, %d bots)\n", len(results), opts.mode, opts.players)
+	fmt.Printf("stalemates:      %d (no winner after %d plays)\n", stalemates, opts.maxPlays)
+	fmt.Printf("stuck:           %d (player to move had no cards)\n", stuck)
+	fmt.Printf("plays per game:  min %d, median %d, max %d\n", plays[0], plays[len(plays)/2], plays[len(plays)-1])
+	fmt.Printf("slaps per game:  %.1f\n", float64(slaps)/float64(len(results)))
+	fmt.Printf("time per game:   %s\n", (elapsed / time.Duration(len(results))).Round(time.Microsecond))
+	fmt.Println("wins by seat:")
+	for seat, n := range wins {
+		fmt.Printf("  %d: %d (%.1f%%)\n", seat+1, n, 100*float64(n)/float64(len(results)))
+	}
+}
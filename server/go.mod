@@ -6,6 +6,8 @@ require (
 	github.com/go-redis/redis/v8 v8.11.5
 	github.com/google/uuid v1.6.0
 	github.com/gorilla/websocket v1.5.1
+	github.com/lib/pq v1.10.9
+	golang.org/x/text v0.13.0
 )
 
 require (
@@ -0,0 +1,163 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Mock is a Clock that only moves when Advance is called, so tests can step
+// through timers deterministically
+type Mock struct {
+	mu      sync.Mutex
+	added   *sync.Cond
+	now     time.Time
+	waiters []*mockWaiter
+}
+
+// mockWaiter is a pending After, Sleep, AfterFunc or ticker
+type mockWaiter struct {
+	clock  *Mock
+	at     time.Time
+	period time.Duration // Tickers only
+	ch     chan time.Time
+	fn     func()
+}
+
+// NewMock creates a mock clock set to start
+func NewMock(start time.Time) *Mock {
+	m := &Mock{now: start}
+	m.added = sync.NewCond(&m.mu)
+	return m
+}
+
+// Now returns the mock time
+func (m *Mock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// Since returns the mock time elapsed since t
+func (m *Mock) Since(t time.Time) time.Duration {
+	return m.Now().Sub(t)
+}
+
+// After returns a channel that receives the time once the clock has been advanced by d
+func (m *Mock) After(d time.Duration) <-chan time.Time {
+	return m.schedule(d, 0, nil).ch
+}
+
+// AfterFunc calls f, on the goroutine calling Advance, once the clock has been advanced by d
+func (m *Mock) AfterFunc(d time.Duration, f func()) Timer {
+	return m.schedule(d, 0, f)
+}
+
+// NewTicker returns a ticker that ticks each time the clock passes another d
+func (m *Mock) NewTicker(d time.Duration) Ticker {
+	return mockTicker{m.schedule(d, d, nil)}
+}
+
+// Sleep blocks until the clock has been advanced by d
+func (m *Mock) Sleep(d time.Duration) {
+	<-m.After(d)
+}
+
+// Advance moves the clock forward by d, firing everything that falls due in order
+func (m *Mock) Advance(d time.Duration) {
+	m.mu.Lock()
+	target := m.now.Add(d)
+	for {
+		w := m.nextDue(target)
+		if w == nil {
+			break
+		}
+		m.now = w.at
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			m.remove(w)
+		}
+		m.mu.Unlock()
+
+		if w.fn != nil {
+			w.fn()
+		} else {
+			select {
+			case w.ch <- m.Now():
+			default:
+			}
+		}
+
+		m.mu.Lock()
+	}
+	m.now = target
+	m.mu.Unlock()
+}
+
+// BlockUntil waits until at least n timers, tickers or sleepers are pending,
+// so a test can be sure a goroutine is waiting before advancing past it
+func (m *Mock) BlockUntil(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.waiters) < n {
+		m.added.Wait()
+	}
+}
+
+// Pending returns how many timers, tickers and sleepers are waiting
+func (m *Mock) Pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.waiters)
+}
+
+func (m *Mock) schedule(d, period time.Duration, fn func()) *mockWaiter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := &mockWaiter{clock: m, at: m.now.Add(d), period: period, fn: fn}
+	if fn == nil {
+		w.ch = make(chan time.Time, 1)
+	}
+	m.waiters = append(m.waiters, w)
+	m.added.Broadcast()
+	return w
+}
+
+// nextDue returns the earliest waiter due at or before target
+// Caller must hold m.mu
+func (m *Mock) nextDue(target time.Time) *mockWaiter {
+	var next *mockWaiter
+	for _, w := range m.waiters {
+		if !w.at.After(target) && (next == nil || w.at.Before(next.at)) {
+			next = w
+		}
+	}
+	return next
+}
+
+// remove drops a waiter, returning false if it wasn't pending
+// Caller must hold m.mu
+func (m *Mock) remove(w *mockWaiter) bool {
+	for i, pending := range m.waiters {
+		if pending == w {
+			m.waiters = append(m.waiters[:i], m.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Stop cancels a timer or ticker
+func (w *mockWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	return w.clock.remove(w)
+}
+
+type mockTicker struct {
+	*mockWaiter
+}
+
+func (t mockTicker) C() <-chan time.Time { return t.ch }
+func (t mockTicker) Stop()               { t.mockWaiter.Stop() }
@@ -0,0 +1,99 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func fired(ch <-chan time.Time) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestMockAfter(t *testing.T) {
+	m := NewMock(epoch)
+	ch := m.After(time.Second)
+
+	m.Advance(999 * time.Millisecond)
+	if fired(ch) {
+		t.Fatal("After fired early")
+	}
+	m.Advance(time.Millisecond)
+	if !fired(ch) {
+		t.Fatal("After didn't fire on time")
+	}
+	if got := m.Since(epoch); got != time.Second {
+		t.Errorf("Since = %v, want 1s", got)
+	}
+	if m.Pending() != 0 {
+		t.Errorf("%d waiters left after firing", m.Pending())
+	}
+}
+
+func TestMockTicker(t *testing.T) {
+	m := NewMock(epoch)
+	ticker := m.NewTicker(time.Second)
+
+	for i := 0; i < 3; i++ {
+		m.Advance(time.Second)
+		if !fired(ticker.C()) {
+			t.Fatalf("tick %d missing", i+1)
+		}
+	}
+
+	ticker.Stop()
+	m.Advance(time.Second)
+	if fired(ticker.C()) {
+		t.Error("stopped ticker ticked")
+	}
+}
+
+func TestMockAfterFuncOrder(t *testing.T) {
+	m := NewMock(epoch)
+	var order []int
+	var at []time.Duration
+	for _, n := range []int{3, 1, 2} {
+		n := n
+		m.AfterFunc(time.Duration(n)*time.Second, func() {
+			order = append(order, n)
+			at = append(at, m.Since(epoch))
+		})
+	}
+	stopped := m.AfterFunc(1500*time.Millisecond, func() { t.Error("stopped timer ran") })
+	if !stopped.Stop() {
+		t.Error("Stop on a pending timer returned false")
+	}
+
+	m.Advance(5 * time.Second)
+	for i, want := range []int{1, 2, 3} {
+		if order[i] != want || at[i] != time.Duration(want)*time.Second {
+			t.Fatalf("ran %v at %v, want 1, 2, 3 at their own times", order, at)
+		}
+	}
+	if stopped.Stop() {
+		t.Error("Stop on a stopped timer returned true")
+	}
+}
+
+func TestMockSleep(t *testing.T) {
+	m := NewMock(epoch)
+	done := make(chan struct{})
+	go func() {
+		m.Sleep(time.Minute)
+		close(done)
+	}()
+
+	m.BlockUntil(1)
+	m.Advance(time.Minute)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Sleep didn't return")
+	}
+}
@@ -0,0 +1,304 @@
+package config
+
+import (
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"slapjack/pkg/protocol"
+)
+
+// Config holds server configuration loaded from the environment
+type Config struct {
+	Port     string
+	RedisURL string
+
+	// How long a Redis call may take before it is given up on
+	RedisTimeout time.Duration
+
+	// Postgres URL finished games are archived to for good; match history
+	// is disabled when empty
+	HistoryDatabaseURL string
+
+	// How long a disconnected player keeps their seat before being removed
+	ReconnectGrace time.Duration
+
+	// Room creation limits per session
+	MaxRoomsPerSession int
+	CreateRoomCooldown time.Duration
+
+	// Rate limits for incoming messages and new connections
+	MessagesPerSecond   int
+	MessageBurst        int
+	ConnectionsPerMinIP int
+
+	// How long clients are warned before the server shuts down
+	ShutdownCountdown time.Duration
+
+	// Bearer token for admin endpoints; admin endpoints are disabled when empty
+	AdminToken string
+
+	// Key used to sign session tokens; a random key is used when empty
+	SessionSecret string
+
+	// Base64 Ed25519 seed used to sign game results; a random key is used when
+	// empty, so results signed before a restart no longer verify
+	ResultSigningKey string
+
+	// Default redaction level for the admin debug endpoint (none, partial, full)
+	DebugRedaction string
+
+	// Minimum level written to the log (debug, info, warn, error)
+	LogLevel string
+
+	// How long Redis can be down before an alert is logged
+	StoreOutageAlert time.Duration
+
+	// Rooms changed within this long of each other are written to Redis
+	// together, once (0 writes every change)
+	RoomSaveInterval time.Duration
+
+	// Waiting or playing rooms with no client messages for this long are closed (0 disables)
+	IdleRoomTimeout time.Duration
+
+	// Connections outside a room that send no messages for this long are
+	// closed, whatever their pings (0 disables)
+	IdleClientTimeout time.Duration
+
+	// Outbound bytes per second per client (0 = uncapped); cosmetic messages
+	// are dropped first when a client falls behind
+	ClientBandwidthBytesPerSec int
+
+	// Offer permessage-deflate to WebSocket clients; messages under a few
+	// hundred bytes are still sent uncompressed
+	WSCompression bool
+
+	// How long rooms and sessions are kept in Redis
+	RoomTTL    time.Duration
+	SessionTTL time.Duration
+
+	// How long a player profile or wallet is kept after it was last used
+	ProfileTTL time.Duration
+
+	// Origins allowed to open WebSockets; any origin is allowed when empty
+	AllowedOrigins []string
+
+	// Proxies (IPs or CIDRs) whose Fly-Client-IP and X-Forwarded-For headers
+	// are believed; other peers are identified by their own address
+	TrustedProxies []string
+
+	// Optional features switched off (see protocol.ServerFeatures)
+	DisabledFeatures []string
+
+	// Reject player names containing blocked words; the word list file has one
+	// word per line, "=word" to match it exactly (default list when empty)
+	ProfanityFilter   bool
+	ProfanityWordList string
+
+	// Client messages taking longer than this to parse and handle are logged
+	SlowMessage time.Duration
+
+	// Env-style file of overrides, re-read on reload
+	ConfigFile string
+}
+
+// Default returns the default server configuration
+func Default() Config {
+	return Config{
+		Port:                "8080",
+		RedisURL:            "redis://localhost:6379",
+		RedisTimeout:        500 * time.Millisecond,
+		ReconnectGrace:      60 * time.Second,
+		MaxRoomsPerSession:  2,
+		CreateRoomCooldown:  3 * time.Second,
+		MessagesPerSecond:   20,
+		MessageBurst:        40,
+		ConnectionsPerMinIP: 30,
+		ShutdownCountdown:   5 * time.Second,
+		DebugRedaction:      "partial",
+		LogLevel:            "info",
+		StoreOutageAlert:    60 * time.Second,
+		RoomSaveInterval:    250 * time.Millisecond,
+		IdleRoomTimeout:     30 * time.Minute,
+		IdleClientTimeout:   10 * time.Minute,
+		RoomTTL:             2 * time.Hour,
+		SessionTTL:          30 * time.Minute,
+		ProfileTTL:          30 * 24 * time.Hour,
+		SlowMessage:         50 * time.Millisecond,
+	}
+}
+
+// Load reads configuration from environment variables, falling back to defaults
+func Load() Config {
+	return load(os.Getenv)
+}
+
+// load reads configuration through env, falling back to defaults
+func load(env func(string) string) Config {
+	cfg := Default()
+
+	if v := env("PORT"); v != "" {
+		cfg.Port = v
+	}
+	if v := env("REDIS_URL"); v != "" {
+		cfg.RedisURL = v
+	}
+	cfg.RedisTimeout = envMillis(env, "REDIS_TIMEOUT_MS", cfg.RedisTimeout)
+	cfg.HistoryDatabaseURL = env("HISTORY_DATABASE_URL")
+	cfg.ReconnectGrace = envSeconds(env, "RECONNECT_GRACE_SECONDS", cfg.ReconnectGrace)
+	cfg.MaxRoomsPerSession = envInt(env, "MAX_ROOMS_PER_SESSION", cfg.MaxRoomsPerSession)
+	cfg.CreateRoomCooldown = envSeconds(env, "CREATE_ROOM_COOLDOWN_SECONDS", cfg.CreateRoomCooldown)
+	cfg.MessagesPerSecond = envInt(env, "RATE_LIMIT_MESSAGES_PER_SECOND", cfg.MessagesPerSecond)
+	cfg.MessageBurst = envInt(env, "RATE_LIMIT_MESSAGE_BURST", cfg.MessageBurst)
+	cfg.ConnectionsPerMinIP = envInt(env, "RATE_LIMIT_CONNECTIONS_PER_MINUTE", cfg.ConnectionsPerMinIP)
+	cfg.ShutdownCountdown = envSeconds(env, "SHUTDOWN_COUNTDOWN_SECONDS", cfg.ShutdownCountdown)
+	cfg.StoreOutageAlert = envSeconds(env, "STORE_OUTAGE_ALERT_SECONDS", cfg.StoreOutageAlert)
+	cfg.RoomSaveInterval = envMillis(env, "ROOM_SAVE_INTERVAL_MS", cfg.RoomSaveInterval)
+	cfg.IdleRoomTimeout = envSeconds(env, "IDLE_ROOM_TIMEOUT_SECONDS", cfg.IdleRoomTimeout)
+	cfg.IdleClientTimeout = envSeconds(env, "IDLE_CLIENT_TIMEOUT_SECONDS", cfg.IdleClientTimeout)
+	cfg.ClientBandwidthBytesPerSec = envInt(env, "CLIENT_BANDWIDTH_BYTES_PER_SECOND", cfg.ClientBandwidthBytesPerSec)
+	cfg.WSCompression = envBool(env, "WS_COMPRESSION", cfg.WSCompression)
+	cfg.RoomTTL = envSeconds(env, "ROOM_TTL_SECONDS", cfg.RoomTTL)
+	cfg.SessionTTL = envSeconds(env, "SESSION_TTL_SECONDS", cfg.SessionTTL)
+	cfg.ProfileTTL = envSeconds(env, "PROFILE_TTL_SECONDS", cfg.ProfileTTL)
+	cfg.SlowMessage = envMillis(env, "SLOW_MESSAGE_MS", cfg.SlowMessage)
+	cfg.AllowedOrigins = envList(env, "ALLOWED_ORIGINS", cfg.AllowedOrigins)
+	cfg.TrustedProxies = envList(env, "TRUSTED_PROXIES", cfg.TrustedProxies)
+	cfg.DisabledFeatures = envList(env, "DISABLED_FEATURES", cfg.DisabledFeatures)
+	cfg.AdminToken = env("ADMIN_TOKEN")
+	cfg.SessionSecret = env("SESSION_SECRET")
+	cfg.ResultSigningKey = env("RESULT_SIGNING_KEY")
+	if v := env("DEBUG_REDACTION"); v != "" {
+		cfg.DebugRedaction = v
+	}
+	if v := env("LOG_LEVEL"); v != "" {
+		cfg.LogLevel = v
+	}
+	cfg.ProfanityFilter = envBool(env, "PROFANITY_FILTER", cfg.ProfanityFilter)
+	cfg.ProfanityWordList = env("PROFANITY_WORDLIST")
+	cfg.ConfigFile = env("CONFIG_FILE")
+
+	return cfg
+}
+
+// FeatureEnabled reports whether an optional feature is switched on
+func (c Config) FeatureEnabled(feature string) bool {
+	for _, f := range c.DisabledFeatures {
+		if f == feature {
+			return false
+		}
+	}
+	return true
+}
+
+// Features lists the optional features switched on
+func (c Config) Features() []string {
+	features := make([]string, 0, len(protocol.ServerFeatures))
+	for _, f := range protocol.ServerFeatures {
+		if c.FeatureEnabled(f) {
+			features = append(features, f)
+		}
+	}
+	return features
+}
+
+// OriginAllowed reports whether a WebSocket may be opened from origin
+func (c Config) OriginAllowed(origin string) bool {
+	if len(c.AllowedOrigins) == 0 {
+		return true
+	}
+	for _, o := range c.AllowedOrigins {
+		if o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// TrustsProxy reports whether forwarding headers from the peer at ip are believed
+func (c Config) TrustsProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, proxy := range c.TrustedProxies {
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			if prefix.Contains(addr) {
+				return true
+			}
+		} else if trusted, err := netip.ParseAddr(proxy); err == nil && trusted.Unmap() == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// envInt parses a non-negative integer from an environment variable
+func envInt(env func(string) string, key string, fallback int) int {
+	v := env(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return fallback
+	}
+	return n
+}
+
+// envSeconds parses an integer number of seconds from an environment variable
+func envSeconds(env func(string) string, key string, fallback time.Duration) time.Duration {
+	v := env(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return fallback
+	}
+	return time.Duration(n) * time.Second
+}
+
+// envMillis parses an integer number of milliseconds from an environment variable
+func envMillis(env func(string) string, key string, fallback time.Duration) time.Duration {
+	v := env(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return fallback
+	}
+	return time.Duration(n) * time.Millisecond
+}
+
+// envList parses a comma-separated list from an environment variable
+func envList(env func(string) string, key string, fallback []string) []string {
+	v := env(key)
+	if v == "" {
+		return fallback
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// envBool parses a boolean from an environment variable
+func envBool(env func(string) string, key string, fallback bool) bool {
+	v := env(key)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fallback
+	}
+	return b
+}
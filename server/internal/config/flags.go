@@ -0,0 +1,59 @@
+package config
+
+import (
+	"flag"
+)
+
+// RegisterFlags adds a command-line flag for every setting, defaulting to the
+// value already in cfg so flags override the environment
+func RegisterFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.StringVar(&cfg.Port, "port", cfg.Port, "HTTP port to listen on (PORT)")
+	fs.StringVar(&cfg.RedisURL, "redis-url", cfg.RedisURL, "Redis connection URL (REDIS_URL)")
+	fs.Func("history-database-url", "Postgres `URL` finished games are archived to, empty disables match history (HISTORY_DATABASE_URL)", setString(&cfg.HistoryDatabaseURL))
+	fs.DurationVar(&cfg.RedisTimeout, "redis-timeout", cfg.RedisTimeout, "how long a Redis call may take before it fails (REDIS_TIMEOUT_MS)")
+	fs.DurationVar(&cfg.ReconnectGrace, "reconnect-grace", cfg.ReconnectGrace, "how long a disconnected player keeps their seat (RECONNECT_GRACE_SECONDS)")
+	fs.IntVar(&cfg.MaxRoomsPerSession, "max-rooms-per-session", cfg.MaxRoomsPerSession, "rooms one session may create (MAX_ROOMS_PER_SESSION)")
+	fs.DurationVar(&cfg.CreateRoomCooldown, "create-room-cooldown", cfg.CreateRoomCooldown, "minimum time between room creations (CREATE_ROOM_COOLDOWN_SECONDS)")
+	fs.IntVar(&cfg.MessagesPerSecond, "rate-limit-messages-per-second", cfg.MessagesPerSecond, "sustained messages per second per client (RATE_LIMIT_MESSAGES_PER_SECOND)")
+	fs.IntVar(&cfg.MessageBurst, "rate-limit-message-burst", cfg.MessageBurst, "message burst per client (RATE_LIMIT_MESSAGE_BURST)")
+	fs.IntVar(&cfg.ConnectionsPerMinIP, "rate-limit-connections-per-minute", cfg.ConnectionsPerMinIP, "new connections per minute per IP (RATE_LIMIT_CONNECTIONS_PER_MINUTE)")
+	fs.DurationVar(&cfg.ShutdownCountdown, "shutdown-countdown", cfg.ShutdownCountdown, "warning given to clients before shutdown (SHUTDOWN_COUNTDOWN_SECONDS)")
+	fs.DurationVar(&cfg.StoreOutageAlert, "store-outage-alert", cfg.StoreOutageAlert, "Redis downtime before an alert is logged (STORE_OUTAGE_ALERT_SECONDS)")
+	fs.DurationVar(&cfg.RoomSaveInterval, "room-save-interval", cfg.RoomSaveInterval, "write changed rooms to Redis at most this often, 0 writes every change (ROOM_SAVE_INTERVAL_MS)")
+	fs.DurationVar(&cfg.IdleRoomTimeout, "idle-room-timeout", cfg.IdleRoomTimeout, "close rooms idle this long, 0 disables (IDLE_ROOM_TIMEOUT_SECONDS)")
+	fs.DurationVar(&cfg.IdleClientTimeout, "idle-client-timeout", cfg.IdleClientTimeout, "disconnect clients outside a room that send nothing this long, 0 disables (IDLE_CLIENT_TIMEOUT_SECONDS)")
+	fs.IntVar(&cfg.ClientBandwidthBytesPerSec, "client-bandwidth-bytes-per-second", cfg.ClientBandwidthBytesPerSec, "outbound bytes per second per client, 0 is uncapped (CLIENT_BANDWIDTH_BYTES_PER_SECOND)")
+	fs.BoolVar(&cfg.WSCompression, "ws-compression", cfg.WSCompression, "offer permessage-deflate compression to WebSocket clients (WS_COMPRESSION)")
+	fs.Func("admin-token", "bearer `token` for admin endpoints (ADMIN_TOKEN)", setString(&cfg.AdminToken))
+	fs.Func("session-secret", "`key` used to sign session tokens (SESSION_SECRET)", setString(&cfg.SessionSecret))
+	fs.Func("result-signing-key", "base64 Ed25519 `seed` used to sign game results (RESULT_SIGNING_KEY)", setString(&cfg.ResultSigningKey))
+	fs.StringVar(&cfg.DebugRedaction, "debug-redaction", cfg.DebugRedaction, "default redaction for /api/debug: none, partial or full (DEBUG_REDACTION)")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "minimum log level: debug, info, warn or error (LOG_LEVEL)")
+	fs.DurationVar(&cfg.RoomTTL, "room-ttl", cfg.RoomTTL, "how long rooms are kept in Redis (ROOM_TTL_SECONDS)")
+	fs.DurationVar(&cfg.SessionTTL, "session-ttl", cfg.SessionTTL, "how long sessions are kept in Redis (SESSION_TTL_SECONDS)")
+	fs.DurationVar(&cfg.ProfileTTL, "profile-ttl", cfg.ProfileTTL, "how long unused player profiles and wallets are kept (PROFILE_TTL_SECONDS)")
+	fs.DurationVar(&cfg.SlowMessage, "slow-message", cfg.SlowMessage, "log client messages slower than this to handle (SLOW_MESSAGE_MS)")
+	fs.Func("allowed-origins", "comma-separated `origins` allowed to open WebSockets, empty allows any (ALLOWED_ORIGINS)", setList(&cfg.AllowedOrigins))
+	fs.Func("trusted-proxies", "comma-separated proxy `addresses` (IPs or CIDRs) whose forwarded client IPs are believed (TRUSTED_PROXIES)", setList(&cfg.TrustedProxies))
+	fs.Func("disabled-features", "comma-separated optional `features` to switch off (DISABLED_FEATURES)", setList(&cfg.DisabledFeatures))
+	fs.BoolVar(&cfg.ProfanityFilter, "profanity-filter", cfg.ProfanityFilter, "reject player names containing blocked words (PROFANITY_FILTER)")
+	fs.StringVar(&cfg.ProfanityWordList, "profanity-wordlist", cfg.ProfanityWordList, "file of blocked words, one per line, =word to match it exactly (PROFANITY_WORDLIST)")
+	fs.StringVar(&cfg.ConfigFile, "config", cfg.ConfigFile, "env-style file of overrides, re-read on SIGHUP (CONFIG_FILE)")
+}
+
+// setString sets a secret from a flag without showing the current value as its
+// default in -h output
+func setString(dest *string) func(string) error {
+	return func(v string) error {
+		*dest = v
+		return nil
+	}
+}
+
+// setList sets a comma-separated list from a flag
+func setList(dest *[]string) func(string) error {
+	return func(v string) error {
+		*dest = envList(func(string) string { return v }, "", nil)
+		return nil
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Settings only read at startup; a reload keeps their current values
+var restartOnly = map[string]bool{
+	"Port":               true,
+	"RedisURL":           true,
+	"RedisTimeout":       true,
+	"HistoryDatabaseURL": true,
+	"SessionSecret":      true,
+	"ResultSigningKey":   true,
+	"ConfigFile":         true,
+	"WSCompression":      true,
+}
+
+// Live is the configuration in effect, swapped atomically on reload
+// Code that should see reloaded values reads it through Get each time
+type Live struct {
+	current atomic.Pointer[Config]
+	load    func() (Config, error)
+	mu      sync.Mutex // Serializes reloads
+}
+
+// ReloadResult lists the settings a reload changed, and those it left alone
+// because they only take effect on restart
+type ReloadResult struct {
+	Changed         []string `json:"changed"`
+	RequiresRestart []string `json:"requiresRestart,omitempty"`
+}
+
+// NewLive wraps cfg, rebuilding it with load on reload
+func NewLive(cfg Config, load func() (Config, error)) *Live {
+	l := &Live{load: load}
+	l.current.Store(&cfg)
+	return l
+}
+
+// Static wraps a configuration that is never reloaded
+func Static(cfg Config) *Live {
+	return NewLive(cfg, func() (Config, error) { return cfg, nil })
+}
+
+// Get returns the configuration in effect
+func (l *Live) Get() Config {
+	return *l.current.Load()
+}
+
+// Reload rebuilds the configuration from its sources and swaps it in
+// Returns the configuration replaced, so callers can apply what changed
+func (l *Live) Reload() (Config, ReloadResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	previous := l.Get()
+	next, err := l.load()
+	if err != nil {
+		return previous, ReloadResult{}, err
+	}
+
+	result := ReloadResult{Changed: []string{}}
+	prev := reflect.ValueOf(previous)
+	nv := reflect.ValueOf(&next).Elem()
+	for i := 0; i < prev.NumField(); i++ {
+		name := prev.Type().Field(i).Name
+		if reflect.DeepEqual(prev.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		if restartOnly[name] {
+			result.RequiresRestart = append(result.RequiresRestart, name)
+			nv.Field(i).Set(prev.Field(i))
+			continue
+		}
+		result.Changed = append(result.Changed, name)
+	}
+
+	l.current.Store(&next)
+	return previous, result, nil
+}
+
+// LoadFile reads configuration like Load, with the KEY=VALUE lines of an
+// env-style file taking precedence over the environment
+func LoadFile(path string) (Config, error) {
+	if path == "" {
+		return Load(), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return Config{}, fmt.Errorf("%s:%d: expected KEY=VALUE", path, n)
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	if err := scanner.Err(); err != nil {
+		return Config{}, err
+	}
+
+	cfg := load(func(key string) string {
+		if v, ok := values[key]; ok {
+			return v
+		}
+		return os.Getenv(key)
+	})
+	cfg.ConfigFile = path
+	return cfg, nil
+}
@@ -0,0 +1,73 @@
+package game
+
+import (
+	"slapjack/pkg/protocol"
+)
+
+// PlayerAFK is a player struck out for letting too many turns in a row time
+// out; their hand goes under the pile and they are out of the game
+type PlayerAFK struct {
+	PlayerID       string
+	Strikes        int
+	CardsForfeited int
+	PileCount      int
+}
+
+func (e PlayerAFK) Message() []byte {
+	return encodeEvent(protocol.PlayerAFK, protocol.PlayerAFKPayload{
+		PlayerID:       e.PlayerID,
+		Strikes:        e.Strikes,
+		CardsForfeited: e.CardsForfeited,
+		PileCount:      e.PileCount,
+	})
+}
+
+func (e PlayerAFK) replay() (protocol.ReplayEvent, bool) {
+	return protocol.ReplayEvent{Type: ReplayAFK, PlayerID: e.PlayerID, Count: e.CardsForfeited}, true
+}
+
+// timeOut handles the current player's turn running out: the card is played
+// for them, unless that was their last strike
+// Caller must hold g.mu and have checked the player can play
+func (g *Game) timeOut(playerID string) {
+	g.timeoutStrikes[playerID]++
+	if g.AFKStrikes > 0 && g.timeoutStrikes[playerID] >= g.AFKStrikes {
+		g.strikeOut(playerID)
+		return
+	}
+	g.play(playerID, true)
+}
+
+// strikeOut puts a player's hand under the pile and takes them out of the game
+// Caller must hold g.mu
+func (g *Game) strikeOut(playerID string) {
+	hand := g.PlayerHands[playerID]
+	g.Pile = append(append(make([]Card, 0, len(hand)+len(g.Pile)), hand...), g.Pile...)
+	g.PlayerHands[playerID] = nil
+	g.AFK[playerID] = true
+
+	g.emit(PlayerAFK{
+		PlayerID:       playerID,
+		Strikes:        g.timeoutStrikes[playerID],
+		CardsForfeited: len(hand),
+		PileCount:      len(g.Pile),
+	})
+	g.eliminate(playerID)
+
+	// A challenge carries on against the next player, as when a challenger runs out
+	g.turnToken++
+	g.advanceTurn()
+}
+
+// clearStrikes forgets a player's timeouts once they act for themselves
+// Caller must hold g.mu
+func (g *Game) clearStrikes(playerID string) {
+	delete(g.timeoutStrikes, playerID)
+}
+
+// IsAFK reports whether a player was struck out for timing out
+func (g *Game) IsAFK(playerID string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.AFK[playerID]
+}
@@ -0,0 +1,59 @@
+package game
+
+import (
+	"slapjack/pkg/protocol"
+)
+
+// Announcer thresholds
+const (
+	comebackHandCards = 3  // Most cards a comeback can start from
+	comebackPileCards = 10 // Fewest cards the comeback pile must hold
+	lastPlayersLeft   = 2
+)
+
+// announce emits an announcer event for clients to call out
+// Caller must hold g.mu
+func (g *Game) announce(kind, playerID string, count int) {
+	g.emit(Announced{Kind: kind, PlayerID: playerID, Count: count})
+}
+
+// announcePlay calls out the pile growing past bigPileCards, once a pile
+// Caller must hold g.mu
+func (g *Game) announcePlay() {
+	if len(g.Pile) >= bigPileCards && !g.bigPileAnnounced {
+		g.bigPileAnnounced = true
+		g.announce(protocol.AnnounceBigPile, "", len(g.Pile))
+	}
+}
+
+// announcePileWon calls out the game's first pile won, and a player low on
+// cards winning a big one back
+// Call once the win's result has been emitted, so the call follows it
+// Caller must hold g.mu
+func (g *Game) announcePileWon(playerID string, cardsWon int) {
+	piles := 0
+	for _, n := range g.pilesWon {
+		piles += n
+	}
+	if piles == 1 {
+		g.announce(protocol.AnnounceFirstBlood, playerID, cardsWon)
+	}
+	held := len(g.PlayerHands[playerID]) - cardsWon
+	if held <= comebackHandCards && cardsWon >= comebackPileCards {
+		g.announce(protocol.AnnounceComeback, playerID, cardsWon)
+	}
+}
+
+// eliminate takes a player out, calling out the moment only two players are
+// left standing, once a game; team games are decided before then
+// Caller must hold g.mu
+func (g *Game) eliminate(playerID string) {
+	g.eliminationsSeen[playerID] = true
+	g.emit(PlayerEliminated{PlayerID: playerID})
+
+	left := len(g.TurnOrder) - len(g.eliminationsSeen)
+	if g.Teams == nil && !g.lastTwoAnnounced && len(g.TurnOrder) > lastPlayersLeft && left == lastPlayersLeft {
+		g.lastTwoAnnounced = true
+		g.announce(protocol.AnnounceLastTwo, "", left)
+	}
+}
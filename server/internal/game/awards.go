@@ -0,0 +1,85 @@
+package game
+
+import "slapjack/pkg/protocol"
+
+// Award keys
+const (
+	AwardFastestHands = "fastest_hands"
+	AwardMostReckless = "most_reckless"
+	AwardComebackKid  = "comeback_kid"
+)
+
+// ComputeAwards picks the end-of-game superlatives
+// Awards nobody qualified for are left out
+func (g *Game) ComputeAwards() []protocol.Award {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	awards := make([]protocol.Award, 0, 3)
+
+	fastest := make(map[string]int64)
+	burned := make(map[string]int)
+	for id, p := range g.Stats.Players {
+		if p.Successes > 0 {
+			fastest[id] = p.FastestMs
+		}
+		burned[id] = p.CardsBurned
+	}
+
+	// Fastest Hands - quickest successful slap
+	if id, ms, ok := minEntry(g.TurnOrder, fastest); ok {
+		awards = append(awards, protocol.Award{
+			Key:         AwardFastestHands,
+			Title:       "Fastest Hands",
+			Description: "Quickest successful slap",
+			PlayerID:    id,
+			Value:       ms,
+		})
+	}
+
+	// Most Reckless - most cards burned on bad slaps
+	if id, burned, ok := maxEntry(g.TurnOrder, burned); ok {
+		awards = append(awards, protocol.Award{
+			Key:         AwardMostReckless,
+			Title:       "Most Reckless",
+			Description: "Most cards burned on bad slaps",
+			PlayerID:    id,
+			Value:       int64(burned),
+		})
+	}
+
+	// Comeback Kid - most slap-ins used
+	if id, slapIns, ok := maxEntry(g.TurnOrder, g.SlapInCounts); ok {
+		awards = append(awards, protocol.Award{
+			Key:         AwardComebackKid,
+			Title:       "Comeback Kid",
+			Description: "Most slap-ins after running out of cards",
+			PlayerID:    id,
+			Value:       int64(slapIns),
+		})
+	}
+
+	return awards
+}
+
+// maxEntry returns the player with the highest positive value, in turn order for ties
+func maxEntry(order []string, values map[string]int) (string, int, bool) {
+	bestID, best := "", 0
+	for _, id := range order {
+		if v := values[id]; v > best {
+			bestID, best = id, v
+		}
+	}
+	return bestID, best, bestID != ""
+}
+
+// minEntry returns the player with the lowest recorded value, in turn order for ties
+func minEntry(order []string, values map[string]int64) (string, int64, bool) {
+	bestID, best, found := "", int64(0), false
+	for _, id := range order {
+		if v, ok := values[id]; ok && (!found || v < best) {
+			bestID, best, found = id, v, true
+		}
+	}
+	return bestID, best, found
+}
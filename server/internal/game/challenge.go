@@ -0,0 +1,102 @@
+package game
+
+// Game modes
+const (
+	ModeClassic  = "classic"
+	ModeRatscrew = "ratscrew" // Egyptian Ratscrew face-card challenges
+)
+
+// ChallengeUpdate describes how a play changed the face-card challenge
+type ChallengeUpdate struct {
+	Started      bool
+	Won          bool
+	OwnerID      string
+	ChallengerID string
+	Chances      int
+	CardsWon     int
+}
+
+// challengeChances returns how many cards the next player gets to answer a face card
+// Returns 0 for number cards
+func challengeChances(c Card) int {
+	switch c.Rank {
+	case "A":
+		return 4
+	case "K":
+		return 3
+	case "Q":
+		return 2
+	case "J":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// afterPlay moves the turn on after a card is played, applying face-card
+// challenge rules in ratscrew mode
+// Caller must hold g.mu
+func (g *Game) afterPlay(playerID string, card Card) *ChallengeUpdate {
+	g.turnToken++
+	if g.Mode != ModeRatscrew {
+		g.advanceTurn()
+		return nil
+	}
+
+	// A face card starts a new challenge against the next player
+	if chances := challengeChances(card); chances > 0 {
+		g.ChallengeOwner = playerID
+		g.ChancesRemaining = chances
+		g.advanceTurn()
+		g.emit(ChallengeStarted{
+			OwnerID:      playerID,
+			ChallengerID: g.TurnOrder[g.CurrentTurnIdx],
+			Chances:      chances,
+		})
+		return &ChallengeUpdate{
+			Started:      true,
+			OwnerID:      playerID,
+			ChallengerID: g.TurnOrder[g.CurrentTurnIdx],
+			Chances:      chances,
+		}
+	}
+
+	if g.ChallengeOwner == "" {
+		g.advanceTurn()
+		return nil
+	}
+
+	// The challenged player keeps playing until they run out of chances
+	g.ChancesRemaining--
+	if g.ChancesRemaining > 0 {
+		if len(g.PlayerHands[playerID]) == 0 {
+			g.advanceTurn()
+		}
+		return nil
+	}
+
+	// Challenge failed - the owner takes the pile and plays next
+	owner := g.ChallengeOwner
+	cardsWon := g.collectPile(owner)
+	g.SlapWindowOpen = false
+	g.clearChallenge()
+	g.LastSlapWinner = owner
+	g.emit(ChallengeWon{OwnerID: owner, CardsWon: cardsWon})
+	g.emitCardCounts()
+	g.announcePileWon(owner, cardsWon)
+	delete(g.eliminationsSeen, owner)
+	g.setTurn(owner)
+
+	return &ChallengeUpdate{
+		Won:      true,
+		OwnerID:  owner,
+		CardsWon: cardsWon,
+	}
+}
+
+// clearChallenge ends any active challenge
+// Caller must hold g.mu
+func (g *Game) clearChallenge() {
+	g.ChallengeOwner = ""
+	g.ChancesRemaining = 0
+}
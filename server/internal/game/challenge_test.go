@@ -0,0 +1,95 @@
+package game
+
+import "testing"
+
+func TestChallengeChances(t *testing.T) {
+	tests := []struct {
+		card string
+		want int
+	}{
+		{"Ah", 4},
+		{"Kh", 3},
+		{"Qh", 2},
+		{"Jh", 1},
+		{"10h", 0},
+		{"2h", 0},
+	}
+
+	for _, tt := range tests {
+		if got := challengeChances(card(tt.card)); got != tt.want {
+			t.Errorf("challengeChances(%s) = %d, want %d", tt.card, got, tt.want)
+		}
+	}
+}
+
+func TestChallengeWon(t *testing.T) {
+	g := newTestGame(t, Options{Mode: ModeRatscrew}, cards("Qh", "3h"), cards("2d", "4d", "5d"), cards("6c"))
+
+	played, challenge, err := g.PlayCard("p1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if challenge == nil || !challenge.Started || challenge.ChallengerID != "p2" || challenge.Chances != 2 {
+		t.Fatalf("playing %v started %+v, want a two-chance challenge against p2", played, challenge)
+	}
+
+	// p2 keeps the turn while they have chances left
+	if _, challenge, _ := g.PlayCard("p2", 0); challenge != nil {
+		t.Fatalf("first answer resolved the challenge: %+v", challenge)
+	}
+	if got := g.GetCurrentPlayer(); got != "p2" {
+		t.Fatalf("current player %s, want p2 to keep answering", got)
+	}
+
+	_, challenge, _ = g.PlayCard("p2", 0)
+	if challenge == nil || !challenge.Won || challenge.OwnerID != "p1" || challenge.CardsWon != 3 {
+		t.Fatalf("failed answer = %+v, want p1 to win 3 cards", challenge)
+	}
+	if got := g.GetCurrentPlayer(); got != "p1" {
+		t.Errorf("current player %s, want the challenge owner", got)
+	}
+	if got := g.GetPlayerCardCount("p1"); got != 4 {
+		t.Errorf("p1 has %d cards, want 4", got)
+	}
+	if g.ChallengeOwner != "" || g.ChancesRemaining != 0 {
+		t.Errorf("challenge still active: owner %q, %d chances", g.ChallengeOwner, g.ChancesRemaining)
+	}
+}
+
+func TestChallengeAnswered(t *testing.T) {
+	g := newTestGame(t, Options{Mode: ModeRatscrew}, cards("Jh", "3h"), cards("Kd", "4d"), cards("6c", "7c"))
+	mustPlay(t, g, "p1")
+
+	// Answering a jack with a king turns the challenge on the next player
+	_, challenge, _ := g.PlayCard("p2", 0)
+	if challenge == nil || !challenge.Started || challenge.OwnerID != "p2" || challenge.ChallengerID != "p3" || challenge.Chances != 3 {
+		t.Fatalf("answer = %+v, want a three-chance challenge from p2 against p3", challenge)
+	}
+}
+
+func TestChallengerRunsOut(t *testing.T) {
+	g := newTestGame(t, Options{Mode: ModeRatscrew}, cards("Ah", "3h"), cards("2d"), cards("6c", "7c"))
+	mustPlay(t, g, "p1")
+	mustPlay(t, g, "p2")
+
+	// p2 is out of cards with chances left, so the challenge moves on
+	if got := g.GetCurrentPlayer(); got != "p3" {
+		t.Errorf("current player %s, want p3", got)
+	}
+}
+
+func TestSlapEndsChallenge(t *testing.T) {
+	g := newTestGame(t, Options{Mode: ModeRatscrew, Rules: Rules{EnableDoubles: true}}, cards("Kh", "3h"), cards("Kd", "4d"), cards("6c", "7c"))
+	mustPlay(t, g, "p1")
+	mustPlay(t, g, "p2")
+
+	if result, _ := g.ProcessSlap("p3", 0, 0); !result.Success {
+		t.Fatalf("slap on doubles = %+v", result)
+	}
+	if g.ChallengeOwner != "" {
+		t.Errorf("challenge owner %q after the pile was slapped", g.ChallengeOwner)
+	}
+	if got := g.GetCurrentPlayer(); got != "p3" {
+		t.Errorf("current player %s, want the slapper", got)
+	}
+}
@@ -0,0 +1,187 @@
+package game
+
+import (
+	"fmt"
+	"time"
+
+	"slapjack/internal/clock"
+	"slapjack/pkg/protocol"
+)
+
+// Suspicion kinds
+const (
+	SuspicionPassive    = "passive_toward_player" // Never contests piles a specific opponent wins
+	SuspicionCoordFalse = "coordinated_false_slaps"
+)
+
+const (
+	// A player must sit out this many of an opponent's wins before being flagged
+	minPassiveOpportunities = 5
+	// ...while contesting at least this share of everyone else's piles
+	minOtherAttemptRate   = 0.5
+	minOtherOpportunities = 4
+
+	// False slaps this close together on the same card count as coordinated
+	coordFalseSlapWindow = 300 * time.Millisecond
+	minCoordFalseSlaps   = 3
+)
+
+// collusionTracker watches slap patterns between pairs of players
+// Flags are surfaced for review, never acted on automatically
+type collusionTracker struct {
+	// Current play
+	attempts   map[string]bool
+	falseSlaps map[string]time.Time
+	winner     string
+
+	// Slappable piles each player could have contested, and how many they did,
+	// in total and per pile winner
+	opportunities   map[string]int
+	attemptsTotal   map[string]int
+	opportunitiesBy map[string]map[string]int
+	attemptsBy      map[string]map[string]int
+
+	// False slaps landing together on the same card, per pair
+	coordFalse map[string]int
+
+	flagged map[string]bool
+	pending []protocol.SuspicionFlag
+
+	clock clock.Clock
+}
+
+func newCollusionTracker(clk clock.Clock) *collusionTracker {
+	return &collusionTracker{
+		clock:           clk,
+		attempts:        make(map[string]bool),
+		falseSlaps:      make(map[string]time.Time),
+		opportunities:   make(map[string]int),
+		attemptsTotal:   make(map[string]int),
+		opportunitiesBy: make(map[string]map[string]int),
+		attemptsBy:      make(map[string]map[string]int),
+		coordFalse:      make(map[string]int),
+		flagged:         make(map[string]bool),
+	}
+}
+
+// slap records a slap attempt on the current play
+func (t *collusionTracker) slap(playerID string, valid bool) {
+	t.attempts[playerID] = true
+	if valid {
+		if t.winner == "" {
+			t.winner = playerID
+		}
+		return
+	}
+
+	// Late slaps after the pile was won aren't false slaps for this purpose
+	if t.winner != "" {
+		return
+	}
+
+	now := t.clock.Now()
+	for otherID, at := range t.falseSlaps {
+		if otherID != playerID && now.Sub(at) <= coordFalseSlapWindow {
+			t.coordinatedFalseSlap(playerID, otherID)
+		}
+	}
+	t.falseSlaps[playerID] = now
+}
+
+func (t *collusionTracker) coordinatedFalseSlap(a, b string) {
+	if b < a {
+		a, b = b, a
+	}
+	key := a + ":" + b
+	t.coordFalse[key]++
+	if t.coordFalse[key] >= minCoordFalseSlaps {
+		t.flag(SuspicionCoordFalse, a, b, fmt.Sprintf("%d false slaps landed within %dms of each other", t.coordFalse[key], coordFalseSlapWindow.Milliseconds()))
+	}
+}
+
+// endPlay closes out the current play before the next card hits the pile
+// contenders are the players who could have slapped
+func (t *collusionTracker) endPlay(contenders []string) {
+	if t.winner != "" {
+		for _, id := range contenders {
+			if id == t.winner {
+				continue
+			}
+			if t.opportunitiesBy[id] == nil {
+				t.opportunitiesBy[id] = make(map[string]int)
+				t.attemptsBy[id] = make(map[string]int)
+			}
+			t.opportunities[id]++
+			t.opportunitiesBy[id][t.winner]++
+			if t.attempts[id] {
+				t.attemptsTotal[id]++
+				t.attemptsBy[id][t.winner]++
+			}
+			t.checkPassive(id, t.winner)
+		}
+	}
+
+	t.attempts = make(map[string]bool)
+	t.falseSlaps = make(map[string]time.Time)
+	t.winner = ""
+}
+
+// checkPassive flags a player who never contests a specific opponent's piles
+// while actively contesting everyone else's
+func (t *collusionTracker) checkPassive(playerID, winnerID string) {
+	passed := t.opportunitiesBy[playerID][winnerID]
+	if passed < minPassiveOpportunities || t.attemptsBy[playerID][winnerID] > 0 {
+		return
+	}
+
+	otherOpps := t.opportunities[playerID] - passed
+	if otherOpps < minOtherOpportunities {
+		return
+	}
+	otherRate := float64(t.attemptsTotal[playerID]) / float64(otherOpps)
+	if otherRate < minOtherAttemptRate {
+		return
+	}
+
+	t.flag(SuspicionPassive, playerID, winnerID, fmt.Sprintf("never slapped on %d piles won by this player, but slapped on %.0f%% of others", passed, otherRate*100))
+}
+
+// flag queues a suspicion once per kind and pair
+func (t *collusionTracker) flag(kind, playerID, otherID, detail string) {
+	key := kind + ":" + playerID + ":" + otherID
+	if t.flagged[key] {
+		return
+	}
+	t.flagged[key] = true
+	t.pending = append(t.pending, protocol.SuspicionFlag{
+		Kind:          kind,
+		PlayerID:      playerID,
+		OtherPlayerID: otherID,
+		Detail:        detail,
+		Timestamp:     t.clock.Now().UnixMilli(),
+	})
+}
+
+// endPlay closes out collusion and contention tracking for the previous card
+// Caller must hold g.mu
+func (g *Game) endPlay() {
+	g.cardSlappers = make(map[string]bool)
+
+	contenders := make([]string, 0, len(g.TurnOrder))
+	for _, id := range g.TurnOrder {
+		if len(g.PlayerHands[id]) > 0 && !g.Disconnected[id] {
+			contenders = append(contenders, id)
+		}
+	}
+	g.collusion.endPlay(contenders)
+}
+
+// DrainSuspicions returns suspicion flags raised since the last call
+func (g *Game) DrainSuspicions() []protocol.SuspicionFlag {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	flags := g.collusion.pending
+	g.collusion.pending = nil
+	return flags
+}
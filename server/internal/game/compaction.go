@@ -0,0 +1,97 @@
+package game
+
+import (
+	"slapjack/pkg/protocol"
+)
+
+const (
+	// A checkpoint is taken after this many replay events
+	checkpointEvery = 200
+
+	// Once the replay grows past maxLogEvents, everything before the latest
+	// checkpoint that still leaves keepLogEvents is dropped
+	maxLogEvents  = 1000
+	keepLogEvents = 400
+)
+
+// Checkpoint is a snapshot of the game taken as it goes, so the log before it
+// can be dropped and a replay can start from it instead
+type Checkpoint struct {
+	Snapshot protocol.ReplaySnapshot
+}
+
+func (e Checkpoint) Message() []byte { return nil }
+
+func (e Checkpoint) replay() (protocol.ReplayEvent, bool) {
+	snapshot := e.Snapshot
+	return protocol.ReplayEvent{Type: ReplayCheckpoint, Snapshot: &snapshot}, true
+}
+
+// snapshot captures the card counts, pile and turn
+// Caller must hold g.mu
+func (g *Game) snapshot() protocol.ReplaySnapshot {
+	s := protocol.ReplaySnapshot{
+		CardCounts: g.cardCounts(),
+		PileCount:  len(g.Pile),
+	}
+	if len(g.TurnOrder) > 0 {
+		s.CurrentPlayerID = g.TurnOrder[g.CurrentTurnIdx]
+	}
+	return s
+}
+
+// checkpoint takes a checkpoint once enough events have been recorded since
+// the last, then compacts the log if it has outgrown its bound
+// Caller must hold g.mu
+func (g *Game) checkpoint() {
+	if g.sinceCheckpoint < checkpointEvery {
+		return
+	}
+	g.sinceCheckpoint = 0
+	g.emit(Checkpoint{Snapshot: g.snapshot()})
+
+	if len(g.Replay) > maxLogEvents {
+		g.compact()
+	}
+}
+
+// compact drops the events and replay entries before the latest checkpoint
+// that leaves at least keepLogEvents after it
+// Caller must hold g.mu
+func (g *Game) compact() {
+	cut := -1
+	for i := len(g.Replay) - keepLogEvents; i > 0; i-- {
+		if g.Replay[i].Type == ReplayCheckpoint {
+			cut = i
+			break
+		}
+	}
+	if cut <= 0 {
+		return
+	}
+
+	// Checkpoints are emitted in the same order as their replay entries, so
+	// the one to keep is the one with as many checkpoints after it
+	after := 0
+	for _, e := range g.Replay[cut+1:] {
+		if e.Type == ReplayCheckpoint {
+			after++
+		}
+	}
+	eventCut := -1
+	for i := len(g.events) - 1; i >= 0; i-- {
+		if _, ok := g.events[i].(Checkpoint); !ok {
+			continue
+		}
+		if after == 0 {
+			eventCut = i
+			break
+		}
+		after--
+	}
+
+	g.Replay = append([]protocol.ReplayEvent(nil), g.Replay[cut:]...)
+	if eventCut > 0 {
+		g.events = append([]GameEvent(nil), g.events[eventCut:]...)
+	}
+}
@@ -0,0 +1,52 @@
+package game
+
+import (
+	"time"
+
+	"slapjack/pkg/protocol"
+)
+
+// Slaps landing this long after the winning one are no longer racing for the
+// same pile, so aren't listed as contenders
+const slapContentionWindow = time.Second
+
+// slapContenders lists the winning slap on the current card first, then the
+// others that landed within the contention window of it in arrival order,
+// with how far behind the winner each arrived; slaps that arrived first but
+// lost on compensated reaction time are ahead of it
+// Returns nil until the card has been won, or if playerID wasn't in the race
+// Caller must hold g.mu
+func (g *Game) slapContenders(playerID string) []protocol.SlapContender {
+	winner := -1
+	for i, attempt := range g.PendingSlaps {
+		if attempt.Won {
+			winner = i
+			break
+		}
+	}
+	if winner < 0 {
+		return nil
+	}
+
+	won := g.PendingSlaps[winner]
+	contenders := []protocol.SlapContender{{PlayerID: won.PlayerID}}
+	included := won.PlayerID == playerID
+	for i, attempt := range g.PendingSlaps {
+		delta := attempt.ServerTimestamp - won.ServerTimestamp
+		if i == winner || delta < -slapContentionWindow.Milliseconds() {
+			continue
+		}
+		if delta > slapContentionWindow.Milliseconds() {
+			break
+		}
+		contenders = append(contenders, protocol.SlapContender{
+			PlayerID: attempt.PlayerID,
+			DeltaMs:  delta,
+		})
+		included = included || attempt.PlayerID == playerID
+	}
+	if !included {
+		return nil
+	}
+	return contenders
+}
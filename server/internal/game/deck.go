@@ -1,6 +1,8 @@
 package game
 
 import (
+	"encoding/json"
+	"fmt"
 	"math/rand"
 	"time"
 
@@ -55,6 +57,18 @@ func (c Card) RankValue() int {
 	}
 }
 
+// PipValue returns the face value of a number card (aces count as one)
+// Returns 0 for face cards
+func (c Card) PipValue() int {
+	if c.Rank == "A" {
+		return 1
+	}
+	if v := c.RankValue(); v <= 10 {
+		return v
+	}
+	return 0
+}
+
 // IsJack returns true if the card is a Jack
 func (c Card) IsJack() bool {
 	return c.Rank == "J"
@@ -68,17 +82,122 @@ type Deck struct {
 	cards []Card
 }
 
-// NewDeck creates a new standard 52-card deck
-func NewDeck() *Deck {
+// RankSet is a set of card ranks, one bit per rank in ranks order
+// The empty set stands for every rank, so the zero value is a full deck
+type RankSet uint16
+
+// allRanks has every rank's bit set
+var allRanks = RankSet(1)<<len(ranks) - 1
+
+// ParseRankSet builds a rank set from rank names, returning false if any
+// isn't a rank; every rank, or none, gives the empty set
+func ParseRankSet(names []string) (RankSet, bool) {
+	var set RankSet
+	for _, name := range names {
+		i := rankIndex(name)
+		if i < 0 {
+			return 0, false
+		}
+		set |= 1 << i
+	}
+	if set == allRanks {
+		set = 0
+	}
+	return set, true
+}
+
+// rankIndex returns a rank's position in ranks, or -1 if it isn't one
+func rankIndex(rank string) int {
+	for i, r := range ranks {
+		if r == rank {
+			return i
+		}
+	}
+	return -1
+}
+
+// Has reports whether the set includes a rank
+func (s RankSet) Has(rank string) bool {
+	i := rankIndex(rank)
+	return i >= 0 && (s == 0 || s&(1<<i) != 0)
+}
+
+// Ranks returns the set's ranks, ace low
+func (s RankSet) Ranks() []string {
+	var names []string
+	for _, rank := range ranks {
+		if s.Has(rank) {
+			names = append(names, rank)
+		}
+	}
+	return names
+}
+
+// Len returns the number of ranks in the set
+func (s RankSet) Len() int {
+	return len(s.Ranks())
+}
+
+// MarshalJSON writes the set as its list of ranks
+func (s RankSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Ranks())
+}
+
+// UnmarshalJSON reads the set from a list of ranks
+func (s *RankSet) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	set, ok := ParseRankSet(names)
+	if !ok {
+		return fmt.Errorf("unknown rank in %v", names)
+	}
+	*s = set
+	return nil
+}
+
+// DeckSpec describes a custom deck: which ranks each suit has, and how
+// many Jacks are added on top
+// The zero value is a standard 52-card deck
+type DeckSpec struct {
+	Ranks      RankSet `json:"ranks"`
+	ExtraJacks int     `json:"extraJacks"`
+}
+
+// Size returns the number of cards in numDecks decks made to the spec
+func (spec DeckSpec) Size(numDecks int) int {
+	return numDecks*len(suits)*spec.Ranks.Len() + spec.ExtraJacks
+}
+
+// NewDeck creates a single deck made to the spec
+func NewDeck(spec DeckSpec) *Deck {
+	return NewMultiDeck(1, spec)
+}
+
+// NewMultiDeck creates a deck made of several decks made to the spec
+// combined, with the spec's extra Jacks added once, suits in turn
+func NewMultiDeck(numDecks int, spec DeckSpec) *Deck {
+	if numDecks < 1 {
+		numDecks = 1
+	}
+
 	deck := &Deck{
-		cards: make([]Card, 0, 52),
+		cards: make([]Card, 0, spec.Size(numDecks)),
 	}
 
-	for _, suit := range suits {
-		for _, rank := range ranks {
-			deck.cards = append(deck.cards, Card{Suit: suit, Rank: rank})
+	for i := 0; i < numDecks; i++ {
+		for _, suit := range suits {
+			for _, rank := range ranks {
+				if spec.Ranks.Has(rank) {
+					deck.cards = append(deck.cards, Card{Suit: suit, Rank: rank})
+				}
+			}
 		}
 	}
+	for i := 0; i < spec.ExtraJacks; i++ {
+		deck.cards = append(deck.cards, Card{Suit: suits[i%len(suits)], Rank: "J"})
+	}
 
 	return deck
 }
@@ -96,7 +215,7 @@ func (d *Deck) Shuffle() {
 func (d *Deck) Deal(numPlayers int) [][]Card {
 	hands := make([][]Card, numPlayers)
 	for i := range hands {
-		hands[i] = make([]Card, 0, 52/numPlayers+1)
+		hands[i] = make([]Card, 0, len(d.cards)/numPlayers+1)
 	}
 
 	for i, card := range d.cards {
@@ -107,6 +226,27 @@ func (d *Deck) Deal(numPlayers int) [][]Card {
 	return hands
 }
 
+// DealEven gives every player the same number of cards
+// Returns the hands and the cards left over
+func (d *Deck) DealEven(numPlayers int) ([][]Card, []Card) {
+	perPlayer := len(d.cards) / numPlayers
+	dealt := perPlayer * numPlayers
+
+	hands := make([][]Card, numPlayers)
+	for i := range hands {
+		hands[i] = make([]Card, 0, perPlayer)
+	}
+
+	for i, card := range d.cards[:dealt] {
+		playerIdx := i % numPlayers
+		hands[playerIdx] = append(hands[playerIdx], card)
+	}
+
+	remainder := make([]Card, len(d.cards)-dealt)
+	copy(remainder, d.cards[dealt:])
+	return hands, remainder
+}
+
 // Cards returns all cards in the deck
 func (d *Deck) Cards() []Card {
 	return d.cards
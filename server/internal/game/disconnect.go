@@ -0,0 +1,30 @@
+package game
+
+import "time"
+
+// What happens to a disconnected player's turns while they may still reconnect
+const (
+	DisconnectedSkip     = "skip"     // Passed over until they are back
+	DisconnectedAutoPlay = "autoplay" // Their top card is played for them
+)
+
+// How long a disconnected player's turn lasts before their card is played for them
+const disconnectedTurnTimeout = 2 * time.Second
+
+// skipsDisconnected reports whether disconnected players' turns are passed over
+func (g *Game) skipsDisconnected() bool {
+	return g.DisconnectedTurns != DisconnectedAutoPlay
+}
+
+// sittingOut reports whether a player's turns are passed over
+// Caller must hold g.mu
+func (g *Game) sittingOut(playerID string) bool {
+	return g.Disconnected[playerID] && g.skipsDisconnected()
+}
+
+// IsDisconnected reports whether a player is disconnected
+func (g *Game) IsDisconnected(playerID string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.Disconnected[playerID]
+}
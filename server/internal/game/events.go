@@ -0,0 +1,300 @@
+package game
+
+import (
+	"slapjack/pkg/protocol"
+)
+
+// GameEvent is one change to a game. Every mutation emits its events into the
+// game's log, and the broadcasts and replay are derived from them, so nothing
+// outside the game needs to know how a change is encoded
+type GameEvent interface {
+	// Message encodes the event's broadcast, or returns nil for events that
+	// aren't broadcast
+	Message() []byte
+
+	// replay converts the event to its replay log entry, if it has one
+	replay() (protocol.ReplayEvent, bool)
+}
+
+// Dealt is a player's starting hand, or with no player the deal's remainder
+type Dealt struct {
+	PlayerID  string
+	Count     int
+	Remainder string // Where the remainder went, for remainder events
+}
+
+func (e Dealt) Message() []byte { return nil }
+
+func (e Dealt) replay() (protocol.ReplayEvent, bool) {
+	return protocol.ReplayEvent{Type: ReplayDeal, PlayerID: e.PlayerID, Reason: e.Remainder, Count: e.Count}, true
+}
+
+// CardPlayed is a card going from a player's hand onto the pile
+type CardPlayed struct {
+	PlayerID  string
+	Card      Card
+	PileCount int
+	Timeout   bool // Played by the turn timer
+}
+
+func (e CardPlayed) Message() []byte {
+	return encodeEvent(protocol.CardPlayed, protocol.CardPlayedPayload{
+		PlayerID:  e.PlayerID,
+		Card:      e.Card.ToProtocol(),
+		PileCount: e.PileCount,
+	})
+}
+
+func (e CardPlayed) replay() (protocol.ReplayEvent, bool) {
+	card := e.Card.ToProtocol()
+	event := protocol.ReplayEvent{Type: ReplayPlay, PlayerID: e.PlayerID, Card: &card, Count: e.PileCount}
+	if e.Timeout {
+		event.Reason = "timeout"
+	}
+	return event, true
+}
+
+// SlapResolved is the outcome of a slap, including those turned away for the
+// cooldown or because the player is out
+type SlapResolved struct {
+	Result protocol.SlapResultPayload
+
+	// Whether the slap was judged against the pile; only those are replayed
+	judged bool
+}
+
+func (e SlapResolved) Message() []byte {
+	return encodeEvent(protocol.SlapResult, e.Result)
+}
+
+func (e SlapResolved) replay() (protocol.ReplayEvent, bool) {
+	return protocol.ReplayEvent{
+		Type:     ReplaySlap,
+		PlayerID: e.Result.PlayerID,
+		Reason:   e.Result.Reason,
+		Count:    e.Result.CardsWon,
+	}, e.judged
+}
+
+// SlapReplayed is a winning slap as clients replay it in slow motion
+type SlapReplayed struct {
+	Replay protocol.SlapReplayPayload
+}
+
+func (e SlapReplayed) Message() []byte {
+	return encodeEvent(protocol.SlapReplay, e.Replay)
+}
+
+func (e SlapReplayed) replay() (protocol.ReplayEvent, bool) {
+	return protocol.ReplayEvent{}, false
+}
+
+// PileDiscarded is an unwon pile at the pile cap being removed from play as
+// a player went to play onto it
+type PileDiscarded struct {
+	PlayerID string
+	Count    int
+}
+
+func (e PileDiscarded) Message() []byte {
+	return encodeEvent(protocol.PileDiscarded, protocol.PileDiscardedPayload{
+		PlayerID: e.PlayerID,
+		Count:    e.Count,
+	})
+}
+
+func (e PileDiscarded) replay() (protocol.ReplayEvent, bool) {
+	return protocol.ReplayEvent{Type: ReplayDiscard, PlayerID: e.PlayerID, Count: e.Count}, true
+}
+
+// PenaltyApplied is cards burned from a player's hand for a false slap
+type PenaltyApplied struct {
+	PlayerID    string
+	Cards       []Card
+	Destination string
+	RecipientID string // Who was given the cards, for BurnToWinner
+	PileCount   int
+}
+
+func (e PenaltyApplied) Message() []byte {
+	cards := make([]protocol.Card, len(e.Cards))
+	for i, c := range e.Cards {
+		cards[i] = c.ToProtocol()
+	}
+	return encodeEvent(protocol.CardsBurned, protocol.CardsBurnedPayload{
+		PlayerID:    e.PlayerID,
+		Cards:       cards,
+		Destination: e.Destination,
+		RecipientID: e.RecipientID,
+		PileCount:   e.PileCount,
+	})
+}
+
+func (e PenaltyApplied) replay() (protocol.ReplayEvent, bool) {
+	return protocol.ReplayEvent{Type: ReplayBurn, PlayerID: e.PlayerID, Reason: e.Destination, Count: len(e.Cards)}, true
+}
+
+// CardCountsChanged is the card counts after a slap or penalty moved cards
+type CardCountsChanged struct {
+	Counts    map[string]int
+	PileCount int
+}
+
+func (e CardCountsChanged) Message() []byte {
+	return encodeEvent(protocol.CardCountsUpdated, protocol.CardCountsUpdatedPayload{
+		PlayerCardCounts: e.Counts,
+		PileCount:        e.PileCount,
+	})
+}
+
+func (e CardCountsChanged) replay() (protocol.ReplayEvent, bool) {
+	return protocol.ReplayEvent{}, false
+}
+
+// PlayerEliminated is a player running out of cards with no slap to win back in
+type PlayerEliminated struct {
+	PlayerID string
+}
+
+func (e PlayerEliminated) Message() []byte {
+	return encodeEvent(protocol.PlayerEliminated, protocol.PlayerEliminatedPayload{PlayerID: e.PlayerID})
+}
+
+func (e PlayerEliminated) replay() (protocol.ReplayEvent, bool) {
+	return protocol.ReplayEvent{Type: ReplayEliminate, PlayerID: e.PlayerID}, true
+}
+
+// Announced is an announcer call on a turn of the game, for clients to play
+// the same sound or banner for
+type Announced struct {
+	Kind     string
+	PlayerID string
+	Count    int
+}
+
+func (e Announced) Message() []byte {
+	return encodeEvent(protocol.Announce, protocol.AnnouncePayload{
+		Kind:     e.Kind,
+		PlayerID: e.PlayerID,
+		Count:    e.Count,
+	})
+}
+
+func (e Announced) replay() (protocol.ReplayEvent, bool) {
+	return protocol.ReplayEvent{}, false
+}
+
+// ChallengeStarted is a face card setting the next player a challenge
+type ChallengeStarted struct {
+	OwnerID      string
+	ChallengerID string
+	Chances      int
+}
+
+func (e ChallengeStarted) Message() []byte {
+	return encodeEvent(protocol.ChallengeStarted, protocol.ChallengeStartedPayload{
+		OwnerID:      e.OwnerID,
+		ChallengerID: e.ChallengerID,
+		Chances:      e.Chances,
+	})
+}
+
+func (e ChallengeStarted) replay() (protocol.ReplayEvent, bool) {
+	return protocol.ReplayEvent{}, false
+}
+
+// ChallengeWon is a challenge's owner taking the pile after it went unanswered
+type ChallengeWon struct {
+	OwnerID  string
+	CardsWon int
+}
+
+func (e ChallengeWon) Message() []byte {
+	return encodeEvent(protocol.ChallengeWon, protocol.ChallengeWonPayload{
+		PlayerID: e.OwnerID,
+		CardsWon: e.CardsWon,
+	})
+}
+
+func (e ChallengeWon) replay() (protocol.ReplayEvent, bool) {
+	return protocol.ReplayEvent{Type: ReplayChallenge, PlayerID: e.OwnerID, Count: e.CardsWon}, true
+}
+
+// GameOver is the game being won
+// Its broadcast needs the room's player names, so it is built by the caller
+type GameOver struct {
+	WinnerID string
+}
+
+func (e GameOver) Message() []byte { return nil }
+
+func (e GameOver) replay() (protocol.ReplayEvent, bool) {
+	return protocol.ReplayEvent{Type: ReplayGameOver, PlayerID: e.WinnerID}, true
+}
+
+// GameEnded is the game being stopped before anyone won
+type GameEnded struct {
+	Reason string
+}
+
+func (e GameEnded) Message() []byte { return nil }
+
+func (e GameEnded) replay() (protocol.ReplayEvent, bool) {
+	return protocol.ReplayEvent{Type: ReplayEnded, Reason: e.Reason}, true
+}
+
+func encodeEvent(msgType string, payload interface{}) []byte {
+	data, err := protocol.EncodePayload(payload)
+	if err != nil {
+		return nil
+	}
+	return protocol.EncodeMessage(msgType, data)
+}
+
+// emit appends an event to the log, queues it for broadcast and adds it to the replay
+// Caller must hold g.mu
+func (g *Game) emit(e GameEvent) {
+	g.events = append(g.events, e)
+	g.unsent = append(g.unsent, e)
+
+	event, ok := e.replay()
+	if !ok {
+		return
+	}
+	g.recorded++
+	event.Seq = g.recorded
+	event.Timestamp = g.clock.Now().UnixMilli()
+	g.Replay = append(g.Replay, event)
+
+	if event.Type == ReplayPlay {
+		g.notePilePlay(event.PlayerID, *event.Card, event.Count, event.Reason == "timeout", event.Timestamp)
+	}
+	if event.Type != ReplayCheckpoint {
+		g.sinceCheckpoint++
+		g.checkpoint()
+	}
+}
+
+// Events returns a copy of the events the game has emitted since its last
+// compaction, in order
+func (g *Game) Events() []GameEvent {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return append([]GameEvent{}, g.events...)
+}
+
+// DrainMessages returns the broadcasts for events emitted since the last call, in order
+func (g *Game) DrainMessages() [][]byte {
+	g.mu.Lock()
+	unsent := g.unsent
+	g.unsent = nil
+	g.mu.Unlock()
+
+	messages := make([][]byte, 0, len(unsent))
+	for _, e := range unsent {
+		if msgData := e.Message(); msgData != nil {
+			messages = append(messages, msgData)
+		}
+	}
+	return messages
+}
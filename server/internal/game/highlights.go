@@ -0,0 +1,89 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"slapjack/pkg/protocol"
+)
+
+// Highlight kinds
+const (
+	HighlightBigPile    = "big_pile"          // A huge pile changed hands
+	HighlightFastSlap   = "lightning_slap"    // A winning slap faster than the eye can follow
+	HighlightContention = "triple_contention" // Three or more players went for the same card
+)
+
+const (
+	bigPileCards       = 20
+	fastSlapMs         = 150
+	contentionSlappers = 3
+)
+
+// highlight records a notable moment for the game's highlights and queues it for broadcast
+// Caller must hold g.mu
+func (g *Game) highlight(kind, playerID, detail string, value int64) {
+	h := protocol.Highlight{
+		Kind:      kind,
+		PlayerID:  playerID,
+		Detail:    detail,
+		Value:     value,
+		Timestamp: g.clock.Now().UnixMilli(),
+	}
+	g.Highlights = append(g.Highlights, h)
+	g.pendingHighlights = append(g.pendingHighlights, h)
+}
+
+// noteCardWon checks a collected pile for a highlight
+// Caller must hold g.mu
+func (g *Game) noteCardWon(playerID string, cardsWon int) {
+	if cardsWon > bigPileCards {
+		g.highlight(HighlightBigPile, playerID, fmt.Sprintf("won a pile of %d cards", cardsWon), int64(cardsWon))
+	}
+}
+
+// noteSlapper tracks who has gone for the top card, noting the moment enough players pile in
+// Caller must hold g.mu
+func (g *Game) noteSlapper(playerID string) {
+	if g.cardSlappers[playerID] {
+		return
+	}
+	g.cardSlappers[playerID] = true
+	if len(g.cardSlappers) == contentionSlappers {
+		g.highlight(HighlightContention, "", fmt.Sprintf("%d players slapped the same card", contentionSlappers), contentionSlappers)
+	}
+}
+
+// noteFastSlap checks a winning slap's reaction time for a highlight
+// Caller must hold g.mu
+func (g *Game) noteFastSlap(playerID string, reaction time.Duration) {
+	if ms := reaction.Milliseconds(); ms < fastSlapMs {
+		g.highlight(HighlightFastSlap, playerID, fmt.Sprintf("won the pile in %dms", ms), ms)
+	}
+}
+
+// GetHighlights returns the game's highlights so far
+func (g *Game) GetHighlights() []protocol.Highlight {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return append([]protocol.Highlight{}, g.Highlights...)
+}
+
+// DrainHighlights returns HIGHLIGHT messages for moments since the last call
+func (g *Game) DrainHighlights() [][]byte {
+	g.mu.Lock()
+	pending := g.pendingHighlights
+	g.pendingHighlights = nil
+	g.mu.Unlock()
+
+	messages := make([][]byte, 0, len(pending))
+	for _, h := range pending {
+		msgData, _ := json.Marshal(protocol.NewMessage(protocol.HighlightMsg, protocol.HighlightPayload{
+			GameID:    g.ID,
+			Highlight: h,
+		}))
+		messages = append(messages, msgData)
+	}
+	return messages
+}
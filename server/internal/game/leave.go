@@ -0,0 +1,139 @@
+package game
+
+import (
+	"slapjack/pkg/protocol"
+)
+
+// What happens to the hand of a player who leaves a game for good
+const (
+	LeaverCardsDeal    = "deal"    // Dealt one at a time to the players holding cards, from the next in turn
+	LeaverCardsPile    = "pile"    // Under the pile
+	LeaverCardsDiscard = "discard" // Out of play
+)
+
+// CardsRedistributed is the hand of a player who left the game being dealt
+// out, put under the pile or taken out of play
+type CardsRedistributed struct {
+	PlayerID    string
+	Destination string
+	Count       int
+	Dealt       map[string]int // Cards each player was given, for LeaverCardsDeal
+	Counts      map[string]int
+	PileCount   int
+}
+
+func (e CardsRedistributed) Message() []byte {
+	return encodeEvent(protocol.CardsRedistributed, protocol.CardsRedistributedPayload{
+		PlayerID:    e.PlayerID,
+		Destination: e.Destination,
+		Count:       e.Count,
+		Dealt:       e.Dealt,
+		PlayerCards: e.Counts,
+		PileCount:   e.PileCount,
+	})
+}
+
+func (e CardsRedistributed) replay() (protocol.ReplayEvent, bool) {
+	return protocol.ReplayEvent{Type: ReplayLeave, PlayerID: e.PlayerID, Reason: e.Destination, Count: e.Count}, true
+}
+
+// RemovePlayer takes a player who has left for good out of the game and its
+// turn order, handling their hand as the game's LeaverCards says. They are
+// gone from then on, without an elimination of their own.
+// Returns true if the turn moved on because it was theirs
+func (g *Game) RemovePlayer(playerID string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	hand, ok := g.PlayerHands[playerID]
+	if !ok || g.over {
+		return false
+	}
+	delete(g.PlayerHands, playerID)
+	delete(g.Disconnected, playerID)
+	if g.LastSlapWinner == playerID {
+		g.LastSlapWinner = ""
+	}
+
+	destination := g.LeaverCards
+	recipients := g.leaverRecipients(playerID)
+	if destination == LeaverCardsDeal && len(recipients) == 0 {
+		destination = LeaverCardsPile
+	}
+
+	event := CardsRedistributed{PlayerID: playerID, Destination: destination, Count: len(hand)}
+	switch destination {
+	case LeaverCardsDeal:
+		event.Dealt = make(map[string]int, len(recipients))
+		for i, c := range hand {
+			id := recipients[i%len(recipients)]
+			g.PlayerHands[id] = append(g.PlayerHands[id], c)
+			event.Dealt[id]++
+		}
+	case LeaverCardsPile:
+		g.Pile = append(append(make([]Card, 0, len(hand)+len(g.Pile)), hand...), g.Pile...)
+	}
+	event.Counts = g.cardCounts()
+	event.PileCount = len(g.Pile)
+	g.emit(event)
+
+	return g.removeFromTurnOrder(playerID)
+}
+
+// removeFromTurnOrder takes a player out of the turn order, keeping the turn
+// with whoever had it, or passing it on if it was theirs; a single side left
+// wins, see lastStanding
+// Returns true if the turn moved on
+// Caller must hold g.mu
+func (g *Game) removeFromTurnOrder(playerID string) bool {
+	idx := -1
+	for i, id := range g.TurnOrder {
+		if id == playerID {
+			idx = i
+		}
+	}
+	if idx < 0 || len(g.TurnOrder) < 2 {
+		return false
+	}
+
+	// A fresh slice, as the turn order may share its array with the caller's
+	// player IDs
+	order := make([]string, 0, len(g.TurnOrder)-1)
+	order = append(order, g.TurnOrder[:idx]...)
+	g.TurnOrder = append(order, g.TurnOrder[idx+1:]...)
+
+	switch {
+	case idx < g.CurrentTurnIdx:
+		g.CurrentTurnIdx--
+		return false
+	case idx > g.CurrentTurnIdx:
+		return false
+	}
+
+	// Advance from the player before them, so the one after them is next
+	g.turnToken++
+	g.CurrentTurnIdx = (idx + len(g.TurnOrder) - 1) % len(g.TurnOrder)
+	g.advanceTurn()
+	return true
+}
+
+// leaverRecipients returns the other players who still hold cards, in turn
+// order from the one after playerID
+// Caller must hold g.mu
+func (g *Game) leaverRecipients(playerID string) []string {
+	start := 0
+	for i, id := range g.TurnOrder {
+		if id == playerID {
+			start = i + 1
+		}
+	}
+
+	var recipients []string
+	for i := range g.TurnOrder {
+		id := g.TurnOrder[(start+i)%len(g.TurnOrder)]
+		if id != playerID && len(g.PlayerHands[id]) > 0 {
+			recipients = append(recipients, id)
+		}
+	}
+	return recipients
+}
@@ -0,0 +1,73 @@
+package game
+
+import (
+	"time"
+)
+
+// Pacing shortens turn timeouts as the pile grows, so tension ramps up, and
+// gives a little extra time on the turn after a pile is claimed
+type Pacing struct {
+	MinTimeoutMs int // Shortest timeout, reached once the pile holds RampCards cards
+	RampCards    int // Pile size at which the timeout bottoms out
+	ClaimBonusMs int // Extra time on the first turn after a pile is claimed
+}
+
+// AutoPace shortens turn timeouts as the game goes on, so long endgames
+// don't drag: the timeout is divided by one more every StepTurns cards
+// played and for every player out, as in 10s, 5s, 3.3s, down to FloorMs
+type AutoPace struct {
+	StepTurns int
+	FloorMs   int
+}
+
+// baseTimeout returns the turn timeout before pile pacing, shortened for how
+// far the game has gone under auto pacing
+// Caller must hold g.mu
+func (g *Game) baseTimeout() time.Duration {
+	base := time.Duration(g.TurnTimeoutMs) * time.Millisecond
+	a := g.AutoPace
+	if a == nil || a.StepTurns <= 0 {
+		return base
+	}
+	steps := 1 + g.plays/a.StepTurns + len(g.eliminationsSeen)
+	floor := time.Duration(a.FloorMs) * time.Millisecond
+	return min(base, max(floor, base/time.Duration(steps)))
+}
+
+// turnTimeout returns the timeout for the turn starting now
+// Caller must hold g.mu
+func (g *Game) turnTimeout() time.Duration {
+	base := g.baseTimeout()
+	if g.Disconnected[g.TurnOrder[g.CurrentTurnIdx]] {
+		return min(base, disconnectedTurnTimeout)
+	}
+	p := g.Pacing
+	if p == nil {
+		return base
+	}
+	if g.pileClaimed {
+		return base + time.Duration(p.ClaimBonusMs)*time.Millisecond
+	}
+
+	floor := time.Duration(p.MinTimeoutMs) * time.Millisecond
+	if floor >= base || p.RampCards <= 0 {
+		return base
+	}
+	cards := len(g.Pile)
+	if cards >= p.RampCards {
+		return floor
+	}
+	return base - (base-floor)*time.Duration(cards)/time.Duration(p.RampCards)
+}
+
+// TurnTimeout returns the effective timeout for the current turn
+func (g *Game) TurnTimeout() time.Duration {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.turnTimeout()
+}
+
+// Paced reports whether turn timeouts adapt to the pile or the game's progress
+func (g *Game) Paced() bool {
+	return g.Pacing != nil || g.AutoPace != nil
+}
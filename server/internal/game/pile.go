@@ -0,0 +1,56 @@
+package game
+
+import (
+	"slapjack/pkg/protocol"
+)
+
+// Recent plays kept for the observer view
+const pileHistoryLimit = 20
+
+// notePilePlay adds a play to the recent pile history
+// Caller must hold g.mu
+func (g *Game) notePilePlay(playerID string, card protocol.Card, pileSize int, timeout bool, timestamp int64) {
+	g.pileHistory = append(g.pileHistory, protocol.PilePlay{
+		PlayerID:  playerID,
+		Card:      card,
+		PileSize:  pileSize,
+		Timeout:   timeout,
+		Timestamp: timestamp,
+	})
+	g.pilePlays++
+	if len(g.pileHistory) > pileHistoryLimit {
+		g.pileHistory = g.pileHistory[len(g.pileHistory)-pileHistoryLimit:]
+	}
+}
+
+// discardCappedPile removes the pile from play once it has reached the pile
+// cap without anyone winning it, before the next card goes down
+// Caller must hold g.mu
+func (g *Game) discardCappedPile(playerID string) {
+	if g.PileCap <= 0 || len(g.Pile) < g.PileCap {
+		return
+	}
+	discarded := len(g.Pile) + len(g.FaceDown)
+	g.Pile = make([]Card, 0, g.PileCap+1)
+	g.FaceDown = nil
+	g.pilePlays = 0
+	g.bigPileAnnounced = false
+	g.emit(PileDiscarded{PlayerID: playerID, Count: discarded})
+}
+
+// GetObserverState returns the game state with the full pile and recent plays,
+// so spectators can follow sandwich and doubles calls
+// Every card on the pile was played face up, so this reveals nothing hidden
+func (g *Game) GetObserverState() protocol.GameStatePayload {
+	state := g.GetState()
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	state.FullPile = make([]protocol.Card, len(g.Pile))
+	for i, card := range g.Pile {
+		state.FullPile[i] = card.ToProtocol()
+	}
+	state.PileHistory = append([]protocol.PilePlay{}, g.pileHistory...)
+	return state
+}
@@ -0,0 +1,155 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"slapjack/internal/clock"
+	"slapjack/pkg/protocol"
+)
+
+// Cheat warning kinds
+const (
+	CheatTooFast      = "reaction_too_fast"    // Reactions faster than people can manage
+	CheatZeroVariance = "reaction_no_variance" // Reactions too consistent to be human
+)
+
+const (
+	// Slaps needed before a player's reactions are judged
+	minReactionSamples = 10
+	// Most recent reactions kept per player
+	maxReactionSamples = 100
+	// Slaps later than this after a card aren't reactions to it
+	maxReactionTime = 5 * time.Second
+
+	// A median below this is faster than human reaction plus network delay
+	implausibleMedianMs = 100
+	// Human reactions vary by tens of milliseconds; scripts barely vary at all
+	minReactionStdDevMs = 8.0
+)
+
+// reactionTracker records how quickly each player slaps after a card is played
+// Warnings are surfaced for review, never acted on automatically
+type reactionTracker struct {
+	samples map[string][]int64
+
+	flagged map[string]bool
+	pending []protocol.CheatWarning
+
+	clock clock.Clock
+}
+
+func newReactionTracker(clk clock.Clock) *reactionTracker {
+	return &reactionTracker{
+		clock:   clk,
+		samples: make(map[string][]int64),
+		flagged: make(map[string]bool),
+	}
+}
+
+// add records one reaction time and checks the player's distribution
+func (t *reactionTracker) add(playerID string, reaction time.Duration) {
+	if reaction < 0 || reaction > maxReactionTime {
+		return
+	}
+
+	samples := append(t.samples[playerID], reaction.Milliseconds())
+	if len(samples) > maxReactionSamples {
+		samples = samples[len(samples)-maxReactionSamples:]
+	}
+	t.samples[playerID] = samples
+
+	if len(samples) < minReactionSamples {
+		return
+	}
+
+	analysis := analyzeReactions(playerID, samples)
+	if analysis.MedianMs < implausibleMedianMs {
+		t.flag(CheatTooFast, analysis, fmt.Sprintf("median reaction of %dms over %d slaps", analysis.MedianMs, analysis.Samples))
+	}
+	if analysis.StdDevMs < minReactionStdDevMs {
+		t.flag(CheatZeroVariance, analysis, fmt.Sprintf("reaction times vary by only %.1fms over %d slaps", analysis.StdDevMs, analysis.Samples))
+	}
+}
+
+// flag queues a warning once per kind and player
+func (t *reactionTracker) flag(kind string, analysis protocol.ReactionAnalysis, detail string) {
+	key := kind + ":" + analysis.PlayerID
+	if t.flagged[key] {
+		return
+	}
+	t.flagged[key] = true
+	t.pending = append(t.pending, protocol.CheatWarning{
+		Kind:      kind,
+		PlayerID:  analysis.PlayerID,
+		Detail:    detail,
+		Analysis:  analysis,
+		Timestamp: t.clock.Now().UnixMilli(),
+	})
+}
+
+// analyzeReactions summarizes a player's reaction time distribution
+func analyzeReactions(playerID string, samples []int64) protocol.ReactionAnalysis {
+	sorted := append([]int64(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum float64
+	for _, ms := range sorted {
+		sum += float64(ms)
+	}
+	mean := sum / float64(len(sorted))
+
+	var variance float64
+	for _, ms := range sorted {
+		variance += (float64(ms) - mean) * (float64(ms) - mean)
+	}
+	variance /= float64(len(sorted))
+
+	return protocol.ReactionAnalysis{
+		PlayerID: playerID,
+		Samples:  len(sorted),
+		MinMs:    sorted[0],
+		MedianMs: sorted[len(sorted)/2],
+		MeanMs:   mean,
+		StdDevMs: math.Sqrt(variance),
+	}
+}
+
+// ReactionAnalysis summarizes every player's slap reaction times so far
+func (g *Game) ReactionAnalysis() []protocol.ReactionAnalysis {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	result := make([]protocol.ReactionAnalysis, 0, len(g.reactions.samples))
+	for _, id := range g.TurnOrder {
+		if samples := g.reactions.samples[id]; len(samples) > 0 {
+			result = append(result, analyzeReactions(id, samples))
+		}
+	}
+	return result
+}
+
+// PlayerReactions summarizes one player's slap reaction times so far
+// Returns false if they haven't slapped a card yet
+func (g *Game) PlayerReactions(playerID string) (protocol.ReactionAnalysis, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	samples := g.reactions.samples[playerID]
+	if len(samples) == 0 {
+		return protocol.ReactionAnalysis{}, false
+	}
+	return analyzeReactions(playerID, samples), true
+}
+
+// DrainCheatWarnings returns cheat warnings raised since the last call
+func (g *Game) DrainCheatWarnings() []protocol.CheatWarning {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	warnings := g.reactions.pending
+	g.reactions.pending = nil
+	return warnings
+}
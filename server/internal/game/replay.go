@@ -0,0 +1,54 @@
+package game
+
+import (
+	"slapjack/pkg/protocol"
+)
+
+// Replay event types
+const (
+	ReplayDeal       = "deal"
+	ReplayPlay       = "play"
+	ReplaySlap       = "slap"
+	ReplayBurn       = "burn"
+	ReplayDiscard    = "discard"
+	ReplayEliminate  = "eliminate"
+	ReplayAFK        = "afk"
+	ReplayLeave      = "leave"
+	ReplayChallenge  = "challenge"
+	ReplayGameOver   = "game_over"
+	ReplayEnded      = "ended"
+	ReplayCheckpoint = "checkpoint"
+)
+
+// RecordGameOver records the end of the game with the winning player
+// Returns false if the game was already over, as when its clock ran out
+// just as the last pile was won
+func (g *Game) RecordGameOver(winnerID string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.over {
+		return false
+	}
+	g.over = true
+	g.emit(GameOver{WinnerID: winnerID})
+	return true
+}
+
+// RecordEnded records the game being ended early
+func (g *Game) RecordEnded(reason string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.over = true
+	g.emit(GameEnded{Reason: reason})
+}
+
+// GetReplay returns a copy of the recorded events
+// A compacted replay starts at a checkpoint, whose Seq is one past the events dropped
+func (g *Game) GetReplay() []protocol.ReplayEvent {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	events := make([]protocol.ReplayEvent, len(g.Replay))
+	copy(events, g.Replay)
+	return events
+}
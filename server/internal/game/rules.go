@@ -4,16 +4,24 @@ package game
 type SlapReason string
 
 const (
-	SlapReasonJack     SlapReason = "jack"
-	SlapReasonDoubles  SlapReason = "doubles"
-	SlapReasonSandwich SlapReason = "sandwich"
-	SlapReasonInvalid  SlapReason = "invalid"
+	SlapReasonJack      SlapReason = "jack"
+	SlapReasonDoubles   SlapReason = "doubles"
+	SlapReasonSandwich  SlapReason = "sandwich"
+	SlapReasonMarriage  SlapReason = "marriage"
+	SlapReasonTopBottom SlapReason = "top_bottom"
+	SlapReasonRun       SlapReason = "run"
+	SlapReasonTens      SlapReason = "tens"
+	SlapReasonInvalid   SlapReason = "invalid"
 )
 
 // Rules handles slap validation
 type Rules struct {
-	EnableDoubles  bool
-	EnableSandwich bool
+	EnableDoubles   bool
+	EnableSandwich  bool
+	EnableMarriage  bool // King and Queen on top of each other
+	EnableTopBottom bool // Top card matches the bottom card of the pile
+	EnableRuns      bool // Top three cards form an ascending or descending run
+	EnableTens      bool // Top two cards add up to ten
 }
 
 // NewRules creates a new Rules instance
@@ -31,28 +39,79 @@ func (r *Rules) CheckSlap(pile []Card) SlapReason {
 		return SlapReasonInvalid
 	}
 
+	top := pile[len(pile)-1]
+
 	// Check for Jack (top card is Jack)
-	if pile[len(pile)-1].IsJack() {
+	if top.IsJack() {
 		return SlapReasonJack
 	}
 
 	// Check for Doubles (top two cards have same rank)
 	if r.EnableDoubles && len(pile) >= 2 {
-		if pile[len(pile)-1].Rank == pile[len(pile)-2].Rank {
+		if top.Rank == pile[len(pile)-2].Rank {
 			return SlapReasonDoubles
 		}
 	}
 
 	// Check for Sandwich (cards at positions 0 and 2 from top have same rank)
 	if r.EnableSandwich && len(pile) >= 3 {
-		if pile[len(pile)-1].Rank == pile[len(pile)-3].Rank {
+		if top.Rank == pile[len(pile)-3].Rank {
 			return SlapReasonSandwich
 		}
 	}
 
+	// Check for Marriage (King and Queen adjacent on top)
+	if r.EnableMarriage && len(pile) >= 2 {
+		pair := top.Rank + pile[len(pile)-2].Rank
+		if pair == "KQ" || pair == "QK" {
+			return SlapReasonMarriage
+		}
+	}
+
+	// Check for Tens (top two number cards sum to ten, aces count as one)
+	if r.EnableTens && len(pile) >= 2 {
+		a, b := top.PipValue(), pile[len(pile)-2].PipValue()
+		if a > 0 && b > 0 && a+b == 10 {
+			return SlapReasonTens
+		}
+	}
+
+	// Check for Runs (top three cards are consecutive, either direction)
+	if r.EnableRuns && len(pile) >= 3 {
+		if isRun(pile[len(pile)-3], pile[len(pile)-2], top) {
+			return SlapReasonRun
+		}
+	}
+
+	// Check for Top-Bottom (top card matches the bottom card)
+	if r.EnableTopBottom && len(pile) >= 3 {
+		if top.Rank == pile[0].Rank {
+			return SlapReasonTopBottom
+		}
+	}
+
 	return SlapReasonInvalid
 }
 
+// isRun returns true if three cards form a consecutive ascending or descending sequence
+// Aces may be played high (Q-K-A) or low (A-2-3)
+func isRun(a, b, c Card) bool {
+	for _, aceLow := range []bool{false, true} {
+		x, y, z := runValue(a, aceLow), runValue(b, aceLow), runValue(c, aceLow)
+		if (y == x+1 && z == y+1) || (y == x-1 && z == y-1) {
+			return true
+		}
+	}
+	return false
+}
+
+func runValue(c Card, aceLow bool) int {
+	if aceLow && c.Rank == "A" {
+		return 1
+	}
+	return c.RankValue()
+}
+
 // IsValidSlap returns true if the current pile state allows a valid slap
 func (r *Rules) IsValidSlap(pile []Card) bool {
 	return r.CheckSlap(pile) != SlapReasonInvalid
@@ -0,0 +1,105 @@
+package game
+
+import "testing"
+
+func TestCheckSlap(t *testing.T) {
+	all := Rules{
+		EnableDoubles:   true,
+		EnableSandwich:  true,
+		EnableMarriage:  true,
+		EnableTopBottom: true,
+		EnableRuns:      true,
+		EnableTens:      true,
+	}
+
+	tests := []struct {
+		name  string
+		rules Rules
+		pile  []Card
+		want  SlapReason
+	}{
+		{"empty pile", all, nil, SlapReasonInvalid},
+		{"jack alone", Rules{}, cards("Jh"), SlapReasonJack},
+		{"jack beats doubles", all, cards("Js", "Jh"), SlapReasonJack},
+		{"jack under the top", all, cards("Jh", "4c"), SlapReasonInvalid},
+
+		{"doubles", Rules{EnableDoubles: true}, cards("5h", "5c"), SlapReasonDoubles},
+		{"doubles off", Rules{}, cards("5h", "5c"), SlapReasonInvalid},
+		{"doubles beat sandwich", all, cards("5d", "5h", "5c"), SlapReasonDoubles},
+
+		{"sandwich", Rules{EnableSandwich: true}, cards("5h", "9c", "5s"), SlapReasonSandwich},
+		{"sandwich off", Rules{EnableDoubles: true}, cards("5h", "9c", "5s"), SlapReasonInvalid},
+		{"sandwich needs three cards", Rules{EnableSandwich: true}, cards("9c", "5s"), SlapReasonInvalid},
+
+		{"marriage king on queen", Rules{EnableMarriage: true}, cards("Qh", "Kh"), SlapReasonMarriage},
+		{"marriage queen on king", Rules{EnableMarriage: true}, cards("Kc", "Qd"), SlapReasonMarriage},
+		{"marriage off", Rules{}, cards("Kc", "Qd"), SlapReasonInvalid},
+
+		{"tens", Rules{EnableTens: true}, cards("3h", "7s"), SlapReasonTens},
+		{"tens with an ace", Rules{EnableTens: true}, cards("Ah", "9s"), SlapReasonTens},
+		{"tens ignores face cards", Rules{EnableTens: true}, cards("Kh", "10s"), SlapReasonInvalid},
+		{"tens off", Rules{}, cards("3h", "7s"), SlapReasonInvalid},
+
+		{"run ascending", Rules{EnableRuns: true}, cards("4h", "5s", "6d"), SlapReasonRun},
+		{"run descending", Rules{EnableRuns: true}, cards("6h", "5s", "4d"), SlapReasonRun},
+		{"run ace high", Rules{EnableRuns: true}, cards("Qh", "Ks", "Ad"), SlapReasonRun},
+		{"run ace low", Rules{EnableRuns: true}, cards("Ah", "2s", "3d"), SlapReasonRun},
+		{"run doesn't wrap", Rules{EnableRuns: true}, cards("Kh", "As", "2d"), SlapReasonInvalid},
+		{"run off", Rules{}, cards("4h", "5s", "6d"), SlapReasonInvalid},
+
+		{"top bottom", Rules{EnableTopBottom: true}, cards("8h", "2s", "4d", "8c"), SlapReasonTopBottom},
+		{"top bottom needs three cards", Rules{EnableTopBottom: true}, cards("8h", "8c"), SlapReasonInvalid},
+		{"top bottom off", Rules{}, cards("8h", "2s", "4d", "8c"), SlapReasonInvalid},
+
+		{"nothing to slap", all, cards("2h", "9s", "5d"), SlapReasonInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := tt.rules
+			if got := rules.CheckSlap(tt.pile); got != tt.want {
+				t.Errorf("CheckSlap(%v) = %q, want %q", tt.pile, got, tt.want)
+			}
+			if got := rules.IsValidSlap(tt.pile); got != (tt.want != SlapReasonInvalid) {
+				t.Errorf("IsValidSlap(%v) = %v, want %v", tt.pile, got, !got)
+			}
+		})
+	}
+}
+
+// TestCheckSlapDoublesSandwichMatrix checks every doubles/sandwich combination
+// against piles that only one of them, both, or neither would allow
+func TestCheckSlapDoublesSandwichMatrix(t *testing.T) {
+	piles := []struct {
+		name     string
+		pile     []Card
+		doubles  bool
+		sandwich bool
+	}{
+		{"doubles only", cards("2c", "7h", "7s"), true, false},
+		{"sandwich only", cards("7h", "2c", "7s"), false, true},
+		{"both", cards("7d", "7h", "7s"), true, true},
+		{"neither", cards("7h", "2c", "9s"), false, false},
+	}
+
+	for _, doubles := range []bool{false, true} {
+		for _, sandwich := range []bool{false, true} {
+			rules := NewRules(doubles, sandwich)
+			for _, p := range piles {
+				want := SlapReasonInvalid
+				switch {
+				case doubles && p.doubles:
+					want = SlapReasonDoubles
+				case sandwich && p.sandwich:
+					want = SlapReasonSandwich
+				}
+				if got := rules.CheckSlap(p.pile); got != want {
+					t.Errorf("doubles=%v sandwich=%v, %s: got %q, want %q", doubles, sandwich, p.name, got, want)
+				}
+				if got := rules.CheckSlap(append(p.pile, card("Jd"))); got != SlapReasonJack {
+					t.Errorf("doubles=%v sandwich=%v, %s with a jack on top: got %q", doubles, sandwich, p.name, got)
+				}
+			}
+		}
+	}
+}
@@ -3,9 +3,13 @@ package game
 import (
 	"encoding/json"
 	"errors"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
+
+	"slapjack/internal/clock"
 	"slapjack/pkg/protocol"
 )
 
@@ -14,10 +18,27 @@ type SlapAttempt struct {
 	PlayerID        string
 	ServerTimestamp int64
 	ClientTimestamp int64
+	Reaction        time.Duration // From the top card landing, less latency and input lag; 0 on an empty pile
+	Won             bool          // This slap won the pile
 }
 
+// Burn destinations
+const (
+	BurnToPile    = "pile"    // Bottom of the pile
+	BurnToDiscard = "discard" // Out of play
+	BurnToWinner  = "winner"  // To the player who last won a pile
+)
+
+// Deal remainder handling, for decks that don't divide evenly
+const (
+	RemainderDeal    = "deal"    // Extra cards go to the first players
+	RemainderPile    = "pile"    // Extra cards start face-down on the pile
+	RemainderDiscard = "discard" // Extra cards are removed from play
+)
+
 // Game represents the game state
 type Game struct {
+	ID             string
 	PlayerHands    map[string][]Card
 	Pile           []Card
 	TurnOrder      []string
@@ -27,39 +48,176 @@ type Game struct {
 	SlapCooldownMs int
 	TurnTimeoutMs  int
 
+	// Adaptive turn timeouts; nil keeps every turn at TurnTimeoutMs
+	Pacing      *Pacing
+	AutoPace    *AutoPace
+	pileClaimed bool // The pile was claimed since the last card was played
+	plays       int  // Cards played so far, for auto pacing
+
+	// The newest turn timer, which supersedes any still running, and when it
+	// fires; zero when no timer is running
+	timerGen      uint64
+	timerDeadline time.Time
+
+	// Where burned cards go, and who last won a pile (for BurnToWinner)
+	BurnDestination string
+	LastSlapWinner  string
+
+	// Escalating penalties for repeated false slaps
+	EscalatePenalty bool
+	FalseSlapStreak map[string]int
+
+	// Remainder cards from an exact-even deal, face-down under the pile
+	// until someone collects it
+	FaceDown      []Card
+	DiscardedDeal int
+
+	// Pile size at which the next play discards the pile instead of adding
+	// to it; 0 for no cap
+	PileCap int
+
 	// Slap-in settings
 	EnableSlapIn bool
 	MaxSlapIns   int
 	SlapInCounts map[string]int // Track how many times each player has slapped back in
 
 	// Slap handling
+	Latency        map[string]time.Duration // Round-trip latency per player, subtracted from reaction times
+	InputLag       map[string]time.Duration // Calibrated device input lag per player, also subtracted
 	LastSlapTime   map[string]time.Time
+	LastPlayTime   time.Time // When the top card hit the pile, for reaction times
 	PendingSlaps   []SlapAttempt
 	SlapWindowOpen bool
 	SlapMu         sync.Mutex
 
-	// Turn timer
+	// How long valid slaps on a card are collected before it is awarded, and
+	// those collected so far; 0 awards it to the first
+	SlapWindow time.Duration
+	race       *slapRace
+
+	// Face-card challenge state (ratscrew mode)
+	Mode             string
+	ChallengeOwner   string
+	ChancesRemaining int
+
+	// Team of each player in a team game, whose players take turns in
+	// alternation and are only out once their whole team is; nil otherwise
+	Teams map[string]int
+
+	// How the game is won, and the piles each player has won towards it
+	Win      WinCondition
+	pilesWon map[string]int
+
+	// Set once the game has been won or ended, which stops its turn timer
+	over bool
+
+	// Announcer calls already made for the current pile, and the game
+	bigPileAnnounced bool
+	lastTwoAnnounced bool
+
+	// Disconnected players, who keep their hand; their turns are passed
+	// over or played for them, as DisconnectedTurns says
+	Disconnected      map[string]bool
+	DisconnectedTurns string
+
+	// What happens to the hands of players who leave the game for good
+	LeaverCards string
+
+	// Turn timer, and timeouts in a row per player; at AFKStrikes the player
+	// is struck out into AFK and can't slap back in
 	TurnTimerCancel chan struct{}
+	AFKStrikes      int
+	timeoutStrikes  map[string]int
+	AFK             map[string]bool
 
-	// Stats
-	Stats          *GameStats
-	StartTime      time.Time
+	// Changes whenever a play is accepted or the turn moves, so a repeated
+	// PLAY_CARD for a turn that has passed can be told apart from a new one
+	turnToken int64
+
+	// Last TURN_CHANGED payload, reused while the turn hasn't moved
+	turnChanged protocol.PayloadCache
 
-	mu sync.RWMutex
+	// Stats
+	Stats     *GameStats
+	StartTime time.Time
+
+	// Every event the game has emitted since the log was last compacted, and
+	// those not yet broadcast
+	events []GameEvent
+	unsent []GameEvent
+
+	// Replay log, derived from the events and compacted with them
+	Replay           []protocol.ReplayEvent
+	recorded         int // Replay events ever recorded, compacted or not
+	sinceCheckpoint  int
+	eliminationsSeen map[string]bool
+
+	// Slap patterns between players, for collusion review
+	collusion *collusionTracker
+
+	// Slap reaction times, for cheat review
+	reactions *reactionTracker
+
+	// Notable moments, and those not yet broadcast
+	Highlights        []protocol.Highlight
+	pendingHighlights []protocol.Highlight
+	cardSlappers      map[string]bool // Players who went for the current top card
+
+	// Most recent plays, for the observer view, and how many of them are
+	// on the current pile
+	pileHistory []protocol.PilePlay
+	pilePlays   int
+
+	clock clock.Clock
+	mu    sync.RWMutex
 }
 
-// GameStats tracks game statistics
-type GameStats struct {
-	TotalSlaps      int
-	SuccessfulSlaps map[string]int
-	CardsBurned     map[string]int
+// Options configures a new game
+type Options struct {
+	Mode            string
+	Rules           Rules
+	BurnPenalty     int
+	BurnDestination string
+	EscalatePenalty bool
+	DealRemainder   string
+	PileCap         int // Unslapped pile size at which the next play discards it; 0 for off
+	NumDecks        int
+	Deck            DeckSpec // Ranks and extra Jacks in each deck; the zero value is standard
+	SlapCooldownMs  int
+	SlapWindow      time.Duration // See Game.SlapWindow
+	TurnTimeoutMs   int
+	Pacing          *Pacing   // Adaptive turn timeouts; nil for off
+	AutoPace        *AutoPace // Shorter turn timeouts as the game goes on; nil for off
+	EnableSlapIn    bool
+	MaxSlapIns      int
+	AFKStrikes      int            // Timeouts in a row that put a player out; 0 never does
+	Teams           map[string]int // Team of each player, for a team game; nil for every player for themselves
+	WinCondition    WinCondition   // Defaults to LastStanding
+	Clock           clock.Clock    // Defaults to the system clock
+
+	// What happens to disconnected players' turns; defaults to DisconnectedSkip
+	DisconnectedTurns string
+
+	// What happens to the hand of a player who leaves; defaults to LeaverCardsDeal
+	LeaverCards string
 }
 
 // NewGame creates a new game with the given players
-func NewGame(playerIDs []string, enableDoubles, enableSandwich bool, burnPenalty, slapCooldownMs, turnTimeoutMs int, enableSlapIn bool, maxSlapIns int) *Game {
-	deck := NewDeck()
+func NewGame(playerIDs []string, opts Options) *Game {
+	if len(opts.Teams) > 0 {
+		playerIDs = teamTurnOrder(playerIDs, opts.Teams)
+	}
+
+	deck := NewMultiDeck(opts.NumDecks, opts.Deck)
 	deck.Shuffle()
-	hands := deck.Deal(len(playerIDs))
+
+	var hands [][]Card
+	var remainder []Card
+	if opts.DealRemainder == RemainderPile || opts.DealRemainder == RemainderDiscard {
+		hands, remainder = deck.DealEven(len(playerIDs))
+	} else {
+		hands = deck.Deal(len(playerIDs))
+	}
 
 	playerHands := make(map[string][]Card)
 	slapInCounts := make(map[string]int)
@@ -68,43 +226,108 @@ func NewGame(playerIDs []string, enableDoubles, enableSandwich bool, burnPenalty
 		slapInCounts[id] = 0
 	}
 
-	return &Game{
-		PlayerHands:     playerHands,
-		Pile:            make([]Card, 0, 52),
-		TurnOrder:       playerIDs,
-		CurrentTurnIdx:  0,
-		Rules:           NewRules(enableDoubles, enableSandwich),
-		BurnPenalty:     burnPenalty,
-		SlapCooldownMs:  slapCooldownMs,
-		TurnTimeoutMs:   turnTimeoutMs,
-		EnableSlapIn:    enableSlapIn,
-		MaxSlapIns:      maxSlapIns,
-		SlapInCounts:    slapInCounts,
-		LastSlapTime:    make(map[string]time.Time),
-		PendingSlaps:    make([]SlapAttempt, 0),
-		TurnTimerCancel: make(chan struct{}),
-		Stats: &GameStats{
-			SuccessfulSlaps: make(map[string]int),
-			CardsBurned:     make(map[string]int),
-		},
-		StartTime: time.Now(),
+	win := opts.WinCondition
+	if win == nil {
+		win = LastStanding{}
+	}
+
+	leaverCards := opts.LeaverCards
+	if leaverCards == "" {
+		leaverCards = LeaverCardsDeal
+	}
+
+	clk := clock.OrReal(opts.Clock)
+	g := &Game{
+		ID:                uuid.New().String(),
+		PlayerHands:       playerHands,
+		Pile:              make([]Card, 0, deck.Len()),
+		TurnOrder:         playerIDs,
+		turnToken:         1, // 0 is reserved for clients that don't send one
+		CurrentTurnIdx:    0,
+		Mode:              opts.Mode,
+		Rules:             &opts.Rules,
+		BurnPenalty:       opts.BurnPenalty,
+		BurnDestination:   opts.BurnDestination,
+		EscalatePenalty:   opts.EscalatePenalty,
+		FalseSlapStreak:   make(map[string]int),
+		PileCap:           opts.PileCap,
+		SlapCooldownMs:    opts.SlapCooldownMs,
+		SlapWindow:        opts.SlapWindow,
+		TurnTimeoutMs:     opts.TurnTimeoutMs,
+		Pacing:            opts.Pacing,
+		AutoPace:          opts.AutoPace,
+		AFKStrikes:        opts.AFKStrikes,
+		timeoutStrikes:    make(map[string]int),
+		AFK:               make(map[string]bool),
+		EnableSlapIn:      opts.EnableSlapIn,
+		MaxSlapIns:        opts.MaxSlapIns,
+		SlapInCounts:      slapInCounts,
+		Teams:             opts.Teams,
+		Win:               win,
+		pilesWon:          make(map[string]int),
+		Disconnected:      make(map[string]bool),
+		DisconnectedTurns: opts.DisconnectedTurns,
+		LeaverCards:       leaverCards,
+		Latency:           make(map[string]time.Duration),
+		InputLag:          make(map[string]time.Duration),
+		LastSlapTime:      make(map[string]time.Time),
+		PendingSlaps:      make([]SlapAttempt, 0),
+		TurnTimerCancel:   make(chan struct{}),
+		Stats:             newGameStats(playerIDs),
+		StartTime:         clk.Now(),
+		Replay:            make([]protocol.ReplayEvent, 0),
+		eliminationsSeen:  make(map[string]bool),
+		collusion:         newCollusionTracker(clk),
+		reactions:         newReactionTracker(clk),
+		cardSlappers:      make(map[string]bool),
+		clock:             clk,
+	}
+
+	for _, id := range playerIDs {
+		g.emit(Dealt{PlayerID: id, Count: len(playerHands[id])})
+	}
+
+	if len(remainder) > 0 {
+		if opts.DealRemainder == RemainderPile {
+			g.FaceDown = remainder
+		} else {
+			g.DiscardedDeal = len(remainder)
+		}
+		g.emit(Dealt{Count: len(remainder), Remainder: opts.DealRemainder})
 	}
+
+	return g
 }
 
+// ErrStalePlay is returned for a play whose turn token has already been used,
+// such as a double click or a retry after a reconnect
+var ErrStalePlay = errors.New("turn already played")
+
 // PlayCard plays the top card from a player's hand
-func (g *Game) PlayCard(playerID string) (*Card, error) {
+// turnToken is the token from the TURN_CHANGED being answered; 0 skips the check
+// Returns a challenge update if the play started or resolved a face-card challenge
+func (g *Game) PlayCard(playerID string, turnToken int64) (*Card, *ChallengeUpdate, error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
+	// Only one play per token
+	if turnToken != 0 && turnToken != g.turnToken {
+		return nil, nil, ErrStalePlay
+	}
+
 	// Check if it's this player's turn
 	if g.TurnOrder[g.CurrentTurnIdx] != playerID {
-		return nil, errors.New("not your turn")
+		return nil, nil, errors.New("not your turn")
 	}
 
 	// Check if player has cards
 	hand := g.PlayerHands[playerID]
 	if len(hand) == 0 {
-		return nil, errors.New("no cards to play")
+		return nil, nil, errors.New("no cards to play")
+	}
+
+	if g.race != nil {
+		return nil, nil, ErrSlapPending
 	}
 
 	// Cancel any existing turn timer
@@ -113,28 +336,43 @@ func (g *Game) PlayCard(playerID string) (*Card, error) {
 	default:
 	}
 
+	g.clearStrikes(playerID)
+	card, challenge := g.play(playerID, false)
+	return &card, challenge, nil
+}
+
+// play moves the top card of a player's hand onto the pile and passes the turn
+// Caller must hold g.mu and have checked the player can play
+func (g *Game) play(playerID string, timeout bool) (Card, *ChallengeUpdate) {
+	g.endPlay()
+
 	// Play top card
+	g.discardCappedPile(playerID)
+	hand := g.PlayerHands[playerID]
 	card := hand[0]
 	g.PlayerHands[playerID] = hand[1:]
 	g.Pile = append(g.Pile, card)
+	g.pileClaimed = false
+	g.plays++
+	g.LastPlayTime = g.clock.Now()
+	g.emit(CardPlayed{PlayerID: playerID, Card: card, PileCount: len(g.Pile), Timeout: timeout})
+	g.announcePlay()
 
 	// Reset slap window
 	g.SlapWindowOpen = true
 	g.PendingSlaps = make([]SlapAttempt, 0)
 
 	// Advance turn
-	g.advanceTurn()
-
-	return &card, nil
+	return card, g.afterPlay(playerID, card)
 }
 
-// advanceTurn moves to the next player with cards
+// advanceTurn moves to the next player with cards who isn't sitting out
 func (g *Game) advanceTurn() {
 	startIdx := g.CurrentTurnIdx
 	for {
 		g.CurrentTurnIdx = (g.CurrentTurnIdx + 1) % len(g.TurnOrder)
 		playerID := g.TurnOrder[g.CurrentTurnIdx]
-		if len(g.PlayerHands[playerID]) > 0 {
+		if len(g.PlayerHands[playerID]) > 0 && !g.sittingOut(playerID) {
 			return
 		}
 		// Check if we've looped all the way around
@@ -144,6 +382,44 @@ func (g *Game) advanceTurn() {
 	}
 }
 
+// SetPlayerConnected marks a player disconnected or back; they keep their
+// hand either way, and while they are gone their turns are passed over or
+// played for them
+// Returns true if the turn moved on because the current player disconnected
+func (g *Game) SetPlayerConnected(playerID string, connected bool) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if connected {
+		delete(g.Disconnected, playerID)
+		return false
+	}
+
+	g.Disconnected[playerID] = true
+	if !g.skipsDisconnected() || g.TurnOrder[g.CurrentTurnIdx] != playerID {
+		return false
+	}
+
+	g.advanceTurn()
+	if g.TurnOrder[g.CurrentTurnIdx] == playerID {
+		return false
+	}
+	g.turnToken++
+	return true
+}
+
+// setTurn makes the given player the next to play
+// Caller must hold g.mu
+func (g *Game) setTurn(playerID string) {
+	g.turnToken++
+	for i, id := range g.TurnOrder {
+		if id == playerID {
+			g.CurrentTurnIdx = i
+			return
+		}
+	}
+}
+
 // GetCurrentPlayer returns the ID of the current player
 func (g *Game) GetCurrentPlayer() string {
 	g.mu.RLock()
@@ -151,117 +427,274 @@ func (g *Game) GetCurrentPlayer() string {
 	return g.TurnOrder[g.CurrentTurnIdx]
 }
 
-// ProcessSlap handles a slap attempt
-func (g *Game) ProcessSlap(playerID string, serverTimestamp, clientTimestamp int64) protocol.SlapResultPayload {
+// TurnChangedMessage builds a TURN_CHANGED message for the current turn and its token
+func (g *Game) TurnChangedMessage() []byte {
+	g.mu.RLock()
+	payload := protocol.TurnChangedPayload{
+		CurrentPlayerID: g.TurnOrder[g.CurrentTurnIdx],
+		TurnToken:       g.turnToken,
+		TurnTimeoutMs:   g.turnTimeout().Milliseconds(),
+	}
+	g.mu.RUnlock()
+
+	key := payload.CurrentPlayerID + ":" + strconv.FormatInt(payload.TurnToken, 10) + ":" + strconv.FormatInt(payload.TurnTimeoutMs, 10)
+	return g.turnChanged.Message(protocol.TurnChanged, key, func() interface{} { return payload })
+}
+
+// EventCount returns how many events the game has recorded; it grows with
+// every play, slap, burn and elimination, so it changes whenever the card counts do.
+// Compaction doesn't lower it.
+func (g *Game) EventCount() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.recorded
+}
+
+// ProcessSlap handles a slap attempt, emitting a SlapResolved event with the result
+// Also returns whether the slap opened a slap window, which SettleSlaps must
+// be called to close once it has passed
+func (g *Game) ProcessSlap(playerID string, serverTimestamp, clientTimestamp int64) (protocol.SlapResultPayload, bool) {
 	g.SlapMu.Lock()
 	defer g.SlapMu.Unlock()
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
-	g.Stats.TotalSlaps++
+	racing := g.race != nil
+	result := g.processSlap(playerID, serverTimestamp, clientTimestamp)
+	return result, !racing && g.race != nil
+}
+
+// processSlap judges a slap attempt
+// Caller must hold g.SlapMu and g.mu
+func (g *Game) processSlap(playerID string, serverTimestamp, clientTimestamp int64) protocol.SlapResultPayload {
 
 	// Check cooldown
 	if lastSlap, ok := g.LastSlapTime[playerID]; ok {
-		if time.Since(lastSlap) < time.Duration(g.SlapCooldownMs)*time.Millisecond {
-			return protocol.SlapResultPayload{
+		if g.clock.Since(lastSlap) < time.Duration(g.SlapCooldownMs)*time.Millisecond {
+			return g.resolveSlap(protocol.SlapResultPayload{
 				PlayerID:    playerID,
 				Success:     false,
 				Reason:      "cooldown",
 				BurnPenalty: 0,
-			}
+			}, false)
 		}
 	}
-	g.LastSlapTime[playerID] = time.Now()
+	g.LastSlapTime[playerID] = g.clock.Now()
+	g.clearStrikes(playerID)
+	attempt := SlapAttempt{
+		PlayerID:        playerID,
+		ServerTimestamp: serverTimestamp,
+		ClientTimestamp: clientTimestamp,
+	}
+	if len(g.Pile) > 0 {
+		attempt.Reaction = g.reactionTime(playerID)
+	}
+	g.PendingSlaps = append(g.PendingSlaps, attempt)
 
 	// Check if slap is valid
-	g.mu.Lock()
-	defer g.mu.Unlock()
+	g.Stats.slap(playerID)
+	g.noteSlapper(playerID)
+
+	if len(g.Pile) > 0 {
+		g.reactions.add(playerID, g.clock.Since(g.LastPlayTime))
+	}
 
 	playerHasCards := len(g.PlayerHands[playerID]) > 0
 	reason := g.Rules.CheckSlap(g.Pile)
 
 	// If player has 0 cards, check if they can slap back in
 	if !playerHasCards {
-		canSlapIn := g.EnableSlapIn && g.SlapInCounts[playerID] < g.MaxSlapIns
+		canSlapIn := g.EnableSlapIn && g.SlapInCounts[playerID] < g.MaxSlapIns && !g.AFK[playerID]
 		if !canSlapIn {
 			// Can't slap - out of slap-ins or feature disabled
-			return protocol.SlapResultPayload{
+			return g.resolveSlap(protocol.SlapResultPayload{
 				PlayerID:    playerID,
 				Success:     false,
 				Reason:      "eliminated",
 				BurnPenalty: 0,
-			}
+			}, false)
 		}
 		// Player with 0 cards can only slap on valid slaps (no penalty for invalid)
 		if reason == SlapReasonInvalid {
-			return protocol.SlapResultPayload{
+			return g.resolveSlap(protocol.SlapResultPayload{
 				PlayerID:    playerID,
 				Success:     false,
 				Reason:      string(reason),
 				BurnPenalty: 0, // No burn penalty for players with 0 cards
-			}
+			}, false)
 		}
 	}
 
 	if reason == SlapReasonInvalid {
-		// Invalid slap - burn penalty
-		burnCount := g.applyBurnPenalty(playerID)
-		g.Stats.CardsBurned[playerID] += burnCount
-		return protocol.SlapResultPayload{
-			PlayerID:    playerID,
-			Success:     false,
-			Reason:      string(reason),
-			BurnPenalty: burnCount,
+		// Invalid slap - burn penalty, growing with each consecutive false slap if escalation is on
+		g.FalseSlapStreak[playerID]++
+		penalty := g.BurnPenalty
+		escalation := 0
+		if g.EscalatePenalty {
+			escalation = g.FalseSlapStreak[playerID]
+			penalty += escalation - 1
 		}
+
+		g.collusion.slap(playerID, false)
+		burn := g.applyBurnPenalty(playerID, penalty)
+		g.Stats.falseSlap(playerID, len(burn.Cards))
+		result := g.resolveSlap(protocol.SlapResultPayload{
+			PlayerID:        playerID,
+			Success:         false,
+			Reason:          string(reason),
+			BurnPenalty:     len(burn.Cards),
+			EscalationLevel: escalation,
+		}, true)
+		g.emit(burn)
+		g.emitCardCounts()
+		return result
+	}
+	delete(g.FalseSlapStreak, playerID)
+
+	// Valid slap - it races any others in the slap window, or wins the pile
+	if g.SlapWindow > 0 {
+		return g.enterSlapRace(playerID, reason, !playerHasCards)
 	}
+	return g.awardSlap(len(g.PendingSlaps)-1, reason, !playerHasCards)
+}
+
+// awardSlap gives the pile to a valid slap in PendingSlaps
+// Caller must hold g.mu
+func (g *Game) awardSlap(attempt int, reason SlapReason, slapIn bool) protocol.SlapResultPayload {
+	g.PendingSlaps[attempt].Won = true
+	playerID := g.PendingSlaps[attempt].PlayerID
 
-	// Valid slap - player wins the pile
-	cardsWon := len(g.Pile)
+	reaction := g.PendingSlaps[attempt].Reaction
+	g.Stats.success(playerID, reaction)
+	g.noteFastSlap(playerID, reaction)
 
 	// Track slap-in if player had 0 cards
-	if !playerHasCards {
+	if slapIn {
 		g.SlapInCounts[playerID]++
 	}
 
-	g.PlayerHands[playerID] = append(g.PlayerHands[playerID], g.Pile...)
-	g.Pile = make([]Card, 0, 52)
+	replay := g.slapReplay(playerID, reason)
+	cardsWon := g.collectPile(playerID)
+	g.collusion.slap(playerID, true)
+	delete(g.eliminationsSeen, playerID)
 	g.SlapWindowOpen = false
-	g.Stats.SuccessfulSlaps[playerID]++
+	g.LastSlapWinner = playerID
+	g.clearChallenge()
 
 	// Set this player as next to play
-	for i, id := range g.TurnOrder {
-		if id == playerID {
-			g.CurrentTurnIdx = i
-			break
-		}
-	}
+	g.setTurn(playerID)
 
-	return protocol.SlapResultPayload{
+	result := g.resolveSlap(protocol.SlapResultPayload{
 		PlayerID: playerID,
 		Success:  true,
 		Reason:   string(reason),
 		CardsWon: cardsWon,
+	}, true)
+	g.emit(replay)
+	g.emitCardCounts()
+	g.announcePileWon(playerID, cardsWon)
+	return result
+}
+
+// resolveSlap emits a slap's result and returns it
+// judged is false for slaps turned away without looking at the pile
+// Caller must hold g.mu
+func (g *Game) resolveSlap(result protocol.SlapResultPayload, judged bool) protocol.SlapResultPayload {
+	result.Contenders = g.slapContenders(result.PlayerID)
+	g.emit(SlapResolved{Result: result, judged: judged})
+	return result
+}
+
+// SetLatency records a player's round-trip latency
+func (g *Game) SetLatency(playerID string, rtt time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.Latency[playerID] = rtt
+}
+
+// SetInputLag records a player's calibrated input lag
+func (g *Game) SetInputLag(playerID string, lag time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.InputLag[playerID] = lag
+}
+
+// reactionTime is how long a player took to slap the top card, not counting
+// the time for the card to reach them, their device to register the slap and
+// the slap to come back, up to maxSlapCompensation
+// Caller must hold g.mu
+func (g *Game) reactionTime(playerID string) time.Duration {
+	reaction := g.clock.Since(g.LastPlayTime) - g.compensation(playerID)
+	if reaction < 0 {
+		return 0
 	}
+	return reaction
 }
 
-// applyBurnPenalty removes cards from a player and gives them to others
-func (g *Game) applyBurnPenalty(playerID string) int {
+// applyBurnPenalty removes up to penalty cards from a player and sends them to the burn destination
+// Returns the burn, for the caller to emit after the slap's result
+// Caller must hold g.mu
+func (g *Game) applyBurnPenalty(playerID string, penalty int) PenaltyApplied {
 	hand := g.PlayerHands[playerID]
 	if len(hand) == 0 {
-		return 0
+		return PenaltyApplied{PlayerID: playerID, Destination: g.BurnDestination, PileCount: len(g.Pile)}
 	}
 
-	burnCount := g.BurnPenalty
+	burnCount := penalty
 	if burnCount > len(hand) {
 		burnCount = len(hand)
 	}
 
-	// Take cards from player
-	burnedCards := hand[:burnCount]
+	// Take cards from player (copied so the pile never aliases the hand)
+	burnedCards := make([]Card, burnCount)
+	copy(burnedCards, hand[:burnCount])
 	g.PlayerHands[playerID] = hand[burnCount:]
 
-	// Add to bottom of pile
-	g.Pile = append(burnedCards, g.Pile...)
+	destination := g.BurnDestination
+	if destination == BurnToWinner && (g.LastSlapWinner == "" || g.LastSlapWinner == playerID) {
+		destination = BurnToPile
+	}
+
+	burn := PenaltyApplied{PlayerID: playerID, Cards: burnedCards, Destination: destination}
+	switch destination {
+	case BurnToDiscard:
+		// Removed from play
+	case BurnToWinner:
+		g.PlayerHands[g.LastSlapWinner] = append(g.PlayerHands[g.LastSlapWinner], burnedCards...)
+		burn.RecipientID = g.LastSlapWinner
+	default:
+		// Add to bottom of pile
+		g.Pile = append(burnedCards, g.Pile...)
+	}
+	burn.PileCount = len(g.Pile)
+
+	return burn
+}
+
+// collectPile gives the pile, including any face-down starting cards, to a player
+// Returns the number of cards collected
+// Caller must hold g.mu
+func (g *Game) collectPile(playerID string) int {
+	cardsWon := len(g.Pile) + len(g.FaceDown)
+	g.PlayerHands[playerID] = append(g.PlayerHands[playerID], g.Pile...)
+	g.PlayerHands[playerID] = append(g.PlayerHands[playerID], g.FaceDown...)
+	g.Pile = make([]Card, 0, 52)
+	g.FaceDown = nil
+	g.pileClaimed = true
+	g.bigPileAnnounced = false
+	g.pilesWon[playerID]++
+	g.Stats.pileWon(playerID, cardsWon)
+	g.pilePlays = 0
+	g.noteCardWon(playerID, cardsWon)
+	return cardsWon
+}
 
-	return burnCount
+// GetDealRemainder returns how many remainder cards started face-down on the
+// pile and how many were discarded
+func (g *Game) GetDealRemainder() (faceDown, discarded int) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.FaceDown), g.DiscardedDeal
 }
 
 // GetPlayerCardCount returns the number of cards a player has
@@ -275,52 +708,54 @@ func (g *Game) GetPlayerCardCount(playerID string) int {
 func (g *Game) GetCardCounts() map[string]int {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
+	return g.cardCounts()
+}
 
-	counts := make(map[string]int)
+// cardCounts maps each player to the cards in their hand
+// Caller must hold g.mu
+func (g *Game) cardCounts() map[string]int {
+	counts := make(map[string]int, len(g.PlayerHands))
 	for id, hand := range g.PlayerHands {
 		counts[id] = len(hand)
 	}
 	return counts
 }
 
-// CheckEliminations checks for and returns eliminated players
+// emitCardCounts emits the card counts after cards changed hands
+// Caller must hold g.mu
+func (g *Game) emitCardCounts() {
+	g.emit(CardCountsChanged{Counts: g.cardCounts(), PileCount: len(g.Pile)})
+}
+
+// CheckEliminations checks for and returns eliminated players, emitting a
+// PlayerEliminated event the first time each is out
+// In a team game players are only out once their whole team is
 func (g *Game) CheckEliminations() []string {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
 	var eliminated []string
 	for _, playerID := range g.TurnOrder {
-		if len(g.PlayerHands[playerID]) == 0 {
+		if !g.teamHasCards(playerID) {
 			// Only eliminate if they can't slap back in (pile is empty or no valid slap)
 			if !g.Rules.IsValidSlap(g.Pile) {
 				eliminated = append(eliminated, playerID)
+				if !g.eliminationsSeen[playerID] {
+					g.eliminate(playerID)
+				}
 			}
 		}
 	}
 	return eliminated
 }
 
-// CheckWinner returns the winner's ID if the game is over
+// CheckWinner returns the winner's ID if the game is over, as decided by
+// its win condition
+// A team game is won by a team, represented by whichever of its players
+// holds the most cards
 func (g *Game) CheckWinner() string {
-	g.mu.RLock()
-	defer g.mu.RUnlock()
-
-	// Count players with cards
-	var playersWithCards []string
-	for _, playerID := range g.TurnOrder {
-		if len(g.PlayerHands[playerID]) > 0 {
-			playersWithCards = append(playersWithCards, playerID)
-		}
-	}
-
-	// If only one player has cards and pile is empty or no valid slap, they win
-	if len(playersWithCards) == 1 {
-		if len(g.Pile) == 0 || !g.Rules.IsValidSlap(g.Pile) {
-			return playersWithCards[0]
-		}
-	}
-
-	return ""
+	winner, _ := g.CheckWin()
+	return winner
 }
 
 // GetState returns the current game state
@@ -342,77 +777,128 @@ func (g *Game) GetState() protocol.GameStatePayload {
 	return protocol.GameStatePayload{
 		Pile:             visiblePile,
 		CurrentPlayerID:  g.TurnOrder[g.CurrentTurnIdx],
+		TurnToken:        g.turnToken,
 		PlayerCardCounts: g.GetCardCounts(),
 		CanSlap:          g.Rules.CanSlap(g.Pile),
+		ChallengeOwnerID: g.ChallengeOwner,
+		ChancesRemaining: g.ChancesRemaining,
+	}
+}
+
+// currentTurnTimer reports whether gen is the newest turn timer
+func (g *Game) currentTurnTimer(gen uint64) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return gen == g.timerGen
+}
+
+// stopTurnTimer notes that turn timer gen has stopped, if it's the newest
+func (g *Game) stopTurnTimer(gen uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if gen == g.timerGen {
+		g.timerDeadline = time.Time{}
 	}
 }
 
-// GetStats returns game statistics
-func (g *Game) GetStats() protocol.GameStats {
+// TurnTimerDeadline returns when the running turn timer fires, or the zero
+// time if none is running
+func (g *Game) TurnTimerDeadline() time.Time {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
+	return g.timerDeadline
+}
+
+// LastPlayAt returns when the last card was played, or the game started if none has been
+func (g *Game) LastPlayAt() time.Time {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.LastPlayTime.IsZero() {
+		return g.StartTime
+	}
+	return g.LastPlayTime
+}
 
-	return protocol.GameStats{
-		TotalSlaps:     g.Stats.TotalSlaps,
-		SuccessfulSlap: g.Stats.SuccessfulSlaps,
-		CardsBurned:    g.Stats.CardsBurned,
-		Duration:       time.Since(g.StartTime).Milliseconds(),
+// CancelTurnTimer stops the running turn timer, if any
+func (g *Game) CancelTurnTimer() {
+	select {
+	case g.TurnTimerCancel <- struct{}{}:
+	default:
 	}
 }
 
 // StartTurnTimer starts a timer for the current turn
+// A timer started while another runs supersedes it, so only one ever fires
 func (g *Game) StartTurnTimer(roomCode string, broadcast func(string, []byte), roomManager interface{}) {
-	timeout := time.Duration(g.TurnTimeoutMs) * time.Millisecond
+	g.mu.Lock()
+	g.timerGen++
+	gen := g.timerGen
+	timeout := g.turnTimeout()
+	g.timerDeadline = g.clock.Now().Add(timeout)
+	g.mu.Unlock()
+	defer g.stopTurnTimer(gen)
+
 	warningTime := 3 * time.Second
 
-	// Warning timer
-	go func() {
-		select {
-		case <-time.After(timeout - warningTime):
-			// Send warning
-			msgData, _ := json.Marshal(protocol.NewMessage(protocol.TurnWarning, protocol.TurnWarningPayload{
-				SecondsRemaining: 3,
-			}))
-			broadcast(roomCode, msgData)
-		case <-g.TurnTimerCancel:
-			return
-		}
-	}()
+	// Warning timer, unless the turn is too short for one, as a disconnected
+	// player's is; it stops with this timer, so a cancel always reaches the
+	// timeout below
+	done := make(chan struct{})
+	defer close(done)
+	if timeout > warningTime {
+		go func() {
+			select {
+			case <-g.clock.After(timeout - warningTime):
+				if !g.currentTurnTimer(gen) {
+					return
+				}
+				// Send warning
+				msgData, _ := json.Marshal(protocol.NewMessage(protocol.TurnWarning, protocol.TurnWarningPayload{
+					SecondsRemaining: 3,
+				}))
+				broadcast(roomCode, msgData)
+			case <-done:
+				return
+			}
+		}()
+	}
 
 	// Timeout timer
 	select {
-	case <-time.After(timeout):
+	case <-g.clock.After(timeout):
 		// Auto-play card for current player
 		g.mu.Lock()
+		if gen != g.timerGen || g.over {
+			g.mu.Unlock()
+			return
+		}
+		g.settleSlapRace() // Slaps already in go first
 		currentPlayer := g.TurnOrder[g.CurrentTurnIdx]
-		hand := g.PlayerHands[currentPlayer]
-		if len(hand) > 0 {
-			card := hand[0]
-			g.PlayerHands[currentPlayer] = hand[1:]
-			g.Pile = append(g.Pile, card)
-			g.SlapWindowOpen = true
-			g.advanceTurn()
+		if len(g.PlayerHands[currentPlayer]) == 0 {
 			g.mu.Unlock()
+			return
+		}
+		if g.Disconnected[currentPlayer] {
+			// Not a strike, so they can't be put out while they might reconnect
+			g.play(currentPlayer, true)
+		} else {
+			g.timeOut(currentPlayer)
+		}
+		g.mu.Unlock()
 
-			// Broadcast the auto-played card
-			msgData, _ := json.Marshal(protocol.NewMessage(protocol.CardPlayed, protocol.CardPlayedPayload{
-				PlayerID:  currentPlayer,
-				Card:      card.ToProtocol(),
-				PileCount: len(g.Pile),
-			}))
+		// Broadcast the auto-played card, or the strike out, and anything it set off
+		for _, msgData := range g.DrainMessages() {
 			broadcast(roomCode, msgData)
+		}
+		for _, highlight := range g.DrainHighlights() {
+			broadcast(roomCode, highlight)
+		}
 
-			// Broadcast turn change
-			turnMsg, _ := json.Marshal(protocol.NewMessage(protocol.TurnChanged, protocol.TurnChangedPayload{
-				CurrentPlayerID: g.GetCurrentPlayer(),
-			}))
-			broadcast(roomCode, turnMsg)
+		// Broadcast turn change
+		broadcast(roomCode, g.TurnChangedMessage())
 
-			// Start new turn timer
-			go g.StartTurnTimer(roomCode, broadcast, roomManager)
-		} else {
-			g.mu.Unlock()
-		}
+		// Start new turn timer
+		go g.StartTurnTimer(roomCode, broadcast, roomManager)
 	case <-g.TurnTimerCancel:
 		return
 	}
@@ -0,0 +1,1322 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"slapjack/internal/clock"
+	"slapjack/pkg/protocol"
+)
+
+var testSuits = map[byte]string{'h': "hearts", 'd': "diamonds", 'c': "clubs", 's': "spades"}
+
+// card parses a short card name such as "10h" or "Js"
+func card(name string) Card {
+	return Card{Rank: name[:len(name)-1], Suit: testSuits[name[len(name)-1]]}
+}
+
+// cards parses a list of short card names, first card first
+func cards(names ...string) []Card {
+	out := make([]Card, len(names))
+	for i, name := range names {
+		out[i] = card(name)
+	}
+	return out
+}
+
+// newTestGame starts a game for players p1, p2, ... holding the given hands
+// and an empty pile
+func newTestGame(t *testing.T, opts Options, hands ...[]Card) *Game {
+	t.Helper()
+
+	ids := make([]string, len(hands))
+	for i := range hands {
+		ids[i] = fmt.Sprintf("p%d", i+1)
+	}
+	g := NewGame(ids, opts)
+	for i, id := range ids {
+		g.PlayerHands[id] = hands[i]
+	}
+	g.Pile = make([]Card, 0)
+	return g
+}
+
+func mustPlay(t *testing.T, g *Game, playerID string) Card {
+	t.Helper()
+	played, _, err := g.PlayCard(playerID, 0)
+	if err != nil {
+		t.Fatalf("%s playing: %v", playerID, err)
+	}
+	return *played
+}
+
+// slap slaps at time zero and returns the result
+func slap(g *Game, playerID string) protocol.SlapResultPayload {
+	result, _ := g.ProcessSlap(playerID, 0, 0)
+	return result
+}
+
+func TestNewGameDeal(t *testing.T) {
+	tests := []struct {
+		name        string
+		players     int
+		remainder   string
+		decks       int
+		wantHands   []int
+		wantFace    int
+		wantDiscard int
+	}{
+		{"two players", 2, RemainderDeal, 1, []int{26, 26}, 0, 0},
+		{"three players deal extras", 3, RemainderDeal, 1, []int{18, 17, 17}, 0, 0},
+		{"three players extras face down", 3, RemainderPile, 1, []int{17, 17, 17}, 1, 0},
+		{"three players extras discarded", 3, RemainderDiscard, 1, []int{17, 17, 17}, 0, 1},
+		{"five players two decks", 5, RemainderPile, 2, []int{20, 20, 20, 20, 20}, 4, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := make([]string, tt.players)
+			for i := range ids {
+				ids[i] = fmt.Sprintf("p%d", i+1)
+			}
+			g := NewGame(ids, Options{DealRemainder: tt.remainder, NumDecks: tt.decks})
+
+			for i, id := range ids {
+				if got := len(g.PlayerHands[id]); got != tt.wantHands[i] {
+					t.Errorf("%s has %d cards, want %d", id, got, tt.wantHands[i])
+				}
+			}
+			faceDown, discarded := g.GetDealRemainder()
+			if faceDown != tt.wantFace || discarded != tt.wantDiscard {
+				t.Errorf("remainder = %d face down, %d discarded, want %d, %d", faceDown, discarded, tt.wantFace, tt.wantDiscard)
+			}
+		})
+	}
+}
+
+func TestCustomDeck(t *testing.T) {
+	fast, ok := ParseRankSet([]string{"A", "7", "8", "9", "10", "J", "Q", "K"})
+	if !ok {
+		t.Fatal("ParseRankSet() rejected standard ranks")
+	}
+	if _, ok := ParseRankSet([]string{"J", "1"}); ok {
+		t.Error("ParseRankSet() accepted an unknown rank")
+	}
+	if full, _ := ParseRankSet(ranks); full != 0 {
+		t.Errorf("every rank parsed to %b, want the empty set", full)
+	}
+
+	tests := []struct {
+		name      string
+		decks     int
+		spec      DeckSpec
+		wantCards int
+		wantJacks int
+	}{
+		{"standard", 1, DeckSpec{}, 52, 4},
+		{"no low cards", 1, DeckSpec{Ranks: fast}, 32, 4},
+		{"extra jacks", 1, DeckSpec{ExtraJacks: 3}, 55, 7},
+		{"two short decks", 2, DeckSpec{Ranks: fast, ExtraJacks: 2}, 66, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deck := NewMultiDeck(tt.decks, tt.spec)
+			if deck.Len() != tt.wantCards || tt.spec.Size(tt.decks) != tt.wantCards {
+				t.Errorf("deck has %d cards, Size() = %d, want %d", deck.Len(), tt.spec.Size(tt.decks), tt.wantCards)
+			}
+			jacks := 0
+			for _, card := range deck.Cards() {
+				if !tt.spec.Ranks.Has(card.Rank) {
+					t.Errorf("deck has a %s, not in the spec", card.Rank)
+				}
+				if card.IsJack() {
+					jacks++
+				}
+			}
+			if jacks != tt.wantJacks {
+				t.Errorf("deck has %d Jacks, want %d", jacks, tt.wantJacks)
+			}
+		})
+	}
+}
+
+func TestTurnRotation(t *testing.T) {
+	tests := []struct {
+		name         string
+		hands        [][]Card
+		disconnected []string
+		want         []string
+	}{
+		{
+			name:  "round robin",
+			hands: [][]Card{cards("2h", "3h"), cards("2d", "3d"), cards("2c", "3c")},
+			want:  []string{"p1", "p2", "p3", "p1", "p2", "p3"},
+		},
+		{
+			name:  "skips empty hands",
+			hands: [][]Card{cards("2h", "3h"), nil, cards("2c", "3c")},
+			want:  []string{"p1", "p3", "p1", "p3"},
+		},
+		{
+			name:  "skips players who run out",
+			hands: [][]Card{cards("2h", "3h"), cards("2d"), cards("2c", "3c")},
+			want:  []string{"p1", "p2", "p3", "p1", "p3"},
+		},
+		{
+			name:         "skips disconnected players",
+			hands:        [][]Card{cards("2h", "3h"), cards("2d", "3d"), cards("2c", "3c")},
+			disconnected: []string{"p2"},
+			want:         []string{"p1", "p3", "p1", "p3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, Options{}, tt.hands...)
+			for _, id := range tt.disconnected {
+				g.SetPlayerConnected(id, false)
+			}
+
+			for i, want := range tt.want {
+				if got := g.GetCurrentPlayer(); got != want {
+					t.Fatalf("turn %d: current player %s, want %s", i, got, want)
+				}
+				mustPlay(t, g, want)
+			}
+		})
+	}
+}
+
+func TestDisconnectMovesTurn(t *testing.T) {
+	g := newTestGame(t, Options{}, cards("2h"), cards("2d"), cards("2c"))
+
+	if g.SetPlayerConnected("p2", false) {
+		t.Error("disconnecting a waiting player moved the turn")
+	}
+	if !g.SetPlayerConnected("p1", false) {
+		t.Fatal("disconnecting the current player didn't move the turn")
+	}
+	if got := g.GetCurrentPlayer(); got != "p3" {
+		t.Errorf("current player %s, want p3", got)
+	}
+
+	g.SetPlayerConnected("p2", true)
+	mustPlay(t, g, "p3")
+	if got := g.GetCurrentPlayer(); got != "p2" {
+		t.Errorf("after reconnect, current player %s, want p2", got)
+	}
+}
+
+func TestPlayCardErrors(t *testing.T) {
+	g := newTestGame(t, Options{}, cards("2h"), cards("2d"))
+
+	if _, _, err := g.PlayCard("p2", 0); err == nil {
+		t.Error("p2 played out of turn")
+	}
+
+	g.PlayerHands["p1"] = nil
+	if _, _, err := g.PlayCard("p1", 0); err == nil {
+		t.Error("p1 played from an empty hand")
+	}
+}
+
+func TestPlayCardTurnToken(t *testing.T) {
+	g := newTestGame(t, Options{}, cards("2h", "3h"), cards("2d", "3d"))
+
+	token := g.GetState().TurnToken
+	if _, _, err := g.PlayCard("p1", token); err != nil {
+		t.Fatalf("play with the current token: %v", err)
+	}
+	if _, _, err := g.PlayCard("p1", token); !errors.Is(err, ErrStalePlay) {
+		t.Errorf("repeated play: got %v, want ErrStalePlay", err)
+	}
+	if got := len(g.Pile); got != 1 {
+		t.Errorf("pile has %d cards after a repeated play, want 1", got)
+	}
+
+	// p2 answering with p1's old token is stale too, even though it's their turn
+	if _, _, err := g.PlayCard("p2", token); !errors.Is(err, ErrStalePlay) {
+		t.Errorf("play with an old token: got %v, want ErrStalePlay", err)
+	}
+	if _, _, err := g.PlayCard("p2", 0); err != nil {
+		t.Errorf("play without a token: %v", err)
+	}
+}
+
+func TestSlapWinsPile(t *testing.T) {
+	g := newTestGame(t, Options{}, cards("2h", "Jh"), cards("5d", "6d"), cards("7c", "8c"))
+	mustPlay(t, g, "p1")
+	mustPlay(t, g, "p2")
+	mustPlay(t, g, "p3")
+	mustPlay(t, g, "p1") // Jack
+
+	result, _ := g.ProcessSlap("p3", 0, 0)
+	if !result.Success || result.Reason != string(SlapReasonJack) {
+		t.Fatalf("slap on a jack = %+v, want success", result)
+	}
+	if result.CardsWon != 4 {
+		t.Errorf("won %d cards, want 4", result.CardsWon)
+	}
+	if got := len(g.Pile); got != 0 {
+		t.Errorf("pile has %d cards after the slap, want 0", got)
+	}
+	if got := g.GetPlayerCardCount("p3"); got != 5 {
+		t.Errorf("p3 has %d cards, want 5", got)
+	}
+	if got := g.GetCurrentPlayer(); got != "p3" {
+		t.Errorf("current player %s, want the slapper", got)
+	}
+}
+
+func TestSlapContenders(t *testing.T) {
+	g := newTestGame(t, Options{}, cards("Jh", "2h"), cards("5d", "6d"), cards("7c", "8c"))
+	mustPlay(t, g, "p1") // Jack
+
+	if result, _ := g.ProcessSlap("p2", 1000, 0); !result.Success {
+		t.Fatalf("first slap = %+v, want success", result)
+	} else if len(result.Contenders) != 1 || result.Contenders[0].PlayerID != "p2" {
+		t.Errorf("winner's contenders = %+v, want just the winner", result.Contenders)
+	}
+
+	late, _ := g.ProcessSlap("p3", 1043, 0)
+	if late.Success {
+		t.Fatalf("late slap = %+v, want failure", late)
+	}
+	want := []protocol.SlapContender{{PlayerID: "p2", DeltaMs: 0}, {PlayerID: "p3", DeltaMs: 43}}
+	if !reflect.DeepEqual(late.Contenders, want) {
+		t.Errorf("late slap contenders = %+v, want %+v", late.Contenders, want)
+	}
+
+	// Slaps long after the pile was won weren't racing for it
+	if result, _ := g.ProcessSlap("p1", 5000, 0); result.Contenders != nil {
+		t.Errorf("slap well after the win listed contenders %+v", result.Contenders)
+	}
+}
+
+func TestSlapRace(t *testing.T) {
+	clk := clock.NewMock(time.Now())
+	g := newTestGame(t, Options{Clock: clk, SlapWindow: DefaultSlapWindow}, cards("Jh", "2h"), cards("5d", "6d"), cards("7c", "8c"), cards("9s", "10s"))
+	g.SetLatency("p3", 100*time.Millisecond)
+	g.SetLatency("p4", 10*time.Second) // Pongs held back to look slower
+	mustPlay(t, g, "p1")               // Jack
+
+	// p2 reaches the server first, but p3 reacted faster once its latency is
+	// taken off; p4's only counts up to maxSlapCompensation, or p4 would win
+	clk.Advance(200 * time.Millisecond)
+	if result, opened := g.ProcessSlap("p2", 200, 0); result.Reason != SlapPending || !opened {
+		t.Fatalf("first valid slap = %+v, opened %v; want it pending and opening the window", result, opened)
+	}
+	clk.Advance(20 * time.Millisecond) // Reacted in 120ms
+	if _, opened := g.ProcessSlap("p3", 220, 0); opened {
+		t.Error("a later slap opened a second slap window")
+	}
+	clk.Advance(60 * time.Millisecond) // Reacted in 130ms, or 0 uncapped
+	g.ProcessSlap("p4", 280, 0)
+
+	if _, _, err := g.PlayCard("p2", 0); !errors.Is(err, ErrSlapPending) {
+		t.Errorf("play while slaps are collected: got %v, want ErrSlapPending", err)
+	}
+	if _, settled := g.SettleSlaps(); settled {
+		t.Fatal("slaps settled before the slap window closed")
+	}
+
+	clk.Advance(DefaultSlapWindow)
+	result, settled := g.SettleSlaps()
+	if !settled || !result.Success || result.PlayerID != "p3" {
+		t.Fatalf("settled slap = %+v, want p3 winning", result)
+	}
+	want := []protocol.SlapContender{{PlayerID: "p3"}, {PlayerID: "p2", DeltaMs: -20}, {PlayerID: "p4", DeltaMs: 60}}
+	if !reflect.DeepEqual(result.Contenders, want) {
+		t.Errorf("contenders = %+v, want %+v", result.Contenders, want)
+	}
+	if got := g.GetCurrentPlayer(); got != "p3" {
+		t.Errorf("current player %s, want the winner", got)
+	}
+
+	beaten := 0
+	for _, event := range g.events {
+		if resolved, ok := event.(SlapResolved); ok && resolved.Result.Reason == SlapBeaten {
+			beaten++
+		}
+	}
+	if beaten != 2 {
+		t.Errorf("%d slaps beaten, want the other 2", beaten)
+	}
+}
+
+func TestSlapReplay(t *testing.T) {
+	clk := clock.NewMock(time.Now())
+	g := newTestGame(t, Options{Clock: clk}, cards("2h", "3h"), cards("Jd", "4d"), cards("5c"))
+	mustPlay(t, g, "p1")
+	mustPlay(t, g, "p2")
+	clk.Advance(250 * time.Millisecond)
+	g.ProcessSlap("p3", 1000, 0)
+
+	var replay *protocol.SlapReplayPayload
+	for _, e := range g.Events() {
+		if e, ok := e.(SlapReplayed); ok {
+			replay = &e.Replay
+		}
+	}
+	if replay == nil {
+		t.Fatal("no slap replay after a winning slap")
+	}
+	if replay.WinnerID != "p3" || replay.Reason != string(SlapReasonJack) {
+		t.Errorf("replay of %s's %s slap, want p3's jack", replay.WinnerID, replay.Reason)
+	}
+	if len(replay.Plays) != 2 || replay.Plays[1].Card.Rank != "J" {
+		t.Errorf("replay plays %+v, want the 2 and the jack on top", replay.Plays)
+	}
+	want := []protocol.ReplaySlap{{PlayerID: "p3", Timestamp: 1000, ReactionMs: 250, Won: true}}
+	if !reflect.DeepEqual(replay.Slaps, want) {
+		t.Errorf("replay slaps %+v, want %+v", replay.Slaps, want)
+	}
+}
+
+func TestGameStats(t *testing.T) {
+	clk := clock.NewMock(time.Now())
+	g := newTestGame(t, Options{BurnPenalty: 2, Clock: clk}, cards("2h", "3h"), cards("5d", "6d", "7d"), cards("Jc", "8c"))
+	mustPlay(t, g, "p1")
+	g.ProcessSlap("p2", 0, 0) // False slap, burns 2 under the pile
+	mustPlay(t, g, "p2")
+	mustPlay(t, g, "p3") // Jack
+	clk.Advance(200 * time.Millisecond)
+	g.ProcessSlap("p1", 0, 0)
+
+	stats := g.GetStats().Players
+	if p1 := stats["p1"]; p1.Successes != 1 || p1.LongestPile != 5 || p1.AverageMs != 200 || p1.FastestMs != 200 {
+		t.Errorf("p1 stats %+v, want one 200ms slap winning a pile of 5", p1)
+	}
+	if p2 := stats["p2"]; p2.MissedSlaps != 1 || p2.FalseSlaps != 1 || p2.BiggestBurn != 2 || p2.LongestPile != 0 {
+		t.Errorf("p2 stats %+v, want one false slap burning 2", p2)
+	}
+}
+
+func TestSlapCollectsFaceDownCards(t *testing.T) {
+	g := newTestGame(t, Options{}, cards("Jh"), cards("5d"))
+	g.FaceDown = cards("2s", "3s")
+	mustPlay(t, g, "p1")
+
+	result, _ := g.ProcessSlap("p2", 0, 0)
+	if result.CardsWon != 3 {
+		t.Errorf("won %d cards, want the jack and both face-down cards", result.CardsWon)
+	}
+	if faceDown, _ := g.GetDealRemainder(); faceDown != 0 {
+		t.Errorf("%d cards still face down", faceDown)
+	}
+}
+
+func TestFalseSlapBurn(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		hand        []Card // the slapper's hand, p1
+		winner      string // last player to win a pile
+		slaps       int
+		wantBurned  []int
+		wantHand    int
+		wantPile    int
+		wantWinner2 int // cards held by p2 afterwards
+	}{
+		{
+			name:        "burn to pile",
+			opts:        Options{BurnPenalty: 1, BurnDestination: BurnToPile},
+			hand:        cards("2h", "3h", "4h"),
+			slaps:       1,
+			wantBurned:  []int{1},
+			wantHand:    2,
+			wantPile:    2,
+			wantWinner2: 1,
+		},
+		{
+			name:        "burn to discard",
+			opts:        Options{BurnPenalty: 2, BurnDestination: BurnToDiscard},
+			hand:        cards("2h", "3h", "4h"),
+			slaps:       1,
+			wantBurned:  []int{2},
+			wantHand:    1,
+			wantPile:    1,
+			wantWinner2: 1,
+		},
+		{
+			name:        "burn to winner",
+			opts:        Options{BurnPenalty: 1, BurnDestination: BurnToWinner},
+			hand:        cards("2h", "3h", "4h"),
+			winner:      "p2",
+			slaps:       1,
+			wantBurned:  []int{1},
+			wantHand:    2,
+			wantPile:    1,
+			wantWinner2: 2,
+		},
+		{
+			name:        "burn to winner without one falls back to pile",
+			opts:        Options{BurnPenalty: 1, BurnDestination: BurnToWinner},
+			hand:        cards("2h", "3h", "4h"),
+			slaps:       1,
+			wantBurned:  []int{1},
+			wantHand:    2,
+			wantPile:    2,
+			wantWinner2: 1,
+		},
+		{
+			name:        "burn to winner when the slapper is the winner falls back to pile",
+			opts:        Options{BurnPenalty: 1, BurnDestination: BurnToWinner},
+			hand:        cards("2h", "3h", "4h"),
+			winner:      "p1",
+			slaps:       1,
+			wantBurned:  []int{1},
+			wantHand:    2,
+			wantPile:    2,
+			wantWinner2: 1,
+		},
+		{
+			name:        "burn exceeding the hand takes what's left",
+			opts:        Options{BurnPenalty: 3, BurnDestination: BurnToPile},
+			hand:        cards("2h", "3h"),
+			slaps:       1,
+			wantBurned:  []int{2},
+			wantHand:    0,
+			wantPile:    3,
+			wantWinner2: 1,
+		},
+		{
+			name:        "escalating penalty",
+			opts:        Options{BurnPenalty: 1, BurnDestination: BurnToDiscard, EscalatePenalty: true},
+			hand:        cards("2h", "3h", "4h", "5h", "6h", "7h", "8h"),
+			slaps:       3,
+			wantBurned:  []int{1, 2, 3},
+			wantHand:    1,
+			wantPile:    1,
+			wantWinner2: 1,
+		},
+		{
+			name:        "escalation capped by the hand",
+			opts:        Options{BurnPenalty: 2, BurnDestination: BurnToDiscard, EscalatePenalty: true},
+			hand:        cards("2h", "3h", "4h", "5h"),
+			slaps:       3,
+			wantBurned:  []int{2, 2, 0},
+			wantHand:    0,
+			wantPile:    1,
+			wantWinner2: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, tt.opts, tt.hand, cards("9d", "10d"))
+			g.LastSlapWinner = tt.winner
+			g.setTurn("p2")
+			mustPlay(t, g, "p2") // A 9 on the pile, nothing to slap
+
+			for i := 0; i < tt.slaps; i++ {
+				result, _ := g.ProcessSlap("p1", 0, 0)
+				if result.Success {
+					t.Fatalf("slap %d succeeded on %v", i+1, g.Pile)
+				}
+				if result.BurnPenalty != tt.wantBurned[i] {
+					t.Errorf("slap %d burned %d, want %d", i+1, result.BurnPenalty, tt.wantBurned[i])
+				}
+			}
+
+			if got := g.GetPlayerCardCount("p1"); got != tt.wantHand {
+				t.Errorf("p1 has %d cards, want %d", got, tt.wantHand)
+			}
+			if got := len(g.Pile); got != tt.wantPile {
+				t.Errorf("pile has %d cards, want %d", got, tt.wantPile)
+			}
+			if got := g.GetPlayerCardCount("p2"); got != tt.wantWinner2 {
+				t.Errorf("p2 has %d cards, want %d", got, tt.wantWinner2)
+			}
+			if top := g.Pile[len(g.Pile)-1]; top != card("9d") {
+				t.Errorf("top card %v, want the burn under the 9", top)
+			}
+		})
+	}
+}
+
+func TestCardsBurnedMessage(t *testing.T) {
+	g := newTestGame(t, Options{BurnPenalty: 2, BurnDestination: BurnToWinner}, cards("2h", "3h", "4h"), cards("9d", "10d"))
+	g.LastSlapWinner = "p2"
+	g.setTurn("p2")
+	mustPlay(t, g, "p2")
+	g.DrainMessages()
+
+	g.ProcessSlap("p1", 0, 0)
+	messages := g.DrainMessages()
+	if len(messages) != 3 {
+		t.Fatalf("got %d messages, want SLAP_RESULT, CARDS_BURNED and CARD_COUNTS_UPDATED", len(messages))
+	}
+
+	var msg struct {
+		Type    string                      `json:"type"`
+		Payload protocol.CardsBurnedPayload `json:"payload"`
+	}
+	if err := json.Unmarshal(messages[1], &msg); err != nil {
+		t.Fatal(err)
+	}
+	want := protocol.CardsBurnedPayload{
+		PlayerID:    "p1",
+		Cards:       []protocol.Card{card("2h").ToProtocol(), card("3h").ToProtocol()},
+		Destination: BurnToWinner,
+		RecipientID: "p2",
+		PileCount:   1,
+	}
+	if msg.Type != protocol.CardsBurned || fmt.Sprint(msg.Payload) != fmt.Sprint(want) {
+		t.Errorf("got %s %+v, want %s %+v", msg.Type, msg.Payload, protocol.CardsBurned, want)
+	}
+
+	var counts struct {
+		Type    string                            `json:"type"`
+		Payload protocol.CardCountsUpdatedPayload `json:"payload"`
+	}
+	if err := json.Unmarshal(messages[2], &counts); err != nil {
+		t.Fatal(err)
+	}
+	if counts.Type != protocol.CardCountsUpdated || counts.Payload.PlayerCardCounts["p1"] != 1 || counts.Payload.PlayerCardCounts["p2"] != 3 {
+		t.Errorf("got %s %+v, want p1 on 1 card and p2 on 3", counts.Type, counts.Payload)
+	}
+}
+
+func TestPileCap(t *testing.T) {
+	g := newTestGame(t, Options{PileCap: 3}, cards("2h", "4h", "6h"), cards("3d", "5d", "7d"))
+	g.FaceDown = cards("Kc")
+	mustPlay(t, g, "p1")
+	mustPlay(t, g, "p2")
+	mustPlay(t, g, "p1")
+	if got := len(g.Pile); got != 3 {
+		t.Fatalf("pile has %d cards at the cap, want 3", got)
+	}
+	g.DrainMessages()
+
+	mustPlay(t, g, "p2")
+	if len(g.Pile) != 1 || g.Pile[0] != card("5d") || g.FaceDown != nil {
+		t.Errorf("pile after the cap = %v, face down %v, want just the 5", g.Pile, g.FaceDown)
+	}
+
+	var msg struct {
+		Type    string                        `json:"type"`
+		Payload protocol.PileDiscardedPayload `json:"payload"`
+	}
+	messages := g.DrainMessages()
+	if len(messages) == 0 {
+		t.Fatal("no messages after the capped play")
+	}
+	if err := json.Unmarshal(messages[0], &msg); err != nil {
+		t.Fatal(err)
+	}
+	want := protocol.PileDiscardedPayload{PlayerID: "p2", Count: 4}
+	if msg.Type != protocol.PileDiscarded || msg.Payload != want {
+		t.Errorf("first message = %s %+v, want %s %+v", msg.Type, msg.Payload, protocol.PileDiscarded, want)
+	}
+}
+
+func TestAnnouncer(t *testing.T) {
+	announced := func(g *Game) []protocol.AnnouncePayload {
+		var calls []protocol.AnnouncePayload
+		for _, msgData := range g.DrainMessages() {
+			var msg struct {
+				Type    string                   `json:"type"`
+				Payload protocol.AnnouncePayload `json:"payload"`
+			}
+			json.Unmarshal(msgData, &msg)
+			if msg.Type == protocol.Announce {
+				calls = append(calls, msg.Payload)
+			}
+		}
+		return calls
+	}
+
+	g := newTestGame(t, Options{EnableSlapIn: true, MaxSlapIns: 1}, cards("2h", "3h", "Jh"), cards("4d", "5d"), cards("6c"))
+	g.FaceDown = make([]Card, bigPileCards-1)
+	g.Pile = make([]Card, bigPileCards-1)
+	mustPlay(t, g, "p1")
+	want := []protocol.AnnouncePayload{{Kind: protocol.AnnounceBigPile, Count: bigPileCards}}
+	if got := announced(g); !reflect.DeepEqual(got, want) {
+		t.Fatalf("play onto a big pile announced %+v, want %+v", got, want)
+	}
+	mustPlay(t, g, "p2")
+	if got := announced(g); len(got) != 0 {
+		t.Errorf("growing the big pile again announced %+v", got)
+	}
+
+	// p3 plays their last card and wins it all back
+	mustPlay(t, g, "p3")
+	g.Pile = append(g.Pile, card("Js"))
+	g.ProcessSlap("p3", 0, 0)
+	won := 2*(bigPileCards-1) + 4 // Face down, the pile, three plays and the Jack
+	want = []protocol.AnnouncePayload{
+		{Kind: protocol.AnnounceFirstBlood, PlayerID: "p3", Count: won},
+		{Kind: protocol.AnnounceComeback, PlayerID: "p3", Count: won},
+	}
+	if got := announced(g); !reflect.DeepEqual(got, want) {
+		t.Errorf("first pile won announced %+v, want %+v", got, want)
+	}
+
+	g.PlayerHands["p2"] = nil
+	g.CheckEliminations()
+	want = []protocol.AnnouncePayload{{Kind: protocol.AnnounceLastTwo, Count: 2}}
+	if got := announced(g); !reflect.DeepEqual(got, want) {
+		t.Errorf("elimination down to two announced %+v, want %+v", got, want)
+	}
+}
+
+func TestFalseSlapStreakResets(t *testing.T) {
+	opts := Options{BurnPenalty: 1, BurnDestination: BurnToDiscard, EscalatePenalty: true, Rules: Rules{EnableDoubles: true}}
+	g := newTestGame(t, opts, cards("2h", "3h", "4h", "5h", "6h"), cards("9d", "9s", "10d"))
+	g.setTurn("p2")
+	mustPlay(t, g, "p2")
+
+	g.ProcessSlap("p1", 0, 0)
+	if got := slap(g, "p1").BurnPenalty; got != 2 {
+		t.Fatalf("second false slap burned %d, want 2", got)
+	}
+
+	// A good slap clears the streak
+	g.setTurn("p2")
+	mustPlay(t, g, "p2")
+	if !slap(g, "p1").Success {
+		t.Fatal("slap on doubles failed")
+	}
+	g.setTurn("p2")
+	mustPlay(t, g, "p2")
+	if got := slap(g, "p1").BurnPenalty; got != 1 {
+		t.Errorf("false slap after a good one burned %d, want 1", got)
+	}
+}
+
+func TestSlapCooldown(t *testing.T) {
+	clk := clock.NewMock(time.Now())
+	g := newTestGame(t, Options{SlapCooldownMs: 500, BurnPenalty: 1, Clock: clk}, cards("2h", "3h", "4h"), cards("9d"))
+	g.setTurn("p2")
+	mustPlay(t, g, "p2")
+
+	if got := slap(g, "p1").Reason; got != string(SlapReasonInvalid) {
+		t.Fatalf("first slap reason %q, want invalid", got)
+	}
+
+	clk.Advance(499 * time.Millisecond)
+	result, _ := g.ProcessSlap("p1", 0, 0)
+	if result.Reason != "cooldown" || result.BurnPenalty != 0 {
+		t.Errorf("slap during cooldown = %+v, want an unpenalized cooldown", result)
+	}
+
+	clk.Advance(time.Millisecond)
+	if got := slap(g, "p1").Reason; got != string(SlapReasonInvalid) {
+		t.Errorf("slap after cooldown reason %q, want invalid", got)
+	}
+}
+
+func TestTurnTimer(t *testing.T) {
+	clk := clock.NewMock(time.Now())
+	g := newTestGame(t, Options{TurnTimeoutMs: 10_000, Clock: clk}, cards("2h", "3h"), cards("9d"))
+
+	messages := make(chan protocol.WSMessage, 16)
+	broadcast := func(_ string, data []byte) {
+		var msg protocol.WSMessage
+		json.Unmarshal(data, &msg)
+		messages <- msg
+	}
+	next := func() protocol.WSMessage {
+		t.Helper()
+		select {
+		case msg := <-messages:
+			return msg
+		case <-time.After(time.Second):
+			t.Fatal("no message broadcast")
+			return protocol.WSMessage{}
+		}
+	}
+
+	go g.StartTurnTimer("ABCD", broadcast, nil)
+	clk.BlockUntil(2) // Warning and timeout
+
+	clk.Advance(6 * time.Second)
+	if len(messages) != 0 {
+		t.Fatalf("broadcast %s before the warning", (<-messages).Type)
+	}
+	clk.Advance(time.Second)
+	if msg := next(); msg.Type != protocol.TurnWarning {
+		t.Fatalf("got %s, want %s", msg.Type, protocol.TurnWarning)
+	}
+
+	clk.Advance(3 * time.Second)
+	for _, want := range []string{protocol.CardPlayed, protocol.TurnChanged} {
+		if msg := next(); msg.Type != want {
+			t.Fatalf("got %s, want %s", msg.Type, want)
+		}
+	}
+	if got := g.GetPlayerCardCount("p1"); got != 1 {
+		t.Errorf("p1 has %d cards after the auto-play, want 1", got)
+	}
+	if got := g.GetCurrentPlayer(); got != "p2" {
+		t.Errorf("current player %s, want p2", got)
+	}
+
+}
+
+func TestAdaptivePacing(t *testing.T) {
+	pacing := &Pacing{MinTimeoutMs: 4000, RampCards: 4, ClaimBonusMs: 3000}
+	g := newTestGame(t, Options{TurnTimeoutMs: 10_000, Pacing: pacing},
+		cards("2h", "3h", "4h"), cards("5d", "Jd", "6d"))
+
+	want := []time.Duration{10 * time.Second, 8500 * time.Millisecond, 7 * time.Second, 5500 * time.Millisecond}
+	for i, id := range []string{"p1", "p2", "p1"} {
+		if got := g.TurnTimeout(); got != want[i] {
+			t.Fatalf("with %d cards on the pile, timeout = %v, want %v", i, got, want[i])
+		}
+		mustPlay(t, g, id)
+	}
+	if got := g.TurnTimeout(); got != want[3] {
+		t.Errorf("with 3 cards on the pile, timeout = %v, want %v", got, want[3])
+	}
+
+	mustPlay(t, g, "p2") // Jack
+	if got := g.TurnTimeout(); got != 4*time.Second {
+		t.Errorf("past the ramp, timeout = %v, want the 4s floor", got)
+	}
+	if !slap(g, "p1").Success {
+		t.Fatal("slap on a jack failed")
+	}
+	if got := g.TurnTimeout(); got != 13*time.Second {
+		t.Errorf("after a claim, timeout = %v, want 13s", got)
+	}
+
+	var turn protocol.TurnChangedPayload
+	var msg protocol.WSMessage
+	json.Unmarshal(g.TurnChangedMessage(), &msg)
+	data, _ := json.Marshal(msg.Payload)
+	json.Unmarshal(data, &turn)
+	if turn.TurnTimeoutMs != 13000 {
+		t.Errorf("TURN_CHANGED timeout = %d, want 13000", turn.TurnTimeoutMs)
+	}
+
+	mustPlay(t, g, "p1")
+	if got := g.TurnTimeout(); got != 8500*time.Millisecond {
+		t.Errorf("after the next play, timeout = %v, want 8.5s", got)
+	}
+}
+
+func TestAutoPace(t *testing.T) {
+	g := newTestGame(t, Options{TurnTimeoutMs: 10_000, AutoPace: &AutoPace{StepTurns: 2, FloorMs: 3000}},
+		cards("2h", "3h", "4h", "5h"), cards("6d", "7d", "8d", "9d"), cards())
+	g.eliminationsSeen["p3"] = true // Out before the first play
+
+	want := []time.Duration{5 * time.Second, 5 * time.Second, 10 * time.Second / 3, 10 * time.Second / 3, 3 * time.Second}
+	for i, id := range []string{"p1", "p2", "p1", "p2"} {
+		if got := g.TurnTimeout(); got != want[i] {
+			t.Fatalf("after %d plays, timeout = %v, want %v", i, got, want[i])
+		}
+		mustPlay(t, g, id)
+	}
+	if got := g.TurnTimeout(); got != want[4] {
+		t.Errorf("after 4 plays, timeout = %v, want the %v floor", got, want[4])
+	}
+}
+
+func TestAFKStrikes(t *testing.T) {
+	g := newTestGame(t, Options{AFKStrikes: 2, EnableSlapIn: true, MaxSlapIns: 3},
+		cards("2h", "3h", "4h", "5h"), cards("6d", "7d", "8d", "3d"), cards("9c", "10c", "2c", "4c"))
+	timeOut := func() {
+		g.mu.Lock()
+		g.timeOut(g.TurnOrder[g.CurrentTurnIdx])
+		g.mu.Unlock()
+	}
+
+	// Playing for yourself clears the strikes
+	timeOut()
+	mustPlay(t, g, "p2")
+	mustPlay(t, g, "p3")
+	mustPlay(t, g, "p1")
+	mustPlay(t, g, "p2")
+	mustPlay(t, g, "p3")
+	timeOut()
+	if g.IsAFK("p1") {
+		t.Fatal("p1 struck out after timeouts with a play between them")
+	}
+	mustPlay(t, g, "p2")
+	mustPlay(t, g, "p3")
+	g.DrainMessages()
+
+	// The second timeout in a row puts p1 out, hand under the pile
+	timeOut()
+	if !g.IsAFK("p1") {
+		t.Fatal("p1 not struck out after two timeouts in a row")
+	}
+	if got := g.GetPlayerCardCount("p1"); got != 0 {
+		t.Errorf("p1 has %d cards after striking out, want 0", got)
+	}
+	if g.Pile[0] != card("5h") || len(g.Pile) != 10 {
+		t.Errorf("pile %v, want p1's last card at the bottom of 10", g.Pile)
+	}
+	if got := g.GetCurrentPlayer(); got != "p2" {
+		t.Errorf("current player %s after p1 struck out, want p2", got)
+	}
+
+	var types []string
+	for _, msgData := range g.DrainMessages() {
+		var msg protocol.WSMessage
+		json.Unmarshal(msgData, &msg)
+		types = append(types, msg.Type)
+	}
+	want := []string{protocol.PlayerAFK, protocol.PlayerEliminated, protocol.Announce} // Last two left
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Errorf("broadcast %v, want %v", types, want)
+	}
+
+	// An AFK player can't slap back in
+	g.Pile = append(g.Pile, card("Jd"))
+	if result, _ := g.ProcessSlap("p1", 0, 0); result.Success {
+		t.Error("AFK player slapped back in")
+	}
+}
+
+func TestSlapIn(t *testing.T) {
+	tests := []struct {
+		name        string
+		enable      bool
+		maxSlapIns  int
+		used        int
+		top         string
+		wantSuccess bool
+		wantReason  string
+	}{
+		{"disabled", false, 3, 0, "Jh", false, "eliminated"},
+		{"valid slap back in", true, 3, 0, "Jh", true, string(SlapReasonJack)},
+		{"last slap-in", true, 3, 2, "Jh", true, string(SlapReasonJack)},
+		{"out of slap-ins", true, 3, 3, "Jh", false, "eliminated"},
+		{"false slap costs nothing", true, 3, 0, "9h", false, string(SlapReasonInvalid)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{EnableSlapIn: tt.enable, MaxSlapIns: tt.maxSlapIns, BurnPenalty: 2}
+			g := newTestGame(t, opts, nil, cards(tt.top, "2d"))
+			g.SlapInCounts["p1"] = tt.used
+			g.setTurn("p2")
+			mustPlay(t, g, "p2")
+
+			result, _ := g.ProcessSlap("p1", 0, 0)
+			if result.Success != tt.wantSuccess || result.Reason != tt.wantReason {
+				t.Fatalf("slap-in = %+v, want success %v reason %q", result, tt.wantSuccess, tt.wantReason)
+			}
+			if result.BurnPenalty != 0 {
+				t.Errorf("empty-handed slap burned %d", result.BurnPenalty)
+			}
+
+			wantUsed := tt.used
+			if tt.wantSuccess {
+				wantUsed++
+				if got := g.GetPlayerCardCount("p1"); got != 1 {
+					t.Errorf("p1 has %d cards after slapping back in, want 1", got)
+				}
+			}
+			if got := g.SlapInCounts["p1"]; got != wantUsed {
+				t.Errorf("slap-ins used %d, want %d", got, wantUsed)
+			}
+		})
+	}
+}
+
+func TestEliminationsAndWinner(t *testing.T) {
+	tests := []struct {
+		name           string
+		hands          [][]Card
+		pile           []Card
+		wantEliminated []string
+		wantWinner     string
+	}{
+		{
+			name:           "nobody out",
+			hands:          [][]Card{cards("2h"), cards("3d"), cards("4c")},
+			wantEliminated: nil,
+			wantWinner:     "",
+		},
+		{
+			name:           "one out",
+			hands:          [][]Card{nil, cards("3d"), cards("4c")},
+			pile:           cards("9s"),
+			wantEliminated: []string{"p1"},
+			wantWinner:     "",
+		},
+		{
+			name:           "simultaneous eliminations leave a winner",
+			hands:          [][]Card{nil, nil, cards("4c")},
+			pile:           cards("9s"),
+			wantEliminated: []string{"p1", "p2"},
+			wantWinner:     "p3",
+		},
+		{
+			name:           "empty pile",
+			hands:          [][]Card{nil, cards("3d")},
+			wantEliminated: []string{"p1"},
+			wantWinner:     "p2",
+		},
+		{
+			name:           "slappable pile keeps everyone in",
+			hands:          [][]Card{nil, nil, cards("4c")},
+			pile:           cards("Js"),
+			wantEliminated: nil,
+			wantWinner:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(t, Options{}, tt.hands...)
+			g.Pile = append(g.Pile, tt.pile...)
+
+			eliminated := g.CheckEliminations()
+			if fmt.Sprint(eliminated) != fmt.Sprint(tt.wantEliminated) {
+				t.Errorf("eliminated %v, want %v", eliminated, tt.wantEliminated)
+			}
+			if got := g.CheckWinner(); got != tt.wantWinner {
+				t.Errorf("winner %q, want %q", got, tt.wantWinner)
+			}
+		})
+	}
+}
+
+func TestTeams(t *testing.T) {
+	teams := Options{Teams: map[string]int{"p1": 1, "p2": 1, "p3": 2, "p4": 2}}
+
+	g := newTestGame(t, teams, cards("2h"), cards("3d"), cards("4c"), cards("5s"))
+	if want := []string{"p1", "p3", "p2", "p4"}; !reflect.DeepEqual(g.TurnOrder, want) {
+		t.Errorf("turn order %v, want teams alternating %v", g.TurnOrder, want)
+	}
+	if got := g.Teammates("p4"); !reflect.DeepEqual(got, []string{"p3", "p4"}) {
+		t.Errorf("p4's teammates %v, want [p3 p4]", got)
+	}
+
+	// Out of cards, but a teammate isn't
+	g = newTestGame(t, teams, nil, cards("3d"), cards("4c"), nil)
+	g.Pile = cards("9s")
+	if eliminated := g.CheckEliminations(); eliminated != nil {
+		t.Errorf("eliminated %v while their teammates have cards", eliminated)
+	}
+	if got := g.CheckWinner(); got != "" {
+		t.Errorf("winner %q with both teams holding cards", got)
+	}
+
+	// The last team with cards wins, represented by its biggest hand
+	g = newTestGame(t, teams, nil, nil, cards("4c"), cards("5s", "6h"))
+	g.Pile = cards("9s")
+	if eliminated := g.CheckEliminations(); !reflect.DeepEqual(eliminated, []string{"p1", "p2"}) {
+		t.Errorf("eliminated %v, want the whole of team 1", eliminated)
+	}
+	if got := g.CheckWinner(); got != "p4" || g.TeamOf(got) != 2 {
+		t.Errorf("winner %q on team %d, want p4 for team 2", got, g.TeamOf(got))
+	}
+	g.Pile = cards("Js")
+	if got := g.CheckWinner(); got != "" {
+		t.Errorf("winner %q while team 1 can still slap back in", got)
+	}
+}
+
+func TestWinConditions(t *testing.T) {
+	// First to a pile target
+	g := newTestGame(t, Options{WinCondition: PileTarget{Piles: 1}}, cards("Jh", "2h"), cards("5d"), cards("7c"))
+	mustPlay(t, g, "p1")
+	if winner, _ := g.CheckWin(); winner != "" {
+		t.Errorf("winner %q before any pile was won", winner)
+	}
+	g.ProcessSlap("p2", 0, 0)
+	if winner, reason := g.CheckWin(); winner != "p2" || reason != WinPiles {
+		t.Errorf("CheckWin() = %q, %q, want p2 by piles", winner, reason)
+	}
+
+	// Most cards when the clock runs out
+	clk := clock.NewMock(time.Now())
+	timed := Options{WinCondition: Timed{Limit: time.Minute}, Clock: clk}
+	g = newTestGame(t, timed, cards("2h", "3h"), cards("5d", "6d"), cards("7c"))
+	g.pilesWon["p2"] = 1
+	if winner, _ := g.CheckWin(); winner != "" {
+		t.Errorf("winner %q with time left", winner)
+	}
+	clk.Advance(40 * time.Second)
+	if remaining, ok := g.ClockRemaining(); !ok || remaining != 20*time.Second {
+		t.Errorf("ClockRemaining() = %v, %v, want 20s", remaining, ok)
+	}
+	clk.Advance(20 * time.Second)
+	if winner, reason := g.CheckWin(); winner != "p2" || reason != WinTimed {
+		t.Errorf("CheckWin() = %q, %q, want p2 by time, ahead of p1 on piles won", winner, reason)
+	}
+
+	// The last player standing still wins outright
+	g = newTestGame(t, timed, cards("2h"), nil, nil)
+	if winner, reason := g.CheckWin(); winner != "p1" || reason != WinLastStanding {
+		t.Errorf("CheckWin() = %q, %q, want p1 as the last standing", winner, reason)
+	}
+
+	if _, ok := newTestGame(t, Options{}, cards("2h"), cards("3d")).ClockRemaining(); ok {
+		t.Error("an untimed game has a clock")
+	}
+}
+
+func TestEliminationRecordedOnce(t *testing.T) {
+	g := newTestGame(t, Options{}, nil, cards("3d"), cards("4c"))
+	g.Pile = cards("9s")
+
+	g.CheckEliminations()
+	g.CheckEliminations()
+
+	count := 0
+	for _, event := range g.Replay {
+		if event.Type == ReplayEliminate {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("elimination recorded %d times, want 1", count)
+	}
+}
+
+func TestEventLog(t *testing.T) {
+	g := newTestGame(t, Options{BurnPenalty: 1, SlapCooldownMs: 1000}, cards("2h", "3h"), cards("Jd"))
+	g.DrainMessages() // The deal
+
+	mustPlay(t, g, "p1")
+	g.ProcessSlap("p2", 0, 0) // False slap, burns p2's only card
+	g.ProcessSlap("p2", 0, 0) // Turned away by the cooldown
+	g.CheckEliminations()
+
+	var got []string
+	for _, e := range g.Events() {
+		got = append(got, fmt.Sprintf("%T", e))
+	}
+	want := []string{
+		"game.Dealt", "game.Dealt",
+		"game.CardPlayed", "game.SlapResolved", "game.PenaltyApplied", "game.CardCountsChanged",
+		"game.SlapResolved", "game.PlayerEliminated",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("events %v, want %v", got, want)
+	}
+
+	// Broadcasts are derived from the events, in order, skipping those with none
+	var types []string
+	for _, msgData := range g.DrainMessages() {
+		var msg protocol.WSMessage
+		if err := json.Unmarshal(msgData, &msg); err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, msg.Type)
+	}
+	wantTypes := []string{protocol.CardPlayed, protocol.SlapResult, protocol.CardsBurned, protocol.CardCountsUpdated, protocol.SlapResult, protocol.PlayerEliminated}
+	if fmt.Sprint(types) != fmt.Sprint(wantTypes) {
+		t.Errorf("broadcast %v, want %v", types, wantTypes)
+	}
+	if len(g.DrainMessages()) != 0 {
+		t.Error("messages broadcast twice")
+	}
+
+	// The replay keeps only judged slaps, with the burn after its slap
+	var replay []string
+	for _, event := range g.GetReplay() {
+		replay = append(replay, event.Type)
+	}
+	wantReplay := []string{ReplayDeal, ReplayDeal, ReplayPlay, ReplaySlap, ReplayBurn, ReplayEliminate}
+	if fmt.Sprint(replay) != fmt.Sprint(wantReplay) {
+		t.Errorf("replay %v, want %v", replay, wantReplay)
+	}
+}
+
+func TestEventLogCompaction(t *testing.T) {
+	g := newTestGame(t, Options{}, cards("2h", "3h"), cards("4d"))
+
+	const total = 3000
+	g.mu.Lock()
+	for g.recorded < total {
+		g.emit(CardPlayed{PlayerID: "p1", Card: g.PlayerHands["p1"][0], PileCount: 1})
+	}
+	g.mu.Unlock()
+
+	if got := g.EventCount(); got != total {
+		t.Errorf("event count %d, want %d", got, total)
+	}
+
+	replay := g.GetReplay()
+	if len(replay) > maxLogEvents {
+		t.Errorf("replay kept %d events, want at most %d", len(replay), maxLogEvents)
+	}
+	if len(replay) < keepLogEvents {
+		t.Errorf("replay kept %d events, want at least %d", len(replay), keepLogEvents)
+	}
+	first := replay[0]
+	if first.Type != ReplayCheckpoint || first.Snapshot == nil {
+		t.Fatalf("compacted replay starts with %q, want a checkpoint", first.Type)
+	}
+	if first.Snapshot.CardCounts["p1"] != 2 || first.Snapshot.CardCounts["p2"] != 1 {
+		t.Errorf("checkpoint card counts %v", first.Snapshot.CardCounts)
+	}
+	for i, e := range replay {
+		if e.Seq != first.Seq+i {
+			t.Fatalf("event %d has seq %d, want %d", i, e.Seq, first.Seq+i)
+		}
+	}
+	if last := replay[len(replay)-1].Seq; last != total {
+		t.Errorf("last seq %d, want %d", last, total)
+	}
+
+	// The event log is cut at the same checkpoint
+	events := g.Events()
+	if _, ok := events[0].(Checkpoint); !ok {
+		t.Errorf("compacted events start with %T, want a checkpoint", events[0])
+	}
+	if len(events) != len(replay) {
+		t.Errorf("kept %d events for %d replay entries", len(events), len(replay))
+	}
+}
+
+func TestDisconnectedAutoPlay(t *testing.T) {
+	clk := clock.NewMock(time.Now())
+	g := newTestGame(t, Options{TurnTimeoutMs: 10_000, AFKStrikes: 1, DisconnectedTurns: DisconnectedAutoPlay, Clock: clk},
+		cards("2h", "3h"), cards("9d", "4d"))
+
+	if g.SetPlayerConnected("p1", false) {
+		t.Fatal("disconnecting the current player moved the turn")
+	}
+	if got := g.TurnTimeout(); got != disconnectedTurnTimeout {
+		t.Errorf("turn timeout %v, want %v", got, disconnectedTurnTimeout)
+	}
+
+	messages := make(chan protocol.WSMessage, 16)
+	broadcast := func(_ string, data []byte) {
+		var msg protocol.WSMessage
+		json.Unmarshal(data, &msg)
+		messages <- msg
+	}
+	go g.StartTurnTimer("ABCD", broadcast, nil)
+	clk.BlockUntil(1) // Timeout only, with no warning
+
+	clk.Advance(disconnectedTurnTimeout)
+	for _, want := range []string{protocol.CardPlayed, protocol.TurnChanged} {
+		select {
+		case msg := <-messages:
+			if msg.Type != want {
+				t.Fatalf("got %s, want %s", msg.Type, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no %s broadcast", want)
+		}
+	}
+	g.CancelTurnTimer()
+
+	// The card is played for them without a strike, and the rest stay theirs
+	if g.IsAFK("p1") {
+		t.Error("disconnected player struck out")
+	}
+	if got := g.GetPlayerCardCount("p1"); got != 1 {
+		t.Errorf("p1 has %d cards after the auto-play, want 1", got)
+	}
+	if got := g.GetCurrentPlayer(); got != "p2" {
+		t.Errorf("current player %s, want p2", got)
+	}
+
+	// They get their turns back on reconnecting, at the usual timeout
+	mustPlay(t, g, "p2")
+	g.SetPlayerConnected("p1", true)
+	if got := g.TurnTimeout(); got != 10*time.Second {
+		t.Errorf("turn timeout after reconnecting %v, want 10s", got)
+	}
+	mustPlay(t, g, "p1")
+}
+
+func TestRemovePlayer(t *testing.T) {
+	tests := []struct {
+		leaverCards string
+		wantHands   map[string][]Card
+		wantPile    []Card
+	}{
+		{
+			// One at a time from the next player who holds cards, skipping p4
+			leaverCards: LeaverCardsDeal,
+			wantHands: map[string][]Card{
+				"p1": nil,
+				"p2": cards("2d", "Ah", "4h"),
+				"p3": cards("2c", "3h"),
+				"p4": nil,
+			},
+			wantPile: cards("Qs"),
+		},
+		{
+			leaverCards: LeaverCardsPile,
+			wantHands:   map[string][]Card{"p1": nil, "p2": cards("2d"), "p3": cards("2c"), "p4": nil},
+			wantPile:    cards("Ah", "3h", "4h", "Qs"),
+		},
+		{
+			leaverCards: LeaverCardsDiscard,
+			wantHands:   map[string][]Card{"p1": nil, "p2": cards("2d"), "p3": cards("2c"), "p4": nil},
+			wantPile:    cards("Qs"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.leaverCards, func(t *testing.T) {
+			g := newTestGame(t, Options{LeaverCards: tt.leaverCards},
+				cards("Ah", "3h", "4h"), cards("2d"), cards("2c"), nil)
+			g.Pile = cards("Qs")
+
+			if !g.RemovePlayer("p1") {
+				t.Error("removing the current player didn't move the turn")
+			}
+			if g.RemovePlayer("p1") {
+				t.Error("removing a player twice moved the turn again")
+			}
+			for id, want := range tt.wantHands {
+				if got := g.PlayerHands[id]; !reflect.DeepEqual(got, want) && len(got)+len(want) > 0 {
+					t.Errorf("%s holds %v, want %v", id, got, want)
+				}
+			}
+			if !reflect.DeepEqual(g.Pile, tt.wantPile) {
+				t.Errorf("pile %v, want %v", g.Pile, tt.wantPile)
+			}
+			if got := g.GetCurrentPlayer(); got != "p2" {
+				t.Errorf("current player %s, want p2", got)
+			}
+			if want := []string{"p2", "p3", "p4"}; !reflect.DeepEqual(g.TurnOrder, want) {
+				t.Errorf("turn order %v, want %v", g.TurnOrder, want)
+			}
+
+			// The departure is broadcast instead of an elimination
+			var types []string
+			for _, msgData := range g.DrainMessages() {
+				var msg protocol.WSMessage
+				json.Unmarshal(msgData, &msg)
+				types = append(types, msg.Type)
+			}
+			g.CheckEliminations()
+			for _, msgData := range g.DrainMessages() {
+				var msg protocol.WSMessage
+				json.Unmarshal(msgData, &msg)
+				types = append(types, msg.Type)
+			}
+			// p4 is eliminated, p1 isn't, and that leaves the last two
+			want := []string{protocol.CardsRedistributed, protocol.PlayerEliminated, protocol.Announce}
+			if fmt.Sprint(types) != fmt.Sprint(want) {
+				t.Errorf("broadcast %v, want %v", types, want)
+			}
+		})
+	}
+}
+
+func TestRemovePlayerKeepsTurn(t *testing.T) {
+	g := newTestGame(t, Options{}, cards("2h"), cards("3h"), cards("4h"))
+	g.setTurn("p3")
+
+	if g.RemovePlayer("p1") {
+		t.Error("removing another player moved the turn")
+	}
+	if got := g.GetCurrentPlayer(); got != "p3" {
+		t.Errorf("current player %s, want p3", got)
+	}
+
+	// The turn goes round the players left, never to the one who left
+	g.advanceTurn()
+	if got := g.GetCurrentPlayer(); got != "p2" {
+		t.Errorf("current player %s, want p2", got)
+	}
+}
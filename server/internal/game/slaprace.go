@@ -0,0 +1,118 @@
+package game
+
+import (
+	"errors"
+	"time"
+
+	"slapjack/pkg/protocol"
+)
+
+// DefaultSlapWindow is how long valid slaps on a card are collected after the
+// first, so the pile goes to the fastest once latency is taken off rather
+// than to whoever's slap reached the server first
+const DefaultSlapWindow = 100 * time.Millisecond
+
+// Most latency and input lag taken off a reaction time. The latency is
+// measured by the server, but the client decides when to answer pings and
+// reports its own input lag, so neither can buy more than this
+const maxSlapCompensation = 150 * time.Millisecond
+
+// Results of valid slaps that didn't win the pile outright
+const (
+	SlapPending = "pending" // Waiting for the slap window to close; see SettleSlaps
+	SlapBeaten  = "beaten"  // Another slap in the window was faster
+)
+
+// ErrSlapPending is returned for a play while slaps on the top card are
+// still being collected
+var ErrSlapPending = errors.New("the pile is being claimed")
+
+// slapRace is the valid slaps on the top card collected so far
+type slapRace struct {
+	closes  time.Time
+	entries []raceEntry
+}
+
+// raceEntry is a valid slap waiting for its race to settle
+type raceEntry struct {
+	attempt int // Index in PendingSlaps
+	reason  SlapReason
+	slapIn  bool // The player had no cards and is slapping back in
+}
+
+// enterSlapRace collects a valid slap, opening the slap window if it is the
+// first on the card
+// Caller must hold g.mu
+func (g *Game) enterSlapRace(playerID string, reason SlapReason, slapIn bool) protocol.SlapResultPayload {
+	if g.race == nil {
+		g.race = &slapRace{closes: g.clock.Now().Add(g.SlapWindow)}
+	}
+	for _, entry := range g.race.entries {
+		if g.PendingSlaps[entry.attempt].PlayerID == playerID {
+			return protocol.SlapResultPayload{PlayerID: playerID, Reason: SlapPending}
+		}
+	}
+	g.race.entries = append(g.race.entries, raceEntry{attempt: len(g.PendingSlaps) - 1, reason: reason, slapIn: slapIn})
+	return protocol.SlapResultPayload{PlayerID: playerID, Reason: SlapPending}
+}
+
+// SettleSlaps awards the pile once the slap window has closed, to the slap
+// with the fastest compensated reaction, the first to arrive on a tie; the
+// other slaps in the window are beaten
+// Returns the winning result, or false if no window has closed
+func (g *Game) SettleSlaps() (protocol.SlapResultPayload, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.race == nil || g.clock.Now().Before(g.race.closes) {
+		return protocol.SlapResultPayload{}, false
+	}
+	return g.settleSlapRace()
+}
+
+// settleSlapRace awards the pile to the fastest slap collected so far
+// Caller must hold g.mu
+func (g *Game) settleSlapRace() (protocol.SlapResultPayload, bool) {
+	race := g.race
+	g.race = nil
+	if race == nil || g.over {
+		return protocol.SlapResultPayload{}, false
+	}
+
+	winner := -1
+	for i, entry := range race.entries {
+		attempt := g.PendingSlaps[entry.attempt]
+		if _, playing := g.PlayerHands[attempt.PlayerID]; !playing {
+			continue // Left during the window
+		}
+		if winner < 0 || attempt.Reaction < g.PendingSlaps[race.entries[winner].attempt].Reaction {
+			winner = i
+		}
+	}
+	if winner < 0 {
+		return protocol.SlapResultPayload{}, false
+	}
+
+	won := race.entries[winner]
+	result := g.awardSlap(won.attempt, won.reason, won.slapIn)
+	for i, entry := range race.entries {
+		if i != winner {
+			g.resolveSlap(protocol.SlapResultPayload{
+				PlayerID: g.PendingSlaps[entry.attempt].PlayerID,
+				Success:  false,
+				Reason:   SlapBeaten,
+			}, true)
+		}
+	}
+	return result, true
+}
+
+// compensation is what is taken off a player's reaction times for the card
+// reaching them, their device registering the slap and the slap coming back
+// Caller must hold g.mu
+func (g *Game) compensation(playerID string) time.Duration {
+	compensation := g.Latency[playerID] + g.InputLag[playerID]
+	if compensation > maxSlapCompensation {
+		return maxSlapCompensation
+	}
+	return compensation
+}
@@ -0,0 +1,34 @@
+package game
+
+import (
+	"slapjack/pkg/protocol"
+)
+
+// Most plays onto the pile shown in a slap replay
+const slapReplayPlays = 5
+
+// slapReplay captures a winning slap for its slow-motion replay: the last
+// plays onto the pile and every slap on the top card so far, with their
+// reaction times
+// Call before the pile is collected
+// Caller must hold g.mu
+func (g *Game) slapReplay(winnerID string, reason SlapReason) SlapReplayed {
+	n := min(slapReplayPlays, g.pilePlays, len(g.pileHistory))
+	plays := append([]protocol.PilePlay{}, g.pileHistory[len(g.pileHistory)-n:]...)
+
+	slaps := make([]protocol.ReplaySlap, len(g.PendingSlaps))
+	for i, attempt := range g.PendingSlaps {
+		slaps[i] = protocol.ReplaySlap{
+			PlayerID:   attempt.PlayerID,
+			Timestamp:  attempt.ServerTimestamp,
+			ReactionMs: attempt.Reaction.Milliseconds(),
+			Won:        attempt.Won,
+		}
+	}
+	return SlapReplayed{Replay: protocol.SlapReplayPayload{
+		WinnerID: winnerID,
+		Reason:   string(reason),
+		Plays:    plays,
+		Slaps:    slaps,
+	}}
+}
@@ -0,0 +1,140 @@
+package game
+
+import (
+	"time"
+
+	"slapjack/pkg/protocol"
+)
+
+// Successful slap reaction times kept per player for the game over screen
+const maxStatsReactions = 50
+
+// GameStats tracks game statistics
+// All fields are only touched while holding g.mu
+type GameStats struct {
+	TotalSlaps int
+	Players    map[string]*PlayerStats
+}
+
+// PlayerStats is one player's record for a game
+type PlayerStats struct {
+	Slaps       int
+	Successes   int
+	FalseSlaps  int
+	CardsBurned int
+	FastestMs   int64   // Quickest successful slap after a card was played; 0 if none
+	ReactionsMs []int64 // Reaction times of the latest successful slaps
+	LongestPile int     // Most cards won in one pile
+	BiggestBurn int     // Most cards burned by one false slap
+
+	reactionTotalMs int64 // Every successful slap's reaction time, for the average
+}
+
+func newGameStats(playerIDs []string) *GameStats {
+	stats := &GameStats{
+		Players: make(map[string]*PlayerStats, len(playerIDs)),
+	}
+	for _, id := range playerIDs {
+		stats.Players[id] = &PlayerStats{}
+	}
+	return stats
+}
+
+// player returns a player's record, creating it if needed
+func (s *GameStats) player(playerID string) *PlayerStats {
+	p, ok := s.Players[playerID]
+	if !ok {
+		p = &PlayerStats{}
+		s.Players[playerID] = p
+	}
+	return p
+}
+
+// slap records a slap attempt that got past the cooldown
+func (s *GameStats) slap(playerID string) {
+	s.TotalSlaps++
+	s.player(playerID).Slaps++
+}
+
+// falseSlap records a bad slap and the cards it burned
+func (s *GameStats) falseSlap(playerID string, burned int) {
+	p := s.player(playerID)
+	p.FalseSlaps++
+	p.CardsBurned += burned
+	p.BiggestBurn = max(p.BiggestBurn, burned)
+}
+
+// success records a pile won by slapping, with the reaction time to the top card
+func (s *GameStats) success(playerID string, reaction time.Duration) {
+	p := s.player(playerID)
+	p.Successes++
+
+	ms := reaction.Milliseconds()
+	if p.Successes == 1 || ms < p.FastestMs {
+		p.FastestMs = ms
+	}
+	p.reactionTotalMs += ms
+	p.ReactionsMs = append(p.ReactionsMs, ms)
+	if len(p.ReactionsMs) > maxStatsReactions {
+		p.ReactionsMs = p.ReactionsMs[len(p.ReactionsMs)-maxStatsReactions:]
+	}
+}
+
+// pileWon records the cards in a pile won by slapping or a face-card challenge
+func (s *GameStats) pileWon(playerID string, cards int) {
+	p := s.player(playerID)
+	p.LongestPile = max(p.LongestPile, cards)
+}
+
+// toProtocol converts a player's record for the game over payload
+func (p *PlayerStats) toProtocol(slapIns int) protocol.PlayerStats {
+	stats := protocol.PlayerStats{
+		Slaps:       p.Slaps,
+		Successes:   p.Successes,
+		MissedSlaps: p.Slaps - p.Successes,
+		FalseSlaps:  p.FalseSlaps,
+		CardsBurned: p.CardsBurned,
+		SlapIns:     slapIns,
+		FastestMs:   p.FastestMs,
+		ReactionsMs: append([]int64{}, p.ReactionsMs...),
+		LongestPile: p.LongestPile,
+		BiggestBurn: p.BiggestBurn,
+	}
+	if p.Successes > 0 {
+		stats.AverageMs = p.reactionTotalMs / int64(p.Successes)
+	}
+	return stats
+}
+
+// GetStats returns game statistics
+func (g *Game) GetStats() protocol.GameStats {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	stats := protocol.GameStats{
+		TotalSlaps:     g.Stats.TotalSlaps,
+		SuccessfulSlap: make(map[string]int, len(g.Stats.Players)),
+		CardsBurned:    make(map[string]int, len(g.Stats.Players)),
+		Players:        make(map[string]protocol.PlayerStats, len(g.Stats.Players)),
+		Duration:       g.clock.Since(g.StartTime).Milliseconds(),
+	}
+	for id, p := range g.Stats.Players {
+		stats.SuccessfulSlap[id] = p.Successes
+		stats.CardsBurned[id] = p.CardsBurned
+		stats.Players[id] = p.toProtocol(g.SlapInCounts[id])
+	}
+	return stats
+}
+
+// ReactionTotal returns a player's successful slaps and their reaction
+// times summed
+func (g *Game) ReactionTotal(playerID string) (slaps int, totalMs int64) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	p, ok := g.Stats.Players[playerID]
+	if !ok {
+		return 0, 0
+	}
+	return p.Successes, p.reactionTotalMs
+}
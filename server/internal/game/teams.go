@@ -0,0 +1,93 @@
+package game
+
+import "sort"
+
+// teamTurnOrder interleaves the teams' players so turns alternate between
+// teams, keeping each team's players in their given order
+// Players without a team are left out
+func teamTurnOrder(playerIDs []string, teams map[string]int) []string {
+	byTeam := make(map[int][]string)
+	var teamIDs []int
+	for _, id := range playerIDs {
+		team := teams[id]
+		if team == 0 {
+			continue
+		}
+		if _, ok := byTeam[team]; !ok {
+			teamIDs = append(teamIDs, team)
+		}
+		byTeam[team] = append(byTeam[team], id)
+	}
+	sort.Ints(teamIDs)
+
+	order := make([]string, 0, len(playerIDs))
+	for i := 0; ; i++ {
+		added := false
+		for _, team := range teamIDs {
+			if i < len(byTeam[team]) {
+				order = append(order, byTeam[team][i])
+				added = true
+			}
+		}
+		if !added {
+			return order
+		}
+	}
+}
+
+// TeamOf returns a player's team, or 0 if the game isn't played in teams
+func (g *Game) TeamOf(playerID string) int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.Teams[playerID]
+}
+
+// Teammates returns the players on a player's team, the player included, in
+// turn order; just the player if the game isn't played in teams
+func (g *Game) Teammates(playerID string) []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.sideOf(playerID)
+}
+
+// teamHasCards reports whether any of a player's teammates, or the player,
+// still holds cards; without teams only the player counts
+// Caller must hold g.mu
+func (g *Game) teamHasCards(playerID string) bool {
+	if len(g.PlayerHands[playerID]) > 0 {
+		return true
+	}
+	team := g.Teams[playerID]
+	if team == 0 {
+		return false
+	}
+	for id, hand := range g.PlayerHands {
+		if g.Teams[id] == team && len(hand) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// teamWinner returns the winner of a team game once a single team holds
+// every card in hand: its player with the most cards, the first in turn
+// order on a tie
+// Caller must hold g.mu
+func (g *Game) teamWinner() string {
+	team := 0
+	winner := ""
+	for _, id := range g.TurnOrder {
+		count := len(g.PlayerHands[id])
+		if count == 0 {
+			continue
+		}
+		if team != 0 && g.Teams[id] != team {
+			return ""
+		}
+		team = g.Teams[id]
+		if winner == "" || count > len(g.PlayerHands[winner]) {
+			winner = id
+		}
+	}
+	return winner
+}
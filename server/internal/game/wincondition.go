@@ -0,0 +1,226 @@
+package game
+
+import (
+	"encoding/json"
+	"time"
+
+	"slapjack/pkg/protocol"
+)
+
+// Win conditions
+const (
+	WinLastStanding = "last_standing" // Last player, or team, with cards
+	WinPiles        = "piles"         // First to win a number of piles
+	WinTimed        = "timed"         // Most cards when the game clock runs out
+)
+
+// WinCondition decides when a game is over and who won it
+type WinCondition interface {
+	// Name is the condition's Win* name
+	Name() string
+
+	// winner returns the winner and the condition that decided the game,
+	// or "" if it isn't over
+	// Caller must hold g.mu
+	winner(g *Game) (winnerID, reason string)
+}
+
+// LastStanding is won by the last player, or team, with cards
+type LastStanding struct{}
+
+func (LastStanding) Name() string { return WinLastStanding }
+
+func (LastStanding) winner(g *Game) (string, string) {
+	return g.lastStanding(), WinLastStanding
+}
+
+// PileTarget is won by the first player, or team, to win Piles piles, or
+// by the last standing if that comes first
+type PileTarget struct {
+	Piles int
+}
+
+func (PileTarget) Name() string { return WinPiles }
+
+func (w PileTarget) winner(g *Game) (string, string) {
+	if id := g.lastStanding(); id != "" {
+		return id, WinLastStanding
+	}
+	for _, id := range g.TurnOrder {
+		if g.sidePilesWon(id) >= w.Piles {
+			return g.sideLeader(id), WinPiles
+		}
+	}
+	return "", ""
+}
+
+// Timed is won by the player, or team, holding the most cards once Limit
+// has passed since the game started, or by the last standing before then
+// Ties go to whoever has won more piles, then to the first in turn order
+type Timed struct {
+	Limit time.Duration
+}
+
+func (Timed) Name() string { return WinTimed }
+
+func (w Timed) winner(g *Game) (string, string) {
+	if id := g.lastStanding(); id != "" {
+		return id, WinLastStanding
+	}
+	if g.clock.Since(g.StartTime) < w.Limit {
+		return "", ""
+	}
+	best := ""
+	for _, id := range g.TurnOrder {
+		if best == "" {
+			best = id
+			continue
+		}
+		cards, bestCards := g.sideCards(id), g.sideCards(best)
+		if cards > bestCards || (cards == bestCards && g.sidePilesWon(id) > g.sidePilesWon(best)) {
+			best = id
+		}
+	}
+	return g.sideLeader(best), WinTimed
+}
+
+// lastStanding returns the last player with cards, or in a team game the
+// last team's player with the most, once nobody else can slap back in
+// Caller must hold g.mu
+func (g *Game) lastStanding() string {
+	// Once everyone else has left there is nobody to slap back in against
+	if id := g.soleSide(); id != "" {
+		return id
+	}
+	if g.Teams != nil {
+		if len(g.Pile) > 0 && g.Rules.IsValidSlap(g.Pile) {
+			return ""
+		}
+		return g.teamWinner()
+	}
+
+	// Count players with cards
+	var playersWithCards []string
+	for _, playerID := range g.TurnOrder {
+		if len(g.PlayerHands[playerID]) > 0 {
+			playersWithCards = append(playersWithCards, playerID)
+		}
+	}
+
+	// If only one player has cards and pile is empty or no valid slap, they win
+	if len(playersWithCards) == 1 {
+		if len(g.Pile) == 0 || !g.Rules.IsValidSlap(g.Pile) {
+			return playersWithCards[0]
+		}
+	}
+	return ""
+}
+
+// soleSide returns the leader of the only side left in the turn order, or
+// "" while there are others
+// Caller must hold g.mu
+func (g *Game) soleSide() string {
+	if len(g.TurnOrder) == 0 || len(g.sideOf(g.TurnOrder[0])) < len(g.TurnOrder) {
+		return ""
+	}
+	return g.sideLeader(g.TurnOrder[0])
+}
+
+// sideCards returns the cards held by a player's team, or the player
+// Caller must hold g.mu
+func (g *Game) sideCards(playerID string) int {
+	total := 0
+	for _, id := range g.sideOf(playerID) {
+		total += len(g.PlayerHands[id])
+	}
+	return total
+}
+
+// sidePilesWon returns the piles won by a player's team, or the player
+// Caller must hold g.mu
+func (g *Game) sidePilesWon(playerID string) int {
+	total := 0
+	for _, id := range g.sideOf(playerID) {
+		total += g.pilesWon[id]
+	}
+	return total
+}
+
+// sideLeader returns the player representing a player's team as its
+// winner: whoever on it holds the most cards, the first in turn order on a
+// tie; just the player without teams
+// Caller must hold g.mu
+func (g *Game) sideLeader(playerID string) string {
+	leader := playerID
+	for _, id := range g.sideOf(playerID) {
+		if len(g.PlayerHands[id]) > len(g.PlayerHands[leader]) {
+			leader = id
+		}
+	}
+	return leader
+}
+
+// sideOf returns a player's teammates, the player included, in turn order;
+// just the player without teams
+// Caller must hold g.mu
+func (g *Game) sideOf(playerID string) []string {
+	team := g.Teams[playerID]
+	if team == 0 {
+		return []string{playerID}
+	}
+	var mates []string
+	for _, id := range g.TurnOrder {
+		if g.Teams[id] == team {
+			mates = append(mates, id)
+		}
+	}
+	return mates
+}
+
+// CheckWin returns the winner's ID and the win condition that decided the
+// game, or "" if the game isn't over
+func (g *Game) CheckWin() (winnerID, reason string) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.Win.winner(g)
+}
+
+// PilesWon returns how many piles each player has won
+func (g *Game) PilesWon() map[string]int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	won := make(map[string]int, len(g.pilesWon))
+	for id, n := range g.pilesWon {
+		won[id] = n
+	}
+	return won
+}
+
+// ClockRemaining returns the time left on a timed game's clock, and false
+// if the game isn't timed
+func (g *Game) ClockRemaining() (time.Duration, bool) {
+	timed, ok := g.Win.(Timed)
+	if !ok {
+		return 0, false
+	}
+	remaining := timed.Limit - g.clock.Since(g.StartTime)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+// ClockMessage returns a GAME_CLOCK message with the time left on a timed
+// game's clock, or nil if the game isn't timed
+func (g *Game) ClockMessage() []byte {
+	remaining, ok := g.ClockRemaining()
+	if !ok {
+		return nil
+	}
+	msgData, _ := json.Marshal(protocol.NewMessage(protocol.GameClock, protocol.GameClockPayload{
+		RemainingMs: remaining.Milliseconds(),
+		LimitMs:     g.Win.(Timed).Limit.Milliseconds(),
+	}))
+	return msgData
+}
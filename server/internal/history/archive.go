@@ -0,0 +1,241 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"slapjack/pkg/protocol"
+)
+
+const (
+	// Games waiting to be archived; games beyond this are dropped rather
+	// than slowing down gameplay
+	queueSize = 256
+
+	// How long Postgres gets to archive a game or answer a query
+	queryTimeout = 5 * time.Second
+
+	// How often Flush checks whether the queue has emptied
+	flushPoll = 50 * time.Millisecond
+
+	// Page sizes for a player's history
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid history cursor")
+
+// schema creates the archive's tables if they don't exist yet. Each game is
+// kept whole as JSON, and indexed by the profiles of the players in it.
+const schema = `
+CREATE TABLE IF NOT EXISTS matches (
+	game_id    TEXT PRIMARY KEY,
+	room_code  TEXT NOT NULL,
+	started_at TIMESTAMPTZ NOT NULL,
+	ended_at   TIMESTAMPTZ NOT NULL,
+	record     JSONB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS match_players (
+	player_id TEXT NOT NULL,
+	game_id   TEXT NOT NULL REFERENCES matches (game_id) ON DELETE CASCADE,
+	ended_at  TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (player_id, game_id)
+);
+CREATE INDEX IF NOT EXISTS match_players_latest ON match_players (player_id, ended_at DESC, game_id DESC);
+`
+
+// Archive records finished games in Postgres, where they are kept for good,
+// unlike the replays Redis expires. Games are written from a single
+// background worker, so a slow database never holds up a game.
+type Archive struct {
+	db      *sql.DB
+	queue   chan protocol.MatchRecord
+	pending atomic.Int64 // Games queued and not yet written
+}
+
+// Open connects to the archive's database and creates its tables; Run
+// writes the games it records
+func Open(databaseURL string) (*Archive, error) {
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to history database: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create history tables: %w", err)
+	}
+
+	return &Archive{
+		db:    db,
+		queue: make(chan protocol.MatchRecord, queueSize),
+	}, nil
+}
+
+func (a *Archive) Close() error {
+	return a.db.Close()
+}
+
+// Record queues a finished game, dropping it if the queue is full
+func (a *Archive) Record(match protocol.MatchRecord) {
+	a.pending.Add(1)
+	select {
+	case a.queue <- match:
+	default:
+		a.pending.Add(-1)
+		slog.Warn("history queue full, dropping game", "gameId", match.GameID)
+	}
+}
+
+// Run archives queued games until the process exits
+func (a *Archive) Run() {
+	for match := range a.queue {
+		if err := a.write(match); err != nil {
+			slog.Error("failed to archive game", "gameId", match.GameID, "error", err)
+		}
+		a.pending.Add(-1)
+	}
+}
+
+// Flush waits for the queue to empty, for shutdown, or for ctx to end
+func (a *Archive) Flush(ctx context.Context) error {
+	ticker := time.NewTicker(flushPoll)
+	defer ticker.Stop()
+	for a.pending.Load() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("%d games not archived: %w", a.pending.Load(), ctx.Err())
+		}
+	}
+	return nil
+}
+
+func (a *Archive) write(match protocol.MatchRecord) error {
+	record, err := json.Marshal(match)
+	if err != nil {
+		return err
+	}
+	startedAt := time.UnixMilli(match.StartedAt)
+	endedAt := time.UnixMilli(match.EndedAt)
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO matches (game_id, room_code, started_at, ended_at, record)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT (game_id) DO NOTHING`,
+		match.GameID, match.RoomCode, startedAt, endedAt, record)
+	if err != nil {
+		return err
+	}
+	for _, p := range match.Players {
+		if p.PlayerID == "" {
+			continue
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO match_players (player_id, game_id, ended_at)
+			VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+			p.PlayerID, match.GameID, endedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Matches returns a page of a player's games, latest first, starting after
+// cursor, or from their latest game if cursor is empty. A limit out of range
+// gets the default.
+func (a *Archive) Matches(ctx context.Context, playerID, cursor string, limit int) (protocol.MatchHistoryResponse, error) {
+	if limit < 1 || limit > MaxLimit {
+		limit = DefaultLimit
+	}
+	// Before every game by default
+	before, beforeID := time.Now().Add(time.Hour), ""
+	if cursor != "" {
+		var err error
+		if before, beforeID, err = decodeCursor(cursor); err != nil {
+			return protocol.MatchHistoryResponse{}, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+	// One row more than the page shows whether there is a next page
+	rows, err := a.db.QueryContext(ctx,
+		`SELECT m.record FROM match_players p JOIN matches m ON m.game_id = p.game_id
+		WHERE p.player_id = $1 AND (p.ended_at, p.game_id) < ($2, $3)
+		ORDER BY p.ended_at DESC, p.game_id DESC LIMIT $4`,
+		playerID, before, beforeID, limit+1)
+	if err != nil {
+		return protocol.MatchHistoryResponse{}, err
+	}
+	defer rows.Close()
+
+	page := protocol.MatchHistoryResponse{Matches: []protocol.MatchRecord{}}
+	for rows.Next() {
+		var record []byte
+		if err := rows.Scan(&record); err != nil {
+			return protocol.MatchHistoryResponse{}, err
+		}
+		var match protocol.MatchRecord
+		if err := json.Unmarshal(record, &match); err != nil {
+			return protocol.MatchHistoryResponse{}, err
+		}
+		page.Matches = append(page.Matches, match)
+	}
+	if err := rows.Err(); err != nil {
+		return protocol.MatchHistoryResponse{}, err
+	}
+
+	if len(page.Matches) > limit {
+		page.Matches = page.Matches[:limit]
+		last := page.Matches[limit-1]
+		page.NextCursor = encodeCursor(last.EndedAt, last.GameID)
+	}
+	return page, nil
+}
+
+// encodeCursor names the position after a game in a player's history
+func encodeCursor(endedAt int64, gameID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(endedAt, 10) + ":" + gameID))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	millis, gameID, ok := strings.Cut(string(data), ":")
+	if !ok {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	endedAt, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	return time.UnixMilli(endedAt), gameID, nil
+}
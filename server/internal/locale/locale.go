@@ -0,0 +1,47 @@
+package locale
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Default is the language messages fall back to
+const Default = "en"
+
+// Languages messages are written in; the first is the fallback
+var languages = []language.Tag{language.English, language.Spanish, language.French, language.German}
+
+var matcher = language.NewMatcher(languages)
+
+// Params fills in the {name} placeholders of a message
+type Params map[string]string
+
+// Match picks the supported language closest to the given language codes or
+// Accept-Language headers, in order of preference, or English
+func Match(preferences ...string) string {
+	tag, _ := language.MatchStrings(matcher, preferences...)
+	base, _ := tag.Base()
+	return base.String()
+}
+
+// Text returns a message in a language with its parameters filled in,
+// falling back to English, or to the key itself for an unknown message
+func Text(lang, key string, params Params) string {
+	translations, ok := catalog[key]
+	if !ok {
+		return key
+	}
+	text, ok := translations[lang]
+	if !ok {
+		text = translations[Default]
+	}
+	if len(params) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, 2*len(params))
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
@@ -0,0 +1,352 @@
+package locale
+
+// Message keys, sent with the text so clients can translate them themselves
+const (
+	RoomNotFound        = "room_not_found"
+	PlayerNotFound      = "player_not_found"
+	PlayerNotInRoom     = "player_not_in_room"
+	RateLimited         = "rate_limited"
+	SlowMode            = "slow_mode"
+	InvalidMessage      = "invalid_message"
+	UnknownMessage      = "unknown_message"
+	FeatureDisabled     = "feature_disabled"
+	IdleTimeout         = "idle_timeout"
+	NotInRoom           = "not_in_room"
+	NotInThisRoom       = "not_in_this_room"
+	SeatedOnly          = "seated_only"
+	SeatedOnlySlap      = "seated_only.slap"
+	SeatedOnlyRematch   = "seated_only.rematch"
+	HostOnlySettings    = "host_only.settings"
+	HostOnlyStart       = "host_only.start"
+	HostOnlyCancelStart = "host_only.cancel_start"
+	HostOnlyKick        = "host_only.kick"
+	HostOnlyTransfer    = "host_only.transfer"
+	HostOnlyEndGame     = "host_only.end_game"
+	HostOnlyMovePlayers = "host_only.move_players"
+	CreateFailed        = "create_failed"
+	RoomCodeRequired    = "room_code_required"
+	ProfileNotFound     = "profile_not_found"
+	ItemIDRequired      = "item_id_required"
+	WalletUnavailable   = "wallet_unavailable"
+	UnknownPreset       = "unknown_preset"
+	SettingsInGame      = "settings_in_game"
+	NameInGame          = "name_in_game"
+	NeedPlayers         = "need_players"
+	NoReadyCheck        = "no_ready_check"
+	NotStarting         = "not_starting"
+	GameNotStarted      = "game_not_started"
+	KickSelf            = "kick_self"
+	AlreadyHost         = "already_host"
+	RematchNotOver      = "rematch_not_over"
+	ReplayNotFound      = "replay_not_found"
+	RTCDisabled         = "rtc_disabled"
+	UnknownSignal       = "unknown_signal"
+	SignalTooLarge      = "signal_too_large"
+	CalibrationNoToken  = "calibration_no_token"
+	InviteOnly          = "invite_only"
+	SpectatorsFull      = "spectators_full"
+	InvalidInvite       = "invalid_invite"
+	InvalidWebhook      = "invalid_webhook"
+	InvalidSchedule     = "invalid_schedule"
+	HostOnlyIntegration = "host_only.integration"
+	HostEndedGame       = "game_ended.host"
+	DropInNextGame      = "game_ended.drop_in"
+)
+
+// catalog holds every message by key and language
+var catalog = map[string]map[string]string{
+	RoomNotFound: {
+		"en": "Room not found",
+		"es": "Sala no encontrada",
+		"fr": "Salle introuvable",
+		"de": "Raum nicht gefunden",
+	},
+	PlayerNotFound: {
+		"en": "Player not found",
+		"es": "Jugador no encontrado",
+		"fr": "Joueur introuvable",
+		"de": "Spieler nicht gefunden",
+	},
+	PlayerNotInRoom: {
+		"en": "Player not found in room",
+		"es": "El jugador no está en la sala",
+		"fr": "Joueur introuvable dans la salle",
+		"de": "Spieler ist nicht im Raum",
+	},
+	RateLimited: {
+		"en": "Too many messages, slow down",
+		"es": "Demasiados mensajes, ve más despacio",
+		"fr": "Trop de messages, ralentissez",
+		"de": "Zu viele Nachrichten, langsamer bitte",
+	},
+	SlowMode: {
+		"en": "Slow mode is on, wait {seconds}s",
+		"es": "El modo lento está activado, espera {seconds} s",
+		"fr": "Le mode lent est activé, attendez {seconds} s",
+		"de": "Langsamer Modus ist an, warte {seconds} s",
+	},
+	InvalidMessage: {
+		"en": "Invalid message format",
+		"es": "Formato de mensaje no válido",
+		"fr": "Format de message invalide",
+		"de": "Ungültiges Nachrichtenformat",
+	},
+	UnknownMessage: {
+		"en": "Unknown message type: {type} (server protocol v{server}, client v{client})",
+		"es": "Tipo de mensaje desconocido: {type} (protocolo del servidor v{server}, cliente v{client})",
+		"fr": "Type de message inconnu : {type} (protocole serveur v{server}, client v{client})",
+		"de": "Unbekannter Nachrichtentyp: {type} (Serverprotokoll v{server}, Client v{client})",
+	},
+	FeatureDisabled: {
+		"en": "The {feature} feature is switched off on this server",
+		"es": "La función {feature} está desactivada en este servidor",
+		"fr": "La fonctionnalité {feature} est désactivée sur ce serveur",
+		"de": "Die Funktion {feature} ist auf diesem Server abgeschaltet",
+	},
+	IdleTimeout: {
+		"en": "Disconnected after being idle",
+		"es": "Desconectado por inactividad",
+		"fr": "Déconnecté après une période d'inactivité",
+		"de": "Wegen Inaktivität getrennt",
+	},
+	NotInRoom: {
+		"en": "You are not in a room",
+		"es": "No estás en ninguna sala",
+		"fr": "Vous n'êtes dans aucune salle",
+		"de": "Du bist in keinem Raum",
+	},
+	NotInThisRoom: {
+		"en": "You are not in this room",
+		"es": "No estás en esta sala",
+		"fr": "Vous n'êtes pas dans cette salle",
+		"de": "Du bist nicht in diesem Raum",
+	},
+	SeatedOnly: {
+		"en": "Only seated players can do that",
+		"es": "Solo los jugadores sentados pueden hacer eso",
+		"fr": "Seuls les joueurs assis peuvent faire cela",
+		"de": "Nur Spieler am Tisch können das tun",
+	},
+	SeatedOnlySlap: {
+		"en": "Only seated players can slap",
+		"es": "Solo los jugadores sentados pueden golpear",
+		"fr": "Seuls les joueurs assis peuvent taper",
+		"de": "Nur Spieler am Tisch können schlagen",
+	},
+	SeatedOnlyRematch: {
+		"en": "Only seated players can vote for a rematch",
+		"es": "Solo los jugadores sentados pueden votar por la revancha",
+		"fr": "Seuls les joueurs assis peuvent voter pour une revanche",
+		"de": "Nur Spieler am Tisch können für eine Revanche stimmen",
+	},
+	HostOnlySettings: {
+		"en": "Only the host can change settings",
+		"es": "Solo el anfitrión puede cambiar la configuración",
+		"fr": "Seul l'hôte peut modifier les paramètres",
+		"de": "Nur der Gastgeber kann die Einstellungen ändern",
+	},
+	HostOnlyStart: {
+		"en": "Only the host can start the game",
+		"es": "Solo el anfitrión puede empezar la partida",
+		"fr": "Seul l'hôte peut lancer la partie",
+		"de": "Nur der Gastgeber kann das Spiel starten",
+	},
+	HostOnlyCancelStart: {
+		"en": "Only the host can cancel the start",
+		"es": "Solo el anfitrión puede cancelar el inicio",
+		"fr": "Seul l'hôte peut annuler le lancement",
+		"de": "Nur der Gastgeber kann den Start abbrechen",
+	},
+	HostOnlyKick: {
+		"en": "Only the host can kick players",
+		"es": "Solo el anfitrión puede expulsar jugadores",
+		"fr": "Seul l'hôte peut exclure des joueurs",
+		"de": "Nur der Gastgeber kann Spieler entfernen",
+	},
+	HostOnlyTransfer: {
+		"en": "Only the host can transfer host",
+		"es": "Solo el anfitrión puede ceder el puesto de anfitrión",
+		"fr": "Seul l'hôte peut transférer le rôle d'hôte",
+		"de": "Nur der Gastgeber kann die Gastgeberrolle übertragen",
+	},
+	HostOnlyEndGame: {
+		"en": "Only the host can end the game",
+		"es": "Solo el anfitrión puede terminar la partida",
+		"fr": "Seul l'hôte peut mettre fin à la partie",
+		"de": "Nur der Gastgeber kann das Spiel beenden",
+	},
+	HostOnlyMovePlayers: {
+		"en": "Only the host can move other players",
+		"es": "Solo el anfitrión puede mover a otros jugadores",
+		"fr": "Seul l'hôte peut déplacer les autres joueurs",
+		"de": "Nur der Gastgeber kann andere Spieler verschieben",
+	},
+	CreateFailed: {
+		"en": "Failed to create room",
+		"es": "No se pudo crear la sala",
+		"fr": "Impossible de créer la salle",
+		"de": "Raum konnte nicht erstellt werden",
+	},
+	RoomCodeRequired: {
+		"en": "Room code is required",
+		"es": "Falta el código de sala",
+		"fr": "Le code de salle est requis",
+		"de": "Raumcode fehlt",
+	},
+	ProfileNotFound: {
+		"en": "Profile not found, register it again",
+		"es": "Perfil no encontrado, vuelve a registrarlo",
+		"fr": "Profil introuvable, enregistrez-le à nouveau",
+		"de": "Profil nicht gefunden, registriere es erneut",
+	},
+	ItemIDRequired: {
+		"en": "Item ID is required",
+		"es": "Falta el ID del artículo",
+		"fr": "L'identifiant de l'article est requis",
+		"de": "Artikel-ID fehlt",
+	},
+	WalletUnavailable: {
+		"en": "Wallet is unavailable, try again",
+		"es": "El monedero no está disponible, inténtalo de nuevo",
+		"fr": "Le portefeuille est indisponible, réessayez",
+		"de": "Geldbörse nicht verfügbar, versuche es erneut",
+	},
+	UnknownPreset: {
+		"en": "Unknown settings preset",
+		"es": "Configuración predefinida desconocida",
+		"fr": "Préréglage de paramètres inconnu",
+		"de": "Unbekannte Voreinstellung",
+	},
+	SettingsInGame: {
+		"en": "Cannot change settings while game is in progress",
+		"es": "No se puede cambiar la configuración durante la partida",
+		"fr": "Impossible de modifier les paramètres pendant la partie",
+		"de": "Einstellungen können während des Spiels nicht geändert werden",
+	},
+	NameInGame: {
+		"en": "Cannot change name while game is in progress",
+		"es": "No se puede cambiar el nombre durante la partida",
+		"fr": "Impossible de changer de nom pendant la partie",
+		"de": "Der Name kann während des Spiels nicht geändert werden",
+	},
+	NeedPlayers: {
+		"en": "Need at least {count} players to start",
+		"es": "Se necesitan al menos {count} jugadores para empezar",
+		"fr": "Il faut au moins {count} joueurs pour commencer",
+		"de": "Zum Starten werden mindestens {count} Spieler gebraucht",
+	},
+	NoReadyCheck: {
+		"en": "No ready check is running",
+		"es": "No hay ninguna comprobación de listos en curso",
+		"fr": "Aucune vérification de disponibilité en cours",
+		"de": "Es läuft keine Bereitschaftsabfrage",
+	},
+	NotStarting: {
+		"en": "The game is not starting",
+		"es": "La partida no está empezando",
+		"fr": "La partie n'est pas en train de commencer",
+		"de": "Das Spiel startet gerade nicht",
+	},
+	GameNotStarted: {
+		"en": "Game has not started",
+		"es": "La partida no ha empezado",
+		"fr": "La partie n'a pas commencé",
+		"de": "Das Spiel hat noch nicht begonnen",
+	},
+	KickSelf: {
+		"en": "Cannot kick yourself",
+		"es": "No puedes expulsarte a ti mismo",
+		"fr": "Vous ne pouvez pas vous exclure vous-même",
+		"de": "Du kannst dich nicht selbst entfernen",
+	},
+	AlreadyHost: {
+		"en": "You are already the host",
+		"es": "Ya eres el anfitrión",
+		"fr": "Vous êtes déjà l'hôte",
+		"de": "Du bist bereits der Gastgeber",
+	},
+	RematchNotOver: {
+		"en": "A rematch can only be requested after the game is over",
+		"es": "Solo se puede pedir la revancha cuando termina la partida",
+		"fr": "Une revanche ne peut être demandée qu'une fois la partie terminée",
+		"de": "Eine Revanche kann erst nach Spielende verlangt werden",
+	},
+	ReplayNotFound: {
+		"en": "Replay not found",
+		"es": "Repetición no encontrada",
+		"fr": "Rediffusion introuvable",
+		"de": "Wiederholung nicht gefunden",
+	},
+	RTCDisabled: {
+		"en": "Voice and video are turned off in this room",
+		"es": "La voz y el vídeo están desactivados en esta sala",
+		"fr": "La voix et la vidéo sont désactivées dans cette salle",
+		"de": "Sprache und Video sind in diesem Raum ausgeschaltet",
+	},
+	UnknownSignal: {
+		"en": "Unknown signal kind",
+		"es": "Tipo de señal desconocido",
+		"fr": "Type de signal inconnu",
+		"de": "Unbekannte Signalart",
+	},
+	SignalTooLarge: {
+		"en": "Signal too large",
+		"es": "Señal demasiado grande",
+		"fr": "Signal trop volumineux",
+		"de": "Signal zu groß",
+	},
+	CalibrationNoToken: {
+		"en": "Calibration needs a player token",
+		"es": "La calibración necesita un token de jugador",
+		"fr": "La calibration nécessite un jeton de joueur",
+		"de": "Die Kalibrierung braucht ein Spieler-Token",
+	},
+	InviteOnly: {
+		"en": "This room is private, you need an invite to join",
+		"es": "Esta sala es privada, necesitas una invitación para entrar",
+		"fr": "Cette salle est privée, il faut une invitation pour la rejoindre",
+		"de": "Dieser Raum ist privat, du brauchst eine Einladung",
+	},
+	SpectatorsFull: {
+		"en": "This room has as many spectators as it allows",
+		"es": "Esta sala ya tiene todos los espectadores que admite",
+		"fr": "Cette salle a déjà autant de spectateurs qu'elle en accepte",
+		"de": "Dieser Raum hat bereits so viele Zuschauer wie erlaubt",
+	},
+	InvalidInvite: {
+		"en": "This invite is invalid, used or expired",
+		"es": "Esta invitación no es válida, ya se usó o ha caducado",
+		"fr": "Cette invitation est invalide, déjà utilisée ou expirée",
+		"de": "Diese Einladung ist ungültig, schon benutzt oder abgelaufen",
+	},
+	InvalidWebhook: {
+		"en": "That isn't a Discord webhook URL",
+		"es": "Esa no es una URL de webhook de Discord",
+		"fr": "Ce n'est pas une URL de webhook Discord",
+		"de": "Das ist keine Discord-Webhook-URL",
+	},
+	InvalidSchedule: {
+		"en": "Games can only be scheduled for the next 48 hours",
+		"es": "Las partidas solo se pueden programar para las próximas 48 horas",
+		"fr": "Les parties ne peuvent être programmées que dans les 48 prochaines heures",
+		"de": "Spiele können nur für die nächsten 48 Stunden geplant werden",
+	},
+	HostOnlyIntegration: {
+		"en": "Only the host can set up integrations",
+		"es": "Solo el anfitrión puede configurar integraciones",
+		"fr": "Seul l'hôte peut configurer les intégrations",
+		"de": "Nur der Gastgeber kann Integrationen einrichten",
+	},
+	HostEndedGame: {
+		"en": "Host ended the game",
+		"es": "El anfitrión terminó la partida",
+		"fr": "L'hôte a mis fin à la partie",
+		"de": "Der Gastgeber hat das Spiel beendet",
+	},
+	DropInNextGame: {
+		"en": "Next game starts when enough players are ready",
+		"es": "La próxima partida empieza cuando haya suficientes jugadores listos",
+		"fr": "La prochaine partie commence quand assez de joueurs sont prêts",
+		"de": "Das nächste Spiel beginnt, sobald genug Spieler bereit sind",
+	},
+}
@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Minimum level logged, adjustable while running
+var minLevel = new(slog.LevelVar)
+
+// Setup installs a JSON logger as the default for slog and the standard log package
+func Setup(level string) {
+	SetLevel(level)
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: minLevel,
+	})
+	slog.SetDefault(slog.New(handler))
+}
+
+// SetLevel changes the minimum level logged
+func SetLevel(level string) {
+	minLevel.Set(ParseLevel(level))
+}
+
+// ParseLevel converts a level name (debug, info, warn, error) to a slog level
+// Unknown names fall back to info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
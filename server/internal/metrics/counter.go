@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is a count that only goes up
+type Counter struct {
+	n atomic.Uint64
+}
+
+// Inc adds one to the counter
+func (c *Counter) Inc() {
+	c.n.Add(1)
+}
+
+// Add adds n to the counter
+func (c *Counter) Add(n uint64) {
+	c.n.Add(n)
+}
+
+// Value returns the current count
+func (c *Counter) Value() uint64 {
+	return c.n.Load()
+}
+
+// CounterVec is a family of counters told apart by label values
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	byKey map[string]*labeledCounter
+	mu    sync.RWMutex
+}
+
+type labeledCounter struct {
+	values []string
+	*Counter
+}
+
+// NewCounterVec creates a counter family with the given label names
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		byKey:  make(map[string]*labeledCounter),
+	}
+}
+
+// With returns the counter for a set of label values, in label order
+// As with histograms, label values should come from a small fixed set
+func (v *CounterVec) With(values ...string) *Counter {
+	key := strings.Join(values, "\xff")
+
+	v.mu.RLock()
+	c, ok := v.byKey[key]
+	v.mu.RUnlock()
+	if ok {
+		return c.Counter
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok := v.byKey[key]; ok {
+		return c.Counter
+	}
+	c = &labeledCounter{values: append([]string(nil), values...), Counter: &Counter{}}
+	v.byKey[key] = c
+	return c.Counter
+}
+
+// Write writes every counter in the family in the Prometheus text format
+func (v *CounterVec) Write(w io.Writer) error {
+	v.mu.RLock()
+	counters := make([]*labeledCounter, 0, len(v.byKey))
+	for _, c := range v.byKey {
+		counters = append(counters, c)
+	}
+	v.mu.RUnlock()
+	sort.Slice(counters, func(i, j int) bool {
+		return strings.Join(counters[i].values, ",") < strings.Join(counters[j].values, ",")
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", v.name, v.help, v.name)
+	for _, c := range counters {
+		labels := strings.TrimSuffix(labelPairs(v.labels, c.values), ",")
+		fmt.Fprintf(&b, "%s{%s} %d\n", v.name, labels, c.Value())
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
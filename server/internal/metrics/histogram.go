@@ -0,0 +1,155 @@
+// Package metrics keeps latency histograms and counters and writes them in the Prometheus
+// text exposition format
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets are bucket upper bounds in seconds, from 100µs to 2.5s
+var LatencyBuckets = []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5}
+
+// Histogram counts observed durations into buckets
+type Histogram struct {
+	bounds []float64
+	counts []uint64 // Per bucket, not cumulative; the last is above every bound
+	count  uint64
+	sum    float64
+	mu     sync.Mutex
+}
+
+func newHistogram(bounds []float64) *Histogram {
+	return &Histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+// Observe records one duration
+func (h *Histogram) Observe(d time.Duration) {
+	seconds := d.Seconds()
+	i := sort.SearchFloat64s(h.bounds, seconds)
+
+	h.mu.Lock()
+	h.counts[i]++
+	h.count++
+	h.sum += seconds
+	h.mu.Unlock()
+}
+
+// Snapshot is a histogram's state at one moment
+type Snapshot struct {
+	Bounds     []float64
+	Cumulative []uint64 // Observations at or below each bound
+	Count      uint64
+	Sum        float64
+}
+
+// Snapshot returns the histogram's current counts
+func (h *Histogram) Snapshot() Snapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := Snapshot{
+		Bounds:     h.bounds,
+		Cumulative: make([]uint64, len(h.bounds)),
+		Count:      h.count,
+		Sum:        h.sum,
+	}
+	var total uint64
+	for i := range h.bounds {
+		total += h.counts[i]
+		s.Cumulative[i] = total
+	}
+	return s
+}
+
+// HistogramVec is a family of histograms told apart by label values
+type HistogramVec struct {
+	name   string
+	help   string
+	labels []string
+	bounds []float64
+
+	byKey map[string]*labeledHistogram
+	mu    sync.RWMutex
+}
+
+type labeledHistogram struct {
+	values []string
+	*Histogram
+}
+
+// NewHistogramVec creates a histogram family with the given label names
+func NewHistogramVec(name, help string, bounds []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		bounds: bounds,
+		byKey:  make(map[string]*labeledHistogram),
+	}
+}
+
+// With returns the histogram for a set of label values, in label order
+// Label values should come from a small fixed set, since every distinct set
+// is kept for good
+func (v *HistogramVec) With(values ...string) *Histogram {
+	key := strings.Join(values, "\xff")
+
+	v.mu.RLock()
+	h, ok := v.byKey[key]
+	v.mu.RUnlock()
+	if ok {
+		return h.Histogram
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if h, ok := v.byKey[key]; ok {
+		return h.Histogram
+	}
+	h = &labeledHistogram{values: append([]string(nil), values...), Histogram: newHistogram(v.bounds)}
+	v.byKey[key] = h
+	return h.Histogram
+}
+
+// Write writes every histogram in the family in the Prometheus text format
+func (v *HistogramVec) Write(w io.Writer) error {
+	v.mu.RLock()
+	histograms := make([]*labeledHistogram, 0, len(v.byKey))
+	for _, h := range v.byKey {
+		histograms = append(histograms, h)
+	}
+	v.mu.RUnlock()
+	sort.Slice(histograms, func(i, j int) bool {
+		return strings.Join(histograms[i].values, ",") < strings.Join(histograms[j].values, ",")
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", v.name, v.help, v.name)
+	for _, h := range histograms {
+		labels := labelPairs(v.labels, h.values)
+		s := h.Snapshot()
+		for i, bound := range s.Bounds {
+			fmt.Fprintf(&b, "%s_bucket{%sle=%q} %d\n", v.name, labels, strconv.FormatFloat(bound, 'g', -1, 64), s.Cumulative[i])
+		}
+		fmt.Fprintf(&b, "%s_bucket{%sle=\"+Inf\"} %d\n", v.name, labels, s.Count)
+		fmt.Fprintf(&b, "%s_sum{%s} %s\n", v.name, strings.TrimSuffix(labels, ","), strconv.FormatFloat(s.Sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "%s_count{%s} %d\n", v.name, strings.TrimSuffix(labels, ","), s.Count)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// labelPairs formats label values as `name="value",` pairs
+func labelPairs(names, values []string) string {
+	var b strings.Builder
+	for i, name := range names {
+		fmt.Fprintf(&b, "%s=%q,", name, values[i])
+	}
+	return b.String()
+}
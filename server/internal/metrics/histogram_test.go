@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogramBuckets(t *testing.T) {
+	h := newHistogram([]float64{.001, .01, .1})
+	for _, d := range []time.Duration{500 * time.Microsecond, time.Millisecond, 5 * time.Millisecond, 50 * time.Millisecond, time.Second} {
+		h.Observe(d)
+	}
+
+	s := h.Snapshot()
+	want := []uint64{2, 3, 4} // A bound includes observations equal to it
+	for i := range want {
+		if s.Cumulative[i] != want[i] {
+			t.Errorf("bucket le=%g has %d, want %d", s.Bounds[i], s.Cumulative[i], want[i])
+		}
+	}
+	if s.Count != 5 {
+		t.Errorf("count %d, want 5", s.Count)
+	}
+	if s.Sum < 1.0565 || s.Sum > 1.0566 {
+		t.Errorf("sum %g, want 1.0565", s.Sum)
+	}
+}
+
+func TestHistogramVecWrite(t *testing.T) {
+	v := NewHistogramVec("test_seconds", "Test latency", []float64{.01, .1}, "type", "stage")
+	v.With("SLAP", "handle").Observe(20 * time.Millisecond)
+	v.With("SLAP", "handle").Observe(5 * time.Millisecond)
+	v.With("PLAY_CARD", "parse").Observe(time.Millisecond)
+
+	var b strings.Builder
+	if err := v.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, line := range []string{
+		"# TYPE test_seconds histogram",
+		`test_seconds_bucket{type="PLAY_CARD",stage="parse",le="0.01"} 1`,
+		`test_seconds_bucket{type="SLAP",stage="handle",le="0.01"} 1`,
+		`test_seconds_bucket{type="SLAP",stage="handle",le="0.1"} 2`,
+		`test_seconds_bucket{type="SLAP",stage="handle",le="+Inf"} 2`,
+		`test_seconds_count{type="SLAP",stage="handle"} 2`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("output missing %q:\n%s", line, got)
+		}
+	}
+	if strings.Index(got, `type="PLAY_CARD"`) > strings.Index(got, `type="SLAP"`) {
+		t.Error("histograms not sorted by label values")
+	}
+}
+
+func TestCounterVecWrite(t *testing.T) {
+	v := NewCounterVec("test_total", "Test events", "event")
+	v.With("dropped").Inc()
+	v.With("dropped").Inc()
+	v.With("resynced").Inc()
+
+	var b strings.Builder
+	if err := v.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "# HELP test_total Test events\n# TYPE test_total counter\n" +
+		"test_total{event=\"dropped\"} 2\ntest_total{event=\"resynced\"} 1\n"
+	if got := b.String(); got != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,239 @@
+package names
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Longest player name allowed, in characters
+const MaxLength = 20
+
+var (
+	ErrEmpty       = errors.New("name is required")
+	ErrTooLong     = errors.New("name must be 20 characters or less")
+	ErrProfanity   = errors.New("please choose a different name")
+	ErrImpersonate = errors.New("that name is too close to another player's")
+)
+
+// Default blocked words. Words are only matched whole, so names like
+// "Dickens", "Hancock" and "Cassie" still pass; the first list also matches
+// the words with common endings ("dicks"), the second only exactly
+var (
+	defaultWords = []string{"fuck", "fucker", "shit", "bitch", "cunt", "whore", "slut", "bastard", "dick", "cock", "piss", "crap", "damn", "asshole"}
+	defaultExact = []string{"ass", "fag", "hell", "tit", "sex"}
+)
+
+// Endings a blocked word is also matched with, unless it is matched exactly
+var wordEndings = []string{"", "s", "es", "ed", "ing", "in"}
+
+// Validator cleans player names and checks them against a word list
+type Validator struct {
+	filterProfanity bool
+	words           []string // Skeletons of the blocked words
+	exact           []string
+}
+
+// NewValidator creates a validator; the default word list is used when
+// wordListPath is empty
+// Profanity is only rejected by Clean when filterProfanity is set, but is
+// always available through ContainsProfanity
+func NewValidator(filterProfanity bool, wordListPath string) (*Validator, error) {
+	words, exact := defaultWords, defaultExact
+	if wordListPath != "" {
+		var err error
+		if words, exact, err = loadWordList(wordListPath); err != nil {
+			return nil, err
+		}
+	}
+
+	v := &Validator{filterProfanity: filterProfanity}
+	for _, word := range words {
+		v.words = append(v.words, skeleton(word))
+	}
+	for _, word := range exact {
+		v.exact = append(v.exact, skeleton(word))
+	}
+	return v, nil
+}
+
+// Default is the validator used when none is configured
+var Default, _ = NewValidator(false, "")
+
+// loadWordList reads one blocked word per line; words starting with "=" only
+// match exactly, and lines starting with "#" are comments
+func loadWordList(path string) (words, exact []string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "="):
+			exact = append(exact, strings.TrimPrefix(line, "="))
+		default:
+			words = append(words, line)
+		}
+	}
+	return words, exact, scanner.Err()
+}
+
+// Clean normalizes a name and checks it, returning the name to use
+// Compatibility forms are folded (so fullwidth and styled letters become plain
+// ones), control and invisible formatting characters are removed, and runs of
+// whitespace become a single space
+func (v *Validator) Clean(name string) (string, error) {
+	name = norm.NFKC.String(name)
+
+	var b strings.Builder
+	space := false
+	for _, r := range name {
+		switch {
+		case unicode.IsSpace(r):
+			space = b.Len() > 0
+		case unicode.IsControl(r) || unicode.Is(unicode.Cf, r) || r == utf8.RuneError:
+		default:
+			if space {
+				b.WriteByte(' ')
+				space = false
+			}
+			b.WriteRune(r)
+		}
+	}
+	name = b.String()
+
+	if name == "" {
+		return "", ErrEmpty
+	}
+	if utf8.RuneCountInString(name) > MaxLength {
+		return "", ErrTooLong
+	}
+	if v.filterProfanity && v.ContainsProfanity(name) {
+		return "", ErrProfanity
+	}
+	return name, nil
+}
+
+// ContainsProfanity reports whether a word in a name is blocked, looking
+// through look-alike characters and words spelled out a letter at a time
+func (v *Validator) ContainsProfanity(name string) bool {
+	for _, word := range words(name) {
+		if v.blocked(word) {
+			return true
+		}
+	}
+	return false
+}
+
+// blocked reports whether the skeleton of a word is a blocked word
+func (v *Validator) blocked(word string) bool {
+	for _, exact := range v.exact {
+		if word == exact {
+			return true
+		}
+	}
+	for _, blocked := range v.words {
+		ending, ok := strings.CutPrefix(word, blocked)
+		if !ok {
+			continue
+		}
+		for _, e := range wordEndings {
+			if ending == e {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// words splits a name into the skeletons of its words, breaking at anything
+// but letters, digits and look-alikes, and where lower case turns to upper
+// ("BigName"). Runs of single letters are joined, so "F U N" is one word
+func words(name string) []string {
+	var split []string
+	var word []rune
+	end := func() {
+		if w := skeleton(string(word)); w != "" {
+			split = append(split, w)
+		}
+		word = word[:0]
+	}
+	var prev rune
+	for _, r := range name {
+		_, lookalike := lookalikes[r]
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r) && !lookalike:
+			end()
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			end()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+		prev = r
+	}
+	end()
+
+	var joined []string
+	letters := ""
+	for _, w := range split {
+		if utf8.RuneCountInString(w) == 1 {
+			letters += w
+			continue
+		}
+		if letters != "" {
+			joined = append(joined, letters)
+			letters = ""
+		}
+		joined = append(joined, w)
+	}
+	if letters != "" {
+		joined = append(joined, letters)
+	}
+	return joined
+}
+
+// Impersonates reports whether name could pass for one of the others, for
+// example "Alice" against "ALlCE" or "A l i c e"
+func Impersonates(name string, others []string) bool {
+	s := skeleton(name)
+	for _, other := range others {
+		if skeleton(other) == s {
+			return true
+		}
+	}
+	return false
+}
+
+// lookalikes maps characters to the letter they pass for
+var lookalikes = map[rune]rune{
+	'0': 'o', '1': 'l', 'i': 'l', '|': 'l', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b', '@': 'a', '$': 's',
+	// Cyrillic and Greek letters that look Latin
+	'а': 'a', 'е': 'e', 'о': 'o', 'р': 'p', 'с': 'c', 'х': 'x', 'у': 'y', 'і': 'l', 'ј': 'j', 'ѕ': 's', 'к': 'k', 'м': 'm', 'т': 't', 'в': 'b', 'н': 'h',
+	'α': 'a', 'ο': 'o', 'ν': 'v', 'τ': 't', 'ι': 'l', 'κ': 'k', 'ρ': 'p',
+}
+
+// skeleton reduces a name to what it looks like: lower case, look-alikes
+// folded, accents and everything but letters and digits dropped
+func skeleton(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(strings.ToLower(name)) {
+		if mapped, ok := lookalikes[r]; ok {
+			r = mapped
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return strings.ReplaceAll(b.String(), "rn", "m")
+}
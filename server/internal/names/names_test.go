@@ -0,0 +1,29 @@
+package names
+
+import "testing"
+
+func TestContainsProfanity(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"Dickens", false},
+		{"Hancock", false},
+		{"Scunthorpe", false},
+		{"Cassie", false},
+		{"Shelly", false},
+		{"Dick", true},
+		{"big dicks", true},
+		{"BigDick", true},
+		{"d1ck", true},
+		{"F U C K", true},
+		{"f.u.c.k", true},
+		{"ass", true},
+		{"asses", false}, // Exact words take no endings
+	}
+	for _, tt := range tests {
+		if got := Default.ContainsProfanity(tt.name); got != tt.want {
+			t.Errorf("ContainsProfanity(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -3,34 +3,56 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
+// Store keeps server state in Redis. Every call takes a context and gives up
+// once the store's timeout passes, so a slow Redis can't hold up its caller
+// for longer than that.
 type Store struct {
-	client *redis.Client
-	ctx    context.Context
+	client  *redis.Client
+	timeout time.Duration
 }
 
-func NewStore(redisURL string) (*Store, error) {
+// NewStore connects to Redis; calls taking longer than timeout fail with
+// context.DeadlineExceeded
+func NewStore(redisURL string, timeout time.Duration) (*Store, error) {
 	opt, err := redis.ParseURL(redisURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse redis URL: %w", err)
 	}
 
 	client := redis.NewClient(opt)
+	s := &Store{
+		client:  client,
+		timeout: timeout,
+	}
 
-	ctx := context.Background()
-	if err := client.Ping(ctx).Err(); err != nil {
+	if err := s.Ping(context.Background()); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to connect to redis: %w", err)
 	}
+	return s, nil
+}
 
-	return &Store{
-		client: client,
-		ctx:    ctx,
-	}, nil
+// withTimeout bounds a call by the store's timeout, as well as by ctx
+func (s *Store) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.timeout)
+}
+
+// Ping checks that Redis is reachable
+func (s *Store) Ping(ctx context.Context) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.Ping(ctx).Err()
 }
 
 func (s *Store) Close() error {
@@ -39,48 +61,126 @@ func (s *Store) Close() error {
 
 // Room operations
 
-func (s *Store) SetRoom(code string, data interface{}, ttl time.Duration) error {
+func (s *Store) SetRoom(ctx context.Context, code string, data interface{}, ttl time.Duration) error {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
-	return s.client.Set(s.ctx, fmt.Sprintf("room:%s:state", code), jsonData, ttl).Err()
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.Set(ctx, fmt.Sprintf("room:%s:state", code), jsonData, ttl).Err()
 }
 
-func (s *Store) GetRoom(code string, dest interface{}) error {
-	data, err := s.client.Get(s.ctx, fmt.Sprintf("room:%s:state", code)).Bytes()
+func (s *Store) GetRoom(ctx context.Context, code string, dest interface{}) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	data, err := s.client.Get(ctx, fmt.Sprintf("room:%s:state", code)).Bytes()
 	if err != nil {
 		return err
 	}
 	return json.Unmarshal(data, dest)
 }
 
-func (s *Store) DeleteRoom(code string) error {
+func (s *Store) DeleteRoom(ctx context.Context, code string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	pipe := s.client.Pipeline()
-	pipe.Del(s.ctx, fmt.Sprintf("room:%s:state", code))
-	pipe.Del(s.ctx, fmt.Sprintf("room:%s:game", code))
-	pipe.SRem(s.ctx, "rooms:active", code)
-	_, err := pipe.Exec(s.ctx)
+	pipe.Del(ctx, fmt.Sprintf("room:%s:state", code))
+	pipe.Del(ctx, fmt.Sprintf("room:%s:game", code))
+	pipe.SRem(ctx, "rooms:active", code)
+	_, err := pipe.Exec(ctx)
 	return err
 }
 
-func (s *Store) RoomExists(code string) (bool, error) {
-	result, err := s.client.Exists(s.ctx, fmt.Sprintf("room:%s:state", code)).Result()
+func (s *Store) RoomExists(ctx context.Context, code string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	result, err := s.client.Exists(ctx, fmt.Sprintf("room:%s:state", code)).Result()
 	return result > 0, err
 }
 
 // Game state operations
 
-func (s *Store) SetGameState(code string, data interface{}, ttl time.Duration) error {
+func (s *Store) SetGameState(ctx context.Context, code string, data interface{}, ttl time.Duration) error {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
-	return s.client.Set(s.ctx, fmt.Sprintf("room:%s:game", code), jsonData, ttl).Err()
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.Set(ctx, fmt.Sprintf("room:%s:game", code), jsonData, ttl).Err()
 }
 
-func (s *Store) GetGameState(code string, dest interface{}) error {
-	data, err := s.client.Get(s.ctx, fmt.Sprintf("room:%s:game", code)).Bytes()
+func (s *Store) GetGameState(ctx context.Context, code string, dest interface{}) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	data, err := s.client.Get(ctx, fmt.Sprintf("room:%s:game", code)).Bytes()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dest)
+}
+
+// Replay operations
+
+func (s *Store) SetReplay(ctx context.Context, code, gameID string, data interface{}, ttl time.Duration) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.Set(ctx, fmt.Sprintf("replay:%s:%s", code, gameID), jsonData, ttl).Err()
+}
+
+func (s *Store) GetReplay(ctx context.Context, code, gameID string, dest interface{}) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	data, err := s.client.Get(ctx, fmt.Sprintf("replay:%s:%s", code, gameID)).Bytes()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dest)
+}
+
+// Highlight operations
+
+func (s *Store) SetHighlights(ctx context.Context, gameID string, data interface{}, ttl time.Duration) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.Set(ctx, fmt.Sprintf("highlights:%s", gameID), jsonData, ttl).Err()
+}
+
+func (s *Store) GetHighlights(ctx context.Context, gameID string, dest interface{}) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	data, err := s.client.Get(ctx, fmt.Sprintf("highlights:%s", gameID)).Bytes()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dest)
+}
+
+// Stats rollups
+
+func (s *Store) SetStatsRollup(ctx context.Context, kind, period string, data interface{}, ttl time.Duration) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.Set(ctx, fmt.Sprintf("stats:%s:%s", kind, period), jsonData, ttl).Err()
+}
+
+func (s *Store) GetStatsRollup(ctx context.Context, kind, period string, dest interface{}) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	data, err := s.client.Get(ctx, fmt.Sprintf("stats:%s:%s", kind, period)).Bytes()
 	if err != nil {
 		return err
 	}
@@ -89,20 +189,52 @@ func (s *Store) GetGameState(code string, dest interface{}) error {
 
 // Active rooms set
 
-func (s *Store) AddActiveRoom(code string) error {
-	return s.client.SAdd(s.ctx, "rooms:active", code).Err()
+func (s *Store) AddActiveRoom(ctx context.Context, code string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.SAdd(ctx, "rooms:active", code).Err()
+}
+
+func (s *Store) RemoveActiveRoom(ctx context.Context, code string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.SRem(ctx, "rooms:active", code).Err()
+}
+
+func (s *Store) IsRoomCodeTaken(ctx context.Context, code string) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.SIsMember(ctx, "rooms:active", code).Result()
 }
 
-func (s *Store) RemoveActiveRoom(code string) error {
-	return s.client.SRem(s.ctx, "rooms:active", code).Err()
+func (s *Store) GetActiveRoomCount(ctx context.Context) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.SCard(ctx, "rooms:active").Result()
 }
 
-func (s *Store) IsRoomCodeTaken(code string) (bool, error) {
-	return s.client.SIsMember(s.ctx, "rooms:active", code).Result()
+func (s *Store) GetActiveRooms(ctx context.Context) ([]string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.SMembers(ctx, "rooms:active").Result()
 }
 
-func (s *Store) GetActiveRoomCount() (int64, error) {
-	return s.client.SCard(s.ctx, "rooms:active").Result()
+// Schema version, advanced by the migrate command
+
+func (s *Store) GetSchemaVersion(ctx context.Context) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	v, err := s.client.Get(ctx, "schema:version").Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return v, err
+}
+
+func (s *Store) SetSchemaVersion(ctx context.Context, version int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.Set(ctx, "schema:version", version, 0).Err()
 }
 
 // Session operations (for reconnection)
@@ -113,16 +245,20 @@ type SessionData struct {
 	ExpiresAt time.Time `json:"expiresAt"`
 }
 
-func (s *Store) SetSession(sessionID string, data SessionData, ttl time.Duration) error {
+func (s *Store) SetSession(ctx context.Context, sessionID string, data SessionData, ttl time.Duration) error {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
-	return s.client.Set(s.ctx, fmt.Sprintf("session:%s", sessionID), jsonData, ttl).Err()
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.Set(ctx, fmt.Sprintf("session:%s", sessionID), jsonData, ttl).Err()
 }
 
-func (s *Store) GetSession(sessionID string) (*SessionData, error) {
-	data, err := s.client.Get(s.ctx, fmt.Sprintf("session:%s", sessionID)).Bytes()
+func (s *Store) GetSession(ctx context.Context, sessionID string) (*SessionData, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	data, err := s.client.Get(ctx, fmt.Sprintf("session:%s", sessionID)).Bytes()
 	if err != nil {
 		if err == redis.Nil {
 			return nil, nil
@@ -136,10 +272,257 @@ func (s *Store) GetSession(sessionID string) (*SessionData, error) {
 	return &session, nil
 }
 
-func (s *Store) DeleteSession(sessionID string) error {
-	return s.client.Del(s.ctx, fmt.Sprintf("session:%s", sessionID)).Err()
+func (s *Store) DeleteSession(ctx context.Context, sessionID string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.Del(ctx, fmt.Sprintf("session:%s", sessionID)).Err()
+}
+
+// SetSessionTokenHash stores the hash of a session's reconnection token
+func (s *Store) SetSessionTokenHash(ctx context.Context, sessionID, hash string, ttl time.Duration) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.Set(ctx, fmt.Sprintf("session_token:%s", sessionID), hash, ttl).Err()
+}
+
+// GetSessionTokenHash returns the stored token hash for a session, or "" if none
+func (s *Store) GetSessionTokenHash(ctx context.Context, sessionID string) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	hash, err := s.client.Get(ctx, fmt.Sprintf("session_token:%s", sessionID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return hash, err
+}
+
+func (s *Store) ExtendSession(ctx context.Context, sessionID string, ttl time.Duration) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.Expire(ctx, fmt.Sprintf("session:%s", sessionID), ttl).Err()
+}
+
+// Room invite operations
+
+// SetInvite stores the hash of a room invite, with the room it lets a player into
+func (s *Store) SetInvite(ctx context.Context, hash, roomCode string, ttl time.Duration) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.Set(ctx, fmt.Sprintf("invite:%s", hash), roomCode, ttl).Err()
+}
+
+// TakeInvite deletes an invite and returns its room, or "" if there is none;
+// reading and deleting in one transaction means an invite is taken only once
+func (s *Store) TakeInvite(ctx context.Context, hash string) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	key := fmt.Sprintf("invite:%s", hash)
+	var get *redis.StringCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return get.Val(), nil
 }
 
-func (s *Store) ExtendSession(sessionID string, ttl time.Duration) error {
-	return s.client.Expire(s.ctx, fmt.Sprintf("session:%s", sessionID), ttl).Err()
+// Wallet operations
+
+// Attempts at a wallet update before giving up on concurrent changes
+const maxWalletRetries = 5
+
+var ErrWalletContention = errors.New("wallet changed concurrently, try again")
+
+// UpdateWallet replaces a wallet with update's result in a transaction, so
+// concurrent updates can't lose coins. update gets the stored JSON, or nil if
+// there is none, and may run more than once. Returns the new wallet.
+func (s *Store) UpdateWallet(ctx context.Context, walletID string, ttl time.Duration, update func(data []byte) ([]byte, error)) ([]byte, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	key := fmt.Sprintf("wallet:%s", walletID)
+
+	var updated []byte
+	txf := func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if updated, err = update(data); err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, updated, ttl)
+			return nil
+		})
+		return err
+	}
+
+	for attempt := 0; attempt < maxWalletRetries; attempt++ {
+		err := s.client.Watch(ctx, txf, key)
+		if err != redis.TxFailedErr {
+			return updated, err
+		}
+	}
+	return nil, ErrWalletContention
+}
+
+// GetWallet returns a wallet's stored JSON, or nil if there is none
+func (s *Store) GetWallet(ctx context.Context, walletID string) ([]byte, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	data, err := s.client.Get(ctx, fmt.Sprintf("wallet:%s", walletID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Player profile operations
+
+func (s *Store) SetProfile(ctx context.Context, profileID string, data interface{}, ttl time.Duration) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.Set(ctx, fmt.Sprintf("profile:%s", profileID), jsonData, ttl).Err()
+}
+
+// GetProfile loads a profile into dest, reporting false if there is none
+func (s *Store) GetProfile(ctx context.Context, profileID string, dest interface{}) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	data, err := s.client.Get(ctx, fmt.Sprintf("profile:%s", profileID)).Bytes()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, dest)
+}
+
+// Input latency calibration operations
+
+func (s *Store) SetCalibration(ctx context.Context, calibrationID string, data interface{}, ttl time.Duration) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.Set(ctx, fmt.Sprintf("calibration:%s", calibrationID), jsonData, ttl).Err()
+}
+
+// GetCalibration loads a calibration into dest, reporting false if there is none
+func (s *Store) GetCalibration(ctx context.Context, calibrationID string, dest interface{}) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	data, err := s.client.Get(ctx, fmt.Sprintf("calibration:%s", calibrationID)).Bytes()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, dest)
+}
+
+// Leaderboard operations
+
+// LeaderboardTotals is a player's running record across finished games
+type LeaderboardTotals struct {
+	ID     string
+	Name   string
+	Played int
+	Wins   int
+	Slaps  int   // Successful slaps
+	SlapMs int64 // Their reaction times, summed
+}
+
+// AddLeaderboardGame adds a finished game to a player's totals, keeping the
+// name they played it under, and returns the new totals
+func (s *Store) AddLeaderboardGame(ctx context.Context, id, name string, won bool, slaps int, slapMs int64) (LeaderboardTotals, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	key := fmt.Sprintf("leaderboard:player:%s", id)
+	wins := int64(0)
+	if won {
+		wins = 1
+	}
+
+	var played, winsCmd, slapsCmd, slapMsCmd *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "name", name)
+		played = pipe.HIncrBy(ctx, key, "played", 1)
+		winsCmd = pipe.HIncrBy(ctx, key, "wins", wins)
+		slapsCmd = pipe.HIncrBy(ctx, key, "slaps", int64(slaps))
+		slapMsCmd = pipe.HIncrBy(ctx, key, "slapMs", slapMs)
+		return nil
+	})
+	if err != nil {
+		return LeaderboardTotals{}, err
+	}
+	return LeaderboardTotals{
+		ID:     id,
+		Name:   name,
+		Played: int(played.Val()),
+		Wins:   int(winsCmd.Val()),
+		Slaps:  int(slapsCmd.Val()),
+		SlapMs: slapMsCmd.Val(),
+	}, nil
+}
+
+// SetLeaderboardScore ranks a player on a leaderboard
+func (s *Store) SetLeaderboardScore(ctx context.Context, board, id string, score float64) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.client.ZAdd(ctx, fmt.Sprintf("leaderboard:%s", board), &redis.Z{Score: score, Member: id}).Err()
+}
+
+// GetLeaderboard returns the totals of a leaderboard's top players, highest
+// score first or, if ascending, lowest
+func (s *Store) GetLeaderboard(ctx context.Context, board string, limit int, ascending bool) ([]LeaderboardTotals, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	key := fmt.Sprintf("leaderboard:%s", board)
+	var ids []string
+	var err error
+	if ascending {
+		ids, err = s.client.ZRange(ctx, key, 0, int64(limit-1)).Result()
+	} else {
+		ids, err = s.client.ZRevRange(ctx, key, 0, int64(limit-1)).Result()
+	}
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	cmds := make([]*redis.StringStringMapCmd, len(ids))
+	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.HGetAll(ctx, fmt.Sprintf("leaderboard:player:%s", id))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make([]LeaderboardTotals, len(ids))
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		totals[i] = LeaderboardTotals{ID: ids[i], Name: fields["name"]}
+		totals[i].Played, _ = strconv.Atoi(fields["played"])
+		totals[i].Wins, _ = strconv.Atoi(fields["wins"])
+		totals[i].Slaps, _ = strconv.Atoi(fields["slaps"])
+		totals[i].SlapMs, _ = strconv.ParseInt(fields["slapMs"], 10, 64)
+	}
+	return totals, nil
 }
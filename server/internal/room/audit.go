@@ -0,0 +1,89 @@
+package room
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"slapjack/pkg/protocol"
+)
+
+// Most recent audit log entries kept per room
+const maxAuditEntries = 100
+
+// auditLog is a ring buffer of a room's most recent audit entries
+type auditLog struct {
+	entries []protocol.AuditEntry
+	next    int // Where the next entry goes once the buffer is full
+}
+
+func (l *auditLog) add(entry protocol.AuditEntry) {
+	if len(l.entries) < maxAuditEntries {
+		l.entries = append(l.entries, entry)
+		return
+	}
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % maxAuditEntries
+}
+
+// list returns the entries oldest first
+func (l *auditLog) list() []protocol.AuditEntry {
+	out := make([]protocol.AuditEntry, 0, len(l.entries))
+	out = append(out, l.entries[l.next:]...)
+	return append(out, l.entries[:l.next]...)
+}
+
+// recordAudit adds an entry to the room's audit log, naming the actor and
+// target from their seats
+// Caller must hold r.mu, with both players still seated
+func (r *Room) recordAudit(kind, actorID, targetID, detail string) {
+	entry := protocol.AuditEntry{
+		Type:      kind,
+		ActorID:   actorID,
+		TargetID:  targetID,
+		Detail:    detail,
+		Timestamp: r.clock.Now().UnixMilli(),
+	}
+	if p, ok := r.Players[actorID]; ok {
+		entry.ActorName = p.Name
+	}
+	if p, ok := r.Players[targetID]; ok {
+		entry.TargetName = p.Name
+	}
+	r.audit.add(entry)
+}
+
+// AuditLog returns the room's most recent audit entries, oldest first
+func (r *Room) AuditLog() []protocol.AuditEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.audit.list()
+}
+
+// IsHostToken reports whether a player token belongs to the room's host
+func (r *Room) IsHostToken(token string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	host, ok := r.Players[r.HostID]
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(host.Token), []byte(token)) == 1
+}
+
+// changedSettings lists the JSON names of the settings that differ, in order
+func changedSettings(before, after protocol.RoomSettings) string {
+	var old, updated map[string]interface{}
+	data, _ := json.Marshal(before)
+	json.Unmarshal(data, &old)
+	data, _ = json.Marshal(after)
+	json.Unmarshal(data, &updated)
+
+	var changed []string
+	for key, value := range updated {
+		if !reflect.DeepEqual(old[key], value) {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return strings.Join(changed, ", ")
+}
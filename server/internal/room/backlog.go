@@ -0,0 +1,127 @@
+package room
+
+import "sync"
+
+// How many of a room's most recent broadcasts are kept for replaying to a
+// reconnecting player
+const maxBacklog = 256
+
+// Broadcast is a sequenced room broadcast kept in the backlog
+type Broadcast struct {
+	Seq     int64
+	Message []byte
+	Except  string // Session the broadcast wasn't sent to, if any
+}
+
+// backlog is a ring buffer of a room's most recent broadcasts, along with
+// the last broadcast each player has acknowledged and the last numbered
+// action of theirs applied, both of which outlive their connection
+// It has its own lock, since broadcasts are recorded without holding r.mu
+type backlog struct {
+	entries []Broadcast
+	next    int // Where the next entry goes once the buffer is full
+	acks    map[string]int64
+	inputs  map[string]int64
+
+	mu sync.Mutex
+}
+
+// Record adds a sequenced broadcast to the room's backlog
+func (r *Room) Record(b Broadcast) {
+	l := &r.backlog
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.entries) < maxBacklog {
+		l.entries = append(l.entries, b)
+		return
+	}
+	l.entries[l.next] = b
+	l.next = (l.next + 1) % maxBacklog
+}
+
+// BacklogSince returns the broadcasts after seq, oldest first, and false if
+// some of them have already dropped out of the backlog
+func (r *Room) BacklogSince(seq int64) ([]Broadcast, bool) {
+	l := &r.backlog
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ordered := make([]Broadcast, 0, len(l.entries))
+	ordered = append(ordered, l.entries[l.next:]...)
+	ordered = append(ordered, l.entries[:l.next]...)
+
+	if len(ordered) == 0 {
+		return nil, seq >= r.Seq()
+	}
+	if ordered[0].Seq > seq+1 {
+		return nil, false
+	}
+	for i, b := range ordered {
+		if b.Seq > seq {
+			return ordered[i:], true
+		}
+	}
+	return nil, true
+}
+
+// Ack records the last broadcast a player has received; acks never go
+// backwards or past the last broadcast
+func (r *Room) Ack(playerID string, seq int64) {
+	if seq > r.Seq() {
+		seq = r.Seq()
+	}
+	l := &r.backlog
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.acks == nil {
+		l.acks = make(map[string]int64)
+	}
+	if seq > l.acks[playerID] {
+		l.acks[playerID] = seq
+	}
+}
+
+// LastAck returns the last broadcast a player acknowledged, or 0 if none
+func (r *Room) LastAck(playerID string) int64 {
+	l := &r.backlog
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.acks[playerID]
+}
+
+// TakeInput records a player's numbered action as applied, returning false
+// if it, or a later one, already was, as when a client resends an action
+// after reconnecting
+func (r *Room) TakeInput(playerID string, inputSeq int64) bool {
+	l := &r.backlog
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if inputSeq <= l.inputs[playerID] {
+		return false
+	}
+	if l.inputs == nil {
+		l.inputs = make(map[string]int64)
+	}
+	l.inputs[playerID] = inputSeq
+	return true
+}
+
+// LastInput returns the last numbered action of a player's applied, or 0 if none
+func (r *Room) LastInput(playerID string) int64 {
+	l := &r.backlog
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inputs[playerID]
+}
+
+// forgetAck drops a departed player's ack and last applied action
+func (r *Room) forgetAck(playerID string) {
+	l := &r.backlog
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.acks, playerID)
+	delete(l.inputs, playerID)
+}
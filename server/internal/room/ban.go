@@ -0,0 +1,81 @@
+package room
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"slapjack/pkg/protocol"
+)
+
+// ErrBanned is returned when a player the host banned tries to rejoin
+var ErrBanned = errors.New("you've been banned from this room")
+
+// Longest ban a host can set; longer ones, and those with no duration, last
+// until the room closes
+const maxBanMinutes = 24 * 60
+
+// Ban bars a player from rejoining, by their player token and any profile,
+// for the given minutes or until the room closes if zero
+// Call before removing the player from the room
+func (r *Room) Ban(playerID string, minutes int, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.Players[playerID]
+	if !ok {
+		return errors.New("player not found")
+	}
+	r.ban(p, minutes, now, r.HostID)
+	return nil
+}
+
+// ban bars a player from rejoining, recording who did it
+// Caller must hold r.mu
+func (r *Room) ban(p *Player, minutes int, now time.Time, actorID string) {
+	var until time.Time
+	detail := "until the room closes"
+	if minutes > 0 && minutes <= maxBanMinutes {
+		until = now.Add(time.Duration(minutes) * time.Minute)
+		detail = fmt.Sprintf("for %d minutes", minutes)
+	}
+	if r.Bans == nil {
+		r.Bans = make(map[string]time.Time)
+	}
+	if p.Token != "" {
+		r.Bans[banKey("token", p.Token)] = until
+	}
+	if p.ProfileID != "" {
+		r.Bans[banKey("profile", p.ProfileID)] = until
+	}
+	r.recordAudit(protocol.AuditBan, actorID, p.ID, detail)
+}
+
+// IsBanned reports whether a player token or profile is banned from the room
+func (r *Room) IsBanned(token, profileID string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range []string{banKey("token", token), banKey("profile", profileID)} {
+		if key == "" {
+			continue
+		}
+		until, ok := r.Bans[key]
+		if !ok {
+			continue
+		}
+		if until.IsZero() || now.Before(until) {
+			return true
+		}
+		delete(r.Bans, key)
+	}
+	return false
+}
+
+// banKey keys the ban list by the kind of identity, or returns "" if there is none
+func banKey(kind, id string) string {
+	if id == "" {
+		return ""
+	}
+	return kind + ":" + id
+}
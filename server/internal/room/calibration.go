@@ -0,0 +1,183 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"slapjack/pkg/protocol"
+)
+
+// Input latency calibration flashes at a steady beat so players tap in time
+// with it rather than reacting to it; the taps then trail the flashes by the
+// round trip plus the device's input and display lag
+const (
+	CalibrationRounds   = 10
+	CalibrationInterval = 800 * time.Millisecond
+
+	// Flashes before the player has found the beat, not counted
+	calibrationWarmup = 2
+
+	// Taps needed for an estimate
+	minCalibrationSamples = 5
+
+	// Most input lag allowed for; more would let a player tapping late on
+	// purpose slap with a head start
+	maxInputLagMs = 150
+)
+
+var ErrCalibrationFailed = errors.New("not enough taps in time with the flashes, try again")
+
+// EstimateInputLag estimates a device's input lag from when calibration
+// flashes were sent and taps arrived, given the client's round trip.
+// Each tap counts against the nearest flash, once, if within half a beat of it;
+// the estimate is the median of what is left after the round trip.
+func EstimateInputLag(flashes, taps []time.Time, rtt time.Duration) (lagMs int64, samples int, err error) {
+	matched := make(map[int]time.Duration)
+	for _, tap := range taps {
+		sent := tap.Add(-rtt)
+		best := -1
+		var bestOffset time.Duration
+		for i, flash := range flashes {
+			offset := sent.Sub(flash)
+			if best == -1 || abs(offset) < abs(bestOffset) {
+				best, bestOffset = i, offset
+			}
+		}
+		if best < calibrationWarmup || abs(bestOffset) > CalibrationInterval/2 {
+			continue
+		}
+		if _, seen := matched[best]; !seen {
+			matched[best] = bestOffset
+		}
+	}
+	if len(matched) < minCalibrationSamples {
+		return 0, len(matched), ErrCalibrationFailed
+	}
+
+	offsets := make([]time.Duration, 0, len(matched))
+	for _, offset := range matched {
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	median := offsets[len(offsets)/2]
+	if len(offsets)%2 == 0 {
+		median = (offsets[len(offsets)/2-1] + median) / 2
+	}
+
+	lagMs = median.Milliseconds()
+	if lagMs < 0 {
+		lagMs = 0
+	}
+	if lagMs > maxInputLagMs {
+		lagMs = maxInputLagMs
+	}
+	return lagMs, len(offsets), nil
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// calibrations holds input lag estimates in memory when there is no Redis
+type calibrations struct {
+	byID map[string]protocol.CalibrationResultPayload
+	mu   sync.Mutex
+}
+
+func newCalibrations() *calibrations {
+	return &calibrations{byID: make(map[string]protocol.CalibrationResultPayload)}
+}
+
+// SaveCalibration stores a player token's input lag estimate; the token is
+// kept per browser, so this is per device
+func (m *Manager) SaveCalibration(token, device string, lagMs int64, samples int) (protocol.CalibrationResultPayload, error) {
+	device = strings.TrimSpace(device)
+	if len(device) > maxDeviceBytes {
+		device = device[:maxDeviceBytes]
+	}
+	result := protocol.CalibrationResultPayload{
+		InputLagMs:   lagMs,
+		Samples:      samples,
+		Device:       device,
+		CalibratedAt: m.clock.Now().UnixMilli(),
+	}
+	if token == "" {
+		return result, ErrNoPlayerToken
+	}
+	id := hashToken(token)
+
+	if m.store == nil {
+		m.calibrations.mu.Lock()
+		m.calibrations.byID[id] = result
+		m.calibrations.mu.Unlock()
+		return result, nil
+	}
+
+	if err := m.store.SetCalibration(context.Background(), id, result, m.cfg.Get().ProfileTTL); err != nil {
+		m.storeHealth.fail("save calibration", err)
+		return result, err
+	}
+	return result, nil
+}
+
+// GetCalibration returns a player token's input lag estimate, reporting false
+// if the device hasn't been calibrated
+func (m *Manager) GetCalibration(token string) (protocol.CalibrationResultPayload, bool) {
+	if token == "" {
+		return protocol.CalibrationResultPayload{}, false
+	}
+	id := hashToken(token)
+
+	if m.store == nil {
+		m.calibrations.mu.Lock()
+		defer m.calibrations.mu.Unlock()
+		result, ok := m.calibrations.byID[id]
+		return result, ok
+	}
+
+	var result protocol.CalibrationResultPayload
+	found, err := m.store.GetCalibration(context.Background(), id, &result)
+	if err != nil {
+		m.storeHealth.fail("load calibration", err)
+		return protocol.CalibrationResultPayload{}, false
+	}
+	return result, found
+}
+
+// inputLag returns a player token's calibrated input lag, or 0 if none
+func (m *Manager) inputLag(token string) int64 {
+	result, _ := m.GetCalibration(token)
+	return result.InputLagMs
+}
+
+// SetInputLag applies a player's new input lag estimate in their room
+// Returns false if the player isn't in the room
+func (m *Manager) SetInputLag(roomCode, playerID string, lagMs int64) bool {
+	room := m.GetRoom(roomCode)
+	if room == nil {
+		return false
+	}
+	return room.setInputLag(playerID, lagMs)
+}
+
+// setInputLag records a player's input lag and passes it to the game for slap timing
+func (r *Room) setInputLag(playerID string, lagMs int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, seated := r.Players[playerID]; !seated {
+		return false
+	}
+	r.inputLag[playerID] = lagMs
+	if r.Game != nil {
+		r.Game.SetInputLag(playerID, time.Duration(lagMs)*time.Millisecond)
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package room
+
+import (
+	"errors"
+	"sort"
+	"unicode"
+
+	"slapjack/internal/game"
+	"slapjack/internal/names"
+)
+
+// Gentle limits enforced while classroom mode is on
+const (
+	classroomMaxBurnPenalty  = 1
+	classroomMinTurnTimeout  = 15000
+	classroomMinSlapCooldown = 300
+	classroomMaxNameLength   = 16
+)
+
+// applyClassroomLimits clamps settings to gentle values for classroom mode
+func (s *Settings) applyClassroomLimits() {
+	if !s.ClassroomMode {
+		return
+	}
+	if s.BurnPenalty > classroomMaxBurnPenalty {
+		s.BurnPenalty = classroomMaxBurnPenalty
+	}
+	if s.TurnTimeoutMs < classroomMinTurnTimeout {
+		s.TurnTimeoutMs = classroomMinTurnTimeout
+	}
+	if s.PacingMinTimeoutMs < classroomMinTurnTimeout {
+		s.PacingMinTimeoutMs = classroomMinTurnTimeout
+	}
+	if s.AutoPaceFloorMs < classroomMinTurnTimeout {
+		s.AutoPaceFloorMs = classroomMinTurnTimeout
+	}
+	if s.SlapCooldownMs < classroomMinSlapCooldown {
+		s.SlapCooldownMs = classroomMinSlapCooldown
+	}
+	s.EscalatePenalty = false
+	s.BurnDestination = game.BurnToPile
+	s.EnableRTC = false
+}
+
+// ValidateClassroomName applies the strict name rules used in classroom mode:
+// letters, digits and spaces only, and no profanity
+func ValidateClassroomName(name string) error {
+	if len(name) > classroomMaxNameLength {
+		return errors.New("name must be 16 characters or less")
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' {
+			return errors.New("name may only contain letters, numbers and spaces")
+		}
+	}
+	if names.Default.ContainsProfanity(name) {
+		return names.ErrProfanity
+	}
+	return nil
+}
+
+// ClassroomNameViolations lists, in seat order, the players whose names break
+// the classroom name rules. Names are only checked as they are chosen, so
+// these are the names to deal with before classroom mode is switched on
+func (r *Room) ClassroomNameViolations() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var players []*Player
+	for _, p := range r.Players {
+		if ValidateClassroomName(p.Name) != nil {
+			players = append(players, p)
+		}
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].Position < players[j].Position })
+	offending := make([]string, len(players))
+	for i, p := range players {
+		offending[i] = p.Name
+	}
+	return offending
+}
@@ -0,0 +1,136 @@
+package room
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"slapjack/pkg/protocol"
+)
+
+// beginCountdown moves the room to starting and returns a channel that is
+// closed if the countdown is cancelled
+func (r *Room) beginCountdown() (chan struct{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.countdownCancel != nil {
+		return nil, errors.New("game is already starting")
+	}
+	if r.Status == "playing" {
+		return nil, errors.New("game already in progress")
+	}
+
+	r.countdownCancel = make(chan struct{})
+	r.Status = "starting"
+	r.startingSince = r.clock.Now()
+	r.version++
+	return r.countdownCancel, nil
+}
+
+// endCountdown finishes the countdown identified by cancel
+// Returns false if it was cancelled, in which case the game must not start
+func (r *Room) endCountdown(cancel chan struct{}) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.countdownCancel != cancel {
+		return false
+	}
+	r.countdownCancel = nil
+	return true
+}
+
+// CancelCountdown stops a countdown in progress and returns the room to waiting
+// Returns false if no countdown was running
+func (r *Room) CancelCountdown() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.countdownCancel == nil {
+		return false
+	}
+	close(r.countdownCancel)
+	r.countdownCancel = nil
+	r.Status = "waiting"
+	r.version++
+	return true
+}
+
+// StartGameCountdown starts the game countdown, and then the game, in the background
+// Returns an error without starting anything if the room can't start a game now
+func (m *Manager) StartGameCountdown(roomCode string, broadcast func(string, []byte)) error {
+	room := m.GetRoom(roomCode)
+	if room == nil {
+		return errors.New("room not found")
+	}
+
+	cancel, err := room.beginCountdown()
+	if err != nil {
+		return err
+	}
+	go m.runCountdown(room, cancel, false, broadcast)
+	return nil
+}
+
+// HostStartGame starts a game at the host's request: as StartGameCountdown,
+// but first checking every player is ready if the room's settings ask for it
+func (m *Manager) HostStartGame(roomCode string, broadcast func(string, []byte)) error {
+	room := m.GetRoom(roomCode)
+	if room == nil {
+		return errors.New("room not found")
+	}
+
+	cancel, err := room.beginCountdown()
+	if err != nil {
+		return err
+	}
+	go m.runCountdown(room, cancel, room.GetSettings().ReadyCheck, broadcast)
+	return nil
+}
+
+// runCountdown ticks the countdown down to the game start, after a ready
+// check if asked for, giving up if it is cancelled
+func (m *Manager) runCountdown(room *Room, cancel chan struct{}, readyCheck bool, broadcast func(string, []byte)) {
+	if readyCheck && !m.runReadyCheck(room, cancel, broadcast) {
+		return
+	}
+
+	ticker := m.clock.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for i := room.GetSettings().CountdownSeconds; i > 0; i-- {
+		msgData, _ := json.Marshal(protocol.NewMessage(protocol.GameStarting, protocol.GameStartingPayload{
+			Countdown: i,
+		}))
+		broadcast(room.Code, msgData)
+
+		select {
+		case <-ticker.C():
+		case <-cancel:
+			return
+		}
+	}
+
+	if !room.endCountdown(cancel) {
+		return
+	}
+	m.startGame(room, broadcast)
+}
+
+// CancelCountdown stops a room's countdown and tells the room why
+// Returns false if the room wasn't counting down
+func (m *Manager) CancelCountdown(roomCode, reason string, broadcast func(string, []byte)) bool {
+	room := m.GetRoom(roomCode)
+	if room == nil || !room.CancelCountdown() {
+		return false
+	}
+
+	slog.Info("game start cancelled", "roomCode", roomCode, "reason", reason)
+	msgData, _ := json.Marshal(protocol.NewMessage(protocol.StartCancelled, protocol.StartCancelledPayload{
+		Reason: reason,
+	}))
+	broadcast(roomCode, msgData)
+	return true
+}
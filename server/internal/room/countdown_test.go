@@ -0,0 +1,364 @@
+package room
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"slapjack/internal/clock"
+	"slapjack/internal/config"
+	"slapjack/pkg/protocol"
+)
+
+// countdownTest is a two-player room on a manager driven by a mock clock
+type countdownTest struct {
+	manager  *Manager
+	clock    *clock.Mock
+	room     *Room
+	messages chan protocol.WSMessage
+}
+
+func newCountdownTest(t *testing.T) *countdownTest {
+	t.Helper()
+
+	ct := &countdownTest{
+		clock:    clock.NewMock(time.Now()),
+		messages: make(chan protocol.WSMessage, 32),
+	}
+	ct.manager = NewManager(nil, config.Static(config.Default()), ct.clock)
+
+	room, _, err := ct.manager.CreateRoom("host-session", "Alex", "host-token", "", nil, ct.broadcast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := ct.manager.JoinRoom(room.Code, "Sam", "sam-token", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	ct.room = room
+	ct.clock.BlockUntil(1) // The manager's cleanup ticker
+	return ct
+}
+
+func (ct *countdownTest) broadcast(_ string, data []byte) {
+	var msg protocol.WSMessage
+	json.Unmarshal(data, &msg)
+	ct.messages <- msg
+}
+
+// expect waits for the next broadcast and checks its type
+func (ct *countdownTest) expect(t *testing.T, msgType string) protocol.WSMessage {
+	t.Helper()
+	select {
+	case msg := <-ct.messages:
+		if msg.Type != msgType {
+			t.Fatalf("got %s, want %s", msg.Type, msgType)
+		}
+		return msg
+	case <-time.After(time.Second):
+		t.Fatalf("no %s broadcast", msgType)
+		return protocol.WSMessage{}
+	}
+}
+
+// expectCount waits for a GAME_STARTING broadcast showing n
+func (ct *countdownTest) expectCount(t *testing.T, n int) {
+	t.Helper()
+	msg := ct.expect(t, protocol.GameStarting)
+	var payload map[string]interface{}
+	json.Unmarshal(msg.Payload, &payload)
+	if got := payload["countdown"]; got != float64(n) {
+		t.Fatalf("countdown showed %v, want %d", got, n)
+	}
+}
+
+func TestCountdownStartsGame(t *testing.T) {
+	ct := newCountdownTest(t)
+
+	if err := ct.manager.StartGameCountdown(ct.room.Code, ct.broadcast); err != nil {
+		t.Fatal(err)
+	}
+	ct.expectCount(t, 3)
+	ct.clock.BlockUntil(2)
+
+	for n := 2; n >= 1; n-- {
+		ct.clock.Advance(999 * time.Millisecond)
+		if len(ct.messages) != 0 {
+			t.Fatalf("broadcast before the second was up")
+		}
+		ct.clock.Advance(time.Millisecond)
+		ct.expectCount(t, n)
+	}
+	if ct.room.Game != nil {
+		t.Fatal("game started before the countdown finished")
+	}
+
+	ct.clock.Advance(time.Second)
+	ct.expect(t, protocol.GameStarted)
+	if ct.room.Status != "playing" {
+		t.Errorf("room status %q, want playing", ct.room.Status)
+	}
+}
+
+func TestCountdownCancelled(t *testing.T) {
+	ct := newCountdownTest(t)
+
+	if err := ct.manager.StartGameCountdown(ct.room.Code, ct.broadcast); err != nil {
+		t.Fatal(err)
+	}
+	ct.expectCount(t, 3)
+	if err := ct.manager.StartGameCountdown(ct.room.Code, ct.broadcast); err == nil {
+		t.Error("a second countdown started")
+	}
+
+	ct.clock.BlockUntil(2)
+	ct.clock.Advance(time.Second)
+	ct.expectCount(t, 2)
+
+	if !ct.manager.CancelCountdown(ct.room.Code, protocol.StartCancelledByHost, ct.broadcast) {
+		t.Fatal("CancelCountdown found nothing to cancel")
+	}
+	ct.expect(t, protocol.StartCancelled)
+
+	ct.clock.Advance(5 * time.Second)
+	select {
+	case msg := <-ct.messages:
+		t.Fatalf("broadcast %s after the countdown was cancelled", msg.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if ct.room.Game != nil || ct.room.Status != "waiting" {
+		t.Errorf("room status %q after cancelling, want waiting with no game", ct.room.Status)
+	}
+}
+
+func TestResolveStuckRooms(t *testing.T) {
+	ct := newCountdownTest(t)
+	if stuck := ct.manager.ResolveStuckRooms(ct.broadcast); len(stuck) != 0 {
+		t.Fatalf("a waiting room was resolved as stuck: %+v", stuck)
+	}
+
+	// Starting for too long, on the manager's clock, goes back to waiting
+	ct.room.mu.Lock()
+	ct.room.Status = "starting"
+	ct.room.startingSince = ct.clock.Now()
+	ct.room.mu.Unlock()
+	if stuck := ct.manager.ResolveStuckRooms(ct.broadcast); len(stuck) != 0 {
+		t.Fatalf("a room that just started starting was resolved as stuck: %+v", stuck)
+	}
+	ct.clock.Advance(maxStartingDuration + time.Second)
+	stuck := ct.manager.ResolveStuckRooms(ct.broadcast)
+	if len(stuck) != 1 || stuck[0].Kind != StuckStarting {
+		t.Fatalf("resolved %+v, want the room stuck starting", stuck)
+	}
+	ct.expect(t, protocol.StartCancelled)
+	ct.expect(t, protocol.RoomUpdated)
+	if ct.room.Status != "waiting" {
+		t.Errorf("status %s after resolving, want waiting", ct.room.Status)
+	}
+
+	// A game with no turn timer gets one
+	ct.room.StartGame(ct.clock)
+	if stuck := ct.manager.ResolveStuckRooms(ct.broadcast); len(stuck) != 0 {
+		t.Fatalf("a game that just started was resolved as stuck: %+v", stuck)
+	}
+	ct.clock.Advance(2 * turnTimerGrace)
+	stuck = ct.manager.ResolveStuckRooms(ct.broadcast)
+	if len(stuck) != 1 || stuck[0].Kind != StuckTurnTimer {
+		t.Fatalf("resolved %+v, want the game's dead turn timer", stuck)
+	}
+
+	// A game nobody plays in is closed
+	other := newCountdownTest(t)
+	other.room.StartGame(other.clock)
+	other.clock.Advance(maxPlayingSilence + time.Minute)
+	stuck = other.manager.ResolveStuckRooms(other.broadcast)
+	if len(stuck) != 1 || stuck[0].Kind != StuckSilent {
+		t.Fatalf("resolved %+v, want the silent game", stuck)
+	}
+	other.expect(t, protocol.RoomExpired)
+	if other.manager.GetRoom(other.room.Code) != nil {
+		t.Error("silent room wasn't closed")
+	}
+}
+
+func TestExpireIdleRooms(t *testing.T) {
+	ct := newCountdownTest(t)
+	timeout := ct.manager.cfg.Get().IdleRoomTimeout
+
+	ct.clock.Advance(timeout - time.Second)
+	ct.room.Touch()
+	ct.clock.Advance(timeout - time.Second)
+	if expired := ct.manager.ExpireIdleRooms(ct.broadcast); len(expired) != 0 {
+		t.Fatalf("room expired %v after activity within the timeout", ct.room.IdleFor())
+	}
+
+	ct.clock.Advance(2 * time.Second)
+	if expired := ct.manager.ExpireIdleRooms(ct.broadcast); len(expired) != 1 {
+		t.Fatalf("room idle for %v not expired", ct.room.IdleFor())
+	}
+	ct.expect(t, protocol.RoomExpired)
+}
+
+func TestReadyCheck(t *testing.T) {
+	ct := newCountdownTest(t)
+	ct.room.mu.Lock()
+	ct.room.Settings.ReadyCheck = true
+	ct.room.Settings.ReadyCheckSeconds = 5
+	ct.room.Settings.CountdownSeconds = 2
+	ct.room.mu.Unlock()
+
+	var ids []string
+	for _, p := range ct.room.GetConnectedPlayers() {
+		ids = append(ids, p.ID)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("%d connected players, want 2", len(ids))
+	}
+
+	// Everyone answering starts the countdown, at the room's length
+	if err := ct.manager.HostStartGame(ct.room.Code, ct.broadcast); err != nil {
+		t.Fatal(err)
+	}
+	var check protocol.ReadyCheckPayload
+	json.Unmarshal(ct.expect(t, protocol.ReadyCheck).Payload, &check)
+	if len(check.PlayerIDs) != 2 || check.TimeoutSeconds != 5 {
+		t.Fatalf("ready check %+v, want both players with 5 seconds", check)
+	}
+	for _, id := range ids {
+		if err := ct.manager.MarkReady(ct.room.Code, id, ct.broadcast); err != nil {
+			t.Fatal(err)
+		}
+		ct.expect(t, protocol.PlayerReady)
+	}
+	ct.expectCount(t, 2)
+	ct.manager.CancelCountdown(ct.room.Code, protocol.StartCancelledByHost, ct.broadcast)
+	ct.expect(t, protocol.StartCancelled)
+	if err := ct.manager.MarkReady(ct.room.Code, ids[0], ct.broadcast); err == nil {
+		t.Error("answered a ready check that had finished")
+	}
+
+	// Anyone not answering in time calls the start off
+	if err := ct.manager.HostStartGame(ct.room.Code, ct.broadcast); err != nil {
+		t.Fatal(err)
+	}
+	ct.expect(t, protocol.ReadyCheck)
+	ct.manager.MarkReady(ct.room.Code, ids[0], ct.broadcast)
+	ct.expect(t, protocol.PlayerReady)
+	ct.clock.BlockUntil(2)
+	ct.clock.Advance(5 * time.Second)
+
+	var cancelled protocol.StartCancelledPayload
+	json.Unmarshal(ct.expect(t, protocol.StartCancelled).Payload, &cancelled)
+	if cancelled.Reason != protocol.StartCancelledNotReady || len(cancelled.PlayerIDs) != 1 || cancelled.PlayerIDs[0] != ids[1] {
+		t.Errorf("start cancelled with %+v, want %s not ready", cancelled, ids[1])
+	}
+	ct.expect(t, protocol.RoomUpdated)
+	if ct.room.Status != "waiting" {
+		t.Errorf("room status %q after the ready check failed, want waiting", ct.room.Status)
+	}
+}
+
+func TestScheduledStart(t *testing.T) {
+	ct := newCountdownTest(t)
+
+	for _, at := range []time.Time{ct.clock.Now().Add(-time.Minute), ct.clock.Now().Add(72 * time.Hour)} {
+		if err := ct.manager.ScheduleStart(ct.room.Code, at); err != ErrInvalidSchedule {
+			t.Errorf("scheduling for %v: error %v, want ErrInvalidSchedule", at, err)
+		}
+	}
+
+	at := ct.clock.Now().Add(20 * time.Minute)
+	if err := ct.manager.ScheduleStart(ct.room.Code, at); err != nil {
+		t.Fatal(err)
+	}
+	if got := ct.room.ToProtocol().ScheduledAt; got != at.UnixMilli() {
+		t.Errorf("scheduledAt = %d, want %d", got, at.UnixMilli())
+	}
+
+	// The latest reminder already due is sent once, then each one as it comes
+	ct.manager.runSchedules(ct.broadcast)
+	msg := ct.expect(t, protocol.ScheduleReminder)
+	var reminder protocol.ScheduleReminderPayload
+	json.Unmarshal(msg.Payload, &reminder)
+	if reminder.SecondsLeft != 1200 || reminder.StartsAt != at.UnixMilli() {
+		t.Errorf("reminder = %+v, want 1200 seconds left", reminder)
+	}
+	ct.manager.runSchedules(ct.broadcast)
+	ct.clock.Advance(10 * time.Minute)
+	ct.manager.runSchedules(ct.broadcast)
+	ct.expect(t, protocol.ScheduleReminder)
+
+	// Idle rooms are kept until their start
+	ct.room.LastActivity = ct.clock.Now().Add(-24 * time.Hour)
+	if expired := ct.manager.ExpireIdleRooms(ct.broadcast); len(expired) != 0 {
+		t.Errorf("scheduled room expired: %v", expired)
+	}
+
+	// The countdown begins so the game starts at the scheduled time
+	ct.clock.Advance(at.Sub(ct.clock.Now()) - 3*time.Second)
+	ct.manager.runSchedules(ct.broadcast)
+	ct.expectCount(t, 3)
+	if ct.room.Scheduled() {
+		t.Error("schedule kept after the countdown began")
+	}
+}
+
+func TestScheduledStartMissed(t *testing.T) {
+	ct := newCountdownTest(t)
+
+	at := ct.clock.Now().Add(30 * time.Second)
+	if err := ct.manager.ScheduleStart(ct.room.Code, at); err != nil {
+		t.Fatal(err)
+	}
+	ct.manager.HandleDisconnect(ct.room.Code, ct.room.HostID, ct.broadcast)
+	ct.expect(t, protocol.PlayerDisconnected)
+	ct.expect(t, protocol.RoomUpdated)
+
+	ct.clock.Advance(30 * time.Second)
+	ct.manager.runSchedules(ct.broadcast)
+	msg := ct.expect(t, protocol.StartCancelled)
+	var payload protocol.StartCancelledPayload
+	json.Unmarshal(msg.Payload, &payload)
+	if payload.Reason != protocol.StartCancelledUnscheduled {
+		t.Errorf("reason = %q, want %q", payload.Reason, protocol.StartCancelledUnscheduled)
+	}
+	if status := ct.room.ToProtocol().Status; status != "waiting" {
+		t.Errorf("status = %q, want waiting", status)
+	}
+}
+
+// A room should outlive one disconnected player's grace period while another
+// player can still reconnect
+func TestGraceExpiryKeepsRoomForOthers(t *testing.T) {
+	ct := newCountdownTest(t)
+	m, code := ct.manager, ct.room.Code
+	grace := m.ReconnectGrace()
+	var alexID, samID string
+	for _, p := range ct.room.GetAllPlayers() {
+		if p.Name == "Alex" {
+			alexID = p.ID
+		} else {
+			samID = p.ID
+		}
+	}
+
+	m.HandleDisconnect(code, alexID, ct.broadcast)
+	ct.clock.Advance(grace / 2)
+	m.HandleDisconnect(code, samID, ct.broadcast)
+	ct.clock.Advance(grace / 2)
+
+	if m.GetRoom(code) == nil {
+		t.Fatal("room deleted while a player was still within their grace period")
+	}
+	if ct.room.GetPlayer(alexID) != nil {
+		t.Error("player still seated after their grace period")
+	}
+	if m.ReconnectPlayer(code, samID) == nil {
+		t.Fatal("player could not reconnect within their grace period")
+	}
+
+	m.HandleDisconnect(code, samID, ct.broadcast)
+	ct.clock.Advance(grace)
+	if m.GetRoom(code) != nil {
+		t.Error("room kept after every grace period ran out")
+	}
+}
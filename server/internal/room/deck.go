@@ -0,0 +1,53 @@
+package room
+
+import (
+	"errors"
+
+	"slapjack/internal/game"
+	"slapjack/pkg/protocol"
+)
+
+// Custom deck limits
+const (
+	minDeckRanks  = 4
+	maxExtraJacks = 8
+)
+
+var (
+	ErrUnknownRank  = errors.New("deck has an unknown rank")
+	ErrDeckNoJacks  = errors.New("deck must include Jacks")
+	ErrDeckTooSmall = errors.New("deck needs at least 4 ranks")
+	ErrTooManyJacks = errors.New("deck can have at most 8 extra Jacks")
+)
+
+// DeckFromProtocol validates a custom deck from a settings payload
+func DeckFromProtocol(p protocol.DeckSpec) (game.DeckSpec, error) {
+	ranks, ok := game.ParseRankSet(p.Ranks)
+	if !ok {
+		return game.DeckSpec{}, ErrUnknownRank
+	}
+	spec := game.DeckSpec{Ranks: ranks, ExtraJacks: p.ExtraJacks}
+	if err := validateDeck(spec); err != nil {
+		return game.DeckSpec{}, err
+	}
+	return spec, nil
+}
+
+// validateDeck reports why a custom deck can't be played with, if it can't
+func validateDeck(spec game.DeckSpec) error {
+	if !spec.Ranks.Has("J") {
+		return ErrDeckNoJacks
+	}
+	if spec.Ranks.Len() < minDeckRanks {
+		return ErrDeckTooSmall
+	}
+	if spec.ExtraJacks < 0 || spec.ExtraJacks > maxExtraJacks {
+		return ErrTooManyJacks
+	}
+	return nil
+}
+
+// deckToProtocol converts a custom deck to its protocol form
+func deckToProtocol(spec game.DeckSpec) protocol.DeckSpec {
+	return protocol.DeckSpec{Ranks: spec.Ranks.Ranks(), ExtraJacks: spec.ExtraJacks}
+}
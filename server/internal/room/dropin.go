@@ -0,0 +1,252 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	"slapjack/internal/game"
+	"slapjack/internal/locale"
+	"slapjack/pkg/protocol"
+)
+
+// Drop-in rooms are public rooms the server keeps open for each rule preset,
+// so players can join a game with PLAY_NOW without creating or choosing a room
+const (
+	// How often drop-in rooms are started, recycled and topped up
+	dropInCheckInterval = time.Second
+
+	// Once two players are in, how long to wait for more before starting
+	dropInFillWait = 10 * time.Second
+
+	// How long a finished game's results are shown before the room resets
+	dropInRecycleDelay = 15 * time.Second
+
+	dropInMaxPlayers = 6
+)
+
+var ErrUnknownPreset = errors.New("unknown rule preset")
+
+// dropInPresets returns the settings of each preset drop-in rooms are kept open for
+func (m *Manager) dropInPresets() map[string]Settings {
+	classic := DefaultSettings()
+	classic.MaxPlayers = dropInMaxPlayers
+	classic.RematchQuorum = RematchMajority
+
+	house := classic
+	house.EnableMarriage = true
+	house.EnableTopBottom = true
+	house.EnableRuns = true
+	house.EnableTens = true
+
+	presets := map[string]Settings{
+		protocol.PresetClassic: classic,
+		protocol.PresetHouse:   house,
+	}
+	if m.cfg.Get().FeatureEnabled(protocol.FeatureRatscrew) {
+		ratscrew := classic
+		ratscrew.GameMode = game.ModeRatscrew
+		presets[protocol.PresetRatscrew] = ratscrew
+	}
+	return presets
+}
+
+// PlayNow seats a player in the fullest open drop-in room for a preset,
+// opening a new one if they are all full or playing
+func (m *Manager) PlayNow(preset, playerName, playerToken string, profile *Profile) (*Room, string, *Player, error) {
+	if _, ok := m.dropInPresets()[preset]; !ok {
+		return nil, "", nil, ErrUnknownPreset
+	}
+
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		room := m.openDropIn(preset)
+		if room == nil {
+			if room, err = m.createDropIn(preset); err != nil {
+				return nil, "", nil, err
+			}
+		}
+
+		var playerID string
+		var player *Player
+		room, playerID, player, err = m.JoinRoom(room.Code, playerName, playerToken, profile, "")
+		if err == nil || errors.Is(err, ErrNameTaken) {
+			return room, playerID, player, err
+		}
+		// The room filled up or started in the meantime, try another
+	}
+	return nil, "", nil, err
+}
+
+// openDropIn returns the waiting drop-in room for a preset with the most
+// players that still has a seat, or nil if there is none
+func (m *Manager) openDropIn(preset string) *Room {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var best *Room
+	bestCount := -1
+	for _, room := range m.rooms {
+		if room.DropIn != preset || room.Status != "waiting" || room.IsFull() {
+			continue
+		}
+		if count := len(room.GetConnectedPlayers()); count > bestCount {
+			best, bestCount = room, count
+		}
+	}
+	return best
+}
+
+// createDropIn opens a new empty drop-in room for a preset
+func (m *Manager) createDropIn(preset string) (*Room, error) {
+	settings, ok := m.dropInPresets()[preset]
+	if !ok {
+		return nil, ErrUnknownPreset
+	}
+
+	code := m.generateRoomCode()
+	if code == "" {
+		return nil, errors.New("failed to generate room code")
+	}
+
+	room := newRoom(code, m.clock)
+	room.DropIn = preset
+	room.Settings = settings
+	room.Settings.Preset = settings.activePreset()
+
+	m.mu.Lock()
+	m.rooms[code] = room
+	m.mu.Unlock()
+
+	m.persist("add active room "+code, func(ctx context.Context) error {
+		return m.store.AddActiveRoom(ctx, code)
+	})
+	m.saveRoom(code, room)
+
+	slog.Info("drop-in room opened", "roomCode", code, "preset", preset)
+	return room, nil
+}
+
+// RunDropIns keeps drop-in rooms going: it starts those with enough players,
+// resets finished ones for the next game, closes surplus empty ones and opens
+// one for any preset without a free seat
+func (m *Manager) RunDropIns(broadcast func(string, []byte)) {
+	m.maintainDropIns(broadcast)
+
+	ticker := m.clock.NewTicker(dropInCheckInterval)
+	for range ticker.C() {
+		m.maintainDropIns(broadcast)
+	}
+}
+
+func (m *Manager) maintainDropIns(broadcast func(string, []byte)) {
+	presets := m.dropInPresets()
+
+	var rooms []*Room
+	m.mu.RLock()
+	for _, room := range m.rooms {
+		if room.DropIn != "" {
+			rooms = append(rooms, room)
+		}
+	}
+	m.mu.RUnlock()
+
+	// Fullest first, so empty rooms are only kept if no other room has a seat
+	connected := make(map[*Room]int, len(rooms))
+	for _, room := range rooms {
+		connected[room] = len(room.GetConnectedPlayers())
+	}
+	sort.Slice(rooms, func(i, j int) bool { return connected[rooms[i]] > connected[rooms[j]] })
+
+	open := make(map[string]int)
+	for _, room := range rooms {
+		_, enabled := presets[room.DropIn]
+
+		switch room.Status {
+		case "waiting":
+			if len(room.GetAllPlayers()) == 0 && (open[room.DropIn] > 0 || !enabled) {
+				m.DeleteRoom(room.Code)
+				slog.Info("drop-in room closed", "roomCode", room.Code, "preset", room.DropIn)
+				continue
+			}
+			if !room.IsFull() {
+				open[room.DropIn]++
+			}
+
+			if connected[room] < 2 {
+				room.resetDropInWait()
+				continue
+			}
+			if room.IsFull() || room.dropInWaited(m.clock.Now()) >= dropInFillWait {
+				room.resetDropInWait()
+				if err := m.StartGameCountdown(room.Code, broadcast); err != nil {
+					slog.Warn("drop-in room failed to start", "roomCode", room.Code, "error", err)
+				}
+			}
+
+		case "finished":
+			if room.dropInWaited(m.clock.Now()) < dropInRecycleDelay || !room.recycleDropIn() {
+				continue
+			}
+			msgData, _ := json.Marshal(protocol.NewMessage(protocol.GameEnded, protocol.GameEndedPayload{
+				Reason:    locale.Text(locale.Default, locale.DropInNextGame, nil),
+				ReasonKey: locale.DropInNextGame,
+			}))
+			broadcast(room.Code, msgData)
+			m.saveRoom(room.Code, room)
+			m.NotifyMembershipChanged(room.Code, broadcast)
+
+		default:
+			room.resetDropInWait()
+		}
+	}
+
+	for preset := range presets {
+		if open[preset] == 0 {
+			if _, err := m.createDropIn(preset); err != nil {
+				slog.Error("failed to open drop-in room", "preset", preset, "error", err)
+			}
+		}
+	}
+}
+
+// dropInWaited returns how long a drop-in room has been ready to start or
+// finished, counting from now if it only just became so
+func (r *Room) dropInWaited(now time.Time) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.dropInSince.IsZero() {
+		r.dropInSince = now
+	}
+	return now.Sub(r.dropInSince)
+}
+
+func (r *Room) resetDropInWait() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dropInSince = time.Time{}
+}
+
+// recycleDropIn clears a finished drop-in room's game so it can start another
+// Returns false if the room is no longer finished, as when a rematch started
+func (r *Room) recycleDropIn() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Status != "finished" {
+		return false
+	}
+	if r.Game != nil {
+		r.Game.CancelTurnTimer()
+	}
+	r.Game = nil
+	r.Status = "waiting"
+	r.rematchVotes = nil
+	r.dropInSince = time.Time{}
+	r.version++
+	return true
+}
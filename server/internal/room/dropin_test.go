@@ -0,0 +1,118 @@
+package room
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"slapjack/internal/clock"
+	"slapjack/internal/config"
+	"slapjack/pkg/protocol"
+)
+
+func newDropInTest(t *testing.T) *countdownTest {
+	t.Helper()
+
+	ct := &countdownTest{
+		clock:    clock.NewMock(time.Now()),
+		messages: make(chan protocol.WSMessage, 32),
+	}
+	ct.manager = NewManager(nil, config.Static(config.Default()), ct.clock)
+	ct.clock.BlockUntil(1) // The manager's cleanup ticker
+	ct.manager.maintainDropIns(ct.broadcast)
+	return ct
+}
+
+// dropIns counts the drop-in rooms open for each preset
+func (ct *countdownTest) dropIns() map[string]int {
+	counts := make(map[string]int)
+	ct.manager.mu.RLock()
+	defer ct.manager.mu.RUnlock()
+	for _, room := range ct.manager.rooms {
+		if room.DropIn != "" {
+			counts[room.DropIn]++
+		}
+	}
+	return counts
+}
+
+func TestDropInRoomsOpenPerPreset(t *testing.T) {
+	ct := newDropInTest(t)
+
+	want := map[string]int{protocol.PresetClassic: 1, protocol.PresetHouse: 1, protocol.PresetRatscrew: 1}
+	got := ct.dropIns()
+	for preset, n := range want {
+		if got[preset] != n {
+			t.Errorf("%d %s drop-in rooms, want %d", got[preset], preset, n)
+		}
+	}
+
+	// Another check doesn't open more while those still have seats
+	ct.manager.maintainDropIns(ct.broadcast)
+	if got := ct.dropIns(); got[protocol.PresetClassic] != 1 {
+		t.Errorf("%d classic drop-in rooms after a second check, want 1", got[protocol.PresetClassic])
+	}
+
+	if _, _, _, err := ct.manager.PlayNow("speed", "Alex", "alex-token", nil); !errors.Is(err, ErrUnknownPreset) {
+		t.Errorf("PlayNow(unknown preset) error = %v, want ErrUnknownPreset", err)
+	}
+}
+
+func TestDropInAutoStart(t *testing.T) {
+	ct := newDropInTest(t)
+
+	alexRoom, _, _, err := ct.manager.PlayNow(protocol.PresetClassic, "Alex", "alex-token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samRoom, _, _, err := ct.manager.PlayNow(protocol.PresetClassic, "Sam", "sam-token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alexRoom != samRoom {
+		t.Fatal("players were put in different drop-in rooms")
+	}
+	if alexRoom.HostID != "" {
+		t.Errorf("drop-in room has host %q", alexRoom.HostID)
+	}
+
+	// Waits for more players before starting
+	ct.manager.maintainDropIns(ct.broadcast)
+	ct.clock.Advance(dropInFillWait - time.Second)
+	ct.manager.maintainDropIns(ct.broadcast)
+	if alexRoom.Status != "waiting" {
+		t.Fatalf("room status %q before the fill wait was up, want waiting", alexRoom.Status)
+	}
+
+	ct.clock.Advance(time.Second)
+	ct.manager.maintainDropIns(ct.broadcast)
+	ct.expectCount(t, 3)
+	if alexRoom.Status != "starting" {
+		t.Errorf("room status %q after the fill wait, want starting", alexRoom.Status)
+	}
+}
+
+func TestDropInRecycle(t *testing.T) {
+	ct := newDropInTest(t)
+
+	room, _, _, err := ct.manager.PlayNow(protocol.PresetHouse, "Alex", "alex-token", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !room.Settings.EnableMarriage || !room.Settings.EnableRuns {
+		t.Errorf("house drop-in room settings %+v, want every slap variant", room.Settings)
+	}
+	room.Status = "finished"
+
+	ct.manager.maintainDropIns(ct.broadcast)
+	ct.clock.Advance(dropInRecycleDelay)
+	ct.manager.maintainDropIns(ct.broadcast)
+	ct.expect(t, protocol.GameEnded)
+	ct.expect(t, protocol.RoomUpdated)
+	if room.Status != "waiting" || room.Game != nil {
+		t.Errorf("room status %q after recycling, want waiting with no game", room.Status)
+	}
+	if ct.manager.GetRoom(room.Code) == nil {
+		t.Error("recycled room was closed")
+	}
+}
@@ -0,0 +1,107 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"slapjack/internal/history"
+	"slapjack/pkg/protocol"
+)
+
+var ErrHistoryDisabled = errors.New("match history is not enabled")
+
+// SetHistory archives finished games from now on
+// Set it before the server starts taking connections
+func (m *Manager) SetHistory(archive *history.Archive) {
+	m.history = archive
+}
+
+// rosterEntry is a player as they were when a game started
+type rosterEntry struct {
+	id, profileID, name string
+}
+
+// recordRoster notes who a game is starting with
+// Caller must hold r.mu
+func (r *Room) recordRoster(playerIDs []string) {
+	r.roster = r.roster[:0]
+	for _, id := range playerIDs {
+		p := r.Players[id]
+		r.roster = append(r.roster, rosterEntry{id: id, profileID: p.ProfileID, name: p.Name})
+	}
+	sort.Slice(r.roster, func(i, j int) bool {
+		return r.Players[r.roster[i].id].Position < r.Players[r.roster[j].id].Position
+	})
+}
+
+// matchRoster returns who the game started with, as they are now if they're
+// still in the room; a room restored mid-game only has the players seated
+func (r *Room) matchRoster() []rosterEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.roster) == 0 {
+		var roster []rosterEntry
+		for _, p := range r.Players {
+			roster = append(roster, rosterEntry{id: p.ID, profileID: p.ProfileID, name: p.Name})
+		}
+		return roster
+	}
+	roster := make([]rosterEntry, len(r.roster))
+	for i, entry := range r.roster {
+		if p, ok := r.Players[entry.id]; ok {
+			entry.profileID, entry.name = p.ProfileID, p.Name
+		}
+		roster[i] = entry
+	}
+	return roster
+}
+
+// RecordMatch archives a finished game, with everyone it started with, even
+// those who left; players with a profile can look it up in their history
+func (m *Manager) RecordMatch(r *Room, winnerID, winReason string) {
+	if m.history == nil || r.Game == nil {
+		return
+	}
+
+	g := r.Game
+	stats := g.GetStats()
+	ended := m.clock.Now()
+	match := protocol.MatchRecord{
+		GameID:    g.ID,
+		RoomCode:  r.Code,
+		StartedAt: g.StartTime.UnixMilli(),
+		EndedAt:   ended.UnixMilli(),
+		Duration:  ended.Sub(g.StartTime).Milliseconds(),
+		WinReason: winReason,
+		Settings:  r.GetSettings().ToProtocol(),
+	}
+	winners := make(map[string]bool)
+	for _, id := range g.Teammates(winnerID) {
+		winners[id] = true
+	}
+	for _, p := range r.matchRoster() {
+		playerStats, played := stats.Players[p.id]
+		if !played {
+			continue
+		}
+		if p.id == winnerID {
+			match.WinnerName = p.name
+		}
+		match.Players = append(match.Players, protocol.MatchPlayer{
+			PlayerID: p.profileID,
+			Name:     p.name,
+			Won:      winners[p.id],
+			Stats:    playerStats,
+		})
+	}
+	m.history.Record(match)
+}
+
+// MatchHistory returns a page of a profile's archived games, latest first
+func (m *Manager) MatchHistory(ctx context.Context, profileID, cursor string, limit int) (protocol.MatchHistoryResponse, error) {
+	if m.history == nil {
+		return protocol.MatchHistoryResponse{}, ErrHistoryDisabled
+	}
+	return m.history.Matches(ctx, profileID, cursor, limit)
+}
@@ -0,0 +1,125 @@
+package room
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"slapjack/internal/webhook"
+	"slapjack/pkg/protocol"
+)
+
+// SetWebhook registers the Discord webhook the room's games are posted to,
+// or removes it if url is empty
+func (r *Room) SetWebhook(url string) error {
+	if url != "" {
+		if err := webhook.Validate(url); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+	detail := "discord webhook removed"
+	if url != "" {
+		detail = "discord webhook set"
+	}
+	r.Webhook = url
+	r.recordAudit(protocol.AuditIntegration, r.HostID, "", detail)
+	return nil
+}
+
+// integrations lists the integrations registered for the room
+// Caller must hold r.mu
+func (r *Room) integrations() []string {
+	if r.Webhook == "" {
+		return nil
+	}
+	return []string{protocol.IntegrationDiscord}
+}
+
+// playerNames returns the names of some of the room's players, sorted
+func (r *Room) playerNames(playerIDs []string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(playerIDs))
+	for _, id := range playerIDs {
+		if p, ok := r.Players[id]; ok {
+			names = append(names, p.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// notify posts an embed to the room's webhook, if it has one and webhooks
+// are switched on
+func (m *Manager) notify(room *Room, embed webhook.Embed) {
+	room.mu.RLock()
+	url := room.Webhook
+	room.mu.RUnlock()
+
+	if url == "" || !m.cfg.Get().FeatureEnabled(protocol.FeatureWebhooks) {
+		return
+	}
+	embed.Footer = &webhook.Footer{Text: "Room " + room.Code}
+	m.webhooks.Send(url, embed)
+}
+
+// notifyGameStarted posts who a room's new game is between
+func (m *Manager) notifyGameStarted(room *Room) {
+	var playerIDs []string
+	piles := 0
+	if games := room.Piles(); games != nil {
+		piles = len(games)
+		for _, g := range games {
+			for id := range g.GetCardCounts() {
+				playerIDs = append(playerIDs, id)
+			}
+		}
+	} else if room.Game != nil {
+		for id := range room.Game.GetCardCounts() {
+			playerIDs = append(playerIDs, id)
+		}
+	}
+
+	names := room.playerNames(playerIDs)
+	description := fmt.Sprintf("%d players: %s", len(names), strings.Join(names, ", "))
+	if piles > 0 {
+		description += fmt.Sprintf("\nSplit across %d piles, the winner of each going through to a finale", piles)
+	}
+	m.notify(room, webhook.Embed{
+		Title:       "Game started",
+		Description: description,
+		Color:       webhook.ColorStart,
+	})
+}
+
+// NotifyGameOver posts the winner of a room's game
+func (m *Manager) NotifyGameOver(room *Room, winnerName, reason string) {
+	m.notify(room, webhook.Embed{
+		Title:       winnerName + " won",
+		Description: fmt.Sprintf("Won by %s", strings.ReplaceAll(reason, "_", " ")),
+		Color:       webhook.ColorWin,
+	})
+}
+
+// NotifyPileWon posts the winner of one of a party room's piles
+func (m *Manager) NotifyPileWon(room *Room, pile int, winnerName string) {
+	m.notify(room, webhook.Embed{
+		Title:       fmt.Sprintf("Pile %d won by %s", pile, winnerName),
+		Description: "Through to the finale",
+		Color:       webhook.ColorResult,
+	})
+}
+
+// notifyFinale posts the finalists of a party room
+func (m *Manager) notifyFinale(room *Room, finalists []string) {
+	m.notify(room, webhook.Embed{
+		Title:       "Finale",
+		Description: strings.Join(room.playerNames(finalists), " vs "),
+		Color:       webhook.ColorResult,
+	})
+}
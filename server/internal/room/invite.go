@@ -0,0 +1,121 @@
+package room
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"slapjack/pkg/protocol"
+)
+
+// How long an invite can be redeemed for
+const inviteTTL = 24 * time.Hour
+
+var ErrInvalidInvite = errors.New("this invite is invalid, used or expired")
+
+// invites holds the hashes of unredeemed invites when Redis is unavailable
+type invites struct {
+	rooms   map[string]string // invite hash -> room code
+	expires map[string]time.Time
+	mu      sync.Mutex
+}
+
+func newInvites() *invites {
+	return &invites{
+		rooms:   make(map[string]string),
+		expires: make(map[string]time.Time),
+	}
+}
+
+// add stores an invite, forgetting the ones that have expired
+func (v *invites) add(hash, roomCode string, expires, now time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for h, at := range v.expires {
+		if now.After(at) {
+			delete(v.rooms, h)
+			delete(v.expires, h)
+		}
+	}
+	v.rooms[hash] = roomCode
+	v.expires[hash] = expires
+}
+
+// take removes an invite and returns its room, or "" if there is none
+func (v *invites) take(hash string, now time.Time) string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	roomCode, expires := v.rooms[hash], v.expires[hash]
+	delete(v.rooms, hash)
+	delete(v.expires, hash)
+	if now.After(expires) {
+		return ""
+	}
+	return roomCode
+}
+
+// inviteSubject is what an invite's signature covers, kept apart from session IDs
+func inviteSubject(roomCode string) string {
+	return "invite:" + roomCode
+}
+
+// CreateInvite issues a single-use invite that lets one player into a room,
+// even a private one. Only its hash is kept.
+func (m *Manager) CreateInvite(ctx context.Context, roomCode string) (protocol.Invite, error) {
+	if m.GetRoom(roomCode) == nil {
+		return protocol.Invite{}, errors.New("room not found")
+	}
+
+	nonceBytes := make([]byte, 32)
+	rand.Read(nonceBytes)
+	nonce := base64.RawURLEncoding.EncodeToString(nonceBytes)
+	token := nonce + "." + m.tokens.sign(inviteSubject(roomCode), nonce)
+	hash := hashToken(token)
+	expires := m.clock.Now().Add(inviteTTL)
+
+	if m.store != nil {
+		if err := m.store.SetInvite(ctx, hash, roomCode, inviteTTL); err != nil {
+			m.storeHealth.fail("save invite", err)
+			return protocol.Invite{}, err
+		}
+	} else {
+		m.invites.add(hash, roomCode, expires, m.clock.Now())
+	}
+
+	return protocol.Invite{
+		RoomCode:  roomCode,
+		Token:     token,
+		ExpiresAt: expires.UnixMilli(),
+	}, nil
+}
+
+// redeemInvite uses up an invite to a room
+func (m *Manager) redeemInvite(roomCode, token string) error {
+	// Reject forged tokens before looking anything up
+	nonce, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(m.tokens.sign(inviteSubject(roomCode), nonce))) {
+		return ErrInvalidInvite
+	}
+
+	hash := hashToken(token)
+	var invitedTo string
+	if m.store != nil {
+		code, err := m.store.TakeInvite(context.Background(), hash)
+		if err != nil {
+			m.storeHealth.fail("take invite", err)
+			return err
+		}
+		invitedTo = code
+	} else {
+		invitedTo = m.invites.take(hash, m.clock.Now())
+	}
+	if invitedTo != roomCode {
+		return ErrInvalidInvite
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"slapjack/internal/redis"
+	"slapjack/pkg/protocol"
+)
+
+var ErrUnknownMetric = errors.New("unknown leaderboard metric")
+
+// Leaderboard limits
+const (
+	defaultLeaderboardLimit = 50
+	maxLeaderboardLimit     = 100
+
+	// Games a player must finish to rank by win rate, and successful slaps
+	// to rank by reaction time, so a lucky first game doesn't top the board
+	minLeaderboardGames = 5
+	minLeaderboardSlaps = 10
+)
+
+// leaderboards holds every player's totals in memory when there is no Redis
+type leaderboards struct {
+	byID map[string]*redis.LeaderboardTotals
+	mu   sync.Mutex
+}
+
+func newLeaderboards() *leaderboards {
+	return &leaderboards{byID: make(map[string]*redis.LeaderboardTotals)}
+}
+
+// leaderboardScore returns a player's score on a leaderboard, and false if
+// they don't rank on it yet
+func leaderboardScore(metric string, t redis.LeaderboardTotals) (float64, bool) {
+	switch metric {
+	case protocol.LeaderboardWins:
+		return float64(t.Wins), t.Wins > 0
+	case protocol.LeaderboardWinRate:
+		if t.Played < minLeaderboardGames {
+			return 0, false
+		}
+		return float64(t.Wins) / float64(t.Played), true
+	case protocol.LeaderboardFastestSlap:
+		if t.Slaps < minLeaderboardSlaps {
+			return 0, false
+		}
+		return float64(t.SlapMs / int64(t.Slaps)), true
+	}
+	return 0, false
+}
+
+// leaderboardMetrics lists every leaderboard
+var leaderboardMetrics = []string{
+	protocol.LeaderboardWins,
+	protocol.LeaderboardWinRate,
+	protocol.LeaderboardFastestSlap,
+}
+
+// RecordLeaderboard adds a finished game to the leaderboards of every
+// seated player, by profile if they have one or else by player token, so
+// their record follows them across reconnects and rooms
+// Like PayOut, games with fewer than two distinct players count for nothing
+func (m *Manager) RecordLeaderboard(r *Room, winnerID string) {
+	ids := make(map[string]string) // Leaderboard ID by player ID
+	names := make(map[string]string)
+	distinct := make(map[string]bool)
+	for _, p := range r.GetAllPlayers() {
+		id := ""
+		switch {
+		case p.ProfileID != "":
+			id = "profile:" + p.ProfileID
+		case p.Token != "":
+			id = "token:" + hashToken(p.Token)
+		}
+		if id != "" {
+			ids[p.ID] = id
+			names[p.ID] = p.Name
+			distinct[id] = true
+		}
+	}
+	if len(distinct) < 2 {
+		return
+	}
+
+	winners := make(map[string]bool)
+	for _, id := range r.Game.Teammates(winnerID) {
+		winners[id] = true
+	}
+	for playerID, id := range ids {
+		slaps, slapMs := r.Game.ReactionTotal(playerID)
+		totals, err := m.addLeaderboardGame(id, names[playerID], winners[playerID], slaps, slapMs)
+		if err != nil {
+			m.storeHealth.fail("record leaderboard", err)
+			continue
+		}
+		if m.store == nil {
+			continue
+		}
+		for _, metric := range leaderboardMetrics {
+			if score, ok := leaderboardScore(metric, totals); ok {
+				m.persist("record leaderboard", func(ctx context.Context) error {
+					return m.store.SetLeaderboardScore(ctx, metric, id, score)
+				})
+			}
+		}
+	}
+}
+
+// addLeaderboardGame adds a finished game to a player's totals
+func (m *Manager) addLeaderboardGame(id, name string, won bool, slaps int, slapMs int64) (redis.LeaderboardTotals, error) {
+	if m.store != nil {
+		return m.store.AddLeaderboardGame(context.Background(), id, name, won, slaps, slapMs)
+	}
+
+	m.leaderboards.mu.Lock()
+	defer m.leaderboards.mu.Unlock()
+	t, ok := m.leaderboards.byID[id]
+	if !ok {
+		t = &redis.LeaderboardTotals{ID: id}
+		m.leaderboards.byID[id] = t
+	}
+	t.Name = name
+	t.Played++
+	if won {
+		t.Wins++
+	}
+	t.Slaps += slaps
+	t.SlapMs += slapMs
+	return *t, nil
+}
+
+// Leaderboard returns a leaderboard's top players, up to limit; a limit
+// out of range gets the default
+func (m *Manager) Leaderboard(ctx context.Context, metric string, limit int) ([]protocol.LeaderboardEntry, error) {
+	if !validLeaderboardMetric(metric) {
+		return nil, ErrUnknownMetric
+	}
+	if limit < 1 || limit > maxLeaderboardLimit {
+		limit = defaultLeaderboardLimit
+	}
+	ascending := metric == protocol.LeaderboardFastestSlap
+
+	var top []redis.LeaderboardTotals
+	if m.store != nil {
+		var err error
+		if top, err = m.store.GetLeaderboard(ctx, metric, limit, ascending); err != nil {
+			m.storeHealth.fail("load leaderboard", err)
+			return nil, err
+		}
+	} else {
+		top = m.leaderboards.top(metric, limit, ascending)
+	}
+
+	entries := make([]protocol.LeaderboardEntry, 0, len(top))
+	for i, t := range top {
+		score, _ := leaderboardScore(metric, t)
+		entry := protocol.LeaderboardEntry{
+			Rank:   i + 1,
+			Name:   t.Name,
+			Score:  score,
+			Played: t.Played,
+			Wins:   t.Wins,
+		}
+		if t.Slaps > 0 {
+			entry.AverageMs = t.SlapMs / int64(t.Slaps)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// validLeaderboardMetric reports whether metric is a known leaderboard
+func validLeaderboardMetric(metric string) bool {
+	for _, known := range leaderboardMetrics {
+		if metric == known {
+			return true
+		}
+	}
+	return false
+}
+
+// top returns the players ranked on a leaderboard in order, up to limit
+func (l *leaderboards) top(metric string, limit int, ascending bool) []redis.LeaderboardTotals {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	type ranked struct {
+		totals redis.LeaderboardTotals
+		score  float64
+	}
+	var all []ranked
+	for _, t := range l.byID {
+		if score, ok := leaderboardScore(metric, *t); ok {
+			all = append(all, ranked{*t, score})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].score != all[j].score {
+			return (all[i].score < all[j].score) == ascending
+		}
+		return all[i].totals.ID < all[j].totals.ID
+	})
+
+	if len(all) > limit {
+		all = all[:limit]
+	}
+	top := make([]redis.LeaderboardTotals, len(all))
+	for i, r := range all {
+		top[i] = r.totals
+	}
+	return top
+}
@@ -1,29 +1,45 @@
 package room
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
+	"slapjack/internal/clock"
+	"slapjack/internal/config"
+	"slapjack/internal/game"
+	"slapjack/internal/history"
 	"slapjack/internal/redis"
+	"slapjack/internal/stats"
+	"slapjack/internal/webhook"
 	"slapjack/pkg/protocol"
 )
 
 const (
 	roomCodeChars   = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // Avoiding confusing chars like 0/O, 1/I
 	roomCodeLength  = 4
-	roomTTL         = 2 * time.Hour
-	sessionTTL      = 30 * time.Minute
 	cleanupInterval = 5 * time.Minute
+
+	// How long the lobby listing is cached between rebuilds
+	roomSummaryCacheTTL = 2 * time.Second
+)
+
+var (
+	ErrCreateCooldown = errors.New("please wait before creating another room")
+	ErrTooManyRooms   = errors.New("too many active rooms for this session")
+	ErrInviteOnly     = errors.New("this room is private, you need an invite to join")
+	ErrSpectatorsFull = errors.New("this room has as many spectators as it allows")
 )
 
 // SessionData for in-memory fallback
 type SessionData struct {
-	PlayerID  string
-	RoomCode  string
+	PlayerID string
+	RoomCode string
 }
 
 // Manager handles room lifecycle and coordination
@@ -31,19 +47,112 @@ type Manager struct {
 	rooms    map[string]*Room
 	sessions map[string]*SessionData // In-memory session fallback
 	store    *redis.Store
+	cfg      *config.Live
 	mu       sync.RWMutex
+
+	// Rooms created by each session (session ID -> room code -> host player ID)
+	roomOwners     map[string]map[string]string
+	lastRoomCreate map[string]time.Time
+
+	// Recently finished game replays
+	replays *replayCache
+
+	// Flagged play patterns and reaction times per room
+	suspicions    *reviewLog[protocol.SuspicionFlag]
+	cheatWarnings *reviewLog[protocol.CheatWarning]
+
+	// Client-reported timings next to the server's, per room
+	telemetry *reviewLog[protocol.TelemetryReport]
+
+	// Reconnection tokens for sessions
+	tokens *sessionTokens
+
+	// Signs the results of won games
+	results *resultSigner
+
+	// Player profiles, cached from Redis
+	profiles *profiles
+
+	// Coin balances and cosmetics, when there is no Redis
+	wallets *wallets
+
+	// Leaderboard totals, when there is no Redis
+	leaderboards *leaderboards
+
+	// Input lag estimates, when there is no Redis
+	calibrations *calibrations
+
+	// Unredeemed room invites, when there is no Redis
+	invites *invites
+
+	// Posts rooms' games to their webhooks
+	webhooks *webhook.Notifier
+
+	// Archives finished games for good, if the server has a history database
+	history *history.Archive
+
+	// Cached lobby listing
+	summaryCache    []RoomSummary
+	summaryCachedAt time.Time
+	summaryMu       sync.Mutex
+
+	// Redis outage tracking, and the background writes that keep Redis
+	// up to date
+	storeHealth *storeHealth
+	storeWriter *storeWriter
+
+	// Server-wide activity rollups
+	stats *stats.Worker
+
+	// Pending removals for disconnected players, keyed by room code + player ID
+	disconnectTimers map[string]clock.Timer
+	timersMu         sync.Mutex
+
+	// Ends a game, or party pile, once its win condition decides a winner;
+	// set by the hub, which sends the results. See SetGameOverCheck
+	gameOverCheck func(roomCode string, r *Room, g *game.Game, pile int) bool
+
+	clock clock.Clock
 }
 
 // NewManager creates a new room manager
-func NewManager(store *redis.Store) *Manager {
+// All of its timers, and those of the games it runs, use clk
+func NewManager(store *redis.Store, cfg *config.Live, clk clock.Clock) *Manager {
 	m := &Manager{
-		rooms:    make(map[string]*Room),
-		sessions: make(map[string]*SessionData),
-		store:    store,
+		rooms:            make(map[string]*Room),
+		sessions:         make(map[string]*SessionData),
+		store:            store,
+		cfg:              cfg,
+		roomOwners:       make(map[string]map[string]string),
+		lastRoomCreate:   make(map[string]time.Time),
+		replays:          newReplayCache(),
+		suspicions:       newReviewLog[protocol.SuspicionFlag](),
+		cheatWarnings:    newReviewLog[protocol.CheatWarning](),
+		telemetry:        newReviewLog[protocol.TelemetryReport](),
+		tokens:           newSessionTokens(cfg.Get().SessionSecret),
+		results:          newResultSigner(cfg.Get().ResultSigningKey),
+		profiles:         newProfiles(),
+		wallets:          newWallets(),
+		leaderboards:     newLeaderboards(),
+		calibrations:     newCalibrations(),
+		invites:          newInvites(),
+		webhooks:         webhook.NewNotifier(),
+		storeHealth:      newStoreHealth(clk),
+		stats:            stats.NewWorker(store),
+		disconnectTimers: make(map[string]clock.Timer),
+		clock:            clk,
 	}
+	m.storeWriter = newStoreWriter(m.storeHealth, cfg)
 
 	// Start cleanup routine
 	go m.cleanupRoutine()
+	go m.stats.Run()
+	go m.webhooks.Run()
+	if store != nil {
+		go m.storeHealthRoutine()
+		go m.storeWriter.run()
+		go m.roomSaveRoutine()
+	}
 
 	return m
 }
@@ -68,30 +177,91 @@ func (m *Manager) generateRoomCode() string {
 	return ""
 }
 
-// CreateRoom creates a new room and returns it with the host's player ID
-func (m *Manager) CreateRoom(hostName string) (*Room, string, error) {
+// CreateRoom creates a new room owned by the given session and returns it with the host's player ID
+// Any rooms the session previously created are cleaned up first
+// The host shows the profile's avatar if one is given
+func (m *Manager) CreateRoom(sessionID, hostName, playerToken, variantID string, profile *Profile, broadcast func(string, []byte)) (*Room, string, error) {
+	var v variant
+	if variantID != "" {
+		var ok bool
+		if v, ok = m.variant(variantID); !ok {
+			return nil, "", ErrUnknownVariant
+		}
+	}
+
+	m.mu.Lock()
+	if last, ok := m.lastRoomCreate[sessionID]; ok && m.clock.Since(last) < m.cfg.Get().CreateRoomCooldown {
+		m.mu.Unlock()
+		return nil, "", ErrCreateCooldown
+	}
+	m.mu.Unlock()
+
+	m.CleanupPlayerRooms(sessionID, broadcast)
+
 	code := m.generateRoomCode()
 	if code == "" {
 		return nil, "", errors.New("failed to generate room code")
 	}
 
-	room, playerID := NewRoom(code, hostName)
+	room, playerID := NewRoom(code, hostName, playerToken, m.clock)
+	if v.id != "" {
+		room.applyVariant(v)
+	}
+	room.setPlayerProfile(playerID, profile)
+	room.setPlayerLoadout(playerID, m.loadout(playerToken))
+	room.setInputLag(playerID, m.inputLag(playerToken))
 
+	// The cooldown and limit are checked again with the room added under the
+	// same lock, so concurrent creates by one session can't both get through
 	m.mu.Lock()
+	if err := m.checkCreateLimits(sessionID); err != nil {
+		m.mu.Unlock()
+		return nil, "", err
+	}
+	if _, taken := m.rooms[code]; taken {
+		m.mu.Unlock()
+		return nil, "", errors.New("failed to generate room code")
+	}
 	m.rooms[code] = room
+	m.roomOwners[sessionID][code] = playerID
+	m.lastRoomCreate[sessionID] = m.clock.Now()
 	m.mu.Unlock()
 
 	// Store in Redis
-	if m.store != nil {
-		m.store.AddActiveRoom(code)
-		m.store.SetRoom(code, room, roomTTL)
-	}
+	m.persist("add active room "+code, func(ctx context.Context) error {
+		return m.store.AddActiveRoom(ctx, code)
+	})
+	m.saveRoom(code, room)
 
 	return room, playerID, nil
 }
 
-// JoinRoom adds a player to an existing room
-func (m *Manager) JoinRoom(code, playerName string) (*Room, string, *Player, error) {
+// checkCreateLimits returns why a session can't create a room now, if it
+// can't: the create cooldown, or the rooms it owns, counting only those that
+// still exist
+// Caller must hold m.mu for writing
+func (m *Manager) checkCreateLimits(sessionID string) error {
+	if last, ok := m.lastRoomCreate[sessionID]; ok && m.clock.Since(last) < m.cfg.Get().CreateRoomCooldown {
+		return ErrCreateCooldown
+	}
+	owned := make(map[string]string)
+	for code, hostID := range m.roomOwners[sessionID] {
+		if _, exists := m.rooms[code]; exists {
+			owned[code] = hostID
+		}
+	}
+	m.roomOwners[sessionID] = owned
+	if len(owned) >= m.cfg.Get().MaxRoomsPerSession {
+		return ErrTooManyRooms
+	}
+	return nil
+}
+
+// JoinRoom adds a player to an existing room, showing the profile's avatar if one is given
+// A player token that is still seated in the room takes its seat back, even mid-game
+// New players need an invite to get into a private room, which it uses up;
+// other rooms ignore it, leaving it for someone else
+func (m *Manager) JoinRoom(code, playerName, playerToken string, profile *Profile, invite string) (*Room, string, *Player, error) {
 	m.mu.RLock()
 	room, exists := m.rooms[code]
 	m.mu.RUnlock()
@@ -100,55 +270,51 @@ func (m *Manager) JoinRoom(code, playerName string) (*Room, string, *Player, err
 		return nil, "", nil, errors.New("room not found")
 	}
 
-	if room.Status != "waiting" {
-		return nil, "", nil, errors.New("game already in progress")
+	profileID := ""
+	if profile != nil {
+		profileID = profile.ID
+	}
+	if room.IsBanned(playerToken, profileID, m.clock.Now()) {
+		return nil, "", nil, ErrBanned
 	}
 
-	if room.IsFull() {
-		return nil, "", nil, errors.New("room is full")
+	if seated := room.GetPlayerByToken(playerToken); seated != nil {
+		m.ReconnectPlayer(code, seated.ID)
+	} else {
+		if room.Status != "waiting" {
+			return nil, "", nil, errors.New("game already in progress")
+		}
+
+		if room.IsFull() {
+			return nil, "", nil, errors.New("room is full")
+		}
+		if room.Settings.Visibility == protocol.VisibilityPrivate {
+			if invite == "" {
+				return nil, "", nil, ErrInviteOnly
+			}
+			if err := m.redeemInvite(code, invite); err != nil {
+				return nil, "", nil, err
+			}
+		}
 	}
 
-	player, err := room.AddPlayer(playerName)
+	player, err := room.AddPlayer(playerName, playerToken)
 	if err != nil {
 		return nil, "", nil, err
 	}
+	room.setPlayerProfile(player.ID, profile)
+	room.setPlayerLoadout(player.ID, m.loadout(playerToken))
+	room.setInputLag(player.ID, m.inputLag(playerToken))
 
 	// Update Redis
-	if m.store != nil {
-		m.store.SetRoom(code, room, roomTTL)
-	}
+	m.saveRoom(code, room)
 
 	return room, player.ID, player, nil
 }
 
 // LeaveRoom removes a player from a room
-func (m *Manager) LeaveRoom(code, playerID string) {
-	m.mu.RLock()
-	room, exists := m.rooms[code]
-	m.mu.RUnlock()
-
-	if !exists {
-		return
-	}
-
-	room.RemovePlayer(playerID)
-
-	// If room is empty, delete immediately
-	if room.IsEmpty() {
-		m.mu.Lock()
-		delete(m.rooms, code)
-		m.mu.Unlock()
-		if m.store != nil {
-			m.store.DeleteRoom(code)
-		}
-		log.Printf("Room %s deleted (all players left)", code)
-		return
-	}
-
-	// Update Redis
-	if m.store != nil {
-		m.store.SetRoom(code, room, roomTTL)
-	}
+func (m *Manager) LeaveRoom(code, playerID string, broadcast func(string, []byte)) {
+	m.RemoveMember(code, playerID, playerLeftEvent(playerID), broadcast)
 }
 
 // GetRoom returns a room by code
@@ -163,53 +329,105 @@ func (m *Manager) DeleteRoom(code string) {
 	m.mu.Lock()
 	delete(m.rooms, code)
 	m.mu.Unlock()
+	m.suspicions.remove(code)
+	m.cheatWarnings.remove(code)
+	m.telemetry.remove(code)
 
-	if m.store != nil {
-		m.store.DeleteRoom(code)
-	}
+	m.deleteStoredRoom(code)
 }
 
 // RoomSummary represents a room for the lobby list
 type RoomSummary struct {
-	Code        string `json:"code"`
-	PlayerCount int    `json:"playerCount"`
-	MaxPlayers  int    `json:"maxPlayers"`
-	Status      string `json:"status"`
-	HostName    string `json:"hostName"`
+	Code           string   `json:"code"`
+	PlayerCount    int      `json:"playerCount"`
+	MaxPlayers     int      `json:"maxPlayers"`
+	Status         string   `json:"status"`
+	HostName       string   `json:"hostName"`
+	SpectatorCount int      `json:"spectatorCount"`
+	AgeSeconds     int64    `json:"ageSeconds"`
+	Rules          []string `json:"rules"`
+	DropIn         string   `json:"dropIn,omitempty"`
+	ScheduledAt    int64    `json:"scheduledAt,omitempty"` // Unix milliseconds
 }
 
 // GetActiveRooms returns a list of joinable rooms
+// The list is rebuilt at most every roomSummaryCacheTTL
 func (m *Manager) GetActiveRooms() []RoomSummary {
+	m.summaryMu.Lock()
+	defer m.summaryMu.Unlock()
+
+	if m.summaryCache != nil && m.clock.Since(m.summaryCachedAt) < roomSummaryCacheTTL {
+		return m.summaryCache
+	}
+
+	m.summaryCache = m.buildRoomSummaries()
+	m.summaryCachedAt = m.clock.Now()
+	return m.summaryCache
+}
+
+// buildRoomSummaries computes the lobby listing
+func (m *Manager) buildRoomSummaries() []RoomSummary {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	rooms := make([]RoomSummary, 0)
 	for _, room := range m.rooms {
-		// Only show waiting rooms that aren't full
-		if room.Status == "waiting" && !room.IsFull() {
+		// Only show public waiting rooms that aren't full, and never classroom rooms
+		if room.Status == "waiting" && !room.IsFull() && !room.Settings.ClassroomMode &&
+			room.Settings.Visibility == protocol.VisibilityPublic {
 			hostName := ""
-			for _, p := range room.Players {
-				if p.ID == room.HostID {
-					hostName = p.Name
-					break
-				}
+			if host := room.GetPlayer(room.HostID); host != nil {
+				hostName = host.Name
 			}
 			rooms = append(rooms, RoomSummary{
-				Code:        room.Code,
-				PlayerCount: len(room.GetConnectedPlayers()),
-				MaxPlayers:  room.Settings.MaxPlayers,
-				Status:      room.Status,
-				HostName:    hostName,
+				Code:           room.Code,
+				PlayerCount:    len(room.GetConnectedPlayers()),
+				MaxPlayers:     room.Settings.MaxPlayers,
+				Status:         room.Status,
+				HostName:       hostName,
+				SpectatorCount: room.SpectatorCount(),
+				AgeSeconds:     int64(m.clock.Since(room.CreatedAt).Seconds()),
+				Rules:          room.Settings.RulesSummary(),
+				DropIn:         room.DropIn,
+				ScheduledAt:    room.scheduledAtMillis(),
 			})
 		}
 	}
 	return rooms
 }
 
+// SpectateRoom adds a session to a room as a spectator, using up the invite
+// if the room is private
+func (m *Manager) SpectateRoom(code, sessionID, invite string) (*Room, error) {
+	room := m.GetRoom(code)
+	if room == nil {
+		return nil, errors.New("room not found")
+	}
+
+	invited := false
+	if invite != "" && room.GetSettings().Visibility == protocol.VisibilityPrivate {
+		if err := m.redeemInvite(code, invite); err != nil {
+			return nil, err
+		}
+		invited = true
+	}
+	if err := room.AddSpectator(sessionID, invited); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// StopSpectating removes a spectator from a room
+func (m *Manager) StopSpectating(code, sessionID string) {
+	if room := m.GetRoom(code); room != nil {
+		room.RemoveSpectator(sessionID)
+	}
+}
+
 // DebugPlayer for debug info
 type DebugPlayer struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name,omitempty"`
 	CardCount   int    `json:"cardCount"`
 	IsHost      bool   `json:"isHost"`
 	IsConnected bool   `json:"isConnected"`
@@ -219,7 +437,7 @@ type DebugPlayer struct {
 type DebugRoom struct {
 	Code    string        `json:"code"`
 	Status  string        `json:"status"`
-	HostID  string        `json:"hostId"`
+	HostID  string        `json:"hostId,omitempty"`
 	Players []DebugPlayer `json:"players"`
 	HasGame bool          `json:"hasGame"`
 }
@@ -265,16 +483,18 @@ func (m *Manager) SaveSession(sessionID, playerID, roomCode string) {
 		RoomCode: roomCode,
 	}
 	m.mu.Unlock()
-	log.Printf("[Session] Saved session %s -> room %s, player %s", sessionID, roomCode, playerID)
+	slog.Debug("session saved", "sessionId", sessionID, "roomCode", roomCode, "playerId", playerID)
 
 	// Also save to Redis if available
-	if m.store != nil {
-		m.store.SetSession(sessionID, redis.SessionData{
-			PlayerID:  playerID,
-			RoomCode:  roomCode,
-			ExpiresAt: time.Now().Add(sessionTTL),
-		}, sessionTTL)
+	session := redis.SessionData{
+		PlayerID:  playerID,
+		RoomCode:  roomCode,
+		ExpiresAt: m.clock.Now().Add(m.cfg.Get().SessionTTL),
 	}
+	ttl := m.cfg.Get().SessionTTL
+	m.persist("save session", func(ctx context.Context) error {
+		return m.store.SetSession(ctx, sessionID, session, ttl)
+	})
 }
 
 // GetSession retrieves a player's session
@@ -285,7 +505,7 @@ func (m *Manager) GetSession(sessionID string) *redis.SessionData {
 	m.mu.RUnlock()
 
 	if exists {
-		log.Printf("[Session] Found in-memory session %s -> room %s", sessionID, session.RoomCode)
+		slog.Debug("found in-memory session", "sessionId", sessionID, "roomCode", session.RoomCode)
 		return &redis.SessionData{
 			PlayerID: session.PlayerID,
 			RoomCode: session.RoomCode,
@@ -294,117 +514,264 @@ func (m *Manager) GetSession(sessionID string) *redis.SessionData {
 
 	// Fall back to Redis
 	if m.store != nil {
-		redisSession, _ := m.store.GetSession(sessionID)
+		redisSession, _ := m.store.GetSession(context.Background(), sessionID)
 		return redisSession
 	}
 
 	return nil
 }
 
-// NotifyPlayerDisconnected notifies other players that someone disconnected
-func (m *Manager) NotifyPlayerDisconnected(roomCode, playerID string, broadcast func(string, []byte)) {
+// ReconnectGrace returns how long disconnected players keep their seat
+func (m *Manager) ReconnectGrace() time.Duration {
+	return m.cfg.Get().ReconnectGrace
+}
+
+// HandleDisconnect marks a player as disconnected and schedules their removal
+// once the reconnection grace period expires
+func (m *Manager) HandleDisconnect(roomCode, playerID string, broadcast func(string, []byte)) {
 	room := m.GetRoom(roomCode)
 	if room == nil {
 		return
 	}
 
-	// Update room state
-	msgData, _ := json.Marshal(protocol.NewMessage(protocol.RoomUpdated, protocol.RoomJoinedPayload{
-		Room: room.ToProtocol(),
+	room.MarkPlayerDisconnected(playerID)
+
+	m.NotifyMembershipChanged(roomCode, broadcast, protocol.NewMessage(protocol.PlayerDisconnected, protocol.PlayerDisconnectedPayload{
+		PlayerID:     playerID,
+		GraceSeconds: int(m.cfg.Get().ReconnectGrace / time.Second),
 	}))
-	broadcast(roomCode, msgData)
+
+	// Pause the player's turns so the game doesn't wait on them
+	if room.Game != nil && room.Game.SetPlayerConnected(playerID, false) {
+		broadcast(roomCode, room.Game.TurnChangedMessage())
+	}
+
+	key := roomCode + ":" + playerID
+	m.timersMu.Lock()
+	if t, ok := m.disconnectTimers[key]; ok {
+		t.Stop()
+	}
+	m.disconnectTimers[key] = m.clock.AfterFunc(m.cfg.Get().ReconnectGrace, func() {
+		m.expireDisconnectedPlayer(roomCode, playerID, broadcast)
+	})
+	m.timersMu.Unlock()
 }
 
-// NotifyPlayerLeft notifies other players that someone left
-func (m *Manager) NotifyPlayerLeft(roomCode, playerID string, broadcast func(string, []byte)) {
-	msgData, _ := json.Marshal(protocol.NewMessage(protocol.PlayerLeft, protocol.PlayerLeftPayload{
-		PlayerID: playerID,
-	}))
-	broadcast(roomCode, msgData)
+// ReconnectPlayer cancels a pending removal and marks the player connected again
+func (m *Manager) ReconnectPlayer(roomCode, playerID string) *Player {
+	m.timersMu.Lock()
+	key := roomCode + ":" + playerID
+	if t, ok := m.disconnectTimers[key]; ok {
+		t.Stop()
+		delete(m.disconnectTimers, key)
+	}
+	m.timersMu.Unlock()
+
+	room := m.GetRoom(roomCode)
+	if room == nil {
+		return nil
+	}
+
+	player := room.GetPlayer(playerID)
+	if player == nil {
+		return nil
+	}
+
+	room.MarkPlayerConnected(playerID)
+	if room.Game != nil {
+		room.Game.SetPlayerConnected(playerID, true)
+	}
+	return player
 }
 
-// CleanupPlayerRooms removes player from any existing rooms (for when they create a new one)
-func (m *Manager) CleanupPlayerRooms(playerID string, broadcast func(string, []byte)) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// hasPendingDisconnects reports whether any player in the room is still within their grace period
+func (m *Manager) hasPendingDisconnects(roomCode string) bool {
+	m.timersMu.Lock()
+	defer m.timersMu.Unlock()
 
-	for code, room := range m.rooms {
-		// Check if player is in this room
-		for _, p := range room.Players {
-			if p.ID == playerID {
-				// If they're the host, delete the whole room
-				if room.HostID == playerID {
-					delete(m.rooms, code)
-					if m.store != nil {
-						m.store.DeleteRoom(code)
-					}
-					log.Printf("Deleted room %s (host created new room)", code)
-					// Notify other players
-					go func(roomCode string) {
-						broadcast(roomCode, []byte(`{"type":"ROOM_CLOSED","payload":{"reason":"Host left"}}`))
-					}(code)
-				} else {
-					// Just remove them from the room
-					room.RemovePlayer(playerID)
-					go func(roomCode, pid string) {
-						m.NotifyPlayerLeft(roomCode, pid, broadcast)
-					}(code, playerID)
-				}
-				break
-			}
+	prefix := roomCode + ":"
+	for key := range m.disconnectTimers {
+		if strings.HasPrefix(key, prefix) {
+			return true
 		}
 	}
+	return false
+}
+
+// hasPendingDisconnect reports whether a player is still within their grace period
+func (m *Manager) hasPendingDisconnect(roomCode, playerID string) bool {
+	m.timersMu.Lock()
+	defer m.timersMu.Unlock()
+	_, ok := m.disconnectTimers[roomCode+":"+playerID]
+	return ok
 }
 
-// StartGameCountdown starts the game countdown and then starts the game
-func (m *Manager) StartGameCountdown(roomCode string, broadcast func(string, []byte)) {
+// expireDisconnectedPlayer removes a player whose grace period ran out,
+// migrating host powers or disbanding the room as needed
+func (m *Manager) expireDisconnectedPlayer(roomCode, playerID string, broadcast func(string, []byte)) {
+	m.timersMu.Lock()
+	delete(m.disconnectTimers, roomCode+":"+playerID)
+	m.timersMu.Unlock()
+
 	room := m.GetRoom(roomCode)
 	if room == nil {
 		return
 	}
 
-	room.Status = "starting"
+	player := room.GetPlayer(playerID)
+	if player == nil || player.IsConnected {
+		return
+	}
+	// Players keep their seat for a scheduled game until it starts
+	if room.Scheduled() {
+		return
+	}
 
-	// 3-2-1 countdown
-	for i := 3; i > 0; i-- {
-		msgData, _ := json.Marshal(protocol.NewMessage(protocol.GameStarting, protocol.GameStartingPayload{
-			Countdown: i,
-		}))
-		broadcast(roomCode, msgData)
-		time.Sleep(1 * time.Second)
+	slog.Info("player did not reconnect in time", "roomCode", roomCode, "playerId", playerID)
+	m.RemoveMember(roomCode, playerID, playerLeftEvent(playerID), broadcast)
+}
+
+// CleanupPlayerRooms removes a session's player from every room it belongs to
+// (for when they create or join another one). Rooms the session hosts are closed.
+func (m *Manager) CleanupPlayerRooms(sessionID string, broadcast func(string, []byte)) {
+	m.LeaveOtherRooms(sessionID, "", broadcast)
+}
+
+// LeaveOtherRooms removes a session's player from every room it belongs to
+// other than keepCode, for once it has joined that one. Rooms the session
+// hosts are closed.
+func (m *Manager) LeaveOtherRooms(sessionID, keepCode string, broadcast func(string, []byte)) {
+	m.mu.Lock()
+	memberships := make(map[string]string)
+	for code, playerID := range m.roomOwners[sessionID] {
+		memberships[code] = playerID
+	}
+	if session, ok := m.sessions[sessionID]; ok {
+		memberships[session.RoomCode] = session.PlayerID
+		delete(m.sessions, sessionID)
+	}
+	m.mu.Unlock()
+	if keepCode != "" {
+		delete(memberships, keepCode)
 	}
 
+	for code, playerID := range memberships {
+		room := m.GetRoom(code)
+		if room == nil || room.GetPlayer(playerID) == nil {
+			continue
+		}
+
+		// If they're the host, close the whole room
+		if room.HostID == playerID {
+			m.DeleteRoom(code)
+			slog.Info("room deleted, host moved to another room", "roomCode", code)
+			msgData, _ := json.Marshal(protocol.NewMessage(protocol.RoomClosed, protocol.RoomClosedPayload{
+				Reason: "Host left",
+			}))
+			broadcast(code, msgData)
+			continue
+		}
+
+		// Otherwise just remove them and send everyone the updated room
+		slog.Info("removing player who moved to another room", "roomCode", code, "playerId", playerID)
+		m.RemoveMember(code, playerID, playerLeftEvent(playerID), broadcast)
+	}
+}
+
+// startGame deals a new game once the countdown has finished and starts the first turn
+func (m *Manager) startGame(room *Room, broadcast func(string, []byte)) {
+	roomCode := room.Code
+
 	// Start the game
-	room.StartGame()
+	room.StartGame(m.clock)
+	if room.Piles() != nil {
+		m.startPiles(room, broadcast)
+		m.notifyGameStarted(room)
+		return
+	}
+	m.dealGame(roomCode, room.Game, broadcast)
+	m.notifyGameStarted(room)
+
+	m.stats.GameStarted()
+	slog.Info("game started", "roomCode", roomCode, "gameId", room.Game.ID)
+}
 
+// dealGame tells a room a game has started and been dealt, and starts its
+// first turn
+func (m *Manager) dealGame(roomCode string, g *game.Game, broadcast func(string, []byte)) {
 	// Send game started
-	gameState := room.Game.GetState()
 	startedMsg, _ := json.Marshal(protocol.NewMessage(protocol.GameStarted, protocol.GameStartedPayload{
-		GameState: gameState,
+		GameID:    g.ID,
+		GameState: g.GetState(),
 	}))
 	broadcast(roomCode, startedMsg)
 
-	// Send cards dealt (card counts per player)
+	// Send cards dealt (card counts per player, plus any remainder)
+	faceDown, discarded := g.GetDealRemainder()
 	dealtMsg, _ := json.Marshal(protocol.NewMessage(protocol.CardsDealt, protocol.CardsDealtPayload{
-		PlayerCards: room.Game.GetCardCounts(),
+		PlayerCards:   g.GetCardCounts(),
+		StartingPile:  faceDown,
+		DiscardedDeal: discarded,
 	}))
 	broadcast(roomCode, dealtMsg)
 
 	// Send first turn
-	turnMsg, _ := json.Marshal(protocol.NewMessage(protocol.TurnChanged, protocol.TurnChangedPayload{
-		CurrentPlayerID: room.Game.GetCurrentPlayer(),
-	}))
-	broadcast(roomCode, turnMsg)
+	broadcast(roomCode, g.TurnChangedMessage())
 
 	// Start turn timer
-	go room.Game.StartTurnTimer(roomCode, broadcast, m)
+	go g.StartTurnTimer(roomCode, broadcast, m)
+}
+
+// TimedGames returns the rooms playing a game with a clock
+func (m *Manager) TimedGames() []*Room {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var timed []*Room
+	for _, room := range m.rooms {
+		room.mu.RLock()
+		status, g := room.Status, room.Game
+		room.mu.RUnlock()
+		if status != "playing" || g == nil {
+			continue
+		}
+		if _, ok := g.ClockRemaining(); ok {
+			timed = append(timed, room)
+		}
+	}
+	return timed
+}
+
+// PersistAll writes every room and in-progress game to Redis, after any
+// writes still queued
+func (m *Manager) PersistAll() {
+	if m.store == nil {
+		return
+	}
+	m.flushRooms()
+	m.storeWriter.flush()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	log.Printf("Game started in room %s", roomCode)
+	ctx := context.Background()
+	for code, room := range m.rooms {
+		if err := m.store.SetRoom(ctx, code, room.Snapshot(), m.roomTTL(room)); err != nil {
+			slog.Error("failed to persist room", "roomCode", code, "error", err)
+		}
+		if room.Game != nil {
+			if err := m.store.SetGameState(ctx, code, room.Game.GetObserverState(), m.cfg.Get().RoomTTL); err != nil {
+				slog.Error("failed to persist game", "roomCode", code, "error", err)
+			}
+		}
+	}
+	m.stats.Flush()
+	slog.Info("persisted rooms", "count", len(m.rooms))
 }
 
 // scheduleRoomCleanup schedules a room for cleanup after a delay
 func (m *Manager) scheduleRoomCleanup(code string, delay time.Duration) {
-	time.Sleep(delay)
+	m.clock.Sleep(delay)
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -414,30 +781,104 @@ func (m *Manager) scheduleRoomCleanup(code string, delay time.Duration) {
 		return
 	}
 
-	// Check if still empty
-	if room.IsEmpty() {
+	// Check if still empty, and not being held open for a scheduled game
+	if room.IsEmpty() && !room.Scheduled() {
 		delete(m.rooms, code)
-		if m.store != nil {
-			m.store.DeleteRoom(code)
+		m.deleteStoredRoom(code)
+		slog.Info("room cleaned up", "roomCode", code)
+	}
+}
+
+// Stats returns the server-wide stats worker
+func (m *Manager) Stats() *stats.Worker {
+	return m.stats
+}
+
+// TouchRoom records client activity in a room
+func (m *Manager) TouchRoom(code string) {
+	if room := m.GetRoom(code); room != nil {
+		room.Touch()
+	}
+}
+
+// ExpireIdleRooms deletes waiting or playing rooms with no activity for the
+// configured idle timeout, telling anyone still in them. It returns the expired room codes.
+func (m *Manager) ExpireIdleRooms(broadcast func(string, []byte)) []string {
+	if m.cfg.Get().IdleRoomTimeout <= 0 {
+		return nil
+	}
+
+	var idle []*Room
+	m.mu.RLock()
+	for _, room := range m.rooms {
+		// Drop-in rooms are meant to sit empty until someone plays, and
+		// scheduled rooms to wait for their start
+		if (room.DropIn != "" && room.IsEmpty()) || room.Scheduled() {
+			continue
+		}
+		if (room.Status == "waiting" || room.Status == "playing") && room.IdleFor() > m.cfg.Get().IdleRoomTimeout {
+			idle = append(idle, room)
+		}
+	}
+	m.mu.RUnlock()
+
+	codes := make([]string, 0, len(idle))
+	for _, room := range idle {
+		if room.Game != nil {
+			room.Game.CancelTurnTimer()
+			room.Game.RecordEnded("Room expired")
+			m.SaveReplay(room.Code, room.Game)
 		}
-		log.Printf("Room %s cleaned up", code)
+
+		msgData, _ := json.Marshal(protocol.NewMessage(protocol.RoomExpired, protocol.RoomExpiredPayload{
+			Reason:      "Room expired after inactivity",
+			IdleSeconds: int(room.IdleFor().Seconds()),
+		}))
+		broadcast(room.Code, msgData)
+
+		m.DeleteRoom(room.Code)
+		codes = append(codes, room.Code)
+		slog.Info("idle room expired", "roomCode", room.Code, "status", room.Status)
 	}
+	return codes
 }
 
 // cleanupRoutine periodically cleans up empty/stale rooms
 func (m *Manager) cleanupRoutine() {
-	ticker := time.NewTicker(cleanupInterval)
-	for range ticker.C {
+	ticker := m.clock.NewTicker(cleanupInterval)
+	for range ticker.C() {
 		m.mu.Lock()
 		for code, room := range m.rooms {
-			if room.IsEmpty() || room.Status == "finished" {
+			// Drop-in rooms are recycled by maintainDropIns instead
+			if room.DropIn != "" {
+				continue
+			}
+			if (room.IsEmpty() && !m.hasPendingDisconnects(code) && !room.Scheduled()) || room.Status == "finished" {
 				delete(m.rooms, code)
-				if m.store != nil {
-					m.store.DeleteRoom(code)
+				m.suspicions.remove(code)
+				m.cheatWarnings.remove(code)
+				m.telemetry.remove(code)
+				m.deleteStoredRoom(code)
+				slog.Info("room cleaned up by routine", "roomCode", code)
+			}
+		}
+		for sessionID, last := range m.lastRoomCreate {
+			if m.clock.Since(last) > m.cfg.Get().CreateRoomCooldown {
+				delete(m.lastRoomCreate, sessionID)
+			}
+		}
+		for sessionID, owned := range m.roomOwners {
+			for code := range owned {
+				if _, exists := m.rooms[code]; !exists {
+					delete(owned, code)
 				}
-				log.Printf("Room %s cleaned up (routine)", code)
+			}
+			if len(owned) == 0 {
+				delete(m.roomOwners, sessionID)
 			}
 		}
+		m.tokens.prune(m.sessions, m.cfg.Get().SessionTTL, m.clock.Now())
 		m.mu.Unlock()
+		m.profiles.prune(m.cfg.Get().ProfileTTL, m.clock.Now())
 	}
 }
@@ -0,0 +1,121 @@
+package room
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"slapjack/internal/game"
+	"slapjack/pkg/protocol"
+)
+
+// NotifyMembershipChanged broadcasts the given membership events followed by an
+// authoritative ROOM_UPDATED, so clients never drift after joins, leaves, kicks
+// or host changes
+func (m *Manager) NotifyMembershipChanged(roomCode string, broadcast func(string, []byte), events ...protocol.WSMessage) {
+	for _, event := range events {
+		msgData, _ := json.Marshal(event)
+		broadcast(roomCode, msgData)
+	}
+
+	room := m.GetRoom(roomCode)
+	if room == nil {
+		return
+	}
+
+	// A game can't start with fewer than two players left
+	if len(room.GetConnectedPlayers()) < 2 {
+		m.CancelCountdown(roomCode, protocol.StartCancelledPlayersLeft, broadcast)
+	}
+
+	broadcast(roomCode, room.UpdatedMessage())
+}
+
+// RemoveMember removes a player from a room and notifies the remaining players
+// with the given event, migrating host powers if needed. Rooms are deleted
+// once no one is connected and no one is still within their grace period.
+func (m *Manager) RemoveMember(roomCode, playerID string, event protocol.WSMessage, broadcast func(string, []byte)) {
+	room := m.GetRoom(roomCode)
+	if room == nil {
+		return
+	}
+
+	g, pile := room.GameFor(playerID)
+	newHostID, turnMoved := room.RemovePlayer(playerID, event.Type == protocol.PlayerKicked)
+
+	// If room is empty, and no one left can still reconnect, delete immediately
+	if room.IsEmpty() && !m.hasPendingDisconnects(roomCode) {
+		m.DeleteRoom(roomCode)
+		slog.Info("room deleted, all players left", "roomCode", roomCode)
+		return
+	}
+
+	if g != nil {
+		m.announceLeave(roomCode, room, g, pile, turnMoved, broadcast)
+	}
+
+	// Update Redis
+	m.saveRoom(roomCode, room)
+
+	events := []protocol.WSMessage{event}
+	if newHostID != "" {
+		slog.Info("host migrated", "roomCode", roomCode, "playerId", playerID, "newHostId", newHostID)
+		events = append(events, protocol.NewMessage(protocol.HostMigrated, protocol.HostMigratedPayload{
+			PreviousHostID: playerID,
+			NewHostID:      newHostID,
+		}))
+	}
+
+	m.NotifyMembershipChanged(roomCode, broadcast, events...)
+}
+
+// SetGameOverCheck sets how games are ended when a player leaving them
+// decides the winner; check returns true if the game has ended
+func (m *Manager) SetGameOverCheck(check func(roomCode string, r *Room, g *game.Game, pile int) bool) {
+	m.gameOverCheck = check
+}
+
+// announceLeave tells the room where the cards of a player who left their
+// game went, and either how the game ended or whose turn it is if it was theirs
+func (m *Manager) announceLeave(roomCode string, room *Room, g *game.Game, pile int, turnMoved bool, broadcast func(string, []byte)) {
+	broadcast = PileBroadcast(pile, broadcast)
+	for _, msgData := range g.DrainMessages() {
+		broadcast(roomCode, msgData)
+	}
+	if m.gameOverCheck != nil && m.gameOverCheck(roomCode, room, g, pile) {
+		return
+	}
+	if turnMoved {
+		broadcast(roomCode, g.TurnChangedMessage())
+		go g.StartTurnTimer(roomCode, broadcast, m)
+	}
+}
+
+// TransferHost hands host powers from the current host to another player and
+// notifies the room
+func (m *Manager) TransferHost(roomCode, newHostID string, broadcast func(string, []byte)) bool {
+	room := m.GetRoom(roomCode)
+	if room == nil {
+		return false
+	}
+
+	previousHostID := room.HostID
+	if !room.TransferHost(newHostID) {
+		return false
+	}
+
+	m.saveRoom(roomCode, room)
+
+	slog.Info("host transferred", "roomCode", roomCode, "playerId", previousHostID, "newHostId", newHostID)
+	m.NotifyMembershipChanged(roomCode, broadcast, protocol.NewMessage(protocol.HostMigrated, protocol.HostMigratedPayload{
+		PreviousHostID: previousHostID,
+		NewHostID:      newHostID,
+	}))
+	return true
+}
+
+// playerLeftEvent builds the PLAYER_LEFT event for a player
+func playerLeftEvent(playerID string) protocol.WSMessage {
+	return protocol.NewMessage(protocol.PlayerLeft, protocol.PlayerLeftPayload{
+		PlayerID: playerID,
+	})
+}
@@ -0,0 +1,217 @@
+package room
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"slapjack/internal/clock"
+	"slapjack/internal/game"
+	"slapjack/pkg/protocol"
+)
+
+// Party rooms: more players than fit round one pile are split across
+// partyPiles piles played at once, and the winner of each pile meets the
+// others in a finale
+const (
+	maxTablePlayers = 8  // Most players one pile is played with
+	maxRoomPlayers  = 16 // Most players a party room holds
+	partyPiles      = 2
+)
+
+// party runs the concurrent piles of a party room up to its finale
+type party struct {
+	games   []*game.Game   // By pile, from 1
+	pileOf  map[string]int // Each player's pile
+	winners []string       // Each pile's winner, "" while it is being played
+}
+
+// startParty splits the players across the party piles by seat, dealing
+// each pile its own game; teams are left out, piles being every player for
+// themselves
+// Caller must hold r.mu
+func (r *Room) startParty(playerIDs []string, opts game.Options) {
+	sort.Slice(playerIDs, func(i, j int) bool {
+		return r.Players[playerIDs[i]].Position < r.Players[playerIDs[j]].Position
+	})
+	piles := make([][]string, partyPiles)
+	p := &party{
+		pileOf:  make(map[string]int, len(playerIDs)),
+		winners: make([]string, partyPiles),
+	}
+	for i, id := range playerIDs {
+		pile := i % partyPiles
+		piles[pile] = append(piles[pile], id)
+		p.pileOf[id] = pile + 1
+	}
+
+	opts.Teams = nil
+	for _, ids := range piles {
+		g := game.NewGame(ids, opts)
+		for _, id := range ids {
+			if ms, ok := r.latency[id]; ok {
+				g.SetLatency(id, time.Duration(ms)*time.Millisecond)
+			}
+			if ms, ok := r.inputLag[id]; ok {
+				g.SetInputLag(id, time.Duration(ms)*time.Millisecond)
+			}
+		}
+		p.games = append(p.games, g)
+	}
+	r.party = p
+	r.Game = nil
+}
+
+// GameFor returns the game a player is playing and its pile: one of the
+// piles while a party room plays them, or the room's game and pile 0
+func (r *Room) GameFor(playerID string) (*game.Game, int) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.gameFor(playerID)
+}
+
+// gameFor returns the game a player is playing and its pile
+// Caller must hold r.mu
+func (r *Room) gameFor(playerID string) (*game.Game, int) {
+	if r.party == nil {
+		return r.Game, 0
+	}
+	pile, ok := r.party.pileOf[playerID]
+	if !ok {
+		pile = 1 // Spectators watch the first pile
+	}
+	return r.party.games[pile-1], pile
+}
+
+// Piles returns the games of a party room's piles, by pile from 1, or nil if
+// it isn't playing them
+func (r *Room) Piles() []*game.Game {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.party == nil {
+		return nil
+	}
+	return append([]*game.Game(nil), r.party.games...)
+}
+
+// PileWon records the winner of a party pile
+// Returns the finalists once every pile has a winner, or nil until then
+func (r *Room) PileWon(pile int, winnerID string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.party == nil || pile < 1 || pile > len(r.party.winners) || r.party.winners[pile-1] != "" {
+		return nil
+	}
+	r.party.winners[pile-1] = winnerID
+	r.version++
+	for _, id := range r.party.winners {
+		if id == "" {
+			return nil
+		}
+	}
+	return append([]string(nil), r.party.winners...)
+}
+
+// EndParty stops a party room's piles, for when the host ends the game
+func (r *Room) EndParty(reason string) {
+	r.mu.Lock()
+	p := r.party
+	r.party = nil
+	r.mu.Unlock()
+
+	if p == nil {
+		return
+	}
+	for _, g := range p.games {
+		g.RecordEnded(reason)
+		g.CancelTurnTimer()
+	}
+}
+
+// startFinale deals the finale between the piles' winners as the room's game
+func (r *Room) startFinale(clk clock.Clock, finalists []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+
+	opts := r.Settings.GameOptions()
+	opts.Clock = clk
+	r.party = nil
+	r.Game = game.NewGame(finalists, opts)
+	for _, id := range finalists {
+		if ms, ok := r.latency[id]; ok {
+			r.Game.SetLatency(id, time.Duration(ms)*time.Millisecond)
+		}
+		if ms, ok := r.inputLag[id]; ok {
+			r.Game.SetInputLag(id, time.Duration(ms)*time.Millisecond)
+		}
+	}
+	r.recordAudit(protocol.AuditGameStart, r.HostID, "", fmt.Sprintf("finale of %d players", len(finalists)))
+}
+
+// partyPiles lists the players on each pile of a party room, or nil
+// Caller must hold r.mu
+func (r *Room) partyPiles() [][]string {
+	if r.party == nil {
+		return nil
+	}
+	piles := make([][]string, len(r.party.games))
+	for id, pile := range r.party.pileOf {
+		piles[pile-1] = append(piles[pile-1], id)
+	}
+	for _, ids := range piles {
+		sort.Strings(ids)
+	}
+	return piles
+}
+
+// startPiles tells a party room its piles are dealt and starts every pile's
+// first turn, each pile's messages marked with its number
+func (m *Manager) startPiles(room *Room, broadcast func(string, []byte)) {
+	room.mu.RLock()
+	piles := room.partyPiles()
+	room.mu.RUnlock()
+
+	startedMsg, _ := json.Marshal(protocol.NewMessage(protocol.PartyStarted, protocol.PartyStartedPayload{
+		Piles: piles,
+	}))
+	broadcast(room.Code, startedMsg)
+
+	for i, g := range room.Piles() {
+		m.dealGame(room.Code, g, PileBroadcast(i+1, broadcast))
+	}
+	m.stats.GameStarted()
+	slog.Info("party piles started", "roomCode", room.Code, "piles", len(piles))
+}
+
+// StartFinale starts the finale of a party room between its piles' winners
+func (m *Manager) StartFinale(roomCode string, finalists []string, broadcast func(string, []byte)) {
+	room := m.GetRoom(roomCode)
+	if room == nil {
+		return
+	}
+	room.startFinale(m.clock, finalists)
+
+	finaleMsg, _ := json.Marshal(protocol.NewMessage(protocol.FinaleStarting, protocol.FinaleStartingPayload{
+		Finalists: finalists,
+	}))
+	broadcast(roomCode, finaleMsg)
+	m.dealGame(roomCode, room.Game, broadcast)
+	m.notifyFinale(room, finalists)
+	slog.Info("party finale started", "roomCode", roomCode, "gameId", room.Game.ID)
+}
+
+// PileBroadcast wraps a room broadcast to mark every message with a party
+// pile; pile 0 leaves them unmarked
+func PileBroadcast(pile int, broadcast func(string, []byte)) func(string, []byte) {
+	if pile == 0 {
+		return broadcast
+	}
+	return func(roomCode string, message []byte) {
+		broadcast(roomCode, protocol.WithPile(pile, message))
+	}
+}
@@ -0,0 +1,189 @@
+package room
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"slapjack/pkg/protocol"
+)
+
+var (
+	ErrProfileNotFound = errors.New("profile not found")
+	ErrInvalidAvatar   = errors.New("avatar must be an emoji with a #rrggbb color")
+)
+
+// Longest avatar emoji accepted, in bytes; enough for flags and ZWJ sequences
+const maxAvatarEmojiBytes = 32
+
+var avatarColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Profile is a player's name and avatar, kept across rooms
+// Clients refer to it by a token signed with the session secret, so a profile
+// can only be used by whoever registered it
+type Profile struct {
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Avatar   protocol.Avatar `json:"avatar"`
+	LastUsed time.Time       `json:"lastUsed"`
+}
+
+// ToProtocol converts Profile to protocol.Profile
+func (p *Profile) ToProtocol() protocol.Profile {
+	return protocol.Profile{
+		ID:     p.ID,
+		Name:   p.Name,
+		Avatar: p.Avatar,
+	}
+}
+
+// profiles holds registered profiles in memory, for when Redis is unavailable
+type profiles struct {
+	byID map[string]*Profile
+	mu   sync.Mutex
+}
+
+func newProfiles() *profiles {
+	return &profiles{byID: make(map[string]*Profile)}
+}
+
+// prune forgets profiles unused for longer than ttl as of now
+func (p *profiles) prune(ttl time.Duration, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, profile := range p.byID {
+		if now.Sub(profile.LastUsed) > ttl {
+			delete(p.byID, id)
+		}
+	}
+}
+
+// CleanAvatar checks an avatar is a short run of emoji and a hex color,
+// returning it with the color lowercased
+func CleanAvatar(avatar protocol.Avatar) (protocol.Avatar, error) {
+	emoji := strings.TrimSpace(avatar.Emoji)
+	if emoji == "" || len(emoji) > maxAvatarEmojiBytes || !avatarColorPattern.MatchString(avatar.Color) {
+		return protocol.Avatar{}, ErrInvalidAvatar
+	}
+	for _, r := range emoji {
+		// Symbols, plus the modifiers, joiners and selectors that combine them
+		if !unicode.In(r, unicode.So, unicode.Sk, unicode.Mn, unicode.Me) && r != '\u200d' {
+			return protocol.Avatar{}, ErrInvalidAvatar
+		}
+	}
+	return protocol.Avatar{Emoji: emoji, Color: strings.ToLower(avatar.Color)}, nil
+}
+
+// profileToken returns the token that refers to a profile
+func (m *Manager) profileToken(profileID string) string {
+	return profileID + "." + m.tokens.sign("profile", profileID)
+}
+
+// profileID returns the profile a token refers to, if its signature is valid
+func (m *Manager) profileID(token string) (string, bool) {
+	id, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(m.tokens.sign("profile", id))) {
+		return "", false
+	}
+	return id, true
+}
+
+// RegisterProfile creates a profile with a name and avatar, or updates the one
+// token refers to, and returns it with its token
+// The name must already have passed the server's name rules
+func (m *Manager) RegisterProfile(token, name string, avatar protocol.Avatar) (*Profile, string, error) {
+	avatar, err := CleanAvatar(avatar)
+	if err != nil {
+		return nil, "", err
+	}
+
+	id := uuid.New().String()
+	if token != "" {
+		existing, err := m.GetProfile(token)
+		if err != nil {
+			return nil, "", err
+		}
+		id = existing.ID
+	}
+
+	profile := &Profile{
+		ID:       id,
+		Name:     name,
+		Avatar:   avatar,
+		LastUsed: m.clock.Now(),
+	}
+	m.saveProfile(profile)
+
+	return profile, m.profileToken(id), nil
+}
+
+// GetProfile returns the profile a token refers to and marks it as used
+func (m *Manager) GetProfile(token string) (*Profile, error) {
+	id, ok := m.profileID(token)
+	if !ok {
+		return nil, ErrProfileNotFound
+	}
+
+	m.profiles.mu.Lock()
+	profile, exists := m.profiles.byID[id]
+	m.profiles.mu.Unlock()
+
+	if !exists && m.store != nil {
+		var stored Profile
+		found, err := m.store.GetProfile(context.Background(), id, &stored)
+		if err != nil {
+			m.storeHealth.fail("load profile", err)
+		}
+		if found {
+			profile, exists = &stored, true
+		}
+	}
+	if !exists {
+		return nil, ErrProfileNotFound
+	}
+
+	used := *profile
+	used.LastUsed = m.clock.Now()
+	m.saveProfile(&used)
+	return &used, nil
+}
+
+// saveProfile stores a profile in memory and, if available, queues it for Redis
+func (m *Manager) saveProfile(profile *Profile) {
+	m.profiles.mu.Lock()
+	m.profiles.byID[profile.ID] = profile
+	m.profiles.mu.Unlock()
+
+	ttl := m.cfg.Get().ProfileTTL
+	m.persist("save profile", func(ctx context.Context) error {
+		err := m.store.SetProfile(ctx, profile.ID, profile, ttl)
+		if err != nil {
+			slog.Error("failed to save profile", "profileId", profile.ID, "error", err)
+		}
+		return err
+	})
+}
+
+// setPlayerProfile shows a profile's avatar on a player
+func (r *Room) setPlayerProfile(playerID string, profile *Profile) {
+	if profile == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, exists := r.Players[playerID]; exists {
+		avatar := profile.Avatar
+		p.Avatar = &avatar
+		p.ProfileID = profile.ID
+		r.version++
+	}
+}
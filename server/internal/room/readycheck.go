@@ -0,0 +1,164 @@
+package room
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	"slapjack/pkg/protocol"
+)
+
+var ErrNoReadyCheck = errors.New("no ready check is running")
+
+// readyCheck is the players a host-started game is waiting on before its
+// countdown begins
+type readyCheck struct {
+	waiting map[string]bool
+	needed  int
+	done    chan struct{} // Closed once nobody is left waiting
+}
+
+// answer marks a player ready, or no longer needed if they left
+// Caller must hold r.mu
+func (rc *readyCheck) answer(playerID string) bool {
+	if !rc.waiting[playerID] {
+		return false
+	}
+	delete(rc.waiting, playerID)
+	if len(rc.waiting) == 0 {
+		close(rc.done)
+	}
+	return true
+}
+
+// beginReadyCheck starts waiting on every connected player, returning the
+// check and the players it waits on
+func (r *Room) beginReadyCheck() (*readyCheck, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rc := &readyCheck{waiting: make(map[string]bool), done: make(chan struct{})}
+	ids := make([]string, 0, len(r.Players))
+	for id, p := range r.Players {
+		if p.IsConnected {
+			rc.waiting[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	rc.needed = len(ids)
+	if rc.needed == 0 {
+		close(rc.done)
+	}
+	r.readyCheck = rc
+	return rc, ids
+}
+
+// endReadyCheck stops the check, returning the players who never answered
+func (r *Room) endReadyCheck(rc *readyCheck) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.readyCheck == rc {
+		r.readyCheck = nil
+	}
+	notReady := make([]string, 0, len(rc.waiting))
+	for id := range rc.waiting {
+		notReady = append(notReady, id)
+	}
+	sort.Strings(notReady)
+	return notReady
+}
+
+// MarkReady answers the room's ready check for a player, returning how many
+// have answered and how many are needed
+// answered is false if the check wasn't waiting on the player
+func (r *Room) MarkReady(playerID string) (ready, needed int, answered bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rc := r.readyCheck
+	if rc == nil {
+		return 0, 0, false, ErrNoReadyCheck
+	}
+	answered = rc.answer(playerID)
+	return rc.needed - len(rc.waiting), rc.needed, answered, nil
+}
+
+// failReadyCheck returns the room to waiting after the ready check for the
+// start identified by cancel timed out
+// Returns false if that start was already cancelled
+func (r *Room) failReadyCheck(cancel chan struct{}) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.countdownCancel != cancel {
+		return false
+	}
+	close(r.countdownCancel)
+	r.countdownCancel = nil
+	r.Status = "waiting"
+	r.version++
+	return true
+}
+
+// MarkReady answers a room's ready check for a player and tells the room
+func (m *Manager) MarkReady(roomCode, playerID string, broadcast func(string, []byte)) error {
+	room := m.GetRoom(roomCode)
+	if room == nil {
+		return errors.New("room not found")
+	}
+	ready, needed, answered, err := room.MarkReady(playerID)
+	if err != nil || !answered {
+		return err
+	}
+
+	msgData, _ := json.Marshal(protocol.NewMessage(protocol.PlayerReady, protocol.PlayerReadyPayload{
+		PlayerID: playerID,
+		Ready:    ready,
+		Needed:   needed,
+	}))
+	broadcast(roomCode, msgData)
+	return nil
+}
+
+// runReadyCheck asks every connected player to confirm they're ready, and
+// waits until they all have or the check times out
+// Returns false if the start was cancelled or someone didn't answer, in
+// which case the start has already been called off
+func (m *Manager) runReadyCheck(room *Room, cancel chan struct{}, broadcast func(string, []byte)) bool {
+	timeout := time.Duration(room.GetSettings().ReadyCheckSeconds) * time.Second
+	rc, ids := room.beginReadyCheck()
+
+	msgData, _ := json.Marshal(protocol.NewMessage(protocol.ReadyCheck, protocol.ReadyCheckPayload{
+		PlayerIDs:      ids,
+		TimeoutSeconds: int(timeout / time.Second),
+	}))
+	broadcast(room.Code, msgData)
+
+	select {
+	case <-rc.done:
+		room.endReadyCheck(rc)
+		return true
+	case <-cancel:
+		room.endReadyCheck(rc)
+		return false
+	case <-m.clock.After(timeout):
+	}
+
+	notReady := room.endReadyCheck(rc)
+	if !room.failReadyCheck(cancel) {
+		return false
+	}
+
+	slog.Info("game start cancelled", "roomCode", room.Code, "reason", protocol.StartCancelledNotReady, "notReady", len(notReady))
+	msgData, _ = json.Marshal(protocol.NewMessage(protocol.StartCancelled, protocol.StartCancelledPayload{
+		Reason:    protocol.StartCancelledNotReady,
+		PlayerIDs: notReady,
+	}))
+	broadcast(room.Code, msgData)
+	m.NotifyMembershipChanged(room.Code, broadcast)
+	return false
+}
@@ -0,0 +1,142 @@
+package room
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"slapjack/internal/game"
+	"slapjack/pkg/protocol"
+)
+
+const (
+	replayTTL          = 24 * time.Hour
+	maxInMemoryReplays = 50
+)
+
+// replayCache keeps recent replays in memory when Redis is unavailable
+type replayCache struct {
+	logs  map[string]protocol.ReplayLog
+	order []string
+	mu    sync.Mutex
+}
+
+func newReplayCache() *replayCache {
+	return &replayCache{
+		logs: make(map[string]protocol.ReplayLog),
+	}
+}
+
+func (c *replayCache) put(key string, replay protocol.ReplayLog) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.logs[key]; !exists {
+		c.order = append(c.order, key)
+	}
+	c.logs[key] = replay
+
+	// Evict oldest replays
+	for len(c.order) > maxInMemoryReplays {
+		delete(c.logs, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+func (c *replayCache) get(key string) (protocol.ReplayLog, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	replay, ok := c.logs[key]
+	return replay, ok
+}
+
+// findGame looks up a cached replay by game ID alone
+func (c *replayCache) findGame(gameID string) (protocol.ReplayLog, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, replay := range c.logs {
+		if replay.GameID == gameID {
+			return replay, true
+		}
+	}
+	return protocol.ReplayLog{}, false
+}
+
+// SaveReplay persists a game's replay log, with its signed result if it was won
+// It is called once when a game ends, so it also reports the game to the stats worker
+// Returns the signed result, or nil if the game wasn't won
+func (m *Manager) SaveReplay(roomCode string, g *game.Game) *protocol.SignedResult {
+	m.stats.GameFinished(m.clock.Since(g.StartTime))
+
+	replay := protocol.ReplayLog{
+		RoomCode:   roomCode,
+		GameID:     g.ID,
+		Events:     g.GetReplay(),
+		Highlights: g.GetHighlights(),
+	}
+	replay.Result = m.signResult(roomCode, g, replay.Events)
+
+	m.replays.put(roomCode+":"+g.ID, replay)
+
+	m.persist("save replay", func(ctx context.Context) error {
+		if err := m.store.SetHighlights(ctx, g.ID, replay.Highlights, replayTTL); err != nil {
+			slog.Error("failed to save highlights", "roomCode", roomCode, "gameId", g.ID, "error", err)
+		}
+		err := m.store.SetReplay(ctx, roomCode, g.ID, replay, replayTTL)
+		if err != nil {
+			slog.Error("failed to save replay", "roomCode", roomCode, "gameId", g.ID, "error", err)
+		}
+		return err
+	})
+	return replay.Result
+}
+
+// GetReplay returns the replay log for a game, including games still in progress
+func (m *Manager) GetReplay(ctx context.Context, roomCode, gameID string) (protocol.ReplayLog, bool) {
+	if room := m.GetRoom(roomCode); room != nil && room.Game != nil && room.Game.ID == gameID {
+		return protocol.ReplayLog{
+			RoomCode: roomCode,
+			GameID:   gameID,
+			Events:   room.Game.GetReplay(),
+		}, true
+	}
+
+	if replay, ok := m.replays.get(roomCode + ":" + gameID); ok {
+		return replay, true
+	}
+
+	if m.store != nil {
+		var replay protocol.ReplayLog
+		if err := m.store.GetReplay(ctx, roomCode, gameID, &replay); err == nil {
+			return replay, true
+		}
+	}
+
+	return protocol.ReplayLog{}, false
+}
+
+// GetHighlights returns the notable moments of a game, including games still in progress
+func (m *Manager) GetHighlights(ctx context.Context, gameID string) ([]protocol.Highlight, bool) {
+	m.mu.RLock()
+	for _, room := range m.rooms {
+		if room.Game != nil && room.Game.ID == gameID {
+			m.mu.RUnlock()
+			return room.Game.GetHighlights(), true
+		}
+	}
+	m.mu.RUnlock()
+
+	if replay, ok := m.replays.findGame(gameID); ok {
+		return replay.Highlights, true
+	}
+
+	if m.store != nil {
+		var highlights []protocol.Highlight
+		if err := m.store.GetHighlights(ctx, gameID, &highlights); err == nil {
+			return highlights, true
+		}
+	}
+
+	return nil, false
+}
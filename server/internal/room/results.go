@@ -0,0 +1,129 @@
+package room
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"slapjack/internal/game"
+	"slapjack/pkg/protocol"
+)
+
+var (
+	ErrUnknownSigningKey = errors.New("signed with a key this server doesn't use")
+	ErrBadSignature      = errors.New("signature doesn't match the result")
+)
+
+// resultSigner signs the results of won games, so leaderboards and tournament
+// organizers can check them against the published public key
+type resultSigner struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// newResultSigner creates a signer from a base64 Ed25519 seed, or a random key if empty
+func newResultSigner(seed string) *resultSigner {
+	key, err := base64.StdEncoding.DecodeString(seed)
+	if seed == "" || err != nil || len(key) != ed25519.SeedSize {
+		if seed != "" {
+			slog.Error("RESULT_SIGNING_KEY is not a base64 Ed25519 seed, using a random key")
+		} else {
+			slog.Warn("RESULT_SIGNING_KEY not set, game results will not verify after a restart")
+		}
+		key = make([]byte, ed25519.SeedSize)
+		rand.Read(key)
+	}
+	private := ed25519.NewKeyFromSeed(key)
+	sum := sha256.Sum256(private.Public().(ed25519.PublicKey))
+	return &resultSigner{key: private, keyID: hex.EncodeToString(sum[:8])}
+}
+
+// sign encodes and signs a result
+func (s *resultSigner) sign(result protocol.GameResult) (*protocol.SignedResult, error) {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.SignedResult{
+		Payload:   base64.RawURLEncoding.EncodeToString(payload),
+		Signature: base64.RawURLEncoding.EncodeToString(ed25519.Sign(s.key, payload)),
+		KeyID:     s.keyID,
+	}, nil
+}
+
+// verify checks a signed result and decodes it
+func (s *resultSigner) verify(signed protocol.SignedResult) (protocol.GameResult, error) {
+	if signed.KeyID != s.keyID {
+		return protocol.GameResult{}, ErrUnknownSigningKey
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(signed.Payload)
+	if err != nil {
+		return protocol.GameResult{}, ErrBadSignature
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signed.Signature)
+	if err != nil || !ed25519.Verify(s.key.Public().(ed25519.PublicKey), payload, sig) {
+		return protocol.GameResult{}, ErrBadSignature
+	}
+	var result protocol.GameResult
+	if err := json.Unmarshal(payload, &result); err != nil {
+		return protocol.GameResult{}, ErrBadSignature
+	}
+	return result, nil
+}
+
+// VerificationKey returns the public key game results are signed with
+func (m *Manager) VerificationKey() protocol.VerificationKey {
+	return protocol.VerificationKey{
+		Algorithm: "Ed25519",
+		KeyID:     m.results.keyID,
+		PublicKey: base64.RawURLEncoding.EncodeToString(m.results.key.Public().(ed25519.PublicKey)),
+	}
+}
+
+// VerifyResult checks that a result was signed by this server, returning it if so
+func (m *Manager) VerifyResult(signed protocol.SignedResult) (protocol.GameResult, error) {
+	return m.results.verify(signed)
+}
+
+// signResult signs the result of a won game, or returns nil if it wasn't won
+func (m *Manager) signResult(roomCode string, g *game.Game, events []protocol.ReplayEvent) *protocol.SignedResult {
+	if len(events) == 0 || events[len(events)-1].Type != game.ReplayGameOver {
+		return nil
+	}
+	last := events[len(events)-1]
+
+	result := protocol.GameResult{
+		GameID:     g.ID,
+		RoomCode:   roomCode,
+		WinnerID:   last.PlayerID,
+		Players:    make([]protocol.ResultPlayer, 0, len(g.TurnOrder)),
+		StartedAt:  g.StartTime.UnixMilli(),
+		FinishedAt: last.Timestamp,
+		Events:     last.Seq,
+	}
+	room := m.GetRoom(roomCode)
+	for _, id := range g.TurnOrder {
+		player := protocol.ResultPlayer{ID: id, TeamID: g.TeamOf(id)}
+		if room != nil {
+			if p := room.GetPlayer(id); p != nil {
+				player.Name = p.Name
+			}
+		}
+		if id == result.WinnerID {
+			result.WinnerName = player.Name
+		}
+		result.Players = append(result.Players, player)
+	}
+
+	signed, err := m.results.sign(result)
+	if err != nil {
+		slog.Error("failed to sign game result", "roomCode", roomCode, "gameId", g.ID, "error", err)
+		return nil
+	}
+	return signed
+}
@@ -1,21 +1,42 @@
 package room
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"slapjack/internal/clock"
 	"slapjack/internal/game"
+	"slapjack/internal/names"
 	"slapjack/pkg/protocol"
 
 	"github.com/google/uuid"
 )
 
+// Latency changes smaller than this, in milliseconds, aren't re-broadcast
+const latencyReportThreshold = 10
+
 // Player represents a player in a room
 type Player struct {
 	ID          string `json:"id"`
+	Token       string `json:"token"` // Durable identity that survives leaving and rejoining
 	Name        string `json:"name"`
 	IsHost      bool   `json:"isHost"`
 	IsConnected bool   `json:"isConnected"`
 	Position    int    `json:"position"`
+
+	// Set when the player joined with a profile
+	Avatar    *protocol.Avatar `json:"avatar,omitempty"`
+	ProfileID string           `json:"profileId,omitempty"`
+
+	// Equipped cosmetic items by slot
+	Loadout map[string]string `json:"loadout,omitempty"`
+
+	// Team, from 1, when the room plays in teams
+	TeamID int `json:"teamId,omitempty"`
 }
 
 // ToProtocol converts Player to protocol.Player
@@ -27,75 +48,338 @@ func (p *Player) ToProtocol() protocol.Player {
 		IsHost:      p.IsHost,
 		IsConnected: p.IsConnected,
 		Position:    p.Position,
+		Avatar:      p.Avatar,
+		ProfileID:   p.ProfileID,
+		Loadout:     p.Loadout,
+		TeamID:      p.TeamID,
 	}
 }
 
 // Room represents a game room
 type Room struct {
-	Code     string            `json:"code"`
+	Code     string             `json:"code"`
 	Players  map[string]*Player `json:"players"`
-	Settings Settings          `json:"settings"`
-	Status   string            `json:"status"` // waiting, starting, playing, finished
-	HostID   string            `json:"hostId"`
-	Game     *game.Game        `json:"-"`
+	Settings Settings           `json:"settings"`
+	Status   string             `json:"status"` // waiting, starting, playing, finished
+	HostID   string             `json:"hostId"`
+	Game     *game.Game         `json:"-"`
+
+	// Rule preset of a drop-in room the server keeps open; such rooms have no host
+	DropIn string `json:"dropIn,omitempty"`
+
+	// Rule variant the room was created with, if any
+	Variant string `json:"variant,omitempty"`
+
+	// Discord webhook the host registered for notifications; never sent to clients
+	Webhook string `json:"webhook,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+
+	// When a scheduled game starts by itself; zero if none is scheduled
+	ScheduledAt time.Time `json:"scheduledAt"`
+
+	// When a client last sent a message for this room
+	LastActivity time.Time `json:"lastActivity"`
+
+	// Spectators watching the room, by session ID
+	Spectators map[string]bool `json:"-"`
+
+	// Player IDs of departed players by token, so rejoining restores the same identity
+	departed map[string]string
+
+	// When each banned player token or profile may rejoin, or zero for never
+	Bans map[string]time.Time `json:"bans,omitempty"`
+
+	// Players who have voted for a rematch since the last game ended
+	rematchVotes map[string]bool
+
+	// Who the current game started with, in seat order, so the match history
+	// still lists players who leave before it ends
+	roster []rosterEntry
+
+	// Sequence number of the last broadcast to the room, and the lock held
+	// while one is numbered and queued so clients receive them in order
+	eventSeq    atomic.Int64
+	broadcastMu sync.Mutex
+
+	// Recent broadcasts, replayed to players who reconnect
+	backlog backlog
+
+	// Measured round-trip latency by player ID, in milliseconds
+	latency map[string]int64
+
+	// Calibrated device input lag by player ID, in milliseconds
+	inputLag map[string]int64
+
+	// When each player or spectator session last reacted, for slow mode
+	lastReaction map[string]time.Time
+
+	// Closed to cancel the countdown to a game start; nil when not counting down
+	countdownCancel chan struct{}
+
+	// When the room last moved to starting, for the stuck room watchdog
+	startingSince time.Time
+
+	// Times the room's activity and starts; shared with its manager
+	clock clock.Clock
+
+	// The concurrent piles of a party room, until its finale; nil otherwise
+	party *party
+
+	// The players a host-started game is waiting on; nil when not checking
+	readyCheck *readyCheck
+
+	// The running kick vote, if any, when each player last started one and
+	// when each player last survived one
+	kickVote     *kickVote
+	voteStarted  map[string]time.Time
+	voteSurvived map[string]time.Time
+	votedOut     string // Player a passed vote is removing
+
+	// Bumped whenever anything shown in ROOM_UPDATED changes, so its last
+	// encoding can be reused until then
+	version uint64
+	updated protocol.PayloadCache
+
+	// When a drop-in room got enough players to start, or finished its game
+	dropInSince time.Time
+
+	// Index of the next scheduleReminders entry to send
+	nextReminder int
+
+	// Recent joins, kicks, setting changes and game starts, for moderation
+	audit auditLog
 
 	mu sync.RWMutex
 }
 
-// NewRoom creates a new room with the given code and host
-func NewRoom(code, hostName string) (*Room, string) {
+// NewRoom creates a new room with the given code and host, timed by clk, or
+// the system clock if nil
+func NewRoom(code, hostName, hostToken string, clk clock.Clock) (*Room, string) {
 	playerID := uuid.New().String()
 
-	host := &Player{
+	room := newRoom(code, clk)
+	room.Players[playerID] = &Player{
 		ID:          playerID,
+		Token:       hostToken,
 		Name:        hostName,
 		IsHost:      true,
 		IsConnected: true,
 		Position:    0,
 	}
+	room.HostID = playerID
+	room.recordAudit(protocol.AuditCreate, playerID, "", "")
+	return room, playerID
+}
 
+// newRoom creates an empty waiting room with default settings, timed by clk,
+// or the system clock if nil
+func newRoom(code string, clk clock.Clock) *Room {
+	clk = clock.OrReal(clk)
 	return &Room{
-		Code:     code,
-		Players:  map[string]*Player{playerID: host},
-		Settings: DefaultSettings(),
-		Status:   "waiting",
-		HostID:   playerID,
-	}, playerID
+		Code:         code,
+		Players:      make(map[string]*Player),
+		Settings:     DefaultSettings(),
+		Status:       "waiting",
+		departed:     make(map[string]string),
+		latency:      make(map[string]int64),
+		inputLag:     make(map[string]int64),
+		CreatedAt:    clk.Now(),
+		LastActivity: clk.Now(),
+		Spectators:   make(map[string]bool),
+		clock:        clk,
+	}
+}
+
+// NextSeq assigns the sequence number for the next broadcast to the room
+func (r *Room) NextSeq() int64 {
+	return r.eventSeq.Add(1)
+}
+
+// LockBroadcasts holds back other broadcasts to the room while one is
+// numbered and queued to its clients
+func (r *Room) LockBroadcasts() {
+	r.broadcastMu.Lock()
+}
+
+// UnlockBroadcasts releases LockBroadcasts
+func (r *Room) UnlockBroadcasts() {
+	r.broadcastMu.Unlock()
+}
+
+// Seq returns the sequence number of the last broadcast to the room
+func (r *Room) Seq() int64 {
+	return r.eventSeq.Load()
+}
+
+// Touch records activity in the room
+func (r *Room) Touch() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.LastActivity = r.clock.Now()
+}
+
+// IdleFor returns how long it has been since the last activity in the room
+func (r *Room) IdleFor() time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.clock.Since(r.LastActivity)
 }
 
 // AddPlayer adds a new player to the room
-func (r *Room) AddPlayer(name string) (*Player, error) {
+// A player token that is already seated or previously left re-associates the same player ID
+func (r *Room) AddPlayer(name, token string) (*Player, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.version++
+
+	if token != "" {
+		for _, p := range r.Players {
+			if p.Token == token {
+				name, err := r.uniqueName(name, p.ID)
+				if err != nil {
+					return nil, err
+				}
+				p.Name = name
+				p.IsConnected = true
+				return p, nil
+			}
+		}
+	}
+
+	name, err := r.uniqueName(name, "")
+	if err != nil {
+		return nil, err
+	}
 
 	playerID := uuid.New().String()
+	if previousID, ok := r.departed[token]; ok && token != "" {
+		playerID = previousID
+		delete(r.departed, token)
+	}
 	position := len(r.Players)
 
 	player := &Player{
 		ID:          playerID,
+		Token:       token,
 		Name:        name,
 		IsHost:      false,
 		IsConnected: true,
 		Position:    position,
 	}
+	if r.Settings.Teams {
+		player.TeamID = r.smallestTeam()
+	}
 
 	r.Players[playerID] = player
+	r.recordAudit(protocol.AuditJoin, playerID, "", "")
 	return player, nil
 }
 
-// RemovePlayer removes a player from the room
-func (r *Room) RemovePlayer(playerID string) {
+// RenamePlayer changes a player's name, applying the room's duplicate name setting
+// Returns the name the player ended up with
+func (r *Room) RenamePlayer(playerID, name string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+
+	player, ok := r.Players[playerID]
+	if !ok {
+		return "", errors.New("player not found")
+	}
+	name, err := r.uniqueName(name, playerID)
+	if err != nil {
+		return "", err
+	}
+	if name != player.Name {
+		r.recordAudit(protocol.AuditRename, playerID, "", "was "+player.Name)
+	}
+	player.Name = name
+	return name, nil
+}
+
+// ErrNameTaken is returned when another player in the room already has the
+// name and the room doesn't number duplicates
+var ErrNameTaken = errors.New("someone in this room already has that name")
+
+// uniqueName resolves a clash with another player's name (ignoring case) according
+// to the room's duplicate name setting, numbering it from 2 when suffixing
+// Caller must hold r.mu
+func (r *Room) uniqueName(name, selfID string) (string, error) {
+	if !r.nameTaken(name, selfID) {
+		return name, nil
+	}
+	if r.Settings.DuplicateNames == DuplicateNamesReject {
+		return "", ErrNameTaken
+	}
+
+	base := []rune(name)
+	for n := 2; ; n++ {
+		suffix := fmt.Sprintf("-%d", n)
+		if keep := names.MaxLength - len(suffix); len(base) > keep {
+			base = base[:keep]
+		}
+		candidate := strings.TrimSpace(string(base)) + suffix
+		if !r.nameTaken(candidate, selfID) {
+			return candidate, nil
+		}
+	}
+}
+
+// nameTaken reports whether a player other than selfID has the name, ignoring case
+// Caller must hold r.mu
+func (r *Room) nameTaken(name, selfID string) bool {
+	for id, p := range r.Players {
+		if id != selfID && strings.EqualFold(p.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemovePlayer removes a player from the room, and from its game if one is
+// being played, recording in the audit log that they left or, if kicked, that
+// the host kicked them
+// Returns the new host's ID if the host was reassigned, and whether the game's
+// turn moved on because it was theirs
+func (r *Room) RemovePlayer(playerID string, kicked bool) (string, bool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.version++
+
+	if _, ok := r.Players[playerID]; ok {
+		if kicked && r.votedOut == playerID {
+			r.recordAudit(protocol.AuditKick, "", playerID, "voted out")
+			r.votedOut = ""
+		} else if kicked {
+			r.recordAudit(protocol.AuditKick, r.HostID, playerID, "")
+		} else {
+			r.recordAudit(protocol.AuditLeave, playerID, "", "")
+		}
+	}
 
+	if p, ok := r.Players[playerID]; ok && p.Token != "" {
+		r.departed[p.Token] = playerID
+	}
 	delete(r.Players, playerID)
+	r.forgetAck(playerID)
+	turnMoved := false
+	if g, _ := r.gameFor(playerID); g != nil && r.Status == "playing" {
+		turnMoved = g.RemovePlayer(playerID)
+	}
+	if r.readyCheck != nil {
+		r.readyCheck.answer(playerID)
+	}
 
 	// If host left, assign new host
+	newHostID := ""
 	if r.HostID == playerID && len(r.Players) > 0 {
 		for id, p := range r.Players {
 			if p.IsConnected {
 				r.HostID = id
 				p.IsHost = true
+				newHostID = id
+				r.recordAudit(protocol.AuditHostTransfer, "", id, "host left")
 				break
 			}
 		}
@@ -107,6 +391,28 @@ func (r *Room) RemovePlayer(playerID string) {
 		p.Position = pos
 		pos++
 	}
+
+	return newHostID, turnMoved
+}
+
+// TransferHost hands host powers to another seated player
+// Returns false if the player is not in the room
+func (r *Room) TransferHost(playerID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+
+	newHost, ok := r.Players[playerID]
+	if !ok {
+		return false
+	}
+	if oldHost, ok := r.Players[r.HostID]; ok {
+		oldHost.IsHost = false
+	}
+	r.recordAudit(protocol.AuditHostTransfer, r.HostID, playerID, "")
+	newHost.IsHost = true
+	r.HostID = playerID
+	return true
 }
 
 // GetPlayer returns a player by ID
@@ -116,10 +422,27 @@ func (r *Room) GetPlayer(playerID string) *Player {
 	return r.Players[playerID]
 }
 
+// GetPlayerByToken returns the seated player with the given token
+func (r *Room) GetPlayerByToken(token string) *Player {
+	if token == "" {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range r.Players {
+		if p.Token == token {
+			return p
+		}
+	}
+	return nil
+}
+
 // MarkPlayerDisconnected marks a player as disconnected
 func (r *Room) MarkPlayerDisconnected(playerID string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.version++
 
 	if p, ok := r.Players[playerID]; ok {
 		p.IsConnected = false
@@ -130,6 +453,7 @@ func (r *Room) MarkPlayerDisconnected(playerID string) {
 func (r *Room) MarkPlayerConnected(playerID string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.version++
 
 	if p, ok := r.Players[playerID]; ok {
 		p.IsConnected = true
@@ -162,6 +486,45 @@ func (r *Room) GetAllPlayers() []*Player {
 	return players
 }
 
+// AddSpectator adds a watching session to the room, unless it is private and
+// the session wasn't invited, or already has as many spectators as it allows
+func (r *Room) AddSpectator(sessionID string, invited bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Settings.Visibility == protocol.VisibilityPrivate && !invited {
+		return ErrInviteOnly
+	}
+	if limit := r.Settings.MaxSpectators; limit > 0 && len(r.Spectators) >= limit && !r.Spectators[sessionID] {
+		return ErrSpectatorsFull
+	}
+	r.version++
+	r.Spectators[sessionID] = true
+	return nil
+}
+
+// RemoveSpectator removes a watching session from the room
+func (r *Room) RemoveSpectator(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+	delete(r.Spectators, sessionID)
+}
+
+// SpectatorCount returns the number of sessions watching the room
+func (r *Room) SpectatorCount() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.Spectators)
+}
+
+// GetSettings returns a copy of the room's settings
+func (r *Room) GetSettings() Settings {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.Settings
+}
+
 // IsFull returns true if the room is at capacity
 func (r *Room) IsFull() bool {
 	r.mu.RLock()
@@ -186,7 +549,53 @@ func (r *Room) IsEmpty() bool {
 func (r *Room) UpdateSettings(payload protocol.UpdateSettingsPayload) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.version++
+	before := r.Settings.ToProtocol()
 	r.Settings.FromProtocol(payload)
+	r.Settings.clampPlayers(r.variantMaxPlayers())
+	if r.Settings.Teams != before.Teams {
+		r.assignTeams()
+	}
+	if changed := changedSettings(before, r.Settings.ToProtocol()); changed != "" {
+		r.recordAudit(protocol.AuditSettings, r.HostID, "", changed)
+	}
+}
+
+// GameStateFor returns the current state of one of the room's games for a
+// client, with the full pile for spectators or when the room shows it to
+// everyone
+func (r *Room) GameStateFor(g *game.Game, spectating bool) protocol.GameStatePayload {
+	r.mu.RLock()
+	showFullPile := r.Settings.ShowFullPile
+	r.mu.RUnlock()
+
+	if spectating || showFullPile {
+		return g.GetObserverState()
+	}
+	return g.GetState()
+}
+
+// UpdatedMessage builds a ROOM_UPDATED message with the room's current state,
+// reusing the last encoding if nothing in it has changed since
+func (r *Room) UpdatedMessage() []byte {
+	return r.updated.Message(protocol.RoomUpdated, r.stateKey(), func() interface{} {
+		return protocol.RoomJoinedPayload{Room: r.ToProtocol()}
+	})
+}
+
+// stateKey identifies the room state shown in ROOM_UPDATED. Status and host are
+// included directly as handlers also set them, and the game's event count
+// stands in for its card counts.
+func (r *Room) stateKey() string {
+	r.mu.RLock()
+	key := fmt.Sprintf("%d:%s:%s", r.version, r.Status, r.HostID)
+	g := r.Game
+	r.mu.RUnlock()
+
+	if g != nil {
+		key += fmt.Sprintf(":%s:%d", g.ID, g.EventCount())
+	}
+	return key
 }
 
 // ToProtocol converts Room to protocol.RoomState
@@ -195,27 +604,117 @@ func (r *Room) ToProtocol() protocol.RoomState {
 	defer r.mu.RUnlock()
 
 	players := make([]protocol.Player, 0, len(r.Players))
+	var latency map[string]int64
 	for _, p := range r.Players {
 		player := p.ToProtocol()
 		if r.Game != nil {
 			player.CardCount = r.Game.GetPlayerCardCount(p.ID)
+		} else if r.party != nil {
+			if pile, ok := r.party.pileOf[p.ID]; ok {
+				player.CardCount = r.party.games[pile-1].GetPlayerCardCount(p.ID)
+			}
 		}
 		players = append(players, player)
+
+		if ms, ok := r.latency[p.ID]; ok {
+			if latency == nil {
+				latency = make(map[string]int64)
+			}
+			latency[p.ID] = ms
+		}
 	}
 
 	return protocol.RoomState{
-		Code:     r.Code,
-		Players:  players,
-		Settings: r.Settings.ToProtocol(),
-		Status:   r.Status,
-		HostID:   r.HostID,
+		Code:           r.Code,
+		Players:        players,
+		Settings:       r.Settings.ToProtocol(),
+		Status:         r.Status,
+		HostID:         r.HostID,
+		DropIn:         r.DropIn,
+		VariantID:      r.Variant,
+		SpectatorCount: len(r.Spectators),
+		PlayerLatency:  latency,
+		Piles:          r.partyPiles(),
+		Integrations:   r.integrations(),
+		ScheduledAt:    r.scheduledAtMillis(),
+	}
+}
+
+// Snapshot returns a deep copy of the room's stored fields, which can be
+// marshaled while handlers go on changing the room
+func (r *Room) Snapshot() *Room {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snap := &Room{
+		Code:         r.Code,
+		Players:      make(map[string]*Player, len(r.Players)),
+		Settings:     r.Settings,
+		Status:       r.Status,
+		HostID:       r.HostID,
+		DropIn:       r.DropIn,
+		Variant:      r.Variant,
+		Webhook:      r.Webhook,
+		CreatedAt:    r.CreatedAt,
+		ScheduledAt:  r.ScheduledAt,
+		LastActivity: r.LastActivity,
+		clock:        r.clock,
+	}
+	for id, p := range r.Players {
+		player := *p
+		if p.Avatar != nil {
+			avatar := *p.Avatar
+			player.Avatar = &avatar
+		}
+		if p.Loadout != nil {
+			player.Loadout = make(map[string]string, len(p.Loadout))
+			for slot, item := range p.Loadout {
+				player.Loadout[slot] = item
+			}
+		}
+		snap.Players[id] = &player
+	}
+	if r.Bans != nil {
+		snap.Bans = make(map[string]time.Time, len(r.Bans))
+		for who, until := range r.Bans {
+			snap.Bans[who] = until
+		}
+	}
+	return snap
+}
+
+// SetLatency records a player's round-trip latency and passes it to the game
+// for slap timing. Returns true if it changed enough to be worth showing.
+func (r *Room) SetLatency(playerID string, ms int64) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, seated := r.Players[playerID]; !seated {
+		return false
 	}
+	if r.Game != nil {
+		r.Game.SetLatency(playerID, time.Duration(ms)*time.Millisecond)
+	}
+
+	prev, known := r.latency[playerID]
+	diff := ms - prev
+	if diff < 0 {
+		diff = -diff
+	}
+	if known && diff < latencyReportThreshold {
+		return false
+	}
+	r.latency[playerID] = ms
+	r.version++
+	return true
 }
 
-// StartGame initializes the game
-func (r *Room) StartGame() {
+// StartGame initializes the game, timed by clk, or a party room's piles when
+// there are more players than one pile is played with
+func (r *Room) StartGame(clk clock.Clock) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.version++
 
 	playerIDs := make([]string, 0, len(r.Players))
 	for _, p := range r.Players {
@@ -224,6 +723,64 @@ func (r *Room) StartGame() {
 		}
 	}
 
-	r.Game = game.NewGame(playerIDs, r.Settings.EnableDoubles, r.Settings.EnableSandwich, r.Settings.BurnPenalty, r.Settings.SlapCooldownMs, r.Settings.TurnTimeoutMs, r.Settings.EnableSlapIn, r.Settings.MaxSlapIns)
 	r.Status = "playing"
+	r.ScheduledAt = time.Time{}
+	r.rematchVotes = nil
+	r.recordRoster(playerIDs)
+	r.recordAudit(protocol.AuditGameStart, r.HostID, "", fmt.Sprintf("%d players", len(playerIDs)))
+
+	opts := r.Settings.GameOptions()
+	opts.Clock = clk
+	if len(playerIDs) > maxTablePlayers {
+		r.startParty(playerIDs, opts)
+		return
+	}
+	opts.Teams = r.gameTeams(playerIDs)
+	r.party = nil
+	r.Game = game.NewGame(playerIDs, opts)
+	for id, ms := range r.latency {
+		r.Game.SetLatency(id, time.Duration(ms)*time.Millisecond)
+	}
+	for id, ms := range r.inputLag {
+		r.Game.SetInputLag(id, time.Duration(ms)*time.Millisecond)
+	}
+}
+
+// VoteRematch records a player's rematch vote after the game is over
+// Returns the tally and whether the vote reached the quorum, in which case the
+// room moves to starting so the rematch is only started once
+func (r *Room) VoteRematch(playerID string) (votes, needed int, ready bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.rematchVotes == nil {
+		r.rematchVotes = make(map[string]bool)
+	}
+	r.rematchVotes[playerID] = true
+
+	// Only count votes from players still connected
+	connected := 0
+	for id, p := range r.Players {
+		if !p.IsConnected {
+			continue
+		}
+		connected++
+		if r.rematchVotes[id] {
+			votes++
+		}
+	}
+
+	needed = connected
+	if r.Settings.RematchQuorum == RematchMajority {
+		needed = connected/2 + 1
+	}
+
+	if votes >= needed && connected >= 2 && r.Status == "finished" {
+		r.Status = "starting"
+		r.startingSince = r.clock.Now()
+		r.rematchVotes = nil
+		r.version++
+		return votes, needed, true
+	}
+	return votes, needed, false
 }
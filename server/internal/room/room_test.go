@@ -0,0 +1,1205 @@
+package room
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"slapjack/internal/clock"
+	"slapjack/internal/config"
+	"slapjack/pkg/protocol"
+)
+
+func TestAddPlayerDuplicateNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		existing []string // Joined after the host, Alex
+		join     string
+		want     string
+		wantErr  error
+	}{
+		{"unique name", DuplicateNamesSuffix, nil, "Sam", "Sam", nil},
+		{"suffixed", DuplicateNamesSuffix, nil, "Alex", "Alex-2", nil},
+		{"suffix ignores case", DuplicateNamesSuffix, nil, "alex", "alex-2", nil},
+		{"next free suffix", DuplicateNamesSuffix, []string{"Alex"}, "Alex", "Alex-3", nil},
+		{"suffix skips a taken number", DuplicateNamesSuffix, []string{"Alex-2"}, "Alex", "Alex-3", nil},
+		{"suffix keeps the length limit", DuplicateNamesSuffix, []string{"Abcdefghijklmnopqrst"}, "abcdefghijklmnopqrst", "abcdefghijklmnopqr-2", nil},
+		{"rejected", DuplicateNamesReject, nil, "Alex", "", ErrNameTaken},
+		{"reject ignores case", DuplicateNamesReject, nil, "ALEX", "", ErrNameTaken},
+		{"reject allows unique names", DuplicateNamesReject, nil, "Sam", "Sam", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := NewRoom("ABCD", "Alex", "host-token", nil)
+			r.Settings.DuplicateNames = tt.mode
+			for _, name := range tt.existing {
+				if _, err := r.AddPlayer(name, ""); err != nil {
+					t.Fatalf("adding %q: %v", name, err)
+				}
+			}
+
+			player, err := r.AddPlayer(tt.join, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AddPlayer(%q) error = %v, want %v", tt.join, err, tt.wantErr)
+			}
+			if err != nil {
+				if got := len(r.Players); got != len(tt.existing)+1 {
+					t.Errorf("room has %d players after a rejected join, want %d", got, len(tt.existing)+1)
+				}
+				return
+			}
+			if player.Name != tt.want {
+				t.Errorf("AddPlayer(%q) named the player %q, want %q", tt.join, player.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddPlayerRejoinKeepsName(t *testing.T) {
+	r, _ := NewRoom("ABCD", "Alex", "host-token", nil)
+	r.Settings.DuplicateNames = DuplicateNamesReject
+
+	sam, err := r.AddPlayer("Sam", "sam-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rejoining under your own name is not a clash
+	again, err := r.AddPlayer("Sam", "sam-token")
+	if err != nil {
+		t.Fatalf("rejoin: %v", err)
+	}
+	if again.ID != sam.ID || again.Name != "Sam" {
+		t.Errorf("rejoin gave %s %q, want %s %q", again.ID, again.Name, sam.ID, "Sam")
+	}
+
+	if _, err := r.AddPlayer("Alex", "sam-token"); !errors.Is(err, ErrNameTaken) {
+		t.Errorf("rejoin as the host's name: error = %v, want ErrNameTaken", err)
+	}
+}
+
+func TestRenamePlayerDuplicateNames(t *testing.T) {
+	r, _ := NewRoom("ABCD", "Alex", "host-token", nil)
+	sam, _ := r.AddPlayer("Sam", "")
+
+	name, err := r.RenamePlayer(sam.ID, "Alex")
+	if err != nil || name != "Alex-2" {
+		t.Errorf("rename with suffixing = %q, %v, want Alex-2", name, err)
+	}
+
+	// Renaming to your own name, in any case, isn't a clash
+	name, err = r.RenamePlayer(sam.ID, "alex-2")
+	if err != nil || name != "alex-2" {
+		t.Errorf("rename to own name = %q, %v, want alex-2", name, err)
+	}
+
+	r.Settings.DuplicateNames = DuplicateNamesReject
+	if _, err := r.RenamePlayer(sam.ID, "alex"); !errors.Is(err, ErrNameTaken) {
+		t.Errorf("rename with rejection: error = %v, want ErrNameTaken", err)
+	}
+	if got := r.GetPlayer(sam.ID).Name; got != "alex-2" {
+		t.Errorf("rejected rename changed the name to %q", got)
+	}
+}
+
+func TestSettingsDuplicateNames(t *testing.T) {
+	s := DefaultSettings()
+	if s.DuplicateNames != DuplicateNamesSuffix {
+		t.Errorf("default duplicate names = %q, want %q", s.DuplicateNames, DuplicateNamesSuffix)
+	}
+
+	payload := s.ToProtocol()
+	payload.DuplicateNames = DuplicateNamesReject
+	s.FromProtocol(protocol.UpdateSettingsPayload(payload))
+	if s.DuplicateNames != DuplicateNamesReject {
+		t.Errorf("after update = %q, want %q", s.DuplicateNames, DuplicateNamesReject)
+	}
+
+	s.DuplicateNames = "bogus"
+	s.Validate()
+	if s.DuplicateNames != DuplicateNamesSuffix {
+		t.Errorf("unknown mode validated to %q, want %q", s.DuplicateNames, DuplicateNamesSuffix)
+	}
+}
+
+func TestClassroomNameViolations(t *testing.T) {
+	r, _ := NewRoom("ABCD", "Alex", "host-token", nil)
+	for _, name := range []string{"Sam_99", "Dickens", "Jo!"} {
+		if _, err := r.AddPlayer(name, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := r.ClassroomNameViolations(), []string{"Sam_99", "Jo!"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ClassroomNameViolations() = %v, want %v", got, want)
+	}
+}
+
+func TestSettingsDeck(t *testing.T) {
+	tests := []struct {
+		name    string
+		deck    protocol.DeckSpec
+		wantErr error
+	}{
+		{"standard", protocol.DeckSpec{}, nil},
+		{"no low cards", protocol.DeckSpec{Ranks: []string{"A", "7", "8", "9", "10", "J", "Q", "K"}, ExtraJacks: 2}, nil},
+		{"unknown rank", protocol.DeckSpec{Ranks: []string{"J", "Q", "K", "A", "Z"}}, ErrUnknownRank},
+		{"no jacks", protocol.DeckSpec{Ranks: []string{"10", "Q", "K", "A"}}, ErrDeckNoJacks},
+		{"too few ranks", protocol.DeckSpec{Ranks: []string{"J", "Q", "K"}}, ErrDeckTooSmall},
+		{"too many jacks", protocol.DeckSpec{ExtraJacks: 9}, ErrTooManyJacks},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := DefaultSettings()
+			payload := s.ToProtocol()
+			payload.Deck = tt.deck
+			if _, err := DeckFromProtocol(tt.deck); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeckFromProtocol() error = %v, want %v", err, tt.wantErr)
+			}
+			s.FromProtocol(protocol.UpdateSettingsPayload(payload))
+
+			got := s.ToProtocol().Deck
+			want := tt.deck
+			if tt.wantErr != nil {
+				want = protocol.DeckSpec{}
+			}
+			if len(want.Ranks) == 0 {
+				want.Ranks = s.Deck.Ranks.Ranks()
+			}
+			if strings.Join(got.Ranks, ",") != strings.Join(want.Ranks, ",") || got.ExtraJacks != want.ExtraJacks {
+				t.Errorf("settings deck = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestKickVote(t *testing.T) {
+	r, hostID := NewRoom("ABCD", "Alex", "host-token", nil)
+	r.Settings.MaxPlayers = 8
+	var ids []string
+	for _, name := range []string{"Sam", "Kim", "Lee", "Ash"} {
+		p, err := r.AddPlayer(name, name+"-token")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, p.ID)
+	}
+	sam, kim, lee, ash := ids[0], ids[1], ids[2], ids[3]
+	now := time.Now()
+
+	if _, _, _, err := r.voteKick(sam, hostID, true, now); !errors.Is(err, ErrVoteHost) {
+		t.Errorf("vote against the host: error = %v, want ErrVoteHost", err)
+	}
+	if _, _, _, err := r.voteKick(hostID, ash, true, now); !errors.Is(err, ErrNotVoter) {
+		t.Errorf("vote started by the host: error = %v, want ErrNotVoter", err)
+	}
+	if _, _, _, err := r.voteKick(sam, ash, false, now); !errors.Is(err, ErrNoKickVote) {
+		t.Errorf("no vote with nothing running: error = %v, want ErrNoKickVote", err)
+	}
+
+	// Kim, Lee and Sam vote on Ash; two of three are needed
+	v, started, _, err := r.voteKick(sam, ash, true, now)
+	if err != nil || !started || v.needed != 2 {
+		t.Fatalf("starting a vote = %v, %v, needed %d; want started with 2 needed", started, err, v.needed)
+	}
+	if _, _, _, err := r.voteKick(kim, lee, true, now); !errors.Is(err, ErrKickVoteActive) {
+		t.Errorf("second vote: error = %v, want ErrKickVoteActive", err)
+	}
+	if _, _, _, err := r.voteKick(ash, ash, false, now); !errors.Is(err, ErrNotVoter) {
+		t.Errorf("target voting: error = %v, want ErrNotVoter", err)
+	}
+	if _, _, result, _ := r.voteKick(kim, ash, false, now); result != nil {
+		t.Fatalf("vote decided early: %+v", result)
+	}
+	_, _, result, err := r.voteKick(lee, ash, true, now)
+	if err != nil || result == nil || result.Result != protocol.VoteResultPassed || result.Yes != 2 || result.No != 1 {
+		t.Fatalf("deciding vote = %+v, %v; want passed 2 to 1", result, err)
+	}
+	if !r.IsBanned("Ash-token", "", now) {
+		t.Error("voted out player isn't kept out")
+	}
+
+	// Starting again straight away is spam; a failed vote protects its target
+	if _, _, _, err := r.voteKick(sam, kim, true, now); !errors.Is(err, ErrVoteCooldown) {
+		t.Errorf("second vote by the same player: error = %v, want ErrVoteCooldown", err)
+	}
+	v, _, _, _ = r.voteKick(kim, lee, true, now)
+	if result := r.expireKickVote(v, now.Add(kickVoteTimeout)); result == nil || result.Result != protocol.VoteResultExpired {
+		t.Fatalf("expired vote = %+v, want expired", result)
+	}
+	later := now.Add(kickVoteCooldown)
+	if _, _, _, err := r.voteKick(sam, lee, true, later); !errors.Is(err, ErrVoteImmune) {
+		t.Errorf("vote against a survivor: error = %v, want ErrVoteImmune", err)
+	}
+}
+
+func TestCleanAvatar(t *testing.T) {
+	tests := []struct {
+		name    string
+		avatar  protocol.Avatar
+		want    protocol.Avatar
+		wantErr bool
+	}{
+		{"emoji", protocol.Avatar{Emoji: "🐸", Color: "#00AA55"}, protocol.Avatar{Emoji: "🐸", Color: "#00aa55"}, false},
+		{"zwj sequence", protocol.Avatar{Emoji: "🧑‍🚀", Color: "#112233"}, protocol.Avatar{Emoji: "🧑‍🚀", Color: "#112233"}, false},
+		{"skin tone", protocol.Avatar{Emoji: "👋🏽", Color: "#112233"}, protocol.Avatar{Emoji: "👋🏽", Color: "#112233"}, false},
+		{"letters", protocol.Avatar{Emoji: "hi", Color: "#112233"}, protocol.Avatar{}, true},
+		{"empty emoji", protocol.Avatar{Emoji: " ", Color: "#112233"}, protocol.Avatar{}, true},
+		{"named color", protocol.Avatar{Emoji: "🐸", Color: "green"}, protocol.Avatar{}, true},
+		{"too long", protocol.Avatar{Emoji: strings.Repeat("🐸", 9), Color: "#112233"}, protocol.Avatar{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CleanAvatar(tt.avatar)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CleanAvatar() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CleanAvatar() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	m := &Manager{
+		cfg:      config.Static(config.Default()),
+		tokens:   newSessionTokens("secret"),
+		profiles: newProfiles(),
+		clock:    clock.NewMock(time.Unix(0, 0)),
+	}
+	avatar := protocol.Avatar{Emoji: "🐸", Color: "#00aa55"}
+
+	profile, token, err := m.RegisterProfile("", "Alex", avatar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.GetProfile(token)
+	if err != nil || got.ID != profile.ID || got.Name != "Alex" {
+		t.Fatalf("GetProfile() = %+v, %v, want profile %s", got, err, profile.ID)
+	}
+
+	// Registering with the token updates the same profile
+	updated, updatedToken, err := m.RegisterProfile(token, "Alexa", avatar)
+	if err != nil || updated.ID != profile.ID || updatedToken != token {
+		t.Fatalf("RegisterProfile(token) = %+v, %q, %v, want profile %s", updated, updatedToken, err, profile.ID)
+	}
+
+	forged := profile.ID + ".not-the-signature"
+	if _, err := m.GetProfile(forged); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("GetProfile(forged) error = %v, want ErrProfileNotFound", err)
+	}
+	if _, _, err := m.RegisterProfile(forged, "Mallory", avatar); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("RegisterProfile(forged) error = %v, want ErrProfileNotFound", err)
+	}
+
+	// Joining with the profile shows its avatar
+	r, hostID := NewRoom("ABCD", "Sam", "", nil)
+	r.setPlayerProfile(hostID, updated)
+	if p := r.GetPlayer(hostID).ToProtocol(); p.Avatar == nil || *p.Avatar != avatar || p.ProfileID != profile.ID {
+		t.Errorf("player = %+v, want avatar %+v and profile %s", p, avatar, profile.ID)
+	}
+}
+
+func TestWallet(t *testing.T) {
+	m := &Manager{
+		cfg:     config.Static(config.Default()),
+		wallets: newWallets(),
+	}
+
+	if _, err := m.BuyItem("alex-token", "card_back_midnight"); !errors.Is(err, ErrNotEnoughCoins) {
+		t.Fatalf("BuyItem() with no coins error = %v, want ErrNotEnoughCoins", err)
+	}
+	if _, err := m.EquipItem("alex-token", "table_ocean"); !errors.Is(err, ErrItemNotOwned) {
+		t.Errorf("EquipItem(unowned) error = %v, want ErrItemNotOwned", err)
+	}
+	if _, err := m.EquipItem("alex-token", "table_felt"); err != nil {
+		t.Errorf("EquipItem(free item) error = %v", err)
+	}
+
+	r, alexID := NewRoom("ABCD", "Alex", "alex-token", nil)
+	sam, _ := r.AddPlayer("Sam", "sam-token")
+	awards := []protocol.Award{{Key: "fastest_hand", PlayerID: alexID}}
+	for i := 0; i < 3; i++ {
+		m.PayOut(r, alexID, awards)
+	}
+	paid := m.PayOut(r, alexID, awards)
+	if got := paid[alexID].Coins; got != 4*(coinsPlayed+coinsWon+coinsPerAward) {
+		t.Errorf("winner has %d coins, want %d", got, 4*(coinsPlayed+coinsWon+coinsPerAward))
+	}
+	if got := paid[sam.ID]; got.Coins != 4*coinsPlayed || len(got.Earned) != 1 {
+		t.Errorf("loser wallet %+v, want %d coins earned for playing", got, 4*coinsPlayed)
+	}
+
+	wallet, err := m.BuyItem("alex-token", "card_back_midnight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wallet.Coins != 160-100 {
+		t.Errorf("%d coins after buying, want %d", wallet.Coins, 160-100)
+	}
+	if _, err := m.BuyItem("alex-token", "card_back_midnight"); !errors.Is(err, ErrAlreadyOwned) {
+		t.Errorf("BuyItem() twice error = %v, want ErrAlreadyOwned", err)
+	}
+	if wallet, err = m.EquipItem("alex-token", "card_back_midnight"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.loadout("alex-token"); got[SlotCardBack] != "card_back_midnight" || got[SlotTable] != "table_felt" {
+		t.Errorf("loadout = %v, want midnight card back on green felt", got)
+	}
+
+	// A game against yourself pays nothing
+	solo, _ := NewRoom("EFGH", "Alex", "alex-token", nil)
+	if paid := m.PayOut(solo, "", nil); paid != nil {
+		t.Errorf("solo game paid %v", paid)
+	}
+}
+
+func TestRecordTelemetry(t *testing.T) {
+	clk := clock.NewMock(time.Now())
+	r, alexID := NewRoom("ABCD", "Alex", "alex-token", nil)
+	sam, _ := r.AddPlayer("Sam", "sam-token")
+	r.StartGame(clk)
+	m := &Manager{
+		rooms:         map[string]*Room{r.Code: r},
+		cheatWarnings: newReviewLog[protocol.CheatWarning](),
+		telemetry:     newReviewLog[protocol.TelemetryReport](),
+		clock:         clk,
+	}
+
+	// Sam slaps 250ms after each of three cards
+	for i := 0; i < 3; i++ {
+		if _, _, err := r.Game.PlayCard(r.Game.GetCurrentPlayer(), 0); err != nil {
+			t.Fatal(err)
+		}
+		clk.Advance(250 * time.Millisecond)
+		r.Game.ProcessSlap(sam.ID, 0, 0)
+		clk.Advance(r.Game.SlapWindow)
+		r.Game.SettleSlaps()
+	}
+
+	if _, _, err := m.RecordTelemetry(r.Code, sam.ID, protocol.ReportTelemetryPayload{InputLagMs: -1}, 80); !errors.Is(err, ErrInvalidTelemetry) {
+		t.Errorf("negative input lag error = %v, want ErrInvalidTelemetry", err)
+	}
+
+	honest := protocol.ReportTelemetryPayload{RenderLatencyMs: 16, InputLagMs: 40, RTTMs: 90}
+	report, warning, err := m.RecordTelemetry(r.Code, sam.ID, honest, 80)
+	if err != nil || warning != nil || report.Flagged {
+		t.Fatalf("honest report = %+v, warning %v, error %v", report, warning, err)
+	}
+	if report.ObservedSlaps != 3 || report.ObservedFastestMs != 250 {
+		t.Errorf("observed %d slaps, fastest %dms, want 3 at 250ms", report.ObservedSlaps, report.ObservedFastestMs)
+	}
+
+	tests := []struct {
+		name     string
+		reported protocol.ReportTelemetryPayload
+	}{
+		{"overstated round trip", protocol.ReportTelemetryPayload{RTTMs: 400}},
+		{"lag slower than a slap", protocol.ReportTelemetryPayload{RenderLatencyMs: 100, InputLagMs: 150}},
+	}
+	for i, tt := range tests {
+		report, warning, _ := m.RecordTelemetry(r.Code, sam.ID, tt.reported, 80)
+		if !report.Flagged {
+			t.Errorf("%s: not flagged", tt.name)
+		}
+		// Only the first misreport warns the host
+		if (warning != nil) != (i == 0) {
+			t.Errorf("%s: warning %v", tt.name, warning)
+		}
+	}
+
+	// A player without slaps can't be checked against them
+	if report, _, _ := m.RecordTelemetry(r.Code, alexID, protocol.ReportTelemetryPayload{InputLagMs: 900}, 80); report.Flagged {
+		t.Errorf("player with no slaps flagged: %s", report.Detail)
+	}
+	if got := len(m.GetTelemetry()[r.Code]); got != 4 {
+		t.Errorf("stored %d reports, want 4", got)
+	}
+}
+
+func TestVariants(t *testing.T) {
+	cfg := config.Default()
+	m := &Manager{cfg: config.Static(cfg)}
+
+	list := m.Variants(VariantLanguage("fr-CA"))
+	if len(list) != len(variants) {
+		t.Fatalf("%d variants listed, want %d", len(list), len(variants))
+	}
+	if list[0].Name != "Slapjack classique" {
+		t.Errorf("classic variant named %q in French", list[0].Name)
+	}
+	beggar := list[2]
+	if beggar.Settings.GameMode != "ratscrew" || beggar.Settings.EnableDoubles || beggar.Settings.MaxPlayers > beggar.MaxPlayers {
+		t.Errorf("beggar-my-neighbour settings %+v", beggar.Settings)
+	}
+
+	for _, tt := range []struct {
+		preferences []string
+		want        string
+	}{
+		{[]string{"", "de-DE,de;q=0.9,en;q=0.8"}, "de"},
+		{[]string{"es", "fr"}, "es"},
+		{[]string{"ja"}, "en"},
+		{nil, "en"},
+	} {
+		if got := VariantLanguage(tt.preferences...); got != tt.want {
+			t.Errorf("VariantLanguage(%q) = %q, want %q", tt.preferences, got, tt.want)
+		}
+	}
+
+	// Variants that need a switched off feature aren't offered
+	cfg.DisabledFeatures = []string{protocol.FeatureRatscrew}
+	m.cfg = config.Static(cfg)
+	if list := m.Variants("en"); len(list) != 1 || list[0].ID != protocol.VariantClassic {
+		t.Errorf("variants with ratscrew off = %+v, want only classic", list)
+	}
+	if _, ok := m.variant(protocol.VariantERS); ok {
+		t.Error("ERS variant available with ratscrew off")
+	}
+
+	r, _ := NewRoom("ABCD", "Alex", "alex-token", nil)
+	v, _ := findVariant(protocol.VariantBeggar)
+	r.applyVariant(v)
+	r.UpdateSettings(protocol.UpdateSettingsPayload{MaxPlayers: 8})
+	if r.Settings.MaxPlayers != v.maxPlayers {
+		t.Errorf("max players %d in a beggar-my-neighbour room, want %d", r.Settings.MaxPlayers, v.maxPlayers)
+	}
+	if got := r.ToProtocol().VariantID; got != protocol.VariantBeggar {
+		t.Errorf("room variant %q", got)
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	start := time.Unix(0, 0)
+	rtt := 60 * time.Millisecond
+	var flashes, taps []time.Time
+	for i := 0; i < CalibrationRounds; i++ {
+		flashes = append(flashes, start.Add(time.Duration(i)*CalibrationInterval))
+	}
+	// 40ms of input lag, give or take; warm-up taps and repeats don't count
+	jitter := []time.Duration{-300, 250, 35, 45, 40, 38, 40, 42, 50, 30}
+	for i, j := range jitter {
+		taps = append(taps, flashes[i].Add(rtt+j*time.Millisecond))
+	}
+	taps = append(taps, flashes[4].Add(rtt+200*time.Millisecond))
+
+	lagMs, samples, err := EstimateInputLag(flashes, taps, rtt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lagMs != 40 || samples != 8 {
+		t.Errorf("EstimateInputLag() = %dms from %d taps, want 40ms from 8", lagMs, samples)
+	}
+	if _, _, err := EstimateInputLag(flashes, taps[:5], rtt); !errors.Is(err, ErrCalibrationFailed) {
+		t.Errorf("EstimateInputLag() with 3 counted taps error = %v, want ErrCalibrationFailed", err)
+	}
+
+	// Lag beyond the cap isn't allowed for in full
+	late := make([]time.Time, len(flashes))
+	for i, f := range flashes {
+		late[i] = f.Add(rtt + 350*time.Millisecond)
+	}
+	if lagMs, _, _ := EstimateInputLag(flashes, late, rtt); lagMs != maxInputLagMs {
+		t.Errorf("EstimateInputLag() tapping late = %dms, want the %dms cap", lagMs, maxInputLagMs)
+	}
+
+	m := &Manager{
+		cfg:          config.Static(config.Default()),
+		calibrations: newCalibrations(),
+		clock:        clock.NewMock(start),
+	}
+	if _, err := m.SaveCalibration("alex-token", " mobile ", 40, 8); err != nil {
+		t.Fatal(err)
+	}
+	if result, ok := m.GetCalibration("alex-token"); !ok || result.Device != "mobile" || m.inputLag("alex-token") != 40 {
+		t.Errorf("GetCalibration() = %+v, %v", result, ok)
+	}
+	if _, ok := m.GetCalibration("sam-token"); ok {
+		t.Error("uncalibrated token has a calibration")
+	}
+
+	r, alexID := NewRoom("ABCD", "Alex", "alex-token", nil)
+	r.AddPlayer("Sam", "sam-token")
+	r.setInputLag(alexID, m.inputLag("alex-token"))
+	r.StartGame(clock.NewMock(start))
+	if got := r.Game.InputLag[alexID]; got != 40*time.Millisecond {
+		t.Errorf("game input lag = %v, want 40ms", got)
+	}
+}
+
+func TestResultSignatures(t *testing.T) {
+	seed := base64.StdEncoding.EncodeToString(make([]byte, ed25519.SeedSize))
+	r, alexID := NewRoom("ABCD", "Alex", "alex-token", nil)
+	r.AddPlayer("Sam", "sam-token")
+	m := &Manager{
+		rooms:   map[string]*Room{"ABCD": r},
+		results: newResultSigner(seed),
+	}
+
+	r.StartGame(clock.NewMock(time.Unix(0, 0)))
+	if signed := m.signResult("ABCD", r.Game, r.Game.GetReplay()); signed != nil {
+		t.Fatal("game still in progress was signed")
+	}
+	r.Game.RecordGameOver(alexID)
+	signed := m.signResult("ABCD", r.Game, r.Game.GetReplay())
+	if signed == nil {
+		t.Fatal("won game wasn't signed")
+	}
+
+	result, err := m.VerifyResult(*signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.WinnerID != alexID || result.WinnerName != "Alex" || len(result.Players) != 2 || result.GameID != r.Game.ID {
+		t.Errorf("verified result %+v", result)
+	}
+
+	// The same seed gives the same key, so results survive a restart
+	if again := newResultSigner(seed); again.keyID != m.results.keyID {
+		t.Error("key ID changed for the same seed")
+	}
+
+	forged := *signed
+	result.WinnerID = "someone-else"
+	payload, _ := json.Marshal(result)
+	forged.Payload = base64.RawURLEncoding.EncodeToString(payload)
+	if _, err := m.VerifyResult(forged); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("VerifyResult(forged) error = %v, want ErrBadSignature", err)
+	}
+	other := newResultSigner("")
+	if _, err := other.verify(*signed); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Errorf("verify() with another key error = %v, want ErrUnknownSigningKey", err)
+	}
+}
+
+func TestSlowMode(t *testing.T) {
+	r, _ := NewRoom("ABCD", "Alex", "host-token", nil)
+	now := time.Unix(1000, 0)
+
+	if wait := r.TakeSlowMode("p1", now); wait != 0 {
+		t.Fatalf("slow mode off: wait = %v, want 0", wait)
+	}
+	if wait := r.TakeSlowMode("p1", now); wait != 0 {
+		t.Fatalf("slow mode off: second wait = %v, want 0", wait)
+	}
+
+	r.Settings.SlowModeSeconds = 10
+	now = now.Add(time.Second)
+	if wait := r.TakeSlowMode("p1", now); wait != 0 {
+		t.Fatalf("first reaction: wait = %v, want 0", wait)
+	}
+	if wait := r.TakeSlowMode("p1", now.Add(4*time.Second)); wait != 6*time.Second {
+		t.Errorf("too soon: wait = %v, want 6s", wait)
+	}
+	if wait := r.TakeSlowMode("p2", now.Add(4*time.Second)); wait != 0 {
+		t.Errorf("other player: wait = %v, want 0", wait)
+	}
+	// A rejected reaction doesn't restart the cooldown
+	if wait := r.TakeSlowMode("p1", now.Add(10*time.Second)); wait != 0 {
+		t.Errorf("after cooldown: wait = %v, want 0", wait)
+	}
+}
+
+func TestSettingsPresets(t *testing.T) {
+	s := DefaultSettings()
+	if s.Preset != protocol.SettingsPresetClassic {
+		t.Errorf("default settings match preset %q, want classic", s.Preset)
+	}
+
+	// Applying a preset sets its fields and leaves the rest
+	p := protocol.UpdateSettingsPayload(s.ToProtocol())
+	p.MaxPlayers = 6
+	p.Preset = protocol.SettingsPresetSpeed
+	s.FromProtocol(p)
+	if s.Preset != protocol.SettingsPresetSpeed || s.SlapCooldownMs != 100 || s.BurnPenalty != 2 || !s.AutoPace {
+		t.Errorf("after applying speed: %+v", s)
+	}
+	if s.MaxPlayers != 6 {
+		t.Errorf("max players %d, want the 6 sent alongside the preset", s.MaxPlayers)
+	}
+
+	// Echoing the active preset keeps individual changes, which leave it
+	p = protocol.UpdateSettingsPayload(s.ToProtocol())
+	p.BurnPenalty = 3
+	s.FromProtocol(p)
+	if s.BurnPenalty != 3 || s.Preset != "" {
+		t.Errorf("after changing the burn: penalty %d, preset %q, want 3 and none", s.BurnPenalty, s.Preset)
+	}
+
+	// Changing back finds the preset again
+	p.BurnPenalty = 2
+	s.FromProtocol(p)
+	if s.Preset != protocol.SettingsPresetSpeed {
+		t.Errorf("preset %q after restoring the burn, want speed", s.Preset)
+	}
+}
+
+func TestTeams(t *testing.T) {
+	r, alexID := NewRoom("ABCD", "Alex", "alex-token", nil)
+	sam, _ := r.AddPlayer("Sam", "sam-token")
+	kim, _ := r.AddPlayer("Kim", "kim-token")
+	if err := r.SetTeam(sam.ID, 1); !errors.Is(err, ErrTeamsOff) {
+		t.Errorf("SetTeam() without teams error = %v, want ErrTeamsOff", err)
+	}
+
+	// Turning teams on splits the players alternately by seat
+	p := protocol.UpdateSettingsPayload(r.Settings.ToProtocol())
+	p.Teams = true
+	r.UpdateSettings(p)
+	want := map[string]int{alexID: 1, sam.ID: 2, kim.ID: 1}
+	if got := r.TeamAssignments(); !reflect.DeepEqual(got, want) {
+		t.Errorf("teams %v, want %v", got, want)
+	}
+
+	// Newcomers join the smaller team
+	lee, _ := r.AddPlayer("Lee", "lee-token")
+	if lee.TeamID != 2 {
+		t.Errorf("Lee joined team %d, want 2", lee.TeamID)
+	}
+
+	if err := r.SetTeam(sam.ID, 3); !errors.Is(err, ErrInvalidTeam) {
+		t.Errorf("SetTeam(3) error = %v, want ErrInvalidTeam", err)
+	}
+	for _, id := range []string{sam.ID, lee.ID} {
+		if err := r.SetTeam(id, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.TeamsReady(); !errors.Is(err, ErrTeamsUneven) {
+		t.Errorf("TeamsReady() with everyone on team 1 = %v, want ErrTeamsUneven", err)
+	}
+	if err := r.SetTeam(lee.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.TeamsReady(); err != nil {
+		t.Errorf("TeamsReady() = %v", err)
+	}
+
+	r.StartGame(clock.NewMock(time.Now()))
+	if got := r.Game.Teammates(lee.ID); len(got) != 1 {
+		t.Errorf("Lee's teammates %v, want Lee alone on team 2", got)
+	}
+	if err := r.SetTeam(lee.ID, 1); !errors.Is(err, ErrTeamsInGame) {
+		t.Errorf("SetTeam() during a game error = %v, want ErrTeamsInGame", err)
+	}
+
+	// Turning teams off clears them
+	r.Status = "waiting"
+	p.Teams = false
+	r.UpdateSettings(p)
+	if got := r.TeamAssignments(); got != nil {
+		t.Errorf("teams %v after turning them off", got)
+	}
+	if r.GetPlayer(sam.ID).TeamID != 0 {
+		t.Error("Sam kept a team after teams were turned off")
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	r, hostID := NewRoom("ABCD", "Alex", "host-token", nil)
+	sam, _ := r.AddPlayer("Sam", "sam-token")
+	kim, _ := r.AddPlayer("Kim", "kim-token")
+
+	settings := r.Settings.ToProtocol()
+	settings.TurnTimeoutMs = 20000
+	settings.SlowModeSeconds = 5
+	r.UpdateSettings(protocol.UpdateSettingsPayload(settings))
+	r.RemovePlayer(sam.ID, true)
+	r.RemovePlayer(kim.ID, false)
+
+	want := []protocol.AuditEntry{
+		{Type: protocol.AuditCreate, ActorID: hostID, ActorName: "Alex"},
+		{Type: protocol.AuditJoin, ActorID: sam.ID, ActorName: "Sam"},
+		{Type: protocol.AuditJoin, ActorID: kim.ID, ActorName: "Kim"},
+		{Type: protocol.AuditSettings, ActorID: hostID, ActorName: "Alex", Detail: "slowModeSeconds, turnTimeoutMs"},
+		{Type: protocol.AuditKick, ActorID: hostID, ActorName: "Alex", TargetID: sam.ID, TargetName: "Sam"},
+		{Type: protocol.AuditLeave, ActorID: kim.ID, ActorName: "Kim"},
+	}
+	got := r.AuditLog()
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		got[i].Timestamp = 0
+		if got[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if !r.IsHostToken("host-token") || r.IsHostToken("sam-token") || r.IsHostToken("") {
+		t.Error("IsHostToken doesn't pick out just the host")
+	}
+
+	// The log keeps only the most recent entries
+	for i := 0; i < maxAuditEntries; i++ {
+		r.RenamePlayer(hostID, fmt.Sprintf("Alex%d", i))
+	}
+	got = r.AuditLog()
+	if len(got) != maxAuditEntries {
+		t.Fatalf("got %d entries, want %d", len(got), maxAuditEntries)
+	}
+	if first, last := got[0].Detail, got[len(got)-1].Detail; first != "was Alex" || last != fmt.Sprintf("was Alex%d", maxAuditEntries-2) {
+		t.Errorf("log runs from %q to %q", first, last)
+	}
+}
+
+func TestMatchRosterKeepsLeavers(t *testing.T) {
+	r, hostID := NewRoom("ABCD", "Alex", "host-token", nil)
+	sam, _ := r.AddPlayer("Sam", "sam-token")
+	jo, _ := r.AddPlayer("Jo", "jo-token")
+	r.StartGame(clock.NewMock(time.Now()))
+
+	r.RemovePlayer(sam.ID, false)
+	if _, err := r.RenamePlayer(jo.ID, "Joanne"); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, p := range r.matchRoster() {
+		got = append(got, p.id+"="+p.name)
+	}
+	want := []string{hostID + "=Alex", sam.ID + "=Sam", jo.ID + "=Joanne"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("matchRoster() = %v, want %v", got, want)
+	}
+}
+
+func TestBan(t *testing.T) {
+	clk := clock.NewMock(time.Unix(0, 0))
+	m := NewManager(nil, config.Static(config.Default()), clk)
+	r, _, err := m.CreateRoom("host-session", "Alex", "host-token", "", nil, func(string, []byte) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := &Profile{ID: "sam-profile"}
+	_, samID, _, err := m.JoinRoom(r.Code, "Sam", "sam-token", profile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, kimID, _, err := m.JoinRoom(r.Code, "Kim", "kim-token", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Banned by token and profile for ten minutes
+	if err := r.Ban(samID, 10, clk.Now()); err != nil {
+		t.Fatal(err)
+	}
+	r.RemovePlayer(samID, true)
+	if _, _, _, err := m.JoinRoom(r.Code, "Sam", "sam-token", nil, ""); !errors.Is(err, ErrBanned) {
+		t.Errorf("rejoin with the banned token: error %v, want ErrBanned", err)
+	}
+	if _, _, _, err := m.JoinRoom(r.Code, "Sammy", "new-token", profile, ""); !errors.Is(err, ErrBanned) {
+		t.Errorf("rejoin with the banned profile: error %v, want ErrBanned", err)
+	}
+	clk.Advance(11 * time.Minute)
+	if _, _, _, err := m.JoinRoom(r.Code, "Sam", "sam-token", profile, ""); err != nil {
+		t.Errorf("rejoin after the ban ran out: %v", err)
+	}
+
+	// With no duration, until the room closes
+	r.Ban(kimID, 0, clk.Now())
+	r.RemovePlayer(kimID, true)
+	clk.Advance(48 * time.Hour)
+	if _, _, _, err := m.JoinRoom(r.Code, "Kim", "kim-token", nil, ""); !errors.Is(err, ErrBanned) {
+		t.Errorf("rejoin after a permanent ban: error %v, want ErrBanned", err)
+	}
+}
+
+func TestVisibility(t *testing.T) {
+	clk := clock.NewMock(time.Unix(0, 0))
+	m := NewManager(nil, config.Static(config.Default()), clk)
+	r, _, err := m.CreateRoom("host-session", "Alex", "host-token", "", nil, func(string, []byte) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := func() bool {
+		clk.Advance(roomSummaryCacheTTL)
+		for _, summary := range m.GetActiveRooms() {
+			if summary.Code == r.Code {
+				return true
+			}
+		}
+		return false
+	}
+	if !listed() {
+		t.Error("public room missing from the lobby")
+	}
+
+	// Unlisted rooms stay out of the lobby but can be joined by code
+	r.Settings.Visibility = protocol.VisibilityUnlisted
+	if listed() {
+		t.Error("unlisted room listed in the lobby")
+	}
+	if _, _, _, err := m.JoinRoom(r.Code, "Sam", "sam-token", nil, ""); err != nil {
+		t.Errorf("join an unlisted room by code: %v", err)
+	}
+
+	// Private rooms only let their own players back in
+	r.Settings.Visibility = protocol.VisibilityPrivate
+	if _, _, _, err := m.JoinRoom(r.Code, "Kim", "kim-token", nil, ""); !errors.Is(err, ErrInviteOnly) {
+		t.Errorf("join a private room: error %v, want ErrInviteOnly", err)
+	}
+	if _, _, _, err := m.JoinRoom(r.Code, "Sam", "sam-token", nil, ""); err != nil {
+		t.Errorf("rejoin a private room: %v", err)
+	}
+	if _, err := m.SpectateRoom(r.Code, "watcher-1", ""); !errors.Is(err, ErrInviteOnly) {
+		t.Errorf("spectate a private room: error %v, want ErrInviteOnly", err)
+	}
+
+	r.Settings.Visibility = protocol.VisibilityPublic
+	r.Settings.MaxSpectators = 1
+	if _, err := m.SpectateRoom(r.Code, "watcher-1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.SpectateRoom(r.Code, "watcher-2", ""); !errors.Is(err, ErrSpectatorsFull) {
+		t.Errorf("spectate past the limit: error %v, want ErrSpectatorsFull", err)
+	}
+	if _, err := m.SpectateRoom(r.Code, "watcher-1", ""); err != nil {
+		t.Errorf("spectator rejoining at the limit: %v", err)
+	}
+}
+
+func TestInvites(t *testing.T) {
+	clk := clock.NewMock(time.Unix(0, 0))
+	m := NewManager(nil, config.Static(config.Default()), clk)
+	r, _, err := m.CreateRoom("host-session", "Alex", "host-token", "", nil, func(string, []byte) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := m.CreateRoom("other-session", "Jo", "other-token", "", nil, func(string, []byte) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Settings.Visibility = protocol.VisibilityPrivate
+
+	invite, err := m.CreateInvite(context.Background(), r.Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := m.JoinRoom(r.Code, "Sam", "sam-token", nil, invite.Token); err != nil {
+		t.Fatalf("join with an invite: %v", err)
+	}
+	if _, _, _, err := m.JoinRoom(r.Code, "Kim", "kim-token", nil, invite.Token); !errors.Is(err, ErrInvalidInvite) {
+		t.Errorf("join with a used invite: error %v, want ErrInvalidInvite", err)
+	}
+
+	// Invites are tied to their room and can't be made up
+	forOther, _ := m.CreateInvite(context.Background(), other.Code)
+	if _, _, _, err := m.JoinRoom(r.Code, "Kim", "kim-token", nil, forOther.Token); !errors.Is(err, ErrInvalidInvite) {
+		t.Errorf("join with another room's invite: error %v, want ErrInvalidInvite", err)
+	}
+	nonce, _, _ := strings.Cut(forOther.Token, ".")
+	if _, _, _, err := m.JoinRoom(r.Code, "Kim", "kim-token", nil, nonce+".forged"); !errors.Is(err, ErrInvalidInvite) {
+		t.Errorf("join with a forged invite: error %v, want ErrInvalidInvite", err)
+	}
+
+	expiring, _ := m.CreateInvite(context.Background(), r.Code)
+	clk.Advance(inviteTTL + time.Minute)
+	if _, err := m.SpectateRoom(r.Code, "watcher", expiring.Token); !errors.Is(err, ErrInvalidInvite) {
+		t.Errorf("spectate with an expired invite: error %v, want ErrInvalidInvite", err)
+	}
+	fresh, _ := m.CreateInvite(context.Background(), r.Code)
+	if _, err := m.SpectateRoom(r.Code, "watcher", fresh.Token); err != nil {
+		t.Errorf("spectate with an invite: %v", err)
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	m := NewManager(nil, config.Static(config.Default()), clock.NewMock(time.Unix(0, 0)))
+	r, alexID := NewRoom("ABCD", "Alex", "alex-token", nil)
+	sam, _ := r.AddPlayer("Sam", "sam-token")
+	r.StartGame(clock.NewMock(time.Unix(0, 0)))
+
+	for i := 0; i < minLeaderboardGames-1; i++ {
+		m.RecordLeaderboard(r, alexID)
+	}
+	if entries, _ := m.Leaderboard(context.Background(), protocol.LeaderboardWinRate, 0); len(entries) != 0 {
+		t.Errorf("win rate board %+v before anyone played enough games", entries)
+	}
+	m.RecordLeaderboard(r, sam.ID)
+
+	wins, err := m.Leaderboard(context.Background(), protocol.LeaderboardWins, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wins) != 2 || wins[0].Name != "Alex" || wins[0].Score != 4 || wins[1].Name != "Sam" || wins[1].Played != minLeaderboardGames {
+		t.Errorf("wins board %+v, want Alex on 4 then Sam", wins)
+	}
+	rate, _ := m.Leaderboard(context.Background(), protocol.LeaderboardWinRate, 1)
+	if len(rate) != 1 || rate[0].Name != "Alex" || rate[0].Score != 0.8 {
+		t.Errorf("win rate board %+v, want just Alex on 0.8", rate)
+	}
+	if _, err := m.Leaderboard(context.Background(), "longest_streak", 0); !errors.Is(err, ErrUnknownMetric) {
+		t.Errorf("unknown metric error %v, want ErrUnknownMetric", err)
+	}
+
+	// A lone player's games don't count
+	solo, soloID := NewRoom("WXYZ", "Kim", "kim-token", nil)
+	solo.StartGame(clock.NewMock(time.Unix(0, 0)))
+	m.RecordLeaderboard(solo, soloID)
+	if wins, _ := m.Leaderboard(context.Background(), protocol.LeaderboardWins, 0); len(wins) != 2 {
+		t.Errorf("wins board %+v after a solo game", wins)
+	}
+}
+
+func TestBacklog(t *testing.T) {
+	r, alexID := NewRoom("ABCD", "Alex", "alex-token", nil)
+	broadcast := func() {
+		seq := r.NextSeq()
+		r.Record(Broadcast{Seq: seq, Message: []byte(fmt.Sprintf(`{"seq":%d}`, seq))})
+	}
+	for i := 0; i < 10; i++ {
+		broadcast()
+	}
+
+	r.Ack(alexID, 7)
+	r.Ack(alexID, 3)
+	r.Ack(alexID, 99)
+	if ack := r.LastAck(alexID); ack != 10 {
+		t.Errorf("last ack %d, want 10: acks only go forward, up to the last broadcast", ack)
+	}
+	missed, ok := r.BacklogSince(7)
+	if !ok || len(missed) != 3 || missed[0].Seq != 8 || missed[2].Seq != 10 {
+		t.Errorf("backlog since 7 = %+v, %v, want broadcasts 8 to 10", missed, ok)
+	}
+	if missed, ok := r.BacklogSince(10); !ok || len(missed) != 0 {
+		t.Errorf("backlog since the last broadcast = %+v, %v, want nothing", missed, ok)
+	}
+
+	// Once the ring wraps, the oldest broadcasts can't be replayed
+	for i := 0; i < maxBacklog; i++ {
+		broadcast()
+	}
+	if _, ok := r.BacklogSince(7); ok {
+		t.Error("backlog since 7 ok after it dropped out of the buffer")
+	}
+	missed, ok = r.BacklogSince(10)
+	if !ok || len(missed) != maxBacklog || missed[0].Seq != 11 {
+		t.Errorf("backlog since 10 has %d broadcasts, %v, want all %d kept", len(missed), ok, maxBacklog)
+	}
+
+	r.RemovePlayer(alexID, false)
+	if ack := r.LastAck(alexID); ack != 0 {
+		t.Errorf("departed player's ack %d, want it forgotten", ack)
+	}
+}
+
+func TestPartyRoom(t *testing.T) {
+	r, _ := NewRoom("ABCD", "Alex", "alex-token", nil)
+	r.UpdateSettings(protocol.UpdateSettingsPayload{MaxPlayers: maxRoomPlayers})
+	if r.Settings.MaxPlayers != maxRoomPlayers {
+		t.Fatalf("max players %d, want %d", r.Settings.MaxPlayers, maxRoomPlayers)
+	}
+	for i := 1; i < 10; i++ {
+		if _, err := r.AddPlayer(fmt.Sprintf("Player %d", i), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clk := clock.NewMock(time.Unix(0, 0))
+	r.StartGame(clk)
+	piles := r.Piles()
+	if r.Game != nil || len(piles) != partyPiles {
+		t.Fatalf("%d piles and room game %v for 10 players, want %d piles", len(piles), r.Game, partyPiles)
+	}
+	state := r.ToProtocol()
+	if len(state.Piles) != partyPiles || len(state.Piles[0]) != 5 || len(state.Piles[1]) != 5 {
+		t.Fatalf("players split %v, want 5 on each pile", state.Piles)
+	}
+	first, second := state.Piles[0][0], state.Piles[1][0]
+	if g, pile := r.GameFor(second); pile != 2 || g != piles[1] {
+		t.Errorf("second pile's player got pile %d", pile)
+	}
+
+	if finalists := r.PileWon(1, first); finalists != nil {
+		t.Errorf("finalists %v with a pile still being played", finalists)
+	}
+	if finalists := r.PileWon(1, second); finalists != nil {
+		t.Errorf("pile 1 won twice, finalists %v", finalists)
+	}
+	finalists := r.PileWon(2, second)
+	if len(finalists) != 2 || finalists[0] != first || finalists[1] != second {
+		t.Fatalf("finalists %v, want both piles' winners", finalists)
+	}
+
+	r.startFinale(clk, finalists)
+	if g, pile := r.GameFor(first); g == nil || g != r.Game || pile != 0 || len(g.TurnOrder) != 2 {
+		t.Errorf("finale isn't the room's game between the 2 finalists")
+	}
+	if r.Piles() != nil {
+		t.Error("piles kept after the finale started")
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	m := NewManager(nil, config.Static(config.Default()), clock.NewMock(time.Unix(0, 0)))
+	r, _, err := m.CreateRoom("host-session", "Alex", "host-token", "", nil, func(string, []byte) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, url := range []string{
+		"http://discord.com/api/webhooks/1/abc",
+		"https://example.com/api/webhooks/1/abc",
+		"https://discord.com.example.com/api/webhooks/1/abc",
+		"https://discord.com/channels/1",
+	} {
+		if err := r.SetWebhook(url); err == nil {
+			t.Errorf("SetWebhook(%q) accepted", url)
+		}
+	}
+
+	const url = "https://discord.com/api/webhooks/1/abc"
+	if err := r.SetWebhook(url); err != nil {
+		t.Fatalf("SetWebhook: %v", err)
+	}
+	state := r.ToProtocol()
+	if !reflect.DeepEqual(state.Integrations, []string{protocol.IntegrationDiscord}) {
+		t.Errorf("integrations = %v, want [discord]", state.Integrations)
+	}
+	// The URL lets anyone post to the channel, so it stays on the server
+	data, _ := json.Marshal(state)
+	if strings.Contains(string(data), url) {
+		t.Error("webhook URL sent to clients")
+	}
+
+	if err := r.SetWebhook(""); err != nil {
+		t.Fatalf("removing the webhook: %v", err)
+	}
+	if state := r.ToProtocol(); state.Integrations != nil {
+		t.Errorf("integrations after removal = %v", state.Integrations)
+	}
+}
+
+func TestRoomWritesCoalesce(t *testing.T) {
+	m := NewManager(nil, config.Static(config.Default()), clock.NewMock(time.Unix(0, 0)))
+	r, _, err := m.CreateRoom("host-session", "Alex", "host-token", "", nil, func(string, []byte) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// However often a room changes, it is written once per flush
+	w := m.storeWriter
+	for i := 0; i < 3; i++ {
+		w.markDirty(r.Code, r)
+	}
+	w.markDirty("GONE", &Room{Code: "GONE"})
+	if got := w.pending(); got != 2 {
+		t.Fatalf("%d rooms dirty, want 2", got)
+	}
+	m.flushRooms()
+	if got := w.pending(); got != 0 {
+		t.Errorf("%d rooms dirty after a flush, want 0", got)
+	}
+	// A room deleted before the flush isn't written back
+	if got := len(w.queue); got != 1 {
+		t.Errorf("%d writes queued, want 1", got)
+	}
+}
+
+func TestRoomWritesWaitForQueueSpace(t *testing.T) {
+	m := NewManager(nil, config.Static(config.Default()), clock.NewMock(time.Unix(0, 0)))
+	r, _, err := m.CreateRoom("host-session", "Alex", "host-token", "", nil, func(string, []byte) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := m.storeWriter
+	for len(w.queue) < cap(w.queue) {
+		w.enqueue("filler", nil)
+	}
+
+	// A flush with no space in the queue leaves the room dirty, not waited on
+	w.markDirty(r.Code, r)
+	m.flushRooms()
+	if got := w.pending(); got != 1 {
+		t.Fatalf("%d rooms dirty after a flush into a full queue, want 1", got)
+	}
+
+	<-w.queue
+	m.flushRooms()
+	if got := w.pending(); got != 0 {
+		t.Errorf("%d rooms dirty once the queue had space, want 0", got)
+	}
+}
+
+func TestRoomSnapshot(t *testing.T) {
+	r, hostID := NewRoom("ABCD", "Alex", "host-token", nil)
+	r.setPlayerLoadout(hostID, map[string]string{"back": "red"})
+	r.Bans = map[string]time.Time{"banned-token": {}}
+
+	snap := r.Snapshot()
+	r.mu.Lock()
+	r.Players[hostID].Loadout["back"] = "blue"
+	r.Players[hostID].Name = "Sam"
+	r.Bans["other-token"] = time.Time{}
+	r.mu.Unlock()
+
+	if got := snap.Players[hostID].Loadout["back"]; got != "red" {
+		t.Errorf("snapshot loadout = %q, want red", got)
+	}
+	if got := snap.Players[hostID].Name; got != "Alex" {
+		t.Errorf("snapshot name = %q, want Alex", got)
+	}
+	if len(snap.Bans) != 1 {
+		t.Errorf("snapshot has %d bans, want 1", len(snap.Bans))
+	}
+}
+
+func TestStoreWritesNeverBlock(t *testing.T) {
+	m := NewManager(nil, config.Static(config.Default()), clock.NewMock(time.Unix(0, 0)))
+	w := m.storeWriter
+	for len(w.queue) < cap(w.queue) {
+		w.enqueue("filler", nil)
+	}
+
+	// A full queue drops the write instead of waiting for space
+	done := make(chan struct{})
+	go func() {
+		w.enqueue("save session", func(context.Context) error { return nil })
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("queuing a write blocked on a full queue")
+	}
+	if got := w.skipped.With(skipDropped).Value(); got != 1 {
+		t.Errorf("%d writes counted as dropped, want 1", got)
+	}
+}
+
+func TestCreateRoomLimitIsAtomic(t *testing.T) {
+	cfg := config.Default()
+	cfg.CreateRoomCooldown = 0
+	cfg.MaxRoomsPerSession = 1
+	m := NewManager(nil, config.Static(cfg), clock.NewMock(time.Unix(0, 0)))
+	broadcast := func(string, []byte) {}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.CreateRoom("session", "Alex", "alex-token", "", nil, broadcast)
+		}()
+	}
+	wg.Wait()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.rooms) > cfg.MaxRoomsPerSession {
+		t.Errorf("session left with %d rooms, want at most %d", len(m.rooms), cfg.MaxRoomsPerSession)
+	}
+}
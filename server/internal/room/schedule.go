@@ -0,0 +1,197 @@
+package room
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"slapjack/pkg/protocol"
+)
+
+// Scheduled rooms start their game by themselves at a time set when they are
+// created. Until then they are kept open, however long they sit idle or empty.
+const (
+	// How often scheduled rooms are checked for reminders and starts
+	scheduleCheckInterval = time.Second
+
+	// How far ahead a game can be scheduled
+	maxScheduleAhead = 48 * time.Hour
+)
+
+// How long before a scheduled start each reminder is sent, longest first
+var scheduleReminders = []time.Duration{
+	time.Hour,
+	30 * time.Minute,
+	10 * time.Minute,
+	5 * time.Minute,
+	time.Minute,
+}
+
+var ErrInvalidSchedule = errors.New("a game can only be scheduled for the next 48 hours")
+
+// CheckSchedule returns an error if a game can't be scheduled for a time
+func (m *Manager) CheckSchedule(at time.Time) error {
+	now := m.clock.Now()
+	if !at.After(now) || at.Sub(now) > maxScheduleAhead {
+		return ErrInvalidSchedule
+	}
+	return nil
+}
+
+// ScheduleStart sets a waiting room's game to start by itself at a time
+func (m *Manager) ScheduleStart(roomCode string, at time.Time) error {
+	if err := m.CheckSchedule(at); err != nil {
+		return err
+	}
+	room := m.GetRoom(roomCode)
+	if room == nil {
+		return errors.New("room not found")
+	}
+
+	room.mu.Lock()
+	if room.Status != "waiting" {
+		room.mu.Unlock()
+		return errors.New("game already in progress")
+	}
+	room.ScheduledAt = at
+	room.nextReminder = 0
+	room.version++
+	room.mu.Unlock()
+
+	m.saveRoom(roomCode, room)
+	slog.Info("game scheduled", "roomCode", roomCode, "startsAt", at)
+	return nil
+}
+
+// Scheduled returns whether the room has a game scheduled that hasn't started
+func (r *Room) Scheduled() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !r.ScheduledAt.IsZero()
+}
+
+// scheduledAtMillis returns the scheduled start in Unix milliseconds, or 0
+// Caller must hold r.mu
+func (r *Room) scheduledAtMillis() int64 {
+	if r.ScheduledAt.IsZero() {
+		return 0
+	}
+	return r.ScheduledAt.UnixMilli()
+}
+
+// dueReminder returns the reminder to send now, if one is due; once several
+// are due, as after a restart, only the latest is sent
+func (r *Room) dueReminder(now time.Time) (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	left := r.ScheduledAt.Sub(now)
+	due := -1
+	for i := r.nextReminder; i < len(scheduleReminders) && left <= scheduleReminders[i]; i++ {
+		due = i
+	}
+	if due < 0 {
+		return 0, false
+	}
+	r.nextReminder = due + 1
+	return left, true
+}
+
+// takeSchedule clears a waiting room's schedule once its start time comes
+// Returns false if the room has no schedule or isn't waiting to start
+func (r *Room) takeSchedule() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ScheduledAt.IsZero() || r.Status != "waiting" {
+		return false
+	}
+	r.ScheduledAt = time.Time{}
+	r.nextReminder = 0
+	r.version++
+	return true
+}
+
+// RunSchedules reminds scheduled rooms of their start, and starts their
+// games when the time comes
+func (m *Manager) RunSchedules(broadcast func(string, []byte)) {
+	ticker := m.clock.NewTicker(scheduleCheckInterval)
+	for range ticker.C() {
+		m.runSchedules(broadcast)
+	}
+}
+
+func (m *Manager) runSchedules(broadcast func(string, []byte)) {
+	var scheduled []*Room
+	m.mu.RLock()
+	for _, room := range m.rooms {
+		if room.Scheduled() {
+			scheduled = append(scheduled, room)
+		}
+	}
+	m.mu.RUnlock()
+
+	now := m.clock.Now()
+	for _, room := range scheduled {
+		room.mu.RLock()
+		at, status := room.ScheduledAt, room.Status
+		room.mu.RUnlock()
+		if status != "waiting" {
+			continue
+		}
+
+		// Begin the countdown so the game itself starts on time
+		countdown := time.Duration(room.GetSettings().CountdownSeconds) * time.Second
+		if at.Sub(now) > countdown {
+			if left, ok := room.dueReminder(now); ok {
+				msgData, _ := json.Marshal(protocol.NewMessage(protocol.ScheduleReminder, protocol.ScheduleReminderPayload{
+					StartsAt:    at.UnixMilli(),
+					SecondsLeft: int(left.Round(time.Second).Seconds()),
+				}))
+				broadcast(room.Code, msgData)
+			}
+			continue
+		}
+
+		if !room.takeSchedule() {
+			continue
+		}
+		if m.releaseSeats(room, broadcast) {
+			continue
+		}
+		m.startScheduled(room, broadcast)
+		m.saveRoom(room.Code, room)
+	}
+}
+
+// releaseSeats removes the players a scheduled room kept seated after their
+// reconnection grace ran out. Returns true if that closed the room.
+func (m *Manager) releaseSeats(room *Room, broadcast func(string, []byte)) bool {
+	for _, p := range room.GetAllPlayers() {
+		if !p.IsConnected && !m.hasPendingDisconnect(room.Code, p.ID) {
+			m.expireDisconnectedPlayer(room.Code, p.ID, broadcast)
+		}
+	}
+	return m.GetRoom(room.Code) == nil
+}
+
+// startScheduled starts a room's game at its scheduled time, or tells the
+// room why it couldn't
+func (m *Manager) startScheduled(room *Room, broadcast func(string, []byte)) {
+	if len(room.GetConnectedPlayers()) >= room.MinPlayers() && room.TeamsReady() == nil {
+		err := m.StartGameCountdown(room.Code, broadcast)
+		if err == nil {
+			slog.Info("scheduled game starting", "roomCode", room.Code)
+			return
+		}
+		slog.Warn("scheduled game failed to start", "roomCode", room.Code, "error", err)
+	}
+
+	slog.Info("scheduled game missed", "roomCode", room.Code, "players", len(room.GetConnectedPlayers()))
+	msgData, _ := json.Marshal(protocol.NewMessage(protocol.StartCancelled, protocol.StartCancelledPayload{
+		Reason: protocol.StartCancelledUnscheduled,
+	}))
+	broadcast(room.Code, msgData)
+	m.NotifyMembershipChanged(room.Code, broadcast)
+}
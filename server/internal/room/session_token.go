@@ -0,0 +1,114 @@
+package room
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionTokens holds hashes of issued session tokens when Redis is unavailable
+type sessionTokens struct {
+	hashes   map[string]string // session ID -> token hash
+	issuedAt map[string]time.Time
+	secret   []byte
+	mu       sync.Mutex
+}
+
+func newSessionTokens(secret string) *sessionTokens {
+	key := []byte(secret)
+	if len(key) == 0 {
+		slog.Warn("SESSION_SECRET not set, session tokens will not survive a restart")
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &sessionTokens{
+		hashes:   make(map[string]string),
+		issuedAt: make(map[string]time.Time),
+		secret:   key,
+	}
+}
+
+// sign returns the HMAC of a session ID and nonce
+func (t *sessionTokens) sign(sessionID, nonce string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(sessionID + "." + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// prune forgets token hashes older than ttl as of now, keeping those of
+// sessions that are still seated in a room
+func (t *sessionTokens) prune(seated map[string]*SessionData, ttl time.Duration, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, at := range t.issuedAt {
+		if _, ok := seated[id]; !ok && now.Sub(at) > ttl {
+			delete(t.hashes, id)
+			delete(t.issuedAt, id)
+		}
+	}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueSessionToken creates a signed random token for a session and stores its hash
+// The token must be presented alongside the session ID to reconnect
+func (m *Manager) IssueSessionToken(sessionID string) string {
+	nonceBytes := make([]byte, 32)
+	rand.Read(nonceBytes)
+	nonce := base64.RawURLEncoding.EncodeToString(nonceBytes)
+	token := nonce + "." + m.tokens.sign(sessionID, nonce)
+	hash := hashToken(token)
+
+	m.tokens.mu.Lock()
+	m.tokens.hashes[sessionID] = hash
+	m.tokens.issuedAt[sessionID] = m.clock.Now()
+	m.tokens.mu.Unlock()
+
+	ttl := m.cfg.Get().RoomTTL
+	m.persist("save session token", func(ctx context.Context) error {
+		err := m.store.SetSessionTokenHash(ctx, sessionID, hash, ttl)
+		if err != nil {
+			slog.Error("failed to store session token", "sessionId", sessionID, "error", err)
+		}
+		return err
+	})
+
+	return token
+}
+
+// VerifySessionToken reports whether a token was issued for the session
+func (m *Manager) VerifySessionToken(sessionID, token string) bool {
+	if sessionID == "" || token == "" {
+		return false
+	}
+
+	// Reject forged tokens before looking anything up
+	nonce, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(m.tokens.sign(sessionID, nonce))) {
+		return false
+	}
+
+	m.tokens.mu.Lock()
+	stored, exists := m.tokens.hashes[sessionID]
+	m.tokens.mu.Unlock()
+
+	if !exists && m.store != nil {
+		stored, _ = m.store.GetSessionTokenHash(context.Background(), sessionID)
+	}
+	if stored == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(hashToken(token))) == 1
+}
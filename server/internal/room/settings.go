@@ -1,50 +1,453 @@
 package room
 
-import "slapjack/pkg/protocol"
+import (
+	"time"
+
+	"slapjack/internal/game"
+	"slapjack/pkg/protocol"
+)
 
 // Settings holds room configuration
 type Settings struct {
 	MaxPlayers     int  `json:"maxPlayers"`
 	SlapCooldownMs int  `json:"slapCooldownMs"`
 	TurnTimeoutMs  int  `json:"turnTimeoutMs"`
+	AFKStrikes     int  `json:"afkStrikes"`
 	EnableSandwich bool `json:"enableSandwich"`
 	EnableDoubles  bool `json:"enableDoubles"`
 	BurnPenalty    int  `json:"burnPenalty"`
 	EnableSlapIn   bool `json:"enableSlapIn"`
 	MaxSlapIns     int  `json:"maxSlapIns"`
+
+	// House rule slap variants
+	EnableMarriage  bool `json:"enableMarriage"`
+	EnableTopBottom bool `json:"enableTopBottom"`
+	EnableRuns      bool `json:"enableRuns"`
+	EnableTens      bool `json:"enableTens"`
+
+	GameMode        string `json:"gameMode"`
+	BurnDestination string `json:"burnDestination"`
+	EscalatePenalty bool   `json:"escalatePenalty"`
+	DealRemainder   string `json:"dealRemainder"`
+	PileCap         int    `json:"pileCap"`
+	RematchQuorum   string `json:"rematchQuorum"`
+	NumDecks        int    `json:"numDecks"`
+
+	// Ranks and extra Jacks in each of the NumDecks decks
+	Deck game.DeckSpec `json:"deck"`
+
+	// Classroom mode disables reactions and voice, enforces strict names, hides the room
+	// from the lobby and keeps settings gentle
+	ClassroomMode bool `json:"classroomMode"`
+
+	// Show everyone the full pile and recent plays, as spectators always see
+	ShowFullPile bool `json:"showFullPile"`
+
+	// What happens when a player picks a name someone in the room already has
+	DuplicateNames string `json:"duplicateNames"`
+
+	// Relay voice and video signaling between players; the host can turn it off
+	EnableRTC bool `json:"enableRtc"`
+
+	// Least time between one player's reactions, to keep busy rooms readable; 0 for off
+	SlowModeSeconds int `json:"slowModeSeconds"`
+
+	// Adaptive pacing shortens turn timeouts as the pile grows, down to
+	// PacingMinTimeoutMs at PacingRampCards cards, and adds PacingClaimBonusMs
+	// to the turn after a pile is claimed
+	AdaptivePacing     bool `json:"adaptivePacing"`
+	PacingMinTimeoutMs int  `json:"pacingMinTimeoutMs"`
+	PacingRampCards    int  `json:"pacingRampCards"`
+	PacingClaimBonusMs int  `json:"pacingClaimBonusMs"`
+
+	// Auto pacing divides the turn timeout by one more every
+	// AutoPaceStepTurns cards played and for every player out, down to
+	// AutoPaceFloorMs, so long endgames don't drag
+	AutoPace          bool `json:"autoPace"`
+	AutoPaceStepTurns int  `json:"autoPaceStepTurns"`
+	AutoPaceFloorMs   int  `json:"autoPaceFloorMs"`
+
+	// Seconds counted down before a game starts
+	CountdownSeconds int `json:"countdownSeconds"`
+
+	// Ask every player to confirm they're ready when the host starts, and
+	// call the start off if anyone hasn't within ReadyCheckSeconds
+	ReadyCheck        bool `json:"readyCheck"`
+	ReadyCheckSeconds int  `json:"readyCheckSeconds"`
+
+	// Play in two teams; see Player.TeamID
+	Teams bool `json:"teams"`
+
+	// Let players vote the host out, not just each other
+	VoteKickHost bool `json:"voteKickHost"`
+
+	// Whether disconnected players' turns are skipped or played for them,
+	// and where the hand of a player who leaves mid-game goes
+	DisconnectedTurns string `json:"disconnectedTurns"`
+	LeaverCards       string `json:"leaverCards"`
+
+	// Who can find and join the room, and the most spectators it takes at
+	// once, 0 for no limit
+	Visibility    string `json:"visibility"`
+	MaxSpectators int    `json:"maxSpectators"`
+
+	// How the game is won; see game.WinCondition
+	WinCondition     string `json:"winCondition"`
+	WinPiles         int    `json:"winPiles"`
+	TimeLimitSeconds int    `json:"timeLimitSeconds"`
+
+	// The settings preset these settings match, if any
+	Preset string `json:"preset,omitempty"`
+}
+
+// Most turn timeouts in a row a room can allow before a player is struck out
+const maxAFKStrikes = 10
+
+// Longest slow mode a host can set
+const maxSlowModeSeconds = 60
+
+// Highest spectator limit a host can set, short of no limit
+const maxSpectatorLimit = 1000
+
+// Adaptive pacing limits
+const (
+	minPacingTimeoutMs  = 3000
+	maxPacingRampCards  = 52
+	maxPacingClaimBonus = 15000
+
+	minAutoPaceStepTurns = 5
+	maxAutoPaceStepTurns = 200
+)
+
+// Pile cap limits, when it's on
+const (
+	minPileCap = 10
+	maxPileCap = 100
+)
+
+// Win condition limits
+const (
+	maxWinPiles         = 50
+	minTimeLimitSeconds = 60
+	maxTimeLimitSeconds = 30 * 60
+)
+
+// Game start limits; a start stuck for longer than the stuck room watchdog
+// allows is abandoned, so the two together must stay well under it
+const (
+	maxCountdownSeconds  = 10
+	minReadyCheckSeconds = 5
+	maxReadyCheckSeconds = 30
+)
+
+// Rematch quorums
+const (
+	RematchAll      = "all"
+	RematchMajority = "majority"
+)
+
+// Duplicate name handling
+const (
+	DuplicateNamesReject = "reject" // Refuse the name with NAME_TAKEN
+	DuplicateNamesSuffix = "suffix" // Number it, as in Alex-2
+)
+
+// settingsPresets are the curated bundles a host can apply in one go; each
+// sets only the fields it cares about, leaving the rest as they were
+var settingsPresets = []struct {
+	name  string
+	apply func(s *Settings)
+}{
+	{protocol.SettingsPresetClassic, func(s *Settings) {
+		s.SlapCooldownMs = 200
+		s.TurnTimeoutMs = 10000
+		s.BurnPenalty = 1
+		s.BurnDestination = game.BurnToPile
+		s.EscalatePenalty = false
+		s.GameMode = game.ModeClassic
+		s.setSlapRules(true, false)
+		s.EnableSlapIn = true
+		s.MaxSlapIns = 3
+		s.AdaptivePacing = false
+		s.AutoPace = false
+	}},
+	// Short turns that get shorter still, quick slaps and costly mistakes
+	{protocol.SettingsPresetSpeed, func(s *Settings) {
+		s.SlapCooldownMs = 100
+		s.TurnTimeoutMs = 5000
+		s.BurnPenalty = 2
+		s.BurnDestination = game.BurnToPile
+		s.EscalatePenalty = true
+		s.GameMode = game.ModeClassic
+		s.setSlapRules(true, false)
+		s.EnableSlapIn = true
+		s.MaxSlapIns = 1
+		s.AdaptivePacing = false
+		s.AutoPace = true
+		s.AutoPaceStepTurns = 20
+		s.AutoPaceFloorMs = 3000
+	}},
+	// Every house rule, relaxed timing and plenty of second chances
+	{protocol.SettingsPresetParty, func(s *Settings) {
+		s.SlapCooldownMs = 300
+		s.TurnTimeoutMs = 15000
+		s.BurnPenalty = 1
+		s.BurnDestination = game.BurnToWinner
+		s.EscalatePenalty = false
+		s.GameMode = game.ModeClassic
+		s.setSlapRules(true, true)
+		s.EnableSlapIn = true
+		s.MaxSlapIns = 5
+		s.AdaptivePacing = false
+		s.AutoPace = false
+	}},
+}
+
+// setSlapRules turns the standard slap rules and the house rule variants on or off
+func (s *Settings) setSlapRules(standard, house bool) {
+	s.EnableSandwich = standard
+	s.EnableDoubles = standard
+	s.EnableMarriage = house
+	s.EnableTopBottom = house
+	s.EnableRuns = house
+	s.EnableTens = house
+}
+
+// ValidSettingsPreset reports whether name is a known settings preset
+func ValidSettingsPreset(name string) bool {
+	for _, preset := range settingsPresets {
+		if preset.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyPreset applies a named settings preset, if there is one
+func (s *Settings) applyPreset(name string) {
+	for _, preset := range settingsPresets {
+		if preset.name == name {
+			preset.apply(s)
+			return
+		}
+	}
+}
+
+// activePreset returns the first preset the settings match, or "" if they've
+// been changed from all of them
+func (s Settings) activePreset() string {
+	s.Preset = ""
+	for _, preset := range settingsPresets {
+		applied := s
+		preset.apply(&applied)
+		if applied == s {
+			return preset.name
+		}
+	}
+	return ""
 }
 
 // DefaultSettings returns the default room settings
 func DefaultSettings() Settings {
-	return Settings{
-		MaxPlayers:     4,
-		SlapCooldownMs: 200,
-		TurnTimeoutMs:  10000,
-		EnableSandwich: true,
-		EnableDoubles:  true,
-		BurnPenalty:    1,
-		EnableSlapIn:   true,
-		MaxSlapIns:     3,
+	s := Settings{
+		MaxPlayers:      4,
+		SlapCooldownMs:  200,
+		TurnTimeoutMs:   10000,
+		AFKStrikes:      3,
+		EnableSandwich:  true,
+		EnableDoubles:   true,
+		BurnPenalty:     1,
+		EnableSlapIn:    true,
+		MaxSlapIns:      3,
+		GameMode:        game.ModeClassic,
+		BurnDestination: game.BurnToPile,
+		DealRemainder:   game.RemainderDeal,
+		RematchQuorum:   RematchAll,
+		NumDecks:        1,
+		DuplicateNames:  DuplicateNamesSuffix,
+		EnableRTC:       true,
+		Visibility:      protocol.VisibilityPublic,
+
+		DisconnectedTurns: game.DisconnectedSkip,
+		LeaverCards:       game.LeaverCardsDeal,
+
+		PacingMinTimeoutMs: 5000,
+		PacingRampCards:    20,
+		PacingClaimBonusMs: 3000,
+
+		AutoPaceStepTurns: 30,
+		AutoPaceFloorMs:   3000,
+
+		CountdownSeconds:  3,
+		ReadyCheckSeconds: 15,
+
+		WinCondition:     game.WinLastStanding,
+		WinPiles:         10,
+		TimeLimitSeconds: 300,
 	}
+	s.Preset = s.activePreset()
+	return s
 }
 
 // ToProtocol converts Settings to protocol.RoomSettings
 func (s Settings) ToProtocol() protocol.RoomSettings {
 	return protocol.RoomSettings{
-		MaxPlayers:     s.MaxPlayers,
-		SlapCooldownMs: s.SlapCooldownMs,
-		TurnTimeoutMs:  s.TurnTimeoutMs,
-		EnableSandwich: s.EnableSandwich,
-		EnableDoubles:  s.EnableDoubles,
-		BurnPenalty:    s.BurnPenalty,
-		EnableSlapIn:   s.EnableSlapIn,
-		MaxSlapIns:     s.MaxSlapIns,
+		MaxPlayers:      s.MaxPlayers,
+		SlapCooldownMs:  s.SlapCooldownMs,
+		TurnTimeoutMs:   s.TurnTimeoutMs,
+		AFKStrikes:      s.AFKStrikes,
+		EnableSandwich:  s.EnableSandwich,
+		EnableDoubles:   s.EnableDoubles,
+		BurnPenalty:     s.BurnPenalty,
+		EnableSlapIn:    s.EnableSlapIn,
+		MaxSlapIns:      s.MaxSlapIns,
+		EnableMarriage:  s.EnableMarriage,
+		EnableTopBottom: s.EnableTopBottom,
+		EnableRuns:      s.EnableRuns,
+		EnableTens:      s.EnableTens,
+		GameMode:        s.GameMode,
+		BurnDestination: s.BurnDestination,
+		EscalatePenalty: s.EscalatePenalty,
+		DealRemainder:   s.DealRemainder,
+		PileCap:         s.PileCap,
+		RematchQuorum:   s.RematchQuorum,
+		NumDecks:        s.NumDecks,
+		Deck:            deckToProtocol(s.Deck),
+		ClassroomMode:   s.ClassroomMode,
+		ShowFullPile:    s.ShowFullPile,
+		DuplicateNames:  s.DuplicateNames,
+		EnableRTC:       s.EnableRTC,
+		SlowModeSeconds: s.SlowModeSeconds,
+
+		AdaptivePacing:     s.AdaptivePacing,
+		PacingMinTimeoutMs: s.PacingMinTimeoutMs,
+		PacingRampCards:    s.PacingRampCards,
+		PacingClaimBonusMs: s.PacingClaimBonusMs,
+
+		AutoPace:          s.AutoPace,
+		AutoPaceStepTurns: s.AutoPaceStepTurns,
+		AutoPaceFloorMs:   s.AutoPaceFloorMs,
+
+		CountdownSeconds:  s.CountdownSeconds,
+		ReadyCheck:        s.ReadyCheck,
+		ReadyCheckSeconds: s.ReadyCheckSeconds,
+
+		Teams:        s.Teams,
+		VoteKickHost: s.VoteKickHost,
+
+		DisconnectedTurns: s.DisconnectedTurns,
+		LeaverCards:       s.LeaverCards,
+
+		Visibility:    s.Visibility,
+		MaxSpectators: s.MaxSpectators,
+
+		WinCondition:     s.WinCondition,
+		WinPiles:         s.WinPiles,
+		TimeLimitSeconds: s.TimeLimitSeconds,
+
+		Preset: s.Preset,
+	}
+}
+
+// RulesSummary lists the game mode and every enabled slap rule
+func (s Settings) RulesSummary() []string {
+	rules := []string{s.GameMode, string(game.SlapReasonJack)}
+	if s.EnableDoubles {
+		rules = append(rules, string(game.SlapReasonDoubles))
+	}
+	if s.EnableSandwich {
+		rules = append(rules, string(game.SlapReasonSandwich))
+	}
+	if s.EnableMarriage {
+		rules = append(rules, string(game.SlapReasonMarriage))
+	}
+	if s.EnableTopBottom {
+		rules = append(rules, string(game.SlapReasonTopBottom))
+	}
+	if s.EnableRuns {
+		rules = append(rules, string(game.SlapReasonRun))
+	}
+	if s.EnableTens {
+		rules = append(rules, string(game.SlapReasonTens))
+	}
+	if s.EnableSlapIn {
+		rules = append(rules, "slap_in")
+	}
+	return rules
+}
+
+// GameOptions converts Settings to the options used to start a game
+func (s Settings) GameOptions() game.Options {
+	return game.Options{
+		Mode: s.GameMode,
+		Rules: game.Rules{
+			EnableDoubles:   s.EnableDoubles,
+			EnableSandwich:  s.EnableSandwich,
+			EnableMarriage:  s.EnableMarriage,
+			EnableTopBottom: s.EnableTopBottom,
+			EnableRuns:      s.EnableRuns,
+			EnableTens:      s.EnableTens,
+		},
+		BurnPenalty:     s.BurnPenalty,
+		BurnDestination: s.BurnDestination,
+		EscalatePenalty: s.EscalatePenalty,
+		DealRemainder:   s.DealRemainder,
+		PileCap:         s.PileCap,
+		NumDecks:        s.NumDecks,
+		Deck:            s.Deck,
+		SlapCooldownMs:  s.SlapCooldownMs,
+		SlapWindow:      game.DefaultSlapWindow,
+		TurnTimeoutMs:   s.TurnTimeoutMs,
+		AFKStrikes:      s.AFKStrikes,
+		EnableSlapIn:    s.EnableSlapIn,
+		MaxSlapIns:      s.MaxSlapIns,
+		Pacing:          s.pacing(),
+		AutoPace:        s.autoPace(),
+		WinCondition:    s.winCondition(),
+
+		DisconnectedTurns: s.DisconnectedTurns,
+		LeaverCards:       s.LeaverCards,
+	}
+}
+
+// winCondition returns how the game is won
+func (s Settings) winCondition() game.WinCondition {
+	switch s.WinCondition {
+	case game.WinPiles:
+		return game.PileTarget{Piles: s.WinPiles}
+	case game.WinTimed:
+		return game.Timed{Limit: time.Duration(s.TimeLimitSeconds) * time.Second}
+	default:
+		return game.LastStanding{}
+	}
+}
+
+// pacing returns the game's adaptive pacing, or nil if it's off
+func (s Settings) pacing() *game.Pacing {
+	if !s.AdaptivePacing {
+		return nil
+	}
+	return &game.Pacing{
+		MinTimeoutMs: s.PacingMinTimeoutMs,
+		RampCards:    s.PacingRampCards,
+		ClaimBonusMs: s.PacingClaimBonusMs,
+	}
+}
+
+// autoPace returns the game's auto pacing, or nil if it's off
+func (s Settings) autoPace() *game.AutoPace {
+	if !s.AutoPace {
+		return nil
+	}
+	return &game.AutoPace{
+		StepTurns: s.AutoPaceStepTurns,
+		FloorMs:   s.AutoPaceFloorMs,
 	}
 }
 
 // FromProtocol updates settings from protocol payload
 func (s *Settings) FromProtocol(p protocol.UpdateSettingsPayload) {
-	if p.MaxPlayers >= 2 && p.MaxPlayers <= 8 {
+	if p.MaxPlayers >= 2 && p.MaxPlayers <= maxRoomPlayers {
 		s.MaxPlayers = p.MaxPlayers
 	}
 	if p.SlapCooldownMs >= 0 && p.SlapCooldownMs <= 1000 {
@@ -53,6 +456,9 @@ func (s *Settings) FromProtocol(p protocol.UpdateSettingsPayload) {
 	if p.TurnTimeoutMs >= 5000 && p.TurnTimeoutMs <= 60000 {
 		s.TurnTimeoutMs = p.TurnTimeoutMs
 	}
+	if p.AFKStrikes >= 0 && p.AFKStrikes <= maxAFKStrikes {
+		s.AFKStrikes = p.AFKStrikes
+	}
 	s.EnableSandwich = p.EnableSandwich
 	s.EnableDoubles = p.EnableDoubles
 	if p.BurnPenalty >= 0 && p.BurnPenalty <= 5 {
@@ -62,6 +468,132 @@ func (s *Settings) FromProtocol(p protocol.UpdateSettingsPayload) {
 	if p.MaxSlapIns >= 1 && p.MaxSlapIns <= 10 {
 		s.MaxSlapIns = p.MaxSlapIns
 	}
+	s.EnableMarriage = p.EnableMarriage
+	s.EnableTopBottom = p.EnableTopBottom
+	s.EnableRuns = p.EnableRuns
+	s.EnableTens = p.EnableTens
+	if p.GameMode == game.ModeClassic || p.GameMode == game.ModeRatscrew {
+		s.GameMode = p.GameMode
+	}
+	if validBurnDestination(p.BurnDestination) {
+		s.BurnDestination = p.BurnDestination
+	}
+	s.EscalatePenalty = p.EscalatePenalty
+	if validDealRemainder(p.DealRemainder) {
+		s.DealRemainder = p.DealRemainder
+	}
+	if p.PileCap == 0 || (p.PileCap >= minPileCap && p.PileCap <= maxPileCap) {
+		s.PileCap = p.PileCap
+	}
+	if p.RematchQuorum == RematchAll || p.RematchQuorum == RematchMajority {
+		s.RematchQuorum = p.RematchQuorum
+	}
+	if p.NumDecks >= 1 && p.NumDecks <= 3 {
+		s.NumDecks = p.NumDecks
+	}
+	if deck, err := DeckFromProtocol(p.Deck); err == nil {
+		s.Deck = deck
+	}
+	s.ClassroomMode = p.ClassroomMode
+	s.EnableRTC = p.EnableRTC
+	if p.SlowModeSeconds >= 0 && p.SlowModeSeconds <= maxSlowModeSeconds {
+		s.SlowModeSeconds = p.SlowModeSeconds
+	}
+	s.AdaptivePacing = p.AdaptivePacing
+	if p.PacingMinTimeoutMs >= minPacingTimeoutMs && p.PacingMinTimeoutMs <= s.TurnTimeoutMs {
+		s.PacingMinTimeoutMs = p.PacingMinTimeoutMs
+	}
+	if p.PacingRampCards >= 1 && p.PacingRampCards <= maxPacingRampCards {
+		s.PacingRampCards = p.PacingRampCards
+	}
+	if p.PacingClaimBonusMs >= 0 && p.PacingClaimBonusMs <= maxPacingClaimBonus {
+		s.PacingClaimBonusMs = p.PacingClaimBonusMs
+	}
+	s.AutoPace = p.AutoPace
+	if p.AutoPaceStepTurns >= minAutoPaceStepTurns && p.AutoPaceStepTurns <= maxAutoPaceStepTurns {
+		s.AutoPaceStepTurns = p.AutoPaceStepTurns
+	}
+	if p.AutoPaceFloorMs >= minPacingTimeoutMs && p.AutoPaceFloorMs <= s.TurnTimeoutMs {
+		s.AutoPaceFloorMs = p.AutoPaceFloorMs
+	}
+	if p.CountdownSeconds >= 1 && p.CountdownSeconds <= maxCountdownSeconds {
+		s.CountdownSeconds = p.CountdownSeconds
+	}
+	s.ReadyCheck = p.ReadyCheck
+	if p.ReadyCheckSeconds >= minReadyCheckSeconds && p.ReadyCheckSeconds <= maxReadyCheckSeconds {
+		s.ReadyCheckSeconds = p.ReadyCheckSeconds
+	}
+	s.ShowFullPile = p.ShowFullPile
+	s.Teams = p.Teams
+	s.VoteKickHost = p.VoteKickHost
+	if validWinCondition(p.WinCondition) {
+		s.WinCondition = p.WinCondition
+	}
+	if p.WinPiles >= 1 && p.WinPiles <= maxWinPiles {
+		s.WinPiles = p.WinPiles
+	}
+	if p.TimeLimitSeconds >= minTimeLimitSeconds && p.TimeLimitSeconds <= maxTimeLimitSeconds {
+		s.TimeLimitSeconds = p.TimeLimitSeconds
+	}
+	if validDuplicateNames(p.DuplicateNames) {
+		s.DuplicateNames = p.DuplicateNames
+	}
+	if validVisibility(p.Visibility) {
+		s.Visibility = p.Visibility
+	}
+	if validDisconnectedTurns(p.DisconnectedTurns) {
+		s.DisconnectedTurns = p.DisconnectedTurns
+	}
+	if validLeaverCards(p.LeaverCards) {
+		s.LeaverCards = p.LeaverCards
+	}
+	if p.MaxSpectators >= 0 && p.MaxSpectators <= maxSpectatorLimit {
+		s.MaxSpectators = p.MaxSpectators
+	}
+
+	// A preset other than the one already matched is applied over the rest;
+	// echoing back the active one changes nothing
+	if p.Preset != "" && p.Preset != s.Preset {
+		s.applyPreset(p.Preset)
+	}
+	s.applyClassroomLimits()
+	s.Preset = s.activePreset()
+}
+
+// validBurnDestination reports whether d is a known burn destination
+func validBurnDestination(d string) bool {
+	return d == game.BurnToPile || d == game.BurnToDiscard || d == game.BurnToWinner
+}
+
+// validDealRemainder reports whether r is a known deal remainder mode
+func validDealRemainder(r string) bool {
+	return r == game.RemainderDeal || r == game.RemainderPile || r == game.RemainderDiscard
+}
+
+// validWinCondition reports whether w is a known win condition
+func validWinCondition(w string) bool {
+	return w == game.WinLastStanding || w == game.WinPiles || w == game.WinTimed
+}
+
+// validDuplicateNames reports whether d is a known duplicate name mode
+func validDuplicateNames(d string) bool {
+	return d == DuplicateNamesReject || d == DuplicateNamesSuffix
+}
+
+// validVisibility reports whether v is a known room visibility
+func validVisibility(v string) bool {
+	return v == protocol.VisibilityPublic || v == protocol.VisibilityUnlisted || v == protocol.VisibilityPrivate
+}
+
+// validDisconnectedTurns reports whether d is a known way to handle
+// disconnected players' turns
+func validDisconnectedTurns(d string) bool {
+	return d == game.DisconnectedSkip || d == game.DisconnectedAutoPlay
+}
+
+// validLeaverCards reports whether l is a known place for a leaver's hand
+func validLeaverCards(l string) bool {
+	return l == game.LeaverCardsDeal || l == game.LeaverCardsPile || l == game.LeaverCardsDiscard
 }
 
 // Validate ensures settings are within acceptable ranges
@@ -69,8 +601,8 @@ func (s *Settings) Validate() {
 	if s.MaxPlayers < 2 {
 		s.MaxPlayers = 2
 	}
-	if s.MaxPlayers > 8 {
-		s.MaxPlayers = 8
+	if s.MaxPlayers > maxRoomPlayers {
+		s.MaxPlayers = maxRoomPlayers
 	}
 	if s.SlapCooldownMs < 0 {
 		s.SlapCooldownMs = 0
@@ -84,6 +616,60 @@ func (s *Settings) Validate() {
 	if s.TurnTimeoutMs > 60000 {
 		s.TurnTimeoutMs = 60000
 	}
+	if s.AFKStrikes < 0 {
+		s.AFKStrikes = 0
+	}
+	if s.AFKStrikes > maxAFKStrikes {
+		s.AFKStrikes = maxAFKStrikes
+	}
+	if s.SlowModeSeconds < 0 {
+		s.SlowModeSeconds = 0
+	}
+	if s.SlowModeSeconds > maxSlowModeSeconds {
+		s.SlowModeSeconds = maxSlowModeSeconds
+	}
+	if s.PacingMinTimeoutMs < minPacingTimeoutMs {
+		s.PacingMinTimeoutMs = minPacingTimeoutMs
+	}
+	if s.PacingMinTimeoutMs > s.TurnTimeoutMs {
+		s.PacingMinTimeoutMs = s.TurnTimeoutMs
+	}
+	if s.PacingRampCards < 1 {
+		s.PacingRampCards = 1
+	}
+	if s.PacingRampCards > maxPacingRampCards {
+		s.PacingRampCards = maxPacingRampCards
+	}
+	if s.PacingClaimBonusMs < 0 {
+		s.PacingClaimBonusMs = 0
+	}
+	if s.PacingClaimBonusMs > maxPacingClaimBonus {
+		s.PacingClaimBonusMs = maxPacingClaimBonus
+	}
+	if s.AutoPaceStepTurns < minAutoPaceStepTurns {
+		s.AutoPaceStepTurns = minAutoPaceStepTurns
+	}
+	if s.AutoPaceStepTurns > maxAutoPaceStepTurns {
+		s.AutoPaceStepTurns = maxAutoPaceStepTurns
+	}
+	if s.AutoPaceFloorMs < minPacingTimeoutMs {
+		s.AutoPaceFloorMs = minPacingTimeoutMs
+	}
+	if s.AutoPaceFloorMs > s.TurnTimeoutMs {
+		s.AutoPaceFloorMs = s.TurnTimeoutMs
+	}
+	if s.CountdownSeconds < 1 {
+		s.CountdownSeconds = 1
+	}
+	if s.CountdownSeconds > maxCountdownSeconds {
+		s.CountdownSeconds = maxCountdownSeconds
+	}
+	if s.ReadyCheckSeconds < minReadyCheckSeconds {
+		s.ReadyCheckSeconds = minReadyCheckSeconds
+	}
+	if s.ReadyCheckSeconds > maxReadyCheckSeconds {
+		s.ReadyCheckSeconds = maxReadyCheckSeconds
+	}
 	if s.BurnPenalty < 0 {
 		s.BurnPenalty = 0
 	}
@@ -96,4 +682,69 @@ func (s *Settings) Validate() {
 	if s.MaxSlapIns > 10 {
 		s.MaxSlapIns = 10
 	}
+	if s.GameMode != game.ModeClassic && s.GameMode != game.ModeRatscrew {
+		s.GameMode = game.ModeClassic
+	}
+	if !validBurnDestination(s.BurnDestination) {
+		s.BurnDestination = game.BurnToPile
+	}
+	if !validDealRemainder(s.DealRemainder) {
+		s.DealRemainder = game.RemainderDeal
+	}
+	if s.RematchQuorum != RematchAll && s.RematchQuorum != RematchMajority {
+		s.RematchQuorum = RematchAll
+	}
+	if s.PileCap < 0 {
+		s.PileCap = 0
+	}
+	if s.PileCap > 0 && s.PileCap < minPileCap {
+		s.PileCap = minPileCap
+	}
+	if s.PileCap > maxPileCap {
+		s.PileCap = maxPileCap
+	}
+	if s.NumDecks < 1 {
+		s.NumDecks = 1
+	}
+	if s.NumDecks > 3 {
+		s.NumDecks = 3
+	}
+	if validateDeck(s.Deck) != nil {
+		s.Deck = game.DeckSpec{}
+	}
+	if !validDuplicateNames(s.DuplicateNames) {
+		s.DuplicateNames = DuplicateNamesSuffix
+	}
+	if !validWinCondition(s.WinCondition) {
+		s.WinCondition = game.WinLastStanding
+	}
+	if s.WinPiles < 1 {
+		s.WinPiles = 1
+	}
+	if s.WinPiles > maxWinPiles {
+		s.WinPiles = maxWinPiles
+	}
+	if s.TimeLimitSeconds < minTimeLimitSeconds {
+		s.TimeLimitSeconds = minTimeLimitSeconds
+	}
+	if s.TimeLimitSeconds > maxTimeLimitSeconds {
+		s.TimeLimitSeconds = maxTimeLimitSeconds
+	}
+	if !validVisibility(s.Visibility) {
+		s.Visibility = protocol.VisibilityPublic
+	}
+	if !validDisconnectedTurns(s.DisconnectedTurns) {
+		s.DisconnectedTurns = game.DisconnectedSkip
+	}
+	if !validLeaverCards(s.LeaverCards) {
+		s.LeaverCards = game.LeaverCardsDeal
+	}
+	if s.MaxSpectators < 0 {
+		s.MaxSpectators = 0
+	}
+	if s.MaxSpectators > maxSpectatorLimit {
+		s.MaxSpectators = maxSpectatorLimit
+	}
+	s.applyClassroomLimits()
+	s.Preset = s.activePreset()
 }
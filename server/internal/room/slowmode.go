@@ -0,0 +1,28 @@
+package room
+
+import (
+	"time"
+)
+
+// TakeSlowMode records a reaction from a player (or spectator session) under
+// the room's slow mode. If slow mode is on and the last one was too recent,
+// the reaction isn't recorded and the time left to wait is returned.
+func (r *Room) TakeSlowMode(senderID string, now time.Time) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	interval := time.Duration(r.Settings.SlowModeSeconds) * time.Second
+	if interval <= 0 {
+		return 0
+	}
+	if last, ok := r.lastReaction[senderID]; ok {
+		if wait := interval - now.Sub(last); wait > 0 {
+			return wait
+		}
+	}
+	if r.lastReaction == nil {
+		r.lastReaction = make(map[string]time.Time)
+	}
+	r.lastReaction[senderID] = now
+	return 0
+}
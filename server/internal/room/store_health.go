@@ -0,0 +1,232 @@
+package room
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"slapjack/internal/clock"
+)
+
+// How often Redis is checked while it is unreachable, and while it is healthy
+const (
+	storeRetryInterval = 5 * time.Second
+	storeCheckInterval = 30 * time.Second
+)
+
+// storeHealth tracks whether Redis writes are succeeding
+// While Redis is down, rooms only exist in memory and Redis falls behind;
+// once it comes back every room is written again
+type storeHealth struct {
+	down      bool
+	downSince time.Time
+	alerted   bool
+
+	outages       int
+	lastOutage    time.Duration
+	longestOutage time.Duration
+	lastResync    time.Time
+
+	// Rooms deleted while Redis was down, deleted from Redis on recovery
+	pendingDeletes map[string]bool
+
+	clock clock.Clock
+	mu    sync.Mutex
+}
+
+func newStoreHealth(clk clock.Clock) *storeHealth {
+	return &storeHealth{
+		pendingDeletes: make(map[string]bool),
+		clock:          clk,
+	}
+}
+
+// fail records a failed Redis operation
+func (h *storeHealth) fail(op string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.down {
+		return
+	}
+	h.down = true
+	h.downSince = h.clock.Now()
+	h.alerted = false
+	h.outages++
+	slog.Error("Redis unavailable, rooms will be kept in memory until it recovers", "op", op, "error", err)
+}
+
+// recovered records the end of an outage and returns how long it lasted
+func (h *storeHealth) recovered() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	outage := h.clock.Since(h.downSince)
+	h.down = false
+	h.lastOutage = outage
+	if outage > h.longestOutage {
+		h.longestOutage = outage
+	}
+	h.lastResync = h.clock.Now()
+	return outage
+}
+
+func (h *storeHealth) isDown() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.down
+}
+
+// StoreStatus reports Redis health and how long state has diverged from it
+type StoreStatus struct {
+	Enabled              bool    `json:"enabled"`
+	Healthy              bool    `json:"healthy"`
+	DownSince            int64   `json:"downSince,omitempty"`
+	DivergedSeconds      float64 `json:"divergedSeconds"`
+	Outages              int     `json:"outages"`
+	LastOutageSeconds    float64 `json:"lastOutageSeconds"`
+	LongestOutageSeconds float64 `json:"longestOutageSeconds"`
+	LastResync           int64   `json:"lastResync,omitempty"`
+	PendingDeletes       int     `json:"pendingDeletes"`
+	DirtyRooms           int     `json:"dirtyRooms"`     // Waiting for the next flush
+	QueuedWrites         int     `json:"queuedWrites"`   // Waiting for the writer
+	DroppedWrites        uint64  `json:"droppedWrites"`  // Lost to a full queue
+	DeferredWrites       uint64  `json:"deferredWrites"` // Room writes left for a later flush
+}
+
+// GetStoreStatus returns Redis health metrics
+func (m *Manager) GetStoreStatus() StoreStatus {
+	if m.store == nil {
+		return StoreStatus{}
+	}
+
+	dirty := m.storeWriter.pending()
+	h := m.storeHealth
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := StoreStatus{
+		Enabled:              true,
+		DirtyRooms:           dirty,
+		QueuedWrites:         len(m.storeWriter.queue),
+		DroppedWrites:        m.storeWriter.skipped.With(skipDropped).Value(),
+		DeferredWrites:       m.storeWriter.skipped.With(skipDeferred).Value(),
+		Healthy:              !h.down,
+		Outages:              h.outages,
+		LastOutageSeconds:    h.lastOutage.Seconds(),
+		LongestOutageSeconds: h.longestOutage.Seconds(),
+		PendingDeletes:       len(h.pendingDeletes),
+	}
+	if h.down {
+		status.DownSince = h.downSince.UnixMilli()
+		status.DivergedSeconds = h.clock.Since(h.downSince).Seconds()
+	}
+	if !h.lastResync.IsZero() {
+		status.LastResync = h.lastResync.UnixMilli()
+	}
+	return status
+}
+
+// roomTTL is how long Redis keeps a room, which for a scheduled room is
+// counted from its start
+func (m *Manager) roomTTL(room *Room) time.Duration {
+	ttl := m.cfg.Get().RoomTTL
+	room.mu.RLock()
+	defer room.mu.RUnlock()
+	if !room.ScheduledAt.IsZero() {
+		ttl += room.ScheduledAt.Sub(m.clock.Now())
+	}
+	return ttl
+}
+
+// storeHealthRoutine watches Redis and resyncs it after an outage
+func (m *Manager) storeHealthRoutine() {
+	for {
+		if m.storeHealth.isDown() {
+			m.clock.Sleep(storeRetryInterval)
+		} else {
+			m.clock.Sleep(storeCheckInterval)
+		}
+
+		if err := m.store.Ping(context.Background()); err != nil {
+			m.storeHealth.fail("ping", err)
+			m.alertLongOutage()
+			continue
+		}
+		if m.storeHealth.isDown() {
+			m.resyncStore()
+		}
+	}
+}
+
+// alertLongOutage logs once per outage when it runs past the configured threshold
+func (m *Manager) alertLongOutage() {
+	h := m.storeHealth
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.alerted || m.cfg.Get().StoreOutageAlert <= 0 {
+		return
+	}
+	if downFor := h.clock.Since(h.downSince); downFor >= m.cfg.Get().StoreOutageAlert {
+		h.alerted = true
+		slog.Error("ALERT: Redis outage ongoing, state is diverging", "downFor", downFor.Round(time.Second).String())
+	}
+}
+
+// resyncStore writes every in-memory room back to Redis and applies deletes
+// queued during the outage. The outage only ends if every write succeeds.
+func (m *Manager) resyncStore() {
+	m.storeHealth.mu.Lock()
+	deletes := m.storeHealth.pendingDeletes
+	m.storeHealth.pendingDeletes = make(map[string]bool)
+	m.storeHealth.mu.Unlock()
+
+	ctx := context.Background()
+	failed := 0
+	for code := range deletes {
+		if err := m.store.DeleteRoom(ctx, code); err != nil {
+			failed++
+			m.storeHealth.mu.Lock()
+			m.storeHealth.pendingDeletes[code] = true
+			m.storeHealth.mu.Unlock()
+		}
+	}
+
+	// Copied so Redis isn't written while holding the rooms lock
+	m.mu.RLock()
+	rooms := make(map[string]*Room, len(m.rooms))
+	for code, room := range m.rooms {
+		rooms[code] = room
+	}
+	m.mu.RUnlock()
+
+	for code, room := range rooms {
+		// Rooms deleted since are left deleted
+		if m.GetRoom(code) != room {
+			continue
+		}
+		ttl := m.roomTTL(room)
+		if err := m.store.AddActiveRoom(ctx, code); err != nil {
+			failed++
+		}
+		if err := m.store.SetRoom(ctx, code, room.Snapshot(), ttl); err != nil {
+			failed++
+		}
+		if room.Game != nil {
+			if err := m.store.SetGameState(ctx, code, room.Game.GetObserverState(), ttl); err != nil {
+				failed++
+			}
+		}
+	}
+	count := len(rooms)
+
+	if failed > 0 {
+		slog.Warn("Redis resync incomplete, will retry", "failed", failed)
+		return
+	}
+
+	outage := m.storeHealth.recovered()
+	slog.Info("Redis recovered, state resynced", "rooms", count, "deletes", len(deletes), "divergedFor", outage.Round(time.Millisecond).String())
+}
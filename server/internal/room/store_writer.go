@@ -0,0 +1,266 @@
+package room
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"slapjack/internal/config"
+	"slapjack/internal/metrics"
+)
+
+// Writes waiting for Redis; once this many are queued, room writes wait for
+// the next flush and other writes are dropped, so callers never block
+const storeQueueSize = 1024
+
+// What happened to a write the queue had no space for
+const (
+	skipDropped  = "dropped"
+	skipDeferred = "deferred"
+)
+
+// How often changed rooms are checked for while RoomSaveInterval is 0 and
+// they are written as they change; catches any left over by a reload
+const immediateSaveCheckInterval = time.Second
+
+// storeWrite is a Redis write queued from a handler or the game loop
+type storeWrite struct {
+	op    string
+	write func(ctx context.Context) error
+	done  chan struct{} // Closed once everything queued before it is written
+}
+
+// storeWriter makes Redis writes from a single background worker, in the
+// order they were queued, so players never wait on a Redis round trip.
+// Rooms are written behind: a changed room is only marked dirty, and
+// however often it changes it is written once per flush.
+type storeWriter struct {
+	queue  chan storeWrite
+	health *storeHealth
+	cfg    *config.Live
+
+	// Writes the queue had no space for, by whether they were dropped or
+	// left for the next flush
+	skipped *metrics.CounterVec
+
+	dirty   map[string]*Room // Rooms changed since the last flush, by code
+	deletes map[string]bool  // Room deletes that didn't fit in the queue
+	mu      sync.Mutex       // Guards dirty and deletes
+}
+
+func newStoreWriter(health *storeHealth, cfg *config.Live) *storeWriter {
+	return &storeWriter{
+		queue:  make(chan storeWrite, storeQueueSize),
+		health: health,
+		cfg:    cfg,
+		skipped: metrics.NewCounterVec("slapjack_redis_writes_skipped_total",
+			"Redis writes the write queue had no space for", "outcome"),
+		dirty:   make(map[string]*Room),
+		deletes: make(map[string]bool),
+	}
+}
+
+// enqueue queues a write, noting an outage if it fails
+// If the queue is full the write is dropped rather than waited on
+func (w *storeWriter) enqueue(op string, write func(ctx context.Context) error) {
+	select {
+	case w.queue <- storeWrite{op: op, write: write}:
+	default:
+		w.skipped.With(skipDropped).Inc()
+		slog.Warn("Redis write queue full, dropping write", "op", op)
+	}
+}
+
+// tryEnqueue queues a write unless the queue is full, returning whether it did
+// Callers keep a write that didn't fit to queue again at the next flush
+func (w *storeWriter) tryEnqueue(op string, write func(ctx context.Context) error) bool {
+	select {
+	case w.queue <- storeWrite{op: op, write: write}:
+		return true
+	default:
+		w.skipped.With(skipDeferred).Inc()
+		return false
+	}
+}
+
+// markDirty notes that a room needs writing at the next flush
+func (w *storeWriter) markDirty(code string, room *Room) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.dirty[code] = room
+}
+
+// pending reports how many rooms are waiting for the next flush
+func (w *storeWriter) pending() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.dirty)
+}
+
+// flush waits until every write queued so far has been made
+func (w *storeWriter) flush() {
+	done := make(chan struct{})
+	w.queue <- storeWrite{done: done}
+	<-done
+}
+
+// run makes queued writes until the process exits
+func (w *storeWriter) run() {
+	for sw := range w.queue {
+		if sw.write != nil {
+			w.write(sw)
+		}
+		if sw.done != nil {
+			close(sw.done)
+		}
+	}
+}
+
+// write makes one queued write, timed out from when it starts rather than
+// from when it was queued
+func (w *storeWriter) write(sw storeWrite) {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeout := w.cfg.Get().RedisTimeout; timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	if err := sw.write(ctx); err != nil {
+		w.health.fail(sw.op, err)
+	}
+}
+
+// WriteStoreMetrics writes the Redis writer's metrics in the Prometheus
+// text format
+func (m *Manager) WriteStoreMetrics(w io.Writer) error {
+	return m.storeWriter.skipped.Write(w)
+}
+
+// persist queues a Redis write, if the server has Redis
+func (m *Manager) persist(op string, write func(ctx context.Context) error) {
+	if m.store == nil {
+		return
+	}
+	m.storeWriter.enqueue(op, write)
+}
+
+// saveRoom marks a room to be written to Redis at the next flush, or queues
+// the write straight away if rooms aren't written behind
+func (m *Manager) saveRoom(code string, room *Room) {
+	if m.store == nil {
+		return
+	}
+	m.storeWriter.markDirty(code, room)
+	if m.cfg.Get().RoomSaveInterval <= 0 {
+		m.flushRooms()
+	}
+}
+
+// flushRooms queues a write of each room changed since the last flush, and
+// any deletes that didn't fit in the queue before. Each room is copied when
+// the flush picks it up, so the writer never reads a room handlers are
+// changing, and a room the queue has no space for is left dirty until the
+// next flush rather than waited on.
+func (m *Manager) flushRooms() {
+	type roomSave struct {
+		code string
+		room *Room
+		snap *Room
+		ttl  time.Duration
+	}
+
+	w := m.storeWriter
+	m.mu.RLock()
+	w.mu.Lock()
+	for code := range w.deletes {
+		if !w.tryEnqueue("delete room "+code, m.deleteWrite(code)) {
+			break
+		}
+		delete(w.deletes, code)
+	}
+	saves := make([]roomSave, 0, len(w.dirty))
+	for code, room := range w.dirty {
+		delete(w.dirty, code)
+		if m.rooms[code] != room {
+			continue
+		}
+		saves = append(saves, roomSave{code: code, room: room, snap: room.Snapshot(), ttl: m.roomTTL(room)})
+	}
+	w.mu.Unlock()
+	m.mu.RUnlock()
+
+	for _, s := range saves {
+		s := s
+		queued := w.tryEnqueue("save room "+s.code, func(ctx context.Context) error {
+			// Rooms are deleted before their delete is queued, so a room
+			// deleted since the flush is skipped rather than brought back
+			if m.GetRoom(s.code) != s.room {
+				return nil
+			}
+			return m.store.SetRoom(ctx, s.code, s.snap, s.ttl)
+		})
+		if !queued {
+			w.requeue(s.code, s.room)
+		}
+	}
+}
+
+// requeue marks a room dirty again after the queue had no space for it,
+// unless it has changed again since
+func (w *storeWriter) requeue(code string, room *Room) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.dirty[code]; !ok {
+		w.dirty[code] = room
+	}
+}
+
+// roomSaveRoutine writes changed rooms to Redis every RoomSaveInterval
+func (m *Manager) roomSaveRoutine() {
+	for {
+		interval := m.cfg.Get().RoomSaveInterval
+		if interval <= 0 {
+			interval = immediateSaveCheckInterval
+		}
+		m.clock.Sleep(interval)
+		m.flushRooms()
+	}
+}
+
+// deleteStoredRoom queues removing a room from Redis, dropping any write of
+// it still waiting for a flush; if the queue is full the delete is queued by
+// the next flush, and if Redis is down it waits for it to recover
+func (m *Manager) deleteStoredRoom(code string) {
+	if m.store == nil {
+		return
+	}
+	w := m.storeWriter
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.dirty, code)
+	if !w.tryEnqueue("delete room "+code, m.deleteWrite(code)) {
+		w.deletes[code] = true
+	}
+}
+
+// deleteWrite removes a room from Redis, or leaves it for the resync after
+// an outage
+func (m *Manager) deleteWrite(code string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if !m.storeHealth.isDown() {
+			err := m.store.DeleteRoom(ctx, code)
+			if err == nil {
+				return nil
+			}
+			m.storeHealth.fail("delete room "+code, err)
+		}
+
+		m.storeHealth.mu.Lock()
+		m.storeHealth.pendingDeletes[code] = true
+		m.storeHealth.mu.Unlock()
+		return nil
+	}
+}
@@ -0,0 +1,134 @@
+package room
+
+import (
+	"log/slog"
+	"sync"
+
+	"slapjack/internal/game"
+	"slapjack/pkg/protocol"
+)
+
+// Most recent flags of each kind kept per room
+const maxSuspicionsPerRoom = 100
+
+// reviewLog keeps flagged play per room for host and admin review
+type reviewLog[T any] struct {
+	flags map[string][]T
+	mu    sync.Mutex
+}
+
+func newReviewLog[T any]() *reviewLog[T] {
+	return &reviewLog[T]{
+		flags: make(map[string][]T),
+	}
+}
+
+func (l *reviewLog[T]) add(roomCode string, flags []T) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := append(l.flags[roomCode], flags...)
+	if len(entries) > maxSuspicionsPerRoom {
+		entries = entries[len(entries)-maxSuspicionsPerRoom:]
+	}
+	l.flags[roomCode] = entries
+}
+
+func (l *reviewLog[T]) remove(roomCode string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.flags, roomCode)
+}
+
+// any reports whether a room's log has an entry matching match
+func (l *reviewLog[T]) any(roomCode string, match func(T) bool) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, flag := range l.flags[roomCode] {
+		if match(flag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *reviewLog[T]) all() map[string][]T {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := make(map[string][]T, len(l.flags))
+	for code, flags := range l.flags {
+		result[code] = append([]T(nil), flags...)
+	}
+	return result
+}
+
+// CollectSuspicions moves any new suspicion flags from a game into the room's log
+// Returns the new flags so they can be shown to the host
+func (m *Manager) CollectSuspicions(roomCode string, g *game.Game) []protocol.SuspicionFlag {
+	flags := g.DrainSuspicions()
+	if len(flags) == 0 {
+		return nil
+	}
+
+	for _, flag := range flags {
+		slog.Warn("suspicion flagged", "roomCode", roomCode, "playerId", flag.PlayerID, "otherPlayerId", flag.OtherPlayerID, "kind", flag.Kind, "detail", flag.Detail)
+	}
+	m.suspicions.add(roomCode, flags)
+	return flags
+}
+
+// GetSuspicions returns the flagged patterns for every room
+func (m *Manager) GetSuspicions() map[string][]protocol.SuspicionFlag {
+	return m.suspicions.all()
+}
+
+// CollectCheatWarnings moves any new reaction time warnings from a game into the room's log
+// Returns the new warnings so they can be shown to the host
+func (m *Manager) CollectCheatWarnings(roomCode string, g *game.Game) []protocol.CheatWarning {
+	warnings := g.DrainCheatWarnings()
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	for _, warning := range warnings {
+		slog.Warn("cheat warning", "roomCode", roomCode, "playerId", warning.PlayerID, "kind", warning.Kind, "detail", warning.Detail)
+	}
+	m.cheatWarnings.add(roomCode, warnings)
+	return warnings
+}
+
+// CheatReport is the reaction time review for one room
+type CheatReport struct {
+	Warnings  []protocol.CheatWarning     `json:"warnings"`
+	Reactions []protocol.ReactionAnalysis `json:"reactions,omitempty"` // Current game only
+}
+
+// GetCheatReports returns cheat warnings for every room, with the reaction
+// analysis of games still in progress
+func (m *Manager) GetCheatReports() map[string]CheatReport {
+	reports := make(map[string]CheatReport)
+	for code, warnings := range m.cheatWarnings.all() {
+		reports[code] = CheatReport{Warnings: warnings}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for code, room := range m.rooms {
+		if room.Game == nil {
+			continue
+		}
+		reactions := room.Game.ReactionAnalysis()
+		if len(reactions) == 0 {
+			continue
+		}
+		report := reports[code]
+		if report.Warnings == nil {
+			report.Warnings = []protocol.CheatWarning{}
+		}
+		report.Reactions = reactions
+		reports[code] = report
+	}
+	return reports
+}
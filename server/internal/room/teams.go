@@ -0,0 +1,136 @@
+package room
+
+import (
+	"errors"
+	"sort"
+)
+
+// Teams in a team game are numbered from 1
+const numTeams = 2
+
+var (
+	ErrTeamsOff    = errors.New("this room isn't playing in teams")
+	ErrInvalidTeam = errors.New("team must be 1 or 2")
+	ErrTeamsInGame = errors.New("teams can't change while a game is on")
+	ErrTeamsUneven = errors.New("each team needs at least one player")
+)
+
+// SetTeam moves a player to a team, between games
+func (r *Room) SetTeam(playerID string, team int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.Settings.Teams {
+		return ErrTeamsOff
+	}
+	if team < 1 || team > numTeams {
+		return ErrInvalidTeam
+	}
+	if r.Status == "starting" || r.Status == "playing" {
+		return ErrTeamsInGame
+	}
+	p, ok := r.Players[playerID]
+	if !ok {
+		return errors.New("player not found")
+	}
+	if p.TeamID != team {
+		p.TeamID = team
+		r.version++
+	}
+	return nil
+}
+
+// TeamAssignments returns every player's team, or nil if the room isn't
+// playing in teams
+func (r *Room) TeamAssignments() map[string]int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.Settings.Teams {
+		return nil
+	}
+	teams := make(map[string]int, len(r.Players))
+	for id, p := range r.Players {
+		teams[id] = p.TeamID
+	}
+	return teams
+}
+
+// TeamsReady returns ErrTeamsUneven if the room plays in teams and one of
+// them has no connected player
+func (r *Room) TeamsReady() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.Settings.Teams {
+		return nil
+	}
+	var ids []string
+	for id, p := range r.Players {
+		if p.IsConnected {
+			ids = append(ids, id)
+		}
+	}
+	if r.gameTeams(ids) == nil {
+		return ErrTeamsUneven
+	}
+	return nil
+}
+
+// gameTeams returns the teams of the players starting a game, or nil if the
+// room isn't playing in teams or they don't make up every team, in which
+// case the game is every player for themselves
+// Caller must hold r.mu
+func (r *Room) gameTeams(playerIDs []string) map[string]int {
+	if !r.Settings.Teams {
+		return nil
+	}
+	teams := make(map[string]int, len(playerIDs))
+	seen := make(map[int]bool)
+	for _, id := range playerIDs {
+		if p, ok := r.Players[id]; ok && p.TeamID != 0 {
+			teams[id] = p.TeamID
+			seen[p.TeamID] = true
+		}
+	}
+	if len(seen) < numTeams || len(teams) < len(playerIDs) {
+		return nil
+	}
+	return teams
+}
+
+// smallestTeam returns the team with the fewest players, the first on a tie
+// Caller must hold r.mu
+func (r *Room) smallestTeam() int {
+	sizes := make([]int, numTeams+1)
+	for _, p := range r.Players {
+		if p.TeamID >= 1 && p.TeamID <= numTeams {
+			sizes[p.TeamID]++
+		}
+	}
+	smallest := 1
+	for team := 2; team <= numTeams; team++ {
+		if sizes[team] < sizes[smallest] {
+			smallest = team
+		}
+	}
+	return smallest
+}
+
+// assignTeams splits the players into teams alternately by seat when teams
+// are turned on, and clears their teams when turned off
+// Caller must hold r.mu
+func (r *Room) assignTeams() {
+	players := make([]*Player, 0, len(r.Players))
+	for _, p := range r.Players {
+		players = append(players, p)
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].Position < players[j].Position })
+
+	for i, p := range players {
+		p.TeamID = 0
+		if r.Settings.Teams {
+			p.TeamID = i%numTeams + 1
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package room
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"slapjack/pkg/protocol"
+)
+
+// CheatMisreportedTelemetry flags a client reporting more lag than the server
+// saw, as a client angling for latency allowances would
+const CheatMisreportedTelemetry = "telemetry_misreport"
+
+const (
+	// Reported timings above this aren't plausible measurements
+	maxTelemetryMs = 5000
+	maxDeviceBytes = 32
+
+	// A reported round trip may exceed the measured one by this factor plus slack,
+	// since the two are taken at different moments over different paths
+	telemetryRTTFactor  = 1.5
+	telemetryRTTSlackMs = 50
+
+	// Slaps needed before reported lag is checked against reaction times,
+	// and how far the lag may exceed the fastest of them
+	minTelemetrySlaps    = 3
+	telemetrySlapSlackMs = 30
+)
+
+var ErrInvalidTelemetry = errors.New("telemetry timings must be between 0 and 5000ms")
+
+// RecordTelemetry validates a client's reported timings against what the
+// server observed of it, and stores both
+// measuredRTTMs is the server's smoothed round trip to the client, or 0 if unknown
+// Returns a cheat warning the first time a player is caught misreporting in a room
+func (m *Manager) RecordTelemetry(roomCode, playerID string, reported protocol.ReportTelemetryPayload, measuredRTTMs int64) (protocol.TelemetryReport, *protocol.CheatWarning, error) {
+	for _, ms := range []int64{reported.RenderLatencyMs, reported.InputLagMs, reported.RTTMs} {
+		if ms < 0 || ms > maxTelemetryMs {
+			return protocol.TelemetryReport{}, nil, ErrInvalidTelemetry
+		}
+	}
+	reported.Device = strings.TrimSpace(reported.Device)
+	if len(reported.Device) > maxDeviceBytes {
+		reported.Device = reported.Device[:maxDeviceBytes]
+	}
+
+	report := protocol.TelemetryReport{
+		PlayerID:      playerID,
+		Reported:      reported,
+		ObservedRTTMs: measuredRTTMs,
+		Timestamp:     m.clock.Now().UnixMilli(),
+	}
+
+	var reactions protocol.ReactionAnalysis
+	if room := m.GetRoom(roomCode); room != nil && room.Game != nil {
+		if analysis, ok := room.Game.PlayerReactions(playerID); ok {
+			reactions = analysis
+			report.ObservedFastestMs = analysis.MinMs
+			report.ObservedSlaps = analysis.Samples
+		}
+	}
+	report.Detail = telemetryMismatch(report)
+	report.Flagged = report.Detail != ""
+
+	// Checked before adding, so only the first misreport warns
+	alreadyFlagged := report.Flagged && m.telemetry.any(roomCode, func(r protocol.TelemetryReport) bool {
+		return r.Flagged && r.PlayerID == playerID
+	})
+	m.telemetry.add(roomCode, []protocol.TelemetryReport{report})
+
+	if !report.Flagged || alreadyFlagged {
+		return report, nil, nil
+	}
+
+	reactions.PlayerID = playerID
+	warning := protocol.CheatWarning{
+		Kind:      CheatMisreportedTelemetry,
+		PlayerID:  playerID,
+		Detail:    report.Detail,
+		Analysis:  reactions,
+		Timestamp: report.Timestamp,
+	}
+	slog.Warn("cheat warning", "roomCode", roomCode, "playerId", playerID, "kind", warning.Kind, "detail", warning.Detail)
+	m.cheatWarnings.add(roomCode, []protocol.CheatWarning{warning})
+	return report, &warning, nil
+}
+
+// telemetryMismatch describes how reported timings contradict the server's, or
+// returns "" if they are plausible
+func telemetryMismatch(r protocol.TelemetryReport) string {
+	if r.Reported.RTTMs > 0 && r.ObservedRTTMs > 0 {
+		limit := int64(float64(r.ObservedRTTMs)*telemetryRTTFactor) + telemetryRTTSlackMs
+		if r.Reported.RTTMs > limit {
+			return fmt.Sprintf("reported a %dms round trip, measured %dms", r.Reported.RTTMs, r.ObservedRTTMs)
+		}
+	}
+
+	// Every slap the server sees took at least the client's render and input
+	// lag plus the round trip, so a faster slap proves the lag overstated
+	if r.ObservedSlaps >= minTelemetrySlaps {
+		lag := r.Reported.RenderLatencyMs + r.Reported.InputLagMs
+		if lag+r.ObservedRTTMs > r.ObservedFastestMs+telemetrySlapSlackMs {
+			return fmt.Sprintf("reported %dms of render and input lag, but slapped %dms after a card with a %dms round trip",
+				lag, r.ObservedFastestMs, r.ObservedRTTMs)
+		}
+	}
+	return ""
+}
+
+// GetTelemetry returns the telemetry reports for every room
+func (m *Manager) GetTelemetry() map[string][]protocol.TelemetryReport {
+	return m.telemetry.all()
+}
@@ -0,0 +1,194 @@
+package room
+
+import (
+	"errors"
+
+	"slapjack/internal/game"
+	"slapjack/internal/locale"
+	"slapjack/pkg/protocol"
+)
+
+var ErrUnknownVariant = errors.New("unknown rule variant")
+
+// variant is a named set of rules a room can be created with
+type variant struct {
+	id         string
+	minPlayers int
+	maxPlayers int
+	feature    string // Feature flag the variant needs, if any
+
+	// Name and description by language
+	names        map[string]string
+	descriptions map[string]string
+
+	// Changes from the default settings
+	apply func(*Settings)
+}
+
+var variants = []variant{
+	{
+		id:         protocol.VariantClassic,
+		minPlayers: 2,
+		maxPlayers: maxRoomPlayers,
+		names: map[string]string{
+			"en": "Classic Slapjack",
+			"es": "Slapjack clásico",
+			"fr": "Slapjack classique",
+			"de": "Klassisches Slapjack",
+		},
+		descriptions: map[string]string{
+			"en": "Take turns flipping cards and slap every jack, double and sandwich to win the pile.",
+			"es": "Voltead cartas por turnos y golpead cada jota, pareja y sándwich para ganar el montón.",
+			"fr": "Retournez les cartes à tour de rôle et tapez sur chaque valet, double et sandwich pour gagner la pile.",
+			"de": "Deckt reihum Karten auf und schlagt auf jeden Buben, jedes Paar und jedes Sandwich, um den Stapel zu gewinnen.",
+		},
+		apply: func(s *Settings) {},
+	},
+	{
+		id:         protocol.VariantERS,
+		minPlayers: 2,
+		maxPlayers: 6,
+		feature:    protocol.FeatureRatscrew,
+		names: map[string]string{
+			"en": "Egyptian Ratscrew",
+			"es": "Ratscrew egipcio",
+			"fr": "Ratscrew égyptien",
+			"de": "Ägyptischer Ratscrew",
+		},
+		descriptions: map[string]string{
+			"en": "Face cards challenge the next player to answer with a face card of their own, and any slap rule can steal the pile.",
+			"es": "Las figuras retan al siguiente jugador a responder con otra figura, y cualquier regla de golpe puede robar el montón.",
+			"fr": "Les figures défient le joueur suivant de répondre par une figure, et toute règle de tape peut voler la pile.",
+			"de": "Bildkarten fordern den nächsten Spieler heraus, mit einer eigenen Bildkarte zu antworten, und jede Schlagregel kann den Stapel stehlen.",
+		},
+		apply: func(s *Settings) {
+			s.GameMode = game.ModeRatscrew
+			s.EnableTopBottom = true
+		},
+	},
+	{
+		id:         protocol.VariantBeggar,
+		minPlayers: 2,
+		maxPlayers: 4,
+		feature:    protocol.FeatureRatscrew,
+		names: map[string]string{
+			"en": "Beggar-my-neighbour",
+			"es": "Arruina a tu vecino",
+			"fr": "Bataille corse simplifiée",
+			"de": "Bettelmann",
+		},
+		descriptions: map[string]string{
+			"en": "The old face card battle, with just the jack to slap: win the pile by answering challenges, or by being fastest to a jack.",
+			"es": "La vieja batalla de figuras, con solo la jota para golpear: gana el montón respondiendo a los retos o siendo el más rápido en una jota.",
+			"fr": "La vieille bataille de figures, avec seulement le valet à taper : gagnez la pile en relevant les défis ou en étant le plus rapide sur un valet.",
+			"de": "Das alte Bildkartenduell, nur der Bube wird geschlagen: Gewinnt den Stapel, indem ihr Herausforderungen beantwortet oder am schnellsten auf einen Buben schlagt.",
+		},
+		apply: func(s *Settings) {
+			s.GameMode = game.ModeRatscrew
+			s.EnableDoubles = false
+			s.EnableSandwich = false
+			s.EnableSlapIn = false
+		},
+	},
+}
+
+// VariantLanguage picks the supported language closest to the given language
+// codes or Accept-Language headers, in order of preference, or English;
+// variant names are written in the same languages as server messages
+func VariantLanguage(preferences ...string) string {
+	return locale.Match(preferences...)
+}
+
+// settings returns the variant's settings, starting from the defaults
+func (v variant) settings() Settings {
+	s := DefaultSettings()
+	v.apply(&s)
+	s.clampPlayers(v.maxPlayers)
+	return s
+}
+
+// toProtocol describes the variant in a language
+func (v variant) toProtocol(lang string) protocol.RuleVariant {
+	name, ok := v.names[lang]
+	if !ok {
+		name = v.names["en"]
+	}
+	description, ok := v.descriptions[lang]
+	if !ok {
+		description = v.descriptions["en"]
+	}
+	return protocol.RuleVariant{
+		ID:          v.id,
+		Name:        name,
+		Description: description,
+		MinPlayers:  v.minPlayers,
+		MaxPlayers:  v.maxPlayers,
+		Settings:    v.settings().ToProtocol(),
+	}
+}
+
+// findVariant looks up a variant by ID
+func findVariant(id string) (variant, bool) {
+	for _, v := range variants {
+		if v.id == id {
+			return v, true
+		}
+	}
+	return variant{}, false
+}
+
+// variant looks up a variant that is switched on by ID
+func (m *Manager) variant(id string) (variant, bool) {
+	v, ok := findVariant(id)
+	if !ok || (v.feature != "" && !m.cfg.Get().FeatureEnabled(v.feature)) {
+		return variant{}, false
+	}
+	return v, true
+}
+
+// Variants lists the rule variants rooms can be created with, described in lang
+func (m *Manager) Variants(lang string) []protocol.RuleVariant {
+	list := make([]protocol.RuleVariant, 0, len(variants))
+	for _, v := range variants {
+		if v, ok := m.variant(v.id); ok {
+			list = append(list, v.toProtocol(lang))
+		}
+	}
+	return list
+}
+
+// applyVariant sets up a new room's rules from a variant
+func (r *Room) applyVariant(v variant) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.version++
+
+	r.Variant = v.id
+	r.Settings = v.settings()
+}
+
+// MinPlayers returns how many players the room needs to start a game
+func (r *Room) MinPlayers() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if v, ok := findVariant(r.Variant); ok {
+		return v.minPlayers
+	}
+	return 2
+}
+
+// variantMaxPlayers returns the most players the room's variant allows, or 0
+// for rooms without one
+// Caller must hold r.mu
+func (r *Room) variantMaxPlayers() int {
+	v, _ := findVariant(r.Variant)
+	return v.maxPlayers
+}
+
+// clampPlayers keeps MaxPlayers within a variant's limit, if it has one
+func (s *Settings) clampPlayers(limit int) {
+	if limit > 0 && s.MaxPlayers > limit {
+		s.MaxPlayers = limit
+	}
+}
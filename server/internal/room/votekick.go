@@ -0,0 +1,262 @@
+package room
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	"slapjack/pkg/protocol"
+)
+
+// Kick vote limits
+const (
+	kickVoteTimeout    = 30 * time.Second
+	kickVoteCooldown   = time.Minute     // Between votes one player starts
+	kickVoteImmunity   = 2 * time.Minute // Before a player who survived a vote faces another
+	kickVoteBanMinutes = 10              // How long a voted out player is kept out
+	minKickVoters      = 2
+)
+
+var (
+	ErrNoKickVote     = errors.New("no kick vote is running against that player")
+	ErrKickVoteActive = errors.New("another kick vote is already running")
+	ErrNotVoter       = errors.New("you can't vote on this kick")
+	ErrVoteSelf       = errors.New("you can't vote to kick yourself")
+	ErrVoteHost       = errors.New("this room doesn't allow voting out the host")
+	ErrTooFewVoters   = errors.New("not enough players to hold a kick vote")
+	ErrVoteCooldown   = errors.New("wait a minute before starting another kick vote")
+	ErrVoteImmune     = errors.New("that player survived a kick vote moments ago")
+)
+
+// kickVote is a running vote to kick a player
+type kickVote struct {
+	targetID  string
+	startedBy string
+	voters    map[string]bool // Who may vote
+	votes     map[string]bool // Yes or no, by voter
+	needed    int
+	done      chan struct{} // Closed once decided
+}
+
+// tally counts the votes cast
+func (v *kickVote) tally() (yes, no int) {
+	for _, vote := range v.votes {
+		if vote {
+			yes++
+		} else {
+			no++
+		}
+	}
+	return yes, no
+}
+
+// outcome returns the vote's result, or "" if it's still undecided
+func (v *kickVote) outcome() string {
+	yes, no := v.tally()
+	if yes >= v.needed {
+		return protocol.VoteResultPassed
+	}
+	if len(v.voters)-no < v.needed {
+		return protocol.VoteResultFailed
+	}
+	return ""
+}
+
+// voteKick starts a kick vote against a player, or casts a vote on the one
+// running against them
+// Returns the vote, whether this started it, and its result once decided
+func (r *Room) voteKick(voterID, targetID string, yes bool, now time.Time) (*kickVote, bool, *protocol.VoteResultPayload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v := r.kickVote
+	if v == nil || v.targetID != targetID {
+		if !yes {
+			return nil, false, nil, ErrNoKickVote
+		}
+		v, err := r.startKickVote(voterID, targetID, now)
+		return v, err == nil, nil, err
+	}
+
+	if !v.voters[voterID] {
+		return nil, false, nil, ErrNotVoter
+	}
+	v.votes[voterID] = yes
+
+	if _, ok := r.Players[targetID]; !ok {
+		return v, false, r.endKickVote(protocol.VoteResultCancelled, now), nil
+	}
+	if result := v.outcome(); result != "" {
+		return v, false, r.endKickVote(result, now), nil
+	}
+	return v, false, nil, nil
+}
+
+// startKickVote opens a vote to kick a player, counting the starter's yes
+// Every connected player but the host and the target may vote
+// Caller must hold r.mu
+func (r *Room) startKickVote(starterID, targetID string, now time.Time) (*kickVote, error) {
+	if r.kickVote != nil {
+		return nil, ErrKickVoteActive
+	}
+	if _, ok := r.Players[starterID]; !ok || starterID == r.HostID {
+		return nil, ErrNotVoter
+	}
+	if starterID == targetID {
+		return nil, ErrVoteSelf
+	}
+	if _, ok := r.Players[targetID]; !ok {
+		return nil, errors.New("player not found")
+	}
+	if targetID == r.HostID && !r.Settings.VoteKickHost {
+		return nil, ErrVoteHost
+	}
+	if last, ok := r.voteStarted[starterID]; ok && now.Sub(last) < kickVoteCooldown {
+		return nil, ErrVoteCooldown
+	}
+	if last, ok := r.voteSurvived[targetID]; ok && now.Sub(last) < kickVoteImmunity {
+		return nil, ErrVoteImmune
+	}
+
+	voters := make(map[string]bool)
+	for id, p := range r.Players {
+		if p.IsConnected && id != r.HostID && id != targetID {
+			voters[id] = true
+		}
+	}
+	if len(voters) < minKickVoters {
+		return nil, ErrTooFewVoters
+	}
+
+	if r.voteStarted == nil {
+		r.voteStarted = make(map[string]time.Time)
+	}
+	r.voteStarted[starterID] = now
+	r.kickVote = &kickVote{
+		targetID:  targetID,
+		startedBy: starterID,
+		voters:    voters,
+		votes:     map[string]bool{starterID: true},
+		needed:    len(voters)/2 + 1,
+		done:      make(chan struct{}),
+	}
+	return r.kickVote, nil
+}
+
+// endKickVote closes the running vote with a result; a player voted out is
+// banned for a while, so they can't walk straight back in
+// Caller must hold r.mu
+func (r *Room) endKickVote(result string, now time.Time) *protocol.VoteResultPayload {
+	v := r.kickVote
+	r.kickVote = nil
+	close(v.done)
+
+	if result == protocol.VoteResultPassed {
+		if p, ok := r.Players[v.targetID]; ok {
+			r.ban(p, kickVoteBanMinutes, now, "")
+			r.votedOut = v.targetID
+		}
+	} else {
+		if r.voteSurvived == nil {
+			r.voteSurvived = make(map[string]time.Time)
+		}
+		r.voteSurvived[v.targetID] = now
+	}
+
+	yes, no := v.tally()
+	return &protocol.VoteResultPayload{
+		TargetID: v.targetID,
+		Result:   result,
+		Yes:      yes,
+		No:       no,
+		Needed:   v.needed,
+	}
+}
+
+// expireKickVote fails a vote that ran out of time, returning its result,
+// or nil if it was already decided
+func (r *Room) expireKickVote(v *kickVote, now time.Time) *protocol.VoteResultPayload {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.kickVote != v {
+		return nil
+	}
+	return r.endKickVote(protocol.VoteResultExpired, now)
+}
+
+// startedPayload describes a vote that has just opened
+// Caller must hold r.mu
+func (r *Room) startedPayload(v *kickVote) protocol.VoteStartedPayload {
+	voters := make([]string, 0, len(v.voters))
+	for id := range v.voters {
+		voters = append(voters, id)
+	}
+	sort.Strings(voters)
+
+	targetName := ""
+	if p, ok := r.Players[v.targetID]; ok {
+		targetName = p.Name
+	}
+	return protocol.VoteStartedPayload{
+		TargetID:       v.targetID,
+		TargetName:     targetName,
+		StartedBy:      v.startedBy,
+		Voters:         voters,
+		Needed:         v.needed,
+		TimeoutSeconds: int(kickVoteTimeout / time.Second),
+	}
+}
+
+// VoteKick starts or votes on a kick vote in a room, telling the room when
+// one starts or is decided
+// Returns the result once the vote is decided; the caller removes the player
+// if it passed
+func (m *Manager) VoteKick(roomCode, voterID, targetID string, yes bool, broadcast func(string, []byte)) (*protocol.VoteResultPayload, error) {
+	room := m.GetRoom(roomCode)
+	if room == nil {
+		return nil, errors.New("room not found")
+	}
+	v, started, result, err := room.voteKick(voterID, targetID, yes, m.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if started {
+		room.mu.RLock()
+		payload := room.startedPayload(v)
+		room.mu.RUnlock()
+
+		slog.Info("kick vote started", "roomCode", roomCode, "playerId", voterID, "targetId", targetID, "needed", payload.Needed)
+		msgData, _ := json.Marshal(protocol.NewMessage(protocol.VoteStarted, payload))
+		broadcast(roomCode, msgData)
+		go m.expireKickVote(room, v, broadcast)
+	}
+	if result != nil {
+		m.broadcastVoteResult(roomCode, result, broadcast)
+	}
+	return result, nil
+}
+
+// expireKickVote fails a kick vote once its time runs out, unless it was
+// decided first
+func (m *Manager) expireKickVote(room *Room, v *kickVote, broadcast func(string, []byte)) {
+	select {
+	case <-v.done:
+		return
+	case <-m.clock.After(kickVoteTimeout):
+	}
+	if result := room.expireKickVote(v, m.clock.Now()); result != nil {
+		m.broadcastVoteResult(room.Code, result, broadcast)
+	}
+}
+
+// broadcastVoteResult tells the room how a kick vote went
+func (m *Manager) broadcastVoteResult(roomCode string, result *protocol.VoteResultPayload, broadcast func(string, []byte)) {
+	slog.Info("kick vote decided", "roomCode", roomCode, "targetId", result.TargetID, "result", result.Result,
+		"yes", result.Yes, "no", result.No)
+	msgData, _ := json.Marshal(protocol.NewMessage(protocol.VoteResult, result))
+	broadcast(roomCode, msgData)
+}
@@ -0,0 +1,298 @@
+package room
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"slapjack/pkg/protocol"
+)
+
+// Coins paid out to each player at the end of a game
+const (
+	coinsPlayed   = 5
+	coinsWon      = 25
+	coinsPerAward = 10
+)
+
+// Cosmetic slots
+const (
+	SlotCardBack    = "card_back"
+	SlotTable       = "table"
+	SlotAvatarFrame = "avatar_frame"
+)
+
+// Catalog lists every cosmetic item; free items are owned by everyone
+var Catalog = []protocol.CosmeticItem{
+	{ID: "card_back_classic", Slot: SlotCardBack, Name: "Classic", Price: 0},
+	{ID: "card_back_midnight", Slot: SlotCardBack, Name: "Midnight", Price: 100},
+	{ID: "card_back_gold", Slot: SlotCardBack, Name: "Gold Leaf", Price: 300},
+	{ID: "table_felt", Slot: SlotTable, Name: "Green Felt", Price: 0},
+	{ID: "table_ocean", Slot: SlotTable, Name: "Ocean", Price: 150},
+	{ID: "table_crimson", Slot: SlotTable, Name: "Crimson", Price: 150},
+	{ID: "avatar_frame_silver", Slot: SlotAvatarFrame, Name: "Silver Frame", Price: 200},
+	{ID: "avatar_frame_crown", Slot: SlotAvatarFrame, Name: "Crown", Price: 500},
+}
+
+var (
+	ErrUnknownItem    = errors.New("unknown item")
+	ErrItemNotOwned   = errors.New("you don't own that item")
+	ErrAlreadyOwned   = errors.New("you already own that item")
+	ErrNotEnoughCoins = errors.New("not enough coins")
+	ErrNoPlayerToken  = errors.New("no player token")
+)
+
+// catalogItem looks up an item by ID
+func catalogItem(id string) (protocol.CosmeticItem, bool) {
+	for _, item := range Catalog {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return protocol.CosmeticItem{}, false
+}
+
+// Wallet is a player's coins and cosmetics, kept per player token
+type Wallet struct {
+	Coins    int               `json:"coins"`
+	Owned    []string          `json:"owned"`
+	Equipped map[string]string `json:"equipped"` // Item ID by slot
+}
+
+// owns reports whether the wallet has bought an item, or it is free
+func (w *Wallet) owns(item protocol.CosmeticItem) bool {
+	if item.Price == 0 {
+		return true
+	}
+	for _, id := range w.Owned {
+		if id == item.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// ToProtocol converts Wallet to protocol.WalletUpdatedPayload
+func (w Wallet) ToProtocol() protocol.WalletUpdatedPayload {
+	owned := append([]string{}, w.Owned...)
+	equipped := make(map[string]string, len(w.Equipped))
+	for slot, id := range w.Equipped {
+		equipped[slot] = id
+	}
+	return protocol.WalletUpdatedPayload{
+		Coins:    w.Coins,
+		Owned:    owned,
+		Equipped: equipped,
+	}
+}
+
+// wallets holds wallets in memory when there is no Redis
+type wallets struct {
+	byID map[string][]byte
+	mu   sync.Mutex
+}
+
+func newWallets() *wallets {
+	return &wallets{byID: make(map[string][]byte)}
+}
+
+// decodeWallet parses a stored wallet, or returns an empty one for nil
+func decodeWallet(data []byte) (Wallet, error) {
+	var w Wallet
+	if data != nil {
+		if err := json.Unmarshal(data, &w); err != nil {
+			return Wallet{}, err
+		}
+	}
+	if w.Equipped == nil {
+		w.Equipped = make(map[string]string)
+	}
+	return w, nil
+}
+
+// GetWallet returns the wallet of a player token
+func (m *Manager) GetWallet(token string) (Wallet, error) {
+	if token == "" {
+		return Wallet{}, ErrNoPlayerToken
+	}
+	id := hashToken(token)
+
+	if m.store == nil {
+		m.wallets.mu.Lock()
+		defer m.wallets.mu.Unlock()
+		return decodeWallet(m.wallets.byID[id])
+	}
+
+	data, err := m.store.GetWallet(context.Background(), id)
+	if err != nil {
+		m.storeHealth.fail("load wallet", err)
+		return Wallet{}, err
+	}
+	return decodeWallet(data)
+}
+
+// updateWallet applies change to a player token's wallet atomically
+// change may run more than once if the wallet is updated concurrently
+func (m *Manager) updateWallet(token string, change func(*Wallet) error) (Wallet, error) {
+	if token == "" {
+		return Wallet{}, ErrNoPlayerToken
+	}
+	id := hashToken(token)
+
+	var updated Wallet
+	apply := func(data []byte) ([]byte, error) {
+		w, err := decodeWallet(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := change(&w); err != nil {
+			return nil, err
+		}
+		updated = w
+		return json.Marshal(w)
+	}
+
+	if m.store == nil {
+		m.wallets.mu.Lock()
+		defer m.wallets.mu.Unlock()
+		data, err := apply(m.wallets.byID[id])
+		if err != nil {
+			return Wallet{}, err
+		}
+		m.wallets.byID[id] = data
+		return updated, nil
+	}
+
+	if _, err := m.store.UpdateWallet(context.Background(), id, m.cfg.Get().ProfileTTL, apply); err != nil {
+		return Wallet{}, err
+	}
+	return updated, nil
+}
+
+// BuyItem spends coins on a cosmetic item
+func (m *Manager) BuyItem(token, itemID string) (Wallet, error) {
+	item, ok := catalogItem(itemID)
+	if !ok {
+		return Wallet{}, ErrUnknownItem
+	}
+	return m.updateWallet(token, func(w *Wallet) error {
+		if w.owns(item) {
+			return ErrAlreadyOwned
+		}
+		if w.Coins < item.Price {
+			return ErrNotEnoughCoins
+		}
+		w.Coins -= item.Price
+		w.Owned = append(w.Owned, item.ID)
+		return nil
+	})
+}
+
+// EquipItem puts an owned item in its slot
+func (m *Manager) EquipItem(token, itemID string) (Wallet, error) {
+	item, ok := catalogItem(itemID)
+	if !ok {
+		return Wallet{}, ErrUnknownItem
+	}
+	return m.updateWallet(token, func(w *Wallet) error {
+		if !w.owns(item) {
+			return ErrItemNotOwned
+		}
+		w.Equipped[item.Slot] = item.ID
+		return nil
+	})
+}
+
+// loadout returns the items a player token has equipped, or nil if none
+func (m *Manager) loadout(token string) map[string]string {
+	if token == "" {
+		return nil
+	}
+	w, err := m.GetWallet(token)
+	if err != nil || len(w.Equipped) == 0 {
+		return nil
+	}
+	return w.Equipped
+}
+
+// SetLoadout shows a player's newly equipped items in their room
+// Returns false if the player isn't in the room
+func (m *Manager) SetLoadout(roomCode, playerID string, loadout map[string]string) bool {
+	room := m.GetRoom(roomCode)
+	if room == nil {
+		return false
+	}
+	return room.setPlayerLoadout(playerID, loadout)
+}
+
+// setPlayerLoadout shows a player's equipped items
+func (r *Room) setPlayerLoadout(playerID string, loadout map[string]string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, exists := r.Players[playerID]
+	if !exists {
+		return false
+	}
+	p.Loadout = loadout
+	r.version++
+	return true
+}
+
+// PayOut credits every seated player for a finished game: coins for playing,
+// for winning and for each award. It returns each credited player's updated
+// wallet with what they earned, by player ID.
+// Games with fewer than two distinct player tokens pay nothing, so a lone
+// player can't farm coins.
+func (m *Manager) PayOut(r *Room, winnerID string, awards []protocol.Award) map[string]protocol.WalletUpdatedPayload {
+	tokens := make(map[string]string)
+	distinct := make(map[string]bool)
+	for _, p := range r.GetAllPlayers() {
+		if p.Token != "" {
+			tokens[p.ID] = p.Token
+			distinct[p.Token] = true
+		}
+	}
+	if len(distinct) < 2 {
+		return nil
+	}
+
+	// A team game's winners are the whole winning team
+	winners := map[string]bool{winnerID: true}
+	if r.Game != nil {
+		for _, id := range r.Game.Teammates(winnerID) {
+			winners[id] = true
+		}
+	}
+
+	paid := make(map[string]protocol.WalletUpdatedPayload, len(tokens))
+	for playerID, token := range tokens {
+		earned := []protocol.CoinCredit{{Reason: "played", Coins: coinsPlayed}}
+		if winners[playerID] {
+			earned = append(earned, protocol.CoinCredit{Reason: "won", Coins: coinsWon})
+		}
+		for _, award := range awards {
+			if award.PlayerID == playerID {
+				earned = append(earned, protocol.CoinCredit{Reason: award.Key, Coins: coinsPerAward})
+			}
+		}
+
+		w, err := m.updateWallet(token, func(w *Wallet) error {
+			for _, credit := range earned {
+				w.Coins += credit.Coins
+			}
+			return nil
+		})
+		if err != nil {
+			slog.Error("failed to pay out coins", "roomCode", r.Code, "playerId", playerID, "error", err)
+			continue
+		}
+
+		payload := w.ToProtocol()
+		payload.Earned = earned
+		paid[playerID] = payload
+	}
+	return paid
+}
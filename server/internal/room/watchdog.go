@@ -0,0 +1,128 @@
+package room
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"slapjack/pkg/protocol"
+)
+
+const (
+	// Longest a room may stay starting before it is returned to waiting
+	maxStartingDuration = time.Minute
+
+	// Longest a game may go without a card played before the room is closed
+	maxPlayingSilence = 10 * time.Minute
+
+	// How far past its deadline a turn timer may be before it counts as dead
+	turnTimerGrace = 10 * time.Second
+)
+
+// Kinds of stuck room, each with its own fix
+const (
+	StuckStarting  = "starting"   // Returned to waiting
+	StuckTurnTimer = "turn_timer" // Turn timer restarted
+	StuckSilent    = "silent"     // Closed with a notice
+)
+
+// StuckRoom is a stuck room the watchdog found and resolved
+type StuckRoom struct {
+	Code string
+	Kind string
+}
+
+// ResolveStuckRooms finds rooms stuck starting, games whose turn timer died
+// and games nobody has played in for too long, and unsticks them. It returns
+// what it resolved; rooms resolved as StuckSilent have been closed.
+func (m *Manager) ResolveStuckRooms(broadcast func(string, []byte)) []StuckRoom {
+	m.mu.RLock()
+	rooms := make([]*Room, 0, len(m.rooms))
+	for _, room := range m.rooms {
+		rooms = append(rooms, room)
+	}
+	m.mu.RUnlock()
+
+	var stuck []StuckRoom
+	for _, room := range rooms {
+		kind := m.stuckKind(room)
+		if kind == "" {
+			continue
+		}
+		slog.Warn("stuck room found", "roomCode", room.Code, "kind", kind, "status", room.Status)
+
+		switch kind {
+		case StuckStarting:
+			if !room.abandonStart() {
+				continue
+			}
+			msgData, _ := json.Marshal(protocol.NewMessage(protocol.StartCancelled, protocol.StartCancelledPayload{
+				Reason: protocol.StartCancelledStuck,
+			}))
+			broadcast(room.Code, msgData)
+			broadcast(room.Code, room.UpdatedMessage())
+		case StuckTurnTimer:
+			go room.Game.StartTurnTimer(room.Code, broadcast, m)
+		case StuckSilent:
+			room.Game.CancelTurnTimer()
+			room.Game.RecordEnded("Game stalled")
+			m.SaveReplay(room.Code, room.Game)
+			msgData, _ := json.Marshal(protocol.NewMessage(protocol.RoomExpired, protocol.RoomExpiredPayload{
+				Reason:      "Room closed because the game stalled",
+				IdleSeconds: int(m.clock.Since(room.Game.LastPlayAt()).Seconds()),
+			}))
+			broadcast(room.Code, msgData)
+			m.DeleteRoom(room.Code)
+		}
+		stuck = append(stuck, StuckRoom{Code: room.Code, Kind: kind})
+	}
+	return stuck
+}
+
+// stuckKind returns how a room is stuck, or "" if it isn't
+func (m *Manager) stuckKind(room *Room) string {
+	room.mu.RLock()
+	status, startingSince, g := room.Status, room.startingSince, room.Game
+	room.mu.RUnlock()
+
+	switch {
+	case status == "starting":
+		if m.clock.Since(startingSince) > maxStartingDuration {
+			return StuckStarting
+		}
+	case status == "playing" && g != nil:
+		now := m.clock.Now()
+		if now.Sub(g.LastPlayAt()) > maxPlayingSilence {
+			return StuckSilent
+		}
+		deadline := g.TurnTimerDeadline()
+		if deadline.IsZero() {
+			// Timers are restarted right after each play, so only one long
+			// gone counts as dead
+			if now.Sub(g.LastPlayAt()) > turnTimerGrace {
+				return StuckTurnTimer
+			}
+		} else if now.Sub(deadline) > turnTimerGrace {
+			return StuckTurnTimer
+		}
+	}
+	return ""
+}
+
+// abandonStart returns a room stuck starting to waiting, cancelling any countdown
+// Returns false if the room is no longer starting
+func (r *Room) abandonStart() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Status != "starting" {
+		return false
+	}
+	if r.countdownCancel != nil {
+		close(r.countdownCancel)
+		r.countdownCancel = nil
+	}
+	r.Status = "waiting"
+	r.version++
+	return true
+}
@@ -0,0 +1,265 @@
+package stats
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"slapjack/internal/redis"
+)
+
+const (
+	// Events waiting to be aggregated; events beyond this are dropped rather
+	// than slowing down gameplay
+	eventBuffer = 1024
+
+	// How often rollups are written to Redis
+	flushInterval = time.Minute
+
+	// Rollups kept in memory and served by the summary
+	keepHourly = 48
+	keepDaily  = 30
+
+	hourlyTTL = 7 * 24 * time.Hour
+	dailyTTL  = 90 * 24 * time.Hour
+
+	hourFormat = "2006-01-02T15"
+	dayFormat  = "2006-01-02"
+)
+
+// Rollup periods
+const (
+	Hourly = "hourly"
+	Daily  = "daily"
+)
+
+type eventKind int
+
+const (
+	gameStarted eventKind = iota
+	gameFinished
+	playersOnline
+)
+
+// event is something that happened on the gameplay path, aggregated later by the worker
+type event struct {
+	kind     eventKind
+	at       time.Time
+	duration time.Duration
+	players  int
+}
+
+// Rollup aggregates activity over one hour or day
+type Rollup struct {
+	Period                 string  `json:"period"`
+	GamesStarted           int     `json:"gamesStarted"`
+	GamesPlayed            int     `json:"gamesPlayed"`
+	PeakConcurrentPlayers  int     `json:"peakConcurrentPlayers"`
+	TotalGameSeconds       float64 `json:"totalGameSeconds"`
+	AverageGameDurationSec float64 `json:"averageGameDurationSec"`
+}
+
+func (r *Rollup) add(e event) {
+	switch e.kind {
+	case gameStarted:
+		r.GamesStarted++
+	case gameFinished:
+		r.GamesPlayed++
+		r.TotalGameSeconds += e.duration.Seconds()
+		r.AverageGameDurationSec = r.TotalGameSeconds / float64(r.GamesPlayed)
+	case playersOnline:
+		if e.players > r.PeakConcurrentPlayers {
+			r.PeakConcurrentPlayers = e.players
+		}
+	}
+}
+
+// Summary is the response for the stats summary endpoint, newest periods first
+type Summary struct {
+	Hourly []Rollup `json:"hourly"`
+	Daily  []Rollup `json:"daily"`
+}
+
+// Worker aggregates game events into hourly and daily rollups in the background
+type Worker struct {
+	events chan event
+	store  *redis.Store
+
+	hourly map[string]*Rollup
+	daily  map[string]*Rollup
+	dirty  map[rollupKey]bool // Rollups changed since the last flush
+	mu     sync.Mutex
+}
+
+type rollupKey struct {
+	kind   string
+	period string
+}
+
+// NewWorker creates a stats worker, resuming the current rollups from Redis if available
+func NewWorker(store *redis.Store) *Worker {
+	w := &Worker{
+		events: make(chan event, eventBuffer),
+		store:  store,
+		hourly: make(map[string]*Rollup),
+		daily:  make(map[string]*Rollup),
+		dirty:  make(map[rollupKey]bool),
+	}
+
+	if store != nil {
+		now := time.Now().UTC()
+		w.load(Hourly, now.Format(hourFormat), w.hourly)
+		w.load(Daily, now.Format(dayFormat), w.daily)
+	}
+
+	return w
+}
+
+func (w *Worker) load(kind, period string, into map[string]*Rollup) {
+	var rollup Rollup
+	if err := w.store.GetStatsRollup(context.Background(), kind, period, &rollup); err == nil {
+		into[period] = &rollup
+	}
+}
+
+// record queues an event without blocking the caller
+func (w *Worker) record(e event) {
+	select {
+	case w.events <- e:
+	default:
+		slog.Warn("stats event dropped, worker is behind")
+	}
+}
+
+// GameStarted records a game starting
+func (w *Worker) GameStarted() {
+	w.record(event{kind: gameStarted, at: time.Now()})
+}
+
+// GameFinished records a game ending after the given duration
+func (w *Worker) GameFinished(duration time.Duration) {
+	w.record(event{kind: gameFinished, at: time.Now(), duration: duration})
+}
+
+// PlayersOnline records the number of connected players
+func (w *Worker) PlayersOnline(n int) {
+	w.record(event{kind: playersOnline, at: time.Now(), players: n})
+}
+
+// Run aggregates events until the process exits
+func (w *Worker) Run() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-w.events:
+			w.apply(e)
+		case <-ticker.C:
+			w.Flush()
+		}
+	}
+}
+
+func (w *Worker) apply(e event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	at := e.at.UTC()
+	hour := at.Format(hourFormat)
+	day := at.Format(dayFormat)
+
+	rollupFor(w.hourly, hour).add(e)
+	rollupFor(w.daily, day).add(e)
+	w.dirty[rollupKey{Hourly, hour}] = true
+	w.dirty[rollupKey{Daily, day}] = true
+
+	prune(w.hourly, keepHourly)
+	prune(w.daily, keepDaily)
+}
+
+func rollupFor(rollups map[string]*Rollup, period string) *Rollup {
+	r, ok := rollups[period]
+	if !ok {
+		r = &Rollup{Period: period}
+		rollups[period] = r
+	}
+	return r
+}
+
+// prune drops the oldest periods beyond keep
+// Period strings sort chronologically
+func prune(rollups map[string]*Rollup, keep int) {
+	if len(rollups) <= keep {
+		return
+	}
+	periods := sortedPeriods(rollups)
+	for _, period := range periods[keep:] {
+		delete(rollups, period)
+	}
+}
+
+// sortedPeriods returns periods newest first
+func sortedPeriods(rollups map[string]*Rollup) []string {
+	periods := make([]string, 0, len(rollups))
+	for period := range rollups {
+		periods = append(periods, period)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(periods)))
+	return periods
+}
+
+// Flush writes changed rollups to Redis
+func (w *Worker) Flush() {
+	if w.store == nil {
+		return
+	}
+
+	w.mu.Lock()
+	type pending struct {
+		kind   string
+		rollup Rollup
+	}
+	var writes []pending
+	for key := range w.dirty {
+		rollups := w.hourly
+		if key.kind == Daily {
+			rollups = w.daily
+		}
+		if r, ok := rollups[key.period]; ok {
+			writes = append(writes, pending{key.kind, *r})
+		}
+	}
+	w.dirty = make(map[rollupKey]bool)
+	w.mu.Unlock()
+
+	for _, p := range writes {
+		ttl := hourlyTTL
+		if p.kind == Daily {
+			ttl = dailyTTL
+		}
+		if err := w.store.SetStatsRollup(context.Background(), p.kind, p.rollup.Period, p.rollup, ttl); err != nil {
+			slog.Error("failed to persist stats rollup", "period", p.rollup.Period, "error", err)
+		}
+	}
+}
+
+// Summary returns the rollups kept in memory
+func (w *Worker) Summary() Summary {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	summary := Summary{
+		Hourly: make([]Rollup, 0, len(w.hourly)),
+		Daily:  make([]Rollup, 0, len(w.daily)),
+	}
+	for _, period := range sortedPeriods(w.hourly) {
+		summary.Hourly = append(summary.Hourly, *w.hourly[period])
+	}
+	for _, period := range sortedPeriods(w.daily) {
+		summary.Daily = append(summary.Daily, *w.daily[period])
+	}
+	return summary
+}
@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Posts waiting to be sent; posts beyond this are dropped rather than
+	// slowing down gameplay
+	queueSize = 256
+
+	// How long Discord gets to accept a post
+	postTimeout = 5 * time.Second
+
+	// Name the notifications are posted under
+	username = "Slapjack"
+)
+
+// Embed colors
+const (
+	ColorStart  = 0x3b82f6
+	ColorWin    = 0xeab308
+	ColorResult = 0x22c55e
+)
+
+// Hosts Discord serves webhooks from
+var discordHosts = map[string]bool{
+	"discord.com":        true,
+	"discordapp.com":     true,
+	"ptb.discord.com":    true,
+	"canary.discord.com": true,
+}
+
+var ErrInvalidURL = errors.New("webhook must be a Discord webhook URL")
+
+// Validate checks that a URL is a Discord webhook, so hosts can't point the
+// server at anything else
+func Validate(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.User != nil || u.Port() != "" {
+		return ErrInvalidURL
+	}
+	if !discordHosts[u.Hostname()] || !strings.HasPrefix(u.Path, "/api/webhooks/") {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+// Embed is a Discord message embed
+type Embed struct {
+	Title       string  `json:"title"`
+	Description string  `json:"description,omitempty"`
+	Color       int     `json:"color,omitempty"`
+	Fields      []Field `json:"fields,omitempty"`
+	Footer      *Footer `json:"footer,omitempty"`
+}
+
+// Field is a name and value shown in an embed
+type Field struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// Footer is the small print under an embed
+type Footer struct {
+	Text string `json:"text"`
+}
+
+// message is the body of a webhook post
+type message struct {
+	Username string  `json:"username"`
+	Embeds   []Embed `json:"embeds"`
+}
+
+type post struct {
+	url   string
+	embed Embed
+}
+
+// Notifier posts to webhooks from a single background worker, so a slow or
+// failing webhook never holds up a game
+type Notifier struct {
+	client *http.Client
+	queue  chan post
+}
+
+// NewNotifier creates a notifier; Run sends what it queues
+func NewNotifier() *Notifier {
+	return &Notifier{
+		client: &http.Client{
+			Timeout: postTimeout,
+			// Webhooks are validated before they are stored; a redirect
+			// could lead anywhere
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		queue: make(chan post, queueSize),
+	}
+}
+
+// Send queues an embed for a webhook, dropping it if the queue is full
+func (n *Notifier) Send(webhookURL string, embed Embed) {
+	select {
+	case n.queue <- post{url: webhookURL, embed: embed}:
+	default:
+		slog.Warn("webhook queue full, dropping notification", "title", embed.Title)
+	}
+}
+
+// Run posts queued notifications until the process exits
+func (n *Notifier) Run() {
+	for p := range n.queue {
+		n.post(p)
+	}
+}
+
+func (n *Notifier) post(p post) {
+	body, err := json.Marshal(message{Username: username, Embeds: []Embed{p.embed}})
+	if err != nil {
+		return
+	}
+	resp, err := n.client.Post(p.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		slog.Warn("webhook post failed", "error", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		slog.Warn("webhook post rejected", "status", resp.StatusCode)
+	}
+}
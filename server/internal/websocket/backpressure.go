@@ -0,0 +1,82 @@
+package websocket
+
+import (
+	"time"
+)
+
+const (
+	// Queued messages at or below which a lagging client has caught up
+	drainedQueueDepth = 8
+
+	// How long a client may keep overflowing its queue before it is disconnected;
+	// it can reconnect and resync rather than play on with a stale table
+	maxLagDuration = 15 * time.Second
+)
+
+// Backpressure events counted in the metrics
+const (
+	lagStarted      = "lagging"
+	lagResynced     = "resynced"
+	lagDisconnected = "disconnected"
+)
+
+// enqueue queues a message for the client without blocking. If the queue is
+// full the message is dropped and the client marked as lagging: it is sent a
+// resync once its queue drains, and disconnected if it stays backed up for
+// longer than maxLagDuration.
+func (c *Client) enqueue(message []byte) bool {
+	select {
+	case c.send <- message:
+		return true
+	default:
+	}
+
+	c.dropped.Add(1)
+	c.hub.droppedMessages.With(messageType(message)).Inc()
+
+	now := c.clock.Now().UnixNano()
+	if c.laggingSince.CompareAndSwap(0, now) {
+		c.hub.backpressure.With(lagStarted).Inc()
+		c.logger().Warn("client send buffer full, dropping messages until it drains")
+		return false
+	}
+	if since := c.laggingSince.Load(); since != 0 && c.clock.Since(time.Unix(0, since)) > maxLagDuration {
+		c.disconnectLagging()
+	}
+	return false
+}
+
+// Lagging reports whether the client has dropped messages it hasn't been resynced after
+func (c *Client) Lagging() bool {
+	return c.laggingSince.Load() != 0
+}
+
+// catchUp sends a lagging client its room's full state once its queue has
+// drained, replacing the broadcasts it missed
+// Called by the writer after each write
+func (c *Client) catchUp() {
+	since := c.laggingSince.Load()
+	if since == 0 || len(c.send) > drainedQueueDepth || !c.laggingSince.CompareAndSwap(since, 0) {
+		return
+	}
+	c.hub.backpressure.With(lagResynced).Inc()
+	c.logger().Info("lagging client drained, resyncing", "dropped", c.dropped.Load())
+
+	c.hub.mu.RLock()
+	roomCode := c.hub.roomOf(c)
+	c.hub.mu.RUnlock()
+	if r := c.hub.rooms.GetRoom(roomCode); r != nil {
+		c.hub.SendResync(c, r)
+	}
+}
+
+// disconnectLagging closes the connection of a client that has stayed backed
+// up; its seat is kept for the reconnection grace period as with any drop
+func (c *Client) disconnectLagging() {
+	if c.conn == nil || !c.lagDisconnected.CompareAndSwap(false, true) {
+		return
+	}
+	c.hub.backpressure.With(lagDisconnected).Inc()
+	c.logger().Warn("disconnecting client after sustained backpressure", "dropped", c.dropped.Load())
+	c.conn.Close()
+}
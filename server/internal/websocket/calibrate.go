@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"slapjack/internal/locale"
+	"slapjack/internal/room"
+	"slapjack/pkg/protocol"
+)
+
+// calibration is one run of input latency calibration flashes for a client
+type calibration struct {
+	device  string
+	flashes []time.Time // When each flash was sent
+	taps    []time.Time // When each tap arrived
+	mu      sync.Mutex
+}
+
+// handleCalibrateStart begins a run of calibration flashes, replacing any
+// run already under way
+func (c *Client) handleCalibrateStart(payload json.RawMessage) {
+	var start protocol.CalibrateStartPayload
+	if !c.decodePayload(payload, &start, "Invalid calibration request") {
+		return
+	}
+	if c.PlayerToken == "" {
+		c.sendLocalized(protocol.CodeCalibrationFailed, locale.CalibrationNoToken, nil)
+		return
+	}
+
+	run := &calibration{device: start.Device}
+	c.calibrating.Store(run)
+	go c.runCalibration(run)
+}
+
+// handleCalibrateTap records a tap for the run under way
+func (c *Client) handleCalibrateTap() {
+	run := c.calibrating.Load()
+	if run == nil {
+		return
+	}
+	run.mu.Lock()
+	if len(run.taps) < 2*room.CalibrationRounds {
+		run.taps = append(run.taps, c.hub.clock.Now())
+	}
+	run.mu.Unlock()
+}
+
+// runCalibration sends the run's flashes on the beat, then estimates the
+// device's input lag from the taps, stores it and applies it in the
+// client's room
+// Runs on its own goroutine, so it only sends through the hub, which drops
+// messages for clients that have gone
+func (c *Client) runCalibration(run *calibration) {
+	ticker := c.hub.clock.NewTicker(room.CalibrationInterval)
+	defer ticker.Stop()
+
+	for round := 1; round <= room.CalibrationRounds; round++ {
+		// Stop if a new run replaced this one or the client went away
+		if c.calibrating.Load() != run || c.hub.GetClientBySession(c.SessionID) != c {
+			return
+		}
+		msgData, _ := json.Marshal(protocol.NewMessage(protocol.CalibrateFlash, protocol.CalibrateFlashPayload{
+			Round:      round,
+			Rounds:     room.CalibrationRounds,
+			IntervalMs: room.CalibrationInterval.Milliseconds(),
+		}))
+		run.mu.Lock()
+		run.flashes = append(run.flashes, c.hub.clock.Now())
+		run.mu.Unlock()
+		c.hub.SendToClient(c.SessionID, msgData)
+
+		// The last beat leaves time for the last tap to arrive
+		<-ticker.C()
+	}
+
+	if !c.calibrating.CompareAndSwap(run, nil) {
+		return
+	}
+	run.mu.Lock()
+	flashes, taps := run.flashes, run.taps
+	run.mu.Unlock()
+
+	rtt := time.Duration(c.Latency()) * time.Millisecond
+	lagMs, samples, err := room.EstimateInputLag(flashes, taps, rtt)
+	if err != nil {
+		msgData, _ := json.Marshal(protocol.NewError(protocol.CodeCalibrationFailed, err.Error()))
+		c.hub.SendToClient(c.SessionID, msgData)
+		return
+	}
+	result, err := c.hub.rooms.SaveCalibration(c.PlayerToken, run.device, lagMs, samples)
+	if err != nil {
+		c.logger().Warn("failed to save calibration", "error", err)
+	}
+
+	// The client may have joined or left a room while calibrating
+	c.hub.mu.RLock()
+	roomCode, playerID := c.RoomCode, c.PlayerID
+	c.hub.mu.RUnlock()
+	if roomCode != "" && playerID != "" {
+		c.hub.rooms.SetInputLag(roomCode, playerID, lagMs)
+	}
+
+	msgData, _ := json.Marshal(protocol.NewMessage(protocol.CalibrateResult, result))
+	c.hub.SendToClient(c.SessionID, msgData)
+	c.logger().Info("input latency calibrated", "inputLagMs", lagMs, "samples", samples, "rttMs", c.Latency())
+}
@@ -2,10 +2,15 @@ package websocket
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"slapjack/internal/clock"
+	"slapjack/internal/locale"
 	"slapjack/pkg/protocol"
 )
 
@@ -17,10 +22,23 @@ const (
 	pongWait = 60 * time.Second
 
 	// Send pings to peer with this period (must be less than pongWait)
-	pingPeriod = (pongWait * 9) / 10
+	// Pings are frequent because they also measure latency
+	pingPeriod = 5 * time.Second
+
+	// Weight of the newest ping in the smoothed latency
+	latencySmoothing = 0.3
+
+	// Recent pings whose median is smoothed, so one slow pong can't move it
+	latencySamples = 5
 
 	// Maximum message size allowed from peer
 	maxMessageSize = 8192
+
+	// Fastest allowed replay playback multiplier
+	maxReplaySpeed = 16
+
+	// Rate-limited messages tolerated before the connection is closed
+	maxRateLimitViolations = 50
 )
 
 // Client represents a single WebSocket connection
@@ -36,6 +54,9 @@ type Client struct {
 	// Session ID for reconnection
 	SessionID string
 
+	// Durable player identity persisted by the client across sessions
+	PlayerToken string
+
 	// Player ID in the game
 	PlayerID string
 
@@ -44,57 +65,157 @@ type Client struct {
 
 	// Player name
 	PlayerName string
+
+	// True when watching RoomCode as a spectator rather than a seated player
+	Spectating bool
+
+	// Room the hub detached the client from (kick, room closed) that the
+	// client's own goroutine has yet to apply; see Hub.applyDetach
+	// RoomCode, PlayerID, PlayerName and Spectating are only written by the
+	// client's own goroutine, under hub.mu; other goroutines read them under hub.mu
+	detachedFrom string
+
+	// Negotiated protocol version and features (from CLIENT_HELLO)
+	ProtocolVersion int
+	Features        map[string]bool
+
+	// Set when the server is closing the connection after flushing queued messages
+	closing bool
+
+	// Closed by the hub along with send once the client has unregistered
+	done chan struct{}
+
+	// Closed to stop the replay being streamed to the client, if any; only
+	// touched by the client's own goroutine
+	replayStop chan struct{}
+
+	// Incoming message rate limiting
+	limiter       *tokenBucket
+	violations    int
+	routeLimiters map[string]*tokenBucket // Per message type, see RateLimit
+
+	// Room broadcast filtering (from SUBSCRIBE/UNSUBSCRIBE)
+	subs subscriptions
+
+	// Event log for clients using the long-polling fallback instead of a WebSocket
+	poll *pollQueue
+
+	// Outbound bandwidth cap (nil if uncapped) and cosmetic broadcasts dropped to relieve a saturated link
+	bandwidth       *tokenBucket
+	droppedCosmetic atomic.Int64
+
+	// Messages dropped because the send queue was full, and since when the
+	// client has been lagging (UnixNano, 0 if it isn't); see enqueue
+	dropped         atomic.Int64
+	laggingSince    atomic.Int64
+	lagDisconnected atomic.Bool
+
+	// Round-trip latency, smoothed, measured from ping to pong (0 until measured),
+	// and the latest round trips, only touched by the read pump
+	pingSentAt atomic.Int64 // UnixNano
+	latency    atomic.Int64 // Milliseconds
+	rtts       []time.Duration
+
+	// Input latency calibration under way, if any
+	calibrating atomic.Pointer[calibration]
+
+	// Wire encoding for outgoing messages; JSON if unset
+	encoder atomic.Pointer[protocol.Encoder]
+
+	// Language of the messages sent to the client; English if unset, see Locale
+	lang atomic.Pointer[string]
+
+	// Whether permessage-deflate was negotiated for the connection
+	compressed bool
+
+	// When the client last sent a message or left a room (UnixNano); see idleTimedOut
+	lastActive atomic.Int64
+
+	// Shared with the hub; connection deadlines still use the system clock,
+	// as the network enforces them
+	clock clock.Clock
 }
 
 // NewClient creates a new Client instance
-func NewClient(hub *Hub, conn *websocket.Conn, sessionID string) *Client {
-	return &Client{
-		hub:       hub,
-		conn:      conn,
-		send:      make(chan []byte, 256),
-		SessionID: sessionID,
+func NewClient(hub *Hub, conn *websocket.Conn, sessionID, playerToken string) *Client {
+	c := &Client{
+		hub:             hub,
+		conn:            conn,
+		send:            make(chan []byte, 256),
+		done:            make(chan struct{}),
+		SessionID:       sessionID,
+		PlayerToken:     playerToken,
+		ProtocolVersion: protocol.MinProtocolVersion,
+		Features:        make(map[string]bool),
+		limiter:         newTokenBucket(hub.clock, float64(hub.cfg.Get().MessagesPerSecond), hub.cfg.Get().MessageBurst),
+		bandwidth:       newBandwidthCap(hub.clock, hub.cfg.Get().ClientBandwidthBytesPerSec),
+		clock:           hub.clock,
 	}
+	c.markActive()
+	return c
 }
 
 // readPump pumps messages from the WebSocket connection to the hub
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.unregister <- c
-		c.conn.Close()
+		// When closing deliberately, writePump flushes queued messages and closes the connection
+		if !c.closing {
+			c.conn.Close()
+		}
 	}()
 
 	c.conn.SetReadLimit(maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(pongWait))
 	c.conn.SetPongHandler(func(string) error {
 		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		if sent := c.pingSentAt.Load(); sent > 0 {
+			c.recordLatency(c.clock.Since(time.Unix(0, sent)))
+		}
 		return nil
 	})
 
 	for {
-		_, message, err := c.conn.ReadMessage()
+		frameType, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				c.logger().Warn("WebSocket error", "error", err)
 			}
 			break
 		}
 
+		// Throttle message floods, eventually dropping abusive connections
+		if !c.limiter.Allow() {
+			c.violations++
+			if c.violations >= maxRateLimitViolations {
+				c.logger().Warn("closing client after rate limit violations", "violations", c.violations)
+				break
+			}
+			c.sendLocalized(protocol.CodeRateLimited, locale.RateLimited, nil)
+			continue
+		}
+
 		// Parse the message
+		received := c.clock.Now()
 		var msg protocol.WSMessage
-		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("Failed to parse message: %v", err)
-			c.sendError("PARSE_ERROR", "Invalid message format")
+		if err := frameEncoder(frameType).Decode(message, &msg); err != nil {
+			c.logger().Info("failed to parse message", "error", err)
+			c.sendLocalized(protocol.CodeParseError, locale.InvalidMessage, nil)
 			continue
 		}
 
 		// Handle the message
-		c.handleMessage(msg)
+		c.markActive()
+		c.handleMessage(msg, c.clock.Since(received))
+		if c.closing {
+			break
+		}
 	}
 }
 
 // writePump pumps messages from the hub to the WebSocket connection
 func (c *Client) writePump() {
-	ticker := time.NewTicker(pingPeriod)
+	ticker := c.clock.NewTicker(pingPeriod)
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
@@ -110,25 +231,40 @@ func (c *Client) writePump() {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			w.Write(message)
-
 			// Add queued messages to the current WebSocket message
+			batch := [][]byte{message}
 			n := len(c.send)
 			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
+				batch = append(batch, <-c.send)
 			}
 
-			if err := w.Close(); err != nil {
+			enc := c.Encoder()
+			frame := c.encodeBatch(enc, batch)
+			if len(frame) == 0 {
+				continue
+			}
+			frameType := websocket.TextMessage
+			if enc.Binary() {
+				frameType = websocket.BinaryMessage
+			}
+			if c.compressed {
+				c.conn.EnableWriteCompression(len(frame) >= compressMinBytes)
+			}
+			c.hub.payloadBytes.With(compressionLabel(c.compressed)).Add(uint64(len(frame)))
+			if err := c.conn.WriteMessage(frameType, frame); err != nil {
 				return
 			}
+			written := len(frame)
+			c.throttleWrite(written)
+			c.catchUp()
 
-		case <-ticker.C:
+		case <-ticker.C():
+			if c.idleTimedOut() {
+				c.writeIdleTimeout()
+				return
+			}
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.pingSentAt.Store(c.clock.Now().UnixNano())
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
@@ -136,6 +272,31 @@ func (c *Client) writePump() {
 	}
 }
 
+// recordLatency folds the median of the latest ping round trips into the
+// smoothed latency
+func (c *Client) recordLatency(rtt time.Duration) {
+	c.rtts = append(c.rtts, rtt)
+	if len(c.rtts) > latencySamples {
+		c.rtts = c.rtts[1:]
+	}
+	sorted := append([]time.Duration(nil), c.rtts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	ms := sorted[len(sorted)/2].Milliseconds()
+	if prev := c.latency.Load(); prev > 0 {
+		ms = int64(latencySmoothing*float64(ms) + (1-latencySmoothing)*float64(prev))
+	}
+	if ms < 1 {
+		ms = 1
+	}
+	c.latency.Store(ms)
+}
+
+// Latency returns the client's smoothed round-trip latency in milliseconds, or 0 if unknown
+func (c *Client) Latency() int64 {
+	return c.latency.Load()
+}
+
 // Start begins the client's read and write pumps
 func (c *Client) Start() {
 	go c.writePump()
@@ -146,20 +307,50 @@ func (c *Client) Start() {
 func (c *Client) SendMessage(msg protocol.WSMessage) {
 	data, err := json.Marshal(msg)
 	if err != nil {
-		log.Printf("Failed to marshal message: %v", err)
+		c.logger().Error("failed to marshal message", "msgType", msg.Type, "error", err)
 		return
 	}
-	select {
-	case c.send <- data:
-	default:
-		// Buffer full, message dropped
-	}
+	c.enqueue(data)
+}
+
+// closeAfterFlush stops reading from the client and closes the connection once
+// already queued messages have been written
+func (c *Client) closeAfterFlush() {
+	c.closing = true
+}
+
+// logger returns a logger tagged with the client's session, room and player
+func (c *Client) logger() *slog.Logger {
+	return slog.With("sessionId", c.SessionID, "roomCode", c.RoomCode, "playerId", c.PlayerID)
+}
+
+// SendError sends an error message to the client
+func (c *Client) SendError(code protocol.ErrorCode, message string) {
+	c.sendError(code, message)
 }
 
 // sendError sends an error message to the client
-func (c *Client) sendError(code, message string) {
-	c.SendMessage(protocol.NewMessage(protocol.Error, protocol.ErrorPayload{
-		Code:    code,
-		Message: message,
-	}))
+func (c *Client) sendError(code protocol.ErrorCode, message string) {
+	c.SendMessage(protocol.NewError(code, message))
+}
+
+// sendFieldError sends an error message naming the payload field that failed validation
+func (c *Client) sendFieldError(code protocol.ErrorCode, field, message string) {
+	c.SendMessage(protocol.NewFieldError(code, field, message))
+}
+
+// decodePayload strictly decodes a message payload into v, replying with
+// INVALID_PAYLOAD, naming the offending field where there is one, if it doesn't fit
+func (c *Client) decodePayload(payload json.RawMessage, v interface{}, message string) bool {
+	err := protocol.DecodePayload(payload, v)
+	if err == nil {
+		return true
+	}
+	var payloadErr *protocol.PayloadError
+	if errors.As(err, &payloadErr) && payloadErr.Field != "" {
+		c.sendFieldError(protocol.CodeInvalidPayload, payloadErr.Field, message+": "+err.Error())
+	} else {
+		c.sendError(protocol.CodeInvalidPayload, message+": "+err.Error())
+	}
+	return false
 }
@@ -0,0 +1,87 @@
+package websocket
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"slapjack/internal/metrics"
+)
+
+// Messages shorter than this are sent uncompressed, as deflate framing
+// outweighs what it saves on them
+const compressMinBytes = 256
+
+// Compression label values for the bandwidth counters
+const (
+	compressionOn  = "on"
+	compressionOff = "off"
+)
+
+func compressionLabel(compressed bool) string {
+	if compressed {
+		return compressionOn
+	}
+	return compressionOff
+}
+
+// OffersCompression reports whether a WebSocket upgrade request offers the
+// permessage-deflate extension
+func OffersCompression(r *http.Request) bool {
+	for _, header := range r.Header.Values("Sec-WebSocket-Extensions") {
+		for _, ext := range strings.Split(header, ",") {
+			name, _, _ := strings.Cut(ext, ";")
+			if strings.EqualFold(strings.TrimSpace(name), "permessage-deflate") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetCompression marks the client's connection as having negotiated
+// permessage-deflate, so larger frames are written compressed
+// Call before Start
+func (c *Client) SetCompression(compressed bool) {
+	c.compressed = compressed
+}
+
+// CountWireBytes wraps the response writer of a WebSocket upgrade so the
+// bytes written to the connection it hands over, after any compression,
+// are counted under the given compression label
+func (h *Hub) CountWireBytes(w http.ResponseWriter, compressed bool) http.ResponseWriter {
+	return &wireCounter{ResponseWriter: w, counter: h.wireBytes.With(compressionLabel(compressed))}
+}
+
+// wireCounter is a response writer whose hijacked connection counts the
+// bytes written to it
+type wireCounter struct {
+	http.ResponseWriter
+	counter *metrics.Counter
+}
+
+func (w *wireCounter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer can't be hijacked")
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &countingConn{Conn: conn, counter: w.counter}, brw, nil
+}
+
+// countingConn counts the bytes written to a connection
+type countingConn struct {
+	net.Conn
+	counter *metrics.Counter
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.counter.Add(uint64(n))
+	return n, err
+}
@@ -0,0 +1,49 @@
+package websocket
+
+import (
+	"github.com/gorilla/websocket"
+
+	"slapjack/pkg/protocol"
+)
+
+// Encoder returns the wire encoding of the client's outgoing messages
+func (c *Client) Encoder() protocol.Encoder {
+	if enc := c.encoder.Load(); enc != nil {
+		return *enc
+	}
+	return protocol.JSON
+}
+
+// SetEncoder switches the encoding of the client's outgoing messages, from
+// the next frame written. Clients tell the encodings apart by frame type, so
+// messages already queued may arrive in either.
+func (c *Client) SetEncoder(enc protocol.Encoder) {
+	c.encoder.Store(&enc)
+}
+
+// frameEncoder returns the encoding of an incoming frame: MessagePack in
+// binary frames, JSON in text frames
+func frameEncoder(frameType int) protocol.Encoder {
+	if frameType == websocket.BinaryMessage {
+		return protocol.MsgPack
+	}
+	return protocol.JSON
+}
+
+// encodeBatch encodes queued messages into one frame: JSON messages
+// separated by newlines, or MessagePack messages back to back
+func (c *Client) encodeBatch(enc protocol.Encoder, batch [][]byte) []byte {
+	var frame []byte
+	for _, message := range batch {
+		encoded, err := enc.Encode(message)
+		if err != nil {
+			c.logger().Error("failed to encode message", "encoding", enc.Name(), "error", err)
+			continue
+		}
+		if len(frame) > 0 && !enc.Binary() {
+			frame = append(frame, '\n')
+		}
+		frame = append(frame, encoded...)
+	}
+	return frame
+}
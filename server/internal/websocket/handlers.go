@@ -1,85 +1,143 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
+	"strconv"
 	"strings"
+	"time"
 
+	"slapjack/internal/game"
+	"slapjack/internal/locale"
+	"slapjack/internal/names"
+	"slapjack/internal/room"
 	"slapjack/pkg/protocol"
 )
 
-// handleMessage routes incoming messages to appropriate handlers
-func (c *Client) handleMessage(msg protocol.WSMessage) {
-	switch msg.Type {
-	case protocol.CreateRoom:
-		c.handleCreateRoom(msg.Payload)
-	case protocol.JoinRoom:
-		c.handleJoinRoom(msg.Payload)
-	case protocol.LeaveRoom:
-		c.handleLeaveRoom()
-	case protocol.UpdateSettings:
-		c.handleUpdateSettings(msg.Payload)
-	case protocol.ChangeName:
-		c.handleChangeName(msg.Payload)
-	case protocol.StartGame:
-		c.handleStartGame()
-	case protocol.PlayCard:
-		c.handlePlayCard()
-	case protocol.Slap:
-		c.handleSlap(msg.Payload, msg.Timestamp)
-	case protocol.React:
-		c.handleReact(msg.Payload)
-	case protocol.KickPlayer:
-		c.handleKickPlayer(msg.Payload)
-	case protocol.EndGame:
-		c.handleEndGame()
-	default:
-		c.sendError("UNKNOWN_MESSAGE", "Unknown message type: "+msg.Type)
+// handleMessage routes incoming messages to their registered handlers
+// handleMessage handles a parsed message, recording how long it took to parse
+// and handle, broadcasts included
+func (c *Client) handleMessage(msg protocol.WSMessage, parse time.Duration) {
+	start := c.clock.Now()
+	c.hub.applyDetach(c)
+	c.hub.router.Dispatch(c, msg)
+	if c.RoomCode != "" {
+		c.hub.rooms.TouchRoom(c.RoomCode)
 	}
+	c.hub.observeMessage(c, msg.Type, parse, c.clock.Since(start))
 }
 
-func (c *Client) handleCreateRoom(payload interface{}) {
-	data, err := json.Marshal(payload)
-	if err != nil {
-		c.sendError("INVALID_PAYLOAD", "Invalid create room payload")
+func (c *Client) handleClientHello(payload json.RawMessage) {
+	var hello protocol.ClientHelloPayload
+	if !c.decodePayload(payload, &hello, "Invalid hello payload") {
+		return
+	}
+
+	// Too old to talk to - reject and close
+	if hello.ProtocolVersion < protocol.MinProtocolVersion {
+		c.logger().Warn("rejecting client with old protocol", "protocolVersion", hello.ProtocolVersion)
+		c.SendMessage(protocol.NewMessage(protocol.ProtocolMismatch, protocol.ProtocolMismatchPayload{
+			ClientVersion:      hello.ProtocolVersion,
+			ServerVersion:      protocol.ProtocolVersion,
+			MinProtocolVersion: protocol.MinProtocolVersion,
+			Message:            "Client is too old, please refresh to update",
+		}))
+		c.closeAfterFlush()
 		return
 	}
 
+	// Newer clients are downgraded to the server's version
+	c.ProtocolVersion = hello.ProtocolVersion
+	if c.ProtocolVersion > protocol.ProtocolVersion {
+		c.ProtocolVersion = protocol.ProtocolVersion
+	}
+
+	supported := make(map[string]bool, len(protocol.ServerFeatures))
+	for _, f := range c.hub.cfg.Get().Features() {
+		supported[f] = true
+	}
+	features := make([]string, 0, len(hello.Features))
+	c.Features = make(map[string]bool)
+	for _, f := range hello.Features {
+		if supported[f] && !c.Features[f] {
+			c.Features[f] = true
+			features = append(features, f)
+		}
+	}
+
+	// The first encoding both sides know; without any, the current one stays.
+	// Long-polling clients only get JSON.
+	enc := c.Encoder()
+	for _, name := range hello.Encodings {
+		if known, ok := protocol.EncoderFor(name); ok && c.poll == nil {
+			enc = known
+			break
+		}
+	}
+
+	if hello.Locale != "" {
+		c.SetLocale(hello.Locale)
+	}
+
+	c.SendMessage(protocol.NewMessage(protocol.ServerHello, protocol.ServerHelloPayload{
+		ProtocolVersion: c.ProtocolVersion,
+		Features:        features,
+		Encoding:        enc.Name(),
+	}))
+	c.SetEncoder(enc)
+}
+
+func (c *Client) handleCreateRoom(payload json.RawMessage) {
 	var createPayload protocol.CreateRoomPayload
-	if err := json.Unmarshal(data, &createPayload); err != nil {
-		c.sendError("INVALID_PAYLOAD", "Invalid create room payload")
+	if !c.decodePayload(payload, &createPayload, "Invalid create room payload") {
 		return
 	}
 
-	if createPayload.PlayerName == "" {
-		c.sendError("INVALID_NAME", "Player name is required")
+	profile, ok := c.profileFor(createPayload.ProfileToken)
+	if !ok {
 		return
 	}
+	if createPayload.PlayerName == "" && profile != nil {
+		createPayload.PlayerName = profile.Name
+	}
 
-	if len(createPayload.PlayerName) > 20 {
-		c.sendError("INVALID_NAME", "Player name must be 20 characters or less")
+	playerName, ok := c.cleanName("playerName", createPayload.PlayerName, nil, "")
+	if !ok {
 		return
 	}
+	createPayload.PlayerName = playerName
+
+	var scheduledAt time.Time
+	if createPayload.ScheduledAt != 0 {
+		scheduledAt = time.UnixMilli(createPayload.ScheduledAt)
+		if err := c.hub.rooms.CheckSchedule(scheduledAt); err != nil {
+			c.sendLocalizedField(protocol.CodeInvalidSchedule, "scheduledAt", locale.InvalidSchedule)
+			return
+		}
+	}
 
 	// Clear any stale session data first
-	c.RoomCode = ""
-	c.PlayerID = ""
-	c.PlayerName = ""
+	c.leaveSpectating()
+	c.hub.setSeat(c, "", "", "")
 
 	// Create the room
-	room, playerID, err := c.hub.rooms.CreateRoom(createPayload.PlayerName)
+	room, playerID, err := c.hub.rooms.CreateRoom(c.SessionID, createPayload.PlayerName, c.PlayerToken, createPayload.VariantID, profile, c.hub.BroadcastToRoom)
 	if err != nil {
-		log.Printf("Failed to create room: %v", err)
-		c.sendError("CREATE_FAILED", "Failed to create room")
+		c.logger().Warn("failed to create room", "error", err)
+		c.sendCreateRoomError(err)
 		return
 	}
 
-	// Update client state
-	c.RoomCode = room.Code
-	c.PlayerID = playerID
-	c.PlayerName = createPayload.PlayerName
+	if !scheduledAt.IsZero() {
+		if err := c.hub.rooms.ScheduleStart(room.Code, scheduledAt); err != nil {
+			c.logger().Warn("failed to schedule game", "error", err)
+		}
+	}
 
-	log.Printf("[CREATE] Client %s now in room %s (PlayerID: %s)", c.SessionID, c.RoomCode, c.PlayerID)
+	// Update client state
+	c.hub.setSeat(c, room.Code, playerID, createPayload.PlayerName)
 
 	// Save session for reconnection
 	c.hub.rooms.SaveSession(c.SessionID, playerID, room.Code)
@@ -90,54 +148,139 @@ func (c *Client) handleCreateRoom(payload interface{}) {
 		Room:     room.ToProtocol(),
 	}))
 
-	log.Printf("Room created: %s by %s", room.Code, createPayload.PlayerName)
+	c.logger().Info("room created", "playerName", createPayload.PlayerName)
 }
 
-func (c *Client) handleJoinRoom(payload interface{}) {
-	data, err := json.Marshal(payload)
-	if err != nil {
-		c.sendError("INVALID_PAYLOAD", "Invalid join room payload")
-		return
+// sendCreateRoomError maps room creation failures to client error codes
+func (c *Client) sendCreateRoomError(err error) {
+	switch {
+	case errors.Is(err, room.ErrCreateCooldown):
+		c.sendError(protocol.CodeCreateCooldown, err.Error())
+	case errors.Is(err, room.ErrTooManyRooms):
+		c.sendError(protocol.CodeRoomLimit, err.Error())
+	case errors.Is(err, room.ErrUnknownVariant):
+		c.sendFieldError(protocol.CodeInvalidVariant, "variantId", err.Error())
+	default:
+		c.sendLocalized(protocol.CodeCreateFailed, locale.CreateFailed, nil)
 	}
+}
 
+func (c *Client) handleJoinRoom(payload json.RawMessage) {
 	var joinPayload protocol.JoinRoomPayload
-	if err := json.Unmarshal(data, &joinPayload); err != nil {
-		c.sendError("INVALID_PAYLOAD", "Invalid join room payload")
+	if !c.decodePayload(payload, &joinPayload, "Invalid join room payload") {
 		return
 	}
 
 	if joinPayload.RoomCode == "" {
-		c.sendError("INVALID_CODE", "Room code is required")
+		c.sendLocalizedField(protocol.CodeInvalidCode, "roomCode", locale.RoomCodeRequired)
 		return
 	}
 
 	// Normalize room code to uppercase
 	joinPayload.RoomCode = strings.ToUpper(joinPayload.RoomCode)
 
-	if joinPayload.PlayerName == "" {
-		c.sendError("INVALID_NAME", "Player name is required")
+	if joinPayload.Spectate {
+		c.spectateRoom(joinPayload.RoomCode, joinPayload.Invite)
+		return
+	}
+
+	profile, ok := c.profileFor(joinPayload.ProfileToken)
+	if !ok {
+		return
+	}
+	if joinPayload.PlayerName == "" && profile != nil {
+		joinPayload.PlayerName = profile.Name
+	}
+
+	// A player rejoining their own seat may keep its name
+	joinRoom := c.hub.rooms.GetRoom(joinPayload.RoomCode)
+	selfID := ""
+	if joinRoom != nil {
+		if p := joinRoom.GetPlayerByToken(c.PlayerToken); p != nil {
+			selfID = p.ID
+		}
+	}
+	playerName, ok := c.cleanName("playerName", joinPayload.PlayerName, joinRoom, selfID)
+	if !ok {
+		return
+	}
+	joinPayload.PlayerName = playerName
+
+	// Already seated in this room, just resend the state
+	if c.RoomCode == joinPayload.RoomCode {
+		if room := c.hub.rooms.GetRoom(c.RoomCode); room != nil && room.GetPlayer(c.PlayerID) != nil {
+			c.SendMessage(protocol.NewMessage(protocol.RoomJoined, protocol.RoomJoinedPayload{
+				Room: room.ToProtocol(),
+			}))
+			return
+		}
+	}
+
+	// Join the room, only leaving the one the client is in once it has, so a
+	// failed join leaves it where it was
+	c.logger().Debug("joining room", "joinRoomCode", joinPayload.RoomCode, "playerName", joinPayload.PlayerName)
+	room, playerID, player, err := c.hub.rooms.JoinRoom(joinPayload.RoomCode, joinPayload.PlayerName, c.PlayerToken, profile, joinPayload.Invite)
+	if err != nil {
+		c.logger().Info("failed to join room", "joinRoomCode", joinPayload.RoomCode, "error", err)
+		c.sendNameError(protocol.CodeJoinFailed, "playerName", err)
+		return
+	}
+
+	c.leaveOtherRooms(room.Code)
+	c.enterRoom(room, playerID, player)
+}
+
+// handlePlayNow seats the player in an open drop-in room for the chosen preset
+func (c *Client) handlePlayNow(payload json.RawMessage) {
+	var playPayload protocol.PlayNowPayload
+	if !c.decodePayload(payload, &playPayload, "Invalid play now payload") {
+		return
+	}
+	if playPayload.Preset == "" {
+		playPayload.Preset = protocol.PresetClassic
+	}
+
+	profile, ok := c.profileFor(playPayload.ProfileToken)
+	if !ok {
 		return
 	}
+	if playPayload.PlayerName == "" && profile != nil {
+		playPayload.PlayerName = profile.Name
+	}
 
-	if len(joinPayload.PlayerName) > 20 {
-		c.sendError("INVALID_NAME", "Player name must be 20 characters or less")
+	playerName, ok := c.cleanName("playerName", playPayload.PlayerName, nil, "")
+	if !ok {
 		return
 	}
 
-	// Join the room
-	log.Printf("[JOIN] Attempting to join room %s as %s", joinPayload.RoomCode, joinPayload.PlayerName)
-	room, playerID, player, err := c.hub.rooms.JoinRoom(joinPayload.RoomCode, joinPayload.PlayerName)
+	dropIn, playerID, player, err := c.hub.rooms.PlayNow(playPayload.Preset, playerName, c.PlayerToken, profile)
+	if errors.Is(err, room.ErrUnknownPreset) {
+		c.sendFieldError(protocol.CodeInvalidPreset, "preset", err.Error())
+		return
+	}
 	if err != nil {
-		log.Printf("[JOIN] Failed to join room %s: %v", joinPayload.RoomCode, err)
-		c.sendError("JOIN_FAILED", err.Error())
+		c.logger().Info("failed to find a drop-in room", "preset", playPayload.Preset, "error", err)
+		c.sendNameError(protocol.CodeJoinFailed, "playerName", err)
 		return
 	}
-	log.Printf("[JOIN] Successfully joined room %s, playerID: %s", joinPayload.RoomCode, playerID)
 
+	c.leaveOtherRooms(dropIn.Code)
+	c.enterRoom(dropIn, playerID, player)
+}
+
+// leaveOtherRooms takes the client out of the room it is in or watching, and
+// its session out of every room but the one it has just joined
+func (c *Client) leaveOtherRooms(keepCode string) {
+	c.leaveSpectating()
+	c.hub.setSeat(c, "", "", "")
+	c.hub.rooms.LeaveOtherRooms(c.SessionID, keepCode, c.hub.BroadcastToRoom)
+}
+
+// enterRoom finishes seating the client in a room it has just joined, sending
+// it the room and telling the other players
+func (c *Client) enterRoom(room *room.Room, playerID string, player *room.Player) {
 	// Update client state
-	c.RoomCode = room.Code
-	c.PlayerID = playerID
-	c.PlayerName = joinPayload.PlayerName
+	c.hub.setSeat(c, room.Code, playerID, player.Name)
 
 	// Save session for reconnection
 	c.hub.rooms.SaveSession(c.SessionID, playerID, room.Code)
@@ -148,133 +291,290 @@ func (c *Client) handleJoinRoom(payload interface{}) {
 	}))
 
 	// Notify other players
-	log.Printf("[JOIN] About to broadcast PLAYER_JOINED to room %s (excluding %s)", room.Code, c.SessionID)
 	msgData, _ := json.Marshal(protocol.NewMessage(protocol.PlayerJoined, protocol.PlayerJoinedPayload{
 		Player: player.ToProtocol(),
 	}))
 	c.hub.BroadcastToRoomExcept(room.Code, c.SessionID, msgData)
+	c.hub.rooms.NotifyMembershipChanged(room.Code, c.hub.BroadcastToRoom)
 
-	log.Printf("[JOIN] Player %s joined room %s", joinPayload.PlayerName, room.Code)
+	c.logger().Info("player joined room", "playerName", player.Name)
 }
 
-func (c *Client) handleLeaveRoom() {
-	if c.RoomCode == "" {
-		c.sendError("NOT_IN_ROOM", "You are not in a room")
+// profileFor looks up the profile a CREATE_ROOM or JOIN_ROOM refers to, if any
+// Sends a field error and returns false if the token doesn't refer to one
+func (c *Client) profileFor(token string) (*room.Profile, bool) {
+	if token == "" {
+		return nil, true
+	}
+	profile, err := c.hub.rooms.GetProfile(token)
+	if err != nil {
+		c.sendLocalizedField(protocol.CodeProfileNotFound, "profileToken", locale.ProfileNotFound)
+		return nil, false
+	}
+	return profile, true
+}
+
+func (c *Client) handleRegisterProfile(payload json.RawMessage) {
+	var profilePayload protocol.RegisterProfilePayload
+	if !c.decodePayload(payload, &profilePayload, "Invalid profile payload") {
 		return
 	}
 
-	roomCode := c.RoomCode
-	playerID := c.PlayerID
+	name, ok := c.cleanName("name", profilePayload.Name, nil, "")
+	if !ok {
+		return
+	}
 
-	// Leave the room
-	c.hub.rooms.LeaveRoom(roomCode, playerID)
+	profile, token, err := c.hub.rooms.RegisterProfile(profilePayload.ProfileToken, name, profilePayload.Avatar)
+	switch {
+	case errors.Is(err, room.ErrInvalidAvatar):
+		c.sendFieldError(protocol.CodeInvalidAvatar, "avatar", err.Error())
+		return
+	case errors.Is(err, room.ErrProfileNotFound):
+		c.sendLocalizedField(protocol.CodeProfileNotFound, "profileToken", locale.ProfileNotFound)
+		return
+	case err != nil:
+		c.sendError(protocol.CodeInvalidPayload, err.Error())
+		return
+	}
 
-	// Notify other players
-	msgData, _ := json.Marshal(protocol.NewMessage(protocol.PlayerLeft, protocol.PlayerLeftPayload{
-		PlayerID: playerID,
+	c.SendMessage(protocol.NewMessage(protocol.ProfileRegistered, protocol.ProfileRegisteredPayload{
+		Profile:      profile.ToProtocol(),
+		ProfileToken: token,
 	}))
-	c.hub.BroadcastToRoomExcept(roomCode, c.SessionID, msgData)
 
-	// Clear client state
-	c.RoomCode = ""
-	c.PlayerID = ""
-	c.PlayerName = ""
-
-	log.Printf("Player left room %s", roomCode)
+	c.logger().Info("profile registered", "profileId", profile.ID)
 }
 
-func (c *Client) handleUpdateSettings(payload interface{}) {
-	if c.RoomCode == "" {
-		c.sendError("NOT_IN_ROOM", "You are not in a room")
+func (c *Client) handleGetWallet() {
+	wallet, err := c.hub.rooms.GetWallet(c.PlayerToken)
+	if err != nil {
+		c.sendWalletError(err)
 		return
 	}
 
-	room := c.hub.rooms.GetRoom(c.RoomCode)
-	if room == nil {
-		c.sendError("ROOM_NOT_FOUND", "Room not found")
+	payload := wallet.ToProtocol()
+	payload.Catalog = room.Catalog
+	c.SendMessage(protocol.NewMessage(protocol.WalletUpdated, payload))
+}
+
+func (c *Client) handleBuyItem(payload json.RawMessage) {
+	itemID, ok := c.parseItem(payload)
+	if !ok {
 		return
 	}
 
-	// Only host can update settings
-	if room.HostID != c.PlayerID {
-		c.sendError("NOT_HOST", "Only the host can change settings")
+	wallet, err := c.hub.rooms.BuyItem(c.PlayerToken, itemID)
+	if err != nil {
+		c.sendWalletError(err)
 		return
 	}
+	c.SendMessage(protocol.NewMessage(protocol.WalletUpdated, wallet.ToProtocol()))
 
-	// Can't change settings during game
-	if room.Status != "waiting" {
-		c.sendError("GAME_IN_PROGRESS", "Cannot change settings while game is in progress")
+	c.logger().Info("item bought", "itemId", itemID)
+}
+
+// handleEquipItem equips an owned item, showing it right away in the player's room
+func (c *Client) handleEquipItem(payload json.RawMessage) {
+	itemID, ok := c.parseItem(payload)
+	if !ok {
 		return
 	}
 
-	data, err := json.Marshal(payload)
+	wallet, err := c.hub.rooms.EquipItem(c.PlayerToken, itemID)
 	if err != nil {
-		c.sendError("INVALID_PAYLOAD", "Invalid settings payload")
+		c.sendWalletError(err)
 		return
 	}
+	c.SendMessage(protocol.NewMessage(protocol.WalletUpdated, wallet.ToProtocol()))
 
-	var settingsPayload protocol.UpdateSettingsPayload
-	if err := json.Unmarshal(data, &settingsPayload); err != nil {
-		c.sendError("INVALID_PAYLOAD", "Invalid settings payload")
+	if c.PlayerID != "" && c.hub.rooms.SetLoadout(c.RoomCode, c.PlayerID, wallet.Equipped) {
+		c.hub.rooms.NotifyMembershipChanged(c.RoomCode, c.hub.BroadcastToRoom)
+	}
+}
+
+// parseItem reads the item ID from a BUY_ITEM or EQUIP_ITEM payload
+func (c *Client) parseItem(payload json.RawMessage) (string, bool) {
+	var itemPayload protocol.ItemPayload
+	if !c.decodePayload(payload, &itemPayload, "Invalid item payload") {
+		return "", false
+	}
+	if itemPayload.ItemID == "" {
+		c.sendLocalizedField(protocol.CodeInvalidPayload, "itemId", locale.ItemIDRequired)
+		return "", false
+	}
+	return itemPayload.ItemID, true
+}
+
+// sendWalletError maps wallet failures to client error codes
+func (c *Client) sendWalletError(err error) {
+	switch {
+	case errors.Is(err, room.ErrUnknownItem):
+		c.sendFieldError(protocol.CodeUnknownItem, "itemId", err.Error())
+	case errors.Is(err, room.ErrItemNotOwned):
+		c.sendFieldError(protocol.CodeItemNotOwned, "itemId", err.Error())
+	case errors.Is(err, room.ErrAlreadyOwned):
+		c.sendFieldError(protocol.CodeAlreadyOwned, "itemId", err.Error())
+	case errors.Is(err, room.ErrNotEnoughCoins):
+		c.sendError(protocol.CodeNotEnoughCoins, err.Error())
+	default:
+		c.logger().Warn("wallet update failed", "error", err)
+		c.sendLocalized(protocol.CodeWalletFailed, locale.WalletUnavailable, nil)
+	}
+}
+
+// spectateRoom starts watching a room without taking a seat
+func (c *Client) spectateRoom(roomCode, invite string) {
+	c.leaveSpectating()
+	c.hub.setSeat(c, "", "", "")
+	c.hub.rooms.CleanupPlayerRooms(c.SessionID, c.hub.BroadcastToRoom)
+
+	r, err := c.hub.rooms.SpectateRoom(roomCode, c.SessionID, invite)
+	switch {
+	case errors.Is(err, room.ErrInviteOnly):
+		c.sendLocalized(protocol.CodeInviteOnly, locale.InviteOnly, nil)
+		return
+	case errors.Is(err, room.ErrSpectatorsFull):
+		c.sendLocalized(protocol.CodeSpectatorsFull, locale.SpectatorsFull, nil)
+		return
+	case errors.Is(err, room.ErrInvalidInvite):
+		c.sendLocalizedField(protocol.CodeInvalidInvite, "invite", locale.InvalidInvite)
+		return
+	case err != nil:
+		c.sendError(protocol.CodeJoinFailed, err.Error())
 		return
 	}
 
-	// Update settings
-	room.UpdateSettings(settingsPayload)
+	c.hub.setSpectating(c, r.Code)
 
-	// Broadcast to all players in room
-	msgData, _ := json.Marshal(protocol.NewMessage(protocol.SettingsChanged, room.Settings))
-	c.hub.BroadcastToRoom(c.RoomCode, msgData)
+	c.SendMessage(protocol.NewMessage(protocol.RoomJoined, protocol.RoomJoinedPayload{
+		Room: r.ToProtocol(),
+	}))
+	c.hub.rooms.NotifyMembershipChanged(r.Code, c.hub.BroadcastToRoom)
+
+	// Catch up on a game in progress, full pile included
+	if r.Game != nil {
+		c.hub.SendResync(c, r)
+	}
+
+	c.logger().Info("client is spectating")
+}
 
-	log.Printf("Settings updated in room %s", c.RoomCode)
+// leaveSpectating stops watching the current room, if spectating
+func (c *Client) leaveSpectating() {
+	if !c.Spectating {
+		return
+	}
+
+	roomCode := c.RoomCode
+	c.hub.setSeat(c, "", "", "")
+	c.hub.rooms.StopSpectating(roomCode, c.SessionID)
+	c.hub.rooms.NotifyMembershipChanged(roomCode, c.hub.BroadcastToRoom)
+}
+
+func (c *Client) handleLeaveRoom() {
+	if c.Spectating {
+		c.leaveSpectating()
+		return
+	}
+
+	roomCode := c.RoomCode
+	playerID := c.PlayerID
+	logger := c.logger()
+
+	// Clear client state
+	c.hub.setSeat(c, "", "", "")
+
+	// Leave the room and notify other players
+	c.hub.rooms.LeaveRoom(roomCode, playerID, c.hub.BroadcastToRoom)
+
+	logger.Info("player left room")
 }
 
-func (c *Client) handleChangeName(payload interface{}) {
-	if c.RoomCode == "" {
-		c.sendError("NOT_IN_ROOM", "You are not in a room")
+func (c *Client) handleUpdateSettings(payload json.RawMessage) {
+	var settingsPayload protocol.UpdateSettingsPayload
+	if !c.decodePayload(payload, &settingsPayload, "Invalid settings payload") {
+		return
+	}
+	if settingsPayload.Preset != "" && !room.ValidSettingsPreset(settingsPayload.Preset) {
+		c.sendLocalizedField(protocol.CodeInvalidPreset, "preset", locale.UnknownPreset)
+		return
+	}
+	if _, err := room.DeckFromProtocol(settingsPayload.Deck); err != nil {
+		c.sendFieldError(protocol.CodeInvalidDeck, "deck", err.Error())
 		return
 	}
 
 	room := c.hub.rooms.GetRoom(c.RoomCode)
 	if room == nil {
-		c.sendError("ROOM_NOT_FOUND", "Room not found")
+		c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
 		return
 	}
 
-	// Can't change name during game
+	// Can't change settings during game
 	if room.Status != "waiting" {
-		c.sendError("GAME_IN_PROGRESS", "Cannot change name while game is in progress")
+		c.sendLocalized(protocol.CodeGameInProgress, locale.SettingsInGame, nil)
 		return
 	}
 
-	data, err := json.Marshal(payload)
-	if err != nil {
-		c.sendError("INVALID_PAYLOAD", "Invalid name payload")
+	// Rooms already in ratscrew mode keep it, but no new ones can switch to it
+	if settingsPayload.GameMode == game.ModeRatscrew && !c.hub.cfg.Get().FeatureEnabled(protocol.FeatureRatscrew) {
+		settingsPayload.GameMode = room.Settings.GameMode
+	}
+
+	// Names chosen before classroom mode must meet its rules too
+	if settingsPayload.ClassroomMode && !room.Settings.ClassroomMode {
+		if offending := room.ClassroomNameViolations(); len(offending) > 0 {
+			c.sendFieldError(protocol.CodeInvalidName, "classroomMode", "rename or remove "+strings.Join(offending, ", ")+" before turning on classroom mode")
+			return
+		}
+	}
+
+	// Update settings
+	teams := room.Settings.Teams
+	room.UpdateSettings(settingsPayload)
+
+	// Broadcast to all players in room
+	msgData, _ := json.Marshal(protocol.NewMessage(protocol.SettingsChanged, room.Settings))
+	c.hub.BroadcastToRoom(c.RoomCode, msgData)
+	if room.Settings.Teams != teams {
+		c.broadcastTeams(room)
+	}
+
+	c.logger().Info("settings updated")
+}
+
+func (c *Client) handleChangeName(payload json.RawMessage) {
+	room := c.hub.rooms.GetRoom(c.RoomCode)
+	if room == nil {
+		c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
 		return
 	}
 
-	var namePayload protocol.ChangeNamePayload
-	if err := json.Unmarshal(data, &namePayload); err != nil {
-		c.sendError("INVALID_PAYLOAD", "Invalid name payload")
+	// Can't change name during game
+	if room.Status != "waiting" {
+		c.sendLocalized(protocol.CodeGameInProgress, locale.NameInGame, nil)
 		return
 	}
 
-	if namePayload.NewName == "" {
-		c.sendError("INVALID_NAME", "Name cannot be empty")
+	var namePayload protocol.ChangeNamePayload
+	if !c.decodePayload(payload, &namePayload, "Invalid name payload") {
 		return
 	}
 
-	if len(namePayload.NewName) > 20 {
-		c.sendError("INVALID_NAME", "Name must be 20 characters or less")
+	newName, ok := c.cleanName("newName", namePayload.NewName, room, c.PlayerID)
+	if !ok {
 		return
 	}
 
 	// Update player name
-	player := room.GetPlayer(c.PlayerID)
-	if player != nil {
-		player.Name = namePayload.NewName
-		c.PlayerName = namePayload.NewName
+	var err error
+	namePayload.NewName, err = room.RenamePlayer(c.PlayerID, newName)
+	if err != nil {
+		c.sendNameError(protocol.CodeInvalidName, "newName", err)
+		return
 	}
+	c.hub.setPlayerName(c, namePayload.NewName)
 
 	// Broadcast name change to all players
 	msgData, _ := json.Marshal(protocol.NewMessage(protocol.NameChanged, protocol.NameChangedPayload{
@@ -283,162 +583,376 @@ func (c *Client) handleChangeName(payload interface{}) {
 	}))
 	c.hub.BroadcastToRoom(c.RoomCode, msgData)
 
-	log.Printf("Player %s changed name to %s in room %s", c.PlayerID, namePayload.NewName, c.RoomCode)
+	c.logger().Info("player changed name", "playerName", namePayload.NewName)
 }
 
 func (c *Client) handleStartGame() {
-	if c.RoomCode == "" {
-		c.sendError("NOT_IN_ROOM", "You are not in a room")
-		return
-	}
-
 	room := c.hub.rooms.GetRoom(c.RoomCode)
 	if room == nil {
-		c.sendError("ROOM_NOT_FOUND", "Room not found")
+		c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
 		return
 	}
 
-	// Only host can start game
-	if room.HostID != c.PlayerID {
-		c.sendError("NOT_HOST", "Only the host can start the game")
+	// Need at least the variant's minimum, or 2 players
+	if minPlayers := room.MinPlayers(); len(room.GetConnectedPlayers()) < minPlayers {
+		c.sendLocalized(protocol.CodeNotEnoughPlayers, locale.NeedPlayers, locale.Params{"count": strconv.Itoa(minPlayers)})
+		return
+	}
+	if err := room.TeamsReady(); err != nil {
+		c.sendError(protocol.CodeNotEnoughPlayers, err.Error())
 		return
 	}
 
-	// Need at least 2 players
-	if len(room.GetConnectedPlayers()) < 2 {
-		c.sendError("NOT_ENOUGH_PLAYERS", "Need at least 2 players to start")
+	// Start the game with countdown, after a ready check if the room has one
+	if err := c.hub.rooms.HostStartGame(c.RoomCode, c.hub.BroadcastToRoom); err != nil {
+		c.sendError(protocol.CodeGameInProgress, err.Error())
 		return
 	}
 
-	// Start the game with countdown
-	go c.hub.rooms.StartGameCountdown(c.RoomCode, c.hub.BroadcastToRoom)
+	c.logger().Info("game starting")
+}
 
-	log.Printf("Game starting in room %s", c.RoomCode)
+// handleReady answers the ready check for a host-started game
+func (c *Client) handleReady() {
+	if err := c.hub.rooms.MarkReady(c.RoomCode, c.PlayerID, c.hub.BroadcastToRoom); err != nil {
+		c.sendLocalized(protocol.CodeNotStarting, locale.NoReadyCheck, nil)
+	}
 }
 
-func (c *Client) handlePlayCard() {
-	if c.RoomCode == "" {
-		c.sendError("NOT_IN_ROOM", "You are not in a room")
+func (c *Client) handleCancelStart() {
+	if !c.hub.rooms.CancelCountdown(c.RoomCode, protocol.StartCancelledByHost, c.hub.BroadcastToRoom) {
+		c.sendLocalized(protocol.CodeNotStarting, locale.NotStarting, nil)
 		return
 	}
+	c.hub.rooms.NotifyMembershipChanged(c.RoomCode, c.hub.BroadcastToRoom)
+
+	c.logger().Info("game start cancelled by host")
+}
 
+func (c *Client) handlePlayCard(payload json.RawMessage) {
 	room := c.hub.rooms.GetRoom(c.RoomCode)
 	if room == nil {
-		c.sendError("ROOM_NOT_FOUND", "Room not found")
+		c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
+		return
+	}
+
+	g, pile := room.GameFor(c.PlayerID)
+	if g == nil {
+		c.sendLocalized(protocol.CodeNoGame, locale.GameNotStarted, nil)
 		return
 	}
 
-	if room.Game == nil {
-		c.sendError("NO_GAME", "Game has not started")
+	var playPayload protocol.PlayCardPayload
+	if !c.decodePayload(payload, &playPayload, "Invalid play payload") {
 		return
 	}
 
 	// Play the card
-	card, err := room.Game.PlayCard(c.PlayerID)
+	_, challenge, err := g.PlayCard(c.PlayerID, playPayload.TurnToken)
+	if errors.Is(err, game.ErrStalePlay) {
+		// A duplicate of a play that already went through; the client has or
+		// will get the resulting CARD_PLAYED, so there's nothing to report
+		c.logger().Debug("duplicate play ignored", "turnToken", playPayload.TurnToken)
+		return
+	}
 	if err != nil {
-		c.sendError("PLAY_FAILED", err.Error())
+		c.sendError(protocol.CodePlayFailed, err.Error())
 		return
 	}
 
-	// Broadcast card played
-	msgData, _ := json.Marshal(protocol.NewMessage(protocol.CardPlayed, protocol.CardPlayedPayload{
-		PlayerID:  c.PlayerID,
-		Card:      card.ToProtocol(),
-		PileCount: len(room.Game.Pile),
-	}))
-	c.hub.BroadcastToRoom(c.RoomCode, msgData)
+	// Broadcast the card played and any face-card challenge it started or resolved
+	broadcast := c.hub.pileBroadcast(pile)
+	c.hub.broadcastGameEvents(c.RoomCode, g, broadcast)
+	c.hub.reportSuspicions(c.RoomCode, room, g)
 
-	// Check for auto-slappable condition and broadcast turn change
-	nextPlayer := room.Game.GetCurrentPlayer()
-	turnMsg, _ := json.Marshal(protocol.NewMessage(protocol.TurnChanged, protocol.TurnChangedPayload{
-		CurrentPlayerID: nextPlayer,
-	}))
-	c.hub.BroadcastToRoom(c.RoomCode, turnMsg)
+	if challenge != nil && challenge.Won && c.hub.checkPileOver(c.RoomCode, room, g, pile) {
+		return
+	}
+
+	// Broadcast turn change
+	broadcast(c.RoomCode, g.TurnChangedMessage())
 
 	// Start turn timer
-	go room.Game.StartTurnTimer(c.RoomCode, c.hub.BroadcastToRoom, c.hub.rooms)
+	go g.StartTurnTimer(c.RoomCode, broadcast, c.hub.rooms)
 }
 
-func (c *Client) handleSlap(payload interface{}, serverTimestamp int64) {
-	if c.RoomCode == "" {
-		c.sendError("NOT_IN_ROOM", "You are not in a room")
-		return
-	}
-
+func (c *Client) handleSlap(payload json.RawMessage, serverTimestamp int64) {
 	room := c.hub.rooms.GetRoom(c.RoomCode)
 	if room == nil {
-		c.sendError("ROOM_NOT_FOUND", "Room not found")
+		c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
 		return
 	}
 
-	if room.Game == nil {
-		c.sendError("NO_GAME", "Game has not started")
+	g, pile := room.GameFor(c.PlayerID)
+	if g == nil {
+		c.sendLocalized(protocol.CodeNoGame, locale.GameNotStarted, nil)
 		return
 	}
+	broadcast := c.hub.pileBroadcast(pile)
 
 	// Parse client timestamp
 	var slapPayload protocol.SlapPayload
-	if payload != nil {
-		data, _ := json.Marshal(payload)
-		json.Unmarshal(data, &slapPayload)
+	if !c.decodePayload(payload, &slapPayload, "Invalid slap payload") {
+		return
 	}
 
 	// Broadcast that player attempted slap (for visual feedback)
 	player := room.GetPlayer(c.PlayerID)
+	if player == nil {
+		c.sendLocalized(protocol.CodeNotAPlayer, locale.SeatedOnlySlap, nil)
+		return
+	}
 	attemptMsg, _ := json.Marshal(protocol.NewMessage(protocol.SlapAttempted, protocol.SlapAttemptedPayload{
 		PlayerID:   c.PlayerID,
 		PlayerName: player.Name,
 	}))
-	c.hub.BroadcastToRoom(c.RoomCode, attemptMsg)
+	broadcast(c.RoomCode, attemptMsg)
+
+	// Process the slap and broadcast the result, once the slap window
+	// closes for a valid one
+	result, opened := g.ProcessSlap(c.PlayerID, serverTimestamp, slapPayload.Timestamp)
+	roomCode := c.RoomCode
+	if result.Reason == game.SlapPending {
+		// The slap that opens the window settles it; the turn timer may
+		// already have if it ran out first
+		if opened {
+			c.hub.clock.AfterFunc(g.SlapWindow, func() {
+				if result, settled := g.SettleSlaps(); settled {
+					c.hub.afterSlap(roomCode, room, g, pile, result)
+				}
+			})
+		}
+		return
+	}
+	c.hub.afterSlap(roomCode, room, g, pile, result)
+}
 
-	// Process the slap
-	result := room.Game.ProcessSlap(c.PlayerID, serverTimestamp, slapPayload.Timestamp)
+// afterSlap broadcasts a slap's result and anything it set off, and whose
+// turn it is if the slap won the pile
+func (h *Hub) afterSlap(roomCode string, r *room.Room, g *game.Game, pile int, result protocol.SlapResultPayload) {
+	broadcast := h.pileBroadcast(pile)
+	h.broadcastGameEvents(roomCode, g, broadcast)
+	h.reportSuspicions(roomCode, r, g)
 
-	// Broadcast result
-	resultMsg, _ := json.Marshal(protocol.NewMessage(protocol.SlapResult, result))
-	c.hub.BroadcastToRoom(c.RoomCode, resultMsg)
+	// Check for elimination and game over
+	if h.checkPileOver(roomCode, r, g, pile) {
+		return
+	}
 
-	// Check for elimination
-	eliminatedPlayers := room.Game.CheckEliminations()
-	for _, playerID := range eliminatedPlayers {
-		elimMsg, _ := json.Marshal(protocol.NewMessage(protocol.PlayerEliminated, protocol.PlayerEliminatedPayload{
-			PlayerID: playerID,
-		}))
-		c.hub.BroadcastToRoom(c.RoomCode, elimMsg)
+	if result.Success {
+		// Winner of slap plays next
+		broadcast(roomCode, g.TurnChangedMessage())
+
+		// Under adaptive pacing the winner gets the longer post-claim timeout
+		if g.Paced() {
+			g.CancelTurnTimer()
+			go g.StartTurnTimer(roomCode, broadcast, h.rooms)
+		}
 	}
+}
 
-	// Check for game over
-	if winner := room.Game.CheckWinner(); winner != "" {
-		winnerPlayer := room.GetPlayer(winner)
-		gameOverMsg, _ := json.Marshal(protocol.NewMessage(protocol.GameOver, protocol.GameOverPayload{
-			WinnerID:   winner,
-			WinnerName: winnerPlayer.Name,
-			Stats:      room.Game.GetStats(),
+// reportSuspicions logs any newly flagged play patterns and reaction times in
+// a game and shows them to the host. Flags are for review only; nobody is
+// penalized automatically
+func (h *Hub) reportSuspicions(roomCode string, r *room.Room, g *game.Game) {
+	for _, flag := range h.rooms.CollectSuspicions(roomCode, g) {
+		msgData, _ := json.Marshal(protocol.NewMessage(protocol.SuspicionFlagged, protocol.SuspicionFlaggedPayload{
+			RoomCode: roomCode,
+			Flag:     flag,
 		}))
-		c.hub.BroadcastToRoom(c.RoomCode, gameOverMsg)
-		room.Status = "finished"
-	} else if result.Success {
-		// Winner of slap plays next
-		turnMsg, _ := json.Marshal(protocol.NewMessage(protocol.TurnChanged, protocol.TurnChangedPayload{
-			CurrentPlayerID: result.PlayerID,
+		h.SendToPlayer(roomCode, r.HostID, msgData)
+	}
+	for _, warning := range h.rooms.CollectCheatWarnings(roomCode, g) {
+		msgData, _ := json.Marshal(protocol.NewMessage(protocol.CheatWarningMsg, protocol.CheatWarningPayload{
+			RoomCode: roomCode,
+			Warning:  warning,
 		}))
-		c.hub.BroadcastToRoom(c.RoomCode, turnMsg)
+		h.SendToPlayer(roomCode, r.HostID, msgData)
 	}
 }
 
-func (c *Client) handleReact(payload interface{}) {
-	if c.RoomCode == "" {
-		return
+// broadcastEvents tells a room about its game's events not yet broadcast,
+// followed by any notable moments they made
+func (h *Hub) broadcastEvents(roomCode string, r *room.Room) {
+	h.broadcastGameEvents(roomCode, r.Game, h.BroadcastToRoom)
+}
+
+// broadcastGameEvents tells the room about one game's events not yet
+// broadcast, followed by any notable moments they made
+func (h *Hub) broadcastGameEvents(roomCode string, g *game.Game, broadcast func(string, []byte)) {
+	for _, msgData := range g.DrainMessages() {
+		broadcast(roomCode, msgData)
+	}
+	for _, msgData := range g.DrainHighlights() {
+		broadcast(roomCode, msgData)
 	}
+}
 
-	// Just broadcast the reaction to all players
-	data, err := json.Marshal(payload)
+// checkGameOver broadcasts eliminations and, if a winner is decided, the game over message
+// Returns true if the game ended
+func (c *Client) checkGameOver(r *room.Room) bool {
+	return c.hub.checkGameOver(c.RoomCode, r)
+}
+
+// pileBroadcast broadcasts to a room with every message marked with a party
+// pile, or unmarked for pile 0
+func (h *Hub) pileBroadcast(pile int) func(string, []byte) {
+	return room.PileBroadcast(pile, h.BroadcastToRoom)
+}
+
+// checkPileOver checks for the end of the game a player is in: the room's,
+// or one of a party room's piles, which once won sends its winner to the
+// finale
+// Returns true if the game has ended
+func (h *Hub) checkPileOver(roomCode string, r *room.Room, g *game.Game, pile int) bool {
+	if pile == 0 {
+		return h.checkGameOver(roomCode, r)
+	}
+
+	g.CheckEliminations()
+	h.broadcastGameEvents(roomCode, g, h.pileBroadcast(pile))
+	winner, _ := g.CheckWin()
+	if winner == "" {
+		return false
+	}
+	if !g.RecordGameOver(winner) {
+		return true
+	}
+	g.CancelTurnTimer()
+
+	winnerName := ""
+	if winnerPlayer := r.GetPlayer(winner); winnerPlayer != nil {
+		winnerName = winnerPlayer.Name
+	}
+	wonMsg, _ := json.Marshal(protocol.NewMessage(protocol.PileWon, protocol.PileWonPayload{
+		Pile:       pile,
+		WinnerID:   winner,
+		WinnerName: winnerName,
+	}))
+	h.BroadcastToRoom(roomCode, wonMsg)
+	h.rooms.NotifyPileWon(r, pile, winnerName)
+	slog.Info("party pile won", "roomCode", roomCode, "pile", pile, "winnerId", winner)
+
+	if finalists := r.PileWon(pile, winner); finalists != nil {
+		h.rooms.StartFinale(roomCode, finalists, h.BroadcastToRoom)
+	}
+	return true
+}
+
+// checkGameOver broadcasts a room's eliminations and, if its game's win
+// condition decides a winner, the game over message and wallet payouts
+// Returns true if the game has ended, by this call or an earlier one
+func (h *Hub) checkGameOver(roomCode string, r *room.Room) bool {
+	// Check for elimination
+	r.Game.CheckEliminations()
+	h.broadcastEvents(roomCode, r)
+
+	// Check for game over
+	winner, reason := r.Game.CheckWin()
+	if winner == "" {
+		return false
+	}
+	if !r.Game.RecordGameOver(winner) {
+		return true
+	}
+	r.Game.CancelTurnTimer()
+
+	winnerName := ""
+	if winnerPlayer := r.GetPlayer(winner); winnerPlayer != nil {
+		winnerName = winnerPlayer.Name
+	}
+	result := h.rooms.SaveReplay(roomCode, r.Game)
+	awards := r.Game.ComputeAwards()
+	gameOverMsg, _ := json.Marshal(protocol.NewMessage(protocol.GameOver, protocol.GameOverPayload{
+		GameID:      r.Game.ID,
+		WinnerID:    winner,
+		WinnerName:  winnerName,
+		WinningTeam: r.Game.TeamOf(winner),
+		WinReason:   reason,
+		Stats:       r.Game.GetStats(),
+		Awards:      awards,
+		Result:      result,
+	}))
+	h.BroadcastToRoom(roomCode, gameOverMsg)
+	r.Status = "finished"
+
+	for playerID, wallet := range h.rooms.PayOut(r, winner, awards) {
+		walletMsg, _ := json.Marshal(protocol.NewMessage(protocol.WalletUpdated, wallet))
+		h.SendToPlayer(roomCode, playerID, walletMsg)
+	}
+	h.rooms.RecordLeaderboard(r, winner)
+	h.rooms.RecordMatch(r, winner, reason)
+	h.rooms.NotifyGameOver(r, winnerName, reason)
+	return true
+}
+
+// cleanName normalizes a player name and checks it against the server's name
+// rules and, when joining or renaming in a room, the room's other players
+// Sends a field error and returns false if the name is rejected
+// selfID is the player whose own name doesn't count against them
+func (c *Client) cleanName(field, name string, r *room.Room, selfID string) (string, bool) {
+	cleaned, err := c.hub.nameValidator.Load().Clean(name)
+	if err == nil && r != nil {
+		err = classroomNameError(r, cleaned)
+	}
+	if err == nil && r != nil {
+		// Exact duplicates are left to the room's duplicate name setting
+		var others []string
+		for _, p := range r.GetAllPlayers() {
+			if p.ID != selfID && !strings.EqualFold(p.Name, cleaned) {
+				others = append(others, p.Name)
+			}
+		}
+		if names.Impersonates(cleaned, others) {
+			err = names.ErrImpersonate
+		}
+	}
 	if err != nil {
+		c.sendFieldError(protocol.CodeInvalidName, field, err.Error())
+		return "", false
+	}
+	return cleaned, true
+}
+
+// sendNameError reports a name clash as NAME_TAKEN on the name field, and any
+// other failure with the given code
+func (c *Client) sendNameError(code protocol.ErrorCode, field string, err error) {
+	if errors.Is(err, room.ErrNameTaken) {
+		c.sendFieldError(protocol.CodeNameTaken, field, err.Error())
+		return
+	}
+	if errors.Is(err, room.ErrBanned) {
+		c.sendError(protocol.CodeBanned, err.Error())
+		return
+	}
+	if errors.Is(err, room.ErrInviteOnly) {
+		c.sendLocalized(protocol.CodeInviteOnly, locale.InviteOnly, nil)
+		return
+	}
+	if errors.Is(err, room.ErrInvalidInvite) {
+		c.sendLocalizedField(protocol.CodeInvalidInvite, "invite", locale.InvalidInvite)
 		return
 	}
+	c.sendError(code, err.Error())
+}
+
+// classroomNameError applies the strict classroom name rules if the room uses them
+func classroomNameError(r *room.Room, name string) error {
+	if r == nil || !r.Settings.ClassroomMode {
+		return nil
+	}
+	return room.ValidateClassroomName(name)
+}
 
+func (c *Client) handleReact(payload json.RawMessage) {
+	// Reactions are disabled in classroom mode
+	if r := c.hub.rooms.GetRoom(c.RoomCode); r != nil && r.Settings.ClassroomMode {
+		return
+	}
+
+	// Just broadcast the reaction to all players
 	var reactPayload protocol.ReactPayload
-	if err := json.Unmarshal(data, &reactPayload); err != nil {
+	if !c.decodePayload(payload, &reactPayload, "Invalid reaction payload") {
 		return
 	}
 
@@ -450,96 +964,299 @@ func (c *Client) handleReact(payload interface{}) {
 	c.hub.BroadcastToRoom(c.RoomCode, msgData)
 }
 
-func (c *Client) handleKickPlayer(payload interface{}) {
-	if c.RoomCode == "" {
-		c.sendError("NOT_IN_ROOM", "You are not in a room")
+func (c *Client) handleReportTelemetry(payload json.RawMessage) {
+	var telemetry protocol.ReportTelemetryPayload
+	if !c.decodePayload(payload, &telemetry, "Invalid telemetry") {
 		return
 	}
 
-	room := c.hub.rooms.GetRoom(c.RoomCode)
-	if room == nil {
-		c.sendError("ROOM_NOT_FOUND", "Room not found")
+	report, warning, err := c.hub.rooms.RecordTelemetry(c.RoomCode, c.PlayerID, telemetry, c.Latency())
+	if err != nil {
+		c.sendError(protocol.CodeInvalidTelemetry, err.Error())
 		return
 	}
 
-	// Only host can kick
-	if room.HostID != c.PlayerID {
-		c.sendError("NOT_HOST", "Only the host can kick players")
-		return
+	// Shown to the host for review, like other cheat warnings
+	if warning != nil {
+		if r := c.hub.rooms.GetRoom(c.RoomCode); r != nil {
+			msgData, _ := json.Marshal(protocol.NewMessage(protocol.CheatWarningMsg, protocol.CheatWarningPayload{
+				RoomCode: c.RoomCode,
+				Warning:  *warning,
+			}))
+			c.hub.SendToPlayer(c.RoomCode, r.HostID, msgData)
+		}
 	}
 
-	data, err := json.Marshal(payload)
-	if err != nil {
-		c.sendError("INVALID_PAYLOAD", "Invalid kick payload")
+	c.logger().Debug("telemetry reported", "renderLatencyMs", telemetry.RenderLatencyMs, "inputLagMs", telemetry.InputLagMs,
+		"observedRttMs", report.ObservedRTTMs, "flagged", report.Flagged)
+}
+
+func (c *Client) handleKickPlayer(payload json.RawMessage) {
+	room := c.hub.rooms.GetRoom(c.RoomCode)
+	if room == nil {
+		c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
 		return
 	}
 
 	var kickPayload protocol.KickPlayerPayload
-	if err := json.Unmarshal(data, &kickPayload); err != nil {
-		c.sendError("INVALID_PAYLOAD", "Invalid kick payload")
+	if !c.decodePayload(payload, &kickPayload, "Invalid kick payload") {
 		return
 	}
 
 	// Can't kick yourself
 	if kickPayload.PlayerID == c.PlayerID {
-		c.sendError("INVALID_KICK", "Cannot kick yourself")
+		c.sendLocalized(protocol.CodeInvalidKick, locale.KickSelf, nil)
 		return
 	}
 
 	// Get player name before removing
 	player := room.GetPlayer(kickPayload.PlayerID)
 	if player == nil {
-		c.sendError("PLAYER_NOT_FOUND", "Player not found")
+		c.sendLocalized(protocol.CodePlayerNotFound, locale.PlayerNotFound, nil)
 		return
 	}
 	playerName := player.Name
 
-	// Remove player from room
-	room.RemovePlayer(kickPayload.PlayerID)
+	if kickPayload.Ban {
+		if err := room.Ban(kickPayload.PlayerID, kickPayload.BanMinutes, c.clock.Now()); err != nil {
+			c.sendError(protocol.CodePlayerNotFound, err.Error())
+			return
+		}
+	}
 
-	// Notify all players about the kick
-	msgData, _ := json.Marshal(protocol.NewMessage(protocol.PlayerKicked, protocol.PlayerKickedPayload{
-		PlayerID:   kickPayload.PlayerID,
+	c.removeKicked(kickPayload.PlayerID, playerName)
+	c.logger().Info("player kicked by host", "kickedPlayerId", kickPayload.PlayerID, "playerName", playerName, "banned", kickPayload.Ban)
+}
+
+// removeKicked removes a kicked player from the client's room and notifies
+// everyone about the kick
+func (c *Client) removeKicked(playerID, playerName string) {
+	c.hub.rooms.RemoveMember(c.RoomCode, playerID, protocol.NewMessage(protocol.PlayerKicked, protocol.PlayerKickedPayload{
+		PlayerID:   playerID,
 		PlayerName: playerName,
-	}))
-	c.hub.BroadcastToRoom(c.RoomCode, msgData)
+	}), c.hub.BroadcastToRoom)
 
-	log.Printf("Player %s kicked from room %s by host", playerName, c.RoomCode)
+	// The kicked player's connection no longer belongs to the room
+	c.hub.DetachPlayer(c.RoomCode, playerID)
 }
 
-func (c *Client) handleEndGame() {
-	if c.RoomCode == "" {
-		c.sendError("NOT_IN_ROOM", "You are not in a room")
+func (c *Client) handleTransferHost(payload json.RawMessage) {
+	room := c.hub.rooms.GetRoom(c.RoomCode)
+	if room == nil {
+		c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
+		return
+	}
+
+	var transferPayload protocol.TransferHostPayload
+	if !c.decodePayload(payload, &transferPayload, "Invalid transfer payload") {
+		return
+	}
+
+	if transferPayload.PlayerID == c.PlayerID {
+		c.sendLocalized(protocol.CodeInvalidTransfer, locale.AlreadyHost, nil)
+		return
+	}
+
+	if !c.hub.rooms.TransferHost(c.RoomCode, transferPayload.PlayerID, c.hub.BroadcastToRoom) {
+		c.sendLocalized(protocol.CodePlayerNotFound, locale.PlayerNotFound, nil)
 		return
 	}
+}
 
+func (c *Client) handleRequestRematch() {
 	room := c.hub.rooms.GetRoom(c.RoomCode)
 	if room == nil {
-		c.sendError("ROOM_NOT_FOUND", "Room not found")
+		c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
+		return
+	}
+
+	if room.GetPlayer(c.PlayerID) == nil {
+		c.sendLocalized(protocol.CodeNotAPlayer, locale.SeatedOnlyRematch, nil)
+		return
+	}
+
+	if room.Status != "finished" {
+		c.sendLocalized(protocol.CodeGameNotOver, locale.RematchNotOver, nil)
+		return
+	}
+
+	votes, needed, ready := room.VoteRematch(c.PlayerID)
+
+	voteMsg, _ := json.Marshal(protocol.NewMessage(protocol.RematchVote, protocol.RematchVotePayload{
+		PlayerID: c.PlayerID,
+		Votes:    votes,
+		Needed:   needed,
+	}))
+	c.hub.BroadcastToRoom(c.RoomCode, voteMsg)
+
+	if !ready {
+		return
+	}
+
+	// Enough players accepted, restart with the same settings
+	startingMsg, _ := json.Marshal(protocol.NewMessage(protocol.RematchStarting, protocol.RematchStartingPayload{
+		Votes: votes,
+	}))
+	c.hub.BroadcastToRoom(c.RoomCode, startingMsg)
+	if err := c.hub.rooms.StartGameCountdown(c.RoomCode, c.hub.BroadcastToRoom); err != nil {
+		c.logger().Warn("rematch failed to start", "error", err)
+		return
+	}
+
+	c.logger().Info("rematch starting", "votes", votes, "needed", needed)
+}
+
+// handleSubscribe updates which room broadcasts this client receives
+func (c *Client) handleSubscribe(payload json.RawMessage, subscribe bool) {
+	var subPayload protocol.SubscribePayload
+	if !c.decodePayload(payload, &subPayload, "Invalid subscribe payload") {
 		return
 	}
 
-	// Only host can end game
-	if room.HostID != c.PlayerID {
-		c.sendError("NOT_HOST", "Only the host can end the game")
+	if subscribe {
+		c.subs.subscribe(subPayload.Events)
+	} else {
+		c.subs.unsubscribe(subPayload.Events)
+	}
+
+	events, muted := c.subs.list()
+	c.SendMessage(protocol.NewMessage(protocol.Subscribed, protocol.SubscribedPayload{
+		Events: events,
+		Muted:  muted,
+	}))
+}
+
+func (c *Client) handleEndGame() {
+	room := c.hub.rooms.GetRoom(c.RoomCode)
+	if room == nil {
+		c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
 		return
 	}
 
-	// End the game
+	// End the game; every client gets the same broadcast, so the reason goes
+	// out in English along with its key for clients to translate
+	reason := locale.Text(locale.Default, locale.HostEndedGame, nil)
+	room.EndParty(reason)
+	if room.Game != nil {
+		room.Game.RecordEnded(reason)
+		c.hub.rooms.SaveReplay(c.RoomCode, room.Game)
+	}
 	room.Game = nil
 	room.Status = "waiting"
 
 	// Notify all players
 	msgData, _ := json.Marshal(protocol.NewMessage(protocol.GameEnded, protocol.GameEndedPayload{
-		Reason: "Host ended the game",
+		Reason:    reason,
+		ReasonKey: locale.HostEndedGame,
 	}))
 	c.hub.BroadcastToRoom(c.RoomCode, msgData)
 
 	// Send updated room state
-	roomMsg, _ := json.Marshal(protocol.NewMessage(protocol.RoomUpdated, protocol.RoomJoinedPayload{
-		Room: room.ToProtocol(),
-	}))
-	c.hub.BroadcastToRoom(c.RoomCode, roomMsg)
+	c.hub.BroadcastToRoom(c.RoomCode, room.UpdatedMessage())
+
+	c.logger().Info("game ended by host")
+}
+
+// handleResync sends the full room state to a client that missed broadcasts
+func (c *Client) handleResync() {
+	r := c.hub.rooms.GetRoom(c.RoomCode)
+	if r == nil {
+		c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
+		return
+	}
+	c.hub.SendResync(c, r)
+}
+
+// handleAck records the last room broadcast the client has received
+func (c *Client) handleAck(payload json.RawMessage) {
+	var ackPayload protocol.AckPayload
+	if !c.decodePayload(payload, &ackPayload, "Invalid ack payload") {
+		return
+	}
+	if r := c.hub.rooms.GetRoom(c.RoomCode); r != nil {
+		r.Ack(c.PlayerID, ackPayload.Seq)
+	}
+}
 
-	log.Printf("Game ended in room %s by host", c.RoomCode)
+// handleSetIntegration registers or removes the room's webhook
+func (c *Client) handleSetIntegration(payload json.RawMessage) {
+	var integration protocol.SetIntegrationPayload
+	if !c.decodePayload(payload, &integration, "Invalid integration payload") {
+		return
+	}
+	if integration.Kind != protocol.IntegrationDiscord {
+		c.sendFieldError(protocol.CodeInvalidPayload, "kind", "Unknown integration")
+		return
+	}
+
+	r := c.hub.rooms.GetRoom(c.RoomCode)
+	if r == nil {
+		c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
+		return
+	}
+	if err := r.SetWebhook(integration.URL); err != nil {
+		c.sendLocalizedField(protocol.CodeInvalidWebhook, "url", locale.InvalidWebhook)
+		return
+	}
+	c.hub.BroadcastToRoom(c.RoomCode, r.UpdatedMessage())
+	c.logger().Info("integration updated", "kind", integration.Kind, "enabled", integration.URL != "")
+}
+
+func (c *Client) handleRequestReplay(payload json.RawMessage) {
+	var replayPayload protocol.RequestReplayPayload
+	if !c.decodePayload(payload, &replayPayload, "Invalid replay payload") {
+		return
+	}
+
+	roomCode := strings.ToUpper(replayPayload.RoomCode)
+	if roomCode == "" {
+		roomCode = c.RoomCode
+	}
+
+	replay, ok := c.hub.rooms.GetReplay(context.Background(), roomCode, replayPayload.GameID)
+	if !ok {
+		c.sendLocalized(protocol.CodeReplayNotFound, locale.ReplayNotFound, nil)
+		return
+	}
+
+	speed := replayPayload.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+	if speed > maxReplaySpeed {
+		speed = maxReplaySpeed
+	}
+
+	// One replay at a time: a new request replaces the one playing
+	if c.replayStop != nil {
+		close(c.replayStop)
+	}
+	c.replayStop = make(chan struct{})
+	go c.streamReplay(replay, speed, c.replayStop)
+}
+
+// streamReplay sends replay events spaced by their original timing divided by
+// speed, until stop is closed or the client disconnects
+func (c *Client) streamReplay(replay protocol.ReplayLog, speed float64, stop chan struct{}) {
+	for i, event := range replay.Events {
+		if i > 0 {
+			gap := time.Duration(event.Timestamp-replay.Events[i-1].Timestamp) * time.Millisecond
+			select {
+			case <-c.clock.After(time.Duration(float64(gap) / speed)):
+			case <-stop:
+				return
+			case <-c.done:
+				return
+			}
+		}
+		c.hub.sendIfConnected(c, protocol.NewMessage(protocol.ReplayEventMsg, protocol.ReplayEventPayload{
+			GameID: replay.GameID,
+			Event:  event,
+		}))
+	}
+
+	c.hub.sendIfConnected(c, protocol.NewMessage(protocol.ReplayComplete, protocol.ReplayCompletePayload{
+		GameID:     replay.GameID,
+		EventCount: len(replay.Events),
+	}))
 }
@@ -1,27 +1,82 @@
 package websocket
 
 import (
-	"log"
+	"encoding/json"
+	"log/slog"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/gorilla/websocket"
+
+	"slapjack/internal/clock"
+	"slapjack/internal/config"
+	"slapjack/internal/metrics"
+	"slapjack/internal/names"
 	"slapjack/internal/redis"
 	"slapjack/internal/room"
+	"slapjack/pkg/protocol"
 )
 
+// How often rooms are checked for inactivity
+const idleRoomCheckInterval = time.Minute
+
+// How often the watchdog looks for stuck rooms
+const stuckRoomCheckInterval = 30 * time.Second
+
+// How often timed games' clocks tick
+const gameClockInterval = time.Second
+
 // Hub maintains the set of active clients and broadcasts messages to the rooms
 type Hub struct {
 	// Registered clients
 	clients map[*Client]bool
 
+	// Registered clients by RoomCode, so room messages only touch that room
+	roomClients map[string]map[*Client]bool
+
 	// Clients by session ID for reconnection
 	sessions map[string]*Client
 
 	// Room manager
 	rooms *room.Manager
 
+	// Handlers for incoming message types
+	router *Router
+
+	// Time spent on each message type
+	messageLatency *metrics.HistogramVec
+
+	// Messages dropped for full send queues, by type, and what became of the
+	// lagging clients
+	droppedMessages *metrics.CounterVec
+	backpressure    *metrics.CounterVec
+	stuckRooms      *metrics.CounterVec
+
+	// Bytes of WebSocket messages written, and bytes that reached the
+	// connection after framing and any compression, by whether the client
+	// negotiated compression
+	payloadBytes *metrics.CounterVec
+	wireBytes    *metrics.CounterVec
+
 	// Redis store
 	store *redis.Store
 
+	cfg *config.Live
+
+	// Drives every timer in the hub, its rooms and their games
+	clock clock.Clock
+
+	// Limits new connections per remote IP
+	connLimiter *ipLimiter
+
+	// Player name rules, rebuilt when the config is reloaded
+	nameValidator atomic.Pointer[names.Validator]
+
+	// Set once shutdown begins; new connections are refused
+	shuttingDown atomic.Bool
+
 	// Register requests from clients
 	register chan *Client
 
@@ -33,15 +88,46 @@ type Hub struct {
 }
 
 // NewHub creates a new Hub instance
-func NewHub(store *redis.Store) *Hub {
-	return &Hub{
-		clients:    make(map[*Client]bool),
-		sessions:   make(map[string]*Client),
-		rooms:      room.NewManager(store),
-		store:      store,
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
+func NewHub(store *redis.Store, cfg *config.Live, clk clock.Clock) *Hub {
+	h := &Hub{
+		clients:     make(map[*Client]bool),
+		roomClients: make(map[string]map[*Client]bool),
+		sessions:    make(map[string]*Client),
+		rooms:       room.NewManager(store, cfg, clk),
+		router:      defaultRouter(),
+		messageLatency: metrics.NewHistogramVec("slapjack_message_duration_seconds",
+			"Time to process a client message, by type and stage (parse, or handle including broadcasts)",
+			metrics.LatencyBuckets, "type", "stage"),
+		droppedMessages: metrics.NewCounterVec("slapjack_dropped_messages_total",
+			"Messages dropped because a client's send queue was full, by type", "type"),
+		backpressure: metrics.NewCounterVec("slapjack_backpressure_events_total",
+			"Clients starting to lag behind, resynced once caught up, or disconnected for staying behind", "event"),
+		stuckRooms: metrics.NewCounterVec("slapjack_stuck_rooms_total",
+			"Stuck rooms the watchdog resolved, by kind", "kind"),
+		payloadBytes: metrics.NewCounterVec("slapjack_ws_payload_bytes_total",
+			"Bytes of WebSocket messages written before compression, by whether the client negotiated compression", "compression"),
+		wireBytes: metrics.NewCounterVec("slapjack_ws_wire_bytes_total",
+			"Bytes written to WebSocket connections, including the handshake, by whether the client negotiated compression", "compression"),
+		store:       store,
+		cfg:         cfg,
+		clock:       clk,
+		connLimiter: newIPLimiter(clk, cfg.Get().ConnectionsPerMinIP),
+		register:    make(chan *Client),
+		unregister:  make(chan *Client),
 	}
+
+	h.loadNameValidator()
+	h.rooms.SetGameOverCheck(h.checkPileOver)
+
+	go h.pollCleanupRoutine()
+	go h.idleRoomRoutine()
+	go h.stuckRoomRoutine()
+	go h.latencyRoutine()
+	go h.gameClockRoutine()
+	go h.rooms.RunDropIns(h.BroadcastToRoom)
+	go h.rooms.RunSchedules(h.BroadcastToRoom)
+
+	return h
 }
 
 // Run starts the hub's main event loop
@@ -51,28 +137,36 @@ func (h *Hub) Run() {
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
+			h.index(client)
 			if client.SessionID != "" {
 				h.sessions[client.SessionID] = client
 			}
+			online := len(h.clients)
 			h.mu.Unlock()
-			log.Printf("Client connected: %s", client.SessionID)
+			h.rooms.Stats().PlayersOnline(online)
+			client.logger().Info("client connected")
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
+			_, registered := h.clients[client]
+			if registered {
 				delete(h.clients, client)
-				if client.SessionID != "" {
+				h.unindex(client)
+				if client.SessionID != "" && h.sessions[client.SessionID] == client {
 					delete(h.sessions, client.SessionID)
 				}
 				close(client.send)
+				close(client.done)
 			}
+			h.applyDetachLocked(client)
 			h.mu.Unlock()
 
 			// Handle room leave if client was in a room
-			if client.RoomCode != "" {
+			// (skipped during shutdown so persisted rooms keep their players)
+			if registered && client.RoomCode != "" && !h.shuttingDown.Load() {
 				h.handlePlayerDisconnect(client)
 			}
-			log.Printf("Client disconnected: %s", client.SessionID)
+			client.logger().Info("client disconnected")
 		}
 	}
 }
@@ -82,6 +176,47 @@ func (h *Hub) Register(client *Client) {
 	h.register <- client
 }
 
+// AllowConnection reports whether a remote IP may open another WebSocket
+func (h *Hub) AllowConnection(ip string) bool {
+	return h.connLimiter.Allow(ip)
+}
+
+// IsShuttingDown reports whether the server is draining connections
+func (h *Hub) IsShuttingDown() bool {
+	return h.shuttingDown.Load()
+}
+
+// Shutdown warns every client, waits out the countdown, flushes room state to
+// Redis and closes all connections so clients reconnect to a new instance
+func (h *Hub) Shutdown(countdown time.Duration) {
+	h.shuttingDown.Store(true)
+
+	msgData, _ := json.Marshal(protocol.NewMessage(protocol.ServerShutdown, protocol.ServerShutdownPayload{
+		CountdownSeconds: int(countdown / time.Second),
+		Message:          "Server is restarting, you will be reconnected automatically",
+	}))
+	h.mu.RLock()
+	for client := range h.clients {
+		client.enqueue(msgData)
+	}
+	h.mu.RUnlock()
+
+	h.clock.Sleep(countdown)
+
+	h.rooms.PersistAll()
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.clients {
+		if client.conn == nil {
+			continue
+		}
+		client.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server restarting"),
+			time.Now().Add(writeWait))
+	}
+}
+
 // GetClientBySession returns a client by their session ID
 func (h *Hub) GetClientBySession(sessionID string) *Client {
 	h.mu.RLock()
@@ -89,6 +224,11 @@ func (h *Hub) GetClientBySession(sessionID string) *Client {
 	return h.sessions[sessionID]
 }
 
+// Router returns the message router, so other packages can register message types
+func (h *Hub) Router() *Router {
+	return h.router
+}
+
 // GetRoomManager returns the room manager
 func (h *Hub) GetRoomManager() *room.Manager {
 	return h.rooms
@@ -96,39 +236,129 @@ func (h *Hub) GetRoomManager() *room.Manager {
 
 // BroadcastToRoom sends a message to all clients in a room
 func (h *Hub) BroadcastToRoom(roomCode string, message []byte) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-
-	for client := range h.clients {
-		if client.RoomCode == roomCode {
-			select {
-			case client.send <- message:
-			default:
-				// Client's send buffer is full, they'll be cleaned up
-			}
-		}
-	}
+	h.broadcast(roomCode, "", message)
 }
 
 // BroadcastToRoomExcept sends a message to all clients in a room except one
 func (h *Hub) BroadcastToRoomExcept(roomCode string, excludeSessionID string, message []byte) {
+	count := h.broadcast(roomCode, excludeSessionID, message)
+	slog.Debug("broadcast sent", "roomCode", roomCode, "excludedSessionId", excludeSessionID, "recipients", count)
+}
+
+// broadcast sends a message to every client in a room but excludeSessionID,
+// returning how many it was queued for. Broadcasts to one room are numbered
+// and queued under the room's lock, so its clients receive them in order.
+// Clients filtering what they receive get them unnumbered, as the ones they
+// filter out would look to them like missed messages.
+func (h *Hub) broadcast(roomCode, excludeSessionID string, message []byte) int {
+	msgType := ""
+	stamped := message
+	if r := h.rooms.GetRoom(roomCode); r != nil {
+		r.LockBroadcasts()
+		defer r.UnlockBroadcasts()
+		msgType = messageType(message)
+		stamped = h.sequence(r, message, msgType, excludeSessionID)
+	}
+
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
 	count := 0
-	for client := range h.clients {
-		log.Printf("[Broadcast] Client %s in room %s (looking for %s)", client.SessionID, client.RoomCode, roomCode)
-		if client.RoomCode == roomCode && client.SessionID != excludeSessionID {
-			select {
-			case client.send <- message:
-				count++
-				log.Printf("[Broadcast] Sent to client %s", client.SessionID)
-			default:
-				log.Printf("[Broadcast] Client %s buffer full", client.SessionID)
-			}
+	for client := range h.roomClients[roomCode] {
+		if client.SessionID == excludeSessionID && excludeSessionID != "" {
+			continue
+		}
+		if !client.wantsBroadcast(message, &msgType) {
+			continue
+		}
+		out := stamped
+		if client.subs.active() {
+			out = message
+		}
+		if client.enqueue(out) {
+			count++
 		}
 	}
-	log.Printf("[Broadcast] Sent to %d clients in room %s (excluding %s)", count, roomCode, excludeSessionID)
+	return count
+}
+
+// sequence stamps a broadcast with the room's next sequence number and
+// keeps it in the room's backlog. Cosmetic messages, which a saturated link
+// drops, are left unnumbered so dropping them never looks like a gap.
+// Caller must hold the room's broadcast lock
+func (h *Hub) sequence(r *room.Room, message []byte, msgType, excludeSessionID string) []byte {
+	if len(message) < 2 || message[0] != '{' || cosmeticMessages[msgType] {
+		return message
+	}
+
+	seq := r.NextSeq()
+	stamped := make([]byte, 0, len(message)+24)
+	stamped = append(stamped, `{"seq":`...)
+	stamped = strconv.AppendInt(stamped, seq, 10)
+	if message[1] != '}' {
+		stamped = append(stamped, ',')
+	}
+	stamped = append(stamped, message[1:]...)
+	r.Record(room.Broadcast{Seq: seq, Message: stamped, Except: excludeSessionID})
+	return stamped
+}
+
+// SendResync sends a client the full state of its room, stamped with the
+// sequence number of the last broadcast it reflects
+func (h *Hub) SendResync(client *Client, r *room.Room) {
+	r.LockBroadcasts()
+	defer r.UnlockBroadcasts()
+	h.sendResync(client, r)
+}
+
+// Resume replays the broadcasts a reconnecting player missed since their
+// last ack, or sends the full state if the backlog no longer reaches back
+// that far or the client filters what it receives
+func (h *Hub) Resume(client *Client, r *room.Room) {
+	r.LockBroadcasts()
+	defer r.UnlockBroadcasts()
+
+	since := r.LastAck(client.PlayerID)
+	missed, ok := r.BacklogSince(since)
+	if !ok || client.subs.active() {
+		h.sendResync(client, r)
+		return
+	}
+
+	replay := make([][]byte, 0, len(missed))
+	for _, b := range missed {
+		if b.Except == client.SessionID {
+			continue
+		}
+		replay = append(replay, b.Message)
+	}
+	client.SendMessage(protocol.NewMessage(protocol.Resumed, protocol.ResumedPayload{
+		FromSeq:      since,
+		ToSeq:        r.Seq(),
+		Count:        len(replay),
+		LastInputSeq: r.LastInput(client.PlayerID),
+	}))
+	for _, message := range replay {
+		client.enqueue(message)
+	}
+	client.logger().Info("resumed connection", "fromSeq", since, "replayed", len(replay))
+}
+
+// sendResync sends a client the full state of its room
+// Caller must hold the room's broadcast lock
+func (h *Hub) sendResync(client *Client, r *room.Room) {
+	payload := protocol.ResyncStatePayload{
+		Seq:          r.Seq(),
+		Room:         r.ToProtocol(),
+		LastInputSeq: r.LastInput(client.PlayerID),
+	}
+	if g, pile := r.GameFor(client.PlayerID); g != nil {
+		state := r.GameStateFor(g, client.Spectating)
+		payload.GameID = g.ID
+		payload.GameState = &state
+		payload.Pile = pile
+	}
+	client.SendMessage(protocol.NewMessage(protocol.ResyncState, payload))
 }
 
 // SendToClient sends a message to a specific client
@@ -138,67 +368,294 @@ func (h *Hub) SendToClient(sessionID string, message []byte) {
 	h.mu.RUnlock()
 
 	if client != nil {
-		select {
-		case client.send <- message:
-		default:
-		}
+		client.enqueue(message)
+	}
+}
+
+// sendIfConnected queues a message for a client unless it has unregistered,
+// for goroutines outliving the client's own
+func (h *Hub) sendIfConnected(c *Client, message protocol.WSMessage) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.clients[c] {
+		c.enqueue(data)
 	}
 }
 
 // handlePlayerDisconnect handles a player disconnecting from a room
+// The player keeps their seat for the reconnection grace period
 func (h *Hub) handlePlayerDisconnect(client *Client) {
-	r := h.rooms.GetRoom(client.RoomCode)
-	if r == nil {
+	if h.rooms.GetRoom(client.RoomCode) == nil {
 		return
 	}
 
-	roomCode := client.RoomCode
-	playerID := client.PlayerID
-	isHost := r.HostID == playerID
+	if client.Spectating {
+		h.rooms.StopSpectating(client.RoomCode, client.SessionID)
+		h.rooms.NotifyMembershipChanged(client.RoomCode, h.BroadcastToRoom)
+		return
+	}
 
-	// If host disconnects, disband the entire room
-	if isHost {
-		log.Printf("Host disconnected, disbanding room %s", roomCode)
-		h.rooms.DeleteRoom(roomCode)
-		// Notify all other players room is closed
-		h.BroadcastToRoomExcept(roomCode, client.SessionID, []byte(`{"type":"ROOM_CLOSED","payload":{"reason":"Host left"}}`))
+	// A newer connection may already have taken over this seat
+	for _, other := range h.GetClientsInRoom(client.RoomCode) {
+		if other != client && other.PlayerID == client.PlayerID {
+			return
+		}
+	}
+
+	client.logger().Info("player disconnected, holding seat", "grace", h.rooms.ReconnectGrace().String())
+	h.rooms.HandleDisconnect(client.RoomCode, client.PlayerID, h.BroadcastToRoom)
+}
+
+// GetClientsInRoom returns all connected clients in a room
+func (h *Hub) GetClientsInRoom(roomCode string) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	clients := make([]*Client, 0, len(h.roomClients[roomCode]))
+	for client := range h.roomClients[roomCode] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// setRoom moves a client into a room's registry, or out of any with ""
+func (h *Hub) setRoom(c *Client, roomCode string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.moveClient(c, roomCode)
+}
+
+// setSeat moves a client into a room as the given player, or out of any with ""
+// Called by the client's own goroutine
+func (h *Hub) setSeat(c *Client, roomCode, playerID, playerName string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.moveClient(c, roomCode)
+	c.PlayerID = playerID
+	c.PlayerName = playerName
+	c.Spectating = false
+}
+
+// setSpectating moves a client into a room as a spectator
+// Called by the client's own goroutine
+func (h *Hub) setSpectating(c *Client, roomCode string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.moveClient(c, roomCode)
+	c.PlayerID = ""
+	c.PlayerName = ""
+	c.Spectating = true
+}
+
+// setPlayerName renames the client's player without moving it
+// Called by the client's own goroutine
+func (h *Hub) setPlayerName(c *Client, playerName string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c.PlayerName = playerName
+}
+
+// applyDetach clears the client's room if the hub has detached it since
+// Called by the client's own goroutine before handling a message
+func (h *Hub) applyDetach(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.applyDetachLocked(c)
+}
+
+// applyDetachLocked is applyDetach for callers already holding h.mu for writing
+func (h *Hub) applyDetachLocked(c *Client) {
+	if c.detachedFrom == "" {
 		return
 	}
+	if c.RoomCode == c.detachedFrom {
+		c.RoomCode = ""
+		c.PlayerID = ""
+		c.PlayerName = ""
+		c.Spectating = false
+	}
+	c.detachedFrom = ""
+}
 
-	// Remove player from room
-	r.RemovePlayer(playerID)
+// roomOf returns the room a client is in, or "" if it was detached from it
+// Caller must hold h.mu
+func (h *Hub) roomOf(c *Client) string {
+	if c.RoomCode == c.detachedFrom {
+		return ""
+	}
+	return c.RoomCode
+}
 
-	// If room is now empty, delete it
-	if r.IsEmpty() {
-		log.Printf("Room %s is empty, deleting", roomCode)
-		h.rooms.DeleteRoom(roomCode)
+// moveClient sets a client's room, keeping the registries in step; clients
+// not yet registered are indexed when they are
+// Caller must hold h.mu for writing
+func (h *Hub) moveClient(c *Client, roomCode string) {
+	if c.RoomCode != "" && roomCode == "" {
+		c.markActive() // Kicked or closed rooms start the idle timer afresh
+	}
+	h.unindex(c)
+	c.RoomCode = roomCode
+	c.detachedFrom = "" // Moving supersedes a pending detach
+	if h.clients[c] {
+		h.index(c)
+	}
+}
+
+// index adds a client to its room's registry
+// Caller must hold h.mu for writing
+func (h *Hub) index(c *Client) {
+	if c.RoomCode == "" {
 		return
 	}
+	members := h.roomClients[c.RoomCode]
+	if members == nil {
+		members = make(map[*Client]bool)
+		h.roomClients[c.RoomCode] = members
+	}
+	members[c] = true
+}
 
-	// Notify other players
-	h.rooms.NotifyPlayerLeft(roomCode, playerID, h.BroadcastToRoom)
+// unindex removes a client from its room's registry, dropping the registry
+// once the room has nobody left
+// Caller must hold h.mu for writing
+func (h *Hub) unindex(c *Client) {
+	members := h.roomClients[c.RoomCode]
+	delete(members, c)
+	if len(members) == 0 {
+		delete(h.roomClients, c.RoomCode)
+	}
 }
 
-// GetClientsInRoom returns all connected clients in a room
-func (h *Hub) GetClientsInRoom(roomCode string) []*Client {
+// DetachPlayer stops routing room messages to a player's connection (e.g. after a kick)
+// The connection's own goroutine clears its room before its next message
+func (h *Hub) DetachPlayer(roomCode, playerID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for client := range h.roomClients[roomCode] {
+		if client.PlayerID == playerID {
+			h.detach(client)
+		}
+	}
+}
+
+// DetachRoom stops routing messages for a room to every connection in it
+func (h *Hub) DetachRoom(roomCode string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for client := range h.roomClients[roomCode] {
+		h.detach(client)
+	}
+}
+
+// detach unindexes a client and leaves its room for its own goroutine to clear
+// Caller must hold h.mu for writing
+func (h *Hub) detach(c *Client) {
+	c.markActive() // Kicked or closed rooms start the idle timer afresh
+	h.unindex(c)
+	c.detachedFrom = c.RoomCode
+}
+
+// idleRoomRoutine periodically closes rooms nobody is using
+func (h *Hub) idleRoomRoutine() {
+	ticker := h.clock.NewTicker(idleRoomCheckInterval)
+	for range ticker.C() {
+		for _, code := range h.rooms.ExpireIdleRooms(h.BroadcastToRoom) {
+			h.DetachRoom(code)
+		}
+	}
+}
+
+// stuckRoomRoutine periodically finds and resolves stuck rooms
+func (h *Hub) stuckRoomRoutine() {
+	ticker := h.clock.NewTicker(stuckRoomCheckInterval)
+	for range ticker.C() {
+		for _, stuck := range h.rooms.ResolveStuckRooms(h.BroadcastToRoom) {
+			h.stuckRooms.With(stuck.Kind).Inc()
+			if stuck.Kind == room.StuckSilent {
+				h.DetachRoom(stuck.Code)
+			}
+		}
+	}
+}
+
+// gameClockRoutine sends every timed game its clock, and ends those whose
+// time is up
+func (h *Hub) gameClockRoutine() {
+	ticker := h.clock.NewTicker(gameClockInterval)
+	for range ticker.C() {
+		for _, r := range h.rooms.TimedGames() {
+			h.BroadcastToRoom(r.Code, r.Game.ClockMessage())
+			if remaining, _ := r.Game.ClockRemaining(); remaining == 0 {
+				h.checkGameOver(r.Code, r)
+			}
+		}
+	}
+}
+
+// latencyRoutine shares seated players' measured latency with their rooms,
+// sending ROOM_UPDATED to rooms where it changed noticeably
+func (h *Hub) latencyRoutine() {
+	ticker := h.clock.NewTicker(pingPeriod)
+	for range ticker.C() {
+		type sample struct {
+			roomCode, playerID string
+			ms                 int64
+		}
+		var samples []sample
+		h.mu.RLock()
+		for client := range h.clients {
+			if h.roomOf(client) != "" && client.PlayerID != "" && !client.Spectating {
+				if ms := client.Latency(); ms > 0 {
+					samples = append(samples, sample{client.RoomCode, client.PlayerID, ms})
+				}
+			}
+		}
+		h.mu.RUnlock()
+
+		changed := make(map[string]bool)
+		for _, s := range samples {
+			if r := h.rooms.GetRoom(s.roomCode); r != nil && r.SetLatency(s.playerID, s.ms) {
+				changed[s.roomCode] = true
+			}
+		}
+		for code := range changed {
+			h.rooms.NotifyMembershipChanged(code, h.BroadcastToRoom)
+		}
+	}
+}
+
+// SendToPlayer sends a message to the client seated as the given player
+func (h *Hub) SendToPlayer(roomCode, playerID string, message []byte) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	var clients []*Client
-	for client := range h.clients {
-		if client.RoomCode == roomCode {
-			clients = append(clients, client)
+	for client := range h.roomClients[roomCode] {
+		if client.PlayerID == playerID {
+			client.enqueue(message)
 		}
 	}
-	return clients
 }
 
 // DebugClient represents client info for debugging
 type DebugClient struct {
-	SessionID  string `json:"sessionId"`
-	PlayerID   string `json:"playerId"`
-	PlayerName string `json:"playerName"`
+	SessionID  string `json:"sessionId,omitempty"`
+	PlayerID   string `json:"playerId,omitempty"`
+	PlayerName string `json:"playerName,omitempty"`
 	RoomCode   string `json:"roomCode"`
+
+	// Cosmetic broadcasts dropped because the client's link was saturated
+	DroppedCosmetic int64 `json:"droppedCosmetic,omitempty"`
+
+	// Messages dropped because its send queue was full, and whether it is
+	// still waiting for a resync
+	DroppedMessages int64 `json:"droppedMessages,omitempty"`
+	Lagging         bool  `json:"lagging,omitempty"`
 }
 
 // DebugInfo contains all debug information
@@ -210,26 +667,74 @@ type DebugInfo struct {
 }
 
 // GetDebugInfo returns debug information about the hub state
-func (h *Hub) GetDebugInfo() DebugInfo {
+// If roomCode is set only that room and its clients are included
+func (h *Hub) GetDebugInfo(roomCode string, redaction Redaction) DebugInfo {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
 	clients := make([]DebugClient, 0, len(h.clients))
 	for client := range h.clients {
+		if roomCode != "" && h.roomOf(client) != roomCode {
+			continue
+		}
 		clients = append(clients, DebugClient{
-			SessionID:  client.SessionID,
-			PlayerID:   client.PlayerID,
-			PlayerName: client.PlayerName,
-			RoomCode:   client.RoomCode,
+			SessionID:  redaction.sessionID(client.SessionID),
+			PlayerID:   redaction.playerID(client.PlayerID),
+			PlayerName: redaction.playerName(client.PlayerName),
+			RoomCode:   redaction.roomCode(h.roomOf(client)),
+
+			DroppedCosmetic: client.droppedCosmetic.Load(),
+			DroppedMessages: client.dropped.Load(),
+			Lagging:         client.Lagging(),
 		})
 	}
 
-	rooms := h.rooms.GetAllRoomsDebug()
+	rooms := make([]room.DebugRoom, 0)
+	for _, r := range h.rooms.GetAllRoomsDebug() {
+		if roomCode != "" && r.Code != roomCode {
+			continue
+		}
+		r.Code = redaction.roomCode(r.Code)
+		r.HostID = redaction.playerID(r.HostID)
+		for i := range r.Players {
+			r.Players[i].ID = redaction.playerID(r.Players[i].ID)
+			r.Players[i].Name = redaction.playerName(r.Players[i].Name)
+		}
+		rooms = append(rooms, r)
+	}
 
 	return DebugInfo{
-		TotalClients: len(h.clients),
+		TotalClients: len(clients),
 		TotalRooms:   len(rooms),
 		Clients:      clients,
 		Rooms:        rooms,
 	}
 }
+
+// PublicStats is the sanitized server summary shown in the lobby
+type PublicStats struct {
+	TotalClients int `json:"totalClients"`
+	TotalRooms   int `json:"totalRooms"`
+	ActiveGames  int `json:"activeGames"`
+}
+
+// GetPublicStats returns connection and room counts without any identifiers
+func (h *Hub) GetPublicStats() PublicStats {
+	h.mu.RLock()
+	totalClients := len(h.clients)
+	h.mu.RUnlock()
+
+	rooms := h.rooms.GetAllRoomsDebug()
+	activeGames := 0
+	for _, r := range rooms {
+		if r.HasGame && r.Status == "playing" {
+			activeGames++
+		}
+	}
+
+	return PublicStats{
+		TotalClients: totalClients,
+		TotalRooms:   len(rooms),
+		ActiveGames:  activeGames,
+	}
+}
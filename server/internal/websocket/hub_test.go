@@ -0,0 +1,519 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"slapjack/internal/clock"
+	"slapjack/internal/config"
+	"slapjack/internal/game"
+	"slapjack/internal/locale"
+	"slapjack/internal/room"
+	"slapjack/pkg/protocol"
+)
+
+// newTestHub creates a hub with clients spread over rooms of roomSize,
+// registered directly rather than through Run
+func newTestHub(tb testing.TB, clients, roomSize int) *Hub {
+	tb.Helper()
+	h := NewHub(nil, config.Static(config.Default()), clock.NewMock(time.Now()))
+	for i := 0; i < clients; i++ {
+		c := NewClient(h, nil, fmt.Sprintf("session-%d", i), "")
+		h.mu.Lock()
+		h.clients[c] = true
+		h.mu.Unlock()
+		h.setRoom(c, fmt.Sprintf("R%d", i/roomSize))
+	}
+	return h
+}
+
+func TestRoomRegistry(t *testing.T) {
+	h := newTestHub(t, 8, 4)
+
+	alex := h.GetClientsInRoom("R0")[0]
+	h.setRoom(alex, "R1")
+	if got := len(h.GetClientsInRoom("R0")); got != 3 {
+		t.Errorf("%d clients left in R0, want 3", got)
+	}
+	if got := len(h.GetClientsInRoom("R1")); got != 5 {
+		t.Errorf("%d clients in R1, want 5", got)
+	}
+
+	h.DetachPlayer("R1", alex.PlayerID) // Detaches every client in R1, none being seated
+	if _, ok := h.roomClients["R1"]; ok || h.roomOf(alex) != "" {
+		t.Errorf("R1 still registered after detaching its clients")
+	}
+	h.applyDetach(alex)
+	if alex.RoomCode != "" {
+		t.Errorf("detached client still in %q once applied", alex.RoomCode)
+	}
+
+	h.DetachRoom("R0")
+	if len(h.roomClients) != 0 {
+		t.Errorf("rooms still registered: %v", h.roomClients)
+	}
+
+	// Unregistered clients aren't indexed until they register
+	stray := NewClient(h, nil, "stray", "")
+	h.setRoom(stray, "R2")
+	if len(h.GetClientsInRoom("R2")) != 0 {
+		t.Error("unregistered client indexed")
+	}
+}
+
+func TestDetachDuringHandler(t *testing.T) {
+	h := newTestHub(t, 1, 1)
+	c := h.GetClientsInRoom("R0")[0]
+	h.setSeat(c, "R0", "p1", "Alex")
+
+	// The client's goroutine handles messages while the hub kicks it and
+	// closes its room; run with -race
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			h.DetachPlayer("R0", "p1")
+			h.DetachRoom("R0")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		h.applyDetach(c)
+		if c.RoomCode == "" && c.PlayerID != "" {
+			t.Fatal("detached client kept its player")
+		}
+		h.setSeat(c, "R0", "p1", "Alex")
+	}
+	<-done
+
+	h.DetachRoom("R0")
+	if got := h.GetDebugInfo("R0", RedactNone).TotalClients; got != 0 {
+		t.Errorf("%d clients listed in closed room before applying the detach, want 0", got)
+	}
+	h.applyDetach(c)
+	if c.RoomCode != "" || c.PlayerID != "" || c.Spectating {
+		t.Errorf("client still in %q as %q after room closed", c.RoomCode, c.PlayerID)
+	}
+}
+
+func TestBackpressure(t *testing.T) {
+	h := newTestHub(t, 2, 2)
+	clients := h.GetClientsInRoom("R0")
+	slow, fast := clients[0], clients[1]
+
+	message := []byte(`{"type":"CARD_PLAYED","payload":{}}`)
+	for i := 0; i < cap(slow.send)+3; i++ {
+		h.BroadcastToRoom("R0", message)
+		<-fast.send
+	}
+	if !slow.Lagging() || slow.dropped.Load() != 3 {
+		t.Fatalf("slow client lagging %v after %d drops, want lagging after 3", slow.Lagging(), slow.dropped.Load())
+	}
+	if fast.Lagging() {
+		t.Error("client keeping up marked as lagging")
+	}
+	if got := h.droppedMessages.With("CARD_PLAYED").Value(); got != 3 {
+		t.Errorf("%d dropped CARD_PLAYED counted, want 3", got)
+	}
+
+	// Still backed up, so no resync yet
+	slow.catchUp()
+	if !slow.Lagging() {
+		t.Error("lagging cleared before the queue drained")
+	}
+	for len(slow.send) > drainedQueueDepth {
+		<-slow.send
+	}
+	slow.catchUp()
+	if slow.Lagging() || h.backpressure.With(lagResynced).Value() != 1 {
+		t.Errorf("lagging %v after draining, want resynced", slow.Lagging())
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	h := newTestHub(t, 1, 1)
+	mock := h.clock.(*clock.Mock)
+	c := h.GetClientsInRoom("R0")[0]
+	timeout := h.cfg.Get().IdleClientTimeout
+
+	// Clients in a room are never idle, however quiet
+	mock.Advance(timeout + time.Second)
+	if c.idleTimedOut() {
+		t.Fatal("client in a room timed out")
+	}
+
+	// Leaving restarts the timer
+	h.setRoom(c, "")
+	mock.Advance(timeout)
+	if c.idleTimedOut() {
+		t.Fatal("client timed out as soon as it left its room")
+	}
+	mock.Advance(time.Second)
+	if !c.idleTimedOut() {
+		t.Fatal("client outside a room not timed out after the idle timeout")
+	}
+
+	// As does sending anything
+	c.markActive()
+	if c.idleTimedOut() {
+		t.Error("client timed out just after sending a message")
+	}
+}
+
+func TestLatencyIgnoresOutliers(t *testing.T) {
+	h := newTestHub(t, 1, 1)
+	c := h.GetClientsInRoom("R0")[0]
+	for _, rtt := range []time.Duration{80, 80, 2000, 80, 3000} {
+		c.recordLatency(rtt * time.Millisecond)
+	}
+	if got := c.Latency(); got != 80 {
+		t.Errorf("latency %dms after two held-back pongs, want 80", got)
+	}
+}
+
+func TestReplayStopsWithClient(t *testing.T) {
+	h := newTestHub(t, 1, 1)
+	c := h.GetClientsInRoom("R0")[0]
+	replay := protocol.ReplayLog{GameID: "game", Events: []protocol.ReplayEvent{{Seq: 1}, {Seq: 2, Timestamp: 60000}}}
+
+	// Replaced by a newer replay between events
+	stop := make(chan struct{})
+	close(stop)
+	c.streamReplay(replay, 1, stop)
+	if len(c.send) != 1 {
+		t.Fatalf("%d messages sent by a stopped replay, want just the first event", len(c.send))
+	}
+	<-c.send
+
+	// Disconnected: the stream ends without sending on the closed queue
+	h.mu.Lock()
+	delete(h.clients, c)
+	close(c.send)
+	close(c.done)
+	h.mu.Unlock()
+	c.streamReplay(replay, 1, make(chan struct{}))
+}
+
+func TestClientIP(t *testing.T) {
+	cfg := config.Default()
+	cfg.TrustedProxies = []string{"10.0.0.0/8", "192.0.2.1"}
+	h := NewHub(nil, config.Static(cfg), clock.NewMock(time.Now()))
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"direct peer", "203.0.113.7:4000", nil, "203.0.113.7"},
+		{"untrusted peer spoofing headers", "203.0.113.7:4000",
+			map[string]string{"Fly-Client-IP": "1.1.1.1", "X-Forwarded-For": "1.1.1.1"}, "203.0.113.7"},
+		{"trusted proxy sets Fly-Client-IP", "10.1.2.3:4000",
+			map[string]string{"Fly-Client-IP": "198.51.100.4"}, "198.51.100.4"},
+		{"client-supplied hops are skipped", "192.0.2.1:4000",
+			map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.4, 10.9.9.9"}, "198.51.100.4"},
+		{"trusted proxy with no headers", "10.1.2.3:4000", nil, "10.1.2.3"},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		r.RemoteAddr = tc.remoteAddr
+		for k, v := range tc.headers {
+			r.Header.Set(k, v)
+		}
+		if got := h.ClientIP(r); got != tc.want {
+			t.Errorf("%s: ClientIP = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	h := NewHub(nil, config.Static(config.Default()), clock.NewMock(time.Now()))
+	r, playerID, err := h.rooms.CreateRoom("session", "Alex", "alex-token", "", nil, h.BroadcastToRoom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connect := func() *Client {
+		c := NewClient(h, nil, "session", "alex-token")
+		h.setRoom(c, r.Code)
+		c.PlayerID = playerID
+		return c
+	}
+
+	handled := 0
+	handler := Idempotent(func(c *Client, msg protocol.WSMessage) { handled++ })
+	c := connect()
+	for _, inputSeq := range []int64{1, 1, 2, 1, 0, 0} {
+		handler(c, protocol.WSMessage{Type: protocol.Slap, InputSeq: inputSeq})
+	}
+	if handled != 4 {
+		t.Errorf("handled %d messages, want 4: retries dropped, unnumbered ones kept", handled)
+	}
+	if len(c.send) != 4 {
+		t.Errorf("%d messages queued, want an INPUT_ACK for each of the 4 numbered actions", len(c.send))
+	}
+
+	// An action resent on a new connection after a reconnect is still a retry
+	handled = 0
+	c = connect()
+	handler(c, protocol.WSMessage{Type: protocol.PlayCard, InputSeq: 2})
+	handler(c, protocol.WSMessage{Type: protocol.PlayCard, InputSeq: 3})
+	if handled != 1 {
+		t.Errorf("handled %d actions after reconnecting, want only the new one", handled)
+	}
+	if got := r.LastInput(playerID); got != 3 {
+		t.Errorf("last input %d, want 3", got)
+	}
+}
+
+// Errors should come in the client's language, keeping the key and
+// parameters for clients that translate them themselves
+func TestLocalizedError(t *testing.T) {
+	h := newTestHub(t, 1, 1)
+	c := h.GetClientsInRoom("R0")[0]
+	c.SetLocale("fr-CA", "en-US,en;q=0.8")
+
+	c.sendLocalized(protocol.CodeNotEnoughPlayers, locale.NeedPlayers, locale.Params{"count": "2"})
+	var msg struct {
+		Payload protocol.ErrorPayload `json:"payload"`
+	}
+	if err := json.Unmarshal(<-c.send, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Il faut au moins 2 joueurs pour commencer"; msg.Payload.Message != want {
+		t.Errorf("message = %q, want %q", msg.Payload.Message, want)
+	}
+	if msg.Payload.Key != locale.NeedPlayers || msg.Payload.Params["count"] != "2" {
+		t.Errorf("key = %q, params = %v, want %q with count 2", msg.Payload.Key, msg.Payload.Params, locale.NeedPlayers)
+	}
+}
+
+// Broadcast cost should follow the room's size, not how many clients the
+// server has in total
+func BenchmarkBroadcastToRoom(b *testing.B) {
+	message := []byte(`{"type":"REACT","payload":{"emoji":"👋"}}`)
+	for _, total := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("clients=%d", total), func(b *testing.B) {
+			h := newTestHub(b, total, 4)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				h.BroadcastToRoom("R0", message)
+				// Keep the room's buffers from filling, so every send is delivered
+				for c := range h.roomClients["R0"] {
+					<-c.send
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetClientsInRoom(b *testing.B) {
+	for _, total := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("clients=%d", total), func(b *testing.B) {
+			h := newTestHub(b, total, 4)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				h.GetClientsInRoom("R0")
+			}
+		})
+	}
+}
+
+// Compressed connections should carry a state-heavy message in fewer bytes
+// than it has
+func TestCompression(t *testing.T) {
+	h := NewHub(nil, config.Static(config.Default()), clock.NewMock(time.Now()))
+	upgrader := websocket.Upgrader{EnableCompression: true}
+	clients := make(chan *Client, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		compressed := OffersCompression(r)
+		conn, err := upgrader.Upgrade(h.CountWireBytes(w, compressed), r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		c := NewClient(h, conn, "session", "")
+		c.SetCompression(compressed)
+		go c.writePump()
+		clients <- c
+	}))
+	defer server.Close()
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	c := <-clients
+	handshake := h.wireBytes.With(compressionOn).Value()
+
+	state := protocol.RoomState{Code: "ABCD", Settings: room.DefaultSettings().ToProtocol(), Status: "waiting"}
+	for i := 0; i < 8; i++ {
+		state.Players = append(state.Players, protocol.Player{
+			ID:          fmt.Sprintf("player-%d", i),
+			Name:        fmt.Sprintf("Player %d", i),
+			IsConnected: true,
+			Position:    i,
+			Avatar:      &protocol.Avatar{Emoji: "🃏", Color: "#336699"},
+		})
+	}
+	state.HostID = state.Players[0].ID
+	c.SendMessage(protocol.NewMessage(protocol.RoomJoined, protocol.RoomJoinedPayload{Room: state}))
+	if _, _, err := conn.ReadMessage(); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	// The server counts the frame just after the client can read it
+	wire := h.wireBytes.With(compressionOn).Value()
+	for deadline := time.Now().Add(time.Second); wire == handshake && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		wire = h.wireBytes.With(compressionOn).Value()
+	}
+	wire -= handshake
+	payload := h.payloadBytes.With(compressionOn).Value()
+	if payload < compressMinBytes {
+		t.Fatalf("only %d payload bytes counted on the compressed connection", payload)
+	}
+	if wire >= payload {
+		t.Errorf("%d bytes on the wire for %d payload bytes, want fewer", wire, payload)
+	}
+	t.Logf("%d payload bytes sent as %d wire bytes (%.0f%%)", payload, wire, 100*float64(wire)/float64(payload))
+}
+
+// A join that fails should leave the player in the room they were in, and
+// one that succeeds should take them out of it
+func TestJoinLeavesPreviousRoomOnlyOnSuccess(t *testing.T) {
+	h := newTestHub(t, 1, 1)
+	c := h.GetClientsInRoom("R0")[0]
+	first, playerID, err := h.rooms.CreateRoom(c.SessionID, "Alex", "alex-token", "", nil, h.BroadcastToRoom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.enterRoom(first, playerID, first.GetPlayer(playerID))
+
+	join := func(code string) {
+		payload, _ := json.Marshal(protocol.JoinRoomPayload{RoomCode: code, PlayerName: "Alex"})
+		c.handleJoinRoom(payload)
+	}
+	join("ZZZZ")
+	if c.RoomCode != first.Code || h.rooms.GetRoom(first.Code) == nil {
+		t.Fatalf("failed join left the client in %q, room open %v", c.RoomCode, h.rooms.GetRoom(first.Code) != nil)
+	}
+
+	second, _, err := h.rooms.CreateRoom("other-session", "Sam", "sam-token", "", nil, h.BroadcastToRoom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	join(second.Code)
+	if c.RoomCode != second.Code {
+		t.Errorf("client in %q after joining %s", c.RoomCode, second.Code)
+	}
+	if h.rooms.GetRoom(first.Code) != nil {
+		t.Error("room the client hosted still open after they joined another")
+	}
+}
+
+// A player leaving mid-game can leave a single player standing, who wins
+// whether or not the turn was the leaver's
+func TestLeaverDecidesWinner(t *testing.T) {
+	for _, turnHolderLeaves := range []bool{false, true} {
+		clk := clock.NewMock(time.Now())
+		h := NewHub(nil, config.Static(config.Default()), clk)
+		r, alexID, err := h.rooms.CreateRoom("alex-session", "Alex", "alex-token", "", nil, h.BroadcastToRoom)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, samID, _, err := h.rooms.JoinRoom(r.Code, "Sam", "sam-token", nil, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.StartGame(clk)
+
+		stayer, leaver := alexID, samID
+		if (r.Game.TurnOrder[r.Game.CurrentTurnIdx] == samID) != turnHolderLeaves {
+			stayer, leaver = samID, alexID
+		}
+		if turnHolderLeaves {
+			// A jack on the pile would otherwise leave the stayer to slap it alone
+			r.Game.Pile = append(r.Game.Pile, game.Card{Rank: "J", Suit: "hearts"})
+		}
+		c := NewClient(h, nil, "stayer-session", "")
+		h.mu.Lock()
+		h.clients[c] = true
+		h.mu.Unlock()
+		h.setSeat(c, r.Code, stayer, "Stayer")
+
+		h.rooms.LeaveRoom(r.Code, leaver, h.BroadcastToRoom)
+		if r.Status != "finished" {
+			t.Fatalf("room %s after all but one player left (turn holder leaving %v), want finished", r.Status, turnHolderLeaves)
+		}
+		gameOver := false
+		for len(c.send) > 0 {
+			var msg protocol.WSMessage
+			json.Unmarshal(<-c.send, &msg)
+			gameOver = gameOver || msg.Type == protocol.GameOver
+		}
+		if !gameOver {
+			t.Errorf("remaining player never told the game was over (turn holder leaving %v)", turnHolderLeaves)
+		}
+	}
+}
+
+// Clients that filter broadcasts, or have cosmetic ones shaped away, should
+// never see what looks like a missed broadcast and ask for a resync
+func TestFilteredClientsSeeNoGaps(t *testing.T) {
+	h := NewHub(nil, config.Static(config.Default()), clock.NewMock(time.Now()))
+	r, _, err := h.rooms.CreateRoom("host-session", "Alex", "host-token", "", nil, h.BroadcastToRoom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	join := func(session string) *Client {
+		c := NewClient(h, nil, session, "")
+		h.mu.Lock()
+		h.clients[c] = true
+		h.mu.Unlock()
+		h.setRoom(c, r.Code)
+		return c
+	}
+	plain, subscribed, shaped := join("plain"), join("subscribed"), join("shaped")
+	subscribed.subs.subscribe([]string{protocol.CardPlayed})
+	for len(shaped.send) < saturatedQueueDepth {
+		shaped.send <- []byte(`{}`)
+	}
+
+	for _, msgType := range []string{protocol.CardPlayed, protocol.React, protocol.TurnChanged, protocol.React, protocol.CardPlayed} {
+		msgData, _ := json.Marshal(protocol.NewMessage(msgType, struct{}{}))
+		h.BroadcastToRoom(r.Code, msgData)
+	}
+
+	// The same check the client makes before sending RESYNC
+	for name, c := range map[string]*Client{"plain": plain, "subscribed": subscribed, "shaped": shaped} {
+		var lastSeq int64
+		received := 0
+		for len(c.send) > 0 {
+			var msg protocol.WSMessage
+			json.Unmarshal(<-c.send, &msg)
+			if msg.Type == "" {
+				continue
+			}
+			received++
+			if msg.Seq == 0 {
+				continue
+			}
+			if lastSeq > 0 && msg.Seq > lastSeq+1 {
+				t.Errorf("%s client saw seq %d after %d and would resync", name, msg.Seq, lastSeq)
+			}
+			lastSeq = msg.Seq
+		}
+		want := map[string]int{"plain": 5, "subscribed": 2, "shaped": 3}[name]
+		if received != want {
+			t.Errorf("%s client received %d broadcasts, want %d", name, received, want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package websocket
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"slapjack/internal/locale"
+	"slapjack/pkg/protocol"
+)
+
+// markActive restarts the client's idle timer
+func (c *Client) markActive() {
+	c.lastActive.Store(c.clock.Now().UnixNano())
+}
+
+// idleTimedOut reports whether the client has been outside a room without
+// sending anything for longer than the configured idle timeout
+// Pongs don't count: a tab left open keeps answering pings
+func (c *Client) idleTimedOut() bool {
+	timeout := c.hub.cfg.Get().IdleClientTimeout
+	if timeout <= 0 {
+		return false
+	}
+	c.hub.mu.RLock()
+	inRoom := c.hub.roomOf(c) != ""
+	c.hub.mu.RUnlock()
+	if inRoom {
+		return false
+	}
+	return c.clock.Since(time.Unix(0, c.lastActive.Load())) > timeout
+}
+
+// writeIdleTimeout tells an idle client why it is being disconnected and
+// closes the connection normally, so it doesn't reconnect by itself
+// Called by the writer, which closes the connection once it returns
+func (c *Client) writeIdleTimeout() {
+	c.logger().Info("disconnecting idle client", "idleTimeout", c.hub.cfg.Get().IdleClientTimeout)
+
+	enc := c.Encoder()
+	frameType := websocket.TextMessage
+	if enc.Binary() {
+		frameType = websocket.BinaryMessage
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	msgData, _ := json.Marshal(protocol.NewMessage(protocol.Error, c.localizedError(protocol.CodeIdleTimeout, locale.IdleTimeout, nil)))
+	if frame := c.encodeBatch(enc, [][]byte{msgData}); len(frame) > 0 {
+		c.conn.WriteMessage(frameType, frame)
+	}
+	c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, string(protocol.CodeIdleTimeout)))
+}
@@ -0,0 +1,45 @@
+package websocket
+
+import (
+	"slapjack/internal/locale"
+	"slapjack/pkg/protocol"
+)
+
+// Locale returns the language the client's messages are written in
+func (c *Client) Locale() string {
+	if lang := c.lang.Load(); lang != nil {
+		return *lang
+	}
+	return locale.Default
+}
+
+// SetLocale picks the supported language closest to the client's preferences
+// (language codes or Accept-Language headers) for the messages sent to it
+func (c *Client) SetLocale(preferences ...string) {
+	lang := locale.Match(preferences...)
+	c.lang.Store(&lang)
+}
+
+// localizedError builds an error in the client's language, keeping the key
+// and parameters so clients can translate it themselves
+func (c *Client) localizedError(code protocol.ErrorCode, key string, params locale.Params) protocol.ErrorPayload {
+	return protocol.ErrorPayload{
+		Code:    code,
+		Message: locale.Text(c.Locale(), key, params),
+		Key:     key,
+		Params:  params,
+	}
+}
+
+// sendLocalized sends an error in the client's language
+func (c *Client) sendLocalized(code protocol.ErrorCode, key string, params locale.Params) {
+	c.SendMessage(protocol.NewMessage(protocol.Error, c.localizedError(code, key, params)))
+}
+
+// sendLocalizedField sends an error in the client's language naming the
+// payload field that failed validation
+func (c *Client) sendLocalizedField(code protocol.ErrorCode, field, key string) {
+	payload := c.localizedError(code, key, nil)
+	payload.Details = map[string]string{"field": field}
+	c.SendMessage(protocol.NewMessage(protocol.Error, payload))
+}
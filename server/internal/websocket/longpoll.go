@@ -0,0 +1,289 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"slapjack/internal/clock"
+	"slapjack/internal/locale"
+	"slapjack/pkg/protocol"
+)
+
+const (
+	// How long an events request waits for something to happen
+	pollTimeout = 25 * time.Second
+
+	// Long-polling sessions are dropped after this long without a request
+	pollIdleTimeout = 2 * time.Minute
+
+	// Most recent events kept per long-polling session
+	maxPollEvents = 500
+
+	// Largest action body accepted
+	maxActionSize = maxMessageSize
+)
+
+// pollEvent is a message queued for a long-polling client
+type pollEvent struct {
+	Seq     int64           `json:"seq"`
+	Message json.RawMessage `json:"message"`
+}
+
+// pollEventsResponse is returned by the events endpoint
+type pollEventsResponse struct {
+	Events  []pollEvent `json:"events"`
+	LastSeq int64       `json:"lastSeq"`
+}
+
+// pollActionResponse is returned by the actions endpoint
+type pollActionResponse struct {
+	SessionID    string `json:"sessionId"`
+	SessionToken string `json:"sessionToken"`
+	PlayerToken  string `json:"playerToken"`
+}
+
+// pollQueue is the sequence-numbered event log for a client without a WebSocket
+type pollQueue struct {
+	events   []pollEvent
+	lastSeq  int64
+	lastSeen time.Time
+	notify   chan struct{}
+
+	// Serializes actions, as the read pump does for WebSocket clients
+	actionMu sync.Mutex
+
+	clock clock.Clock
+	mu    sync.Mutex
+}
+
+func newPollQueue(clk clock.Clock) *pollQueue {
+	return &pollQueue{
+		lastSeen: clk.Now(),
+		notify:   make(chan struct{}),
+		clock:    clk,
+	}
+}
+
+// push appends a message and wakes any waiting events request
+func (q *pollQueue) push(message []byte) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.lastSeq++
+	q.events = append(q.events, pollEvent{Seq: q.lastSeq, Message: message})
+	if len(q.events) > maxPollEvents {
+		q.events = q.events[len(q.events)-maxPollEvents:]
+	}
+
+	close(q.notify)
+	q.notify = make(chan struct{})
+}
+
+// since returns events after the given sequence number, or a channel that is
+// closed when the next event arrives if there are none
+func (q *pollQueue) since(seq int64) ([]pollEvent, int64, <-chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.lastSeen = q.clock.Now()
+
+	var events []pollEvent
+	for _, event := range q.events {
+		if event.Seq > seq {
+			events = append(events, event)
+		}
+	}
+	return events, q.lastSeq, q.notify
+}
+
+func (q *pollQueue) touch() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.lastSeen = q.clock.Now()
+}
+
+func (q *pollQueue) idle() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.clock.Since(q.lastSeen) > pollIdleTimeout
+}
+
+// newPollClient creates a client that is driven over HTTP long-polling
+func newPollClient(hub *Hub, sessionID, playerToken string) *Client {
+	c := NewClient(hub, nil, sessionID, playerToken)
+	c.poll = newPollQueue(hub.clock)
+	return c
+}
+
+// pollPump moves messages sent to a long-polling client into its event log
+func (c *Client) pollPump() {
+	for message := range c.send {
+		c.poll.push(message)
+	}
+}
+
+// pollClient returns the long-polling client for a session, if the token matches
+func (h *Hub) pollClient(r *http.Request) *Client {
+	sessionID := r.URL.Query().Get("sessionId")
+	client := h.GetClientBySession(sessionID)
+	if client == nil || client.poll == nil {
+		return nil
+	}
+	if !h.rooms.VerifySessionToken(sessionID, r.URL.Query().Get("sessionToken")) {
+		return nil
+	}
+	return client
+}
+
+// pollCleanupRoutine drops long-polling sessions that stopped polling
+func (h *Hub) pollCleanupRoutine() {
+	ticker := h.clock.NewTicker(pollIdleTimeout / 2)
+	for range ticker.C() {
+		var idle []*Client
+		h.mu.RLock()
+		for client := range h.clients {
+			if client.poll != nil && client.poll.idle() {
+				idle = append(idle, client)
+			}
+		}
+		h.mu.RUnlock()
+
+		for _, client := range idle {
+			client.logger().Info("long-poll session timed out")
+			h.unregister <- client
+		}
+	}
+}
+
+// ServePollEvents handles GET /api/rooms/{code}/events?sessionId=...&sessionToken=...&since=seq
+// It returns queued events after since, waiting for new ones if there are none
+func (h *Hub) ServePollEvents(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	client := h.pollClient(r)
+	if client == nil {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+
+	since, _ := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+
+	events, lastSeq, notify := client.poll.since(since)
+	if len(events) == 0 {
+		select {
+		case <-notify:
+			events, lastSeq, _ = client.poll.since(since)
+		case <-h.clock.After(pollTimeout):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if events == nil {
+		events = []pollEvent{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pollEventsResponse{
+		Events:  events,
+		LastSeq: lastSeq,
+	})
+}
+
+// ServePollActions handles POST /api/rooms/{code}/actions?sessionId=...&sessionToken=...
+// The body is a client message, handled exactly as if it arrived over a WebSocket.
+// Sessions are created on the first action and returned in the response.
+func (h *Hub) ServePollActions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.IsShuttingDown() {
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	received := h.clock.Now()
+	var msg protocol.WSMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxActionSize)).Decode(&msg); err != nil {
+		http.Error(w, "invalid message", http.StatusBadRequest)
+		return
+	}
+	parse := h.clock.Since(received)
+
+	client := h.pollClient(r)
+	sessionToken := r.URL.Query().Get("sessionToken")
+	if client == nil {
+		if !h.AllowConnection(h.ClientIP(r)) {
+			http.Error(w, "too many connections", http.StatusTooManyRequests)
+			return
+		}
+
+		playerToken := r.URL.Query().Get("playerToken")
+		if _, err := uuid.Parse(playerToken); err != nil {
+			playerToken = uuid.New().String()
+		}
+
+		client = newPollClient(h, uuid.New().String(), playerToken)
+		client.SetLocale(r.URL.Query().Get("lang"), r.Header.Get("Accept-Language"))
+		sessionToken = h.rooms.IssueSessionToken(client.SessionID)
+		h.Register(client)
+		go client.pollPump()
+
+		if r.URL.Query().Get("sessionId") != "" {
+			client.SendMessage(protocol.NewMessage(protocol.SessionInvalid, protocol.SessionInvalidPayload{
+				Message: "Session could not be verified, starting a new session",
+			}))
+		}
+		client.SendMessage(protocol.NewMessage(protocol.Connected, protocol.ConnectedPayload{
+			SessionID:       client.SessionID,
+			SessionToken:    sessionToken,
+			PlayerToken:     client.PlayerToken,
+			ProtocolVersion: protocol.ProtocolVersion,
+			Features:        h.cfg.Get().Features(),
+		}))
+	}
+
+	client.poll.actionMu.Lock()
+	client.poll.touch()
+	h.applyDetach(client)
+
+	// Actions other than creating or joining a room must target the client's room
+	code := strings.ToUpper(r.PathValue("code"))
+	inRoom := msg.Type == protocol.CreateRoom || msg.Type == protocol.JoinRoom || client.RoomCode == code
+	switch {
+	case !inRoom:
+		client.sendLocalized(protocol.CodeNotInRoom, locale.NotInThisRoom, nil)
+	case !client.limiter.Allow():
+		client.sendLocalized(protocol.CodeRateLimited, locale.RateLimited, nil)
+	default:
+		client.handleMessage(msg, parse)
+	}
+	closing := client.closing
+	client.poll.actionMu.Unlock()
+
+	if closing {
+		h.unregister <- client
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pollActionResponse{
+		SessionID:    client.SessionID,
+		SessionToken: sessionToken,
+		PlayerToken:  client.PlayerToken,
+	})
+}
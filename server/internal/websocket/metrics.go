@@ -0,0 +1,61 @@
+package websocket
+
+import (
+	"io"
+	"time"
+)
+
+// Message handling stages timed per message type
+const (
+	stageParse  = "parse"
+	stageHandle = "handle" // Including the broadcasts it sends
+)
+
+// observeMessage records how long a client message took, logging it with its
+// room's context if it was slow
+func (h *Hub) observeMessage(c *Client, msgType string, parse, handle time.Duration) {
+	// Types are client-chosen, so unknown ones share a label
+	label := msgType
+	if !h.router.Has(msgType) {
+		label = "unknown"
+	}
+	h.messageLatency.With(label, stageParse).Observe(parse)
+	h.messageLatency.With(label, stageHandle).Observe(handle)
+
+	threshold := h.cfg.Get().SlowMessage
+	if threshold <= 0 || parse+handle < threshold {
+		return
+	}
+
+	attrs := []interface{}{"msgType", msgType, "parseMs", parse.Milliseconds(), "handleMs", handle.Milliseconds()}
+	if r := h.rooms.GetRoom(c.RoomCode); r != nil {
+		attrs = append(attrs, "roomStatus", r.Status, "players", len(r.GetAllPlayers()))
+		if g := r.Game; g != nil {
+			attrs = append(attrs, "gameId", g.ID, "gameEvents", g.EventCount())
+		}
+	}
+	c.logger().Warn("slow message", attrs...)
+}
+
+// WriteMetrics writes the hub's metrics in the Prometheus text format
+func (h *Hub) WriteMetrics(w io.Writer) error {
+	if err := h.messageLatency.Write(w); err != nil {
+		return err
+	}
+	if err := h.droppedMessages.Write(w); err != nil {
+		return err
+	}
+	if err := h.backpressure.Write(w); err != nil {
+		return err
+	}
+	if err := h.stuckRooms.Write(w); err != nil {
+		return err
+	}
+	if err := h.payloadBytes.Write(w); err != nil {
+		return err
+	}
+	if err := h.wireBytes.Write(w); err != nil {
+		return err
+	}
+	return h.rooms.WriteStoreMetrics(w)
+}
@@ -0,0 +1,191 @@
+package websocket
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"slapjack/internal/clock"
+)
+
+// tokenBucket is a simple token-bucket rate limiter
+type tokenBucket struct {
+	rate     float64 // tokens added per second
+	burst    float64
+	tokens   float64
+	lastFill time.Time
+	clock    clock.Clock
+	mu       sync.Mutex
+}
+
+func newTokenBucket(clk clock.Clock, rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:     rate,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		lastFill: clk.Now(),
+		clock:    clk,
+	}
+}
+
+// fill adds the tokens earned since the last fill
+// Caller must hold b.mu
+func (b *tokenBucket) fill() {
+	now := b.clock.Now()
+	b.tokens += now.Sub(b.lastFill).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.lastFill = now
+}
+
+// Allow takes a token if one is available
+func (b *tokenBucket) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.fill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// setRate changes the bucket's rate and burst, keeping the tokens already earned
+func (b *tokenBucket) setRate(rate float64, burst int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.fill()
+	b.rate = rate
+	b.burst = float64(burst)
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// reserve takes n tokens even if that overdraws the bucket, returning how long
+// until the bucket is back in credit
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.fill()
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// overdrawn reports whether more tokens were reserved than have been earned
+func (b *tokenBucket) overdrawn() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.fill()
+	return b.tokens < 0
+}
+
+// idle reports whether the bucket has refilled completely
+func (b *tokenBucket) idle() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens+b.clock.Since(b.lastFill).Seconds()*b.rate >= b.burst
+}
+
+// ipLimiter keeps a token bucket per remote IP
+type ipLimiter struct {
+	rate    float64
+	burst   int
+	buckets map[string]*tokenBucket
+	clock   clock.Clock
+	mu      sync.Mutex
+}
+
+func newIPLimiter(clk clock.Clock, perMinute int) *ipLimiter {
+	l := &ipLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   perMinute,
+		buckets: make(map[string]*tokenBucket),
+		clock:   clk,
+	}
+	go l.cleanupRoutine()
+	return l
+}
+
+// Allow reports whether the IP may open another connection
+func (l *ipLimiter) Allow(ip string) bool {
+	l.mu.Lock()
+	bucket, ok := l.buckets[ip]
+	if !ok {
+		bucket = newTokenBucket(l.clock, l.rate, l.burst)
+		l.buckets[ip] = bucket
+	}
+	l.mu.Unlock()
+	return bucket.Allow()
+}
+
+// setRate changes the connection limit for new and existing IPs
+func (l *ipLimiter) setRate(perMinute int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rate = float64(perMinute) / 60
+	l.burst = perMinute
+	for _, bucket := range l.buckets {
+		bucket.setRate(l.rate, l.burst)
+	}
+}
+
+// cleanupRoutine drops buckets for IPs that have gone quiet
+func (l *ipLimiter) cleanupRoutine() {
+	ticker := l.clock.NewTicker(time.Minute)
+	for range ticker.C() {
+		l.mu.Lock()
+		for ip, bucket := range l.buckets {
+			if bucket.idle() {
+				delete(l.buckets, ip)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// ClientIP returns the remote IP, taking the address reported by the proxy
+// only when the request came through a trusted one
+func (h *Hub) ClientIP(r *http.Request) string {
+	return clientIP(r, h.cfg.Get().TrustsProxy)
+}
+
+// clientIP returns the peer's address, or for a trusted peer the client it
+// forwarded for: Fly-Client-IP, else the nearest untrusted X-Forwarded-For hop
+func clientIP(r *http.Request, trusted func(ip string) bool) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	if !trusted(peer) {
+		return peer
+	}
+	if ip := strings.TrimSpace(r.Header.Get("Fly-Client-IP")); ip != "" {
+		return ip
+	}
+	// Hops are appended as the request passes through proxies, so anything
+	// left of the last trusted one may have been made up by the client
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			break
+		}
+		if !trusted(hop) {
+			return hop
+		}
+		peer = hop
+	}
+	return peer
+}
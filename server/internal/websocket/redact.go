@@ -0,0 +1,62 @@
+package websocket
+
+// Redaction controls how much identifying data debug output includes
+type Redaction string
+
+// Redaction levels, from most to least revealing
+const (
+	RedactNone    Redaction = "none"    // Everything visible
+	RedactPartial Redaction = "partial" // Session IDs hidden, player IDs shortened
+	RedactFull    Redaction = "full"    // Only counts, statuses and shortened room codes
+)
+
+// ParseRedaction parses a redaction level, returning fallback if it is unknown
+func ParseRedaction(s string, fallback Redaction) Redaction {
+	switch Redaction(s) {
+	case RedactNone, RedactPartial, RedactFull:
+		return Redaction(s)
+	}
+	return fallback
+}
+
+// Each field is redacted according to how sensitive it is. Session IDs let
+// anyone resume a seat, so they are only shown unredacted.
+
+func (r Redaction) sessionID(id string) string {
+	if r == RedactNone {
+		return id
+	}
+	return ""
+}
+
+func (r Redaction) playerID(id string) string {
+	switch r {
+	case RedactNone:
+		return id
+	case RedactPartial:
+		return shorten(id)
+	}
+	return ""
+}
+
+func (r Redaction) playerName(name string) string {
+	if r == RedactFull {
+		return ""
+	}
+	return name
+}
+
+func (r Redaction) roomCode(code string) string {
+	if r == RedactFull {
+		return shorten(code)
+	}
+	return code
+}
+
+// shorten keeps the first two characters of an identifier
+func shorten(id string) string {
+	if len(id) <= 2 {
+		return id
+	}
+	return id[:2] + "…"
+}
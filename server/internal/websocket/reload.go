@@ -0,0 +1,54 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"slapjack/internal/config"
+	"slapjack/internal/names"
+	"slapjack/pkg/protocol"
+)
+
+// ApplyConfig brings connected clients in line with a reloaded configuration
+// and tells every client which settings changed
+// Settings read on demand (TTLs, room limits, timeouts) need nothing here; the
+// bandwidth cap only applies to clients that connect after the reload
+func (h *Hub) ApplyConfig(previous config.Config, result config.ReloadResult) {
+	cfg := h.cfg.Get()
+
+	if cfg.ConnectionsPerMinIP != previous.ConnectionsPerMinIP {
+		h.connLimiter.setRate(cfg.ConnectionsPerMinIP)
+	}
+	if cfg.ProfanityFilter != previous.ProfanityFilter || cfg.ProfanityWordList != previous.ProfanityWordList {
+		h.loadNameValidator()
+	}
+	limitsChanged := cfg.MessagesPerSecond != previous.MessagesPerSecond || cfg.MessageBurst != previous.MessageBurst
+
+	msgData, _ := json.Marshal(protocol.NewMessage(protocol.ConfigReloaded, protocol.ConfigReloadedPayload{
+		Changed:  result.Changed,
+		Features: cfg.Features(),
+	}))
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.clients {
+		if limitsChanged {
+			client.limiter.setRate(float64(cfg.MessagesPerSecond), cfg.MessageBurst)
+		}
+		client.enqueue(msgData)
+	}
+
+	slog.Info("config reloaded", "changed", result.Changed, "requiresRestart", result.RequiresRestart, "clients", len(h.clients))
+}
+
+// loadNameValidator builds the player name rules from the config
+// If the word list can't be read, the default list is used instead
+func (h *Hub) loadNameValidator() {
+	cfg := h.cfg.Get()
+	v, err := names.NewValidator(cfg.ProfanityFilter, cfg.ProfanityWordList)
+	if err != nil {
+		slog.Error("failed to load profanity word list, using the default list", "path", cfg.ProfanityWordList, "error", err)
+		v, _ = names.NewValidator(cfg.ProfanityFilter, "")
+	}
+	h.nameValidator.Store(v)
+}
@@ -0,0 +1,277 @@
+package websocket
+
+import (
+	"math"
+	"strconv"
+	"sync"
+
+	"slapjack/internal/locale"
+	"slapjack/pkg/protocol"
+)
+
+// HandlerFunc handles one incoming message from a client
+type HandlerFunc func(c *Client, msg protocol.WSMessage)
+
+// Middleware wraps a handler, typically to check a precondition before it runs
+type Middleware func(HandlerFunc) HandlerFunc
+
+// Router maps message types to handlers
+// Other packages can add message types through Hub.Router without touching this package
+type Router struct {
+	routes map[string]HandlerFunc
+	mu     sync.RWMutex
+}
+
+// NewRouter creates an empty router
+func NewRouter() *Router {
+	return &Router{
+		routes: make(map[string]HandlerFunc),
+	}
+}
+
+// Handle registers the handler for a message type, wrapped in the given
+// middleware (the first middleware runs first). Registering a type again replaces it.
+func (r *Router) Handle(msgType string, handler HandlerFunc, middleware ...Middleware) {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[msgType] = handler
+}
+
+// Has reports whether a handler is registered for a message type
+func (r *Router) Has(msgType string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.routes[msgType]
+	return ok
+}
+
+// Dispatch runs the handler registered for the message's type
+func (r *Router) Dispatch(c *Client, msg protocol.WSMessage) {
+	r.mu.RLock()
+	handler, ok := r.routes[msg.Type]
+	r.mu.RUnlock()
+
+	c.logger().Debug("message received", "msgType", msg.Type)
+	if !ok {
+		c.sendLocalized(protocol.CodeUnknownMessage, locale.UnknownMessage, locale.Params{
+			"type":   msg.Type,
+			"server": strconv.Itoa(protocol.ProtocolVersion),
+			"client": strconv.Itoa(c.ProtocolVersion),
+		})
+		return
+	}
+	handler(c, msg)
+}
+
+// RequireRoom rejects messages from clients that are not in a room
+func RequireRoom(next HandlerFunc) HandlerFunc {
+	return func(c *Client, msg protocol.WSMessage) {
+		if c.RoomCode == "" {
+			c.sendLocalized(protocol.CodeNotInRoom, locale.NotInRoom, nil)
+			return
+		}
+		next(c, msg)
+	}
+}
+
+// RequireAuth rejects messages from clients whose player token doesn't match
+// the seat they claim, so only the seat's owner can act for it
+func RequireAuth(next HandlerFunc) HandlerFunc {
+	return func(c *Client, msg protocol.WSMessage) {
+		room := c.hub.rooms.GetRoom(c.RoomCode)
+		if room == nil {
+			c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
+			return
+		}
+		player := room.GetPlayer(c.PlayerID)
+		if player == nil || (player.Token != "" && player.Token != c.PlayerToken) {
+			c.sendLocalized(protocol.CodeNotAPlayer, locale.SeatedOnly, nil)
+			return
+		}
+		next(c, msg)
+	}
+}
+
+// RequireHost rejects messages from anyone but the room's host with the
+// message under key
+func RequireHost(key string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Client, msg protocol.WSMessage) {
+			room := c.hub.rooms.GetRoom(c.RoomCode)
+			if room == nil {
+				c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
+				return
+			}
+			if room.HostID != c.PlayerID {
+				c.sendLocalized(protocol.CodeNotHost, key, nil)
+				return
+			}
+			next(c, msg)
+		}
+	}
+}
+
+// RequireFeature rejects messages for an optional feature that is switched off
+func RequireFeature(feature string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Client, msg protocol.WSMessage) {
+			if !c.hub.cfg.Get().FeatureEnabled(feature) {
+				c.sendLocalized(protocol.CodeFeatureDisabled, locale.FeatureDisabled, locale.Params{"feature": feature})
+				return
+			}
+			next(c, msg)
+		}
+	}
+}
+
+// RateLimit limits how often each client may send a message type, on top of
+// the connection-wide message limit
+func RateLimit(perSecond float64, burst int) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Client, msg protocol.WSMessage) {
+			// Messages from one client are handled one at a time, so no locking is needed
+			if c.routeLimiters == nil {
+				c.routeLimiters = make(map[string]*tokenBucket)
+			}
+			limiter, ok := c.routeLimiters[msg.Type]
+			if !ok {
+				limiter = newTokenBucket(c.clock, perSecond, burst)
+				c.routeLimiters[msg.Type] = limiter
+			}
+			if !limiter.Allow() {
+				c.sendLocalized(protocol.CodeRateLimited, locale.RateLimited, nil)
+				return
+			}
+			next(c, msg)
+		}
+	}
+}
+
+// Idempotent handles each numbered action once: the first delivery is
+// handled and acknowledged with INPUT_ACK, and retries of it are dropped.
+// The last action applied is kept per player in the room, so an action
+// resent after a reconnect is still recognised.
+// Messages without an input sequence number are always handled
+func Idempotent(next HandlerFunc) HandlerFunc {
+	return func(c *Client, msg protocol.WSMessage) {
+		r := c.hub.rooms.GetRoom(c.RoomCode)
+		if msg.InputSeq == 0 || r == nil {
+			next(c, msg)
+			return
+		}
+		if !r.TakeInput(c.PlayerID, msg.InputSeq) {
+			c.logger().Debug("duplicate input dropped", "msgType", msg.Type, "inputSeq", msg.InputSeq)
+			c.SendMessage(protocol.NewMessage(protocol.InputAck, protocol.InputAckPayload{InputSeq: msg.InputSeq}))
+			return
+		}
+		next(c, msg)
+		c.SendMessage(protocol.NewMessage(protocol.InputAck, protocol.InputAckPayload{InputSeq: msg.InputSeq}))
+	}
+}
+
+// SlowMode holds each player to the room's slow mode, if the host turned it
+// on, rejecting messages sent too soon after their last with the time left
+func SlowMode(next HandlerFunc) HandlerFunc {
+	return func(c *Client, msg protocol.WSMessage) {
+		r := c.hub.rooms.GetRoom(c.RoomCode)
+		if r == nil {
+			next(c, msg)
+			return
+		}
+		sender := c.PlayerID
+		if sender == "" {
+			sender = c.SessionID
+		}
+		if wait := r.TakeSlowMode(sender, c.clock.Now()); wait > 0 {
+			seconds := int(math.Ceil(wait.Seconds()))
+			payload := c.localizedError(protocol.CodeSlowMode, locale.SlowMode, locale.Params{"seconds": strconv.Itoa(seconds)})
+			payload.Details = map[string]string{"retryAfterMs": strconv.FormatInt(wait.Milliseconds(), 10)}
+			c.SendMessage(protocol.NewMessage(protocol.Error, payload))
+			return
+		}
+		next(c, msg)
+	}
+}
+
+// defaultRouter registers the built-in message types
+func defaultRouter() *Router {
+	r := NewRouter()
+
+	r.Handle(protocol.ClientHello, func(c *Client, msg protocol.WSMessage) { c.handleClientHello(msg.Payload) })
+	r.Handle(protocol.Subscribe, func(c *Client, msg protocol.WSMessage) { c.handleSubscribe(msg.Payload, true) })
+	r.Handle(protocol.Unsubscribe, func(c *Client, msg protocol.WSMessage) { c.handleSubscribe(msg.Payload, false) })
+	r.Handle(protocol.RequestReplay, func(c *Client, msg protocol.WSMessage) { c.handleRequestReplay(msg.Payload) },
+		RequireFeature(protocol.FeatureReplay), RateLimit(0.2, 2))
+
+	// Lobby
+	r.Handle(protocol.CreateRoom, func(c *Client, msg protocol.WSMessage) { c.handleCreateRoom(msg.Payload) })
+	r.Handle(protocol.JoinRoom, func(c *Client, msg protocol.WSMessage) { c.handleJoinRoom(msg.Payload) })
+	r.Handle(protocol.PlayNow, func(c *Client, msg protocol.WSMessage) { c.handlePlayNow(msg.Payload) },
+		RateLimit(1, 3))
+
+	// Wallet
+	r.Handle(protocol.GetWallet, func(c *Client, msg protocol.WSMessage) { c.handleGetWallet() },
+		RateLimit(1, 3))
+	r.Handle(protocol.BuyItem, func(c *Client, msg protocol.WSMessage) { c.handleBuyItem(msg.Payload) },
+		RateLimit(1, 3))
+	r.Handle(protocol.EquipItem, func(c *Client, msg protocol.WSMessage) { c.handleEquipItem(msg.Payload) },
+		RateLimit(1, 3))
+	r.Handle(protocol.CalibrateStart, func(c *Client, msg protocol.WSMessage) { c.handleCalibrateStart(msg.Payload) },
+		RateLimit(0.1, 2))
+	r.Handle(protocol.CalibrateTap, func(c *Client, msg protocol.WSMessage) { c.handleCalibrateTap() },
+		RateLimit(5, 10))
+	r.Handle(protocol.RegisterProfile, func(c *Client, msg protocol.WSMessage) { c.handleRegisterProfile(msg.Payload) },
+		RateLimit(0.2, 3))
+	r.Handle(protocol.LeaveRoom, func(c *Client, msg protocol.WSMessage) { c.handleLeaveRoom() },
+		RequireRoom)
+
+	// Room members
+	r.Handle(protocol.ChangeName, func(c *Client, msg protocol.WSMessage) { c.handleChangeName(msg.Payload) },
+		RequireRoom, RequireAuth)
+	r.Handle(protocol.React, func(c *Client, msg protocol.WSMessage) { c.handleReact(msg.Payload) },
+		RequireRoom, RateLimit(3, 5), SlowMode)
+	r.Handle(protocol.RequestRematch, func(c *Client, msg protocol.WSMessage) { c.handleRequestRematch() },
+		RequireRoom, RequireAuth)
+	r.Handle(protocol.Ready, func(c *Client, msg protocol.WSMessage) { c.handleReady() },
+		RequireRoom, RequireAuth)
+	r.Handle(protocol.SetTeam, func(c *Client, msg protocol.WSMessage) { c.handleSetTeam(msg.Payload) },
+		RequireRoom, RequireAuth, RateLimit(2, 5))
+	r.Handle(protocol.VoteKick, func(c *Client, msg protocol.WSMessage) { c.handleVoteKick(msg.Payload) },
+		RequireRoom, RequireAuth, RateLimit(0.5, 3))
+	r.Handle(protocol.Resync, func(c *Client, msg protocol.WSMessage) { c.handleResync() },
+		RequireRoom, RateLimit(1, 3))
+	r.Handle(protocol.Ack, func(c *Client, msg protocol.WSMessage) { c.handleAck(msg.Payload) },
+		RequireRoom, RequireAuth, RateLimit(2, 5))
+	r.Handle(protocol.ReportTelemetry, func(c *Client, msg protocol.WSMessage) { c.handleReportTelemetry(msg.Payload) },
+		RequireRoom, RequireAuth, RateLimit(0.1, 2))
+	r.Handle(protocol.RTCSignal, func(c *Client, msg protocol.WSMessage) { c.handleRTCSignal(msg.Payload) },
+		RequireFeature(protocol.FeatureRTC), RequireRoom, RequireAuth, RateLimit(20, 50))
+
+	// Host powers
+	r.Handle(protocol.UpdateSettings, func(c *Client, msg protocol.WSMessage) { c.handleUpdateSettings(msg.Payload) },
+		RequireRoom, RequireAuth, RequireHost(locale.HostOnlySettings))
+	r.Handle(protocol.StartGame, func(c *Client, msg protocol.WSMessage) { c.handleStartGame() },
+		RequireRoom, RequireAuth, RequireHost(locale.HostOnlyStart))
+	r.Handle(protocol.CancelStart, func(c *Client, msg protocol.WSMessage) { c.handleCancelStart() },
+		RequireRoom, RequireAuth, RequireHost(locale.HostOnlyCancelStart))
+	r.Handle(protocol.KickPlayer, func(c *Client, msg protocol.WSMessage) { c.handleKickPlayer(msg.Payload) },
+		RequireRoom, RequireAuth, RequireHost(locale.HostOnlyKick))
+	r.Handle(protocol.TransferHost, func(c *Client, msg protocol.WSMessage) { c.handleTransferHost(msg.Payload) },
+		RequireRoom, RequireAuth, RequireHost(locale.HostOnlyTransfer))
+	r.Handle(protocol.EndGame, func(c *Client, msg protocol.WSMessage) { c.handleEndGame() },
+		RequireRoom, RequireAuth, RequireHost(locale.HostOnlyEndGame))
+	r.Handle(protocol.SetIntegration, func(c *Client, msg protocol.WSMessage) { c.handleSetIntegration(msg.Payload) },
+		RequireFeature(protocol.FeatureWebhooks), RequireRoom, RequireAuth, RequireHost(locale.HostOnlyIntegration), RateLimit(0.2, 3))
+
+	// Gameplay
+	r.Handle(protocol.PlayCard, func(c *Client, msg protocol.WSMessage) { c.handlePlayCard(msg.Payload) },
+		RequireRoom, RequireAuth, Idempotent)
+	r.Handle(protocol.Slap, func(c *Client, msg protocol.WSMessage) { c.handleSlap(msg.Payload, msg.Timestamp) },
+		RequireRoom, RequireAuth, Idempotent)
+
+	return r
+}
@@ -0,0 +1,54 @@
+package websocket
+
+import (
+	"encoding/json"
+
+	"slapjack/internal/locale"
+	"slapjack/pkg/protocol"
+)
+
+// Largest signal relayed; an SDP offer with video runs to a few KB
+const maxRTCSignalBytes = 16 << 10
+
+// handleRTCSignal relays WebRTC signaling to another player in the room, so
+// players can set up voice and video between themselves; media never passes
+// through the server
+func (c *Client) handleRTCSignal(payload json.RawMessage) {
+	var signal protocol.RTCSignalPayload
+	if !c.decodePayload(payload, &signal, "Invalid signal") {
+		return
+	}
+
+	r := c.hub.rooms.GetRoom(c.RoomCode)
+	if r == nil {
+		c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
+		return
+	}
+
+	// Hangups still go through, so calls can be torn down once the host turns it off
+	if !r.Settings.EnableRTC && signal.Kind != protocol.RTCHangup {
+		c.sendLocalized(protocol.CodeRTCDisabled, locale.RTCDisabled, nil)
+		return
+	}
+	switch signal.Kind {
+	case protocol.RTCOffer, protocol.RTCAnswer, protocol.RTCCandidate, protocol.RTCHangup:
+	default:
+		c.sendLocalizedField(protocol.CodeInvalidSignal, "kind", locale.UnknownSignal)
+		return
+	}
+	if len(signal.Data) > maxRTCSignalBytes {
+		c.sendLocalizedField(protocol.CodeInvalidSignal, "data", locale.SignalTooLarge)
+		return
+	}
+
+	targetID := signal.TargetID
+	if targetID == c.PlayerID || r.GetPlayer(targetID) == nil {
+		c.sendLocalizedField(protocol.CodePlayerNotFound, "targetId", locale.PlayerNotInRoom)
+		return
+	}
+
+	signal.TargetID = ""
+	signal.FromID = c.PlayerID
+	msgData, _ := json.Marshal(protocol.NewMessage(protocol.RTCSignal, signal))
+	c.hub.SendToPlayer(c.RoomCode, targetID, msgData)
+}
@@ -0,0 +1,60 @@
+package websocket
+
+import (
+	"slapjack/internal/clock"
+	"slapjack/pkg/protocol"
+)
+
+// Queued outbound messages beyond which a client's link counts as saturated
+const saturatedQueueDepth = 32
+
+// cosmeticMessages are dropped first when a client's link is saturated, so
+// gameplay messages still arrive on time
+var cosmeticMessages = map[string]bool{
+	protocol.React:         true,
+	protocol.SlapAttempted: true,
+	protocol.HighlightMsg:  true,
+}
+
+// newBandwidthCap creates the outbound byte budget for a client, or nil if uncapped
+func newBandwidthCap(clk clock.Clock, bytesPerSecond int) *tokenBucket {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return newTokenBucket(clk, float64(bytesPerSecond), bytesPerSecond)
+}
+
+// saturated reports whether the client's outbound link is backed up, either
+// because writePump can't keep up or because it is over its bandwidth cap
+func (c *Client) saturated() bool {
+	if len(c.send) >= saturatedQueueDepth {
+		return true
+	}
+	return c.bandwidth != nil && c.bandwidth.overdrawn()
+}
+
+// shapeDrop reports whether a broadcast should be dropped to relieve a saturated link
+func (c *Client) shapeDrop(message []byte, msgType *string) bool {
+	if !c.saturated() {
+		return false
+	}
+	if *msgType == "" {
+		*msgType = messageType(message)
+	}
+	if !cosmeticMessages[*msgType] {
+		return false
+	}
+	c.droppedCosmetic.Add(1)
+	return true
+}
+
+// throttleWrite charges written bytes against the bandwidth cap, pausing the
+// writer when it is over budget
+func (c *Client) throttleWrite(bytes int) {
+	if c.bandwidth == nil {
+		return
+	}
+	if wait := c.bandwidth.reserve(float64(bytes)); wait > 0 {
+		c.clock.Sleep(wait)
+	}
+}
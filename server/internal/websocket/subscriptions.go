@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// subscriptions filters which room broadcasts a client receives
+// The zero value receives everything
+type subscriptions struct {
+	only  map[string]bool // When set, only these message types are delivered
+	muted map[string]bool // Message types never delivered
+	mu    sync.RWMutex
+}
+
+// subscribe limits delivery to the given message types (added to any existing
+// subscriptions). An empty list resets the filter to receive everything.
+func (s *subscriptions) subscribe(types []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(types) == 0 {
+		s.only = nil
+		s.muted = nil
+		return
+	}
+
+	if s.only == nil {
+		s.only = make(map[string]bool)
+	}
+	for _, t := range types {
+		s.only[t] = true
+		delete(s.muted, t)
+	}
+}
+
+// unsubscribe stops delivery of the given message types
+func (s *subscriptions) unsubscribe(types []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range types {
+		if s.only != nil {
+			delete(s.only, t)
+			continue
+		}
+		if s.muted == nil {
+			s.muted = make(map[string]bool)
+		}
+		s.muted[t] = true
+	}
+}
+
+// active reports whether any filter is in place
+func (s *subscriptions) active() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.only != nil || len(s.muted) > 0
+}
+
+// wants reports whether a message type should be delivered
+func (s *subscriptions) wants(msgType string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.muted[msgType] {
+		return false
+	}
+	return s.only == nil || s.only[msgType]
+}
+
+// list returns the subscribed and muted message types
+func (s *subscriptions) list() (only, muted []string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for t := range s.only {
+		only = append(only, t)
+	}
+	for t := range s.muted {
+		muted = append(muted, t)
+	}
+	sort.Strings(only)
+	sort.Strings(muted)
+	return only, muted
+}
+
+// wantsBroadcast reports whether the client should get a room broadcast, given
+// its subscriptions and whether its link is saturated
+// msgType caches the decoded message type across clients of the same broadcast
+func (c *Client) wantsBroadcast(message []byte, msgType *string) bool {
+	if c.shapeDrop(message, msgType) {
+		return false
+	}
+	if !c.subs.active() {
+		return true
+	}
+	if *msgType == "" {
+		*msgType = messageType(message)
+	}
+	return c.subs.wants(*msgType)
+}
+
+// messageType extracts the type of an encoded message for filtering
+func messageType(message []byte) string {
+	var envelope struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(message, &envelope)
+	return envelope.Type
+}
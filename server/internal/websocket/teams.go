@@ -0,0 +1,58 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+
+	"slapjack/internal/locale"
+	"slapjack/internal/room"
+	"slapjack/pkg/protocol"
+)
+
+// handleSetTeam moves a player to a team: players may move themselves and
+// the host anyone
+func (c *Client) handleSetTeam(payload json.RawMessage) {
+	var teamPayload protocol.SetTeamPayload
+	if !c.decodePayload(payload, &teamPayload, "Invalid team payload") {
+		return
+	}
+	if teamPayload.PlayerID == "" {
+		teamPayload.PlayerID = c.PlayerID
+	}
+
+	r := c.hub.rooms.GetRoom(c.RoomCode)
+	if r == nil {
+		c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
+		return
+	}
+	if teamPayload.PlayerID != c.PlayerID && r.HostID != c.PlayerID {
+		c.sendLocalized(protocol.CodeNotHost, locale.HostOnlyMovePlayers, nil)
+		return
+	}
+
+	switch err := r.SetTeam(teamPayload.PlayerID, teamPayload.TeamID); {
+	case errors.Is(err, room.ErrInvalidTeam):
+		c.sendFieldError(protocol.CodeInvalidTeam, "teamId", err.Error())
+		return
+	case errors.Is(err, room.ErrTeamsInGame):
+		c.sendError(protocol.CodeGameInProgress, err.Error())
+		return
+	case errors.Is(err, room.ErrTeamsOff):
+		c.sendError(protocol.CodeInvalidTeam, err.Error())
+		return
+	case err != nil:
+		c.sendLocalized(protocol.CodePlayerNotFound, locale.PlayerNotFound, nil)
+		return
+	}
+
+	c.broadcastTeams(r)
+	c.logger().Info("player changed team", "targetId", teamPayload.PlayerID, "teamId", teamPayload.TeamID)
+}
+
+// broadcastTeams tells the room every player's team
+func (c *Client) broadcastTeams(r *room.Room) {
+	msgData, _ := json.Marshal(protocol.NewMessage(protocol.TeamsChanged, protocol.TeamsChangedPayload{
+		Teams: r.TeamAssignments(),
+	}))
+	c.hub.BroadcastToRoom(c.RoomCode, msgData)
+}
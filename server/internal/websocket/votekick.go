@@ -0,0 +1,45 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+
+	"slapjack/internal/locale"
+	"slapjack/internal/room"
+	"slapjack/pkg/protocol"
+)
+
+// handleVoteKick starts or votes on a kick vote, removing the player once a
+// majority of the other players has voted them out
+func (c *Client) handleVoteKick(payload json.RawMessage) {
+	var votePayload protocol.VoteKickPayload
+	if !c.decodePayload(payload, &votePayload, "Invalid vote payload") {
+		return
+	}
+
+	r := c.hub.rooms.GetRoom(c.RoomCode)
+	if r == nil {
+		c.sendLocalized(protocol.CodeRoomNotFound, locale.RoomNotFound, nil)
+		return
+	}
+	target := r.GetPlayer(votePayload.PlayerID)
+	if target == nil {
+		c.sendLocalized(protocol.CodePlayerNotFound, locale.PlayerNotFound, nil)
+		return
+	}
+
+	result, err := c.hub.rooms.VoteKick(c.RoomCode, c.PlayerID, votePayload.PlayerID, votePayload.Yes, c.hub.BroadcastToRoom)
+	switch {
+	case errors.Is(err, room.ErrVoteCooldown), errors.Is(err, room.ErrVoteImmune):
+		c.sendError(protocol.CodeVoteCooldown, err.Error())
+		return
+	case err != nil:
+		c.sendError(protocol.CodeInvalidVote, err.Error())
+		return
+	}
+
+	if result != nil && result.Result == protocol.VoteResultPassed {
+		c.removeKicked(target.ID, target.Name)
+		c.logger().Info("player voted out", "kickedPlayerId", target.ID, "playerName", target.Name, "yes", result.Yes)
+	}
+}
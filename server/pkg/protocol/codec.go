@@ -0,0 +1,123 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// Wire encodings a client may ask for, in CLIENT_HELLO or the encoding query
+// parameter when connecting. JSON is the default.
+const (
+	EncodingJSON    = "json"
+	EncodingMsgPack = "msgpack"
+)
+
+// Encoder converts messages between JSON, which the server builds them in,
+// and a client's wire encoding
+type Encoder interface {
+	Name() string
+
+	// Binary reports whether messages go in binary rather than text frames
+	Binary() bool
+
+	// Encode converts one JSON message to the wire encoding
+	Encode(msg []byte) ([]byte, error)
+
+	// Decode parses one message in the wire encoding
+	Decode(data []byte, msg *WSMessage) error
+}
+
+var (
+	JSON    Encoder = jsonEncoder{}
+	MsgPack Encoder = msgPackEncoder{}
+)
+
+// EncoderFor returns the encoder for an encoding name
+func EncoderFor(name string) (Encoder, bool) {
+	switch name {
+	case EncodingJSON:
+		return JSON, true
+	case EncodingMsgPack:
+		return MsgPack, true
+	}
+	return nil, false
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) Name() string { return EncodingJSON }
+
+func (jsonEncoder) Binary() bool { return false }
+
+func (jsonEncoder) Encode(msg []byte) ([]byte, error) { return msg, nil }
+
+func (jsonEncoder) Decode(data []byte, msg *WSMessage) error {
+	return json.Unmarshal(data, msg)
+}
+
+// msgPackEncoder encodes messages as MessagePack maps with the same keys as
+// their JSON. Several may be sent back to back in one frame.
+type msgPackEncoder struct{}
+
+func (msgPackEncoder) Name() string { return EncodingMsgPack }
+
+func (msgPackEncoder) Binary() bool { return true }
+
+func (msgPackEncoder) Encode(msg []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(msg))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return appendMsgPack(make([]byte, 0, len(msg)), fromJSON(v))
+}
+
+func (msgPackEncoder) Decode(data []byte, msg *WSMessage) error {
+	r := msgPackReader{data: data}
+	v, err := r.value()
+	if err != nil {
+		return err
+	}
+	fields, ok := v.(map[string]interface{})
+	if !ok {
+		return errors.New("msgpack: message is not a map")
+	}
+
+	// Handlers decode payloads from JSON, so the payload is converted back
+	payload, err := json.Marshal(fields["payload"])
+	if err != nil {
+		return err
+	}
+	*msg = WSMessage{Payload: payload}
+	if msg.Type, ok = fields["type"].(string); !ok {
+		return errors.New("msgpack: message has no type")
+	}
+	msg.Timestamp, _ = fields["timestamp"].(int64)
+	msg.Seq, _ = fields["seq"].(int64)
+	msg.InputSeq, _ = fields["inputSeq"].(int64)
+	return nil
+}
+
+// fromJSON converts JSON numbers in a decoded value to int64 where they are
+// whole, and float64 otherwise
+func fromJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, item := range v {
+			v[i] = fromJSON(item)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = fromJSON(item)
+		}
+	}
+	return v
+}
@@ -0,0 +1,93 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Buffers larger than this aren't returned to the pool, so one huge message
+// doesn't pin its memory for good
+const maxPooledBuffer = 64 << 10
+
+// bufferPool reuses encoding buffers across broadcasts
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// EncodePayload encodes v as JSON, like json.Marshal, but builds it in a
+// pooled buffer so only the exactly sized result is allocated
+func EncodePayload(v interface{}) ([]byte, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			buf.Reset()
+			bufferPool.Put(buf)
+		}
+	}()
+
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+	encoded := bytes.TrimSuffix(buf.Bytes(), []byte{'\n'})
+	return append(make([]byte, 0, len(encoded)), encoded...), nil
+}
+
+// EncodeMessage wraps an encoded payload in a message envelope stamped with
+// the current time, producing the same bytes as json.Marshal(NewMessage(...))
+func EncodeMessage(msgType string, payload []byte) []byte {
+	msg := make([]byte, 0, len(msgType)+len(payload)+48)
+	msg = append(msg, `{"type":`...)
+	msg = strconv.AppendQuote(msg, msgType)
+	msg = append(msg, `,"payload":`...)
+	msg = append(msg, payload...)
+	msg = append(msg, `,"timestamp":`...)
+	msg = strconv.AppendInt(msg, time.Now().UnixMilli(), 10)
+	return append(msg, '}')
+}
+
+// WithPile marks an encoded message with the party pile it is about; pile 0
+// leaves it as it is
+func WithPile(pile int, message []byte) []byte {
+	if pile == 0 || len(message) < 2 || message[0] != '{' {
+		return message
+	}
+	marked := make([]byte, 0, len(message)+12)
+	marked = append(marked, `{"pile":`...)
+	marked = strconv.AppendInt(marked, int64(pile), 10)
+	if message[1] != '}' {
+		marked = append(marked, ',')
+	}
+	return append(marked, message[1:]...)
+}
+
+// PayloadCache keeps the encoding of the last payload built for a key, so a
+// message that is broadcast again unchanged isn't encoded again
+// The key must change whenever the payload would
+type PayloadCache struct {
+	last atomic.Pointer[cachedPayload]
+}
+
+type cachedPayload struct {
+	key     string
+	payload []byte
+}
+
+// Message returns an encoded message of the given type, building and encoding
+// its payload only if key differs from the last call's
+func (c *PayloadCache) Message(msgType, key string, build func() interface{}) []byte {
+	if last := c.last.Load(); last != nil && last.key == key {
+		return EncodeMessage(msgType, last.payload)
+	}
+
+	payload, err := EncodePayload(build())
+	if err != nil {
+		payload = []byte("null")
+	} else {
+		c.last.Store(&cachedPayload{key: key, payload: payload})
+	}
+	return EncodeMessage(msgType, payload)
+}
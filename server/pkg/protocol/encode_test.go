@@ -0,0 +1,184 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestEncodeMessageMatchesMarshal(t *testing.T) {
+	payload := TurnChangedPayload{CurrentPlayerID: "p<1>", TurnToken: 7}
+
+	encoded, err := EncodePayload(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := EncodeMessage(TurnChanged, encoded)
+
+	var msg WSMessage
+	if err := json.Unmarshal(got, &msg); err != nil {
+		t.Fatalf("invalid message %s: %v", got, err)
+	}
+	wantMsg := NewMessage(TurnChanged, payload)
+	wantMsg.Timestamp = msg.Timestamp
+	want, _ := json.Marshal(wantMsg)
+	if string(got) != string(want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestPayloadCache(t *testing.T) {
+	var cache PayloadCache
+	builds := 0
+	build := func() interface{} {
+		builds++
+		return TurnChangedPayload{CurrentPlayerID: "p1", TurnToken: int64(builds)}
+	}
+
+	cache.Message(TurnChanged, "a", build)
+	cache.Message(TurnChanged, "a", build)
+	if builds != 1 {
+		t.Errorf("built %d times for an unchanged key, want 1", builds)
+	}
+	cache.Message(TurnChanged, "b", build)
+	if builds != 2 {
+		t.Errorf("built %d times after the key changed, want 2", builds)
+	}
+}
+
+func BenchmarkEncodeMessage(b *testing.B) {
+	var cache PayloadCache
+	payload := TurnChangedPayload{CurrentPlayerID: "p1", TurnToken: 1}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cache.Message(TurnChanged, "p1:1", func() interface{} { return payload })
+	}
+}
+
+func TestMsgPackRoundTrip(t *testing.T) {
+	payload, _ := EncodePayload(SlapResultPayload{PlayerID: "p1", Success: true, Reason: "jack", CardsWon: 12})
+	message := EncodeMessage(SlapResult, payload)
+
+	encoded, err := MsgPack.Encode(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(encoded) >= len(message) {
+		t.Errorf("MessagePack is %d bytes, JSON %d", len(encoded), len(message))
+	}
+
+	var got WSMessage
+	if err := MsgPack.Decode(encoded, &got); err != nil {
+		t.Fatal(err)
+	}
+	var want WSMessage
+	JSON.Decode(message, &want)
+	if got.Type != SlapResult || got.Timestamp != want.Timestamp {
+		t.Errorf("decoded %s at %d, want %s at %d", got.Type, got.Timestamp, SlapResult, want.Timestamp)
+	}
+	var fields map[string]interface{}
+	json.Unmarshal(got.Payload, &fields)
+	if fields["playerId"] != "p1" || fields["success"] != true || fields["cardsWon"] != float64(12) {
+		t.Errorf("decoded payload %v", fields)
+	}
+}
+
+func TestMsgPackEncoding(t *testing.T) {
+	for _, tt := range []struct {
+		value interface{}
+		want  []byte
+	}{
+		{nil, []byte{0xc0}},
+		{int64(5), []byte{0x05}},
+		{int64(-3), []byte{0xfd}},
+		{int64(200), []byte{0xd1, 0x00, 0xc8}},
+		{int64(-70000), []byte{0xd2, 0xff, 0xfe, 0xee, 0x90}},
+		{"hi", []byte{0xa2, 'h', 'i'}},
+		{[]interface{}{true, false}, []byte{0x92, 0xc3, 0xc2}},
+		{map[string]interface{}{"a": int64(1)}, []byte{0x81, 0xa1, 'a', 0x01}},
+	} {
+		got, err := appendMsgPack(nil, tt.value)
+		if err != nil || string(got) != string(tt.want) {
+			t.Errorf("appendMsgPack(%v) = % x, %v, want % x", tt.value, got, err, tt.want)
+		}
+		r := msgPackReader{data: got}
+		if v, err := r.value(); err != nil || fmt.Sprint(v) != fmt.Sprint(tt.value) {
+			t.Errorf("decoded % x as %v, %v, want %v", got, v, err, tt.value)
+		}
+	}
+
+	// Lengths past the end of the data are rejected before allocating
+	for _, bad := range [][]byte{{0xdd, 0xff, 0xff, 0xff, 0xff}, {0xa5, 'x'}, {0x81, 0x01, 0x01}} {
+		r := msgPackReader{data: bad}
+		if _, err := r.value(); err == nil {
+			t.Errorf("decoded invalid % x", bad)
+		}
+	}
+}
+
+func BenchmarkMsgPackEncode(b *testing.B) {
+	payload, _ := EncodePayload(CardPlayedPayload{PlayerID: "p1", Card: Card{Suit: "hearts", Rank: "J"}, PileCount: 14})
+	message := EncodeMessage(CardPlayed, payload)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		MsgPack.Encode(message)
+	}
+}
+
+func TestDecodePayload(t *testing.T) {
+	for _, tt := range []struct {
+		payload string
+		field   string // Empty if the error names no field
+		ok      bool
+	}{
+		{payload: `{"roomCode":"ABCD","playerName":"Alex"}`, ok: true},
+		{payload: `null`, ok: true},
+		{payload: ``, ok: true},
+		{payload: `{"roomCode":"ABCD","nickname":"Alex"}`, field: "nickname"},
+		{payload: `{"roomCode":1234}`, field: "roomCode"},
+		{payload: `{"spectate":"yes"}`, field: "spectate"},
+		{payload: `"ABCD"`},
+		{payload: `{"roomCode":"ABCD"`},
+		{payload: `{"roomCode":"ABCD"} {}`},
+	} {
+		var join JoinRoomPayload
+		err := DecodePayload(json.RawMessage(tt.payload), &join)
+		if tt.ok {
+			if err != nil {
+				t.Errorf("%s: %v", tt.payload, err)
+			}
+			continue
+		}
+		var payloadErr *PayloadError
+		if !errors.As(err, &payloadErr) {
+			t.Errorf("%s: got %v, want a PayloadError", tt.payload, err)
+			continue
+		}
+		if payloadErr.Field != tt.field {
+			t.Errorf("%s: error names field %q, want %q (%v)", tt.payload, payloadErr.Field, tt.field, err)
+		}
+	}
+
+	// Nested fields are named by their path
+	var settings struct {
+		Limits struct {
+			Max int `json:"max"`
+		} `json:"limits"`
+	}
+	err := DecodePayload(json.RawMessage(`{"limits":{"max":"ten"}}`), &settings)
+	var payloadErr *PayloadError
+	if !errors.As(err, &payloadErr) || payloadErr.Field != "limits.max" {
+		t.Errorf("nested type error = %v, want one naming limits.max", err)
+	}
+}
+
+func TestWithPile(t *testing.T) {
+	message := []byte(`{"type":"CARD_PLAYED","payload":{}}`)
+	if got := string(WithPile(2, message)); got != `{"pile":2,"type":"CARD_PLAYED","payload":{}}` {
+		t.Errorf("marked message %s", got)
+	}
+	if got := WithPile(0, message); string(got) != string(message) {
+		t.Errorf("pile 0 changed the message to %s", got)
+	}
+}
@@ -0,0 +1,81 @@
+package protocol
+
+// ErrorCode is a machine-readable error identifier sent in ERROR messages
+type ErrorCode string
+
+// Error codes
+const (
+	CodeParseError        ErrorCode = "PARSE_ERROR"
+	CodeUnknownMessage    ErrorCode = "UNKNOWN_MESSAGE"
+	CodeInvalidPayload    ErrorCode = "INVALID_PAYLOAD"
+	CodeInvalidName       ErrorCode = "INVALID_NAME"
+	CodeNameTaken         ErrorCode = "NAME_TAKEN"
+	CodeInvalidAvatar     ErrorCode = "INVALID_AVATAR"
+	CodeProfileNotFound   ErrorCode = "PROFILE_NOT_FOUND"
+	CodeInvalidCode       ErrorCode = "INVALID_CODE"
+	CodeCreateFailed      ErrorCode = "CREATE_FAILED"
+	CodeCreateCooldown    ErrorCode = "CREATE_COOLDOWN"
+	CodeRoomLimit         ErrorCode = "ROOM_LIMIT"
+	CodeJoinFailed        ErrorCode = "JOIN_FAILED"
+	CodeInvalidPreset     ErrorCode = "INVALID_PRESET"
+	CodeInvalidVariant    ErrorCode = "INVALID_VARIANT"
+	CodeInvalidDeck       ErrorCode = "INVALID_DECK"
+	CodeUnknownItem       ErrorCode = "UNKNOWN_ITEM"
+	CodeItemNotOwned      ErrorCode = "ITEM_NOT_OWNED"
+	CodeAlreadyOwned      ErrorCode = "ALREADY_OWNED"
+	CodeNotEnoughCoins    ErrorCode = "NOT_ENOUGH_COINS"
+	CodeWalletFailed      ErrorCode = "WALLET_FAILED"
+	CodeNotInRoom         ErrorCode = "NOT_IN_ROOM"
+	CodeRoomNotFound      ErrorCode = "ROOM_NOT_FOUND"
+	CodeNotHost           ErrorCode = "NOT_HOST"
+	CodeNotAPlayer        ErrorCode = "NOT_A_PLAYER"
+	CodePlayerNotFound    ErrorCode = "PLAYER_NOT_FOUND"
+	CodeInvalidKick       ErrorCode = "INVALID_KICK"
+	CodeInvalidTransfer   ErrorCode = "INVALID_TRANSFER"
+	CodeGameInProgress    ErrorCode = "GAME_IN_PROGRESS"
+	CodeNotEnoughPlayers  ErrorCode = "NOT_ENOUGH_PLAYERS"
+	CodeNotStarting       ErrorCode = "NOT_STARTING"
+	CodeNoGame            ErrorCode = "NO_GAME"
+	CodeGameNotOver       ErrorCode = "GAME_NOT_OVER"
+	CodePlayFailed        ErrorCode = "PLAY_FAILED"
+	CodeReplayNotFound    ErrorCode = "REPLAY_NOT_FOUND"
+	CodeRateLimited       ErrorCode = "RATE_LIMITED"
+	CodeFeatureDisabled   ErrorCode = "FEATURE_DISABLED"
+	CodeInvalidTelemetry  ErrorCode = "INVALID_TELEMETRY"
+	CodeRTCDisabled       ErrorCode = "RTC_DISABLED"
+	CodeInvalidSignal     ErrorCode = "INVALID_SIGNAL"
+	CodeCalibrationFailed ErrorCode = "CALIBRATION_FAILED"
+	CodeSlowMode          ErrorCode = "SLOW_MODE"
+	CodeBanned            ErrorCode = "BANNED"
+	CodeIdleTimeout       ErrorCode = "IDLE_TIMEOUT"
+	CodeInvalidTeam       ErrorCode = "INVALID_TEAM"
+	CodeInvalidVote       ErrorCode = "INVALID_VOTE"
+	CodeVoteCooldown      ErrorCode = "VOTE_COOLDOWN"
+	CodeInviteOnly        ErrorCode = "INVITE_ONLY"
+	CodeSpectatorsFull    ErrorCode = "SPECTATORS_FULL"
+	CodeInvalidInvite     ErrorCode = "INVALID_INVITE"
+	CodeInvalidWebhook    ErrorCode = "INVALID_WEBHOOK"
+	CodeInvalidSchedule   ErrorCode = "INVALID_SCHEDULE"
+)
+
+// NewError creates an ERROR message
+func NewError(code ErrorCode, message string) WSMessage {
+	return NewMessage(Error, ErrorPayload{
+		Code:    code,
+		Message: message,
+	})
+}
+
+// NewErrorWithDetails creates an ERROR message with machine-readable details
+func NewErrorWithDetails(code ErrorCode, message string, details map[string]string) WSMessage {
+	return NewMessage(Error, ErrorPayload{
+		Code:    code,
+		Message: message,
+		Details: details,
+	})
+}
+
+// NewFieldError creates an ERROR message naming the payload field that failed validation
+func NewFieldError(code ErrorCode, field, message string) WSMessage {
+	return NewErrorWithDetails(code, message, map[string]string{"field": field})
+}
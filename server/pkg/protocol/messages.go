@@ -1,6 +1,35 @@
 package protocol
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+// Protocol versions spoken by this server
+const (
+	ProtocolVersion    = 2
+	MinProtocolVersion = 1
+)
+
+// Optional features a client may declare in CLIENT_HELLO
+const (
+	FeatureReplay         = "replay"
+	FeatureRatscrew       = "ratscrew"
+	FeatureSlapVariants   = "slap_variants"
+	FeatureReconnectGrace = "reconnect_grace"
+	FeatureRTC            = "rtc"      // Voice and video signaling relay
+	FeatureWebhooks       = "webhooks" // Discord notifications of a room's games
+)
+
+// ServerFeatures lists every optional feature this server supports
+var ServerFeatures = []string{
+	FeatureReplay,
+	FeatureRatscrew,
+	FeatureSlapVariants,
+	FeatureReconnectGrace,
+	FeatureRTC,
+	FeatureWebhooks,
+}
 
 // Message types for client -> server
 const (
@@ -14,72 +43,341 @@ const (
 	Slap           = "SLAP"
 	React          = "REACT"
 	KickPlayer     = "KICK_PLAYER"
+	TransferHost   = "TRANSFER_HOST"
+	RequestRematch = "REQUEST_REMATCH"
+	Subscribe      = "SUBSCRIBE"
+	Unsubscribe    = "UNSUBSCRIBE"
 	EndGame        = "END_GAME"
+	RequestReplay  = "REQUEST_REPLAY"
+	Resync         = "RESYNC"
+	CancelStart    = "CANCEL_START"
+	ClientHello    = "CLIENT_HELLO"
+
+	RegisterProfile = "REGISTER_PROFILE"
+	PlayNow         = "PLAY_NOW"
+	GetWallet       = "GET_WALLET"
+	BuyItem         = "BUY_ITEM"
+	EquipItem       = "EQUIP_ITEM"
+	ReportTelemetry = "REPORT_TELEMETRY"
+
+	// Sent to relay WebRTC signaling to another player, and received with the
+	// sender filled in
+	RTCSignal = "RTC_SIGNAL"
+
+	// Input latency calibration: CALIBRATE_START begins a run of flashes, and
+	// the client sends CALIBRATE_TAP in time with each
+	CalibrateStart = "CALIBRATE_START"
+	CalibrateTap   = "CALIBRATE_TAP"
+
+	// Answers the READY_CHECK sent when the host starts a game
+	Ready = "READY"
+
+	// Moves a player to a team in a team game: any player themselves, or the
+	// host anyone
+	SetTeam = "SET_TEAM"
+
+	// Starts a vote to kick a player, or votes on the one running; any
+	// player but the host may vote
+	VoteKick = "VOTE_KICK"
+
+	// Acknowledges the room broadcasts received so far, so a reconnect
+	// replays only the ones after it
+	Ack = "ACK"
+
+	// Registers or removes the room's webhook; host only
+	SetIntegration = "SET_INTEGRATION"
+)
+
+// Integrations a host can register with SET_INTEGRATION
+const (
+	IntegrationDiscord = "discord" // Posts game starts, wins and party results to a Discord webhook
+)
+
+// Rule presets of the drop-in rooms joined with PLAY_NOW
+const (
+	PresetClassic  = "classic"
+	PresetHouse    = "house"    // Every house rule slap variant
+	PresetRatscrew = "ratscrew" // Egyptian Ratscrew face-card challenges
+)
+
+// Settings presets a host can apply with UPDATE_SETTINGS
+const (
+	SettingsPresetClassic = "classic"
+	SettingsPresetSpeed   = "speed" // Short turns, quick slaps and costly mistakes
+	SettingsPresetParty   = "party" // Every house rule and relaxed timing
+)
+
+// Room visibility: who sees a room in the lobby and who can join it by code
+const (
+	VisibilityPublic   = "public"   // Listed, anyone with the code can join
+	VisibilityUnlisted = "unlisted" // Not listed, anyone with the code can join
+	VisibilityPrivate  = "private"  // Not listed, only invited players and those already in it can join
+)
+
+// Rule variants a room can be created with, listed at /api/variants
+const (
+	VariantClassic = "classic"
+	VariantERS     = "ers"    // Egyptian Ratscrew
+	VariantBeggar  = "beggar" // Beggar-my-neighbour challenges with jack slaps
 )
 
 // Message types for server -> client
 const (
-	RoomCreated       = "ROOM_CREATED"
-	RoomJoined        = "ROOM_JOINED"
-	RoomUpdated       = "ROOM_UPDATED"
-	PlayerJoined      = "PLAYER_JOINED"
-	PlayerLeft        = "PLAYER_LEFT"
-	PlayerKicked      = "PLAYER_KICKED"
-	NameChanged       = "NAME_CHANGED"
-	SettingsChanged   = "SETTINGS_CHANGED"
-	GameStarting      = "GAME_STARTING"
-	GameStarted       = "GAME_STARTED"
-	CardsDealt        = "CARDS_DEALT"
-	CardPlayed        = "CARD_PLAYED"
-	TurnChanged       = "TURN_CHANGED"
-	SlapAttempted     = "SLAP_ATTEMPTED"
-	SlapResult        = "SLAP_RESULT"
-	PlayerEliminated  = "PLAYER_ELIMINATED"
-	GameOver          = "GAME_OVER"
-	GameEnded         = "GAME_ENDED"
-	Error             = "ERROR"
-	Connected         = "CONNECTED"
-	Reconnected       = "RECONNECTED"
-	PlayerReconnected = "PLAYER_RECONNECTED"
-	TurnWarning       = "TURN_WARNING"
+	RoomCreated        = "ROOM_CREATED"
+	RoomJoined         = "ROOM_JOINED"
+	RoomUpdated        = "ROOM_UPDATED"
+	PlayerJoined       = "PLAYER_JOINED"
+	PlayerLeft         = "PLAYER_LEFT"
+	PlayerKicked       = "PLAYER_KICKED"
+	NameChanged        = "NAME_CHANGED"
+	SettingsChanged    = "SETTINGS_CHANGED"
+	GameStarting       = "GAME_STARTING"
+	GameStarted        = "GAME_STARTED"
+	CardsDealt         = "CARDS_DEALT"
+	CardPlayed         = "CARD_PLAYED"
+	TurnChanged        = "TURN_CHANGED"
+	SlapAttempted      = "SLAP_ATTEMPTED"
+	SlapResult         = "SLAP_RESULT"
+	PlayerEliminated   = "PLAYER_ELIMINATED"
+	PlayerAFK          = "PLAYER_AFK"
+	GameOver           = "GAME_OVER"
+	GameEnded          = "GAME_ENDED"
+	Error              = "ERROR"
+	Connected          = "CONNECTED"
+	Reconnected        = "RECONNECTED"
+	PlayerReconnected  = "PLAYER_RECONNECTED"
+	TurnWarning        = "TURN_WARNING"
+	PlayerDisconnected = "PLAYER_DISCONNECTED"
+	HostMigrated       = "HOST_MIGRATED"
+	RoomClosed         = "ROOM_CLOSED"
+	RoomExpired        = "ROOM_EXPIRED"
+	ReplayEventMsg     = "REPLAY_EVENT"
+	ReplayComplete     = "REPLAY_COMPLETE"
+	ChallengeStarted   = "CHALLENGE_STARTED"
+	ChallengeWon       = "CHALLENGE_WON"
+	ServerHello        = "SERVER_HELLO"
+	ProtocolMismatch   = "PROTOCOL_MISMATCH"
+	ServerShutdown     = "SERVER_SHUTDOWN"
+	SessionInvalid     = "SESSION_INVALID"
+	SuspicionFlagged   = "SUSPICION_FLAGGED"
+	CheatWarningMsg    = "CHEAT_WARNING"
+	RematchVote        = "REMATCH_VOTE"
+	RematchStarting    = "REMATCH_STARTING"
+	Subscribed         = "SUBSCRIBED"
+	ResyncState        = "RESYNC_STATE"
+	HighlightMsg       = "HIGHLIGHT"
+	StartCancelled     = "START_CANCELLED"
+	ConfigReloaded     = "CONFIG_RELOADED"
+	ProfileRegistered  = "PROFILE_REGISTERED"
+	WalletUpdated      = "WALLET_UPDATED"
+	CardsBurned        = "CARDS_BURNED"
+	CalibrateFlash     = "CALIBRATE_FLASH"
+	CalibrateResult    = "CALIBRATE_RESULT"
+	CardCountsUpdated  = "CARD_COUNTS_UPDATED"
+	ReadyCheck         = "READY_CHECK"
+	PlayerReady        = "PLAYER_READY"
+	TeamsChanged       = "TEAMS_CHANGED"
+	GameClock          = "GAME_CLOCK"
+	SlapReplay         = "SLAP_REPLAY"
+	PileDiscarded      = "PILE_DISCARDED"
+	CardsRedistributed = "CARDS_REDISTRIBUTED"
+	Announce           = "ANNOUNCE"
+	VoteStarted        = "VOTE_STARTED"
+	VoteResult         = "VOTE_RESULT"
+	Resumed            = "RESUMED"
+	InputAck           = "INPUT_ACK"
+	PartyStarted       = "PARTY_STARTED"
+	PileWon            = "PILE_WON"
+	FinaleStarting     = "FINALE_STARTING"
+	ScheduleReminder   = "SCHEDULE_REMINDER"
 )
 
 // WSMessage is the base message structure for all WebSocket communication
+// Payload is kept raw so each handler can decode it strictly into its own type
 type WSMessage struct {
-	Type      string      `json:"type"`
-	Payload   interface{} `json:"payload"`
-	Timestamp int64       `json:"timestamp"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	Timestamp int64           `json:"timestamp"`
+	Seq       int64           `json:"seq,omitempty"` // Per-room sequence number, set on room broadcasts
+
+	// Increasing number a client puts on its actions, kept across
+	// reconnects, so one it resends is applied once
+	InputSeq int64 `json:"inputSeq,omitempty"`
+
+	// Which pile of a party room a game message is about, set on the
+	// messages of each pile's game
+	Pile int `json:"pile,omitempty"`
 }
 
 // NewMessage creates a new WebSocket message with current timestamp
 func NewMessage(msgType string, payload interface{}) WSMessage {
+	encoded, err := EncodePayload(payload)
+	if err != nil {
+		encoded = []byte("null")
+	}
 	return WSMessage{
 		Type:      msgType,
-		Payload:   payload,
+		Payload:   encoded,
 		Timestamp: time.Now().UnixMilli(),
 	}
 }
 
 // Client -> Server Payloads
 
+// CreateRoomPayload and JoinRoomPayload take the avatar, and the name if
+// PlayerName is empty, from the profile when ProfileToken is set
 type CreateRoomPayload struct {
-	PlayerName string `json:"playerName"`
+	PlayerName   string `json:"playerName"`
+	ProfileToken string `json:"profileToken,omitempty"`
+
+	// Rule variant to start the room's settings from; the defaults if empty
+	VariantID string `json:"variantId,omitempty"`
+
+	// When the game should start by itself, in Unix milliseconds; unscheduled if 0
+	ScheduledAt int64 `json:"scheduledAt,omitempty"`
 }
 
 type JoinRoomPayload struct {
-	RoomCode   string `json:"roomCode"`
-	PlayerName string `json:"playerName"`
+	RoomCode     string `json:"roomCode"`
+	PlayerName   string `json:"playerName"`
+	ProfileToken string `json:"profileToken,omitempty"`
+	Spectate     bool   `json:"spectate,omitempty"`
+	Invite       string `json:"invite,omitempty"` // Invite token, to get into a private room
+}
+
+// PlayNowPayload joins the fullest open drop-in room for a preset, classic if
+// empty, with the same name and profile handling as JOIN_ROOM
+type PlayNowPayload struct {
+	Preset       string `json:"preset,omitempty"`
+	PlayerName   string `json:"playerName"`
+	ProfileToken string `json:"profileToken,omitempty"`
+}
+
+// ItemPayload names a cosmetic item for BUY_ITEM and EQUIP_ITEM
+type ItemPayload struct {
+	ItemID string `json:"itemId"`
+}
+
+// ReportTelemetryPayload is optional timing a client measured itself, in milliseconds
+type ReportTelemetryPayload struct {
+	RenderLatencyMs int64  `json:"renderLatencyMs"`  // From a message arriving to it being on screen
+	InputLagMs      int64  `json:"inputLagMs"`       // From a key press or tap to the message being sent
+	RTTMs           int64  `json:"rttMs,omitempty"`  // The client's own round-trip estimate
+	Device          string `json:"device,omitempty"` // Free-form device class, such as "mobile"
+}
+
+// CalibrateStartPayload begins input latency calibration
+type CalibrateStartPayload struct {
+	Device string `json:"device,omitempty"` // Free-form device class, such as "mobile"
+}
+
+// WebRTC signal kinds
+const (
+	RTCOffer     = "offer"
+	RTCAnswer    = "answer"
+	RTCCandidate = "candidate"
+	RTCHangup    = "hangup"
+)
+
+// RTCSignalPayload carries WebRTC signaling between two players in a room.
+// The server relays Data untouched; it never sees any media.
+type RTCSignalPayload struct {
+	TargetID string          `json:"targetId,omitempty"` // Set by the sender
+	FromID   string          `json:"fromId,omitempty"`   // Set by the server when relaying
+	Kind     string          `json:"kind"`               // offer, answer, candidate, hangup
+	Data     json.RawMessage `json:"data,omitempty"`     // SDP or ICE candidate
+}
+
+// RegisterProfilePayload creates a profile, or updates the one ProfileToken refers to
+type RegisterProfilePayload struct {
+	ProfileToken string `json:"profileToken,omitempty"`
+	Name         string `json:"name"`
+	Avatar       Avatar `json:"avatar"`
 }
 
 type UpdateSettingsPayload struct {
-	MaxPlayers      int  `json:"maxPlayers"`
-	SlapCooldownMs  int  `json:"slapCooldownMs"`
-	TurnTimeoutMs   int  `json:"turnTimeoutMs"`
-	EnableSandwich  bool `json:"enableSandwich"`
-	EnableDoubles   bool `json:"enableDoubles"`
-	BurnPenalty     int  `json:"burnPenalty"`
-	EnableSlapIn    bool `json:"enableSlapIn"`
-	MaxSlapIns      int  `json:"maxSlapIns"`
+	MaxPlayers      int    `json:"maxPlayers"`
+	SlapCooldownMs  int    `json:"slapCooldownMs"`
+	TurnTimeoutMs   int    `json:"turnTimeoutMs"`
+	AFKStrikes      int    `json:"afkStrikes"` // Timeouts in a row before a player is out; 0 for never
+	EnableSandwich  bool   `json:"enableSandwich"`
+	EnableDoubles   bool   `json:"enableDoubles"`
+	BurnPenalty     int    `json:"burnPenalty"`
+	EnableSlapIn    bool   `json:"enableSlapIn"`
+	MaxSlapIns      int    `json:"maxSlapIns"`
+	EnableMarriage  bool   `json:"enableMarriage"`
+	EnableTopBottom bool   `json:"enableTopBottom"`
+	EnableRuns      bool   `json:"enableRuns"`
+	EnableTens      bool   `json:"enableTens"`
+	GameMode        string `json:"gameMode"`        // classic, ratscrew
+	BurnDestination string `json:"burnDestination"` // pile, discard, winner
+	EscalatePenalty bool   `json:"escalatePenalty"`
+	DealRemainder   string `json:"dealRemainder"` // deal, pile, discard
+	PileCap         int    `json:"pileCap"`       // Unwon pile size at which the next play discards it; 0 for off
+	RematchQuorum   string `json:"rematchQuorum"` // all, majority
+	NumDecks        int    `json:"numDecks"`
+	ClassroomMode   bool   `json:"classroomMode"`
+	ShowFullPile    bool   `json:"showFullPile"`    // Full pile and recent plays for everyone, not just spectators
+	DuplicateNames  string `json:"duplicateNames"`  // reject, suffix
+	EnableRTC       bool   `json:"enableRtc"`       // Relay voice and video signaling between players
+	SlowModeSeconds int    `json:"slowModeSeconds"` // Least time between one player's reactions; 0 for off
+
+	// Adaptive pacing: turn timeouts shorten as the pile grows and lengthen after it's claimed
+	AdaptivePacing     bool `json:"adaptivePacing"`
+	PacingMinTimeoutMs int  `json:"pacingMinTimeoutMs"` // Shortest turn timeout
+	PacingRampCards    int  `json:"pacingRampCards"`    // Pile size at which the shortest timeout is reached
+	PacingClaimBonusMs int  `json:"pacingClaimBonusMs"` // Extra time on the turn after a pile is claimed
+
+	// Auto pacing: turn timeouts shorten as the game goes on, every few turns and for every player out
+	AutoPace          bool `json:"autoPace"`
+	AutoPaceStepTurns int  `json:"autoPaceStepTurns"` // Cards played per step down
+	AutoPaceFloorMs   int  `json:"autoPaceFloorMs"`   // Shortest turn timeout
+
+	CountdownSeconds  int  `json:"countdownSeconds"`  // Countdown before a game starts
+	ReadyCheck        bool `json:"readyCheck"`        // Players confirm they're ready when the host starts
+	ReadyCheckSeconds int  `json:"readyCheckSeconds"` // How long they have to
+
+	// Play in two teams that alternate turns and are only out once every
+	// teammate is; players are assigned with SET_TEAM
+	Teams bool `json:"teams"`
+
+	// Let players vote the host out with VOTE_KICK, not just each other
+	VoteKickHost bool `json:"voteKickHost"`
+
+	// What happens to a disconnected player's turns until they reconnect or
+	// their seat is given up: skip passes over them, autoplay plays their
+	// top card for them. Their hand is kept either way.
+	DisconnectedTurns string `json:"disconnectedTurns"`
+
+	// Where the hand of a player who leaves mid-game goes: deal shares it
+	// out among the players holding cards, pile puts it under the pile,
+	// discard takes it out of play
+	LeaverCards string `json:"leaverCards"`
+
+	// Who can find and join the room, see VisibilityPublic, and the most
+	// spectators it takes at once, 0 for no limit
+	Visibility    string `json:"visibility"`
+	MaxSpectators int    `json:"maxSpectators"`
+
+	// How the game is won: last_standing, piles (first to WinPiles piles
+	// won) or timed (most cards when TimeLimitSeconds run out); last player
+	// standing still wins the other two outright
+	WinCondition     string `json:"winCondition"`
+	WinPiles         int    `json:"winPiles"`
+	TimeLimitSeconds int    `json:"timeLimitSeconds"`
+
+	// Custom deck composition, for each of the NumDecks decks
+	Deck DeckSpec `json:"deck"`
+
+	// Settings preset: in UPDATE_SETTINGS, one to apply over the other
+	// fields; in the room's settings, the one they match, if any
+	Preset string `json:"preset,omitempty"`
+}
+
+// PlayCardPayload echoes the turn token from TURN_CHANGED, so a repeated
+// PLAY_CARD is only played once
+type PlayCardPayload struct {
+	TurnToken int64 `json:"turnToken,omitempty"`
 }
 
 type SlapPayload struct {
@@ -90,16 +388,115 @@ type ReactPayload struct {
 	Emoji string `json:"emoji"`
 }
 
+// WalletUpdatedPayload is a player's coins and cosmetics, sent on request and
+// whenever they change. Earned lists what a finished game paid out.
+type WalletUpdatedPayload struct {
+	Coins    int               `json:"coins"`
+	Owned    []string          `json:"owned"`
+	Equipped map[string]string `json:"equipped"` // Item ID by slot
+	Earned   []CoinCredit      `json:"earned,omitempty"`
+	Catalog  []CosmeticItem    `json:"catalog,omitempty"` // Only in reply to GET_WALLET
+}
+
+// CalibrateFlashPayload is one flash of an input latency calibration run;
+// the client shows it at once and the player taps in time with it
+type CalibrateFlashPayload struct {
+	Round      int   `json:"round"` // From 1
+	Rounds     int   `json:"rounds"`
+	IntervalMs int64 `json:"intervalMs"` // Until the next flash
+}
+
+// CalibrationResultPayload is a device's input latency estimate, from the
+// player's taps less the round trip. Slap reaction times are judged net of it.
+type CalibrationResultPayload struct {
+	InputLagMs   int64  `json:"inputLagMs"`
+	Samples      int    `json:"samples"` // Taps the estimate was taken from
+	Device       string `json:"device,omitempty"`
+	CalibratedAt int64  `json:"calibratedAt"`
+}
+
+// CoinCredit is coins earned for one reason
+type CoinCredit struct {
+	Reason string `json:"reason"` // played, won, or an award key
+	Coins  int    `json:"coins"`
+}
+
+// CosmeticItem is something coins can buy
+type CosmeticItem struct {
+	ID    string `json:"id"`
+	Slot  string `json:"slot"` // card_back, table, avatar_frame
+	Name  string `json:"name"`
+	Price int    `json:"price"`
+}
+
+// ProfileRegisteredPayload returns a registered profile and the token that refers to it
+type ProfileRegisteredPayload struct {
+	Profile      Profile `json:"profile"`
+	ProfileToken string  `json:"profileToken"`
+}
+
 type ChangeNamePayload struct {
 	NewName string `json:"newName"`
 }
 
 type KickPlayerPayload struct {
 	PlayerID string `json:"playerId"`
+
+	// Also bar the player from rejoining, for BanMinutes or until the room closes if 0
+	Ban        bool `json:"ban,omitempty"`
+	BanMinutes int  `json:"banMinutes,omitempty"`
+}
+
+// VoteKickPayload votes to kick a player; a yes with no vote running
+// against them starts one
+type VoteKickPayload struct {
+	PlayerID string `json:"playerId"`
+	Yes      bool   `json:"yes"`
+}
+
+// AckPayload acknowledges every room broadcast up to Seq
+type AckPayload struct {
+	Seq int64 `json:"seq"`
+}
+
+// SetIntegrationPayload registers a room's webhook, or removes it if the URL
+// is empty; the URL stays on the server
+type SetIntegrationPayload struct {
+	Kind string `json:"kind"`
+	URL  string `json:"url"`
+}
+
+type SetTeamPayload struct {
+	PlayerID string `json:"playerId,omitempty"` // Defaults to the sender
+	TeamID   int    `json:"teamId"`
+}
+
+type TransferHostPayload struct {
+	PlayerID string `json:"playerId"`
+}
+
+// SubscribePayload lists message types for SUBSCRIBE and UNSUBSCRIBE
+type SubscribePayload struct {
+	Events []string `json:"events"`
+}
+
+type RequestReplayPayload struct {
+	RoomCode string  `json:"roomCode"`
+	GameID   string  `json:"gameId"`
+	Speed    float64 `json:"speed"` // playback multiplier, 1 = original speed
+}
+
+type ClientHelloPayload struct {
+	ProtocolVersion int      `json:"protocolVersion"`
+	Features        []string `json:"features"`
+	Encodings       []string `json:"encodings,omitempty"` // Wire encodings accepted, preferred first
+	Locale          string   `json:"locale,omitempty"`    // Language for server messages, e.g. "fr-CA"
 }
 
 type GameEndedPayload struct {
-	Reason string `json:"reason"`
+	Reason    string            `json:"reason"`
+	ReasonKey string            `json:"reasonKey,omitempty"` // Message key, for clients translating the reason themselves
+	Params    map[string]string `json:"params,omitempty"`
 }
 
 type PlayerKickedPayload struct {
@@ -110,7 +507,28 @@ type PlayerKickedPayload struct {
 // Server -> Client Payloads
 
 type ConnectedPayload struct {
-	SessionID string `json:"sessionId"`
+	SessionID       string   `json:"sessionId"`
+	SessionToken    string   `json:"sessionToken"` // Required with sessionId to reconnect
+	PlayerToken     string   `json:"playerToken"`
+	ProtocolVersion int      `json:"protocolVersion"`
+	Features        []string `json:"features"`
+}
+
+type SessionInvalidPayload struct {
+	Message string `json:"message"`
+}
+
+type ServerHelloPayload struct {
+	ProtocolVersion int      `json:"protocolVersion"` // negotiated version
+	Features        []string `json:"features"`        // features both sides support
+	Encoding        string   `json:"encoding"`        // used for messages after this one
+}
+
+type ProtocolMismatchPayload struct {
+	ClientVersion      int    `json:"clientVersion"`
+	ServerVersion      int    `json:"serverVersion"`
+	MinProtocolVersion int    `json:"minProtocolVersion"`
+	Message            string `json:"message"`
 }
 
 type RoomCreatedPayload struct {
@@ -130,21 +548,171 @@ type PlayerLeftPayload struct {
 	PlayerID string `json:"playerId"`
 }
 
+type PlayerDisconnectedPayload struct {
+	PlayerID     string `json:"playerId"`
+	GraceSeconds int    `json:"graceSeconds"`
+}
+
+type PlayerReconnectedPayload struct {
+	PlayerID string `json:"playerId"`
+}
+
+type HostMigratedPayload struct {
+	PreviousHostID string `json:"previousHostId"`
+	NewHostID      string `json:"newHostId"`
+}
+
+type RoomClosedPayload struct {
+	Reason string `json:"reason"`
+}
+
+// RoomExpiredPayload is sent before a room is closed for inactivity
+type RoomExpiredPayload struct {
+	Reason      string `json:"reason"`
+	IdleSeconds int    `json:"idleSeconds"`
+}
+
 type NameChangedPayload struct {
 	PlayerID string `json:"playerId"`
 	NewName  string `json:"newName"`
 }
 
+// ResyncStatePayload is the full room and game state as of broadcast Seq
+type ResyncStatePayload struct {
+	Seq       int64             `json:"seq"`
+	Room      RoomState         `json:"room"`
+	GameID    string            `json:"gameId,omitempty"`
+	GameState *GameStatePayload `json:"gameState,omitempty"`
+	Pile      int               `json:"pile,omitempty"` // The party pile GameState is for, if any
+
+	// The player's last numbered action the server applied; the client
+	// resends any later ones it hasn't had an INPUT_ACK for
+	LastInputSeq int64 `json:"lastInputSeq"`
+}
+
+// ResumedPayload comes before the replay of the broadcasts a reconnecting
+// player missed, those after FromSeq up to ToSeq
+type ResumedPayload struct {
+	FromSeq int64 `json:"fromSeq"`
+	ToSeq   int64 `json:"toSeq"`
+	Count   int   `json:"count"`
+
+	// As in ResyncStatePayload
+	LastInputSeq int64 `json:"lastInputSeq"`
+}
+
+// InputAckPayload confirms the action numbered InputSeq was handled
+type InputAckPayload struct {
+	InputSeq int64 `json:"inputSeq"`
+}
+
+// PartyStartedPayload lists the players on each pile of a party room, from
+// pile 1; each pile's GAME_STARTED follows, marked with its pile
+type PartyStartedPayload struct {
+	Piles [][]string `json:"piles"`
+}
+
+// PileWonPayload names the winner of a party pile, who goes on to the finale
+type PileWonPayload struct {
+	Pile       int    `json:"pile"`
+	WinnerID   string `json:"winnerId"`
+	WinnerName string `json:"winnerName"`
+}
+
+// FinaleStartingPayload names the piles' winners playing a party room's
+// finale, an ordinary game that follows
+type FinaleStartingPayload struct {
+	Finalists []string `json:"finalists"`
+}
+
 type GameStartingPayload struct {
 	Countdown int `json:"countdown"`
 }
 
+// Reasons a game start countdown was cancelled
+const (
+	StartCancelledByHost      = "host_cancelled"
+	StartCancelledPlayersLeft = "not_enough_players"
+	StartCancelledStuck       = "stuck"           // The start never finished and the server gave up on it
+	StartCancelledNotReady    = "not_ready"       // Someone didn't answer the ready check in time
+	StartCancelledUnscheduled = "schedule_missed" // Too few players were in at the scheduled start
+)
+
+// ScheduleReminderPayload counts down to a scheduled game, ahead of the
+// GAME_STARTING countdown that begins just before it
+type ScheduleReminderPayload struct {
+	StartsAt    int64 `json:"startsAt"` // Unix milliseconds
+	SecondsLeft int   `json:"secondsLeft"`
+}
+
+type StartCancelledPayload struct {
+	Reason    string   `json:"reason"`
+	PlayerIDs []string `json:"playerIds,omitempty"` // Those who weren't ready, for not_ready
+}
+
+// VoteStartedPayload is a kick vote opening; it passes once Needed of the
+// listed voters say yes, and fails when that can no longer happen or it
+// runs out of time
+type VoteStartedPayload struct {
+	TargetID       string   `json:"targetId"`
+	TargetName     string   `json:"targetName"`
+	StartedBy      string   `json:"startedBy"`
+	Voters         []string `json:"voters"`
+	Needed         int      `json:"needed"`
+	TimeoutSeconds int      `json:"timeoutSeconds"`
+}
+
+// Kick vote results
+const (
+	VoteResultPassed    = "passed"
+	VoteResultFailed    = "failed"    // Too many voted no
+	VoteResultExpired   = "expired"   // Time ran out first
+	VoteResultCancelled = "cancelled" // The player left before it was decided
+)
+
+// VoteResultPayload is a kick vote's outcome; a passed vote is followed by
+// the player's PLAYER_KICKED
+type VoteResultPayload struct {
+	TargetID string `json:"targetId"`
+	Result   string `json:"result"`
+	Yes      int    `json:"yes"`
+	No       int    `json:"no"`
+	Needed   int    `json:"needed"`
+}
+
+// ReadyCheckPayload asks the listed players to send READY before the countdown starts
+type ReadyCheckPayload struct {
+	PlayerIDs      []string `json:"playerIds"`
+	TimeoutSeconds int      `json:"timeoutSeconds"`
+}
+
+// PlayerReadyPayload is a player answering the ready check
+type PlayerReadyPayload struct {
+	PlayerID string `json:"playerId"`
+	Ready    int    `json:"ready"`
+	Needed   int    `json:"needed"`
+}
+
+// TeamsChangedPayload gives every player's team, or none when teams are off
+type TeamsChangedPayload struct {
+	Teams map[string]int `json:"teams"`
+}
+
+// ConfigReloadedPayload names the server settings a reload changed, without their values
+type ConfigReloadedPayload struct {
+	Changed  []string `json:"changed"`
+	Features []string `json:"features"`
+}
+
 type GameStartedPayload struct {
+	GameID    string           `json:"gameId"`
 	GameState GameStatePayload `json:"gameState"`
 }
 
 type CardsDealtPayload struct {
-	PlayerCards map[string]int `json:"playerCards"`
+	PlayerCards   map[string]int `json:"playerCards"`
+	StartingPile  int            `json:"startingPile"`            // Remainder cards placed face-down on the pile
+	DiscardedDeal int            `json:"discardedDeal,omitempty"` // Remainder cards removed from play
 }
 
 type CardPlayedPayload struct {
@@ -155,12 +723,31 @@ type CardPlayedPayload struct {
 
 type TurnChangedPayload struct {
 	CurrentPlayerID string `json:"currentPlayerId"`
+	TurnToken       int64  `json:"turnToken"`
+	TurnTimeoutMs   int64  `json:"turnTimeoutMs"` // Effective timeout for this turn, shorter as the pile grows under adaptive pacing
 }
 
 type TurnWarningPayload struct {
 	SecondsRemaining int `json:"secondsRemaining"`
 }
 
+// GameClockPayload ticks down a timed game, once a second
+type GameClockPayload struct {
+	RemainingMs int64 `json:"remainingMs"`
+	LimitMs     int64 `json:"limitMs"`
+}
+
+type ChallengeStartedPayload struct {
+	OwnerID      string `json:"ownerId"`
+	ChallengerID string `json:"challengerId"`
+	Chances      int    `json:"chances"`
+}
+
+type ChallengeWonPayload struct {
+	PlayerID string `json:"playerId"`
+	CardsWon int    `json:"cardsWon"`
+}
+
 type SlapAttemptedPayload struct {
 	PlayerID   string `json:"playerId"`
 	PlayerName string `json:"playerName"`
@@ -169,35 +756,319 @@ type SlapAttemptedPayload struct {
 type SlapResultPayload struct {
 	PlayerID    string `json:"playerId"`
 	Success     bool   `json:"success"`
-	Reason      string `json:"reason"` // "jack", "doubles", "sandwich", "invalid"
+	Reason      string `json:"reason"` // "jack", "doubles", "sandwich", "marriage", "top_bottom", "run", "tens", "invalid", "cooldown", "eliminated", "beaten"
 	CardsWon    int    `json:"cardsWon,omitempty"`
 	BurnPenalty int    `json:"burnPenalty,omitempty"`
+
+	// Consecutive false slaps by this player when escalating penalties are on
+	EscalationLevel int `json:"escalationLevel,omitempty"`
+
+	// Once the card has been won, the winner and everyone who slapped it
+	// after them, in arrival order
+	Contenders []SlapContender `json:"contenders,omitempty"`
+}
+
+// SlapContender is a player who went for a card someone else may have won
+type SlapContender struct {
+	PlayerID string `json:"playerId"`
+	DeltaMs  int64  `json:"deltaMs"` // Behind the winning slap
+}
+
+// SlapReplayPayload follows a winning slap's SLAP_RESULT so clients can
+// replay it in slow motion: the last plays onto the pile and every slap on
+// its top card up to the winning one
+// Slaps landing after the winner are in their own SLAP_RESULT's contenders
+type SlapReplayPayload struct {
+	WinnerID string       `json:"winnerId"`
+	Reason   string       `json:"reason"`
+	Plays    []PilePlay   `json:"plays"` // Oldest first, the top card last
+	Slaps    []ReplaySlap `json:"slaps"` // In arrival order
+}
+
+// ReplaySlap is one slap in a slap replay
+type ReplaySlap struct {
+	PlayerID   string `json:"playerId"`
+	Timestamp  int64  `json:"timestamp"`  // When the server received it
+	ReactionMs int64  `json:"reactionMs"` // After the top card landed, less latency and input lag
+	Won        bool   `json:"won,omitempty"`
+}
+
+// CardsBurnedPayload shows the cards a false slap cost and where they went
+type CardsBurnedPayload struct {
+	PlayerID    string `json:"playerId"`
+	Cards       []Card `json:"cards"`
+	Destination string `json:"destination"`           // pile, discard, winner
+	RecipientID string `json:"recipientId,omitempty"` // The pile winner given them, for winner
+	PileCount   int    `json:"pileCount"`
+}
+
+// CardsRedistributedPayload is the hand of a player who left mid-game being
+// dealt to the players holding cards, put under the pile or taken out of play
+type CardsRedistributedPayload struct {
+	PlayerID    string         `json:"playerId"`
+	Destination string         `json:"destination"` // deal, pile, discard
+	Count       int            `json:"count"`
+	Dealt       map[string]int `json:"dealt,omitempty"` // Cards each player was given, for deal
+	PlayerCards map[string]int `json:"playerCards"`     // Card counts afterwards
+	PileCount   int            `json:"pileCount"`
+}
+
+// PileDiscardedPayload is an unwon pile at the room's pile cap being removed
+// from play, just before the player's CARD_PLAYED starts a new one
+type PileDiscardedPayload struct {
+	PlayerID string `json:"playerId"`
+	Count    int    `json:"count"`
+}
+
+// Announcer calls
+const (
+	AnnounceBigPile    = "BIG_PILE"         // The pile has grown big; Count is its size
+	AnnounceComeback   = "COMEBACK"         // A player nearly out won a big pile; Count is its size
+	AnnounceLastTwo    = "LAST_TWO_PLAYERS" // Only two players are left standing
+	AnnounceFirstBlood = "FIRST_BLOOD"      // The game's first pile was won; Count is its size
+)
+
+// AnnouncePayload is an announcer call, worked out by the server so every
+// client plays the same sound or banner for it
+type AnnouncePayload struct {
+	Kind     string `json:"kind"`
+	PlayerID string `json:"playerId,omitempty"`
+	Count    int    `json:"count,omitempty"`
+}
+
+// CardCountsUpdatedPayload is every player's card count after a slap or
+// penalty moved cards, so clients needn't work them out
+type CardCountsUpdatedPayload struct {
+	PlayerCardCounts map[string]int `json:"playerCardCounts"`
+	PileCount        int            `json:"pileCount"`
 }
 
 type PlayerEliminatedPayload struct {
 	PlayerID string `json:"playerId"`
 }
 
+// PlayerAFKPayload is a player struck out for letting their turn time out
+// too many times in a row; their hand went under the pile
+type PlayerAFKPayload struct {
+	PlayerID       string `json:"playerId"`
+	Strikes        int    `json:"strikes"`
+	CardsForfeited int    `json:"cardsForfeited"`
+	PileCount      int    `json:"pileCount"`
+}
+
 type GameOverPayload struct {
-	WinnerID   string    `json:"winnerId"`
-	WinnerName string    `json:"winnerName"`
-	Stats      GameStats `json:"stats"`
+	GameID      string        `json:"gameId"`
+	WinnerID    string        `json:"winnerId"`
+	WinnerName  string        `json:"winnerName"`
+	WinningTeam int           `json:"winningTeam,omitempty"` // In a team game; the winner is its player with the most cards
+	WinReason   string        `json:"winReason"`             // The win condition that decided the game, such as piles
+	Stats       GameStats     `json:"stats"`
+	Awards      []Award       `json:"awards"`
+	Result      *SignedResult `json:"result,omitempty"` // Unset if the result couldn't be signed
+}
+
+// GameResult is the authoritative outcome of a won game
+type GameResult struct {
+	GameID     string         `json:"gameId"`
+	RoomCode   string         `json:"roomCode"`
+	WinnerID   string         `json:"winnerId"`
+	WinnerName string         `json:"winnerName"`
+	Players    []ResultPlayer `json:"players"` // In turn order
+	StartedAt  int64          `json:"startedAt"`
+	FinishedAt int64          `json:"finishedAt"`
+	Events     int            `json:"events"` // Replay events recorded, tying the result to its replay
+}
+
+// ResultPlayer is a player in a game result
+type ResultPlayer struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	TeamID int    `json:"teamId,omitempty"`
+}
+
+// SignedResult is a game result signed by the server, so others can check it
+// wasn't made up. Payload is the result's JSON and Signature its Ed25519
+// signature, both base64url encoded without padding; the signature is over
+// the payload's decoded bytes, by the key published at /api/verify.
+type SignedResult struct {
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+	KeyID     string `json:"keyId"`
+}
+
+// VerificationKey is the public key game results are signed with
+type VerificationKey struct {
+	Algorithm string `json:"alg"` // Always "Ed25519"
+	KeyID     string `json:"keyId"`
+	PublicKey string `json:"publicKey"` // base64url, unpadded
+}
+
+// Invite is a single-use invite into a room, from POST /api/rooms/{code}/invites
+type Invite struct {
+	RoomCode  string `json:"roomCode"`
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expiresAt"` // Unix milliseconds
+}
+
+// VerifyResponse reports whether a signed result is genuine, with the result if so
+type VerifyResponse struct {
+	Valid  bool        `json:"valid"`
+	Result *GameResult `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// Leaderboard metrics, for GET /api/leaderboard?metric=
+const (
+	LeaderboardWins        = "wins"
+	LeaderboardWinRate     = "win_rate"
+	LeaderboardFastestSlap = "fastest_slap" // Lowest average reaction time
+)
+
+// LeaderboardResponse is a leaderboard's top players, best first
+type LeaderboardResponse struct {
+	Metric  string             `json:"metric"`
+	Entries []LeaderboardEntry `json:"entries"`
+}
+
+// LeaderboardEntry is one player's place on a leaderboard
+type LeaderboardEntry struct {
+	Rank      int     `json:"rank"`
+	Name      string  `json:"name"`
+	Score     float64 `json:"score"` // Wins, win rate from 0 to 1, or average reaction in ms, by metric
+	Played    int     `json:"played"`
+	Wins      int     `json:"wins"`
+	AverageMs int64   `json:"averageMs,omitempty"` // Average reaction of their successful slaps
+}
+
+// MatchHistoryResponse is a page of a player's finished games, latest first
+type MatchHistoryResponse struct {
+	Matches    []MatchRecord `json:"matches"`
+	NextCursor string        `json:"nextCursor,omitempty"` // Fetches the next page; empty on the last
+}
+
+// MatchRecord is a finished game in the match history
+type MatchRecord struct {
+	GameID     string        `json:"gameId"`
+	RoomCode   string        `json:"roomCode"`
+	StartedAt  int64         `json:"startedAt"` // Unix milliseconds
+	EndedAt    int64         `json:"endedAt"`   // Unix milliseconds
+	Duration   int64         `json:"duration"`  // milliseconds
+	WinnerName string        `json:"winnerName"`
+	WinReason  string        `json:"winReason"`
+	Settings   RoomSettings  `json:"settings"`
+	Players    []MatchPlayer `json:"players"`
+}
+
+// MatchPlayer is one player's part in a finished game
+type MatchPlayer struct {
+	PlayerID string      `json:"playerId,omitempty"` // Profile ID, for players who had a profile
+	Name     string      `json:"name"`
+	Won      bool        `json:"won"`
+	Stats    PlayerStats `json:"stats"`
+}
+
+// Room audit log entry types
+const (
+	AuditCreate       = "create"
+	AuditJoin         = "join"
+	AuditLeave        = "leave"
+	AuditKick         = "kick"
+	AuditBan          = "ban"
+	AuditRename       = "rename"
+	AuditHostTransfer = "host_transfer"
+	AuditSettings     = "settings"
+	AuditGameStart    = "game_start"
+	AuditIntegration  = "integration"
+)
+
+// AuditEntry is one event in a room's audit log, served to its host and admins
+// by GET /api/rooms/{code}/audit
+type AuditEntry struct {
+	Type       string `json:"type"`
+	ActorID    string `json:"actorId,omitempty"` // Who did it; empty for the server
+	ActorName  string `json:"actorName,omitempty"`
+	TargetID   string `json:"targetId,omitempty"` // Who it was done to, for kicks and host transfers
+	TargetName string `json:"targetName,omitempty"`
+	Detail     string `json:"detail,omitempty"` // Changed settings, old name and the like
+	Timestamp  int64  `json:"timestamp"`
+}
+
+type ReplayEventPayload struct {
+	GameID string      `json:"gameId"`
+	Event  ReplayEvent `json:"event"`
+}
+
+type ReplayCompletePayload struct {
+	GameID     string `json:"gameId"`
+	EventCount int    `json:"eventCount"`
+}
+
+type ServerShutdownPayload struct {
+	CountdownSeconds int    `json:"countdownSeconds"`
+	Message          string `json:"message"`
+}
+
+type SubscribedPayload struct {
+	Events []string `json:"events,omitempty"` // Only these room events are delivered; empty means all
+	Muted  []string `json:"muted,omitempty"`
+}
+
+type RematchVotePayload struct {
+	PlayerID string `json:"playerId"`
+	Votes    int    `json:"votes"`
+	Needed   int    `json:"needed"`
+}
+
+type RematchStartingPayload struct {
+	Votes int `json:"votes"`
+}
+
+type SuspicionFlaggedPayload struct {
+	RoomCode string        `json:"roomCode"`
+	Flag     SuspicionFlag `json:"flag"`
+}
+
+type CheatWarningPayload struct {
+	RoomCode string       `json:"roomCode"`
+	Warning  CheatWarning `json:"warning"`
 }
 
 type ErrorPayload struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code    ErrorCode         `json:"code"`
+	Message string            `json:"message"` // In the client's language where the server has it
+	Details map[string]string `json:"details,omitempty"`
+	Key     string            `json:"key,omitempty"` // Message key, for clients translating messages themselves
+	Params  map[string]string `json:"params,omitempty"`
 }
 
 // Shared Types
 
 type Player struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	CardCount   int    `json:"cardCount"`
-	IsHost      bool   `json:"isHost"`
-	IsConnected bool   `json:"isConnected"`
-	Position    int    `json:"position"`
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	CardCount   int     `json:"cardCount"`
+	IsHost      bool    `json:"isHost"`
+	IsConnected bool    `json:"isConnected"`
+	Position    int     `json:"position"`
+	Avatar      *Avatar `json:"avatar,omitempty"`
+	ProfileID   string  `json:"profileId,omitempty"` // Same across rooms for players joining with a profile
+	TeamID      int     `json:"teamId,omitempty"`    // 1 or 2 when the room plays in teams
+
+	// Equipped cosmetic item IDs by slot
+	Loadout map[string]string `json:"loadout,omitempty"`
+}
+
+// Avatar is an emoji shown on a colored background
+type Avatar struct {
+	Emoji string `json:"emoji"`
+	Color string `json:"color"` // #rrggbb
+}
+
+// Profile is a player identity kept across rooms and visits
+type Profile struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Avatar Avatar `json:"avatar"`
 }
 
 type Card struct {
@@ -206,14 +1077,90 @@ type Card struct {
 }
 
 type RoomSettings struct {
-	MaxPlayers      int  `json:"maxPlayers"`
-	SlapCooldownMs  int  `json:"slapCooldownMs"`
-	TurnTimeoutMs   int  `json:"turnTimeoutMs"`
-	EnableSandwich  bool `json:"enableSandwich"`
-	EnableDoubles   bool `json:"enableDoubles"`
-	BurnPenalty     int  `json:"burnPenalty"`
-	EnableSlapIn    bool `json:"enableSlapIn"`
-	MaxSlapIns      int  `json:"maxSlapIns"`
+	MaxPlayers      int    `json:"maxPlayers"`
+	SlapCooldownMs  int    `json:"slapCooldownMs"`
+	TurnTimeoutMs   int    `json:"turnTimeoutMs"`
+	AFKStrikes      int    `json:"afkStrikes"` // Timeouts in a row before a player is out; 0 for never
+	EnableSandwich  bool   `json:"enableSandwich"`
+	EnableDoubles   bool   `json:"enableDoubles"`
+	BurnPenalty     int    `json:"burnPenalty"`
+	EnableSlapIn    bool   `json:"enableSlapIn"`
+	MaxSlapIns      int    `json:"maxSlapIns"`
+	EnableMarriage  bool   `json:"enableMarriage"`
+	EnableTopBottom bool   `json:"enableTopBottom"`
+	EnableRuns      bool   `json:"enableRuns"`
+	EnableTens      bool   `json:"enableTens"`
+	GameMode        string `json:"gameMode"`        // classic, ratscrew
+	BurnDestination string `json:"burnDestination"` // pile, discard, winner
+	EscalatePenalty bool   `json:"escalatePenalty"`
+	DealRemainder   string `json:"dealRemainder"` // deal, pile, discard
+	PileCap         int    `json:"pileCap"`       // Unwon pile size at which the next play discards it; 0 for off
+	RematchQuorum   string `json:"rematchQuorum"` // all, majority
+	NumDecks        int    `json:"numDecks"`
+	ClassroomMode   bool   `json:"classroomMode"`
+	ShowFullPile    bool   `json:"showFullPile"`    // Full pile and recent plays for everyone, not just spectators
+	DuplicateNames  string `json:"duplicateNames"`  // reject, suffix
+	EnableRTC       bool   `json:"enableRtc"`       // Relay voice and video signaling between players
+	SlowModeSeconds int    `json:"slowModeSeconds"` // Least time between one player's reactions; 0 for off
+
+	// Adaptive pacing: turn timeouts shorten as the pile grows and lengthen after it's claimed
+	AdaptivePacing     bool `json:"adaptivePacing"`
+	PacingMinTimeoutMs int  `json:"pacingMinTimeoutMs"` // Shortest turn timeout
+	PacingRampCards    int  `json:"pacingRampCards"`    // Pile size at which the shortest timeout is reached
+	PacingClaimBonusMs int  `json:"pacingClaimBonusMs"` // Extra time on the turn after a pile is claimed
+
+	// Auto pacing: turn timeouts shorten as the game goes on, every few turns and for every player out
+	AutoPace          bool `json:"autoPace"`
+	AutoPaceStepTurns int  `json:"autoPaceStepTurns"` // Cards played per step down
+	AutoPaceFloorMs   int  `json:"autoPaceFloorMs"`   // Shortest turn timeout
+
+	CountdownSeconds  int  `json:"countdownSeconds"`  // Countdown before a game starts
+	ReadyCheck        bool `json:"readyCheck"`        // Players confirm they're ready when the host starts
+	ReadyCheckSeconds int  `json:"readyCheckSeconds"` // How long they have to
+
+	// Play in two teams that alternate turns and are only out once every
+	// teammate is; players are assigned with SET_TEAM
+	Teams bool `json:"teams"`
+
+	// Let players vote the host out with VOTE_KICK, not just each other
+	VoteKickHost bool `json:"voteKickHost"`
+
+	// What happens to a disconnected player's turns until they reconnect or
+	// their seat is given up: skip passes over them, autoplay plays their
+	// top card for them. Their hand is kept either way.
+	DisconnectedTurns string `json:"disconnectedTurns"`
+
+	// Where the hand of a player who leaves mid-game goes: deal shares it
+	// out among the players holding cards, pile puts it under the pile,
+	// discard takes it out of play
+	LeaverCards string `json:"leaverCards"`
+
+	// Who can find and join the room, see VisibilityPublic, and the most
+	// spectators it takes at once, 0 for no limit
+	Visibility    string `json:"visibility"`
+	MaxSpectators int    `json:"maxSpectators"`
+
+	// How the game is won: last_standing, piles (first to WinPiles piles
+	// won) or timed (most cards when TimeLimitSeconds run out); last player
+	// standing still wins the other two outright
+	WinCondition     string `json:"winCondition"`
+	WinPiles         int    `json:"winPiles"`
+	TimeLimitSeconds int    `json:"timeLimitSeconds"`
+
+	// Custom deck composition, for each of the NumDecks decks
+	Deck DeckSpec `json:"deck"`
+
+	// Settings preset: in UPDATE_SETTINGS, one to apply over the other
+	// fields; in the room's settings, the one they match, if any
+	Preset string `json:"preset,omitempty"`
+}
+
+// DeckSpec describes a custom deck: the ranks each suit has, which must
+// include the Jack, and how many Jacks are added on top
+// No ranks means every rank
+type DeckSpec struct {
+	Ranks      []string `json:"ranks"`
+	ExtraJacks int      `json:"extraJacks"`
 }
 
 type RoomState struct {
@@ -222,32 +1169,194 @@ type RoomState struct {
 	Settings RoomSettings `json:"settings"`
 	Status   string       `json:"status"` // waiting, starting, playing, finished
 	HostID   string       `json:"hostId"`
+
+	// Rule preset of a drop-in room; these have no host and start by themselves
+	DropIn string `json:"dropIn,omitempty"`
+
+	// Rule variant the room was created with, if any
+	VariantID string `json:"variantId,omitempty"`
+
+	SpectatorCount int `json:"spectatorCount"`
+
+	// Round-trip latency in milliseconds by player ID, for players with a measurement
+	PlayerLatency map[string]int64 `json:"playerLatency,omitempty"`
+
+	// Player IDs on each pile, from pile 1, while a party room plays its piles
+	Piles [][]string `json:"piles,omitempty"`
+
+	// Integrations the host has registered, see SET_INTEGRATION
+	Integrations []string `json:"integrations,omitempty"`
+
+	// When a scheduled game starts by itself, in Unix milliseconds
+	ScheduledAt int64 `json:"scheduledAt,omitempty"`
 }
 
 type GameStatePayload struct {
 	Pile             []Card         `json:"pile"`
 	CurrentPlayerID  string         `json:"currentPlayerId"`
+	TurnToken        int64          `json:"turnToken"`
 	PlayerCardCounts map[string]int `json:"playerCardCounts"`
 	CanSlap          bool           `json:"canSlap"`
+	ChallengeOwnerID string         `json:"challengeOwnerId,omitempty"`
+	ChancesRemaining int            `json:"chancesRemaining,omitempty"`
+
+	// Observer view only: every card on the pile, bottom first, and the most recent plays
+	FullPile    []Card     `json:"fullPile,omitempty"`
+	PileHistory []PilePlay `json:"pileHistory,omitempty"`
+}
+
+// PilePlay is one card played onto the pile
+type PilePlay struct {
+	PlayerID  string `json:"playerId"`
+	Card      Card   `json:"card"`
+	PileSize  int    `json:"pileSize"` // Pile size after the play
+	Timeout   bool   `json:"timeout,omitempty"`
+	Timestamp int64  `json:"timestamp"`
 }
 
 type GameStats struct {
-	TotalSlaps     int            `json:"totalSlaps"`
-	SuccessfulSlap map[string]int `json:"successfulSlaps"`
-	CardsBurned    map[string]int `json:"cardsBurned"`
-	Duration       int64          `json:"duration"` // milliseconds
+	TotalSlaps     int                    `json:"totalSlaps"`
+	SuccessfulSlap map[string]int         `json:"successfulSlaps"`
+	CardsBurned    map[string]int         `json:"cardsBurned"`
+	Players        map[string]PlayerStats `json:"players"`
+	Duration       int64                  `json:"duration"` // milliseconds
+}
+
+// PlayerStats is one player's record for a finished game
+type PlayerStats struct {
+	Slaps       int     `json:"slaps"`
+	Successes   int     `json:"successes"`
+	MissedSlaps int     `json:"missedSlaps"` // Slaps that didn't win a pile, burned for or not
+	FalseSlaps  int     `json:"falseSlaps"`  // Missed slaps that were penalized
+	CardsBurned int     `json:"cardsBurned"`
+	SlapIns     int     `json:"slapIns"`
+	FastestMs   int64   `json:"fastestMs,omitempty"`
+	AverageMs   int64   `json:"averageMs,omitempty"` // Over every successful slap
+	ReactionsMs []int64 `json:"reactionsMs"`         // The latest successful slaps'
+	LongestPile int     `json:"longestPile"`         // Most cards won in one pile
+	BiggestBurn int     `json:"biggestBurn"`         // Most cards burned by one false slap
+}
+
+// Award is an end-of-game superlative
+type Award struct {
+	Key         string `json:"key"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	PlayerID    string `json:"playerId"`
+	Value       int64  `json:"value"`
+}
+
+// SuspicionFlag is a pattern of play worth a human look, such as possible collusion
+type SuspicionFlag struct {
+	Kind          string `json:"kind"`
+	PlayerID      string `json:"playerId"`
+	OtherPlayerID string `json:"otherPlayerId,omitempty"`
+	Detail        string `json:"detail"`
+	Timestamp     int64  `json:"timestamp"`
+}
+
+// ReactionAnalysis summarizes a player's slap reaction times
+type ReactionAnalysis struct {
+	PlayerID string  `json:"playerId"`
+	Samples  int     `json:"samples"`
+	MinMs    int64   `json:"minMs"`
+	MedianMs int64   `json:"medianMs"`
+	MeanMs   float64 `json:"meanMs"`
+	StdDevMs float64 `json:"stdDevMs"`
+}
+
+// CheatWarning flags reaction times that look automated
+type CheatWarning struct {
+	Kind      string           `json:"kind"`
+	PlayerID  string           `json:"playerId"`
+	Detail    string           `json:"detail"`
+	Analysis  ReactionAnalysis `json:"analysis"`
+	Timestamp int64            `json:"timestamp"`
+}
+
+// TelemetryReport is a client's reported timing stored alongside what the
+// server observed of that client when it was reported
+type TelemetryReport struct {
+	PlayerID          string                 `json:"playerId"`
+	Reported          ReportTelemetryPayload `json:"reported"`
+	ObservedRTTMs     int64                  `json:"observedRttMs"`               // 0 if not yet measured
+	ObservedFastestMs int64                  `json:"observedFastestMs,omitempty"` // Fastest slap after a card this game, before latency
+	ObservedSlaps     int                    `json:"observedSlaps"`
+	Flagged           bool                   `json:"flagged"`
+	Detail            string                 `json:"detail,omitempty"` // Why it was flagged
+	Timestamp         int64                  `json:"timestamp"`
+}
+
+// RuleVariant describes a rule variant for room creation menus, in the
+// requested language
+type RuleVariant struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	MinPlayers  int          `json:"minPlayers"`
+	MaxPlayers  int          `json:"maxPlayers"`
+	Settings    RoomSettings `json:"settings"` // The room's starting settings
+}
+
+// ReplayEvent is a single recorded game event
+type ReplayEvent struct {
+	Seq       int    `json:"seq"`
+	Type      string `json:"type"` // deal, play, slap, burn, eliminate, afk, challenge, checkpoint, game_over, ended
+	PlayerID  string `json:"playerId,omitempty"`
+	Card      *Card  `json:"card,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Count     int    `json:"count,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+
+	// Game state at a checkpoint, from which a compacted replay resumes
+	Snapshot *ReplaySnapshot `json:"snapshot,omitempty"`
+}
+
+// ReplaySnapshot is the game state at a replay checkpoint
+type ReplaySnapshot struct {
+	CardCounts      map[string]int `json:"cardCounts"`
+	PileCount       int            `json:"pileCount"`
+	CurrentPlayerID string         `json:"currentPlayerId"`
+}
+
+// ReplayLog is the full ordered event list of a game
+type ReplayLog struct {
+	RoomCode   string        `json:"roomCode"`
+	GameID     string        `json:"gameId"`
+	Events     []ReplayEvent `json:"events"`
+	Highlights []Highlight   `json:"highlights,omitempty"`
+	Result     *SignedResult `json:"result,omitempty"` // For won games
+}
+
+// Highlight is a notable moment in a game
+type Highlight struct {
+	Kind      string `json:"kind"`
+	PlayerID  string `json:"playerId,omitempty"`
+	Detail    string `json:"detail"`
+	Value     int64  `json:"value"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type HighlightPayload struct {
+	GameID    string    `json:"gameId"`
+	Highlight Highlight `json:"highlight"`
 }
 
 // DefaultSettings returns the default room settings
 func DefaultSettings() RoomSettings {
 	return RoomSettings{
-		MaxPlayers:     4,
-		SlapCooldownMs: 200,
-		TurnTimeoutMs:  10000,
-		EnableSandwich: true,
-		EnableDoubles:  true,
-		BurnPenalty:    1,
-		EnableSlapIn:   true,
-		MaxSlapIns:     3,
+		MaxPlayers:      4,
+		SlapCooldownMs:  200,
+		TurnTimeoutMs:   10000,
+		EnableSandwich:  true,
+		EnableDoubles:   true,
+		BurnPenalty:     1,
+		EnableSlapIn:    true,
+		MaxSlapIns:      3,
+		GameMode:        "classic",
+		BurnDestination: "pile",
+		DealRemainder:   "deal",
+		RematchQuorum:   "all",
+		NumDecks:        1,
 	}
 }
@@ -0,0 +1,285 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// A small MessagePack codec for JSON-shaped values: nil, bool, int64,
+// float64, string, []interface{} and map[string]interface{}
+
+var errMsgPackTruncated = errors.New("msgpack: truncated data")
+
+// appendMsgPack appends the MessagePack encoding of v
+func appendMsgPack(b []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case int:
+		return appendMsgPackInt(b, int64(v)), nil
+	case int64:
+		return appendMsgPackInt(b, v), nil
+	case float64:
+		b = append(b, 0xcb)
+		return binary.BigEndian.AppendUint64(b, math.Float64bits(v)), nil
+	case string:
+		return appendMsgPackString(b, v), nil
+	case []interface{}:
+		b = appendMsgPackHeader(b, len(v), 0x90, 0xdc, 0xdd)
+		var err error
+		for _, item := range v {
+			if b, err = appendMsgPack(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]interface{}:
+		b = appendMsgPackHeader(b, len(v), 0x80, 0xde, 0xdf)
+		var err error
+		for key, item := range v {
+			b = appendMsgPackString(b, key)
+			if b, err = appendMsgPack(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("msgpack: can't encode %T", v)
+}
+
+func appendMsgPackInt(b []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n <= 127:
+		return append(b, byte(n))
+	case n < 0 && n >= -32:
+		return append(b, byte(n))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		return append(b, 0xd0, byte(n))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(n))
+}
+
+func appendMsgPackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+// appendMsgPackHeader appends an array or map header, using the fix form for
+// fewer than 16 entries
+func appendMsgPackHeader(b []byte, n int, fix, code16, code32 byte) []byte {
+	switch {
+	case n < 16:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, code16), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, code32), uint32(n))
+}
+
+// msgPackReader decodes MessagePack values from a buffer
+type msgPackReader struct {
+	data  []byte
+	depth int
+}
+
+// Deepest nesting decoded, so a hostile message can't exhaust the stack
+const maxMsgPackDepth = 32
+
+func (r *msgPackReader) take(n int) ([]byte, error) {
+	if n < 0 || len(r.data) < n {
+		return nil, errMsgPackTruncated
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b, nil
+}
+
+// length reads a big-endian length of size bytes
+func (r *msgPackReader) length(size int) (int, error) {
+	b, err := r.take(size)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, x := range b {
+		n = n<<8 | uint64(x)
+	}
+	// Every byte of a string, and every entry of an array or map, takes at
+	// least a byte, so longer lengths can't be genuine
+	if n > uint64(len(r.data)) {
+		return 0, errMsgPackTruncated
+	}
+	return int(n), nil
+}
+
+// value decodes one value; integers come out as int64 and floats as float64
+func (r *msgPackReader) value() (interface{}, error) {
+	head, err := r.take(1)
+	if err != nil {
+		return nil, err
+	}
+	c := head[0]
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return r.mapOf(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return r.arrayOf(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return r.str(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xd9: // bin 8 is accepted as a string
+		return r.strOf(1)
+	case 0xc5, 0xda:
+		return r.strOf(2)
+	case 0xc6, 0xdb:
+		return r.strOf(4)
+	case 0xca:
+		b, err := r.take(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 0xcb:
+		b, err := r.take(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf, 0xd0, 0xd1, 0xd2, 0xd3:
+		return r.integer(c)
+	case 0xdc:
+		n, err := r.length(2)
+		if err != nil {
+			return nil, err
+		}
+		return r.arrayOf(n)
+	case 0xdd:
+		n, err := r.length(4)
+		if err != nil {
+			return nil, err
+		}
+		return r.arrayOf(n)
+	case 0xde:
+		n, err := r.length(2)
+		if err != nil {
+			return nil, err
+		}
+		return r.mapOf(n)
+	case 0xdf:
+		n, err := r.length(4)
+		if err != nil {
+			return nil, err
+		}
+		return r.mapOf(n)
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type 0x%02x", c)
+}
+
+func (r *msgPackReader) integer(c byte) (interface{}, error) {
+	size := 1 << (c & 0x03)
+	b, err := r.take(size)
+	if err != nil {
+		return nil, err
+	}
+	var u uint64
+	for _, x := range b {
+		u = u<<8 | uint64(x)
+	}
+	if c <= 0xcf { // Unsigned
+		if u > math.MaxInt64 {
+			return float64(u), nil
+		}
+		return int64(u), nil
+	}
+	shift := 64 - 8*size
+	return int64(u<<shift) >> shift, nil
+}
+
+func (r *msgPackReader) strOf(size int) (interface{}, error) {
+	n, err := r.length(size)
+	if err != nil {
+		return nil, err
+	}
+	return r.str(n)
+}
+
+func (r *msgPackReader) str(n int) (interface{}, error) {
+	b, err := r.take(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (r *msgPackReader) arrayOf(n int) (interface{}, error) {
+	if r.depth++; r.depth > maxMsgPackDepth {
+		return nil, errors.New("msgpack: nested too deeply")
+	}
+	defer func() { r.depth-- }()
+
+	items := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		item, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (r *msgPackReader) mapOf(n int) (interface{}, error) {
+	if r.depth++; r.depth > maxMsgPackDepth {
+		return nil, errors.New("msgpack: nested too deeply")
+	}
+	defer func() { r.depth-- }()
+
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		k, ok := key.(string)
+		if !ok {
+			return nil, errors.New("msgpack: map keys must be strings")
+		}
+		if m[k], err = r.value(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,89 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PayloadError describes why a client payload was rejected, naming the
+// offending field where there is one
+type PayloadError struct {
+	Field  string // Dotted path to the field, empty for the payload as a whole
+	Reason string
+}
+
+func (e *PayloadError) Error() string {
+	if e.Field == "" {
+		return e.Reason
+	}
+	return e.Field + ": " + e.Reason
+}
+
+// DecodePayload decodes a client payload strictly into v: unknown fields,
+// values of the wrong type and trailing data are all rejected with a
+// *PayloadError. A missing or null payload leaves v as it is.
+func DecodePayload(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return payloadError(err)
+	}
+	if dec.More() {
+		return &PayloadError{Reason: "unexpected data after the payload"}
+	}
+	return nil
+}
+
+// payloadError converts a decoding error to a PayloadError
+func payloadError(err error) *PayloadError {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return &PayloadError{Reason: fmt.Sprintf("payload must be %s, not %s", jsonKind(typeErr.Type.Kind().String()), typeErr.Value)}
+		}
+		return &PayloadError{
+			Field:  typeErr.Field,
+			Reason: fmt.Sprintf("must be %s, not %s", jsonKind(typeErr.Type.Kind().String()), typeErr.Value),
+		}
+	case errors.As(err, &syntaxErr):
+		return &PayloadError{Reason: "malformed JSON at offset " + strconv.FormatInt(syntaxErr.Offset, 10)}
+	}
+
+	// encoding/json reports unknown fields only in the message text
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		if unquoted, err := strconv.Unquote(name); err == nil {
+			name = unquoted
+		}
+		return &PayloadError{Field: name, Reason: "unknown field"}
+	}
+	return &PayloadError{Reason: err.Error()}
+}
+
+// jsonKind names a Go kind the way a JSON client would think of it
+func jsonKind(kind string) string {
+	switch kind {
+	case "string":
+		return "a string"
+	case "bool":
+		return "a boolean"
+	case "slice", "array":
+		return "an array"
+	case "struct", "map":
+		return "an object"
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return "an integer"
+	case "float32", "float64":
+		return "a number"
+	}
+	return kind
+}
@@ -12,4 +12,4 @@ done
 echo "Redis is ready"
 
 # Start the Go server (foreground)
-exec ./server
+exec ./server serve